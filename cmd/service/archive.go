@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/archive"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// archiveDateLayout is the date format accepted by archive flags
+const archiveDateLayout = "2006-01-02"
+
+// runArchiveCommand handles "archive export" and "archive verify" and
+// returns the process exit code
+func runArchiveCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: archive <export|verify> [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "export":
+		return runArchiveExport(args[1:])
+	case "verify":
+		return runArchiveVerify(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown archive command: %s\n", args[0])
+		return 2
+	}
+}
+
+// runArchiveExport writes the sales of a period to an archive file
+func runArchiveExport(args []string) int {
+	fs := flag.NewFlagSet("archive export", flag.ExitOnError)
+	from := fs.String("from", "", "First day of the period (YYYY-MM-DD, inclusive)")
+	to := fs.String("to", "", "Last day of the period (YYYY-MM-DD, inclusive)")
+	outDir := fs.String("out", ".", "Directory to write the archive to")
+	profile := fs.String("profile", "", "Database profile to export from")
+	fs.Parse(args)
+
+	fromDate, err := time.Parse(archiveDateLayout, *from)
+	if err != nil {
+		log.Printf("Invalid -from date: %v", err)
+		return 2
+	}
+	toDate, err := time.Parse(archiveDateLayout, *to)
+	if err != nil {
+		log.Printf("Invalid -to date: %v", err)
+		return 2
+	}
+	toDate = toDate.AddDate(0, 0, 1) // Exclusive upper bound
+
+	app, err := NewApplication(false, *profile)
+	if err != nil {
+		log.Printf("Failed to initialize application: %v", err)
+		return 1
+	}
+	defer app.db.Close()
+
+	sales, err := app.db.ListSales(fromDate, toDate)
+	if err != nil {
+		log.Printf("Failed to load sales: %v", err)
+		return 1
+	}
+
+	cfg, err := app.config.Get()
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
+		return 1
+	}
+
+	name := fmt.Sprintf("sales-%s-%s-%s%s", cfg.GetStoreID(), *from, *to, archive.FileExt)
+	path := filepath.Join(*outDir, name)
+	manifest, err := archive.WriteSales(path, sales, app.serverKey, &archive.Options{
+		StoreID:   cfg.GetStoreID(),
+		MachineID: app.machineID,
+		From:      fromDate,
+		To:        toDate,
+	})
+	if err != nil {
+		log.Printf("Failed to write archive: %v", err)
+		return 1
+	}
+
+	fmt.Printf("Archived %d sale(s) to %s (key ID %s)\n", manifest.RecordCount, path, manifest.Encryption.KeyID)
+	return 0
+}
+
+// runArchiveVerify validates one or more archive files
+func runArchiveVerify(args []string) int {
+	fs := flag.NewFlagSet("archive verify", flag.ExitOnError)
+	keyB64 := fs.String("key", "", "Base64 server key; enables decryption and record validation")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: archive verify [-key BASE64] FILE...")
+		return 2
+	}
+
+	var serverKey []byte
+	if *keyB64 != "" {
+		key, err := security.ServerKeyFromBase64(*keyB64)
+		if err != nil {
+			log.Printf("Invalid -key: %v", err)
+			return 2
+		}
+		serverKey = key
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		manifest, err := archive.Verify(path, serverKey)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		depth := "structure"
+		if serverKey != nil {
+			depth = "structure and records"
+		}
+		fmt.Printf("OK   %s: %d record(s), %s to %s, format v%d (%s verified)\n",
+			path, manifest.RecordCount, manifest.PeriodFrom, manifest.PeriodTo, manifest.FormatVersion, depth)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
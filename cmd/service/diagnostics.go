@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/professor93/promo-pos/internal/diagnostics"
+)
+
+// versionInfo is the version.json member of a diagnostics bundle
+type versionInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	SafeMode  bool   `json:"safe_mode"`
+}
+
+// sealDiagnostics completes a GET /diagnostics bundle with the sanitized
+// config and version, encrypted with the server key so support can open
+// it with the store's key
+func (app *Application) sealDiagnostics(files []diagnostics.File) ([]byte, error) {
+	config, err := app.config.Redacted()
+	if err != nil {
+		return nil, err
+	}
+	info, err := json.MarshalIndent(versionInfo{
+		Version:   version,
+		BuildTime: buildTime,
+		GitCommit: gitCommit,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		SafeMode:  app.safeMode,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal version: %w", err)
+	}
+	files = append(files,
+		diagnostics.File{Name: "config.json", Data: config},
+		diagnostics.File{Name: "version.json", Data: info},
+	)
+
+	storeID := ""
+	if cfg, err := app.config.Get(); err == nil {
+		storeID = cfg.GetStoreID()
+	}
+	return diagnostics.Write(files, app.serverKey, storeID)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/professor93/promo-pos/internal/config"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"github.com/professor93/promo-pos/pkg/paths"
+)
+
+// runLogsCommand handles "logs decrypt" and returns the process exit code
+func runLogsCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: logs decrypt [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "decrypt":
+		return runLogsDecrypt(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown logs command: %s\n", args[0])
+		return 2
+	}
+}
+
+// runLogsDecrypt writes a readable copy of an encrypted log file. Lines are
+// sealed with this terminal's config key, so it only works here and for an
+// account that can read the key store (the service account or an
+// administrator).
+func runLogsDecrypt(args []string) int {
+	fs := flag.NewFlagSet("logs decrypt", flag.ExitOnError)
+	in := fs.String("in", "", "Log file to decrypt (defaults to the service log)")
+	out := fs.String("out", "", "File to write the decrypted log to (defaults to stdout)")
+	fs.Parse(args)
+
+	appPaths, err := paths.Resolve(nil)
+	if err != nil {
+		log.Printf("Failed to resolve paths: %v", err)
+		return 1
+	}
+	if *in == "" {
+		*in = filepath.Join(appPaths.LogDir, constants.LogFileName)
+	}
+
+	machineID, err := security.GetMachineID()
+	if err != nil {
+		log.Printf("Failed to get machine ID: %v", err)
+		return 1
+	}
+	configMgr, err := config.NewManager(machineID, appPaths.ConfigDir)
+	if err != nil {
+		log.Printf("Failed to load config key: %v", err)
+		return 1
+	}
+
+	src, err := os.Open(*in)
+	if err != nil {
+		log.Printf("Failed to open log file: %v", err)
+		return 1
+	}
+	defer src.Close()
+
+	var dst io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Printf("Failed to create output file: %v", err)
+			return 1
+		}
+		defer file.Close()
+		dst = file
+	}
+
+	failed, err := logging.Decrypt(src, dst, logDeciphers(configMgr)...)
+	if err != nil {
+		log.Printf("Failed to decrypt log: %v", err)
+		return 1
+	}
+	if failed > 0 {
+		log.Printf("%d line(s) could not be decrypted with this terminal's keys", failed)
+		return 1
+	}
+	return 0
+}
+
+// readLogs serves GET /logs from the service log directory
+func (app *Application) readLogs(q logging.Query) (*logging.Tail, error) {
+	return logging.ReadTail(app.paths.LogDir, q, logDeciphers(app.config)...)
+}
+
+// logDeciphers returns the keys that may have sealed log lines: the
+// config key, then older ones
+func logDeciphers(configMgr *config.Manager) []logging.Decipher {
+	encryptions := configMgr.Encryptions()
+	deciphers := make([]logging.Decipher, 0, len(encryptions))
+	for _, enc := range encryptions {
+		deciphers = append(deciphers, enc)
+	}
+	return deciphers
+}
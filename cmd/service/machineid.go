@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/professor93/promo-pos/internal/config"
+	"github.com/professor93/promo-pos/internal/provision"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/internal/sync"
+	"github.com/professor93/promo-pos/pkg/paths"
+)
+
+// runMachineIDCommand handles "machine-id status|migrate" and returns the
+// process exit code
+func runMachineIDCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: machine-id status|migrate [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "status":
+		return runMachineIDStatus()
+	case "migrate":
+		return runMachineIDMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown machine-id command: %s\n", args[0])
+		return 2
+	}
+}
+
+// runMachineIDStatus prints the machine ID and whether the hardware changed
+func runMachineIDStatus() int {
+	check, err := security.CheckMachineID()
+	if err != nil {
+		log.Printf("Failed to check machine ID: %v", err)
+		return 1
+	}
+
+	fmt.Printf("Machine ID: %s\n", check.MachineID)
+	fmt.Printf("Hardware:   %s\n", check.Fingerprint)
+	if check.Changed {
+		fmt.Println("The hardware changed since the machine ID was confirmed; run \"machine-id migrate\" to re-enroll")
+	}
+	return 0
+}
+
+// runMachineIDMigrate re-enrolls the terminal under the machine ID of its
+// current hardware. After the operator confirms and the backend approves,
+// config.enc, the file vault and the sealed keys are re-encrypted for the
+// new ID, which then replaces the stored one. An interrupted migration can
+// be run again.
+func runMachineIDMigrate(args []string) int {
+	fs := flag.NewFlagSet("machine-id migrate", flag.ExitOnError)
+	previous := fs.String("previous", "", "Machine ID config.enc is encrypted for, when it is no longer stored (head office has it)")
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+	fs.Parse(args)
+
+	appPaths, err := paths.Resolve(nil)
+	if err != nil {
+		log.Printf("Failed to resolve paths: %v", err)
+		return 1
+	}
+
+	check, err := security.CheckMachineID()
+	if err != nil {
+		log.Printf("Failed to check machine ID: %v", err)
+		return 1
+	}
+	oldID, newID := check.MachineID, check.Fingerprint
+	if *previous != "" {
+		oldID = *previous
+	}
+	if oldID == newID {
+		fmt.Println("The machine ID already matches this hardware")
+		return 0
+	}
+
+	// Read the config as the terminal was enrolled. An interrupted
+	// migration may already have re-encrypted it for the new ID, after the
+	// backend approved.
+	oldMgr, err := config.NewManager(oldID, appPaths.ConfigDir)
+	if err != nil {
+		log.Printf("Failed to create config manager: %v", err)
+		return 1
+	}
+	cfg, err := oldMgr.Load()
+	resumed := false
+	if err != nil {
+		newMgr, newErr := config.NewManager(newID, appPaths.ConfigDir)
+		if newErr != nil {
+			log.Printf("Failed to create config manager: %v", newErr)
+			return 1
+		}
+		if cfg, newErr = newMgr.Load(); newErr != nil {
+			log.Printf("config.enc cannot be opened for machine ID %s: %v", oldID, err)
+			log.Printf("Pass the machine ID head office has for this terminal with -previous")
+			return 1
+		}
+		resumed = true
+		log.Printf("Resuming an interrupted migration to machine ID %s", newID)
+	}
+
+	keyDir := filepath.Join(appPaths.DataDir, keyStoreDir)
+	keys, err := security.NewKeyStore(keyDir, cfg.GetKeyStorage())
+	if err != nil {
+		log.Printf("Failed to open key store: %v", err)
+		return 1
+	}
+
+	if !resumed {
+		vault, err := openSecretsVault(cfg.GetSecretVault(), keyDir, oldMgr)
+		if err != nil {
+			log.Printf("Failed to open secrets vault: %v", err)
+			return 1
+		}
+		if err := oldMgr.UseVault(vault); err != nil {
+			log.Printf("Failed to read secrets: %v", err)
+			return 1
+		}
+
+		fmt.Printf("Store:              %s\n", cfg.GetStoreID())
+		fmt.Printf("Current machine ID: %s\n", oldID)
+		fmt.Printf("New machine ID:     %s\n", newID)
+		if !*yes && !confirm("Re-enroll this terminal under the new machine ID? Type \"yes\" to continue: ") {
+			fmt.Println("Migration cancelled")
+			return 1
+		}
+
+		if err := approveMachineID(cfg, oldID, newID, keys, oldMgr); err != nil {
+			log.Printf("Migration not approved: %v", err)
+			return 1
+		}
+		log.Println("Backend approved the new machine ID")
+	}
+
+	// Re-encrypt for the new ID; files still under the old one are opened
+	// with it once more
+	newMgr, err := config.NewMigrationManager(newID, oldID, appPaths.ConfigDir)
+	if err != nil {
+		log.Printf("Failed to create config manager: %v", err)
+		return 1
+	}
+	cfg, err = newMgr.Load()
+	if err != nil {
+		log.Printf("Failed to re-encrypt config: %v", err)
+		return 1
+	}
+	vault, err := openSecretsVault(cfg.GetSecretVault(), keyDir, newMgr)
+	if err != nil {
+		log.Printf("Failed to open secrets vault: %v", err)
+		return 1
+	}
+	if err := newMgr.UseVault(vault); err != nil {
+		log.Printf("Failed to read secrets: %v", err)
+		return 1
+	}
+	if err := newMgr.Save(cfg); err != nil {
+		log.Printf("Failed to re-encrypt secrets: %v", err)
+		return 1
+	}
+
+	provisioner, err := provision.New(&provision.Config{Keys: keys, Wrapper: newMgr})
+	if err != nil {
+		log.Printf("Failed to create provisioner: %v", err)
+		return 1
+	}
+	if err := provisioner.Rewrap(); err != nil {
+		log.Printf("Failed to re-encrypt sealed keys: %v", err)
+		return 1
+	}
+
+	if err := security.ReplaceMachineID(newID); err != nil {
+		log.Printf("Failed to store the new machine ID: %v", err)
+		return 1
+	}
+	log.Printf("Terminal re-enrolled under machine ID %s; restart the service", newID)
+	return 0
+}
+
+// approveMachineID asks the backend to accept newID for the terminal
+// enrolled as oldID
+func approveMachineID(cfg *config.Config, oldID, newID string, keys security.KeyStore, wrapper provision.Wrapper) error {
+	client, err := sync.NewHTTPClient(&sync.ClientConfig{
+		Timeout: provisionTimeout,
+		Pins:    cfg.GetBackendPins(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create provisioning client: %w", err)
+	}
+
+	// Unsigned until the store is enrolled, like the startup provisioning
+	signer, err := security.NewRequestSigner(cfg.GetStoreID(), []byte(cfg.GetStoreToken()))
+	if err != nil {
+		signer = nil
+	}
+
+	provisioner, err := provision.New(&provision.Config{
+		ServerURL:  cfg.GetServerURL(),
+		StoreID:    cfg.GetStoreID(),
+		StoreToken: cfg.GetStoreToken(),
+		MachineID:  oldID,
+		Keys:       keys,
+		Wrapper:    wrapper,
+		HTTPClient: client,
+		Signer:     signer,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+	defer cancel()
+	return provisioner.ApproveMachineID(ctx, newID)
+}
+
+// confirm asks a yes/no question on the terminal
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "yes")
+}
@@ -1,22 +1,63 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/professor93/promo-pos/internal/auth"
 	"github.com/professor93/promo-pos/internal/config"
 	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/directives"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/hub"
+	"github.com/professor93/promo-pos/internal/integrity"
+	"github.com/professor93/promo-pos/internal/jobs"
+	"github.com/professor93/promo-pos/internal/journal"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/metrics"
+	"github.com/professor93/promo-pos/internal/mqtt"
+	"github.com/professor93/promo-pos/internal/peripheral"
+	"github.com/professor93/promo-pos/internal/provision"
+	"github.com/professor93/promo-pos/internal/receipt"
+	"github.com/professor93/promo-pos/internal/report"
+	"github.com/professor93/promo-pos/internal/sales"
 	"github.com/professor93/promo-pos/internal/security"
 	"github.com/professor93/promo-pos/internal/server"
 	"github.com/professor93/promo-pos/internal/service"
+	"github.com/professor93/promo-pos/internal/sync"
+	"github.com/professor93/promo-pos/internal/webhook"
 	"github.com/professor93/promo-pos/pkg/constants"
+	"github.com/professor93/promo-pos/pkg/paths"
+	"go.uber.org/zap"
 )
 
+// salesJournalFile is the write-ahead journal stored next to the database
+const salesJournalFile = "sales.journal"
+
+// provisionTimeout bounds the first-start server key request
+const provisionTimeout = 30 * time.Second
+
+// keyStoreDir holds sealed secrets (TPM or DPAPI/file) under the data directory
+const keyStoreDir = "keys"
+
+// airgapExportLimit caps the outbox entries written to a single bundle
+const airgapExportLimit = 10000
+
 var (
 	version   = "1.0.0"
 	buildTime = "unknown"
@@ -25,14 +66,51 @@ var (
 
 // Application holds the main application state
 type Application struct {
-	machineID     string
-	config        *config.Manager
-	db            *database.DB
-	httpServer    *server.Server
+	machineID      string
+	serverKey      []byte
+	keys           security.KeyStore
+	vault          security.Vault
+	provisioner    *provision.Provisioner
+	paths          *paths.Paths
+	logger         *zap.Logger
+	logLevel       zap.AtomicLevel
+	config         *config.Manager
+	db             *database.DB
+	httpServer     *server.Server
+	hub            *hub.Hub
+	jobs           *jobs.Manager
+	journal        *journal.Journal
+	journalKey     *security.DatabaseEncryption
+	ledger         *sales.Ledger
+	bundles        *sync.BundleSyncer
+	directives     *directives.Processor
+	mqtt           *mqtt.Bridge
+	reports        *report.Scheduler
+	peripherals    *peripheral.Monitor
+	bootID         int64
+	safeMode       bool
+	mailer         *report.Mailer
+	syncStats      *sync.Stats
+	metrics        *metrics.Registry
+	metricsAddr    string
+	grpcAddr       string // gRPC listener; "" when disabled
 	serviceManager *service.Manager
+	events         *events.Bus
+	webhooks       *webhook.Dispatcher
 }
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		os.Exit(runArchiveCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "logs" {
+		os.Exit(runLogsCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "machine-id" {
+		os.Exit(runMachineIDCommand(os.Args[2:]))
+	}
+
 	// Parse command-line flags
 	var (
 		installFlag   = flag.Bool("install", false, "Install the service")
@@ -43,6 +121,15 @@ func main() {
 		statusFlag    = flag.Bool("status", false, "Show service status")
 		versionFlag   = flag.Bool("version", false, "Show version information")
 		debugFlag     = flag.Bool("debug", false, "Run in debug mode (foreground)")
+		demoFlag      = flag.Bool("demo", false, "Use an in-memory database (nothing is persisted)")
+		profileFlag   = flag.String("profile", "", "Database profile to use (defaults to the configured store ID)")
+		exportFlag    = flag.String("export-bundle", "", "Write an air-gapped sync bundle to the given directory")
+		importFlag    = flag.String("import-bundle", "", "Apply an air-gapped sync bundle from the given file")
+		wipeFlag      = flag.Bool("wipe", false, "Securely delete all local data and key material (decommissioning)")
+		confirmFlag   = flag.String("confirm", "", "Machine ID confirming a destructive command such as -wipe")
+		issueFlag     = flag.String("issue-token", "", "Issue an API token for a role (admin, staff, attendant, cashier, self_checkout, terminal) and print it")
+		labelFlag     = flag.String("token-label", "", "Label recorded with -issue-token, e.g. the lane or device")
+		passwordFlag  = flag.Bool("set-admin-password", false, "Set the admin password required by destructive routes (read from stdin)")
 	)
 	flag.Parse()
 
@@ -55,7 +142,7 @@ func main() {
 	}
 
 	// Initialize application
-	app, err := NewApplication()
+	app, err := NewApplication(*demoFlag, *profileFlag)
 	if err != nil {
 		log.Fatalf("Failed to initialize application: %v", err)
 	}
@@ -111,12 +198,59 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Decommissioning: destroy local data and key material
+	if *wipeFlag {
+		if err := app.Wipe(*confirmFlag); err != nil {
+			log.Fatalf("Failed to wipe terminal: %v", err)
+		}
+		fmt.Println("Terminal wiped successfully")
+		os.Exit(0)
+	}
+
+	// Bearer tokens for attendants and self-checkout lanes
+	if *issueFlag != "" {
+		token, err := auth.Issue(app.db, *issueFlag, *labelFlag, 0)
+		if err != nil {
+			log.Fatalf("Failed to issue token: %v", err)
+		}
+		fmt.Printf("Token (shown once): %s\n", token)
+		os.Exit(0)
+	}
+
+	if *passwordFlag {
+		if err := setAdminPassword(app.db); err != nil {
+			log.Fatalf("Failed to set admin password: %v", err)
+		}
+		fmt.Println("Admin password set")
+		os.Exit(0)
+	}
+
+	// Air-gapped sync via removable media
+	if *exportFlag != "" {
+		path, err := app.bundles.Export(*exportFlag, airgapExportLimit)
+		if err != nil {
+			log.Fatalf("Failed to export sync bundle: %v", err)
+		}
+		fmt.Printf("Sync bundle written to %s\n", path)
+		os.Exit(0)
+	}
+
+	if *importFlag != "" {
+		bundle, err := app.bundles.Import(*importFlag)
+		if err != nil {
+			log.Fatalf("Failed to import sync bundle: %v", err)
+		}
+		fmt.Printf("Sync bundle %d applied\n", bundle.Sequence)
+		os.Exit(0)
+	}
+
 	// Run in debug mode (foreground)
 	if *debugFlag {
 		fmt.Println("Running in debug mode...")
 		if err := app.RunDebug(); err != nil {
 			log.Fatalf("Debug mode failed: %v", err)
 		}
+		app.config.Close()
 		return
 	}
 
@@ -125,12 +259,39 @@ func main() {
 	if err := program.Run(); err != nil {
 		log.Fatalf("Service failed: %v", err)
 	}
+
+	// The config keys seal the log, so they go last
+	app.config.Close()
 }
 
 // NewApplication creates and initializes the application
-func NewApplication() (*Application, error) {
+// In demo mode the database is kept in memory and discarded on exit.
+// Each profile (one per store ID by default) gets an isolated database.
+func NewApplication(demo bool, profile string) (*Application, error) {
 	app := &Application{}
 
+	// Refuse to run a modified binary
+	verified, err := integrity.VerifySelf()
+	if err != nil {
+		return nil, err
+	}
+	if verified.Method == integrity.MethodNone {
+		log.Println("Development build: executable signature not verified")
+	} else {
+		log.Printf("Executable signature verified (%s)", verified.Method)
+	}
+
+	// Resolve and create application directories
+	appPaths, err := paths.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve paths: %w", err)
+	}
+	if err := appPaths.Ensure(); err != nil {
+		return nil, err
+	}
+	app.paths = appPaths
+	log.Printf("Data directory: %s", appPaths.DataDir)
+
 	// Get machine ID
 	machineID, err := security.GetMachineID()
 	if err != nil {
@@ -138,59 +299,374 @@ func NewApplication() (*Application, error) {
 	}
 	app.machineID = machineID
 	log.Printf("Machine ID: %s", machineID)
+	if check, err := security.CheckMachineID(); err != nil {
+		log.Printf("Warning: failed to check machine ID: %v", err)
+	} else if check.Changed {
+		log.Printf("Warning: the hardware changed since machine ID %s was confirmed; run \"pos-service machine-id migrate\" to re-enroll", machineID)
+	}
 
 	// Initialize config manager
-	configMgr, err := config.NewManager(machineID)
+	configMgr, err := config.NewManager(machineID, appPaths.ConfigDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config manager: %w", err)
 	}
 	app.config = configMgr
 
+	// Route all logging, including the standard logger, through zap; the
+	// log file is sealed with the config encryption
+	app.logLevel = zap.NewAtomicLevel()
+	logger, err := logging.New(appPaths.LogDir, app.logLevel, configMgr.Encryption())
+	if err != nil {
+		return nil, err
+	}
+	app.logger = logger
+	logging.RedirectStdLog(logger)
+
 	// Load configuration
 	cfg, err := configMgr.Load()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config (after a hardware change, run \"pos-service machine-id migrate\"): %w", err)
 	}
 	log.Printf("Configuration loaded (Port: %d)", cfg.Port)
+	if err := logging.SetLevel(app.logLevel, cfg.GetLogLevel()); err != nil {
+		return nil, err
+	}
+	// Settings applied without a restart when PUT /config changes them
+	configMgr.OnReload("log_level", func(c *config.Config) error {
+		return logging.SetLevel(app.logLevel, c.GetLogLevel())
+	})
+	if configMgr.MigratedKey() {
+		log.Println("Configuration re-encrypted with the new config key")
+	}
 
-	// Initialize database (with a dummy server key for now)
-	// TODO: Fetch server key from API
-	serverKey, err := security.GenerateServerKey()
+	if profile == "" {
+		profile = cfg.GetStoreID()
+	}
+
+	// Open the sealed key store; secrets sealed here need this machine (and
+	// its TPM, when present) to be read back
+	keys, err := security.NewKeyStore(filepath.Join(appPaths.DataDir, keyStoreDir), cfg.GetKeyStorage())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key store: %w", err)
+	}
+	app.keys = keys
+	log.Printf("Key store backend: %s", keys.Backend())
+
+	// Tokens and passwords from the config (store token, api_secret, MQTT
+	// and SMTP passwords) live in the secrets vault, not config.enc
+	vault, err := openSecretsVault(cfg.GetSecretVault(), filepath.Join(appPaths.DataDir, keyStoreDir), configMgr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate server key: %w", err)
+		return nil, fmt.Errorf("failed to open secrets vault: %w", err)
+	}
+	if err := configMgr.UseVault(vault); err != nil {
+		return nil, err
+	}
+	app.vault = vault
+	log.Printf("Secrets vault backend: %s", vault.Backend())
+
+	// Database server key: provisioned from the backend with the store
+	// token on first start, then wrapped with the config key and sealed so
+	// every later boot opens the same database. Demo databases are
+	// discarded on exit, so they get a throwaway key.
+	var serverKey []byte
+	if demo {
+		serverKey, err = security.GenerateServerKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate server key: %w", err)
+		}
+	} else {
+		// The server key travels over this connection: pin it like sync
+		provisionClient, err := sync.NewHTTPClient(&sync.ClientConfig{
+			Timeout: provisionTimeout,
+			Pins:    cfg.GetBackendPins(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provisioning client: %w", err)
+		}
+
+		app.provisioner, err = provision.New(&provision.Config{
+			ServerURL:  cfg.GetServerURL(),
+			StoreID:    cfg.GetStoreID(),
+			StoreToken: cfg.GetStoreToken(),
+			MachineID:  machineID,
+			Keys:       keys,
+			Wrapper:    configMgr,
+			HTTPClient: provisionClient,
+			Signer:     app.requestSigner(cfg),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provisioner: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+		key, fetched, err := app.provisioner.ServerKey(ctx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to provision server key: %w", err)
+		}
+		serverKey = key
+		if fetched {
+			log.Println("Server key provisioned from backend")
+		} else {
+			log.Println("Server key loaded from key store")
+		}
 	}
 
+	app.serverKey = serverKey
+
 	db, err := database.New(&database.Config{
-		ServerKey: serverKey,
-		DataDir:   "",
+		ServerKey:     serverKey,
+		DataDir:       appPaths.DataDir,
+		InMemory:      demo,
+		Profile:       profile,
+		CompressAbove: cfg.GetCompressAbove(),
+		Cipher:        cfg.GetDBCipher(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 	app.db = db
-	log.Println("Database initialized")
+	log.Printf("Database initialized (cipher: %s)", cfg.GetDBCipher())
 
-	// Initialize HTTP server
-	serverCfg := &server.Config{
-		Port: cfg.Port,
+	// A crash loop starts safe mode: the core API and diagnostics run, the
+	// store hub, MQTT bridge and report scheduler do not
+	if threshold := cfg.GetSafeModeCrashes(); threshold > 0 {
+		crashes, err := db.CountCrashes(time.Now().Add(-cfg.GetSafeModeWindow()))
+		if err != nil {
+			log.Printf("Warning: failed to count recent crashes: %v", err)
+		} else if crashes >= threshold {
+			app.safeMode = true
+			log.Printf("SAFE MODE: %d crashes in the last %s; peer sync, MQTT and reports are disabled", crashes, cfg.GetSafeModeWindow())
+		}
+	}
+
+	if err := app.resumeKeyRotation(); err != nil {
+		return nil, err
+	}
+
+	// Sales commit through a write-ahead journal next to the database;
+	// replay anything a crash left behind before accepting new sales
+	if !db.IsInMemory() {
+		dbEncryption, err := security.NewDatabaseEncryption(app.serverKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create journal encryption: %w", err)
+		}
+		if err := dbEncryption.SetCipher(cfg.GetDBCipher()); err != nil {
+			return nil, err
+		}
+
+		salesJournal, err := journal.Open(filepath.Join(filepath.Dir(db.Path()), salesJournalFile), dbEncryption)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sales journal: %w", err)
+		}
+		app.journal = salesJournal
+		app.journalKey = dbEncryption
+
+		ledger, err := sales.NewLedger(db, salesJournal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sales ledger: %w", err)
+		}
+		stats, err := ledger.Recover(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover sales journal: %w", err)
+		}
+		if stats.Replayed > 0 {
+			log.Printf("Recovered %d journaled sale(s)", stats.Replayed)
+		}
+		app.ledger = ledger
 	}
-	httpServer := server.New(serverCfg)
-	app.httpServer = httpServer
-	log.Printf("HTTP server configured on port %d", cfg.Port)
 
-	// Initialize service manager
+	// Initialize async job manager
+	jobManager, err := jobs.NewManager(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job manager: %w", err)
+	}
+	app.jobs = jobManager
+
+	// Initialize air-gapped bundle sync
+	bundles, err := sync.NewBundleSyncer(db, app.serverKey, cfg.GetStoreID(), machineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle syncer: %w", err)
+	}
+	app.bundles = bundles
+
+	// Directives from head office share one processor across delivery channels
+	app.directives = app.newDirectiveProcessor()
+
+	if cfg.MQTTEnabled() && !app.safeMode {
+		bridge, err := mqtt.New(&mqtt.Config{
+			BrokerURL:   cfg.MQTTBrokerURL,
+			Username:    cfg.MQTTUsername,
+			Password:    cfg.MQTTPassword,
+			TopicPrefix: cfg.MQTTTopicPrefix,
+			StoreID:     cfg.GetStoreID(),
+			MachineID:   machineID,
+			Processor:   app.directives,
+			Heartbeat:   app.heartbeat,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MQTT bridge: %w", err)
+		}
+		app.mqtt = bridge
+		log.Println("MQTT bridge configured")
+	}
+
+	// Previous day's reports for the local library, mailed when configured
+	var mailer *report.Mailer
+	if email := cfg.GetReportEmail(); email.SMTPAddr != "" {
+		mailer = report.NewMailer(email.SMTPAddr, email.Username, email.Password, email.From, email.To)
+	}
+	app.mailer = mailer
+	reports, err := report.NewScheduler(db, cfg.GetReports(), cfg.GetReportFormats(), cfg.GetReportTime(), mailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report scheduler: %w", err)
+	}
+	app.reports = reports
+
+	// Peripheral health for /status: networked ones are probed, the rest
+	// reported by the peripheral layer
+	app.peripherals = peripheral.New(constants.PeripheralStaleSeconds * time.Second)
+	for name, p := range cfg.GetPeripherals() {
+		app.peripherals.Add(name, p.Kind, p.Address)
+	}
+
+	// Sync outcomes feed /metrics and the health checks
+	app.syncStats = sync.NewStats()
+
+	// Service events reach the frontends and the configured webhooks
+	app.events = events.NewBus()
+	if webhooks := cfg.GetWebhooks(); len(webhooks) > 0 && !app.safeMode {
+		endpoints := make([]webhook.Endpoint, len(webhooks))
+		for i, hook := range webhooks {
+			endpoints[i] = webhook.Endpoint{URL: hook.URL, Events: hook.Events}
+		}
+		dispatcher, err := webhook.New(&webhook.Config{
+			Endpoints: endpoints,
+			Secret:    []byte(cfg.GetWebhookSecret()),
+		})
+		if err != nil {
+			log.Printf("Warning: webhooks disabled: %v", err)
+		} else {
+			app.webhooks = dispatcher
+			log.Printf("%d webhook(s) configured", len(endpoints))
+		}
+	}
+
+	// Initialize service manager; /service/* controls it
 	serviceMgr, err := service.NewManager(&service.Config{
 		Name:        constants.WindowsServiceName,
 		DisplayName: constants.WindowsServiceDisplayName,
 		Description: constants.WindowsServiceDescription,
 		OnStart:     app.OnServiceStart,
 		OnStop:      app.OnServiceStop,
+		Logger:      logger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create service manager: %w", err)
 	}
 	app.serviceManager = serviceMgr
 
+	// Initialize HTTP server
+	serverCfg := &server.Config{
+		Port:              cfg.Port,
+		BindAddress:       cfg.GetBindAddress(),
+		DB:                db,
+		Jobs:              jobManager,
+		Ledger:            app.ledger,
+		RotateKey:         app.rotateServerKey,
+		Profile:           cfg.GetLaneProfile(),
+		SCOMaxItems:       cfg.GetSCOMaxItems(),
+		AgeRestrictedSKUs: cfg.GetAgeRestrictedSKUs(),
+		SyncSchedule:      sync.NewSchedule(machineID, time.Duration(cfg.GetSyncInterval())*time.Second),
+		Printers:          receiptPrinters(cfg),
+		APISecret:         []byte(cfg.GetAPISecret()),
+		AllowedOrigins:    cfg.GetAllowedOrigins(),
+		GraphQL:           cfg.GraphQLEnabled(),
+		Pprof:             cfg.PprofEnabled(),
+		Locale:            cfg.GetLocale(),
+		BodyLimit:         cfg.GetMaxBodyBytes(),
+		ImportBodyLimit:   cfg.GetMaxImportBodyBytes(),
+
+		ClosingChecklist:      cfg.GetClosingChecklist(),
+		ClosingMaxPendingSync: cfg.GetClosingMaxPendingSync(),
+
+		Peripherals: app.peripherals,
+		Events:      app.events,
+		SafeMode:    app.safeMode,
+
+		SyncStats:    app.syncStats,
+		CheckConfig:  app.config.Check,
+		ConfigLoaded: app.config.Loaded,
+		UpdateConfig: app.config.Patch,
+		MaxOffline:   time.Duration(cfg.GetMaxOfflineHours()) * time.Hour,
+		Service:      serviceMgr,
+		ReadLogs:     app.readLogs,
+
+		SealDiagnostics: app.sealDiagnostics,
+	}
+	if app.metricsAddr = cfg.GetMetricsAddress(); app.metricsAddr != "" {
+		app.metrics = metrics.NewRegistry()
+		serverCfg.Metrics = app.metrics
+	}
+	app.grpcAddr = cfg.GetGRPCAddress()
+	if hubURL := cfg.GetHubAPIURL(); hubURL != "" && !app.safeMode {
+		serverCfg.Hub = hub.NewClient(hubURL, cfg.HubToken, nil)
+	}
+	httpServer := server.New(serverCfg)
+	app.httpServer = httpServer
+	app.config.OnReload("locale", func(c *config.Config) error {
+		httpServer.SetLocale(c.GetLocale())
+		return nil
+	})
+	log.Printf("HTTP server configured on %s", net.JoinHostPort(cfg.GetBindAddress(), strconv.Itoa(cfg.Port)))
+	if cfg.IsLANExposed() {
+		log.Printf("Warning: the local API is reachable from the LAN on %s: any machine there holding a token or API key can use it, and anyone can guess at them; set bind_address to %s on single-terminal installs", cfg.GetBindAddress(), constants.BindLoopback)
+	}
+	if set, err := auth.HasAdminPassword(db); err == nil && !set {
+		log.Printf("Warning: no admin password is set, so service control, configuration changes, backups, restores and key rotation are refused; run -set-admin-password")
+	}
+
+	// Store hub role: serve shared state and proxy sync for other terminals
+	if cfg.IsHub() && !app.safeMode {
+		// Upstream sync may use the experimental QUIC transport on lossy links
+		transportMetrics := sync.NewTransportMetrics()
+		resolver, err := backendResolver(cfg)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, err := app.syncTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		syncClient, err := sync.NewHTTPClient(&sync.ClientConfig{
+			Transport: cfg.GetSyncTransport(),
+			Timeout:   30 * time.Second,
+			TLSConfig: tlsConfig,
+			Pins:      cfg.GetBackendPins(),
+			Metrics:   transportMetrics,
+			Signer:    app.requestSigner(cfg),
+			Resolver:  resolver,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sync client: %w", err)
+		}
+		log.Printf("Sync transport: %s", cfg.GetSyncTransport())
+
+		storeHub, err := hub.New(&hub.Config{
+			DB:               db,
+			ServerURL:        cfg.GetServerURL(),
+			BlobDir:          cfg.HubBlobDir,
+			HTTPClient:       syncClient,
+			TransportMetrics: transportMetrics,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create store hub: %w", err)
+		}
+		storeHub.Register(httpServer.API().Group("/hub"))
+		app.hub = storeHub
+		log.Println("Store hub mode enabled")
+	}
+
 	return app, nil
 }
 
@@ -198,6 +674,19 @@ func NewApplication() (*Application, error) {
 func (app *Application) OnServiceStart(ctx context.Context) error {
 	log.Println("Service starting...")
 
+	// Count the boot; boots left open by a crash are closed as crashes
+	boot, err := app.db.RecordBoot(version, constants.BootHistoryDays*24*time.Hour)
+	if err != nil {
+		log.Printf("Warning: failed to record boot: %v", err)
+	} else {
+		app.bootID = boot.ID
+		log.Printf("Boot #%d", boot.ID)
+		go app.db.RunBootHeartbeat(ctx, boot.ID, constants.BootHeartbeatSeconds*time.Second)
+	}
+	if app.safeMode {
+		app.alertSafeMode()
+	}
+
 	// Start HTTP server in background
 	go func() {
 		if err := app.httpServer.StartWithContext(ctx); err != nil {
@@ -207,6 +696,68 @@ func (app *Application) OnServiceStart(ctx context.Context) error {
 
 	log.Println("HTTP server started")
 
+	// Prometheus metrics on their own port (optional)
+	if app.metrics != nil {
+		go func() {
+			if err := metrics.Serve(ctx, app.metricsAddr, app.metrics); err != nil {
+				log.Printf("Metrics server error: %v", err)
+			}
+		}()
+		log.Printf("Metrics served on http://%s/metrics", app.metricsAddr)
+	}
+
+	// gRPC interface on its own port (optional)
+	if app.grpcAddr != "" {
+		go func() {
+			if err := app.httpServer.ServeGRPC(ctx, app.grpcAddr); err != nil {
+				log.Printf("gRPC server error: %v", err)
+			}
+		}()
+		log.Printf("gRPC served on %s", app.grpcAddr)
+	}
+
+	// Remove expired TTL settings in background
+	go app.db.RunSettingsCleanup(ctx, time.Minute)
+
+	// Prune synced history so long-running terminals don't grow unbounded
+	retentionDays := constants.DefaultRetentionDays
+	maxOfflineHours := constants.DefaultMaxOfflineHours
+	if cfg, err := app.config.Get(); err == nil {
+		retentionDays = cfg.GetRetentionDays()
+		maxOfflineHours = cfg.GetMaxOfflineHours()
+	}
+	go app.db.RunRetentionPruner(ctx, time.Hour, time.Duration(retentionDays)*24*time.Hour)
+
+	// Refuse writes once the terminal has gone too long without a sync
+	go sync.RunOfflineWatch(ctx, app.db, time.Minute, time.Duration(maxOfflineHours)*time.Hour, app.httpServer.SetOfflineMode)
+
+	// Quarantine rows orphaned by crashes; counts are reported in /health
+	go app.db.RunOrphanRepair(ctx, time.Hour)
+
+	// Anchor the audit log's head so the next sync carries it to head office
+	go app.db.RunAuditAnchor(ctx, time.Hour)
+
+	// Render the previous day's reports each morning
+	if !app.safeMode {
+		go app.reports.Run(ctx, time.Minute)
+	}
+
+	// Probe networked peripherals (printers, EFT terminals) for /status
+	go app.peripherals.Run(ctx, constants.PeripheralProbeSeconds*time.Second)
+
+	// Event delivery over MQTT (optional)
+	if app.mqtt != nil {
+		if err := app.mqtt.Start(ctx); err != nil {
+			log.Printf("Warning: MQTT bridge failed to start: %v", err)
+		}
+	}
+
+	// Webhooks, and the promotion starts they announce
+	if app.webhooks != nil {
+		go app.webhooks.Run(ctx, app.events)
+	}
+	go app.httpServer.RunPromotionWatch(ctx, time.Minute)
+
 	// TODO: Start sync scheduler
 	// TODO: Initialize other background tasks
 
@@ -229,15 +780,242 @@ func (app *Application) OnServiceStop() error {
 		}
 	}
 
+	if app.mqtt != nil {
+		log.Println("Disconnecting MQTT bridge...")
+		app.mqtt.Close()
+	}
+
+	// Stop running jobs before the database goes away
+	if app.jobs != nil {
+		log.Println("Stopping background jobs...")
+		app.jobs.Shutdown()
+	}
+
+	if app.journal != nil {
+		if err := app.journal.Close(); err != nil {
+			log.Printf("Sales journal close error: %v", err)
+		}
+		app.journalKey.Close()
+	}
+
 	// Close database
 	if app.db != nil {
+		if app.bootID != 0 {
+			if err := app.db.RecordShutdown(app.bootID, database.ShutdownStopped); err != nil {
+				log.Printf("Failed to record shutdown: %v", err)
+			}
+		}
 		log.Println("Closing database...")
 		if err := app.db.Close(); err != nil {
 			log.Printf("Database close error: %v", err)
 		}
 	}
 
+	// Nothing needs the server key anymore
+	if app.bundles != nil {
+		app.bundles.Close()
+	}
+	security.Wipe(app.serverKey)
+
 	log.Println("Service stopped")
+	app.logger.Sync()
+	return nil
+}
+
+// alertSafeMode records safe mode in the audit log and, when mailing is
+// configured, mails the report recipients in the background
+func (app *Application) alertSafeMode() {
+	detail := fmt.Sprintf("version %s started in safe mode after repeated crashes", version)
+	if err := app.db.RecordAudit(&database.AuditEntry{Event: database.AuditSafeMode, Detail: detail}); err != nil {
+		log.Printf("Warning: failed to record safe mode in audit log: %v", err)
+	}
+
+	if app.mailer == nil {
+		return
+	}
+	subject := fmt.Sprintf("POS terminal %s started in safe mode", app.machineID)
+	body := "The POS service crashed repeatedly and started in safe mode: peer sync, " +
+		"MQTT and scheduled reports are disabled. See GET /admin/uptime on the terminal.\r\n"
+	go func() {
+		if err := app.mailer.Send(subject, body, nil); err != nil {
+			log.Printf("Warning: failed to mail safe mode alert: %v", err)
+		}
+	}()
+}
+
+// openSecretsVault opens the secrets vault, reading it with every config
+// key of mgr and writing with the current one
+func openSecretsVault(backend, dir string, mgr *config.Manager) (security.Vault, error) {
+	encryptions := mgr.Encryptions()
+	ciphers := make([]security.VaultCipher, 0, len(encryptions))
+	for _, enc := range encryptions {
+		ciphers = append(ciphers, enc)
+	}
+	return security.NewVault(backend, dir, ciphers...)
+}
+
+// requestSigner returns the signer for backend requests, keyed by the store
+// token; nil (unsigned) until the store is enrolled
+func (app *Application) requestSigner(cfg *config.Config) *security.RequestSigner {
+	signer, err := security.NewRequestSigner(cfg.GetStoreID(), []byte(cfg.GetStoreToken()))
+	if err != nil {
+		return nil
+	}
+	return signer
+}
+
+// syncTLSConfig presents the client certificate issued during provisioning,
+// so the sync API accepts this terminal; nil (plain TLS) if none was issued
+func (app *Application) syncTLSConfig() (*tls.Config, error) {
+	if app.provisioner == nil {
+		return nil, nil
+	}
+
+	cert, err := app.provisioner.ClientCertificate()
+	if errors.Is(err, security.ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	log.Println("Sync client certificate loaded (mTLS)")
+	return &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// receiptPrinters converts the configured printers for the receipt package
+func receiptPrinters(cfg *config.Config) map[string]receipt.Printer {
+	printers := make(map[string]receipt.Printer)
+	for name, printer := range cfg.GetPrinters() {
+		printers[name] = receipt.Printer{
+			Width:    printer.Width,
+			Encoding: printer.Encoding,
+			CodePage: printer.CodePage,
+		}
+	}
+	return printers
+}
+
+// backendResolver creates the caching resolver for sync traffic, with the
+// configured static addresses for the backend host
+func backendResolver(cfg *config.Config) (*sync.Resolver, error) {
+	fallback := make(map[string][]string)
+	if ips := cfg.GetBackendFallbackIPs(); len(ips) > 0 {
+		serverURL, err := url.Parse(cfg.GetServerURL())
+		if err != nil || serverURL.Hostname() == "" {
+			return nil, fmt.Errorf("backend_fallback_ips needs a valid server_url")
+		}
+		fallback[serverURL.Hostname()] = ips
+	}
+
+	resolver, err := sync.NewResolver(sync.DefaultDNSCacheTTL, fallback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resolver: %w", err)
+	}
+	return resolver, nil
+}
+
+// newDirectiveProcessor registers the directives this terminal understands
+func (app *Application) newDirectiveProcessor() *directives.Processor {
+	processor := directives.NewProcessor()
+
+	processor.Register("ping", func(ctx context.Context, d *directives.Directive) error {
+		return nil
+	})
+
+	processor.Register("recommendation_rules", func(ctx context.Context, d *directives.Directive) error {
+		var rules []database.RecommendationRule
+		if err := json.Unmarshal(d.Payload, &rules); err != nil {
+			return fmt.Errorf("invalid recommendation rules: %w", err)
+		}
+		return app.db.ReplaceRecommendationRules(rules)
+	})
+
+	processor.Register("prune_history", func(ctx context.Context, d *directives.Directive) error {
+		retentionDays := constants.DefaultRetentionDays
+		if cfg, err := app.config.Get(); err == nil {
+			retentionDays = cfg.GetRetentionDays()
+		}
+		_, err := app.db.PruneSyncedHistory(time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour))
+		return err
+	})
+
+	return processor
+}
+
+// heartbeat builds the periodic heartbeat payload
+func (app *Application) heartbeat() interface{} {
+	pending, _ := app.db.CountPendingOutbox()
+	pendingByClass, _ := app.db.CountPendingOutboxByClass()
+	return map[string]interface{}{
+		"version":                 version,
+		"machine_id":              app.machineID,
+		"read_only":               app.db.IsReadOnly(),
+		"pending_outbox":          pending,
+		"pending_outbox_by_class": pendingByClass,
+		"timestamp":               time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Wipe securely deletes the database, configuration and machine ID.
+// confirm must equal the machine ID so the command can't run by accident,
+// and the service must be stopped so nothing holds the files open.
+func (app *Application) Wipe(confirm string) error {
+	if confirm != app.machineID {
+		return fmt.Errorf("refusing to wipe: pass -confirm %s to proceed", app.machineID)
+	}
+
+	if _, running, err := app.serviceManager.GetStatus(); err == nil && running {
+		return fmt.Errorf("refusing to wipe while the service is running; stop it first")
+	}
+
+	if err := app.db.Wipe(); err != nil {
+		return fmt.Errorf("failed to wipe database: %w", err)
+	}
+	if app.journal != nil {
+		app.journal.Close()
+		if err := security.SecureDelete(app.journal.Path()); err != nil {
+			return fmt.Errorf("failed to wipe sales journal: %w", err)
+		}
+	}
+	log.Println("Database wiped")
+
+	if err := security.SecureDelete(app.paths.ConfigFile()); err != nil {
+		return fmt.Errorf("failed to wipe configuration: %w", err)
+	}
+	log.Println("Configuration wiped")
+
+	if err := security.DeleteMachineID(); err != nil {
+		return err
+	}
+	log.Println("Machine ID removed")
+
+	if err := app.keys.Delete(security.ServerKeyName); err != nil {
+		return fmt.Errorf("failed to wipe sealed server key: %w", err)
+	}
+	log.Println("Sealed server key removed")
+
+	if err := app.keys.Delete(security.ClientCertName); err != nil && !errors.Is(err, security.ErrSecretNotFound) {
+		return fmt.Errorf("failed to wipe sealed client certificate: %w", err)
+	}
+
+	for _, name := range config.SecretNames() {
+		if err := app.vault.Delete(name); err != nil && !errors.Is(err, security.ErrVaultReadOnly) {
+			return fmt.Errorf("failed to wipe vault secret %s: %w", name, err)
+		}
+	}
+	if err := security.SecureDelete(filepath.Join(app.paths.DataDir, keyStoreDir, security.VaultFileName)); err != nil {
+		return fmt.Errorf("failed to wipe secrets vault: %w", err)
+	}
+	log.Println("Secrets vault wiped")
+
+	if err := security.DeleteConfigKey(); err != nil {
+		return err
+	}
+	log.Println("Config key removed")
+
 	return nil
 }
 
@@ -284,3 +1062,23 @@ func (app *Application) RunDebug() error {
 	fmt.Println("Shutdown complete")
 	return nil
 }
+
+// setAdminPassword reads a new admin password twice from stdin and stores
+// its hash, recording the change in the audit log
+func setAdminPassword(db *database.DB) error {
+	reader := bufio.NewReader(os.Stdin)
+	read := func(prompt string) string {
+		fmt.Print(prompt)
+		line, _ := reader.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	password := read("New admin password: ")
+	if read("Repeat admin password: ") != password {
+		return errors.New("passwords do not match")
+	}
+	if err := auth.SetAdminPassword(db, password); err != nil {
+		return err
+	}
+	return db.RecordAudit(&database.AuditEntry{Event: database.AuditAdminPassword, Detail: "admin password set"})
+}
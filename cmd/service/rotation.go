@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/professor93/promo-pos/internal/jobs"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// resumeKeyRotation settles a server key rotation cut short by a crash.
+// The staged key wins if the database was already re-encrypted under it;
+// otherwise the re-encryption rolled back and the staged key is dropped.
+func (app *Application) resumeKeyRotation() error {
+	if app.provisioner == nil {
+		return nil
+	}
+
+	staged, err := app.provisioner.PendingRotation()
+	if errors.Is(err, security.ErrSecretNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read staged server key: %w", err)
+	}
+
+	rotated, err := app.db.KeyMatches(staged)
+	if err != nil {
+		return fmt.Errorf("failed to check staged server key: %w", err)
+	}
+	if !rotated {
+		log.Println("Discarding server key staged by an interrupted rotation")
+		return app.provisioner.AbandonRotation()
+	}
+
+	if err := app.db.UseServerKey(staged); err != nil {
+		return err
+	}
+	if err := app.provisioner.FinishRotation(staged); err != nil {
+		return err
+	}
+	security.Wipe(app.serverKey)
+	app.serverKey = staged
+	log.Println("Finished interrupted server key rotation")
+	return nil
+}
+
+// rotateServerKey unwraps a rotated server key delivered by the backend
+// and returns the job that re-encrypts the database, sales journal and
+// bundle keys with it (POST /security/rotate-key)
+func (app *Application) rotateServerKey(wrappedKey string) (jobs.RunFunc, error) {
+	if app.provisioner == nil {
+		return nil, fmt.Errorf("demo databases do not rotate keys")
+	}
+
+	newKey, err := security.RotateServerKey(app.serverKey, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, p *jobs.Progress) (string, error) {
+		committed := false
+		err := app.provisioner.Rotate(newKey, func() error {
+			err := app.db.Rekey(ctx, newKey, func(done, total int) {
+				p.Update(done*100/total, fmt.Sprintf("Re-encrypted %d of %d records", done, total))
+			})
+			committed = err == nil
+			return err
+		})
+		if !committed {
+			security.Wipe(newKey)
+			return "", err
+		}
+
+		// The database is under the new key now; everything else follows
+		// even if persisting it failed (the staged key is kept for restart)
+		security.Wipe(app.serverKey)
+		app.serverKey = newKey
+		if app.ledger != nil {
+			if rekeyErr := app.ledger.Rekey(newKey); rekeyErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to rekey sales journal: %w", rekeyErr))
+			}
+		}
+		if rekeyErr := app.bundles.Rekey(newKey); rekeyErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to rekey bundle syncer: %w", rekeyErr))
+		}
+		if err != nil {
+			return "", err
+		}
+
+		log.Println("Server key rotated")
+		return "", nil
+	}, nil
+}
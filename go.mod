@@ -1,6 +1,6 @@
 module github.com/professor93/promo-pos
 
-go 1.25
+go 1.25.0
 
 require (
 	// System Tray (requires CGO)
@@ -8,6 +8,9 @@ require (
 
 	// Encryption utilities
 	github.com/ProtonMail/gopenpgp/v2 v2.7.5
+
+	// MQTT bridge
+	github.com/eclipse/paho.mqtt.golang v1.5.0
 	github.com/go-chi/chi/v5 v5.1.0
 
 	// Validation
@@ -21,6 +24,9 @@ require (
 	// JWT Token handling
 	github.com/golang-jwt/jwt/v5 v5.2.2
 
+	// TPM-sealed key storage
+	github.com/google/go-tpm v0.9.1
+
 	// Utilities
 	github.com/google/uuid v1.6.0
 
@@ -38,6 +44,9 @@ require (
 
 	// Database migrations
 	github.com/pressly/goose/v3 v3.22.1
+
+	// Experimental HTTP/3 sync transport
+	github.com/quic-go/quic-go v0.48.2
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/cobra v1.8.1
 
@@ -61,25 +70,51 @@ require (
 	github.com/spf13/pflag v1.0.5 // indirect
 	// Indirect dependencies will be added automatically by go mod tidy
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/text v0.29.0 // indirect
+	golang.org/x/text v0.29.0
+)
+
+require (
+	github.com/fasthttp/websocket v1.5.8
+	github.com/gofiber/fiber/v2 v2.52.15
+	github.com/valyala/fasthttp v1.65.0
+)
+
+require (
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/savsgio/gotils v0.0.0-20240303185622-093b76447511 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
 )
 
 require (
 	github.com/andybalholm/brotli v1.2.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.10 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/gofiber/contrib/websocket v1.3.4
 	github.com/gofiber/schema v1.6.0 // indirect
 	github.com/gofiber/utils/v2 v2.0.0-rc.1 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/graph-gophers/graphql-go v1.9.0
 	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/philhofer/fwd v1.2.0 // indirect
+	github.com/quic-go/qpack v0.5.1 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/tinylib/msgp v1.4.0 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	github.com/valyala/fasthttp v1.65.0 // indirect
+	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
 	golang.org/x/net v0.44.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	google.golang.org/grpc v1.75.1
+	google.golang.org/protobuf v1.36.10
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
 )
 
 // Optional: Add replace directives for local development
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Read endpoints that frontends poll send an ETag derived from the version
+// of the data behind them (not from the response, which carries a fresh
+// request ID every time). A request whose If-None-Match lists the current
+// ETag is answered 304 Not Modified without a body.
+
+// ETag returns a strong entity tag over the given version parts
+func ETag(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header value lists etag.
+// Comparison is weak (RFC 9110 §13.1.2): a W/ prefix is ignored.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package api
+
+import "testing"
+
+func TestETag(t *testing.T) {
+	tag := ETag("product", `{"id":"P1"}`)
+	if tag != ETag("product", `{"id":"P1"}`) || tag == ETag("product", `{"id":"P2"}`) {
+		t.Errorf("Expected ETags to follow the version parts")
+	}
+	if ETag("ab", "c") == ETag("a", "bc") {
+		t.Errorf("Expected part boundaries to matter")
+	}
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{tag, true},
+		{"W/" + tag, true},
+		{`"other", ` + tag, true},
+		{"*", true},
+		{`"other"`, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ETagMatches(tt.header, tag); got != tt.want {
+			t.Errorf("ETagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
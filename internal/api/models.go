@@ -3,11 +3,11 @@ package api
 // APIResponse is the standardized response structure for ALL HTTP endpoints
 // This structure MUST be used by every endpoint in the application
 type APIResponse struct {
-	OK      bool        `json:"ok"`                // true if successful, false if error
-	Code    int         `json:"code"`              // Response code: positive (success), negative (error)
-	Message string      `json:"message"`           // Human-readable message
-	Result  interface{} `json:"result,omitempty"`  // Response data (optional)
-	Meta    interface{} `json:"meta,omitempty"`    // Metadata (pagination, etc.)
+	OK      bool        `json:"ok"`               // true if successful, false if error
+	Code    int         `json:"code"`             // Response code: positive (success), negative (error)
+	Message string      `json:"message"`          // Human-readable message
+	Result  interface{} `json:"result,omitempty"` // Response data (optional)
+	Meta    interface{} `json:"meta,omitempty"`   // Metadata (pagination, etc.)
 }
 
 // NewSuccessResponse creates a successful API response
@@ -52,66 +52,147 @@ func NewErrorResponseWithMeta(code int, message string, meta interface{}) *APIRe
 	}
 }
 
+// ErrorHint is the machine-actionable remediation metadata attached to the
+// Meta field of error responses, so frontends can apply uniform retry/UX logic
+type ErrorHint struct {
+	Retryable          bool         `json:"retryable"`                     // Whether repeating the same request may succeed
+	RetryAfter         int          `json:"retry_after,omitempty"`         // Seconds to wait before retrying
+	RequiredPermission string       `json:"required_permission,omitempty"` // Permission the caller is missing
+	DocCode            string       `json:"doc_code,omitempty"`            // Stable code for documentation lookup
+	Fields             []FieldError `json:"fields,omitempty"`              // Invalid request fields, for validation errors
+}
+
+// FieldError describes one request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`           // JSON path of the field, e.g. "lines[0].sku"
+	Rule    string `json:"rule"`            // Failed rule, e.g. "required", "min"
+	Param   string `json:"param,omitempty"` // Rule parameter, e.g. "1" for min=1
+	Message string `json:"message"`         // Human-readable explanation
+}
+
 // Response codes (application-specific)
 // Positive codes = Success operations
 // Negative codes = Error operations
 const (
 	// Success codes (1-999)
-	CodeSuccess           = 1   // Generic success
-	CodeDataRetrieved     = 10  // Data retrieved successfully
-	CodeDataCreated       = 11  // Data created successfully
-	CodeDataUpdated       = 12  // Data updated successfully
-	CodeDataDeleted       = 13  // Data deleted successfully
-	CodeSyncSuccess       = 20  // Sync operation successful
-	CodeServiceStarted    = 30  // Service started successfully
-	CodeServiceStopped    = 31  // Service stopped successfully
-	CodeServiceRestarted  = 32  // Service restarted successfully
-	CodeConfigUpdated     = 40  // Configuration updated
+	CodeSuccess          = 1  // Generic success
+	CodeDataRetrieved    = 10 // Data retrieved successfully
+	CodeDataCreated      = 11 // Data created successfully
+	CodeDataUpdated      = 12 // Data updated successfully
+	CodeDataDeleted      = 13 // Data deleted successfully
+	CodeSyncSuccess      = 20 // Sync operation successful
+	CodeServiceStarted   = 30 // Service started successfully
+	CodeServiceStopped   = 31 // Service stopped successfully
+	CodeServiceRestarted = 32 // Service restarted successfully
+	CodeConfigUpdated    = 40 // Configuration updated
+	CodeJobAccepted      = 50 // Async job accepted
 
 	// Error codes (-1 to -999)
-	CodeErrorGeneric      = -1   // Generic error
-	CodeErrorBadRequest   = -10  // Invalid request parameters
-	CodeErrorUnauthorized = -11  // Unauthorized access
-	CodeErrorForbidden    = -12  // Forbidden operation
-	CodeErrorNotFound     = -13  // Resource not found
-	CodeErrorDatabase     = -20  // Database error
-	CodeErrorEncryption   = -21  // Encryption/Decryption error
-	CodeErrorConfig       = -22  // Configuration error
-	CodeErrorSync         = -30  // Synchronization error
-	CodeErrorOffline      = -31  // Service offline too long
-	CodeErrorService      = -40  // Windows service error
-	CodeErrorInternal     = -99  // Internal server error
+	CodeErrorGeneric      = -1  // Generic error
+	CodeErrorBadRequest   = -10 // Invalid request parameters
+	CodeErrorUnauthorized = -11 // Unauthorized access
+	CodeErrorForbidden    = -12 // Forbidden operation
+	CodeErrorNotFound     = -13 // Resource not found
+	CodeErrorConflict     = -14 // Concurrent modification conflict
+	CodeErrorDatabase     = -20 // Database error
+	CodeErrorEncryption   = -21 // Encryption/Decryption error
+	CodeErrorConfig       = -22 // Configuration error
+	CodeErrorSync         = -30 // Synchronization error
+	CodeErrorOffline      = -31 // Service offline too long
+	CodeErrorService      = -40 // Windows service error
+	CodeErrorInternal     = -99 // Internal server error
 )
 
 // Common response messages
 const (
-	MessageSuccess              = "Success"
-	MessageCreated              = "Resource created successfully"
-	MessageUpdated              = "Resource updated successfully"
-	MessageDeleted              = "Resource deleted successfully"
-	MessageBadRequest           = "Invalid request parameters"
-	MessageUnauthorized         = "Unauthorized"
-	MessageForbidden            = "Forbidden"
-	MessageNotFound             = "Resource not found"
-	MessageInternalError        = "Internal server error"
-	MessageServiceUnavailable   = "Service unavailable"
-	MessageOfflineTooLong       = "Service offline for more than 24 hours"
+	MessageSuccess            = "Success"
+	MessageCreated            = "Resource created successfully"
+	MessageUpdated            = "Resource updated successfully"
+	MessageDeleted            = "Resource deleted successfully"
+	MessageBadRequest         = "Invalid request parameters"
+	MessageValidationFailed   = "Request validation failed"
+	MessageUnauthorized       = "Unauthorized"
+	MessageForbidden          = "Forbidden"
+	MessageNotFound           = "Resource not found"
+	MessageConflict           = "Resource was modified by another request"
+	MessageInternalError      = "Internal server error"
+	MessageServiceUnavailable = "Service unavailable"
+	MessageOfflineTooLong     = "Service offline for more than 24 hours"
 )
 
 // ServiceStatus represents the current service status
 type ServiceStatus struct {
-	Status          string `json:"status"`            // "running", "stopped", "offline"
-	LastSyncTime    string `json:"last_sync_time"`    // ISO 8601 timestamp
-	OfflineHours    int    `json:"offline_hours"`     // Hours since last successful sync
-	IsHealthy       bool   `json:"is_healthy"`        // Overall health status
-	WindowsService  string `json:"windows_service"`   // "running", "stopped"
+	Status         string `json:"status"`                   // "running", "stopped", "offline"
+	LastSyncTime   string `json:"last_sync_time"`           // ISO 8601 timestamp, empty before the first sync
+	OfflineHours   int    `json:"offline_hours"`            // Hours since last successful sync
+	IsHealthy      bool   `json:"is_healthy"`               // Overall health status
+	WindowsService string `json:"windows_service"`          // "running", "stopped"
+	SyncOffsetMs   int64  `json:"sync_offset_ms"`           // This terminal's slot within the sync interval
+	NextSyncTime   string `json:"next_sync_time,omitempty"` // ISO 8601 timestamp of the next scheduled sync
+
+	// Peripherals are soft dependencies: they never make the service
+	// unhealthy, but the frontend warns before a sale that can't print
+	Peripherals []PeripheralStatus `json:"peripherals,omitempty"`
+}
+
+// PeripheralStatus is the last known state of one external peripheral
+type PeripheralStatus struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`  // "printer", "eft", "scale" or "display"
+	State     string `json:"state"` // "online", "offline" or "unknown"
+	Detail    string `json:"detail,omitempty"`
+	CheckedAt string `json:"checked_at,omitempty"` // ISO 8601 timestamp
 }
 
 // HealthCheck represents the health check response
 type HealthCheck struct {
-	Healthy       bool   `json:"healthy"`
-	Version       string `json:"version"`
-	Timestamp     string `json:"timestamp"`
-	DatabaseOK    bool   `json:"database_ok"`
-	ConfigOK      bool   `json:"config_ok"`
+	Healthy    bool   `json:"healthy"`
+	Version    string `json:"version"`
+	Timestamp  string `json:"timestamp"`
+	DatabaseOK bool   `json:"database_ok"`
+	ConfigOK   bool   `json:"config_ok"`
+
+	// Integrity is the result of the last orphan repair pass, if any ran
+	Integrity *IntegrityStatus `json:"integrity,omitempty"`
+
+	// SafeMode is set after a crash loop: only the core API and diagnostics run
+	SafeMode bool `json:"safe_mode,omitempty"`
+
+	// Checks are the individual probes; any failed probe makes the service
+	// unhealthy, a degraded one only warns
+	Checks HealthChecks `json:"checks"`
+}
+
+// Health probe statuses
+const (
+	ProbeOK       = "ok"
+	ProbeDegraded = "degraded"
+	ProbeFailed   = "failed"
+)
+
+// HealthChecks holds one result per /health probe
+type HealthChecks struct {
+	Database       HealthProbe `json:"database"`        // Ping and a periodic quick integrity check
+	Config         HealthProbe `json:"config"`          // Config file readable and decryptable
+	LastSync       HealthProbe `json:"last_sync"`       // Age of the last successful sync
+	DiskSpace      HealthProbe `json:"disk_space"`      // Free space on the data volume
+	BackendContact HealthProbe `json:"backend_contact"` // Time since the backend last answered
+}
+
+// HealthProbe is the result of one health probe
+type HealthProbe struct {
+	Status     string `json:"status"` // "ok", "degraded" or "failed"
+	Message    string `json:"message,omitempty"`
+	At         string `json:"at,omitempty"`          // ISO 8601 time of the event measured (last sync, last contact, last integrity check)
+	AgeSeconds *int64 `json:"age_seconds,omitempty"` // Seconds since At, or since startup when it never happened
+	FreeBytes  *int64 `json:"free_bytes,omitempty"`  // disk_space only
+}
+
+// IntegrityStatus counts the referential integrity problems last found
+type IntegrityStatus struct {
+	CheckedAt           string `json:"checked_at"` // ISO 8601 timestamp
+	OrphanedBasketLines int64  `json:"orphaned_basket_lines"`
+	BasketMismatches    int64  `json:"basket_mismatches"`
+	InvalidSales        int64  `json:"invalid_sales"`
+	Quarantined         int64  `json:"quarantined"` // Rows held in quarantine in total
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// List endpoints answer one page of items in Result and a Pagination in
+// Meta. Clients either count pages (?page=&per_page=) or follow
+// next_cursor (?cursor=) until it is empty.
+
+// Page size bounds of list endpoints
+const (
+	DefaultPerPage = 50
+	MaxPerPage     = 1000
+)
+
+// cursorPrefix tags cursors so a page number is not mistaken for one
+const cursorPrefix = "page:"
+
+// ErrInvalidCursor is returned for a cursor not issued by NewPagination
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Pagination is the paging metadata of list responses, sent in Meta
+type Pagination struct {
+	Page       int    `json:"page"`                  // 1-based page number
+	PerPage    int    `json:"per_page"`              // Items per page
+	Total      int    `json:"total"`                 // Items across all pages
+	NextCursor string `json:"next_cursor,omitempty"` // Cursor of the next page; empty on the last one
+}
+
+// PageRequest is the page a client asked for
+type PageRequest struct {
+	Page    int // 1-based
+	PerPage int
+}
+
+// Offset returns the number of items before the requested page
+func (r PageRequest) Offset() int {
+	return (r.Page - 1) * r.PerPage
+}
+
+// NewPagination describes page r of total items
+func NewPagination(r PageRequest, total int) Pagination {
+	p := Pagination{Page: r.Page, PerPage: r.PerPage, Total: total}
+	if r.Offset()+r.PerPage < total {
+		p.NextCursor = EncodeCursor(r.Page + 1)
+	}
+	return p
+}
+
+// Paginate returns page r of items along with its pagination metadata
+func Paginate[T any](items []T, r PageRequest) ([]T, Pagination) {
+	start := min(r.Offset(), len(items))
+	end := min(start+r.PerPage, len(items))
+	return items[start:end], NewPagination(r, len(items))
+}
+
+// EncodeCursor returns the opaque cursor of a page
+func EncodeCursor(page int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(page)))
+}
+
+// DecodeCursor returns the page a cursor points to
+func DecodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	page, err := strconv.Atoi(strings.TrimPrefix(string(data), cursorPrefix))
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) || page < 1 {
+		return 0, ErrInvalidCursor
+	}
+	return page, nil
+}
+
+// NewListResponse creates a successful response carrying one page of items
+func NewListResponse(message string, items interface{}, page Pagination) *APIResponse {
+	return NewSuccessResponseWithMeta(CodeDataRetrieved, message, items, page)
+}
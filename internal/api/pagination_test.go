@@ -0,0 +1,50 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name     string
+		page     PageRequest
+		want     []int
+		lastPage bool
+	}{
+		{"first page", PageRequest{Page: 1, PerPage: 2}, []int{1, 2}, false},
+		{"last partial page", PageRequest{Page: 3, PerPage: 2}, []int{5}, true},
+		{"exact fit", PageRequest{Page: 1, PerPage: 5}, []int{1, 2, 3, 4, 5}, true},
+		{"past the end", PageRequest{Page: 4, PerPage: 2}, []int{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, meta := Paginate(items, tt.page)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if meta.Total != len(items) || meta.Page != tt.page.Page || meta.PerPage != tt.page.PerPage {
+				t.Errorf("Unexpected pagination: %+v", meta)
+			}
+			if (meta.NextCursor == "") != tt.lastPage {
+				t.Errorf("Expected next cursor only before the last page, got %q", meta.NextCursor)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	page, err := DecodeCursor(EncodeCursor(7))
+	if err != nil || page != 7 {
+		t.Fatalf("Expected page 7, got %d (%v)", page, err)
+	}
+
+	for _, cursor := range []string{"7", "not base64!", EncodeCursor(0), "cGFnZTp4"} {
+		if _, err := DecodeCursor(cursor); err != ErrInvalidCursor {
+			t.Errorf("Expected %q to be rejected, got %v", cursor, err)
+		}
+	}
+}
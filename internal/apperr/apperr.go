@@ -0,0 +1,257 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// Documentation codes emitted in error hints
+const (
+	DocBadRequest   = "ERR_BAD_REQUEST"
+	DocUnauthorized = "ERR_UNAUTHORIZED"
+	DocForbidden    = "ERR_FORBIDDEN"
+	DocNotFound     = "ERR_NOT_FOUND"
+	DocTooLarge     = "ERR_TOO_LARGE"
+	DocConflict     = "ERR_CONFLICT"
+	DocDatabase     = "ERR_DATABASE"
+	DocEncryption   = "ERR_ENCRYPTION"
+	DocConfig       = "ERR_CONFIG"
+	DocSync         = "ERR_SYNC"
+	DocOffline      = "ERR_OFFLINE_TOO_LONG"
+	DocUnavailable  = "ERR_UNAVAILABLE"
+	DocService      = "ERR_SERVICE"
+	DocInternal     = "ERR_INTERNAL"
+)
+
+// Error is an application error carrying everything needed to render a
+// standardized APIResponse: HTTP status, application code, message and hint
+type Error struct {
+	Status  int    // HTTP status code
+	Code    int    // Application code (negative)
+	Message string // Human-readable message
+	Hint    api.ErrorHint
+	Err     error // Underlying cause (not exposed to clients)
+}
+
+// New creates an application error
+func New(status, code int, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+		Hint:    api.ErrorHint{DocCode: docCodeFor(code)},
+	}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap attaches an underlying cause
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// Retryable marks the error as retryable, optionally after a delay
+func (e *Error) Retryable(after time.Duration) *Error {
+	e.Hint.Retryable = true
+	e.Hint.RetryAfter = int(after.Round(time.Second) / time.Second)
+	return e
+}
+
+// RequiresPermission records the permission the caller is missing
+func (e *Error) RequiresPermission(permission string) *Error {
+	e.Hint.RequiredPermission = permission
+	return e
+}
+
+// WithDocCode overrides the documentation code
+func (e *Error) WithDocCode(docCode string) *Error {
+	e.Hint.DocCode = docCode
+	return e
+}
+
+// Response renders the error as a standardized API response
+func (e *Error) Response() *api.APIResponse {
+	return api.NewErrorResponseWithMeta(e.Code, e.Message, e.Hint)
+}
+
+// Handler is a Fiber error handler rendering any error as a standardized
+// API response with remediation hints in Meta
+func Handler(c *fiber.Ctx, err error) error {
+	appErr := From(err)
+
+	if appErr.Hint.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(appErr.Hint.RetryAfter))
+	}
+
+	return c.Status(appErr.Status).JSON(appErr.Response())
+}
+
+// --- Constructors for common errors ---
+
+// BadRequest creates a 400 error
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, api.CodeErrorBadRequest, message)
+}
+
+// Invalid creates a 400 validation error listing the invalid fields
+func Invalid(fields []api.FieldError) *Error {
+	e := BadRequest(api.MessageValidationFailed)
+	e.Hint.Fields = fields
+	return e
+}
+
+// Unauthorized creates a 401 error
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, api.CodeErrorUnauthorized, message)
+}
+
+// Forbidden creates a 403 error naming the missing permission
+func Forbidden(permission string) *Error {
+	return New(http.StatusForbidden, api.CodeErrorForbidden, api.MessageForbidden).RequiresPermission(permission)
+}
+
+// NotFound creates a 404 error
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, api.CodeErrorNotFound, message)
+}
+
+// TooLarge creates a 413 error for a request body over limit bytes
+func TooLarge(limit int) *Error {
+	return New(http.StatusRequestEntityTooLarge, api.CodeErrorBadRequest, fmt.Sprintf("Request body exceeds the %d byte limit", limit)).WithDocCode(DocTooLarge)
+}
+
+// Conflict creates a 409 error for a lost optimistic-locking race
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, api.CodeErrorConflict, message)
+}
+
+// Database creates a 500 database error; busy/locked conditions are retryable
+func Database(err error) *Error {
+	if errors.Is(err, database.ErrReadOnly) {
+		return Offline().Wrap(err)
+	}
+	if errors.Is(err, database.ErrVersionConflict) {
+		return Conflict(api.MessageConflict).Wrap(err)
+	}
+
+	e := New(http.StatusInternalServerError, api.CodeErrorDatabase, "Database error").Wrap(err)
+	if database.IsBusy(err) {
+		e.Retryable(time.Second)
+	}
+	return e
+}
+
+// Offline creates the error returned once the offline grace period expired
+func Offline() *Error {
+	return New(http.StatusServiceUnavailable, api.CodeErrorOffline, api.MessageOfflineTooLong).Retryable(time.Minute)
+}
+
+// Unavailable creates a retryable 503 error
+func Unavailable(message string, retryAfter time.Duration) *Error {
+	return New(http.StatusServiceUnavailable, api.CodeErrorGeneric, message).WithDocCode(DocUnavailable).Retryable(retryAfter)
+}
+
+// TooManyRequests creates a retryable 429 error for a locked-out caller
+func TooManyRequests(message string, retryAfter time.Duration) *Error {
+	return New(http.StatusTooManyRequests, api.CodeErrorUnauthorized, message).Retryable(retryAfter)
+}
+
+// Internal creates a 500 error hiding the cause from clients
+func Internal(err error) *Error {
+	return New(http.StatusInternalServerError, api.CodeErrorInternal, api.MessageInternalError).Wrap(err)
+}
+
+// From converts any error into an application error
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if errors.Is(err, database.ErrReadOnly) {
+		return Offline().Wrap(err)
+	}
+	if errors.Is(err, database.ErrVersionConflict) {
+		return Conflict(api.MessageConflict).Wrap(err)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fromStatus(fiberErr.Code, fiberErr.Message)
+	}
+
+	return fromStatus(http.StatusInternalServerError, err.Error())
+}
+
+// fromStatus maps an HTTP status to an application error
+func fromStatus(status int, message string) *Error {
+	switch status {
+	case http.StatusBadRequest:
+		return New(status, api.CodeErrorBadRequest, message)
+	case http.StatusUnauthorized:
+		return New(status, api.CodeErrorUnauthorized, message)
+	case http.StatusForbidden:
+		return New(status, api.CodeErrorForbidden, message)
+	case http.StatusNotFound:
+		return New(status, api.CodeErrorNotFound, message)
+	case http.StatusConflict:
+		return New(status, api.CodeErrorConflict, message)
+	case http.StatusRequestEntityTooLarge:
+		return New(status, api.CodeErrorBadRequest, message).WithDocCode(DocTooLarge)
+	case http.StatusTooManyRequests:
+		return New(status, api.CodeErrorGeneric, message).WithDocCode(DocUnavailable).Retryable(time.Minute)
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return New(status, api.CodeErrorGeneric, message).WithDocCode(DocUnavailable).Retryable(5 * time.Second)
+	default:
+		return New(status, api.CodeErrorInternal, message)
+	}
+}
+
+// docCodeFor returns the default documentation code for an application code
+func docCodeFor(code int) string {
+	switch code {
+	case api.CodeErrorBadRequest:
+		return DocBadRequest
+	case api.CodeErrorUnauthorized:
+		return DocUnauthorized
+	case api.CodeErrorForbidden:
+		return DocForbidden
+	case api.CodeErrorNotFound:
+		return DocNotFound
+	case api.CodeErrorConflict:
+		return DocConflict
+	case api.CodeErrorDatabase:
+		return DocDatabase
+	case api.CodeErrorEncryption:
+		return DocEncryption
+	case api.CodeErrorConfig:
+		return DocConfig
+	case api.CodeErrorSync:
+		return DocSync
+	case api.CodeErrorOffline:
+		return DocOffline
+	case api.CodeErrorService:
+		return DocService
+	default:
+		return DocInternal
+	}
+}
@@ -0,0 +1,103 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestFrom_FiberError(t *testing.T) {
+	appErr := From(fiber.NewError(fiber.StatusNotFound, "missing"))
+
+	if appErr.Status != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", appErr.Status)
+	}
+	if appErr.Code != api.CodeErrorNotFound {
+		t.Errorf("Expected code %d, got %d", api.CodeErrorNotFound, appErr.Code)
+	}
+	if appErr.Hint.DocCode != DocNotFound {
+		t.Errorf("Expected doc code %s, got %s", DocNotFound, appErr.Hint.DocCode)
+	}
+	if appErr.Hint.Retryable {
+		t.Error("404 should not be retryable")
+	}
+}
+
+func TestFrom_BodyTooLarge(t *testing.T) {
+	// Fiber's own BodyLimit rejection reaches the error handler as this
+	appErr := From(fiber.ErrRequestEntityTooLarge)
+
+	if appErr.Status != http.StatusRequestEntityTooLarge || appErr.Code != api.CodeErrorBadRequest {
+		t.Errorf("Expected 413 with code %d, got %d %d", api.CodeErrorBadRequest, appErr.Status, appErr.Code)
+	}
+	if appErr.Hint.DocCode != DocTooLarge {
+		t.Errorf("Expected doc code %s, got %s", DocTooLarge, appErr.Hint.DocCode)
+	}
+}
+
+func TestFrom_WrappedAppError(t *testing.T) {
+	inner := Forbidden("settings:write")
+	appErr := From(fmt.Errorf("handler: %w", inner))
+
+	if appErr != inner {
+		t.Error("Expected wrapped application error to be returned as-is")
+	}
+	if appErr.Hint.RequiredPermission != "settings:write" {
+		t.Errorf("Expected required permission, got %q", appErr.Hint.RequiredPermission)
+	}
+}
+
+func TestFrom_ReadOnly(t *testing.T) {
+	appErr := From(database.ErrReadOnly)
+
+	if appErr.Code != api.CodeErrorOffline {
+		t.Errorf("Expected code %d, got %d", api.CodeErrorOffline, appErr.Code)
+	}
+	if !appErr.Hint.Retryable || appErr.Hint.RetryAfter != 60 {
+		t.Errorf("Expected retryable after 60s, got %+v", appErr.Hint)
+	}
+}
+
+func TestFrom_VersionConflict(t *testing.T) {
+	appErr := From(fmt.Errorf("update: %w", database.ErrVersionConflict))
+
+	if appErr.Status != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", appErr.Status)
+	}
+	if appErr.Code != api.CodeErrorConflict {
+		t.Errorf("Expected code %d, got %d", api.CodeErrorConflict, appErr.Code)
+	}
+	if appErr.Hint.DocCode != DocConflict {
+		t.Errorf("Expected doc code %s, got %s", DocConflict, appErr.Hint.DocCode)
+	}
+}
+
+func TestDatabase_BusyIsRetryable(t *testing.T) {
+	if Database(errors.New("constraint failed")).Hint.Retryable {
+		t.Error("Constraint failures should not be retryable")
+	}
+	if !Database(errors.New("database is locked (5) (SQLITE_BUSY)")).Hint.Retryable {
+		t.Error("Busy errors should be retryable")
+	}
+}
+
+func TestError_Unwrap(t *testing.T) {
+	cause := errors.New("disk full")
+	appErr := Internal(cause)
+
+	if !errors.Is(appErr, cause) {
+		t.Error("Expected cause to be reachable via errors.Is")
+	}
+	if appErr.Response().Message != api.MessageInternalError {
+		t.Error("Internal error message should not leak the cause")
+	}
+	if Unavailable("busy", 1500*time.Millisecond).Hint.RetryAfter != 2 {
+		t.Error("Expected retry_after to be rounded to seconds")
+	}
+}
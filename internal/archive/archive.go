@@ -0,0 +1,303 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+const (
+	// FormatName identifies the archive format in manifests
+	FormatName = "pos-archive"
+
+	// FormatVersion is the newest archive format this code reads and writes
+	FormatVersion = 1
+
+	// FileExt is the extension of archive files
+	FileExt = ".posarchive"
+
+	// EncryptionAlgorithm names the record encryption scheme
+	EncryptionAlgorithm = "chacha20-poly1305"
+
+	// Archive member names
+	readmeFile   = "README.txt"
+	manifestFile = "manifest.json"
+	schemaFile   = "schema.json"
+	recordsFile  = "records.jsonl.enc"
+
+	// keyIDPurpose derives the key fingerprint stored in manifests
+	keyIDPurpose = "pos-archive-key-id-v1"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("archive format version is not supported")
+	ErrChecksumMismatch   = errors.New("archive file checksum mismatch")
+	ErrWrongKey           = errors.New("archive was encrypted with a different key")
+)
+
+// Manifest describes an archive
+type Manifest struct {
+	Format        string         `json:"format"`
+	FormatVersion int            `json:"format_version"`
+	Entity        string         `json:"entity"` // Kind of records, e.g. "sales"
+	CreatedAt     string         `json:"created_at"`
+	StoreID       string         `json:"store_id"`
+	MachineID     string         `json:"machine_id,omitempty"`
+	PeriodFrom    string         `json:"period_from"` // Inclusive, ISO 8601
+	PeriodTo      string         `json:"period_to"`   // Exclusive, ISO 8601
+	RecordCount   int            `json:"record_count"`
+	Encryption    EncryptionInfo `json:"encryption"`
+	Files         []FileInfo     `json:"files"`
+}
+
+// EncryptionInfo describes how records are encrypted
+type EncryptionInfo struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+}
+
+// FileInfo records the checksum of an archive member
+type FileInfo struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// Options describes an archive to write
+type Options struct {
+	StoreID   string
+	MachineID string
+	From      time.Time
+	To        time.Time
+}
+
+// KeyID returns the fingerprint of a server key as stored in manifests
+func KeyID(serverKey []byte) string {
+	sum := sha256.Sum256(security.DeriveSubkey(serverKey, keyIDPurpose))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WriteSales writes the given sales to an archive file at path
+func WriteSales(path string, sales []database.Sale, serverKey []byte, opts *Options) (*Manifest, error) {
+	encryption, err := security.NewDatabaseEncryption(serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive encryption: %w", err)
+	}
+	defer encryption.Close()
+
+	var records bytes.Buffer
+	for i := range sales {
+		line, err := json.Marshal(&sales[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal sale %s: %w", sales[i].ID, err)
+		}
+		records.Write(line)
+		records.WriteByte('\n')
+	}
+
+	encrypted, err := encryption.Encrypt(records.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt archive records: %w", err)
+	}
+
+	schema, err := json.MarshalIndent(SalesSchema(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	members := []struct {
+		name string
+		data []byte
+	}{
+		{readmeFile, []byte(readme)},
+		{schemaFile, schema},
+		{recordsFile, []byte(encrypted)},
+	}
+
+	manifest := &Manifest{
+		Format:        FormatName,
+		FormatVersion: FormatVersion,
+		Entity:        "sales",
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		StoreID:       opts.StoreID,
+		MachineID:     opts.MachineID,
+		PeriodFrom:    opts.From.UTC().Format(time.RFC3339),
+		PeriodTo:      opts.To.UTC().Format(time.RFC3339),
+		RecordCount:   len(sales),
+		Encryption:    EncryptionInfo{Algorithm: EncryptionAlgorithm, KeyID: KeyID(serverKey)},
+	}
+	for _, m := range members {
+		manifest.Files = append(manifest.Files, FileInfo{Name: m.name, SHA256: checksum(m.data)})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range append([]struct {
+		name string
+		data []byte
+	}{{manifestFile, manifestData}}, members...) {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to archive: %w", m.name, err)
+		}
+		if _, err := w.Write(m.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s to archive: %w", m.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+
+	// Write atomically so an interrupted export never leaves a half archive
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, buf.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to rename archive: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Verify checks an archive's structure, version and checksums. When
+// serverKey is given it also decrypts the records and validates every one
+// against the embedded schema and the manifest's record count.
+func Verify(path string, serverKey []byte) (*Manifest, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		data, err := readMember(f)
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = data
+	}
+
+	manifestData, ok := files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", manifestFile)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Format != FormatName {
+		return nil, fmt.Errorf("not a %s file (format %q)", FormatName, manifest.Format)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, manifest.FormatVersion)
+	}
+
+	for _, fi := range manifest.Files {
+		data, ok := files[fi.Name]
+		if !ok {
+			return nil, fmt.Errorf("archive is missing %s", fi.Name)
+		}
+		if checksum(data) != fi.SHA256 {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, fi.Name)
+		}
+	}
+
+	if serverKey == nil {
+		return &manifest, nil
+	}
+
+	if KeyID(serverKey) != manifest.Encryption.KeyID {
+		return nil, ErrWrongKey
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(files[schemaFile], &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	encryption, err := security.NewDatabaseEncryption(serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive encryption: %w", err)
+	}
+	defer encryption.Close()
+	records, err := encryption.Decrypt(string(files[recordsFile]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt records: %w", err)
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(records))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		count++
+		if err := schema.Validate(scanner.Bytes()); err != nil {
+			return nil, fmt.Errorf("record %d: %w", count, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+
+	if count != manifest.RecordCount {
+		return nil, fmt.Errorf("manifest lists %d records, archive holds %d", manifest.RecordCount, count)
+	}
+
+	return &manifest, nil
+}
+
+// readMember reads one file from a ZIP archive
+func readMember(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return data, nil
+}
+
+// checksum returns the hex SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readme is embedded in every archive for readers without this software
+const readme = `POS transaction archive (format "pos-archive", version 1)
+
+manifest.json      Metadata: store, period, record count, key ID and the
+                   SHA-256 of every other file in this archive.
+schema.json        Field names, types and descriptions of each record.
+records.jsonl.enc  Records, one JSON object per line (JSONL, UTF-8),
+                   encrypted as a whole.
+
+Decrypting records.jsonl.enc: base64-decode the file; the first 12 bytes
+are the nonce, the rest is ChaCha20-Poly1305 (RFC 8439) ciphertext with
+its 16-byte tag, no associated data. The key is the store's 32-byte server
+key; manifest.json "key_id" identifies which key was used.
+
+Monetary amounts are integers in minor currency units (e.g. cents).
+`
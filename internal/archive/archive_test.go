@@ -0,0 +1,116 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func testSales() []database.Sale {
+	return []database.Sale{
+		{ID: "s1", TerminalID: "till-1", Total: 990, CreatedAt: "2026-01-05T10:00:00Z",
+			Lines: []database.SaleLine{{SKU: "MILK", Quantity: 1, Price: 990}}},
+		{ID: "s2", TerminalID: "till-1", Total: 900, CreatedAt: "2026-01-05T11:00:00Z",
+			Lines: []database.SaleLine{{SKU: "BREAD", Quantity: 2, Price: 450}}},
+	}
+}
+
+func writeTestArchive(t *testing.T, key []byte) string {
+	path := filepath.Join(t.TempDir(), "sales"+FileExt)
+	_, err := WriteSales(path, testSales(), key, &Options{
+		StoreID: "store-1",
+		From:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("WriteSales failed: %v", err)
+	}
+	return path
+}
+
+func TestWriteAndVerify(t *testing.T) {
+	key, _ := security.GenerateServerKey()
+	path := writeTestArchive(t, key)
+
+	manifest, err := Verify(path, key)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if manifest.RecordCount != 2 || manifest.FormatVersion != FormatVersion {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	// Structural verification works without the key
+	if _, err := Verify(path, nil); err != nil {
+		t.Errorf("Keyless Verify failed: %v", err)
+	}
+}
+
+func TestVerify_WrongKey(t *testing.T) {
+	key, _ := security.GenerateServerKey()
+	other, _ := security.GenerateServerKey()
+	path := writeTestArchive(t, key)
+
+	if _, err := Verify(path, other); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey, got %v", err)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	key, _ := security.GenerateServerKey()
+	path := writeTestArchive(t, key)
+
+	// Rebuild the archive with a modified schema but the original manifest
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	tampered := filepath.Join(t.TempDir(), "tampered"+FileExt)
+	out, _ := os.Create(tampered)
+	zw := zip.NewWriter(out)
+	for _, f := range zr.File {
+		data, _ := readMember(f)
+		if f.Name == schemaFile {
+			data = []byte(`{"name":"sale","version":1,"fields":[]}`)
+		}
+		w, _ := zw.Create(f.Name)
+		w.Write(data)
+	}
+	zw.Close()
+	out.Close()
+	zr.Close()
+
+	if _, err := Verify(tampered, key); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := SalesSchema()
+
+	tests := []struct {
+		name    string
+		record  string
+		wantErr bool
+	}{
+		{"valid", `{"id":"s1","total":100,"created_at":"2026-01-01T00:00:00Z","lines":[{"sku":"A","quantity":1,"price":100}]}`, false},
+		{"missing id", `{"total":100,"created_at":"x","lines":[]}`, true},
+		{"fractional total", `{"id":"s1","total":1.5,"created_at":"x","lines":[]}`, true},
+		{"bad line", `{"id":"s1","total":1,"created_at":"x","lines":[{"sku":"A"}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.record))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
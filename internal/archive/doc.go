@@ -0,0 +1,21 @@
+// Package archive reads and writes long-term transaction archives.
+//
+// Archives must stay readable for the 5+ year retention periods accountants
+// require, long after this service has changed, so the format is plain and
+// self-describing. An archive is a ZIP file (extension .posarchive) with:
+//
+//	README.txt        Human-readable description of this format
+//	manifest.json     Plaintext metadata (see Manifest)
+//	schema.json       Plaintext descriptor of the record fields (see Schema)
+//	records.jsonl.enc Encrypted records
+//
+// records.jsonl.enc holds one JSON object per line (JSONL), UTF-8, each
+// matching schema.json, encrypted as a whole. The encrypted form is base64
+// of nonce(12 bytes) || ChaCha20-Poly1305 ciphertext+tag, keyed with the
+// 32-byte server key. manifest.json records the SHA-256 of every other
+// file and a key ID (first 8 bytes of SHA-256 over a key derived from the
+// server key) so the right key can be located without exposing it.
+//
+// Format versions only ever add optional fields; readers must ignore
+// unknown fields and reject a FormatVersion newer than they support.
+package archive
@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Field types used in schema descriptors
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeArray   = "array"
+)
+
+// Schema describes the records of an archive
+type Schema struct {
+	Name    string  `json:"name"`
+	Version int     `json:"version"`
+	Fields  []Field `json:"fields"`
+}
+
+// Field describes one record field
+type Field struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Items       []Field `json:"items,omitempty"` // Fields of array elements
+}
+
+// SalesSchema returns the descriptor for database.Sale records
+func SalesSchema() *Schema {
+	return &Schema{
+		Name:    "sale",
+		Version: 2,
+		Fields: []Field{
+			{Name: "id", Type: TypeString, Required: true, Description: "Unique sale identifier"},
+			{Name: "type", Type: TypeString, Description: `"sale" (default when absent) or "refund"`},
+			{Name: "terminal_id", Type: TypeString, Description: "Terminal that recorded the sale"},
+			{Name: "operator_id", Type: TypeString, Description: "Cashier who recorded the sale"},
+			{Name: "total", Type: TypeInteger, Required: true, Description: "Sale total in minor currency units"},
+			{Name: "voided_lines", Type: TypeInteger, Description: "Lines scanned then removed before tender"},
+			{Name: "scan_seconds", Type: TypeInteger, Description: "Seconds from first scan to tender"},
+			{Name: "created_at", Type: TypeString, Required: true, Description: "Sale time, ISO 8601 UTC"},
+			{Name: "lines", Type: TypeArray, Required: true, Description: "Items sold", Items: []Field{
+				{Name: "sku", Type: TypeString, Required: true, Description: "Stock keeping unit"},
+				{Name: "quantity", Type: TypeInteger, Required: true, Description: "Units sold"},
+				{Name: "price", Type: TypeInteger, Required: true, Description: "Unit price in minor currency units"},
+			}},
+		},
+	}
+}
+
+// Validate checks one JSON record against the schema
+func (s *Schema) Validate(record []byte) error {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(record, &obj); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return validateFields(s.Fields, obj)
+}
+
+// validateFields checks the fields of one JSON object
+func validateFields(fields []Field, obj map[string]interface{}) error {
+	for _, f := range fields {
+		value, ok := obj[f.Name]
+		if !ok || value == nil {
+			if f.Required {
+				return fmt.Errorf("missing required field %q", f.Name)
+			}
+			continue
+		}
+
+		switch f.Type {
+		case TypeString:
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("field %q must be a string", f.Name)
+			}
+		case TypeInteger:
+			n, ok := value.(float64)
+			if !ok || n != float64(int64(n)) {
+				return fmt.Errorf("field %q must be an integer", f.Name)
+			}
+		case TypeArray:
+			items, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("field %q must be an array", f.Name)
+			}
+			for i, item := range items {
+				itemObj, ok := item.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("field %q[%d] must be an object", f.Name, i)
+				}
+				if err := validateFields(f.Items, itemObj); err != nil {
+					return fmt.Errorf("field %q[%d]: %w", f.Name, i, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// Scopes an API key can be granted. Each opens a fixed set of routes to a
+// third-party integration; see the server's scope table.
+const (
+	ScopeCatalogRead  = "catalog:read"  // Product lookups (scales, price checkers)
+	ScopeStockWrite   = "stock:write"   // Stock adjustments
+	ScopeLabelsWrite  = "labels:write"  // Shelf label requests
+	ScopeCartsWrite   = "carts:write"   // Building and checking out carts (kiosk apps)
+	ScopeReceiptsRead = "receipts:read" // Receipt pages
+	ScopeStatusRead   = "status:read"   // Service status
+	ScopeDataRead     = "data:read"     // GraphQL queries over local data (reporting tools)
+)
+
+// Scopes lists every scope an API key can carry
+var Scopes = []string{ScopeCatalogRead, ScopeStockWrite, ScopeLabelsWrite, ScopeCartsWrite, ScopeReceiptsRead, ScopeStatusRead, ScopeDataRead}
+
+// RoleIntegration is the role of callers presenting an API key
+const RoleIntegration = "integration"
+
+// apiKeyPrefix starts every API key so it is recognizable in logs and
+// secret scanners; the key ID follows, then the secret
+const apiKeyPrefix = "posk_"
+
+// apiKeyKeyPrefix prefixes the settings holding API key records
+const apiKeyKeyPrefix = "auth.apikey."
+
+// ErrInvalidAPIKey is returned for malformed, unknown or revoked API keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey describes an issued API key. Only the SHA-256 of its secret is
+// kept, inside the (encrypted) settings table.
+type APIKey struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"` // ISO 8601 timestamp
+	SecretHash string   `json:"secret_hash,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidScope reports whether scope can be granted
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKey issues a key named name with scopes and returns its
+// plaintext, which is only shown once
+func CreateAPIKey(db *database.DB, name string, scopes []string) (string, *APIKey, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("API key name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("API key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := hex.EncodeToString(b)
+
+	key := &APIKey{
+		ID:         strings.ToLower(ids.New()),
+		Name:       name,
+		Scopes:     append([]string(nil), scopes...),
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		SecretHash: hashAPIKeySecret(secret),
+	}
+	if err := db.SetSettingJSON(apiKeyKeyPrefix+key.ID, key); err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	key.SecretHash = ""
+	return apiKeyPrefix + key.ID + "_" + secret, key, nil
+}
+
+// LookupAPIKey returns the record of a plaintext API key
+func LookupAPIKey(db *database.DB, plaintext string) (*APIKey, error) {
+	rest, ok := strings.CutPrefix(plaintext, apiKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var key APIKey
+	if err := db.GetSettingJSON(apiKeyKeyPrefix+id, &key); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(hashAPIKeySecret(secret))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key.SecretHash = ""
+	return &key, nil
+}
+
+// ListAPIKeys returns the issued API keys, oldest first, without secrets
+func ListAPIKeys(db *database.DB) ([]APIKey, error) {
+	settings, err := db.GetSettingsWithPrefix(apiKeyKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	keys := []APIKey{}
+	for name, value := range settings {
+		var key APIKey
+		if err := json.Unmarshal([]byte(value), &key); err != nil {
+			return nil, fmt.Errorf("failed to decode API key %s: %w", name, err)
+		}
+		key.SecretHash = ""
+		keys = append(keys, key)
+	}
+
+	// IDs are ULIDs, so they sort by creation time
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// RevokeAPIKey deletes an API key by ID
+func RevokeAPIKey(db *database.DB, id string) error {
+	if err := db.DeleteSetting(apiKeyKeyPrefix + id); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return ErrInvalidAPIKey
+		}
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// hashAPIKeySecret returns the stored hash of an API key secret
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAPIKey_CreateLookupRevoke(t *testing.T) {
+	db := setupTestDB(t)
+
+	secret, key, err := CreateAPIKey(db, "Deli scale", []string{ScopeCatalogRead})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, apiKeyPrefix+key.ID+"_") {
+		t.Errorf("Unexpected key format %q", secret)
+	}
+
+	got, err := LookupAPIKey(db, secret)
+	if err != nil {
+		t.Fatalf("LookupAPIKey failed: %v", err)
+	}
+	if got.Name != "Deli scale" || !got.HasScope(ScopeCatalogRead) || got.HasScope(ScopeStockWrite) {
+		t.Errorf("Unexpected key %+v", got)
+	}
+	if got.SecretHash != "" {
+		t.Error("Expected the secret hash to be withheld")
+	}
+
+	for _, bad := range []string{"", "posk_", secret[:len(secret)-1] + "0", "posk_" + key.ID, strings.TrimPrefix(secret, apiKeyPrefix)} {
+		if bad == secret {
+			continue
+		}
+		if _, err := LookupAPIKey(db, bad); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Expected ErrInvalidAPIKey for %q, got %v", bad, err)
+		}
+	}
+
+	if err := RevokeAPIKey(db, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if _, err := LookupAPIKey(db, secret); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+	if err := RevokeAPIKey(db, key.ID); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected ErrInvalidAPIKey revoking twice, got %v", err)
+	}
+}
+
+func TestAPIKey_List(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, _, err := CreateAPIKey(db, "Kiosk", []string{ScopeCartsWrite, ScopeReceiptsRead}); err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if _, _, err := CreateAPIKey(db, "Scale", []string{ScopeCatalogRead}); err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if _, err := Issue(db, RoleStaff, "till", 0); err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	keys, err := ListAPIKeys(db)
+	if err != nil {
+		t.Fatalf("ListAPIKeys failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Name != "Kiosk" || keys[1].Name != "Scale" {
+		t.Fatalf("Expected Kiosk and Scale in creation order, got %+v", keys)
+	}
+	for _, key := range keys {
+		if key.SecretHash != "" {
+			t.Errorf("Key %s listed with its secret hash", key.ID)
+		}
+	}
+}
+
+func TestAPIKey_RejectsInvalidInput(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, _, err := CreateAPIKey(db, "", []string{ScopeCatalogRead}); err == nil {
+		t.Error("Expected an error without a name")
+	}
+	if _, _, err := CreateAPIKey(db, "Scale", nil); err == nil {
+		t.Error("Expected an error without scopes")
+	}
+	if _, _, err := CreateAPIKey(db, "Scale", []string{"admin"}); err == nil {
+		t.Error("Expected an error for an unknown scope")
+	}
+}
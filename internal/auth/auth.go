@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// Roles a bearer token can carry
+const (
+	RoleAdmin        = "admin"         // Full local API, including service control and configuration
+	RoleStaff        = "staff"         // Local API bar admin routes (staffed tills, back office)
+	RoleAttendant    = "attendant"     // Staff supervising self-checkout lanes
+	RoleCashier      = "cashier"       // Ringing up sales only
+	RoleSelfCheckout = "self_checkout" // Customer-facing lane, restricted API surface
+	RoleHandheld     = "handheld"      // Stock-taking device, catalog/stock/label API only
+	RoleTerminal     = "terminal"      // Another lane calling the store hub, hub API only
+)
+
+// tokenKeyPrefix prefixes the settings holding issued tokens. Only the
+// SHA-256 of a token is stored, so a settings dump can't be replayed.
+const tokenKeyPrefix = "auth.token."
+
+// ErrInvalidToken is returned for unknown, revoked or expired tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// Token describes an issued bearer token
+type Token struct {
+	Role     string `json:"role"`
+	Label    string `json:"label,omitempty"`   // Lane or device the token was issued to (device ID for handhelds)
+	UserID   string `json:"user_id,omitempty"` // Cashier signed in with a PIN, attributed on their sales
+	IssuedAt string `json:"issued_at"`         // ISO 8601 timestamp
+}
+
+// ValidRole reports whether role can be issued
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleStaff, RoleAttendant, RoleCashier, RoleSelfCheckout, RoleHandheld, RoleTerminal:
+		return true
+	}
+	return false
+}
+
+// Issue creates a token for role and returns its plaintext, which is only
+// shown once. A positive ttl makes the token expire.
+func Issue(db *database.DB, role, label string, ttl time.Duration) (string, error) {
+	return issue(db, Token{Role: role, Label: label}, ttl)
+}
+
+// issue stores a new token with the given claims and returns its plaintext
+func issue(db *database.DB, t Token, ttl time.Duration) (string, error) {
+	if !ValidRole(t.Role) {
+		return "", fmt.Errorf("invalid role: %s", t.Role)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	t.IssuedAt = time.Now().Format(time.RFC3339)
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	// Sign-ins keep working in offline mode (see database.SetOffline)
+	if err := db.SetSessionSetting(tokenKey(token), string(data), ttl); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Lookup returns the token record for a plaintext token
+func Lookup(db *database.DB, token string) (*Token, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var t Token
+	if err := db.GetSettingJSON(tokenKey(token), &t); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to look up token: %w", err)
+	}
+
+	// Sessions of deleted users stop working immediately
+	if t.UserID != "" {
+		if _, err := db.GetUser(t.UserID); err != nil {
+			if errors.Is(err, database.ErrUserNotFound) {
+				return nil, ErrInvalidToken
+			}
+			return nil, fmt.Errorf("failed to look up token user: %w", err)
+		}
+	}
+	return &t, nil
+}
+
+// Revoke deletes a token
+func Revoke(db *database.DB, token string) error {
+	if err := db.DeleteSessionSetting(tokenKey(token)); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// tokenKey returns the settings key of a token
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return tokenKeyPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestIssueLookupRevoke(t *testing.T) {
+	db := setupTestDB(t)
+
+	token, err := Issue(db, RoleSelfCheckout, "lane-3", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	got, err := Lookup(db, token)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got.Role != RoleSelfCheckout || got.Label != "lane-3" {
+		t.Errorf("Unexpected token record: %+v", got)
+	}
+
+	// Only the hash is stored
+	settings, _ := db.GetAllSettings()
+	for key := range settings {
+		if key == tokenKeyPrefix+token {
+			t.Error("Token stored in plaintext")
+		}
+	}
+
+	if err := Revoke(db, token); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := Lookup(db, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken after revoke, got %v", err)
+	}
+	if err := Revoke(db, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken revoking twice, got %v", err)
+	}
+}
+
+func TestIssueRejectsUnknownRole(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, err := Issue(db, "root", "", 0); err == nil {
+		t.Error("Expected error for unknown role")
+	}
+	if _, err := Lookup(db, ""); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for empty token, got %v", err)
+	}
+}
+
+func TestTokenExpires(t *testing.T) {
+	db := setupTestDB(t)
+
+	token, err := Issue(db, RoleAttendant, "", time.Millisecond)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	if _, err := Lookup(db, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected expired token to be invalid, got %v", err)
+	}
+}
+
+func TestAdminPassword(t *testing.T) {
+	db := setupTestDB(t)
+
+	if set, err := HasAdminPassword(db); err != nil || set {
+		t.Fatalf("Expected no admin password yet (%v)", err)
+	}
+	if err := CheckAdminPassword(db, "anything"); !errors.Is(err, ErrNoAdminPassword) {
+		t.Errorf("Expected ErrNoAdminPassword, got %v", err)
+	}
+	if err := SetAdminPassword(db, "short"); err == nil {
+		t.Error("Expected a short password to be rejected")
+	}
+
+	if err := SetAdminPassword(db, "correct horse battery"); err != nil {
+		t.Fatalf("SetAdminPassword failed: %v", err)
+	}
+	if stored, _ := db.GetSetting(adminPasswordKey); stored == "correct horse battery" {
+		t.Error("Admin password stored in plaintext")
+	}
+	if err := CheckAdminPassword(db, "correct horse battery"); err != nil {
+		t.Errorf("Expected the password to match: %v", err)
+	}
+	if err := CheckAdminPassword(db, "wrong horse battery"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Expected ErrInvalidPassword, got %v", err)
+	}
+}
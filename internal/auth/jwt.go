@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Frontend tokens are HS256 JWTs signed with the api_secret shared between
+// the service and the POS frontend. The frontend may mint them itself with
+// any JWT library or trade the secret for one at POST /auth/token. Since
+// every holder of the secret can sign any claims, the server accepts a JWT
+// only with the role of the lane it runs on.
+const (
+	JWTIssuer   = "pos-service"
+	JWTAudience = "pos-local-api"
+
+	// FrontendSubject is the subject of tokens issued to the POS frontend
+	FrontendSubject = "pos-frontend"
+
+	// DefaultJWTTTL is the lifetime of tokens issued by the service
+	DefaultJWTTTL = 12 * time.Hour
+
+	// MinJWTSecretLength is the shortest accepted api_secret (HS256 needs
+	// at least as many key bytes as its output)
+	MinJWTSecretLength = constants.MinAPISecretLength
+)
+
+// Claims are the claims of a frontend token
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// IssueJWT signs a token for role and subject that expires after ttl
+func IssueJWT(secret []byte, role, subject string, ttl time.Duration) (string, time.Time, error) {
+	if !ValidRole(role) {
+		return "", time.Time{}, fmt.Errorf("invalid role: %s", role)
+	}
+	if len(secret) < MinJWTSecretLength {
+		return "", time.Time{}, fmt.Errorf("api secret must be at least %d bytes", MinJWTSecretLength)
+	}
+	if ttl <= 0 {
+		ttl = DefaultJWTTTL
+	}
+
+	now := time.Now()
+	expires := now.Add(ttl).Truncate(time.Second)
+	claims := Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    JWTIssuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{JWTAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expires, nil
+}
+
+// ParseJWT verifies a frontend token and returns its claims. Tokens without
+// an expiry, for another audience or signed with anything but HS256 are
+// rejected with ErrInvalidToken.
+func ParseJWT(secret []byte, token string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(JWTIssuer),
+		jwt.WithAudience(JWTAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !ValidRole(claims.Role) {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidToken, claims.Role)
+	}
+	return &claims, nil
+}
+
+// IsJWT reports whether a bearer token looks like a JWT rather than an
+// opaque token from Issue (which is hex, without dots)
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
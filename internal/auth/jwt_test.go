@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestJWT_RoundTrip(t *testing.T) {
+	token, expires, err := IssueJWT(testSecret, RoleStaff, FrontendSubject, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	if !IsJWT(token) {
+		t.Errorf("Expected %q to look like a JWT", token)
+	}
+	if until := time.Until(expires); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("Unexpected expiry %v", expires)
+	}
+
+	claims, err := ParseJWT(testSecret, token)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.Role != RoleStaff || claims.Subject != FrontendSubject {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	// Another secret or a tampered payload does not verify
+	if _, err := ParseJWT([]byte("another-secret-another-secret-xx"), token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for another secret, got %v", err)
+	}
+	parts := strings.Split(token, ".")
+	parts[1] = parts[1][:len(parts[1])-2] + "AA"
+	if _, err := ParseJWT(testSecret, strings.Join(parts, ".")); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a tampered token, got %v", err)
+	}
+}
+
+func TestJWT_RejectsWeakTokens(t *testing.T) {
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		return token
+	}
+	valid := jwt.RegisteredClaims{
+		Issuer:    JWTIssuer,
+		Audience:  jwt.ClaimStrings{JWTAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	tests := map[string]string{
+		"unsigned": sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, Claims{RoleStaff, valid}),
+		"no expiry": sign(jwt.SigningMethodHS256, testSecret, Claims{RoleStaff, jwt.RegisteredClaims{
+			Issuer: JWTIssuer, Audience: jwt.ClaimStrings{JWTAudience},
+		}}),
+		"expired": sign(jwt.SigningMethodHS256, testSecret, Claims{RoleStaff, jwt.RegisteredClaims{
+			Issuer: JWTIssuer, Audience: jwt.ClaimStrings{JWTAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		}}),
+		"other audience": sign(jwt.SigningMethodHS256, testSecret, Claims{RoleStaff, jwt.RegisteredClaims{
+			Issuer: JWTIssuer, Audience: jwt.ClaimStrings{"backend"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}}),
+		"unknown role": sign(jwt.SigningMethodHS256, testSecret, Claims{"root", valid}),
+	}
+	for name, token := range tests {
+		if _, err := ParseJWT(testSecret, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	if _, _, err := IssueJWT([]byte("short"), RoleStaff, FrontendSubject, 0); err == nil {
+		t.Error("Expected error for a short secret")
+	}
+	if IsJWT("0123456789abcdef") {
+		t.Error("Expected an opaque token not to look like a JWT")
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// adminPasswordKey is the setting holding the bcrypt hash of the admin
+// password
+const adminPasswordKey = "auth.admin_password"
+
+// adminPasswordCost is the bcrypt cost of new admin password hashes
+const adminPasswordCost = 12
+
+var (
+	// ErrInvalidPassword is returned when the admin password does not match
+	ErrInvalidPassword = errors.New("invalid admin password")
+
+	// ErrNoAdminPassword is returned when no admin password has been set
+	ErrNoAdminPassword = errors.New("admin password not set")
+)
+
+// SetAdminPassword sets the admin password destructive routes ask for,
+// replacing any previous one. Only its bcrypt hash is stored.
+func SetAdminPassword(db *database.DB, password string) error {
+	if utf8.RuneCountInString(password) < constants.MinAdminPasswordLength {
+		return fmt.Errorf("admin password must be at least %d characters", constants.MinAdminPasswordLength)
+	}
+	// bcrypt ignores everything past 72 bytes
+	if len(password) > 72 {
+		return fmt.Errorf("admin password must be at most 72 bytes")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), adminPasswordCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash admin password: %w", err)
+	}
+	if err := db.SetSetting(adminPasswordKey, string(hash)); err != nil {
+		return fmt.Errorf("failed to store admin password: %w", err)
+	}
+	return nil
+}
+
+// HasAdminPassword reports whether an admin password has been set
+func HasAdminPassword(db *database.DB) (bool, error) {
+	_, err := db.GetSetting(adminPasswordKey)
+	if errors.Is(err, database.ErrSettingNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read admin password: %w", err)
+	}
+	return true, nil
+}
+
+// CheckAdminPassword returns nil if password is the admin password,
+// ErrInvalidPassword if it is not and ErrNoAdminPassword if none is set
+func CheckAdminPassword(db *database.DB, password string) error {
+	hash, err := db.GetSetting(adminPasswordKey)
+	if errors.Is(err, database.ErrSettingNotFound) {
+		return ErrNoAdminPassword
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read admin password: %w", err)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return ErrInvalidPassword
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2id parameters of new PIN hashes. They are recorded in each hash,
+// so raising them later leaves existing PINs verifiable.
+const (
+	pinArgonTime    = 2
+	pinArgonMemory  = 19 * 1024 // KiB
+	pinArgonThreads = 1
+	pinArgonKeyLen  = 32
+	pinSaltLen      = 16
+)
+
+// ErrInvalidPIN is returned for unknown users and wrong PINs alike
+var ErrInvalidPIN = errors.New("invalid user or PIN")
+
+// dummyPINSalt is hashed against when the user is unknown, so a lookup of
+// a missing user takes as long as a wrong PIN
+var dummyPINSalt = make([]byte, pinSaltLen)
+
+// ValidPINRole reports whether users with role may sign in with a PIN.
+// PINs are short, so they never grant admin rights.
+func ValidPINRole(role string) bool {
+	return role == RoleCashier || role == RoleAttendant
+}
+
+// ValidatePIN checks that pin is all digits and of an accepted length
+func ValidatePIN(pin string) error {
+	if len(pin) < constants.MinPINLength || len(pin) > constants.MaxPINLength {
+		return fmt.Errorf("PIN must be %d to %d digits", constants.MinPINLength, constants.MaxPINLength)
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("PIN must be %d to %d digits", constants.MinPINLength, constants.MaxPINLength)
+		}
+	}
+	return nil
+}
+
+// HashPIN returns the argon2id hash of pin in the PHC string format
+func HashPIN(pin string) (string, error) {
+	if err := ValidatePIN(pin); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, pinSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(pin), salt, pinArgonTime, pinArgonMemory, pinArgonThreads, pinArgonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, pinArgonMemory, pinArgonTime, pinArgonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPIN reports whether pin matches a hash made by HashPIN
+func VerifyPIN(hash, pin string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+
+	got := argon2.IDKey([]byte(pin), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// PINSession is a cashier session opened with a PIN
+type PINSession struct {
+	Token     string
+	User      *database.User
+	ExpiresAt time.Time
+}
+
+// SignInWithPIN checks a user's PIN and issues a short-lived token for
+// their role, carrying their ID for attribution. Unknown users and wrong
+// PINs both return ErrInvalidPIN.
+func SignInWithPIN(db *database.DB, userID, pin string) (*PINSession, error) {
+	user, err := db.GetUser(userID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		argon2.IDKey([]byte(pin), dummyPINSalt, pinArgonTime, pinArgonMemory, pinArgonThreads, pinArgonKeyLen)
+		return nil, ErrInvalidPIN
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !VerifyPIN(user.PINHash, pin) {
+		return nil, ErrInvalidPIN
+	}
+
+	ttl := constants.PINSessionTTLMinutes * time.Minute
+	token, err := issue(db, Token{Role: user.Role, Label: user.Name, UserID: user.ID}, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.TouchUser(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to record sign-in: %w", err)
+	}
+
+	return &PINSession{Token: token, User: user, ExpiresAt: time.Now().Add(ttl)}, nil
+}
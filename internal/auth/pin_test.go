@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestHashPIN(t *testing.T) {
+	for _, pin := range []string{"", "123", "123456789", "12a4"} {
+		if _, err := HashPIN(pin); err == nil {
+			t.Errorf("Expected PIN %q to be rejected", pin)
+		}
+	}
+
+	hash, err := HashPIN("4821")
+	if err != nil {
+		t.Fatalf("HashPIN failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") || strings.Contains(hash, "4821") {
+		t.Errorf("Unexpected hash: %s", hash)
+	}
+	if other, _ := HashPIN("4821"); other == hash {
+		t.Error("Expected a fresh salt per hash")
+	}
+
+	if !VerifyPIN(hash, "4821") {
+		t.Error("Expected the PIN to verify")
+	}
+	if VerifyPIN(hash, "4822") || VerifyPIN("garbage", "4821") {
+		t.Error("Expected a wrong PIN or malformed hash to fail")
+	}
+}
+
+func TestSignInWithPIN(t *testing.T) {
+	db := setupTestDB(t)
+
+	hash, _ := HashPIN("4821")
+	if err := db.CreateUser(&database.User{ID: "C-01", Name: "Dana", Role: RoleCashier, PINHash: hash}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	if _, err := SignInWithPIN(db, "C-01", "0000"); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("Expected ErrInvalidPIN for a wrong PIN, got %v", err)
+	}
+	if _, err := SignInWithPIN(db, "C-99", "4821"); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("Expected ErrInvalidPIN for an unknown user, got %v", err)
+	}
+
+	session, err := SignInWithPIN(db, "C-01", "4821")
+	if err != nil {
+		t.Fatalf("SignInWithPIN failed: %v", err)
+	}
+	token, err := Lookup(db, session.Token)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if token.Role != RoleCashier || token.UserID != "C-01" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	// Deleting the user ends the session
+	if err := db.DeleteUser("C-01"); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if _, err := Lookup(db, session.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken after deleting the user, got %v", err)
+	}
+}
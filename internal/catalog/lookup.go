@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// DefaultRemoteTimeout bounds the read-through lookup so a slow backend
+// never stalls the checkout scan
+const DefaultRemoteTimeout = 1500 * time.Millisecond
+
+// RemoteLookup fetches a product from head office by barcode.
+// It returns database.ErrProductNotFound when the backend does not know the barcode.
+type RemoteLookup interface {
+	LookupBarcode(ctx context.Context, barcode string) (*database.Product, error)
+}
+
+// Resolver resolves barcodes against the local catalog, falling back to a
+// remote lookup while online and caching what it finds
+type Resolver struct {
+	db      *database.DB
+	remote  RemoteLookup
+	timeout time.Duration
+	online  func() bool
+}
+
+// ResolverConfig holds resolver configuration
+type ResolverConfig struct {
+	DB      *database.DB
+	Remote  RemoteLookup  // Optional; nil disables the read-through fallback
+	Timeout time.Duration // Remote lookup timeout, default DefaultRemoteTimeout
+	Online  func() bool   // Reports whether the terminal is currently online
+}
+
+// NewResolver creates a new barcode resolver
+func NewResolver(cfg *ResolverConfig) (*Resolver, error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("resolver requires a database")
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultRemoteTimeout
+	}
+
+	online := cfg.Online
+	if online == nil {
+		online = func() bool { return true }
+	}
+
+	return &Resolver{
+		db:      cfg.DB,
+		remote:  cfg.Remote,
+		timeout: timeout,
+		online:  online,
+	}, nil
+}
+
+// Lookup returns the product for a barcode. Local misses are recorded as
+// catalog gaps for sync telemetry and, when online, resolved remotely.
+func (r *Resolver) Lookup(ctx context.Context, barcode string) (*database.Product, error) {
+	product, err := r.db.GetProductByBarcode(barcode)
+	if err == nil {
+		return product, nil
+	}
+	if !errors.Is(err, database.ErrProductNotFound) {
+		return nil, err
+	}
+
+	if r.remote == nil || !r.online() {
+		r.recordGap(barcode, false)
+		return nil, database.ErrProductNotFound
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	product, err = r.remote.LookupBarcode(lookupCtx, barcode)
+	if err != nil {
+		r.recordGap(barcode, false)
+		if errors.Is(err, database.ErrProductNotFound) {
+			return nil, database.ErrProductNotFound
+		}
+		return nil, fmt.Errorf("remote barcode lookup failed: %w", err)
+	}
+
+	r.recordGap(barcode, true)
+
+	// Cache locally; a failed cache write must not fail the scan
+	if err := r.db.UpsertProduct(product, database.ProductSourceRemote); err != nil {
+		log.Printf("Warning: failed to cache remote product %s: %v", barcode, err)
+	}
+
+	return product, nil
+}
+
+// recordGap stores a catalog gap, logging instead of failing the lookup
+func (r *Resolver) recordGap(barcode string, resolved bool) {
+	if err := r.db.RecordCatalogGap(barcode, resolved); err != nil {
+		log.Printf("Warning: failed to record catalog gap %s: %v", barcode, err)
+	}
+}
+
+// HTTPRemoteLookup looks barcodes up on the head office catalog API
+type HTTPRemoteLookup struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPRemoteLookup creates a remote lookup against serverURL
+func NewHTTPRemoteLookup(serverURL string, client *http.Client) *HTTPRemoteLookup {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPRemoteLookup{
+		baseURL: strings.TrimRight(serverURL, "/"),
+		client:  client,
+	}
+}
+
+// LookupBarcode implements RemoteLookup
+func (h *HTTPRemoteLookup) LookupBarcode(ctx context.Context, barcode string) (*database.Product, error) {
+	endpoint := h.baseURL + "/catalog/barcode/" + url.PathEscape(barcode)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, database.ErrProductNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Head office answers with the standard APIResponse envelope
+	var envelope struct {
+		OK     bool             `json:"ok"`
+		Result database.Product `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode remote product: %w", err)
+	}
+	if !envelope.OK || envelope.Result.Barcode == "" {
+		return nil, database.ErrProductNotFound
+	}
+
+	return &envelope.Result, nil
+}
@@ -0,0 +1,115 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestLookup_RemoteFallbackCaches(t *testing.T) {
+	calls := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/catalog/barcode/4006381333931" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"code":10,"message":"ok","result":{"id":"p1","barcode":"4006381333931","name":"Pen","price":150,"active":true}}`))
+	}))
+	defer backend.Close()
+
+	db := setupTestDB(t)
+	resolver, _ := NewResolver(&ResolverConfig{
+		DB:     db,
+		Remote: NewHTTPRemoteLookup(backend.URL, nil),
+	})
+
+	product, err := resolver.Lookup(context.Background(), "4006381333931")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if product.Name != "Pen" {
+		t.Errorf("Expected product Pen, got %q", product.Name)
+	}
+
+	// Second lookup is served from the local cache
+	if _, err := resolver.Lookup(context.Background(), "4006381333931"); err != nil {
+		t.Fatalf("Cached lookup failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 remote call, got %d", calls)
+	}
+
+	gaps, err := db.GetUnreportedCatalogGaps()
+	if err != nil {
+		t.Fatalf("GetUnreportedCatalogGaps failed: %v", err)
+	}
+	if len(gaps) != 1 || !gaps[0].Resolved {
+		t.Errorf("Expected one resolved catalog gap, got %+v", gaps)
+	}
+}
+
+func TestLookup_OfflineRecordsGap(t *testing.T) {
+	db := setupTestDB(t)
+	resolver, _ := NewResolver(&ResolverConfig{
+		DB:     db,
+		Remote: NewHTTPRemoteLookup("http://127.0.0.1:1", nil),
+		Online: func() bool { return false },
+	})
+
+	_, err := resolver.Lookup(context.Background(), "missing")
+	if !errors.Is(err, database.ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+
+	gaps, _ := db.GetUnreportedCatalogGaps()
+	if len(gaps) != 1 || gaps[0].Resolved {
+		t.Errorf("Expected one unresolved catalog gap, got %+v", gaps)
+	}
+}
+
+func TestLookup_RemoteTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer backend.Close()
+
+	db := setupTestDB(t)
+	resolver, _ := NewResolver(&ResolverConfig{
+		DB:      db,
+		Remote:  NewHTTPRemoteLookup(backend.URL, nil),
+		Timeout: 20 * time.Millisecond,
+	})
+
+	start := time.Now()
+	if _, err := resolver.Lookup(context.Background(), "slow"); err == nil {
+		t.Error("Expected timeout error")
+	}
+	if time.Since(start) > 150*time.Millisecond {
+		t.Error("Remote lookup did not respect the timeout")
+	}
+}
@@ -0,0 +1,126 @@
+package closing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Items lists every checklist item in evaluation order
+var Items = []string{
+	constants.ClosingTabsClosed,
+	constants.ClosingDrawerReconciled,
+	constants.ClosingPendingSync,
+	constants.ClosingBackupDone,
+}
+
+// BackupMaxAge is how recent a backup must be for backup_done
+const BackupMaxAge = 24 * time.Hour
+
+// Inputs are the facts the POS reports when asking to close
+type Inputs struct {
+	// DrawerReconciled is set once the cashier has counted the drawer and
+	// it matched the expected float and takings
+	DrawerReconciled bool
+}
+
+// Checklist evaluates the day-close checklist against the local database
+type Checklist struct {
+	db         *database.DB
+	items      []string
+	maxPending int
+	now        func() time.Time
+}
+
+// New creates a checklist of items (nil means every item) that tolerates
+// maxPending unsynced changes
+func New(db *database.DB, items []string, maxPending int) *Checklist {
+	if len(items) == 0 {
+		items = Items
+	}
+	return &Checklist{db: db, items: items, maxPending: maxPending, now: time.Now}
+}
+
+// Result is an evaluated checklist
+type Result struct {
+	Items []database.ClosingItem `json:"items"`
+	Met   bool                   `json:"met"` // Every item met
+}
+
+// Unmet returns the names of the items that are not met
+func (r *Result) Unmet() []string {
+	var unmet []string
+	for _, item := range r.Items {
+		if !item.Met {
+			unmet = append(unmet, item.Name)
+		}
+	}
+	return unmet
+}
+
+// Evaluate checks every configured item
+func (c *Checklist) Evaluate(in Inputs) (*Result, error) {
+	result := &Result{Met: true}
+	for _, name := range c.items {
+		item, err := c.evaluate(name, in)
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, item)
+		result.Met = result.Met && item.Met
+	}
+	return result, nil
+}
+
+// evaluate checks one item
+func (c *Checklist) evaluate(name string, in Inputs) (database.ClosingItem, error) {
+	item := database.ClosingItem{Name: name}
+
+	switch name {
+	case constants.ClosingTabsClosed:
+		open, err := c.db.CountOpenBaskets()
+		if err != nil {
+			return item, err
+		}
+		item.Met = open == 0
+		if !item.Met {
+			item.Detail = fmt.Sprintf("%d carts still open", open)
+		}
+
+	case constants.ClosingDrawerReconciled:
+		item.Met = in.DrawerReconciled
+		if !item.Met {
+			item.Detail = "drawer not reconciled"
+		}
+
+	case constants.ClosingPendingSync:
+		pending, err := c.db.CountPendingOutbox()
+		if err != nil {
+			return item, err
+		}
+		item.Met = pending <= c.maxPending
+		if !item.Met {
+			item.Detail = fmt.Sprintf("%d changes not synced (limit %d)", pending, c.maxPending)
+		}
+
+	case constants.ClosingBackupDone:
+		at, ok, err := c.db.LastBackup()
+		if err != nil {
+			return item, err
+		}
+		item.Met = ok && c.now().Sub(at) <= BackupMaxAge
+		switch {
+		case !ok:
+			item.Detail = "no backup taken"
+		case !item.Met:
+			item.Detail = "last backup " + at.UTC().Format(time.RFC3339)
+		}
+
+	default:
+		return item, fmt.Errorf("unknown checklist item: %s", name)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,100 @@
+package closing
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// unmet returns the names of a result's unmet items as a set
+func unmet(r *Result) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range r.Unmet() {
+		names[name] = true
+	}
+	return names
+}
+
+func TestChecklist_Evaluate(t *testing.T) {
+	db := setupTestDB(t)
+	checklist := New(db, nil, 0)
+
+	if _, err := db.AppendBasketLines("C1", "T1", []database.SaleLine{{SKU: "MILK", Quantity: 1, Price: 990}}, 0); err != nil {
+		t.Fatalf("AppendBasketLines failed: %v", err)
+	}
+
+	result, err := checklist.Evaluate(Inputs{})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if result.Met || len(result.Items) != len(Items) {
+		t.Fatalf("Expected every item evaluated and the checklist unmet, got %+v", result)
+	}
+	got := unmet(result)
+	if !got[constants.ClosingTabsClosed] || !got[constants.ClosingDrawerReconciled] || !got[constants.ClosingBackupDone] || got[constants.ClosingPendingSync] {
+		t.Errorf("Unexpected unmet items %v", result.Unmet())
+	}
+
+	if err := db.DeleteBasket("C1"); err != nil {
+		t.Fatalf("DeleteBasket failed: %v", err)
+	}
+	if err := db.Backup(filepath.Join(t.TempDir(), "backup.db")); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	result, err = checklist.Evaluate(Inputs{DrawerReconciled: true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if !result.Met {
+		t.Errorf("Expected the checklist to be met, unmet: %v", result.Unmet())
+	}
+
+	// A day-old backup no longer counts
+	checklist.now = func() time.Time { return time.Now().Add(BackupMaxAge + time.Minute) }
+	result, _ = checklist.Evaluate(Inputs{DrawerReconciled: true})
+	if !unmet(result)[constants.ClosingBackupDone] {
+		t.Errorf("Expected a stale backup to fail, got %+v", result)
+	}
+}
+
+func TestChecklist_PendingSyncAndSelection(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.UpsertProduct(&database.Product{ID: "P1", Barcode: "111", Name: "Milk"}, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	result, err := New(db, []string{constants.ClosingPendingSync}, 0).Evaluate(Inputs{})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if result.Met || len(result.Items) != 1 {
+		t.Errorf("Expected only pending_sync, unmet, got %+v", result)
+	}
+
+	result, _ = New(db, []string{constants.ClosingPendingSync}, 1).Evaluate(Inputs{})
+	if !result.Met {
+		t.Errorf("Expected one pending change to be tolerated, got %+v", result)
+	}
+}
@@ -3,62 +3,260 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/i18n"
 	"github.com/professor93/promo-pos/internal/security"
 	"github.com/professor93/promo-pos/pkg/constants"
+	"github.com/professor93/promo-pos/pkg/paths"
 )
 
 // Config represents the application configuration
 type Config struct {
 	ServerURL       string `json:"server_url"`
 	StoreID         string `json:"store_id"`
+	StoreToken      string `json:"store_token"` // Provisioning token issued by the backend for this store
 	Port            int    `json:"port"`
+	BindAddress     string `json:"bind_address"`      // "127.0.0.1", "0.0.0.0" or one LAN address; empty binds loopback
 	SyncInterval    int    `json:"sync_interval"`     // seconds, default 59
 	MaxOfflineHours int    `json:"max_offline_hours"` // default 24
 	LogLevel        string `json:"log_level"`
-	Encrypted       bool   `json:"encrypted"` // Whether this config is encrypted
+	RetentionDays   int    `json:"retention_days"` // days synced history is kept, default 30
+	CompressAbove   int    `json:"compress_above"` // bytes; larger database values are compressed before encryption, default 4096, -1 disables
+	SyncTransport   string `json:"sync_transport"` // "tcp" (default) or experimental "quic"
+	KeyStorage      string `json:"key_storage"`    // "auto" (default), "tpm" or "file"
+	SecretVault     string `json:"secret_vault"`   // "auto" (default), "file", "credman" or "env"; holds the secrets below
+	DBCipher        string `json:"db_cipher"`      // "chacha20-poly1305" (default), "xchacha20-poly1305" or "aes-256-gcm"
+	FIPSMode        bool   `json:"fips_mode"`      // Only FIPS-validated algorithms: forces aes-256-gcm
+	Encrypted       bool   `json:"encrypted"`      // Whether this config is encrypted
+
+	// Static addresses for server_url's host, used when DNS fails and no
+	// earlier answer is cached
+	BackendFallbackIPs []string `json:"backend_fallback_ips"`
+
+	// SPKI pins ("sha256/<base64>") the backend's certificate chain must
+	// match; empty trusts the system CA store alone
+	BackendPins []string `json:"backend_pins"`
+
+	// Store roles: "terminal" (default) or "hub"
+	Role       string `json:"role"`
+	HubURL     string `json:"hub_url"`      // Terminals: sync via this store hub instead of ServerURL
+	HubToken   string `json:"hub_token"`    // Terminals: bearer token the hub issued for this lane
+	HubBlobDir string `json:"hub_blob_dir"` // Hub: directory with catalog blobs served over the LAN
+
+	// Lane profile: "till" (default) or "self_checkout"
+	LaneProfile       string   `json:"lane_profile"`
+	SCOMaxItems       int      `json:"sco_max_items"`       // Self-checkout: items per cart, default 50
+	AgeRestrictedSKUs []string `json:"age_restricted_skus"` // Self-checkout: SKUs needing an attendant age check
+
+	// Shared with the POS frontend, which trades it at /auth/token for a JWT
+	// signed with it (at least 32 bytes); empty disables frontend JWTs
+	APISecret string `json:"api_secret"`
+
+	// Browser origins of the POS frontends, such as "http://localhost:3000";
+	// pages from other origins get no CORS headers and cannot open /ws/status
+	AllowedOrigins []string `json:"allowed_origins"`
+
+	// Receipt printers by name (see PrinterConfig)
+	Printers map[string]PrinterConfig `json:"printers"`
+
+	// Peripherals by name whose health /status reports (see PeripheralConfig)
+	Peripherals map[string]PeripheralConfig `json:"peripherals"`
+
+	// Day-close checklist: the items evaluated at close (default all) and
+	// the unsynced changes tolerated by pending_sync (default 50)
+	ClosingChecklist      []string `json:"closing_checklist"`
+	ClosingMaxPendingSync int      `json:"closing_max_pending_sync"`
+
+	// Scheduled reports: the kinds rendered each day (default all), their
+	// formats (default pdf and csv) and the local "HH:MM" the previous day's
+	// reports are generated (default 06:00). They are mailed when
+	// report_email has an smtp_addr.
+	Reports       []string          `json:"reports"`
+	ReportFormats []string          `json:"report_formats"`
+	ReportTime    string            `json:"report_time"`
+	ReportEmail   ReportEmailConfig `json:"report_email"`
+
+	// Safe mode: this many crashes within safe_mode_window_minutes start the
+	// service with only the core API and diagnostics (default 3 in 15
+	// minutes; -1 disables)
+	SafeModeCrashes       int `json:"safe_mode_crashes"`
+	SafeModeWindowMinutes int `json:"safe_mode_window_minutes"`
+
+	// Optional read-only GraphQL endpoint (/graphql) over the local catalog
+	// and sales, for reporting tools; off by default
+	GraphQL bool `json:"graphql"`
+
+	// Optional Prometheus metrics (/metrics) on their own port so fleet
+	// monitoring can scrape them without reaching the API; 0 disables.
+	// metrics_bind_address defaults to bind_address.
+	MetricsPort        int    `json:"metrics_port"`
+	MetricsBindAddress string `json:"metrics_bind_address"`
+
+	// Optional gRPC interface (api/proto/pos/v1) on its own port, bound to
+	// bind_address; 0 disables
+	GRPCPort int `json:"grpc_port"`
+
+	// Optional Go runtime profiles (/debug/pprof) for admins, to diagnose
+	// slow terminals in the field; off by default
+	Pprof bool `json:"pprof"`
+
+	// Optional webhooks: local URLs (a kitchen display, a signage box) sent
+	// signed JSON callbacks on service events. webhook_secret keys the
+	// HMAC-SHA256 signature; none are sent without it.
+	Webhooks      []WebhookConfig `json:"webhooks"`
+	WebhookSecret string          `json:"webhook_secret"`
+
+	// Language of API response messages ("en", "es" or "ru") for callers
+	// whose Accept-Language names none of them; empty means English
+	Locale string `json:"locale"`
+
+	// Request body limits in bytes: larger requests are refused before they
+	// are read. POST /import uploads get their own, larger limit. 0 uses the
+	// defaults (1 MiB and 32 MiB).
+	MaxBodyBytes       int `json:"max_body_bytes"`
+	MaxImportBodyBytes int `json:"max_import_body_bytes"`
+
+	// Optional MQTT bridge (heartbeats/events out, directives in); disabled when MQTTBrokerURL is empty
+	MQTTBrokerURL   string `json:"mqtt_broker_url"`
+	MQTTUsername    string `json:"mqtt_username"`
+	MQTTPassword    string `json:"mqtt_password"`
+	MQTTTopicPrefix string `json:"mqtt_topic_prefix"`
 
 	// Internal fields (not serialized)
-	mu         sync.RWMutex      `json:"-"`
+	mu         sync.RWMutex               `json:"-"`
 	encryption *security.ConfigEncryption `json:"-"`
-	filePath   string            `json:"-"`
-	lastSaved  time.Time         `json:"-"`
+	filePath   string                     `json:"-"`
+	lastSaved  time.Time                  `json:"-"`
+}
+
+// PrinterConfig configures one ESC/POS receipt printer
+type PrinterConfig struct {
+	Width    int    `json:"width"`     // Characters per line, default 42
+	Encoding string `json:"encoding"`  // "ascii" (default), "cp866", "cp1251" or "utf-8"
+	CodePage int    `json:"code_page"` // ESC t table number when the model differs from the usual one
+}
+
+// PeripheralConfig describes one external peripheral
+type PeripheralConfig struct {
+	Kind    string `json:"kind"`    // "printer", "eft", "scale" or "display"
+	Address string `json:"address"` // host:port probed over TCP; empty when the peripheral layer reports it
+}
+
+// WebhookConfig is one URL notified of service events
+type WebhookConfig struct {
+	URL    string   `json:"url"`    // http(s) URL the events are POSTed to
+	Events []string `json:"events"` // Event types to send, e.g. "sale.completed"; empty sends all but sync.progress
+}
+
+// ReportEmailConfig configures mailing of scheduled reports
+type ReportEmailConfig struct {
+	SMTPAddr string   `json:"smtp_addr"` // host:port; empty disables mailing
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
 }
 
 // Manager handles configuration loading, saving, and syncing
 type Manager struct {
 	config     *Config
 	encryption *security.ConfigEncryption
+	previous   []*security.ConfigEncryption // Decrypt files written under older keys, newest first
 	configPath string
 	machineID  string
+	migrated   bool
+	vault      security.Vault                 // Holds the secret fields (see UseVault); nil keeps them in the file
+	reloaders  map[string]func(*Config) error // Apply changed keys without a restart (see OnReload)
 	mu         sync.RWMutex
 }
 
-// NewManager creates a new configuration manager
-func NewManager(machineID string) (*Manager, error) {
+// NewManager creates a new configuration manager.
+// configDir is the resolved config directory; empty uses the default.
+func NewManager(machineID, configDir string) (*Manager, error) {
 	if machineID == "" {
 		return nil, fmt.Errorf("machine ID cannot be empty")
 	}
 
+	masterKey, previousKeys, err := security.LoadConfigKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config key: %w", err)
+	}
+	defer wipeKeys(masterKey, previousKeys)
+
+	return newManager(masterKey, previousKeys, machineID, configDir)
+}
+
+// NewMigrationManager creates a configuration manager for machineID that
+// also opens files encrypted for previousMachineID; Load re-encrypts them
+// for machineID. Used to re-enroll a terminal whose hardware changed.
+func NewMigrationManager(machineID, previousMachineID, configDir string) (*Manager, error) {
+	if machineID == "" || previousMachineID == "" {
+		return nil, fmt.Errorf("machine ID cannot be empty")
+	}
+
+	masterKey, previousKeys, err := security.LoadConfigKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config key: %w", err)
+	}
+	defer wipeKeys(masterKey, previousKeys)
+
+	m, err := newManager(masterKey, previousKeys, machineID, configDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range append([][]byte{masterKey}, previousKeys...) {
+		enc, err := security.NewConfigEncryption(key, previousMachineID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create previous config encryption: %w", err)
+		}
+		m.previous = append(m.previous, enc)
+	}
+	return m, nil
+}
+
+// wipeKeys wipes the master keys once the derived keys are built
+func wipeKeys(masterKey []byte, previousKeys [][]byte) {
+	security.Wipe(masterKey)
+	for _, key := range previousKeys {
+		security.Wipe(key)
+	}
+}
+
+// newManager creates a configuration manager with explicit master keys
+func newManager(masterKey []byte, previousKeys [][]byte, machineID, configDir string) (*Manager, error) {
 	// Create config encryption handler
-	encryption, err := security.NewConfigEncryption(machineID)
+	encryption, err := security.NewConfigEncryption(masterKey, machineID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config encryption: %w", err)
 	}
 
+	previous := make([]*security.ConfigEncryption, 0, len(previousKeys))
+	for _, key := range previousKeys {
+		enc, err := security.NewConfigEncryption(key, machineID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create previous config encryption: %w", err)
+		}
+		previous = append(previous, enc)
+	}
+
 	// Determine config path
-	configDir := os.Getenv("PROGRAMDATA")
 	if configDir == "" {
-		configDir = "." // Fallback for development
+		configDir = paths.Default().ConfigDir
 	}
-	configPath := filepath.Join(configDir, "POSService", constants.ConfigFileName)
+	configPath := filepath.Join(configDir, constants.ConfigFileName)
 
 	return &Manager{
 		encryption: encryption,
+		previous:   previous,
 		configPath: configPath,
 		machineID:  machineID,
 	}, nil
@@ -83,10 +281,20 @@ func (m *Manager) Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Decrypt config
+	// Decrypt config; files written by older releases (hard-coded key, or
+	// a build key before machine binding) are migrated below
+	migrate := false
 	decryptedData, err := m.encryption.Decrypt(string(encryptedData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+		for _, enc := range m.previous {
+			if data, prevErr := enc.Decrypt(string(encryptedData)); prevErr == nil {
+				decryptedData, migrate = data, true
+				break
+			}
+		}
+		if !migrate {
+			return nil, fmt.Errorf("failed to decrypt config: %w", err)
+		}
 	}
 
 	// Parse JSON
@@ -99,15 +307,116 @@ func (m *Manager) Load() (*Config, error) {
 	config.filePath = m.configPath
 	config.Encrypted = true
 
+	// Re-encrypt with the current key so older keys are needed only once
+	if migrate {
+		if err := m.save(&config); err != nil {
+			return nil, fmt.Errorf("failed to migrate config to new key: %w", err)
+		}
+		m.migrated = true
+	}
+
 	m.config = &config
 	return &config, nil
 }
 
+// Loaded reports whether a config has been loaded (thread-safe)
+func (m *Manager) Loaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config != nil
+}
+
+// Check reads and decrypts the config file without applying it, for the
+// health check. A missing file is fine: the defaults are in use.
+func (m *Manager) Check() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	encryptedData, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var decryptedData []byte
+	for _, enc := range append([]*security.ConfigEncryption{m.encryption}, m.previous...) {
+		if decryptedData, err = enc.Decrypt(string(encryptedData)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decrypt config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(decryptedData, &config); err != nil {
+		return fmt.Errorf("failed to parse config JSON: %w", err)
+	}
+	return nil
+}
+
+// MigratedKey reports whether Load re-encrypted a config file that was
+// written under an older key
+func (m *Manager) MigratedKey() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.migrated
+}
+
+// Encryption returns the config encryption, which also seals the log file
+func (m *Manager) Encryption() *security.ConfigEncryption {
+	return m.encryption
+}
+
+// Encryptions returns the config encryption followed by those of older
+// config keys, for data that may predate a key migration
+func (m *Manager) Encryptions() []*security.ConfigEncryption {
+	return append([]*security.ConfigEncryption{m.encryption}, m.previous...)
+}
+
+// Close wipes the derived config keys and the cached master keys they
+// came from. Everything encrypted with them, including the log file and
+// the secrets vault, is unusable afterwards.
+func (m *Manager) Close() {
+	for _, enc := range m.Encryptions() {
+		enc.Close()
+	}
+	security.WipeConfigKeys()
+}
+
+// WrapSecret encrypts a secret with the config key, binding it to this
+// machine the same way config.enc is
+func (m *Manager) WrapSecret(secret []byte) (string, error) {
+	return m.encryption.Encrypt(secret)
+}
+
+// UnwrapSecret decrypts a secret from WrapSecret. migrated is true when an
+// older config key was needed, so the caller should wrap it again.
+func (m *Manager) UnwrapSecret(wrapped string) (secret []byte, migrated bool, err error) {
+	secret, err = m.encryption.Decrypt(wrapped)
+	if err == nil {
+		return secret, false, nil
+	}
+	for _, enc := range m.previous {
+		if prev, prevErr := enc.Decrypt(wrapped); prevErr == nil {
+			return prev, true, nil
+		}
+	}
+	return nil, false, fmt.Errorf("failed to unwrap secret: %w", err)
+}
+
 // Save saves the current configuration to encrypted file
 func (m *Manager) Save(config *Config) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.save(config)
+}
+
+// save writes config; the caller holds m.mu
+func (m *Manager) save(config *Config) error {
 	// Ensure directory exists
 	configDir := filepath.Dir(m.configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -117,8 +426,17 @@ func (m *Manager) Save(config *Config) error {
 	// Mark as encrypted
 	config.Encrypted = true
 
+	// Secrets go to the vault rather than the file
+	fileConfig, err := config.clone()
+	if err != nil {
+		return err
+	}
+	if err := m.storeSecrets(config, fileConfig); err != nil {
+		return err
+	}
+
 	// Serialize to JSON
-	jsonData, err := json.MarshalIndent(config, "", "  ")
+	jsonData, err := json.MarshalIndent(fileConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -156,8 +474,24 @@ func (m *Manager) Get() (*Config, error) {
 	}
 
 	// Return a copy to prevent external modifications
-	configCopy := *m.config
-	return &configCopy, nil
+	return m.config.clone()
+}
+
+// clone returns a deep copy of c. Config holds its own lock, so it is
+// copied through JSON rather than by value.
+func (c *Config) clone() (*Config, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	clone := &Config{encryption: c.encryption, filePath: c.filePath, lastSaved: c.lastSaved}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return clone, nil
 }
 
 // Update updates specific configuration fields and saves
@@ -175,7 +509,7 @@ func (m *Manager) Update(updateFunc func(*Config) error) error {
 	}
 
 	// Save updated config
-	return m.Save(m.config)
+	return m.save(m.config)
 }
 
 // getDefaultConfig returns the default configuration
@@ -187,6 +521,14 @@ func (m *Manager) getDefaultConfig() *Config {
 		SyncInterval:    constants.DefaultSyncInterval,
 		MaxOfflineHours: constants.DefaultMaxOfflineHours,
 		LogLevel:        constants.DefaultLogLevel,
+		RetentionDays:   constants.DefaultRetentionDays,
+		CompressAbove:   constants.DefaultCompressAbove,
+		SyncTransport:   constants.DefaultSyncTransport,
+		KeyStorage:      constants.DefaultKeyStorage,
+		SecretVault:     constants.DefaultSecretVault,
+		Role:            constants.DefaultRole,
+		LaneProfile:     constants.DefaultLaneProfile,
+		SCOMaxItems:     constants.DefaultSCOMaxItems,
 		Encrypted:       false,
 		encryption:      m.encryption,
 		filePath:        m.configPath,
@@ -218,6 +560,53 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max_offline_hours must be at least 1 hour")
 	}
 
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("retention_days cannot be negative")
+	}
+
+	if c.CompressAbove < -1 {
+		return fmt.Errorf("compress_above must be -1 (disabled) or a size in bytes")
+	}
+
+	for _, addr := range c.BackendFallbackIPs {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("invalid backend_fallback_ips entry %q: must be an IP address", addr)
+		}
+	}
+
+	for _, pin := range c.BackendPins {
+		if _, err := security.ParseSPKIPin(pin); err != nil {
+			return fmt.Errorf("invalid backend_pins entry: %w", err)
+		}
+	}
+
+	switch c.SyncTransport {
+	case "", constants.SyncTransportTCP, constants.SyncTransportQUIC:
+	default:
+		return fmt.Errorf("invalid sync_transport: must be tcp or quic")
+	}
+
+	switch c.DBCipher {
+	case "", constants.DBCipherChaCha20, constants.DBCipherXChaCha20, constants.DBCipherAES256GCM:
+	default:
+		return fmt.Errorf("invalid db_cipher: must be %s, %s or %s", constants.DBCipherChaCha20, constants.DBCipherXChaCha20, constants.DBCipherAES256GCM)
+	}
+	if (c.FIPSMode || security.FIPSRequired()) && c.DBCipher != "" && c.DBCipher != constants.DBCipherAES256GCM {
+		return fmt.Errorf("db_cipher %s is not FIPS-validated: use %s or leave it empty", c.DBCipher, constants.DBCipherAES256GCM)
+	}
+
+	switch c.KeyStorage {
+	case "", constants.KeyStorageAuto, constants.KeyStorageTPM, constants.KeyStorageFile:
+	default:
+		return fmt.Errorf("invalid key_storage: must be auto, tpm or file")
+	}
+
+	switch c.SecretVault {
+	case "", constants.SecretVaultAuto, constants.SecretVaultFile, constants.SecretVaultCredential, constants.SecretVaultEnv:
+	default:
+		return fmt.Errorf("invalid secret_vault: must be auto, file, credman or env")
+	}
+
 	validLogLevels := map[string]bool{
 		"debug": true,
 		"info":  true,
@@ -229,6 +618,155 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log_level: must be debug, info, warn, or error")
 	}
 
+	switch c.Role {
+	case "", constants.RoleTerminal:
+	case constants.RoleHub:
+		if c.HubURL != "" {
+			return fmt.Errorf("hub_url must be empty when role is hub")
+		}
+	default:
+		return fmt.Errorf("invalid role: must be terminal or hub")
+	}
+
+	if c.BindAddress != "" && net.ParseIP(c.BindAddress) == nil {
+		return fmt.Errorf("invalid bind_address: must be an IP address such as %s or %s", constants.BindLoopback, constants.BindAll)
+	}
+	if c.Role == constants.RoleHub && !c.lanExposed() {
+		return fmt.Errorf("bind_address must be set to a LAN address when role is hub: other terminals must reach it")
+	}
+
+	if c.MetricsPort < 0 || c.MetricsPort > 65535 {
+		return fmt.Errorf("metrics_port must be 0 (disabled) or between 1 and 65535")
+	}
+	if c.MetricsPort != 0 && c.MetricsPort == c.Port {
+		return fmt.Errorf("metrics_port must differ from port")
+	}
+	if c.MetricsBindAddress != "" && net.ParseIP(c.MetricsBindAddress) == nil {
+		return fmt.Errorf("invalid metrics_bind_address: must be an IP address such as %s or %s", constants.BindLoopback, constants.BindAll)
+	}
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+		return fmt.Errorf("grpc_port must be 0 (disabled) or between 1 and 65535")
+	}
+	if c.GRPCPort != 0 && (c.GRPCPort == c.Port || c.GRPCPort == c.MetricsPort) {
+		return fmt.Errorf("grpc_port must differ from port and metrics_port")
+	}
+
+	switch c.LaneProfile {
+	case "", constants.LaneProfileTill, constants.LaneProfileSelfCheckout:
+	default:
+		return fmt.Errorf("invalid lane_profile: must be till or self_checkout")
+	}
+
+	if c.SCOMaxItems < 0 {
+		return fmt.Errorf("sco_max_items cannot be negative")
+	}
+
+	if c.APISecret != "" && len(c.APISecret) < constants.MinAPISecretLength {
+		return fmt.Errorf("api_secret must be at least %d bytes", constants.MinAPISecretLength)
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("invalid allowed_origins entry %q: must be a scheme and host such as http://localhost:3000", origin)
+		}
+	}
+
+	for name, printer := range c.Printers {
+		switch printer.Encoding {
+		case "", constants.ReceiptEncodingASCII, constants.ReceiptEncodingCP866,
+			constants.ReceiptEncodingCP1251, constants.ReceiptEncodingUTF8:
+		default:
+			return fmt.Errorf("invalid encoding for printer %q: must be ascii, cp866, cp1251 or utf-8", name)
+		}
+		if printer.Width < 0 || printer.CodePage < 0 || printer.CodePage > 255 {
+			return fmt.Errorf("invalid width or code_page for printer %q", name)
+		}
+	}
+
+	for name, p := range c.Peripherals {
+		switch p.Kind {
+		case constants.PeripheralPrinter, constants.PeripheralEFT,
+			constants.PeripheralScale, constants.PeripheralDisplay:
+		default:
+			return fmt.Errorf("invalid kind for peripheral %q: must be printer, eft, scale or display", name)
+		}
+		if p.Address != "" {
+			if _, _, err := net.SplitHostPort(p.Address); err != nil {
+				return fmt.Errorf("invalid address for peripheral %q: must be host:port", name)
+			}
+		}
+	}
+
+	if c.Locale != "" && !i18n.Supported(c.Locale) {
+		return fmt.Errorf("invalid locale: must be en, es or ru")
+	}
+
+	if c.MaxBodyBytes < 0 || c.MaxImportBodyBytes < 0 {
+		return fmt.Errorf("max_body_bytes and max_import_body_bytes cannot be negative")
+	}
+
+	for i, hook := range c.Webhooks {
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url for webhook %d: must be an http or https URL", i)
+		}
+		for _, eventType := range hook.Events {
+			if !events.Known(eventType) {
+				return fmt.Errorf("invalid event %q for webhook %d", eventType, i)
+			}
+		}
+	}
+
+	for _, item := range c.ClosingChecklist {
+		switch item {
+		case constants.ClosingTabsClosed, constants.ClosingDrawerReconciled,
+			constants.ClosingPendingSync, constants.ClosingBackupDone:
+		default:
+			return fmt.Errorf("invalid closing_checklist item %q: must be tabs_closed, drawer_reconciled, pending_sync or backup_done", item)
+		}
+	}
+
+	if c.ClosingMaxPendingSync < 0 {
+		return fmt.Errorf("closing_max_pending_sync cannot be negative")
+	}
+
+	if c.SafeModeCrashes < -1 {
+		return fmt.Errorf("safe_mode_crashes must be -1 (disabled) or a number of crashes")
+	}
+
+	if c.SafeModeWindowMinutes < 0 {
+		return fmt.Errorf("safe_mode_window_minutes cannot be negative")
+	}
+
+	for _, kind := range c.Reports {
+		switch kind {
+		case constants.ReportDailySales, constants.ReportPromoUptake, constants.ReportRefundSummary:
+		default:
+			return fmt.Errorf("invalid reports entry %q: must be daily_sales, promo_uptake or refund_summary", kind)
+		}
+	}
+
+	for _, format := range c.ReportFormats {
+		if format != constants.ReportFormatPDF && format != constants.ReportFormatCSV {
+			return fmt.Errorf("invalid report_formats entry %q: must be pdf or csv", format)
+		}
+	}
+
+	if c.ReportTime != "" {
+		if _, err := time.Parse("15:04", c.ReportTime); err != nil {
+			return fmt.Errorf("invalid report_time: must be HH:MM")
+		}
+	}
+
+	if c.ReportEmail.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.ReportEmail.SMTPAddr); err != nil {
+			return fmt.Errorf("invalid report_email smtp_addr: must be host:port")
+		}
+		if c.ReportEmail.From == "" || len(c.ReportEmail.To) == 0 {
+			return fmt.Errorf("report_email needs from and at least one to address")
+		}
+	}
+
 	return nil
 }
 
@@ -246,6 +784,103 @@ func (c *Config) GetStoreID() string {
 	return c.StoreID
 }
 
+// GetStoreToken returns the store provisioning token (thread-safe)
+func (c *Config) GetStoreToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.StoreToken
+}
+
+// GetRetentionDays returns the local history retention in days (thread-safe).
+// Configs written before retention existed fall back to the default.
+func (c *Config) GetRetentionDays() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.RetentionDays < 1 {
+		return constants.DefaultRetentionDays
+	}
+	return c.RetentionDays
+}
+
+// GetCompressAbove returns the size in bytes above which database values are
+// compressed before encryption; 0 means compression is disabled (thread-safe)
+func (c *Config) GetCompressAbove() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case c.CompressAbove < 0:
+		return 0
+	case c.CompressAbove == 0:
+		return constants.DefaultCompressAbove
+	}
+	return c.CompressAbove
+}
+
+// GetBackendFallbackIPs returns the static backend addresses (thread-safe)
+func (c *Config) GetBackendFallbackIPs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.BackendFallbackIPs...)
+}
+
+// GetBackendPins returns the backend certificate pins (thread-safe)
+func (c *Config) GetBackendPins() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.BackendPins...)
+}
+
+// GetSyncTransport returns the sync transport (thread-safe)
+func (c *Config) GetSyncTransport() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.SyncTransport == "" {
+		return constants.DefaultSyncTransport
+	}
+	return c.SyncTransport
+}
+
+// GetDBCipher returns the cipher new database values are sealed with;
+// AES-256-GCM in FIPS mode (fips_mode, or a FIPS build) (thread-safe)
+func (c *Config) GetDBCipher() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case c.FIPSMode || security.FIPSRequired():
+		return constants.DBCipherAES256GCM
+	case c.DBCipher == "":
+		return constants.DefaultDBCipher
+	}
+	return c.DBCipher
+}
+
+// GetKeyStorage returns the sealed key storage backend (thread-safe)
+func (c *Config) GetKeyStorage() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.KeyStorage == "" {
+		return constants.DefaultKeyStorage
+	}
+	return c.KeyStorage
+}
+
+// GetSecretVault returns the secrets vault backend (thread-safe)
+func (c *Config) GetSecretVault() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.SecretVault == "" {
+		return constants.DefaultSecretVault
+	}
+	return c.SecretVault
+}
+
+// MQTTEnabled reports whether the MQTT bridge is configured (thread-safe)
+func (c *Config) MQTTEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MQTTBrokerURL != ""
+}
+
 // GetPort returns the port (thread-safe)
 func (c *Config) GetPort() int {
 	c.mu.RLock()
@@ -253,6 +888,36 @@ func (c *Config) GetPort() int {
 	return c.Port
 }
 
+// GetBindAddress returns the address the HTTP listener binds to: the
+// configured one, else loopback (thread-safe)
+func (c *Config) GetBindAddress() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.bindAddress()
+}
+
+// bindAddress returns the effective bind address; callers hold c.mu
+func (c *Config) bindAddress() string {
+	if c.BindAddress != "" {
+		return c.BindAddress
+	}
+	return constants.BindLoopback
+}
+
+// IsLANExposed reports whether the HTTP listener is reachable from other
+// machines (thread-safe)
+func (c *Config) IsLANExposed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lanExposed()
+}
+
+// lanExposed is IsLANExposed for callers holding c.mu
+func (c *Config) lanExposed() bool {
+	ip := net.ParseIP(c.bindAddress())
+	return ip == nil || !ip.IsLoopback()
+}
+
 // GetSyncInterval returns the sync interval in seconds (thread-safe)
 func (c *Config) GetSyncInterval() int {
 	c.mu.RLock()
@@ -267,6 +932,276 @@ func (c *Config) GetMaxOfflineHours() int {
 	return c.MaxOfflineHours
 }
 
+// IsHub reports whether this machine is the store hub (thread-safe)
+func (c *Config) IsHub() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Role == constants.RoleHub
+}
+
+// GetSyncURL returns the URL sync traffic should go to: the store hub
+// for terminals that have one, otherwise the server (thread-safe)
+func (c *Config) GetSyncURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Role != constants.RoleHub && c.HubURL != "" {
+		return strings.TrimRight(c.HubURL, "/") + "/hub/sync"
+	}
+	return c.ServerURL
+}
+
+// GetHubAPIURL returns the store hub API root used for cart transfers and
+// self-checkout interventions: this machine for the hub itself, the
+// configured hub for terminals, or "" when there is no hub. It keeps the
+// unversioned /hub path, which hubs of every release serve, so terminals
+// and hubs can be upgraded in any order (thread-safe)
+func (c *Config) GetHubAPIURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Role == constants.RoleHub {
+		host := constants.BindLoopback
+		if ip := net.ParseIP(c.bindAddress()); ip != nil && !ip.IsUnspecified() {
+			host = ip.String()
+		}
+		return fmt.Sprintf("http://%s/hub", net.JoinHostPort(host, strconv.Itoa(c.Port)))
+	}
+	if c.HubURL != "" {
+		return strings.TrimRight(c.HubURL, "/") + "/hub"
+	}
+	return ""
+}
+
+// GetLaneProfile returns the lane profile (thread-safe)
+func (c *Config) GetLaneProfile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.LaneProfile == "" {
+		return constants.DefaultLaneProfile
+	}
+	return c.LaneProfile
+}
+
+// GetSCOMaxItems returns the self-checkout item limit (thread-safe)
+func (c *Config) GetSCOMaxItems() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.SCOMaxItems == 0 {
+		return constants.DefaultSCOMaxItems
+	}
+	return c.SCOMaxItems
+}
+
+// GetAgeRestrictedSKUs returns a copy of the age-restricted SKUs (thread-safe)
+func (c *Config) GetAgeRestrictedSKUs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.AgeRestrictedSKUs...)
+}
+
+// GetAPISecret returns the frontend token secret (thread-safe)
+func (c *Config) GetAPISecret() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.APISecret
+}
+
+// GetAllowedOrigins returns a copy of the frontend origins (thread-safe)
+func (c *Config) GetAllowedOrigins() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.AllowedOrigins...)
+}
+
+// GetPrinters returns a copy of the receipt printer settings (thread-safe)
+func (c *Config) GetPrinters() map[string]PrinterConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	printers := make(map[string]PrinterConfig, len(c.Printers))
+	for name, printer := range c.Printers {
+		printers[name] = printer
+	}
+	return printers
+}
+
+// GetClosingChecklist returns the day-close checklist items (thread-safe);
+// nil means every item
+func (c *Config) GetClosingChecklist() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.ClosingChecklist...)
+}
+
+// GetClosingMaxPendingSync returns the unsynced changes tolerated at day
+// close (thread-safe)
+func (c *Config) GetClosingMaxPendingSync() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ClosingMaxPendingSync == 0 {
+		return constants.DefaultClosingMaxPendingSync
+	}
+	return c.ClosingMaxPendingSync
+}
+
+// GraphQLEnabled reports whether /graphql is served (thread-safe)
+func (c *Config) GraphQLEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.GraphQL
+}
+
+// GetMetricsAddress returns the host:port the metrics listener binds to,
+// or "" when metrics are disabled (thread-safe)
+func (c *Config) GetMetricsAddress() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.MetricsPort == 0 {
+		return ""
+	}
+	host := c.MetricsBindAddress
+	if host == "" {
+		host = c.bindAddress()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(c.MetricsPort))
+}
+
+// GetGRPCAddress returns the host:port the gRPC listener binds to, or ""
+// when gRPC is disabled (thread-safe)
+func (c *Config) GetGRPCAddress() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.GRPCPort == 0 {
+		return ""
+	}
+	return net.JoinHostPort(c.bindAddress(), strconv.Itoa(c.GRPCPort))
+}
+
+// PprofEnabled reports whether /debug/pprof is served (thread-safe)
+func (c *Config) PprofEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Pprof
+}
+
+// GetSafeModeCrashes returns the crashes within the safe mode window that
+// start safe mode; 0 means safe mode is disabled (thread-safe)
+func (c *Config) GetSafeModeCrashes() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case c.SafeModeCrashes < 0:
+		return 0
+	case c.SafeModeCrashes == 0:
+		return constants.DefaultSafeModeCrashes
+	}
+	return c.SafeModeCrashes
+}
+
+// GetSafeModeWindow returns how far back crashes count towards safe mode
+// (thread-safe)
+func (c *Config) GetSafeModeWindow() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	minutes := c.SafeModeWindowMinutes
+	if minutes == 0 {
+		minutes = constants.DefaultSafeModeWindowMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetPeripherals returns the monitored peripherals by name (thread-safe)
+func (c *Config) GetPeripherals() map[string]PeripheralConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	peripherals := make(map[string]PeripheralConfig, len(c.Peripherals))
+	for name, p := range c.Peripherals {
+		peripherals[name] = p
+	}
+	return peripherals
+}
+
+// GetWebhooks returns a copy of the webhook settings (thread-safe)
+func (c *Config) GetWebhooks() []WebhookConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	webhooks := make([]WebhookConfig, len(c.Webhooks))
+	for i, hook := range c.Webhooks {
+		webhooks[i] = WebhookConfig{URL: hook.URL, Events: append([]string(nil), hook.Events...)}
+	}
+	return webhooks
+}
+
+// GetWebhookSecret returns the key signing webhook callbacks (thread-safe)
+func (c *Config) GetWebhookSecret() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.WebhookSecret
+}
+
+// GetLocale returns the fallback language of API messages (thread-safe)
+func (c *Config) GetLocale() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Locale
+}
+
+// GetMaxBodyBytes returns the request body limit in bytes (thread-safe)
+func (c *Config) GetMaxBodyBytes() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.MaxBodyBytes == 0 {
+		return constants.DefaultBodyLimit
+	}
+	return c.MaxBodyBytes
+}
+
+// GetMaxImportBodyBytes returns the body limit of POST /import in bytes,
+// never below the general limit (thread-safe)
+func (c *Config) GetMaxImportBodyBytes() int {
+	limit := c.GetMaxBodyBytes()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.MaxImportBodyBytes == 0 {
+		return max(constants.DefaultImportBodyLimit, limit)
+	}
+	return max(c.MaxImportBodyBytes, limit)
+}
+
+// GetReports returns the report kinds generated each day (thread-safe);
+// nil means every kind
+func (c *Config) GetReports() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.Reports...)
+}
+
+// GetReportFormats returns the formats reports are rendered in (thread-safe);
+// nil means every format
+func (c *Config) GetReportFormats() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.ReportFormats...)
+}
+
+// GetReportTime returns the local "HH:MM" reports are generated (thread-safe)
+func (c *Config) GetReportTime() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ReportTime == "" {
+		return constants.DefaultReportTime
+	}
+	return c.ReportTime
+}
+
+// GetReportEmail returns the report mailing settings (thread-safe)
+func (c *Config) GetReportEmail() ReportEmailConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	email := c.ReportEmail
+	email.To = append([]string(nil), c.ReportEmail.To...)
+	return email
+}
+
 // GetLogLevel returns the log level (thread-safe)
 func (c *Config) GetLogLevel() string {
 	c.mu.RLock()
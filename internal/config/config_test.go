@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+var testMasterKey = []byte("test-config-master-key-0123456789abcdef")
+
+func TestLoad_MigratesLegacyKey(t *testing.T) {
+	dir := t.TempDir()
+	machineID := "machine-1"
+
+	// Write a config the way releases with the hard-coded key did
+	legacy, err := security.NewConfigEncryption(security.LegacyConfigKey(), machineID)
+	if err != nil {
+		t.Fatalf("Failed to create legacy encryption: %v", err)
+	}
+	encrypted, err := legacy.Encrypt([]byte(`{"store_id":"store-7","port":9090}`))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	path := filepath.Join(dir, constants.ConfigFileName)
+	if err := os.WriteFile(path, []byte(encrypted), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	mgr, err := newManager(testMasterKey, [][]byte{security.LegacyConfigKey()}, machineID, dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		t.Fatalf("Failed to load legacy config: %v", err)
+	}
+	if cfg.StoreID != "store-7" || cfg.Port != 9090 {
+		t.Errorf("Unexpected config after migration: %+v", cfg)
+	}
+	if !mgr.MigratedKey() {
+		t.Error("Expected migration to be reported")
+	}
+
+	// The file is now readable with the new key only
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if _, err := legacy.Decrypt(string(data)); err == nil {
+		t.Error("Expected migrated file to no longer use the legacy key")
+	}
+
+	reloaded, err := newManager(testMasterKey, [][]byte{security.LegacyConfigKey()}, machineID, dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to reload migrated config: %v", err)
+	}
+	if reloaded.MigratedKey() {
+		t.Error("Expected no migration on second load")
+	}
+}
+
+func TestLoad_MigratesPreviousBuildKey(t *testing.T) {
+	dir := t.TempDir()
+	previousKey := []byte("previous-build-config-key-0123456789")
+
+	old, err := newManager(previousKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := old.Load(); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+	if err := old.Update(func(c *Config) error { c.StoreID = "store-3"; return nil }); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// A machine-bound key replaces the plain build key after an upgrade
+	mgr, err := newManager(testMasterKey, [][]byte{previousKey, security.LegacyConfigKey()}, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config written under previous key: %v", err)
+	}
+	if cfg.StoreID != "store-3" || !mgr.MigratedKey() {
+		t.Errorf("Expected migrated config, got %+v (migrated: %v)", cfg, mgr.MigratedKey())
+	}
+}
+
+func TestLoad_WrongKey(t *testing.T) {
+	dir := t.TempDir()
+
+	mgr, err := newManager(testMasterKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := mgr.Load(); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+	if err := mgr.Update(func(c *Config) error { c.StoreID = "store-1"; return nil }); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	other, err := newManager([]byte("another-config-master-key-0123456789"), nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := other.Load(); err == nil {
+		t.Error("Expected load with a different master key to fail")
+	}
+	if err := other.Check(); err == nil {
+		t.Error("Expected check with a different master key to fail")
+	}
+	if err := mgr.Check(); err != nil {
+		t.Errorf("Expected check with the right key to pass: %v", err)
+	}
+}
+
+func TestBindAddress(t *testing.T) {
+	cfg := &Config{ServerURL: "https://pos.example.com", StoreID: "S1", Port: 8080, SyncInterval: 59,
+		MaxOfflineHours: 24, LogLevel: "info"}
+
+	if got := cfg.GetBindAddress(); got != constants.BindLoopback || cfg.IsLANExposed() {
+		t.Errorf("Expected terminals to default to loopback, got %s", got)
+	}
+
+	// The hub defaults to loopback too, so its operator has to pick the
+	// address other terminals reach it on
+	cfg.Role = constants.RoleHub
+	if got := cfg.GetBindAddress(); got != constants.BindLoopback || cfg.IsLANExposed() {
+		t.Errorf("Expected the hub to default to loopback, got %s", got)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected a hub without bind_address to be rejected")
+	}
+
+	cfg.BindAddress = constants.BindLoopback
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected a loopback hub to be rejected")
+	}
+
+	cfg.BindAddress = "192.168.1.20"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a LAN address to be accepted: %v", err)
+	}
+	if got := cfg.GetHubAPIURL(); got != "http://192.168.1.20:8080/hub" {
+		t.Errorf("Expected the hub API on its LAN address, got %s", got)
+	}
+
+	cfg.BindAddress = "localhost"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected a host name to be rejected")
+	}
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	cfg := &Config{ServerURL: "https://pos.example.com", StoreID: "S1", Port: 8080, SyncInterval: 59,
+		MaxOfflineHours: 24, LogLevel: "info"}
+
+	cfg.AllowedOrigins = []string{"http://localhost:3000", "https://pos.store.local/"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected origins to be accepted: %v", err)
+	}
+	for _, origin := range []string{"*", "localhost:3000", "http://localhost:3000/app", "file:///pos"} {
+		cfg.AllowedOrigins = []string{origin}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %q to be rejected", origin)
+		}
+	}
+}
+
+func TestGRPCAddress(t *testing.T) {
+	cfg := &Config{ServerURL: "https://pos.example.com", StoreID: "S1", Port: 8080, SyncInterval: 59,
+		MaxOfflineHours: 24, LogLevel: "info", MetricsPort: 9464}
+
+	if addr := cfg.GetGRPCAddress(); addr != "" {
+		t.Errorf("Expected gRPC off by default, got %q", addr)
+	}
+	cfg.GRPCPort = 9090
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected grpc_port to be accepted: %v", err)
+	}
+	if addr := cfg.GetGRPCAddress(); addr != "127.0.0.1:9090" {
+		t.Errorf("Expected the loopback default, got %q", addr)
+	}
+	for _, port := range []int{-1, 8080, 9464, 70000} {
+		cfg.GRPCPort = port
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected grpc_port %d to be rejected", port)
+		}
+	}
+}
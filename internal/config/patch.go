@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalid is returned by Patch for updates that name unknown or
+// read-only keys or fail validation; nothing is saved
+var ErrInvalid = errors.New("invalid config update")
+
+// readOnlyKeys cannot be changed through Patch
+var readOnlyKeys = map[string]bool{
+	"encrypted": true,
+}
+
+// UpdateReport lists the config keys a Patch changed, by when they take
+// effect. Keys are JSON names; values are never reported.
+type UpdateReport struct {
+	Changed         []string `json:"changed"`
+	Applied         []string `json:"applied"`          // In effect now (see OnReload)
+	RestartRequired []string `json:"restart_required"` // In effect after the service restarts
+}
+
+// OnReload registers fn to apply a change to key (its JSON name) without a
+// restart. Patch calls it with the saved config; an error leaves the key
+// waiting for a restart.
+func (m *Manager) OnReload(key string, fn func(*Config) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reloaders == nil {
+		m.reloaders = make(map[string]func(*Config) error)
+	}
+	m.reloaders[key] = fn
+}
+
+// Patch applies a partial update: each top-level key in patch replaces that
+// setting, the others keep their values. The result is validated and
+// saved, then the changed keys with a reloader are applied.
+func (m *Manager) Patch(patch []byte) (*UpdateReport, error) {
+	var updates map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &updates); err != nil || updates == nil {
+		return nil, fmt.Errorf("%w: body must be a JSON object", ErrInvalid)
+	}
+
+	updated, changed, reloaders, err := m.patch(updates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reloaders run outside the lock: they may read the config back
+	report := &UpdateReport{Changed: changed, Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range changed {
+		if fn, ok := reloaders[key]; ok && fn(updated) == nil {
+			report.Applied = append(report.Applied, key)
+		} else {
+			report.RestartRequired = append(report.RestartRequired, key)
+		}
+	}
+	return report, nil
+}
+
+// patch merges updates into the loaded config and saves it if anything
+// changed. It returns the saved config, the changed keys in order and the
+// reloaders to run.
+func (m *Manager) patch(updates map[string]json.RawMessage) (*Config, []string, map[string]func(*Config) error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.config == nil {
+		return nil, nil, nil, fmt.Errorf("configuration not loaded")
+	}
+
+	m.config.mu.RLock()
+	before, err := jsonFields(m.config)
+	m.config.mu.RUnlock()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(before))
+	for key, value := range before {
+		merged[key] = value
+	}
+	keys := make([]string, 0, len(updates))
+	for key, value := range updates {
+		if _, ok := before[key]; !ok {
+			return nil, nil, nil, fmt.Errorf("%w: unknown key %q", ErrInvalid, key)
+		}
+		if readOnlyKeys[key] {
+			return nil, nil, nil, fmt.Errorf("%w: %s cannot be changed", ErrInvalid, key)
+		}
+		merged[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	updated := &Config{}
+	if err := json.Unmarshal(data, updated); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := updated.Validate(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	updated.encryption = m.config.encryption
+	updated.filePath = m.config.filePath
+
+	// Compare what would be stored, so 5 and 5.0 or reordered map keys are
+	// not changes
+	after, err := jsonFields(updated)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	changed := []string{}
+	for _, key := range keys {
+		if !bytes.Equal(before[key], after[key]) {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		return updated, changed, nil, nil
+	}
+
+	if err := m.save(updated); err != nil {
+		return nil, nil, nil, err
+	}
+	reloaders := make(map[string]func(*Config) error, len(m.reloaders))
+	for key, fn := range m.reloaders {
+		reloaders[key] = fn
+	}
+	return updated, changed, reloaders, nil
+}
+
+// jsonFields returns config's JSON encoding by top-level key
+func jsonFields(config *Config) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return fields, nil
+}
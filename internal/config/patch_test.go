@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPatch_PartialUpdate(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := newManager(testMasterKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := mgr.Load(); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+
+	var reloaded string
+	mgr.OnReload("log_level", func(c *Config) error {
+		reloaded = c.GetLogLevel()
+		return nil
+	})
+
+	report, err := mgr.Patch([]byte(`{"server_url": "https://pos.example.com", "store_id": "store-1", "log_level": "debug", "port": 8080}`))
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	want := &UpdateReport{
+		Changed:         []string{"log_level", "server_url", "store_id"},
+		Applied:         []string{"log_level"},
+		RestartRequired: []string{"server_url", "store_id"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Unexpected report %+v, want %+v", report, want)
+	}
+	if reloaded != "debug" {
+		t.Errorf("Expected the log level reloaded, got %q", reloaded)
+	}
+
+	// Saved, and untouched keys kept their values
+	reopened, err := newManager(testMasterKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfg, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if cfg.StoreID != "store-1" || cfg.LogLevel != "debug" || cfg.SyncInterval != mgr.getDefaultConfig().SyncInterval {
+		t.Errorf("Unexpected saved config: %+v", cfg)
+	}
+}
+
+func TestPatch_Rejects(t *testing.T) {
+	mgr, err := newManager(testMasterKey, nil, "machine-1", t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := mgr.Patch([]byte(`{"log_level": "debug"}`)); err == nil || errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected an unloaded config to fail, got %v", err)
+	}
+	if _, err := mgr.Load(); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+
+	for name, patch := range map[string]string{
+		"not an object": `["log_level"]`,
+		"unknown key":   `{"colour": "red"}`,
+		"read-only key": `{"encrypted": false}`,
+		"wrong type":    `{"port": "eighty"}`,
+		"invalid":       `{"server_url": "https://pos.example.com", "store_id": "s", "log_level": "loud"}`,
+	} {
+		if _, err := mgr.Patch([]byte(patch)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+	if cfg, _ := mgr.Get(); cfg.LogLevel == "loud" {
+		t.Error("A rejected patch must not change the config")
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// Secrets vault names of the config fields kept out of config.enc
+const (
+	SecretStoreToken          = "store_token"
+	SecretAPISecret           = "api_secret"
+	SecretMQTTPassword        = "mqtt_password"
+	SecretReportEmailPassword = "report_email_password"
+	SecretWebhookSecret       = "webhook_secret"
+	SecretHubToken            = "hub_token"
+)
+
+// SecretNames lists the vault names of the config's secrets
+func SecretNames() []string {
+	return []string{SecretStoreToken, SecretAPISecret, SecretMQTTPassword, SecretReportEmailPassword, SecretWebhookSecret, SecretHubToken}
+}
+
+// secretFields returns the config fields held in the secrets vault by name
+func secretFields(c *Config) map[string]*string {
+	return map[string]*string{
+		SecretStoreToken:          &c.StoreToken,
+		SecretAPISecret:           &c.APISecret,
+		SecretMQTTPassword:        &c.MQTTPassword,
+		SecretReportEmailPassword: &c.ReportEmail.Password,
+		SecretWebhookSecret:       &c.WebhookSecret,
+		SecretHubToken:            &c.HubToken,
+	}
+}
+
+// redactedSecret stands in for a set secret in Redacted
+const redactedSecret = "[redacted]"
+
+// Redacted returns the loaded config as indented JSON with its secrets
+// masked, for diagnostics bundles. An unset secret stays empty, so support
+// can tell a missing one from a wrong one.
+func (m *Manager) Redacted() ([]byte, error) {
+	cfg, err := m.Get()
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range secretFields(cfg) {
+		if *field != "" {
+			*field = redactedSecret
+		}
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
+
+// UseVault moves the config's secrets into vault. Secrets in the vault
+// fill the loaded config; secrets still in config.enc (written before the
+// vault) are moved over and the file is saved without them. A read-only
+// vault (env) only fills in what it holds.
+func (m *Manager) UseVault(vault security.Vault) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.vault = vault
+	if m.config == nil {
+		return nil
+	}
+
+	m.config.mu.Lock()
+	moved := false
+	for name, field := range secretFields(m.config) {
+		secret, err := vault.Get(name)
+		if err == nil {
+			*field = string(secret)
+			continue
+		}
+		if !errors.Is(err, security.ErrSecretNotFound) {
+			m.config.mu.Unlock()
+			return fmt.Errorf("failed to read %s from vault: %w", name, err)
+		}
+		if *field != "" {
+			moved = true
+		}
+	}
+	m.config.mu.Unlock()
+
+	if !moved {
+		return nil
+	}
+	return m.save(m.config)
+}
+
+// Vault returns the secrets vault, or nil before UseVault
+func (m *Manager) Vault() security.Vault {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.vault
+}
+
+// storeSecrets writes config's secrets to the vault and blanks those the
+// vault holds in fileConfig, the copy written to config.enc. Without a
+// vault secrets stay in the file. The caller holds m.mu.
+func (m *Manager) storeSecrets(config, fileConfig *Config) error {
+	if m.vault == nil {
+		return nil
+	}
+
+	fileFields := secretFields(fileConfig)
+	for name, field := range secretFields(config) {
+		var err error
+		if *field == "" {
+			err = m.vault.Delete(name)
+		} else {
+			err = m.vault.Put(name, []byte(*field))
+		}
+
+		if errors.Is(err, security.ErrVaultReadOnly) {
+			// Keep a secret in the file unless the vault supplies it
+			stored, getErr := m.vault.Get(name)
+			if getErr != nil || string(stored) != *field {
+				continue
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to store %s in vault: %w", name, err)
+		}
+		*fileFields[name] = ""
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestUseVault_MovesSecretsOutOfConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	mgr, err := newManager(testMasterKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Written before the vault existed: the token sits in config.enc
+	cfg.StoreID = "store-7"
+	cfg.StoreToken = "enrolment-token"
+	if err := mgr.Save(cfg); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	vault, err := security.NewVault(constants.SecretVaultFile, filepath.Join(dir, "keys"), mgr.Encryption())
+	if err != nil {
+		t.Fatalf("Failed to open vault: %v", err)
+	}
+	if err := mgr.UseVault(vault); err != nil {
+		t.Fatalf("UseVault failed: %v", err)
+	}
+
+	if token, err := vault.Get(SecretStoreToken); err != nil || string(token) != "enrolment-token" {
+		t.Fatalf("Expected the token moved to the vault, got %q (%v)", token, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, constants.ConfigFileName))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	plaintext, err := mgr.Encryption().Decrypt(string(data))
+	if err != nil {
+		t.Fatalf("Failed to decrypt config: %v", err)
+	}
+	if strings.Contains(string(plaintext), "enrolment-token") {
+		t.Errorf("Config file still holds the token: %s", plaintext)
+	}
+
+	// A fresh load gets the token back from the vault
+	reloaded, err := newManager(testMasterKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfg, err = reloaded.Load()
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if cfg.GetStoreToken() != "" {
+		t.Errorf("Expected no token before the vault is attached")
+	}
+	if err := reloaded.UseVault(vault); err != nil {
+		t.Fatalf("UseVault failed: %v", err)
+	}
+	if cfg.GetStoreToken() != "enrolment-token" || cfg.GetStoreID() != "store-7" {
+		t.Errorf("Unexpected config after reload: %s / %s", cfg.GetStoreID(), cfg.GetStoreToken())
+	}
+}
+
+func TestUseVault_EnvSuppliesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(security.EnvSecretPrefix+"MQTT_PASSWORD", "from-env")
+
+	mgr, err := newManager(testMasterKey, nil, "machine-1", dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.StoreID = "store-7"
+	cfg.APISecret = "kept-in-file-0123456789abcdef0123"
+
+	vault, err := security.NewVault(constants.SecretVaultEnv, "")
+	if err != nil {
+		t.Fatalf("Failed to open vault: %v", err)
+	}
+	if err := mgr.UseVault(vault); err != nil {
+		t.Fatalf("UseVault failed: %v", err)
+	}
+	if cfg.MQTTPassword != "from-env" {
+		t.Errorf("Expected the MQTT password from the environment, got %q", cfg.MQTTPassword)
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, constants.ConfigFileName))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	plaintext, err := mgr.Encryption().Decrypt(string(data))
+	if err != nil {
+		t.Fatalf("Failed to decrypt config: %v", err)
+	}
+	if strings.Contains(string(plaintext), "from-env") {
+		t.Errorf("Secrets from the environment must not be written to the file")
+	}
+	if !strings.Contains(string(plaintext), "kept-in-file") {
+		t.Errorf("Secrets the read-only vault lacks must stay in the file")
+	}
+}
+
+func TestRedacted_MasksSecrets(t *testing.T) {
+	mgr, err := newManager(testMasterKey, nil, "machine-1", t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.StoreID = "store-7"
+	cfg.APISecret = "0123456789abcdef0123456789abcdef"
+	cfg.ReportEmail.Password = "smtp-password"
+	if err := mgr.Save(cfg); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	data, err := mgr.Redacted()
+	if err != nil {
+		t.Fatalf("Redacted failed: %v", err)
+	}
+	text := string(data)
+	if strings.Contains(text, cfg.APISecret) || strings.Contains(text, "smtp-password") {
+		t.Fatalf("Redacted config holds a secret: %s", text)
+	}
+	if !strings.Contains(text, `"api_secret": "[redacted]"`) || !strings.Contains(text, `"store_token": ""`) || !strings.Contains(text, `"store-7"`) {
+		t.Errorf("Unexpected redacted config: %s", text)
+	}
+
+	// The loaded config keeps its secrets
+	if loaded, _ := mgr.Get(); loaded.APISecret != cfg.APISecret {
+		t.Errorf("Redacted changed the loaded config")
+	}
+}
@@ -0,0 +1,352 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Audit events recorded outside device trails
+const (
+	AuditAuthFailure   = "auth.failure"        // A token, API key or secret was rejected
+	AuditAuthLockout   = "auth.lockout"        // A source was locked out after repeated failures
+	AuditSafeMode      = "service.safe_mode"   // The service started in safe mode after a crash loop
+	AuditAdminPassword = "auth.admin_password" // The admin password was set or changed
+	AuditPINSignIn     = "auth.pin_sign_in"    // A cashier signed in with their PIN
+)
+
+// The audit log is hash-chained: each entry's hash covers the previous
+// entry's hash, its event and its plaintext, so editing, reordering or
+// deleting an entry breaks every later hash. Deleting the newest entries
+// leaves a valid chain, so the head is anchored to head office through the
+// outbox, and an anchored entry that is gone or changed is detected too.
+
+// AuditEntry is one security event in the audit log
+type AuditEntry struct {
+	ID     int64  `json:"id"`
+	Event  string `json:"event"`
+	Source string `json:"source,omitempty"` // Remote address the event came from
+	Detail string `json:"detail,omitempty"`
+	At     string `json:"at"`             // ISO 8601 timestamp
+	Hash   string `json:"hash,omitempty"` // Chain hash (hex SHA-256), not part of the hashed data
+}
+
+// AuditAnchor records the head of the audit chain for head office
+type AuditAnchor struct {
+	ID         string `json:"id"`  // Seq as a string
+	Seq        int64  `json:"seq"` // ID of the head entry
+	Hash       string `json:"hash"`
+	AnchoredAt string `json:"anchored_at"` // ISO 8601 timestamp
+}
+
+// AuditVerification is the outcome of walking the audit chain
+type AuditVerification struct {
+	OK       bool   `json:"ok"`
+	Entries  int    `json:"entries"`             // Entries checked
+	HeadSeq  int64  `json:"head_seq,omitempty"`  // ID of the newest entry
+	HeadHash string `json:"head_hash,omitempty"` // Its chain hash
+	BrokenAt int64  `json:"broken_at,omitempty"` // First entry whose hash does not match
+	Detail   string `json:"detail,omitempty"`
+}
+
+// auditHash chains an entry's event and plaintext onto the previous hash
+func auditHash(prevHash, event string, plaintext []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(event))
+	h.Write([]byte{'\n'})
+	h.Write(plaintext)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditHead returns the ID and hash of the newest audit entry within tx
+func auditHead(tx *sql.Tx) (int64, string, error) {
+	var seq int64
+	var hash string
+	err := tx.QueryRow("SELECT id, hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&seq, &hash)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read audit head: %w", err)
+	}
+	return seq, hash, nil
+}
+
+// RecordAudit appends an entry to the audit log, chaining it to the newest
+// entry. Offline mode does not stop it.
+func (db *DB) RecordAudit(entry *AuditEntry) error {
+	entry.At = time.Now().UTC().Format(time.RFC3339)
+	entry.Hash = ""
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("audit_log", entry.Event))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt audit entry: %w", err)
+	}
+
+	return db.sessionTransaction(func(tx *sql.Tx) error {
+		_, prevHash, err := auditHead(tx)
+		if err != nil {
+			return err
+		}
+		hash := auditHash(prevHash, entry.Event, jsonData)
+
+		result, err := tx.Exec("INSERT INTO audit_log (event, data, hash) VALUES (?, ?, ?)", entry.Event, encryptedData, hash)
+		if err != nil {
+			return fmt.Errorf("failed to insert audit entry: %w", err)
+		}
+		entry.ID, _ = result.LastInsertId()
+		entry.Hash = hash
+		return nil
+	})
+}
+
+// ListAudit returns the most recent audit entries, newest first
+func (db *DB) ListAudit(limit int) ([]AuditEntry, error) {
+	return db.ListAuditPage(0, limit)
+}
+
+// ListAuditPage returns up to limit audit entries, newest first, skipping
+// the offset most recent ones
+func (db *DB) ListAuditPage(offset, limit int) ([]AuditEntry, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT id, event, data, hash FROM audit_log ORDER BY id DESC LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var id int64
+		var event, encryptedData, hash string
+		if err := rows.Scan(&id, &event, &encryptedData, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("audit_log", event))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt audit entry: %w", err)
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(jsonData, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit entry: %w", err)
+		}
+		entry.ID = id
+		entry.Hash = hash
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// CountAudit returns the number of audit entries
+func (db *DB) CountAudit() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM audit_log").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	return count, nil
+}
+
+// VerifyAudit walks the audit chain from the first entry, recomputing every
+// hash, and checks the last anchored head is still part of it
+func (db *DB) VerifyAudit() (*AuditVerification, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	result := &AuditVerification{OK: true}
+
+	rows, err := db.conn.Query("SELECT id, event, data, hash FROM audit_log ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[int64]string)
+	prevHash := ""
+	for rows.Next() {
+		var id int64
+		var event, encryptedData, hash string
+		if err := rows.Scan(&id, &event, &encryptedData, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		result.Entries++
+
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("audit_log", event))
+		if err != nil {
+			result.OK, result.BrokenAt, result.Detail = false, id, "entry cannot be decrypted"
+			return result, nil
+		}
+		if auditHash(prevHash, event, jsonData) != hash {
+			result.OK, result.BrokenAt, result.Detail = false, id, "hash does not match the chain"
+			return result, nil
+		}
+		hashes[id] = hash
+		prevHash = hash
+		result.HeadSeq, result.HeadHash = id, hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	anchor, err := db.lastAuditAnchor()
+	if err != nil {
+		return nil, err
+	}
+	if anchor != nil && hashes[anchor.Seq] != anchor.Hash {
+		result.OK, result.BrokenAt = false, anchor.Seq
+		result.Detail = "anchored entry is missing or changed"
+	}
+
+	return result, nil
+}
+
+// lastAuditAnchor returns the newest anchor, or nil; the caller holds db.mu
+func (db *DB) lastAuditAnchor() (*AuditAnchor, error) {
+	var id, encryptedData string
+	err := db.conn.QueryRow("SELECT id, data FROM audit_anchors ORDER BY seq DESC LIMIT 1").Scan(&id, &encryptedData)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit anchor: %w", err)
+	}
+
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("audit_anchors", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt audit anchor: %w", err)
+	}
+	var anchor AuditAnchor
+	if err := json.Unmarshal(jsonData, &anchor); err != nil {
+		return nil, fmt.Errorf("failed to parse audit anchor: %w", err)
+	}
+	return &anchor, nil
+}
+
+// errNothingToAnchor ends AnchorAudit's transaction when the head is anchored
+var errNothingToAnchor = errors.New("audit head already anchored")
+
+// AnchorAudit records the current head of the audit chain, reaching head
+// office through the outbox. It returns nil without anchoring when the head
+// is already anchored or the log is empty.
+func (db *DB) AnchorAudit() (*AuditAnchor, error) {
+	var anchor *AuditAnchor
+	err := db.Transaction(func(tx *sql.Tx) error {
+		seq, hash, err := auditHead(tx)
+		if err != nil {
+			return err
+		}
+		if seq == 0 {
+			return errNothingToAnchor
+		}
+		var anchored int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM audit_anchors WHERE seq = ?", seq).Scan(&anchored); err != nil {
+			return fmt.Errorf("failed to check audit anchor: %w", err)
+		}
+		if anchored > 0 {
+			return errNothingToAnchor
+		}
+
+		anchor = &AuditAnchor{
+			ID:         strconv.FormatInt(seq, 10),
+			Seq:        seq,
+			Hash:       hash,
+			AnchoredAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		jsonData, err := json.Marshal(anchor)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit anchor: %w", err)
+		}
+		encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("audit_anchors", anchor.ID))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt audit anchor: %w", err)
+		}
+		if _, err := tx.Exec("INSERT INTO audit_anchors (id, seq, data) VALUES (?, ?, ?)", anchor.ID, seq, encryptedData); err != nil {
+			return fmt.Errorf("failed to insert audit anchor: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingToAnchor) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return anchor, nil
+}
+
+// RunAuditAnchor anchors the audit chain's head every interval until ctx
+// is cancelled, so the anchor rides along with the next sync
+func (db *DB) RunAuditAnchor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := db.AnchorAudit(); err != nil && !errors.Is(err, ErrReadOnly) {
+				log.Printf("Warning: failed to anchor audit log: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// chainAuditLog hashes audit entries written before the log was chained
+func (db *DB) chainAuditLog() error {
+	rows, err := db.conn.Query("SELECT id, event, data, hash FROM audit_log ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to query audit log: %w", err)
+	}
+
+	type link struct {
+		id   int64
+		hash string
+	}
+	var links []link
+	prevHash := ""
+	for rows.Next() {
+		var id int64
+		var event, encryptedData, hash string
+		if err := rows.Scan(&id, &event, &encryptedData, &hash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("audit_log", event))
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decrypt audit entry %d: %w", id, err)
+		}
+		prevHash = auditHash(prevHash, event, jsonData)
+		links = append(links, link{id, prevHash})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range links {
+		if _, err := db.conn.Exec("UPDATE audit_log SET hash = ? WHERE id = ?", l.hash, l.id); err != nil {
+			return fmt.Errorf("failed to chain audit entry %d: %w", l.id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"testing"
+)
+
+// recordAudits appends n entries to the audit log
+func recordAudits(t *testing.T, db *DB, n int) {
+	for i := 0; i < n; i++ {
+		if err := db.RecordAudit(&AuditEntry{Event: AuditAuthFailure, Source: "10.0.0.9", Detail: "invalid token"}); err != nil {
+			t.Fatalf("RecordAudit failed: %v", err)
+		}
+	}
+}
+
+func TestAudit_ChainDetectsTampering(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	recordAudits(t, db, 3)
+
+	result, err := db.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit failed: %v", err)
+	}
+	if !result.OK || result.Entries != 3 || result.HeadSeq != 3 || result.HeadHash == "" {
+		t.Fatalf("Expected an intact chain of 3, got %+v", result)
+	}
+
+	entries, err := db.ListAudit(10)
+	if err != nil {
+		t.Fatalf("ListAudit failed: %v", err)
+	}
+	if entries[0].Hash != result.HeadHash {
+		t.Errorf("Expected the newest entry to carry the head hash")
+	}
+
+	// Deleting an entry breaks the next one's hash
+	if _, err := db.conn.Exec("DELETE FROM audit_log WHERE id = 2"); err != nil {
+		t.Fatalf("Failed to tamper: %v", err)
+	}
+	result, err = db.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit failed: %v", err)
+	}
+	if result.OK || result.BrokenAt != 3 {
+		t.Errorf("Expected the chain broken at 3, got %+v", result)
+	}
+}
+
+func TestAudit_AnchorDetectsTruncation(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if anchor, err := db.AnchorAudit(); err != nil || anchor != nil {
+		t.Fatalf("Expected nothing to anchor in an empty log, got %v (%v)", anchor, err)
+	}
+
+	recordAudits(t, db, 2)
+	anchor, err := db.AnchorAudit()
+	if err != nil || anchor == nil || anchor.Seq != 2 {
+		t.Fatalf("Expected an anchor at 2, got %+v (%v)", anchor, err)
+	}
+	if again, err := db.AnchorAudit(); err != nil || again != nil {
+		t.Errorf("Expected the anchored head not to be anchored again, got %+v (%v)", again, err)
+	}
+
+	// The anchor reaches head office through the outbox
+	entries, err := db.GetPendingOutbox(100)
+	if err != nil {
+		t.Fatalf("GetPendingOutbox failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Entity != "audit_anchors" || entries[0].EntityID != "2" {
+		t.Fatalf("Expected the anchor in the outbox, got %+v", entries)
+	}
+
+	// Dropping the newest entry leaves a valid chain, but not the anchor
+	if _, err := db.conn.Exec("DELETE FROM audit_log WHERE id = 2"); err != nil {
+		t.Fatalf("Failed to tamper: %v", err)
+	}
+	result, err := db.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit failed: %v", err)
+	}
+	if result.OK || result.BrokenAt != 2 {
+		t.Errorf("Expected the missing anchored entry detected, got %+v", result)
+	}
+}
+
+func TestAudit_ChainsEntriesWrittenBeforeChaining(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	recordAudits(t, db, 3)
+	if _, err := db.conn.Exec("UPDATE audit_log SET hash = ''"); err != nil {
+		t.Fatalf("Failed to unchain: %v", err)
+	}
+
+	if err := db.chainAuditLog(); err != nil {
+		t.Fatalf("chainAuditLog failed: %v", err)
+	}
+	result, err := db.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit failed: %v", err)
+	}
+	if !result.OK || result.Entries != 3 {
+		t.Errorf("Expected the backfilled chain to verify, got %+v", result)
+	}
+}
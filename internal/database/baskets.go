@@ -0,0 +1,199 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBasketNotFound is returned when no basket matches a lookup
+	ErrBasketNotFound = errors.New("basket not found")
+
+	// ErrBasketTooLarge is returned when an append would exceed the line cap
+	ErrBasketTooLarge = errors.New("basket line limit exceeded")
+
+	// ErrInvalidBasketLine is returned for lines without a SKU or quantity
+	ErrInvalidBasketLine = errors.New("invalid basket line")
+)
+
+// BasketTotals is the running summary of a basket, maintained per chunk
+type BasketTotals struct {
+	BasketID   string `json:"basket_id"`
+	TerminalID string `json:"terminal_id"`
+	LineCount  int    `json:"line_count"`
+	Quantity   int    `json:"quantity"`
+	Total      int64  `json:"total"` // Minor currency units
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// AppendBasketLines adds a chunk of lines to a basket, creating it on the
+// first chunk, and returns the updated totals. Totals are adjusted by the
+// chunk's delta so the cost of an append does not grow with the basket.
+// maxLines caps the basket size (0 means unlimited).
+func (db *DB) AppendBasketLines(basketID, terminalID string, lines []SaleLine, maxLines int) (*BasketTotals, error) {
+	var chunkQuantity int
+	var chunkTotal int64
+	for i, line := range lines {
+		if line.SKU == "" || line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: line %d must have a sku and positive quantity", ErrInvalidBasketLine, i)
+		}
+		chunkQuantity += line.Quantity
+		chunkTotal += int64(line.Quantity) * line.Price
+	}
+
+	// Encrypt outside the transaction to keep the write lock short
+	encrypted := make([]string, len(lines))
+	for i := range lines {
+		jsonData, err := json.Marshal(lines[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal basket line: %w", err)
+		}
+		if encrypted[i], err = db.encryption.EncryptWithAAD(jsonData, rowAAD("basket_lines", basketID)); err != nil {
+			return nil, fmt.Errorf("failed to encrypt basket line: %w", err)
+		}
+	}
+
+	var totals *BasketTotals
+	err := db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"INSERT INTO baskets (id, terminal_id) VALUES (?, ?) ON CONFLICT(id) DO NOTHING",
+			basketID, terminalID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create basket: %w", err)
+		}
+
+		var owner string
+		var lineCount int
+		if err := tx.QueryRow("SELECT terminal_id, line_count FROM baskets WHERE id = ?", basketID).Scan(&owner, &lineCount); err != nil {
+			return fmt.Errorf("failed to read basket: %w", err)
+		}
+		if owner != terminalID {
+			// Basket IDs are client-generated; never let one terminal extend another's
+			return ErrBasketNotFound
+		}
+		if maxLines > 0 && lineCount+len(lines) > maxLines {
+			return fmt.Errorf("%w: %d lines, limit %d", ErrBasketTooLarge, lineCount+len(lines), maxLines)
+		}
+
+		stmt, err := tx.Prepare("INSERT INTO basket_lines (basket_id, seq, data) VALUES (?, ?, ?)")
+		if err != nil {
+			return fmt.Errorf("failed to prepare basket line insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for i, data := range encrypted {
+			if _, err := stmt.Exec(basketID, lineCount+i, data); err != nil {
+				return fmt.Errorf("failed to insert basket line: %w", err)
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE baskets SET
+				line_count = line_count + ?,
+				quantity = quantity + ?,
+				total = total + ?,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, len(lines), chunkQuantity, chunkTotal, basketID)
+		if err != nil {
+			return fmt.Errorf("failed to update basket totals: %w", err)
+		}
+
+		totals, err = scanBasketTotals(tx.QueryRow(basketTotalsQuery, basketID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+const basketTotalsQuery = `
+	SELECT id, terminal_id, line_count, quantity, total,
+		strftime('%Y-%m-%dT%H:%M:%SZ', updated_at)
+	FROM baskets WHERE id = ?
+`
+
+// scanBasketTotals reads one basket totals row
+func scanBasketTotals(row *sql.Row) (*BasketTotals, error) {
+	var t BasketTotals
+	err := row.Scan(&t.BasketID, &t.TerminalID, &t.LineCount, &t.Quantity, &t.Total, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrBasketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query basket: %w", err)
+	}
+	return &t, nil
+}
+
+// GetBasketTotals returns the running totals of a basket
+func (db *DB) GetBasketTotals(basketID string) (*BasketTotals, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return scanBasketTotals(db.conn.QueryRow(basketTotalsQuery, basketID))
+}
+
+// GetBasketLines returns up to limit lines starting at offset, in scan order.
+// A limit of 0 returns every line from offset.
+func (db *DB) GetBasketLines(basketID string, offset, limit int) ([]SaleLine, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	rows, err := db.conn.Query(
+		"SELECT data FROM basket_lines WHERE basket_id = ? ORDER BY seq LIMIT ? OFFSET ?",
+		basketID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query basket lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []SaleLine
+	for rows.Next() {
+		var encryptedData string
+		if err := rows.Scan(&encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan basket line: %w", err)
+		}
+
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("basket_lines", basketID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt basket line: %w", err)
+		}
+
+		var line SaleLine
+		if err := json.Unmarshal(jsonData, &line); err != nil {
+			return nil, fmt.Errorf("failed to parse basket line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// DeleteBasket removes a basket and its lines (after checkout or abandon)
+func (db *DB) DeleteBasket(basketID string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM basket_lines WHERE basket_id = ?", basketID); err != nil {
+			return fmt.Errorf("failed to delete basket lines: %w", err)
+		}
+
+		result, err := tx.Exec("DELETE FROM baskets WHERE id = ?", basketID)
+		if err != nil {
+			return fmt.Errorf("failed to delete basket: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrBasketNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAppendBasketLines_ChunkedTotals(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// 600 lines in chunks of 200, as a wholesale client would send them
+	for chunk := 0; chunk < 3; chunk++ {
+		lines := make([]SaleLine, 200)
+		for i := range lines {
+			lines[i] = SaleLine{SKU: fmt.Sprintf("SKU-%d-%d", chunk, i), Quantity: 3, Price: 100}
+		}
+		totals, err := db.AppendBasketLines("order-1", "T1", lines, 1000)
+		if err != nil {
+			t.Fatalf("Append chunk %d failed: %v", chunk, err)
+		}
+		if totals.LineCount != (chunk+1)*200 {
+			t.Errorf("Expected %d lines, got %d", (chunk+1)*200, totals.LineCount)
+		}
+	}
+
+	totals, err := db.GetBasketTotals("order-1")
+	if err != nil {
+		t.Fatalf("GetBasketTotals failed: %v", err)
+	}
+	if totals.Quantity != 1800 || totals.Total != 180000 {
+		t.Errorf("Unexpected totals: %+v", totals)
+	}
+
+	page, err := db.GetBasketLines("order-1", 200, 10)
+	if err != nil {
+		t.Fatalf("GetBasketLines failed: %v", err)
+	}
+	if len(page) != 10 || page[0].SKU != "SKU-1-0" {
+		t.Errorf("Expected second chunk in scan order, got %+v", page)
+	}
+
+	all, _ := db.GetBasketLines("order-1", 0, 0)
+	if len(all) != 600 {
+		t.Errorf("Expected 600 lines, got %d", len(all))
+	}
+}
+
+func TestAppendBasketLines_Limits(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	lines := []SaleLine{{SKU: "A", Quantity: 1, Price: 100}, {SKU: "B", Quantity: 1, Price: 100}}
+	if _, err := db.AppendBasketLines("order-1", "T1", lines, 3); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	if _, err := db.AppendBasketLines("order-1", "T1", lines, 3); !errors.Is(err, ErrBasketTooLarge) {
+		t.Errorf("Expected ErrBasketTooLarge, got %v", err)
+	}
+	if _, err := db.AppendBasketLines("order-1", "T2", lines[:1], 3); !errors.Is(err, ErrBasketNotFound) {
+		t.Errorf("Expected another terminal's basket to be hidden, got %v", err)
+	}
+	if _, err := db.AppendBasketLines("order-1", "T1", []SaleLine{{SKU: "C"}}, 3); !errors.Is(err, ErrInvalidBasketLine) {
+		t.Errorf("Expected ErrInvalidBasketLine, got %v", err)
+	}
+
+	// Rejected chunks leave the totals untouched
+	totals, _ := db.GetBasketTotals("order-1")
+	if totals.LineCount != 2 || totals.Total != 200 {
+		t.Errorf("Unexpected totals after rejected chunks: %+v", totals)
+	}
+
+	if err := db.DeleteBasket("order-1"); err != nil {
+		t.Fatalf("DeleteBasket failed: %v", err)
+	}
+	if _, err := db.GetBasketTotals("order-1"); !errors.Is(err, ErrBasketNotFound) {
+		t.Errorf("Expected ErrBasketNotFound after delete, got %v", err)
+	}
+}
+
+func TestVoidBasket_RecordsAndDeletes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	lines := []SaleLine{{SKU: "A", Quantity: 2, Price: 150}, {SKU: "B", Quantity: 1, Price: 100}}
+	if _, err := db.AppendBasketLines("order-1", "T1", lines, 0); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	before, _ := db.CountPendingOutbox()
+
+	void := &TransactionVoid{ID: "order-1", Reason: "customer left"}
+	if err := db.VoidBasket(void); err != nil {
+		t.Fatalf("VoidBasket failed: %v", err)
+	}
+	if void.TerminalID != "T1" || void.LineCount != 2 || void.Quantity != 3 || void.Total != 400 {
+		t.Errorf("Void not built from the basket totals: %+v", void)
+	}
+
+	if _, err := db.GetBasketTotals("order-1"); !errors.Is(err, ErrBasketNotFound) {
+		t.Errorf("Expected the basket deleted, got %v", err)
+	}
+	stored, err := db.GetVoid("order-1")
+	if err != nil || stored.Reason != "customer left" {
+		t.Errorf("GetVoid returned %+v, %v", stored, err)
+	}
+	if after, _ := db.CountPendingOutbox(); after != before+1 {
+		t.Errorf("Expected the void in the outbox, pending %d -> %d", before, after)
+	}
+
+	if err := db.VoidBasket(&TransactionVoid{ID: "order-1"}); !errors.Is(err, ErrBasketNotFound) {
+		t.Errorf("Expected ErrBasketNotFound voiding again, got %v", err)
+	}
+}
+
+func TestSaleValidate_Tenders(t *testing.T) {
+	lines := []SaleLine{{SKU: "A", Quantity: 1, Price: 1000}}
+	tests := []struct {
+		name    string
+		typ     string
+		tenders []Tender
+		wantErr bool
+	}{
+		{"exact card", "", []Tender{{Method: TenderCard, Amount: 1000}}, false},
+		{"cash with change", "", []Tender{{Method: TenderCash, Amount: 2000}}, false},
+		{"split", "", []Tender{{Method: TenderVoucher, Amount: 300}, {Method: TenderCard, Amount: 700}}, false},
+		{"short", "", []Tender{{Method: TenderCard, Amount: 900}}, true},
+		{"card overpaid", "", []Tender{{Method: TenderCard, Amount: 1500}}, true},
+		{"unknown method", "", []Tender{{Method: "barter", Amount: 1000}}, true},
+		{"refund exact", SaleTypeRefund, []Tender{{Method: TenderCash, Amount: 1000}}, false},
+		{"refund overpaid", SaleTypeRefund, []Tender{{Method: TenderCash, Amount: 1200}}, true},
+	}
+	for _, tt := range tests {
+		sale := &Sale{ID: "S1", Type: tt.typ, Lines: lines, Total: 1000, Tenders: tt.tenders}
+		if err := sale.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	sale := &Sale{ID: "S1", Lines: lines, Total: 1000, Tenders: []Tender{{Method: TenderCash, Amount: 2000}}}
+	if change := sale.Change(); change != 1000 {
+		t.Errorf("Expected change 1000, got %d", change)
+	}
+}
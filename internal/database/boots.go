@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Shutdown reasons recorded in the boot history
+const (
+	ShutdownStopped = "stopped" // The service was stopped
+	ShutdownCrash   = "crash"   // No shutdown was recorded: crash, kill or power loss
+)
+
+// Boot is one start of the service. The boot history holds no business
+// data, so it is stored unencrypted.
+type Boot struct {
+	ID            int64  `json:"id"` // Boot counter; never reused
+	Version       string `json:"version"`
+	BootedAt      string `json:"booted_at"`             // ISO 8601 timestamp
+	AliveAt       string `json:"alive_at"`              // Last heartbeat
+	ShutdownAt    string `json:"shutdown_at,omitempty"` // Empty for crashes and the running boot
+	Reason        string `json:"reason,omitempty"`      // Empty for the running boot
+	UptimeSeconds int64  `json:"uptime_seconds"`        // Until shutdown, or the last heartbeat
+}
+
+// bootColumns selects a boot with timestamps rendered as ISO 8601
+const bootColumns = `id, version,
+	strftime('%Y-%m-%dT%H:%M:%SZ', booted_at),
+	strftime('%Y-%m-%dT%H:%M:%SZ', alive_at),
+	COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', shutdown_at), ''),
+	reason,
+	CAST(strftime('%s', COALESCE(shutdown_at, alive_at)) - strftime('%s', booted_at) AS INTEGER)`
+
+// RecordBoot starts a boot for version. Earlier boots that never recorded
+// a shutdown are closed as crashes, and boots older than keep are pruned.
+func (db *DB) RecordBoot(version string, keep time.Duration) (*Boot, error) {
+	if db.refusesWrites() {
+		return nil, ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.conn.Exec("UPDATE boots SET reason = ? WHERE reason = ''", ShutdownCrash); err != nil {
+		return nil, fmt.Errorf("failed to close previous boots: %w", err)
+	}
+
+	cutoff := time.Now().Add(-keep).UTC().Format(sqliteTimestampFormat)
+	if _, err := db.conn.Exec("DELETE FROM boots WHERE booted_at < ?", cutoff); err != nil {
+		return nil, fmt.Errorf("failed to prune boots: %w", err)
+	}
+
+	now := time.Now().UTC().Format(sqliteTimestampFormat)
+	result, err := db.conn.Exec("INSERT INTO boots (version, booted_at, alive_at) VALUES (?, ?, ?)", version, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record boot: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get boot ID: %w", err)
+	}
+
+	return scanBoot(db.conn.QueryRow("SELECT "+bootColumns+" FROM boots WHERE id = ?", id))
+}
+
+// TouchBoot records that a boot is still running
+func (db *DB) TouchBoot(id int64) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC().Format(sqliteTimestampFormat)
+	if _, err := db.conn.Exec("UPDATE boots SET alive_at = ? WHERE id = ? AND reason = ''", now, id); err != nil {
+		return fmt.Errorf("failed to touch boot: %w", err)
+	}
+	return nil
+}
+
+// RecordShutdown closes a boot with reason
+func (db *DB) RecordShutdown(id int64, reason string) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC().Format(sqliteTimestampFormat)
+	_, err := db.conn.Exec(
+		"UPDATE boots SET alive_at = ?, shutdown_at = ?, reason = ? WHERE id = ? AND reason = ''",
+		now, now, reason, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record shutdown: %w", err)
+	}
+	return nil
+}
+
+// CountCrashes returns the boots since since that ended in a crash. Called
+// before RecordBoot, boots still open count too, as RecordBoot will close
+// them as crashes.
+func (db *DB) CountCrashes(since time.Time) (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	err := db.conn.QueryRow(
+		"SELECT COUNT(*) FROM boots WHERE booted_at >= ? AND reason IN (?, '')",
+		since.UTC().Format(sqliteTimestampFormat), ShutdownCrash,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count crashes: %w", err)
+	}
+	return count, nil
+}
+
+// ListBoots returns the boot history, newest first
+func (db *DB) ListBoots() ([]Boot, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT " + bootColumns + " FROM boots ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query boots: %w", err)
+	}
+	defer rows.Close()
+
+	boots := make([]Boot, 0)
+	for rows.Next() {
+		boot, err := scanBoot(rows)
+		if err != nil {
+			return nil, err
+		}
+		boots = append(boots, *boot)
+	}
+
+	return boots, rows.Err()
+}
+
+// BootCount returns how many times the service has started. IDs are never
+// reused, so pruned boots still count.
+func (db *DB) BootCount() (int64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int64
+	err := db.conn.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM sqlite_sequence WHERE name = 'boots'").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count boots: %w", err)
+	}
+	return count, nil
+}
+
+// RunBootHeartbeat touches boot id every interval until ctx is cancelled,
+// bounding the uptime of a boot that ends in a crash
+func (db *DB) RunBootHeartbeat(ctx context.Context, id int64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := db.TouchBoot(id); err != nil {
+				log.Printf("Warning: boot heartbeat failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scanBoot reads a boot selected with bootColumns
+func scanBoot(row rowScanner) (*Boot, error) {
+	var boot Boot
+	err := row.Scan(&boot.ID, &boot.Version, &boot.BootedAt, &boot.AliveAt, &boot.ShutdownAt, &boot.Reason, &boot.UptimeSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan boot: %w", err)
+	}
+	return &boot, nil
+}
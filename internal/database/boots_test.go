@@ -0,0 +1,96 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoots_History(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	keep := 90 * 24 * time.Hour
+
+	first, err := db.RecordBoot("1.0.0", keep)
+	if err != nil {
+		t.Fatalf("RecordBoot failed: %v", err)
+	}
+	if err := db.RecordShutdown(first.ID, ShutdownStopped); err != nil {
+		t.Fatalf("RecordShutdown failed: %v", err)
+	}
+
+	// The second boot never shuts down, as in a crash
+	if _, err := db.RecordBoot("1.0.0", keep); err != nil {
+		t.Fatalf("RecordBoot failed: %v", err)
+	}
+	current, err := db.RecordBoot("1.1.0", keep)
+	if err != nil {
+		t.Fatalf("RecordBoot failed: %v", err)
+	}
+	if current.Version != "1.1.0" || current.Reason != "" || current.BootedAt == "" {
+		t.Errorf("Unexpected current boot %+v", current)
+	}
+
+	boots, err := db.ListBoots()
+	if err != nil {
+		t.Fatalf("ListBoots failed: %v", err)
+	}
+	if len(boots) != 3 {
+		t.Fatalf("Expected 3 boots, got %d", len(boots))
+	}
+	if boots[0].ID != current.ID || boots[1].Reason != ShutdownCrash || boots[2].Reason != ShutdownStopped {
+		t.Errorf("Unexpected history %+v", boots)
+	}
+	if boots[2].ShutdownAt == "" || boots[1].ShutdownAt != "" {
+		t.Errorf("Only the stopped boot should have a shutdown time: %+v", boots)
+	}
+
+	// Pruning drops old boots but not the count
+	if _, err := db.conn.Exec("UPDATE boots SET booted_at = '2000-01-01 00:00:00' WHERE id < ?", current.ID); err != nil {
+		t.Fatalf("Failed to age boots: %v", err)
+	}
+	if _, err := db.RecordBoot("1.1.0", keep); err != nil {
+		t.Fatalf("RecordBoot failed: %v", err)
+	}
+	boots, err = db.ListBoots()
+	if err != nil {
+		t.Fatalf("ListBoots failed: %v", err)
+	}
+	if len(boots) != 2 {
+		t.Errorf("Expected old boots pruned, got %d boots", len(boots))
+	}
+
+	count, err := db.BootCount()
+	if err != nil {
+		t.Fatalf("BootCount failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 boots counted, got %d", count)
+	}
+}
+
+func TestBoots_CountCrashes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	keep := 90 * 24 * time.Hour
+	since := time.Now().Add(-time.Hour)
+
+	stopped, _ := db.RecordBoot("1.0.0", keep)
+	db.RecordShutdown(stopped.ID, ShutdownStopped)
+	db.RecordBoot("1.0.0", keep) // crashes
+	db.RecordBoot("1.0.0", keep) // still open, as when counted at the next start
+
+	crashes, err := db.CountCrashes(since)
+	if err != nil {
+		t.Fatalf("CountCrashes failed: %v", err)
+	}
+	if crashes != 2 {
+		t.Errorf("Expected 2 crashes, got %d", crashes)
+	}
+
+	// Crashes before the window are not counted
+	if crashes, _ := db.CountCrashes(time.Now().Add(time.Minute)); crashes != 0 {
+		t.Errorf("Expected no crashes in the future window, got %d", crashes)
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Busy retry tuning. PRAGMA busy_timeout already waits inside SQLite; this
+// adds a second layer that releases the DB mutex between attempts so a long
+// sync write cannot turn a frontend request into a spurious failure.
+const (
+	busyRetryInitialDelay = 10 * time.Millisecond
+	busyRetryMaxDelay     = 500 * time.Millisecond
+
+	// busyRetryBudget bounds retries when the caller's context has no deadline
+	busyRetryBudget = 10 * time.Second
+)
+
+// RetryStats reports busy/locked retry activity
+type RetryStats struct {
+	Retries   int64 `json:"retries"`   // Attempts repeated after a busy error
+	Recovered int64 `json:"recovered"` // Operations that succeeded after retrying
+	Exhausted int64 `json:"exhausted"` // Operations that gave up while still busy
+}
+
+// retryCounters holds the live retry counters
+type retryCounters struct {
+	retries   atomic.Int64
+	recovered atomic.Int64
+	exhausted atomic.Int64
+}
+
+// IsBusy reports whether err is a transient SQLITE_BUSY/SQLITE_LOCKED condition
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "sqlite_busy") ||
+		strings.Contains(msg, "sqlite_locked")
+}
+
+// RetryStats returns a snapshot of the busy retry counters
+func (db *DB) RetryStats() RetryStats {
+	return RetryStats{
+		Retries:   db.retries.retries.Load(),
+		Recovered: db.retries.recovered.Load(),
+		Exhausted: db.retries.exhausted.Load(),
+	}
+}
+
+// withBusyRetry runs fn, retrying with jittered exponential backoff while it
+// fails with a busy error. It stops at the caller's deadline (or the default
+// budget) and returns the last error. fn must not hold db.mu across calls.
+func (db *DB) withBusyRetry(ctx context.Context, fn func() error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, busyRetryBudget)
+		defer cancel()
+	}
+
+	delay := busyRetryInitialDelay
+	retried := false
+
+	for {
+		err := fn()
+		if !IsBusy(err) {
+			if err == nil && retried {
+				db.retries.recovered.Add(1)
+			}
+			return err
+		}
+
+		// Full jitter keeps competing writers from retrying in lockstep
+		wait := time.Duration(rand.Int63n(int64(delay))) + time.Millisecond
+
+		select {
+		case <-ctx.Done():
+			db.retries.exhausted.Add(1)
+			return fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		db.retries.retries.Add(1)
+		retried = true
+
+		if delay *= 2; delay > busyRetryMaxDelay {
+			delay = busyRetryMaxDelay
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBusy = errors.New("database is locked (5) (SQLITE_BUSY)")
+
+func TestIsBusy(t *testing.T) {
+	if !IsBusy(errBusy) {
+		t.Error("Expected SQLITE_BUSY to be detected")
+	}
+	if IsBusy(errors.New("no such table: foo")) || IsBusy(nil) {
+		t.Error("Expected non-busy errors to be ignored")
+	}
+}
+
+func TestWithBusyRetry_Recovers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	attempts := 0
+	err := db.withBusyRetry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return errBusy
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected recovery, got %v", err)
+	}
+
+	stats := db.RetryStats()
+	if stats.Retries != 2 || stats.Recovered != 1 || stats.Exhausted != 0 {
+		t.Errorf("Unexpected retry stats: %+v", stats)
+	}
+}
+
+func TestWithBusyRetry_RespectsDeadline(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := db.withBusyRetry(ctx, func() error { return errBusy })
+	if !IsBusy(err) {
+		t.Fatalf("Expected busy error after deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Retry ignored the deadline (took %v)", elapsed)
+	}
+	if db.RetryStats().Exhausted != 1 {
+		t.Errorf("Expected exhausted count 1, got %d", db.RetryStats().Exhausted)
+	}
+}
+
+func TestWithBusyRetry_DoesNotRetryOtherErrors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	attempts := 0
+	wantErr := errors.New("constraint failed")
+	err := db.withBusyRetry(context.Background(), func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) || attempts != 1 {
+		t.Errorf("Expected single attempt returning %v, got %d attempts and %v", wantErr, attempts, err)
+	}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	// ErrDayClosed is returned when a terminal's day has already been closed
+	ErrDayClosed = errors.New("day already closed")
+
+	// ErrNotBackup is returned when a file to restore is not a database
+	ErrNotBackup = errors.New("not a database backup")
+)
+
+// restoreSuffix names the copy of a backup StageRestore leaves next to the
+// database file for New to swap in
+const restoreSuffix = ".restore"
+
+// sqliteHeader starts every SQLite database file
+const sqliteHeader = "SQLite format 3\x00"
+
+// lastBackupKey is the setting recording when Backup last succeeded
+const lastBackupKey = "backup.last_at"
+
+// ClosingItem is the outcome of one day-close checklist item
+type ClosingItem struct {
+	Name   string `json:"name"`
+	Met    bool   `json:"met"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DayClosing is a terminal's day-close report, captured into the outbox
+// for head office
+type DayClosing struct {
+	ID         string        `json:"id"` // <day>/<terminal>
+	Day        string        `json:"day"`
+	TerminalID string        `json:"terminal_id"`
+	Items      []ClosingItem `json:"items"`
+	Overridden bool          `json:"overridden,omitempty"`  // Closed with unmet items on manager approval
+	ApprovedBy string        `json:"approved_by,omitempty"` // Label of the approving manager's token
+	ClosedAt   string        `json:"closed_at"`             // ISO 8601 timestamp
+}
+
+// DayClosingID returns the ID of a terminal's closing for day
+func DayClosingID(day, terminalID string) string {
+	return day + "/" + terminalID
+}
+
+// RecordDayClosing stores a day-close report. A second close of the same
+// terminal and day fails with ErrDayClosed.
+func (db *DB) RecordDayClosing(closing *DayClosing) error {
+	if closing.ClosedAt == "" {
+		closing.ClosedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	closing.ID = DayClosingID(closing.Day, closing.TerminalID)
+
+	jsonData, err := json.Marshal(closing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal day closing: %w", err)
+	}
+
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("day_closings", closing.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt day closing: %w", err)
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"INSERT INTO day_closings (id, data) VALUES (?, ?) ON CONFLICT(id) DO NOTHING",
+			closing.ID, encryptedData,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert day closing: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrDayClosed
+		}
+		return nil
+	})
+}
+
+// DayClosed reports whether a terminal's day has been closed
+func (db *DB) DayClosed(day, terminalID string) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM day_closings WHERE id = ?", DayClosingID(day, terminalID)).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check day closing: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// CountOpenBaskets returns the number of carts not yet checked out or
+// deleted
+func (db *DB) CountOpenBaskets() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM baskets").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count baskets: %w", err)
+	}
+
+	return count, nil
+}
+
+// Backup writes a consistent copy of the database to dest (which must not
+// exist) and records the time for the day-close checklist
+func (db *DB) Backup(dest string) error {
+	db.mu.RLock()
+	_, err := db.conn.Exec("VACUUM INTO ?", dest)
+	db.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	if err := db.SetSetting(lastBackupKey, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to record backup: %w", err)
+	}
+	return nil
+}
+
+// StageRestore copies the backup at src next to the database file. The
+// running database is left alone: the next New swaps the copy in, so the
+// restore takes effect when the service restarts.
+func (db *DB) StageRestore(src string) error {
+	if db.IsInMemory() {
+		return errors.New("in-memory databases cannot be restored")
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer in.Close()
+
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(in, header); err != nil || string(header) != sqliteHeader {
+		return ErrNotBackup
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	// Copy under a temporary name so New never sees half a backup
+	staged := db.dbPath + restoreSuffix
+	out, err := os.OpenFile(staged+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(staged + ".tmp")
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+	if err := os.Rename(staged+".tmp", staged); err != nil {
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+	return nil
+}
+
+// applyStagedRestore swaps a backup staged by StageRestore in for the
+// database file at path, dropping the replaced file's WAL and shared memory
+func applyStagedRestore(path string) error {
+	staged := path + restoreSuffix
+	if _, err := os.Stat(staged); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check for a staged restore: %w", err)
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+	}
+	if err := os.Rename(staged, path); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	return nil
+}
+
+// LastBackup returns when Backup last succeeded; ok is false if never
+func (db *DB) LastBackup() (at time.Time, ok bool, err error) {
+	value, err := db.GetSetting(lastBackupKey)
+	if errors.Is(err, ErrSettingNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	at, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse last backup time: %w", err)
+	}
+	return at, true, nil
+}
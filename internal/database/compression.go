@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// compressExisting rewrites stored ciphertexts larger than threshold so
+// values written before compression was enabled (or while the threshold was
+// higher) are compressed too. It runs from New and only when threshold is
+// lower than the one recorded by the previous run; raising the threshold
+// needs no rewrite, since compressed values stay readable.
+func (db *DB) compressExisting(threshold int) error {
+	if threshold <= 0 {
+		// Values written from now on are uncompressed; forget the last run
+		// so enabling compression again rewrites them
+		if _, err := db.conn.Exec("UPDATE compression_state SET threshold = 0 WHERE id = 1"); err != nil {
+			return fmt.Errorf("failed to record compression state: %w", err)
+		}
+		return nil
+	}
+
+	var applied int
+	if err := db.conn.QueryRow("SELECT threshold FROM compression_state WHERE id = 1").Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read compression state: %w", err)
+	}
+	if applied > 0 && applied <= threshold {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 1 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to suppress change capture: %w", err)
+	}
+
+	for _, ec := range encryptedColumns {
+		// A ciphertext is always longer than its plaintext, so shorter
+		// ones cannot hold a value above the threshold
+		large := ec
+		large.where = fmt.Sprintf("length(%s) > %d", ec.column, threshold)
+		if ec.where != "" {
+			large.where = ec.where + " AND " + large.where
+		}
+
+		if err := reencryptColumn(context.Background(), tx, large, db.encryption.DecryptWithAAD, db.encryption.EncryptWithAAD, func(int) {}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 0 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to restore change capture: %w", err)
+	}
+	// Recorded in the same transaction so a crash simply reruns the pass
+	if _, err := tx.Exec("UPDATE compression_state SET threshold = ? WHERE id = 1", threshold); err != nil {
+		return fmt.Errorf("failed to record compression state: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit compression migration: %w", err)
+	}
+	return nil
+}
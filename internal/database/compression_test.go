@@ -0,0 +1,57 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func TestCompressExisting_RewritesLargeValues(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+	template := strings.Repeat("<line>Thank you for shopping</line>\n", 200)
+
+	db, err := New(&Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if err := db.SetSetting("receipt.template", template); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if err := db.SetSetting("store.name", "Corner Shop"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	before := storedSetting(t, db, "receipt.template")
+	db.Close()
+
+	db, err = New(&Config{ServerKey: serverKey, DataDir: dir, CompressAbove: 1024})
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	after := storedSetting(t, db, "receipt.template")
+	if len(after) >= len(before)/2 {
+		t.Errorf("Expected the template to be compressed, %d -> %d bytes", len(before), len(after))
+	}
+	if small := storedSetting(t, db, "store.name"); strings.HasPrefix(small, "z:") {
+		t.Error("Expected a small setting to stay uncompressed")
+	}
+
+	if value, err := db.GetSetting("receipt.template"); err != nil || value != template {
+		t.Errorf("Compressed setting unreadable: %v", err)
+	}
+	if pending, _ := db.CountPendingOutbox(); pending != 0 {
+		t.Errorf("Migration queued %d changes for sync", pending)
+	}
+}
+
+// storedSetting returns a setting's ciphertext as stored
+func storedSetting(t *testing.T, db *DB, key string) string {
+	var value string
+	if err := db.GetConnection().QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value); err != nil {
+		t.Fatalf("Failed to read setting %s: %v", key, err)
+	}
+	return value
+}
@@ -0,0 +1,383 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCustomerNotFound is returned when no customer matches a lookup
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	// ErrInsufficientPoints is returned when a redemption exceeds the balance
+	ErrInsufficientPoints = errors.New("insufficient loyalty points")
+)
+
+// Loyalty entry kinds
+const (
+	LoyaltyAccrual    = "accrual"
+	LoyaltyRedemption = "redemption"
+)
+
+// Customer is a loyalty customer synced from head office or imported
+// locally. LoyaltyPoints is the balance head office reported with the record.
+type Customer struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Phone         string `json:"phone,omitempty"`
+	Email         string `json:"email,omitempty"`
+	LoyaltyCard   string `json:"loyalty_card,omitempty"`
+	LoyaltyPoints int64  `json:"loyalty_points"`
+	UpdatedAt     string `json:"updated_at"` // ISO 8601 timestamp
+}
+
+// LoyaltyEntry records points earned or spent on this terminal. Entries are
+// append-only and reach head office through the outbox, so entries from
+// every terminal merge there into the customer's balance.
+type LoyaltyEntry struct {
+	ID         string `json:"id"` // Client-generated, makes retries idempotent
+	CustomerID string `json:"customer_id"`
+	Kind       string `json:"kind"`   // LoyaltyAccrual or LoyaltyRedemption
+	Points     int64  `json:"points"` // Positive; redemptions are subtracted
+	SaleID     string `json:"sale_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	CreatedAt  string `json:"created_at"` // ISO 8601 timestamp
+}
+
+// signed returns the entry's effect on the balance
+func (e *LoyaltyEntry) signed() int64 {
+	if e.Kind == LoyaltyRedemption {
+		return -e.Points
+	}
+	return e.Points
+}
+
+// LoyaltyBalance is a customer's balance: what head office last reported
+// plus the local entries it had not yet received
+type LoyaltyBalance struct {
+	CustomerID    string `json:"customer_id"`
+	SyncedPoints  int64  `json:"synced_points"`  // Balance reported by head office
+	PendingPoints int64  `json:"pending_points"` // Net local entries not yet reconciled
+	LoyaltyPoints int64  `json:"loyalty_points"` // Spendable balance
+}
+
+// --- Customer Methods ---
+
+// ApplyCustomers applies server-delivered customers and deletions without
+// feeding them back into the outbox. Local entries head office has received
+// by now are included in its balance and stop counting as pending, so apply
+// customers after acknowledging the outbox.
+func (db *DB) ApplyCustomers(customers []Customer, deletedIDs []string) error {
+	return db.ApplySync(func(tx *sql.Tx) error {
+		for i := range customers {
+			customer := &customers[i]
+			if customer.ID == "" {
+				return fmt.Errorf("customer id is required")
+			}
+			if customer.UpdatedAt == "" {
+				customer.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+			}
+
+			jsonData, err := json.Marshal(customer)
+			if err != nil {
+				return fmt.Errorf("failed to marshal customer: %w", err)
+			}
+			encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("customers", customer.ID))
+			if err != nil {
+				return fmt.Errorf("failed to encrypt customer: %w", err)
+			}
+
+			_, err = tx.Exec(`
+				INSERT INTO customers (id, data, loyalty_points, updated_at)
+				VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+				ON CONFLICT(id) DO UPDATE SET
+					data = excluded.data,
+					loyalty_points = excluded.loyalty_points,
+					updated_at = CURRENT_TIMESTAMP
+			`, customer.ID, encryptedData, customer.LoyaltyPoints)
+			if err != nil {
+				return fmt.Errorf("failed to upsert customer %s: %w", customer.ID, err)
+			}
+
+			_, err = tx.Exec(`
+				UPDATE loyalty_entries SET reconciled = 1
+				WHERE customer_id = ? AND reconciled = 0 AND id NOT IN (
+					SELECT entity_id FROM outbox WHERE entity = 'loyalty_entries' AND synced_at IS NULL
+				)
+			`, customer.ID)
+			if err != nil {
+				return fmt.Errorf("failed to reconcile loyalty entries of %s: %w", customer.ID, err)
+			}
+		}
+
+		for _, id := range deletedIDs {
+			if _, err := tx.Exec("DELETE FROM customers WHERE id = ?", id); err != nil {
+				return fmt.Errorf("failed to delete customer %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ImportCustomers stores a batch of locally imported customers in one
+// transaction, captured into the sync outbox for head office. Loyalty
+// balances stay head office's: an existing customer keeps its balance and
+// a new one starts at zero.
+func (db *DB) ImportCustomers(customers []Customer) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		for i := range customers {
+			customer := &customers[i]
+			if customer.ID == "" {
+				return fmt.Errorf("customer id is required")
+			}
+			customer.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+			err := tx.QueryRow("SELECT loyalty_points FROM customers WHERE id = ?", customer.ID).Scan(&customer.LoyaltyPoints)
+			if err == sql.ErrNoRows {
+				customer.LoyaltyPoints = 0
+			} else if err != nil {
+				return fmt.Errorf("failed to query customer %s: %w", customer.ID, err)
+			}
+
+			jsonData, err := json.Marshal(customer)
+			if err != nil {
+				return fmt.Errorf("failed to marshal customer: %w", err)
+			}
+			encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("customers", customer.ID))
+			if err != nil {
+				return fmt.Errorf("failed to encrypt customer: %w", err)
+			}
+
+			_, err = tx.Exec(`
+				INSERT INTO customers (id, data, loyalty_points, updated_at)
+				VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+				ON CONFLICT(id) DO UPDATE SET
+					data = excluded.data,
+					updated_at = CURRENT_TIMESTAMP
+			`, customer.ID, encryptedData, customer.LoyaltyPoints)
+			if err != nil {
+				return fmt.Errorf("failed to upsert customer %s: %w", customer.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetCustomer retrieves a customer by ID (decrypts automatically)
+func (db *DB) GetCustomer(id string) (*Customer, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var encryptedData string
+	err := db.conn.QueryRow("SELECT data FROM customers WHERE id = ?", id).Scan(&encryptedData)
+	if err == sql.ErrNoRows {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query customer: %w", err)
+	}
+
+	return db.decryptCustomer(id, encryptedData)
+}
+
+// FindCustomers returns the customers whose name, phone, email or loyalty
+// card contains query (case-insensitive), ordered by name. Customer records
+// are encrypted, so every record is decrypted and matched in memory.
+func (db *DB) FindCustomers(query string) ([]Customer, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT id, data FROM customers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query customers: %w", err)
+	}
+	defer rows.Close()
+
+	query = strings.ToLower(query)
+	customers := []Customer{}
+	for rows.Next() {
+		var id, encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan customer: %w", err)
+		}
+
+		customer, err := db.decryptCustomer(id, encryptedData)
+		if err != nil {
+			return nil, err
+		}
+		if query == "" ||
+			strings.Contains(strings.ToLower(customer.Name), query) ||
+			strings.Contains(customer.Phone, query) ||
+			strings.Contains(strings.ToLower(customer.Email), query) ||
+			strings.Contains(strings.ToLower(customer.LoyaltyCard), query) {
+			customers = append(customers, *customer)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read customers: %w", err)
+	}
+
+	sort.Slice(customers, func(i, j int) bool {
+		if customers[i].Name != customers[j].Name {
+			return customers[i].Name < customers[j].Name
+		}
+		return customers[i].ID < customers[j].ID
+	})
+	return customers, nil
+}
+
+// decryptCustomer decrypts and parses a customer record
+func (db *DB) decryptCustomer(id, encryptedData string) (*Customer, error) {
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("customers", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt customer: %w", err)
+	}
+
+	var customer Customer
+	if err := json.Unmarshal(jsonData, &customer); err != nil {
+		return nil, fmt.Errorf("failed to parse customer: %w", err)
+	}
+	return &customer, nil
+}
+
+// --- Loyalty Methods ---
+
+// GetLoyaltyBalance returns a customer's spendable balance
+func (db *DB) GetLoyaltyBalance(customerID string) (*LoyaltyBalance, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return loyaltyBalance(db.conn, customerID)
+}
+
+// loyaltyQuerier is satisfied by *sql.DB and *sql.Tx
+type loyaltyQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// loyaltyBalance computes a balance with q
+func loyaltyBalance(q loyaltyQuerier, customerID string) (*LoyaltyBalance, error) {
+	balance := &LoyaltyBalance{CustomerID: customerID}
+
+	err := q.QueryRow("SELECT loyalty_points FROM customers WHERE id = ?", customerID).Scan(&balance.SyncedPoints)
+	if err == sql.ErrNoRows {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query customer balance: %w", err)
+	}
+
+	err = q.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN kind = ? THEN -points ELSE points END), 0)
+		FROM loyalty_entries WHERE customer_id = ? AND reconciled = 0
+	`, LoyaltyRedemption, customerID).Scan(&balance.PendingPoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum pending loyalty entries: %w", err)
+	}
+
+	balance.LoyaltyPoints = balance.SyncedPoints + balance.PendingPoints
+	return balance, nil
+}
+
+// RecordLoyalty stores a loyalty entry and returns the new balance. It is
+// idempotent on the entry ID: a retry stores nothing and applied is false.
+// A redemption larger than the balance fails with ErrInsufficientPoints.
+func (db *DB) RecordLoyalty(entry *LoyaltyEntry) (balance *LoyaltyBalance, applied bool, err error) {
+	if entry.ID == "" || entry.CustomerID == "" || entry.Points <= 0 {
+		return nil, false, fmt.Errorf("loyalty entry id, customer and positive points are required")
+	}
+	if entry.Kind != LoyaltyAccrual && entry.Kind != LoyaltyRedemption {
+		return nil, false, fmt.Errorf("invalid loyalty entry kind: %s", entry.Kind)
+	}
+	if entry.CreatedAt == "" {
+		entry.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal loyalty entry: %w", err)
+	}
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("loyalty_entries", entry.ID))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encrypt loyalty entry: %w", err)
+	}
+
+	err = db.Transaction(func(tx *sql.Tx) error {
+		applied = false
+		current, err := loyaltyBalance(tx, entry.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM loyalty_entries WHERE id = ?", entry.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check loyalty entry: %w", err)
+		}
+		if exists > 0 {
+			balance = current
+			return nil
+		}
+		if current.LoyaltyPoints+entry.signed() < 0 {
+			return ErrInsufficientPoints
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO loyalty_entries (id, customer_id, kind, points, data) VALUES (?, ?, ?, ?, ?)",
+			entry.ID, entry.CustomerID, entry.Kind, entry.Points, encryptedData,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert loyalty entry: %w", err)
+		}
+
+		current.PendingPoints += entry.signed()
+		current.LoyaltyPoints += entry.signed()
+		balance, applied = current, true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return balance, applied, nil
+}
+
+// ListLoyaltyEntries returns up to limit of a customer's local entries,
+// newest first
+func (db *DB) ListLoyaltyEntries(customerID string, limit int) ([]LoyaltyEntry, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query(
+		"SELECT id, data FROM loyalty_entries WHERE customer_id = ? ORDER BY created_at DESC, id DESC LIMIT ?",
+		customerID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loyalty entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []LoyaltyEntry{}
+	for rows.Next() {
+		var id, encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan loyalty entry: %w", err)
+		}
+
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("loyalty_entries", id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt loyalty entry: %w", err)
+		}
+
+		var entry LoyaltyEntry
+		if err := json.Unmarshal(jsonData, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse loyalty entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoyalty_RecordAndReconcile(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.ApplyCustomers([]Customer{{ID: "C1", Name: "Dilnoza", Phone: "+998901234567", LoyaltyPoints: 100}}, nil); err != nil {
+		t.Fatalf("ApplyCustomers failed: %v", err)
+	}
+
+	balance, applied, err := db.RecordLoyalty(&LoyaltyEntry{ID: "L1", CustomerID: "C1", Kind: LoyaltyAccrual, Points: 30})
+	if err != nil || !applied || balance.LoyaltyPoints != 130 || balance.PendingPoints != 30 {
+		t.Fatalf("Accrual returned %+v, %v, %v", balance, applied, err)
+	}
+	if _, applied, _ := db.RecordLoyalty(&LoyaltyEntry{ID: "L1", CustomerID: "C1", Kind: LoyaltyAccrual, Points: 30}); applied {
+		t.Error("Expected a retried entry not to apply twice")
+	}
+	if _, _, err := db.RecordLoyalty(&LoyaltyEntry{ID: "L2", CustomerID: "C1", Kind: LoyaltyRedemption, Points: 500}); !errors.Is(err, ErrInsufficientPoints) {
+		t.Errorf("Expected ErrInsufficientPoints, got %v", err)
+	}
+	if _, _, err := db.RecordLoyalty(&LoyaltyEntry{ID: "L3", CustomerID: "missing", Kind: LoyaltyAccrual, Points: 1}); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("Expected ErrCustomerNotFound, got %v", err)
+	}
+
+	// A balance applied before the entry was uploaded leaves it pending
+	db.ApplyCustomers([]Customer{{ID: "C1", Name: "Dilnoza", LoyaltyPoints: 100}}, nil)
+	if balance, _ := db.GetLoyaltyBalance("C1"); balance.LoyaltyPoints != 130 {
+		t.Errorf("Expected the unsent entry still pending, got %+v", balance)
+	}
+
+	// Once head office acknowledged it, its balance includes the entry
+	entries, _ := db.GetPendingOutbox(100)
+	var ids []int64
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	db.MarkOutboxSynced(ids)
+	db.ApplyCustomers([]Customer{{ID: "C1", Name: "Dilnoza", LoyaltyPoints: 130}}, nil)
+	if balance, _ := db.GetLoyaltyBalance("C1"); balance.LoyaltyPoints != 130 || balance.PendingPoints != 0 {
+		t.Errorf("Expected the entry reconciled, got %+v", balance)
+	}
+}
+
+func TestFindCustomers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.ApplyCustomers([]Customer{
+		{ID: "C1", Name: "Bobur", Phone: "+998901111111", LoyaltyCard: "LC-001"},
+		{ID: "C2", Name: "Aziza", Email: "aziza@example.com", LoyaltyCard: "LC-002"},
+	}, nil)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 2},
+		{"aziza", 1},
+		{"901111", 1},
+		{"lc-00", 2},
+		{"nobody", 0},
+	}
+	for _, tt := range tests {
+		customers, err := db.FindCustomers(tt.query)
+		if err != nil {
+			t.Fatalf("FindCustomers(%q) failed: %v", tt.query, err)
+		}
+		if len(customers) != tt.want {
+			t.Errorf("FindCustomers(%q) found %d, want %d", tt.query, len(customers), tt.want)
+		}
+	}
+	if customers, _ := db.FindCustomers(""); customers[0].Name != "Aziza" {
+		t.Errorf("Expected customers ordered by name, got %+v", customers)
+	}
+}
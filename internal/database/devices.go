@@ -0,0 +1,344 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrDeviceNotFound is returned when no device matches a lookup
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrDeviceExists is returned when registering an ID already in use
+	ErrDeviceExists = errors.New("device already registered")
+
+	// ErrLabelRequestNotFound is returned when no label request matches a lookup
+	ErrLabelRequestNotFound = errors.New("label request not found")
+)
+
+// Device is a registered handheld (stock-taking scanner)
+type Device struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	SecretHash   string `json:"-"`                      // SHA-256 of the enrollment secret
+	RegisteredAt string `json:"registered_at"`          // ISO 8601 timestamp
+	LastSeenAt   string `json:"last_seen_at,omitempty"` // Last token exchange
+}
+
+// DeviceAuditEntry records one request made by a device
+type DeviceAuditEntry struct {
+	ID       int64  `json:"id"`
+	DeviceID string `json:"device_id"`
+	Action   string `json:"action"` // e.g. "POST /stock/:sku/adjust"
+	Status   int    `json:"status"` // HTTP status returned
+	Detail   string `json:"detail,omitempty"`
+	At       string `json:"at"` // ISO 8601 timestamp
+}
+
+// StockAdjustment is one movement in a SKU's stock log: a delivery
+// received, a correction or a physical count (see RecordStockMovements)
+type StockAdjustment struct {
+	ID        string `json:"id"`             // Client-generated, makes retries idempotent
+	Kind      string `json:"kind,omitempty"` // StockMovement*; empty means an adjustment
+	SKU       string `json:"sku"`
+	Delta     int    `json:"delta"`
+	Counted   *int   `json:"counted,omitempty"`   // Quantity found by a count
+	Reference string `json:"reference,omitempty"` // Delivery note or purchase order of a receipt
+	Reason    string `json:"reason,omitempty"`    // e.g. "count", "damaged"
+	DeviceID  string `json:"device_id,omitempty"`
+	CreatedAt string `json:"created_at"` // ISO 8601 timestamp
+}
+
+// LabelRequest asks the back office to print shelf labels for a SKU
+type LabelRequest struct {
+	ID        int64  `json:"id"`
+	DeviceID  string `json:"device_id"`
+	SKU       string `json:"sku"`
+	Copies    int    `json:"copies"`
+	CreatedAt string `json:"created_at"`
+	PrintedAt string `json:"printed_at,omitempty"`
+}
+
+// --- Device Registry Methods ---
+
+// RegisterDevice stores a new device
+func (db *DB) RegisterDevice(device *Device) error {
+	device.RegisteredAt = time.Now().UTC().Format(time.RFC3339)
+
+	jsonData, err := json.Marshal(device)
+	if err != nil {
+		return fmt.Errorf("failed to marshal device: %w", err)
+	}
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("devices", device.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt device: %w", err)
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"INSERT INTO devices (id, data, secret_hash) VALUES (?, ?, ?) ON CONFLICT(id) DO NOTHING",
+			device.ID, encryptedData, device.SecretHash,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert device: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrDeviceExists
+		}
+		return nil
+	})
+}
+
+// GetDevice retrieves a device by ID (decrypts automatically)
+func (db *DB) GetDevice(id string) (*Device, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	device, err := db.scanDevice(db.conn.QueryRow(
+		"SELECT id, data, secret_hash, COALESCE(last_seen_at, '') FROM devices WHERE id = ?", id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceNotFound
+	}
+	return device, err
+}
+
+// ListDevices returns all registered devices ordered by ID
+func (db *DB) ListDevices() ([]Device, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT id, data, secret_hash, COALESCE(last_seen_at, '') FROM devices ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := make([]Device, 0)
+	for rows.Next() {
+		device, err := db.scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	return devices, rows.Err()
+}
+
+// TouchDevice records that a device was just seen
+func (db *DB) TouchDevice(id string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE devices SET last_seen_at = ? WHERE id = ?",
+			time.Now().UTC().Format(sqliteTimestampFormat), id)
+		if err != nil {
+			return fmt.Errorf("failed to update device: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteDevice unregisters a device. Its audit trail is kept.
+func (db *DB) DeleteDevice(id string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM devices WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete device: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrDeviceNotFound
+		}
+		return nil
+	})
+}
+
+// scanDevice decodes an (id, data, secret_hash, last_seen_at) row
+func (db *DB) scanDevice(row rowScanner) (*Device, error) {
+	var id, encryptedData, secretHash, lastSeen string
+	if err := row.Scan(&id, &encryptedData, &secretHash, &lastSeen); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan device: %w", err)
+	}
+
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("devices", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt device: %w", err)
+	}
+
+	var device Device
+	if err := json.Unmarshal(jsonData, &device); err != nil {
+		return nil, fmt.Errorf("failed to parse device: %w", err)
+	}
+	device.SecretHash = secretHash
+	device.LastSeenAt = lastSeen
+	return &device, nil
+}
+
+// --- Device Audit Methods ---
+
+// RecordDeviceAudit appends an entry to a device's audit trail, in offline
+// mode too
+func (db *DB) RecordDeviceAudit(entry *DeviceAuditEntry) error {
+	entry.At = time.Now().UTC().Format(time.RFC3339)
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("device_audit", entry.DeviceID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt audit entry: %w", err)
+	}
+
+	return db.sessionTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO device_audit (device_id, data) VALUES (?, ?)", entry.DeviceID, encryptedData); err != nil {
+			return fmt.Errorf("failed to insert audit entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListDeviceAudit returns a device's most recent audit entries, newest first
+func (db *DB) ListDeviceAudit(deviceID string, limit int) ([]DeviceAuditEntry, error) {
+	return db.ListDeviceAuditPage(deviceID, 0, limit)
+}
+
+// ListDeviceAuditPage returns up to limit of a device's audit entries,
+// newest first, skipping the offset most recent ones
+func (db *DB) ListDeviceAuditPage(deviceID string, offset, limit int) ([]DeviceAuditEntry, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query(
+		"SELECT id, data FROM device_audit WHERE device_id = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		deviceID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]DeviceAuditEntry, 0)
+	for rows.Next() {
+		var id int64
+		var encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("device_audit", deviceID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt audit entry: %w", err)
+		}
+		var entry DeviceAuditEntry
+		if err := json.Unmarshal(jsonData, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit entry: %w", err)
+		}
+		entry.ID = id
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// CountDeviceAudit returns the number of a device's audit entries
+func (db *DB) CountDeviceAudit(deviceID string) (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM device_audit WHERE device_id = ?", deviceID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count device audit entries: %w", err)
+	}
+	return count, nil
+}
+
+// --- Stock Adjustment Methods ---
+
+// AdjustStock applies a counted correction and returns the new on-hand
+// quantity. It is idempotent on the adjustment ID: a retry returns the
+// current level without moving stock again. Adjustments reach the outbox
+// through CDC triggers.
+func (db *DB) AdjustStock(adj *StockAdjustment) (quantity int, applied bool, err error) {
+	if adj.ID == "" || adj.SKU == "" || adj.Delta == 0 {
+		return 0, false, fmt.Errorf("adjustment id, sku and non-zero delta are required")
+	}
+	adj.Kind = StockMovementAdjustment
+	adj.Counted = nil
+
+	levels, appliedCount, err := db.RecordStockMovements([]*StockAdjustment{adj})
+	if err != nil {
+		return 0, false, err
+	}
+	return levels[adj.SKU], appliedCount > 0, nil
+}
+
+// --- Label Request Methods ---
+
+// RequestLabels queues a shelf label print request
+func (db *DB) RequestLabels(req *LabelRequest) error {
+	if req.SKU == "" || req.Copies < 1 {
+		return fmt.Errorf("label request needs a sku and at least one copy")
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"INSERT INTO label_requests (device_id, sku, copies) VALUES (?, ?, ?)",
+			req.DeviceID, req.SKU, req.Copies,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert label request: %w", err)
+		}
+		req.ID, err = result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to read label request id: %w", err)
+		}
+		req.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		return nil
+	})
+}
+
+// ListPendingLabels returns label requests not yet printed, oldest first
+func (db *DB) ListPendingLabels() ([]LabelRequest, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query(
+		"SELECT id, device_id, sku, copies, created_at FROM label_requests WHERE printed_at IS NULL ORDER BY id",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query label requests: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make([]LabelRequest, 0)
+	for rows.Next() {
+		var req LabelRequest
+		if err := rows.Scan(&req.ID, &req.DeviceID, &req.SKU, &req.Copies, &req.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan label request: %w", err)
+		}
+		labels = append(labels, req)
+	}
+
+	return labels, rows.Err()
+}
+
+// MarkLabelsPrinted removes a label request from the pending queue
+func (db *DB) MarkLabelsPrinted(id int64) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"UPDATE label_requests SET printed_at = CURRENT_TIMESTAMP WHERE id = ? AND printed_at IS NULL", id,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update label request: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrLabelRequestNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDevices_RegisterGetDelete(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	device := &Device{ID: "HH-01", Name: "Aisle scanner", SecretHash: "abc"}
+	if err := db.RegisterDevice(device); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	if err := db.RegisterDevice(&Device{ID: "HH-01"}); !errors.Is(err, ErrDeviceExists) {
+		t.Errorf("Expected ErrDeviceExists, got %v", err)
+	}
+
+	if err := db.TouchDevice("HH-01"); err != nil {
+		t.Fatalf("TouchDevice failed: %v", err)
+	}
+	got, err := db.GetDevice("HH-01")
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if got.Name != "Aisle scanner" || got.SecretHash != "abc" || got.LastSeenAt == "" {
+		t.Errorf("Unexpected device: %+v", got)
+	}
+
+	if devices, _ := db.ListDevices(); len(devices) != 1 {
+		t.Errorf("Expected 1 device, got %d", len(devices))
+	}
+
+	if err := db.DeleteDevice("HH-01"); err != nil {
+		t.Fatalf("DeleteDevice failed: %v", err)
+	}
+	if _, err := db.GetDevice("HH-01"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestDeviceAudit_NewestFirst(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, action := range []string{"GET /products/:barcode", "POST /stock/:sku/adjust"} {
+		if err := db.RecordDeviceAudit(&DeviceAuditEntry{DeviceID: "HH-01", Action: action, Status: 200}); err != nil {
+			t.Fatalf("RecordDeviceAudit failed: %v", err)
+		}
+	}
+	db.RecordDeviceAudit(&DeviceAuditEntry{DeviceID: "HH-02", Action: "POST /labels", Status: 200})
+
+	entries, err := db.ListDeviceAudit("HH-01", 10)
+	if err != nil {
+		t.Fatalf("ListDeviceAudit failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "POST /stock/:sku/adjust" {
+		t.Errorf("Expected 2 entries newest first, got %+v", entries)
+	}
+}
+
+func TestAdjustStock_IdempotentAndCaptured(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetStockLevel("MILK", 10)
+	before, _ := db.CountPendingOutbox()
+
+	adj := &StockAdjustment{ID: "adj-1", SKU: "MILK", Delta: -3, Reason: "damaged", DeviceID: "HH-01"}
+	quantity, applied, err := db.AdjustStock(adj)
+	if err != nil {
+		t.Fatalf("AdjustStock failed: %v", err)
+	}
+	if !applied || quantity != 7 {
+		t.Errorf("Expected applied adjustment to 7, got %d (applied=%v)", quantity, applied)
+	}
+
+	// A retry doesn't move stock again
+	quantity, applied, _ = db.AdjustStock(adj)
+	if applied || quantity != 7 {
+		t.Errorf("Expected retry to be a no-op at 7, got %d (applied=%v)", quantity, applied)
+	}
+
+	if after, _ := db.CountPendingOutbox(); after != before+1 {
+		t.Errorf("Expected one outbox entry for the adjustment, got %d", after-before)
+	}
+
+	if _, _, err := db.AdjustStock(&StockAdjustment{ID: "adj-2", SKU: "MILK"}); err == nil {
+		t.Error("Expected error for a zero delta")
+	}
+}
+
+func TestLabelRequests_Queue(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	req := &LabelRequest{DeviceID: "HH-01", SKU: "MILK", Copies: 2}
+	if err := db.RequestLabels(req); err != nil {
+		t.Fatalf("RequestLabels failed: %v", err)
+	}
+	if err := db.RequestLabels(&LabelRequest{SKU: "MILK"}); err == nil {
+		t.Error("Expected error for zero copies")
+	}
+
+	pending, err := db.ListPendingLabels()
+	if err != nil {
+		t.Fatalf("ListPendingLabels failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != req.ID || pending[0].Copies != 2 {
+		t.Fatalf("Unexpected pending labels: %+v", pending)
+	}
+
+	if err := db.MarkLabelsPrinted(req.ID); err != nil {
+		t.Fatalf("MarkLabelsPrinted failed: %v", err)
+	}
+	if err := db.MarkLabelsPrinted(req.ID); !errors.Is(err, ErrLabelRequestNotFound) {
+		t.Errorf("Expected ErrLabelRequestNotFound printing twice, got %v", err)
+	}
+	if pending, _ := db.ListPendingLabels(); len(pending) != 0 {
+		t.Errorf("Expected empty queue, got %d", len(pending))
+	}
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// diagnosticPragmas are reported in diagnostics snapshots
+var diagnosticPragmas = []string{
+	"journal_mode",
+	"synchronous",
+	"busy_timeout",
+	"foreign_keys",
+	"auto_vacuum",
+	"page_size",
+	"page_count",
+	"freelist_count",
+	"cache_size",
+	"wal_autocheckpoint",
+	"quick_check",
+}
+
+// databaseFiles are the suffixes of the files SQLite keeps for a database
+var databaseFiles = []string{"", "-wal", "-shm"}
+
+// Diagnostics is a sanitized database report for support tickets. It holds
+// only metadata: no decrypted values, keys, or full filesystem paths.
+type Diagnostics struct {
+	GeneratedAt    string            `json:"generated_at"`
+	SchemaVersion  int               `json:"schema_version"`
+	SQLiteVersion  string            `json:"sqlite_version"`
+	InMemory       bool              `json:"in_memory"`
+	ReadOnly       bool              `json:"read_only"`
+	TableRows      map[string]int64  `json:"table_rows"`
+	FileSizes      map[string]int64  `json:"file_sizes"` // Keyed by file name only
+	Pragmas        map[string]string `json:"pragmas"`
+	LastCheckpoint string            `json:"last_checkpoint,omitempty"` // ISO 8601; empty if none this run
+	BusyRetries    RetryStats        `json:"busy_retries"`
+}
+
+// Checkpoint flushes the WAL into the main database file and truncates it
+func (db *DB) Checkpoint() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+
+	db.lastCheckpoint.Store(time.Now().UnixNano())
+	return nil
+}
+
+// DiagnosticsSnapshot collects a sanitized report of the database state
+func (db *DB) DiagnosticsSnapshot() (*Diagnostics, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	diag := &Diagnostics{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		InMemory:    db.IsInMemory(),
+		ReadOnly:    db.IsReadOnly(),
+		TableRows:   make(map[string]int64),
+		FileSizes:   make(map[string]int64),
+		Pragmas:     make(map[string]string),
+		BusyRetries: db.RetryStats(),
+	}
+
+	if err := db.conn.QueryRow("PRAGMA user_version").Scan(&diag.SchemaVersion); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if err := db.conn.QueryRow("SELECT sqlite_version()").Scan(&diag.SQLiteVersion); err != nil {
+		return nil, fmt.Errorf("failed to read sqlite version: %w", err)
+	}
+
+	// Row counts per table (names come from sqlite_master, not user input)
+	rows, err := db.conn.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		var count int64
+		if err := db.conn.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, table)).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		diag.TableRows[table] = count
+	}
+
+	for _, pragma := range diagnosticPragmas {
+		var value string
+		if err := db.conn.QueryRow("PRAGMA " + pragma).Scan(&value); err != nil {
+			value = "error: " + err.Error()
+		}
+		diag.Pragmas[pragma] = value
+	}
+
+	if !diag.InMemory {
+		for _, suffix := range databaseFiles {
+			if info, err := os.Stat(db.dbPath + suffix); err == nil {
+				diag.FileSizes[filepath.Base(db.dbPath+suffix)] = info.Size()
+			}
+		}
+	}
+
+	if ts := db.lastCheckpoint.Load(); ts != 0 {
+		diag.LastCheckpoint = time.Unix(0, ts).UTC().Format(time.RFC3339)
+	}
+
+	return diag, nil
+}
+
+// SizeOnDisk returns the bytes taken by the database file and its WAL and
+// shared-memory files (0 in memory)
+func (db *DB) SizeOnDisk() int64 {
+	if db.IsInMemory() {
+		return 0
+	}
+	var size int64
+	for _, suffix := range databaseFiles {
+		if info, err := os.Stat(db.dbPath + suffix); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// FreeSpace returns the bytes free on the volume holding the database, for
+// the disk space health check. In memory it reports on the temp directory.
+func (db *DB) FreeSpace() (int64, error) {
+	dir := os.TempDir()
+	if !db.IsInMemory() {
+		dir = filepath.Dir(db.dbPath)
+	}
+	return freeSpace(dir)
+}
+
+// QuickCheck runs SQLite's quick integrity check (structure only, no index
+// cross-checks) and returns an error describing the first problem found
+func (db *DB) QuickCheck() error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var result string
+	if err := db.conn.QueryRow("PRAGMA quick_check(1)").Scan(&result); err != nil {
+		return fmt.Errorf("failed to run quick check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("quick check failed: %s", result)
+	}
+	return nil
+}
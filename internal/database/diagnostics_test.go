@@ -0,0 +1,55 @@
+package database
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func TestDiagnosticsSnapshot(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	db, err := New(&Config{ServerKey: serverKey, DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetSetting("api_token", "super-secret-value"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+
+	diag, err := db.DiagnosticsSnapshot()
+	if err != nil {
+		t.Fatalf("DiagnosticsSnapshot failed: %v", err)
+	}
+
+	if diag.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SchemaVersion, diag.SchemaVersion)
+	}
+	if diag.TableRows["settings"] != 1 {
+		t.Errorf("Expected 1 settings row, got %d", diag.TableRows["settings"])
+	}
+	if diag.Pragmas["journal_mode"] != "wal" {
+		t.Errorf("Expected WAL journal mode, got %q", diag.Pragmas["journal_mode"])
+	}
+	if _, ok := diag.FileSizes["data.db"]; !ok {
+		t.Errorf("Expected data.db size, got %v", diag.FileSizes)
+	}
+	if diag.LastCheckpoint == "" {
+		t.Error("Expected last checkpoint time")
+	}
+
+	// The report must not leak values or full paths
+	report, _ := json.Marshal(diag)
+	if strings.Contains(string(report), "super-secret-value") {
+		t.Error("Diagnostics leaked a decrypted value")
+	}
+	if strings.Contains(string(report), db.Path()) {
+		t.Error("Diagnostics leaked the full database path")
+	}
+}
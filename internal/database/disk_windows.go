@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package database
+
+import "golang.org/x/sys/windows"
+
+// freeSpace returns the bytes available to this process on the volume
+// holding dir
+func freeSpace(dir string) (int64, error) {
+	dir16, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(dir16, &available, nil, nil); err != nil {
+		return 0, err
+	}
+	return int64(available), nil
+}
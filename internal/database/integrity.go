@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Sale lines, payments and refunds live inside the encrypted sale record, so
+// the relations SQLite can see are few: basket lines belong to a basket, and
+// denormalized columns (basket counters, the sale total) must agree with the
+// rows or record they summarize. Writes keep these consistent inside one
+// transaction; the checks below catch damage from crashes, restored backups
+// or hand edits.
+
+// IntegrityReport counts integrity problems found by one check
+type IntegrityReport struct {
+	CheckedAt           string `json:"checked_at"`            // ISO 8601 timestamp
+	OrphanedBasketLines int64  `json:"orphaned_basket_lines"` // Lines whose basket is gone
+	BasketMismatches    int64  `json:"basket_mismatches"`     // Baskets whose line_count disagrees with their lines
+	InvalidSales        int64  `json:"invalid_sales"`         // Sales that fail to decrypt, validate or match their total column
+	Quarantined         int64  `json:"quarantined"`           // Rows moved to quarantine by this check
+}
+
+// Problems returns the number of problems found
+func (r IntegrityReport) Problems() int64 {
+	return r.OrphanedBasketLines + r.BasketMismatches + r.InvalidSales
+}
+
+// CheckIntegrity counts integrity problems without changing anything
+func (db *DB) CheckIntegrity() (*IntegrityReport, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	report := &IntegrityReport{CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM basket_lines
+		WHERE basket_id NOT IN (SELECT id FROM baskets)
+	`).Scan(&report.OrphanedBasketLines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orphaned basket lines: %w", err)
+	}
+
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*) FROM baskets b
+		WHERE b.line_count != (SELECT COUNT(*) FROM basket_lines l WHERE l.basket_id = b.id)
+	`).Scan(&report.BasketMismatches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count basket mismatches: %w", err)
+	}
+
+	if report.InvalidSales, err = db.countInvalidSales(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// countInvalidSales decrypts every sale and counts those that do not
+// validate or whose total column disagrees with the record
+func (db *DB) countInvalidSales() (int64, error) {
+	rows, err := db.conn.Query("SELECT id, data, total FROM sales")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query sales: %w", err)
+	}
+	defer rows.Close()
+
+	var invalid int64
+	for rows.Next() {
+		var id, encryptedData string
+		var total int64
+		if err := rows.Scan(&id, &encryptedData, &total); err != nil {
+			return 0, fmt.Errorf("failed to scan sale: %w", err)
+		}
+
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("sales", id))
+		if err != nil {
+			invalid++
+			continue
+		}
+		var sale Sale
+		if err := json.Unmarshal(jsonData, &sale); err != nil || sale.ID != id || sale.Validate() != nil || sale.Total != total {
+			invalid++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate sales: %w", err)
+	}
+
+	return invalid, nil
+}
+
+// RepairOrphans checks integrity and moves orphaned basket lines into the
+// quarantine table, where they are kept for support rather than deleted.
+// Invalid sales and basket mismatches are only reported: sales are captured
+// into the outbox and must not disappear locally.
+func (db *DB) RepairOrphans() (*IntegrityReport, error) {
+	report, err := db.CheckIntegrity()
+	if err != nil {
+		return nil, err
+	}
+	if report.OrphanedBasketLines == 0 || db.refusesWrites() {
+		db.integrity.Store(report)
+		return report, nil
+	}
+
+	err = db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO quarantine (source_table, row_id, data, reason)
+			SELECT 'basket_lines', basket_id, data, 'basket missing (line ' || seq || ')'
+			FROM basket_lines
+			WHERE basket_id NOT IN (SELECT id FROM baskets)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine basket lines: %w", err)
+		}
+
+		result, err := tx.Exec("DELETE FROM basket_lines WHERE basket_id NOT IN (SELECT id FROM baskets)")
+		if err != nil {
+			return fmt.Errorf("failed to remove orphaned basket lines: %w", err)
+		}
+		report.Quarantined, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.integrity.Store(report)
+	return report, nil
+}
+
+// LastIntegrityReport returns the report of the last repair pass, or nil
+// if none has run
+func (db *DB) LastIntegrityReport() *IntegrityReport {
+	return db.integrity.Load()
+}
+
+// CountQuarantined returns the number of rows held in quarantine
+func (db *DB) CountQuarantined() (int64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int64
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM quarantine").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count quarantined rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// RunOrphanRepair checks integrity once at startup (the likeliest moment to
+// find crash damage) and then every interval until ctx is cancelled
+func (db *DB) RunOrphanRepair(ctx context.Context, interval time.Duration) {
+	repair := func() {
+		report, err := db.RepairOrphans()
+		if err != nil {
+			log.Printf("Warning: integrity check failed: %v", err)
+			return
+		}
+		if report.Problems() > 0 {
+			log.Printf("Integrity check found problems (orphaned basket lines: %d, basket mismatches: %d, invalid sales: %d, quarantined: %d)",
+				report.OrphanedBasketLines, report.BasketMismatches, report.InvalidSales, report.Quarantined)
+		}
+	}
+
+	repair()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			repair()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
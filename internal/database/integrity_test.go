@@ -0,0 +1,75 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestRepairOrphans(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	lines := []SaleLine{{SKU: "MILK", Quantity: 2, Price: 990}, {SKU: "BREAD", Quantity: 1, Price: 450}}
+	for _, id := range []string{"kept", "crashed", "miscounted"} {
+		if _, err := db.AppendBasketLines(id, "T1", lines, 0); err != nil {
+			t.Fatalf("AppendBasketLines failed: %v", err)
+		}
+	}
+	for _, id := range []string{"S1", "S2"} {
+		err := db.Transaction(func(tx *sql.Tx) error {
+			_, err := db.ApplySale(tx, &Sale{ID: id, TerminalID: "T1", Lines: lines, Total: 2430})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("ApplySale failed: %v", err)
+		}
+	}
+
+	report, err := db.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if report.Problems() != 0 {
+		t.Fatalf("Expected a clean database, got %+v", report)
+	}
+
+	// Damage a crash or a hand edit could leave behind
+	for _, query := range []string{
+		"DELETE FROM baskets WHERE id = 'crashed'",
+		"UPDATE baskets SET line_count = 5 WHERE id = 'miscounted'",
+		"UPDATE sales SET total = 1 WHERE id = 'S2'",
+	} {
+		if _, err := db.conn.Exec(query); err != nil {
+			t.Fatalf("Failed to damage database: %v", err)
+		}
+	}
+
+	report, err = db.RepairOrphans()
+	if err != nil {
+		t.Fatalf("RepairOrphans failed: %v", err)
+	}
+	if report.OrphanedBasketLines != 2 || report.BasketMismatches != 1 || report.InvalidSales != 1 || report.Quarantined != 2 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if db.LastIntegrityReport() != report {
+		t.Error("Expected the report to be kept for /health")
+	}
+
+	if quarantined, err := db.CountQuarantined(); err != nil || quarantined != 2 {
+		t.Errorf("Expected 2 quarantined rows, got %d (%v)", quarantined, err)
+	}
+	if page, err := db.GetBasketLines("kept", 0, 10); err != nil || len(page) != 2 {
+		t.Errorf("Expected intact basket to keep its lines, got %d (%v)", len(page), err)
+	}
+	if _, err := db.GetSale("S2"); err != nil {
+		t.Errorf("Expected the invalid sale to be kept, got %v", err)
+	}
+
+	report, err = db.RepairOrphans()
+	if err != nil {
+		t.Fatalf("RepairOrphans failed: %v", err)
+	}
+	if report.OrphanedBasketLines != 0 || report.Quarantined != 0 {
+		t.Errorf("Expected orphans to be gone after repair, got %+v", report)
+	}
+}
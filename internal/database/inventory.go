@@ -0,0 +1,291 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Stock movement kinds. Sales move stock too, but they are logged as sales.
+const (
+	StockMovementReceipt    = "receipt"    // Goods delivered; Delta is the quantity received
+	StockMovementAdjustment = "adjustment" // A correction such as damage or shrinkage
+	StockMovementCount      = "count"      // A physical count; Delta is derived from Counted
+)
+
+// StockLevel is the on-hand quantity of a SKU
+type StockLevel struct {
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// StockLevelFilter narrows ListStockLevels; zero fields match everything
+type StockLevelFilter struct {
+	SKUPrefix string
+	AtMost    *int // Only SKUs with this quantity or less
+}
+
+// validate checks a movement before it is recorded
+func (m *StockAdjustment) validate() error {
+	if m.ID == "" || m.SKU == "" {
+		return fmt.Errorf("stock movement id and sku are required")
+	}
+	switch m.Kind {
+	case StockMovementReceipt:
+		if m.Delta <= 0 {
+			return fmt.Errorf("receipt %s must add a positive quantity", m.ID)
+		}
+	case StockMovementAdjustment:
+		if m.Delta == 0 {
+			return fmt.Errorf("adjustment %s must have a non-zero delta", m.ID)
+		}
+	case StockMovementCount:
+		if m.Counted == nil || *m.Counted < 0 {
+			return fmt.Errorf("count %s must have a counted quantity of zero or more", m.ID)
+		}
+	default:
+		return fmt.Errorf("stock movement %s has an invalid kind: %s", m.ID, m.Kind)
+	}
+	return nil
+}
+
+// RecordStockMovements logs receipts, adjustments and counts and applies
+// them to the stock levels in one transaction. Each movement is idempotent
+// on its ID: a movement already logged is not applied again, and is filled
+// in from the log so the caller sees what was recorded. A count sets its
+// Delta to the difference from the level it replaced, zero included, so
+// the log still shows the SKU was counted. Returns the resulting level of
+// every SKU moved and how many movements were newly applied. Movements
+// reach the outbox through CDC triggers.
+func (db *DB) RecordStockMovements(movements []*StockAdjustment) (levels map[string]int, applied int, err error) {
+	for _, m := range movements {
+		if m.Kind == "" {
+			m.Kind = StockMovementAdjustment
+		}
+		if err := m.validate(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	err = db.Transaction(func(tx *sql.Tx) error {
+		levels = make(map[string]int, len(movements))
+		applied = 0
+
+		for _, m := range movements {
+			var stored string
+			err := tx.QueryRow("SELECT data FROM stock_adjustments WHERE id = ?", m.ID).Scan(&stored)
+			switch {
+			case err == nil:
+				jsonData, err := db.encryption.DecryptWithAAD(stored, rowAAD("stock_adjustments", m.ID))
+				if err != nil {
+					return fmt.Errorf("failed to decrypt stock movement %s: %w", m.ID, err)
+				}
+				if err := json.Unmarshal(jsonData, m); err != nil {
+					return fmt.Errorf("failed to parse stock movement %s: %w", m.ID, err)
+				}
+			case err == sql.ErrNoRows:
+				if err := db.insertStockMovement(tx, m); err != nil {
+					return err
+				}
+				applied++
+			default:
+				return fmt.Errorf("failed to query stock movement: %w", err)
+			}
+
+			quantity, err := stockLevel(tx, m.SKU)
+			if err != nil {
+				return err
+			}
+			levels[m.SKU] = quantity
+		}
+		return nil
+	})
+	return levels, applied, err
+}
+
+// insertStockMovement logs one new movement and moves its SKU's stock
+func (db *DB) insertStockMovement(tx *sql.Tx, m *StockAdjustment) error {
+	if m.Kind == StockMovementCount {
+		current, err := stockLevel(tx, m.SKU)
+		if err != nil {
+			return err
+		}
+		m.Delta = *m.Counted - current
+	}
+	if m.CreatedAt == "" {
+		m.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stock movement: %w", err)
+	}
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("stock_adjustments", m.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt stock movement: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO stock_adjustments (id, sku, data) VALUES (?, ?, ?)",
+		m.ID, m.SKU, encryptedData,
+	); err != nil {
+		return fmt.Errorf("failed to insert stock movement: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_levels (sku, quantity, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(sku) DO UPDATE SET
+			quantity = quantity + excluded.quantity,
+			updated_at = CURRENT_TIMESTAMP
+	`, m.SKU, m.Delta)
+	if err != nil {
+		return fmt.Errorf("failed to move stock for %s: %w", m.SKU, err)
+	}
+	return nil
+}
+
+// stockLevel reads a SKU's on-hand quantity inside a transaction
+func stockLevel(tx *sql.Tx, sku string) (int, error) {
+	var quantity int
+	err := tx.QueryRow("SELECT quantity FROM stock_levels WHERE sku = ?", sku).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stock level: %w", err)
+	}
+	return quantity, nil
+}
+
+// ListStockLevels returns the stock levels matching filter, ordered by SKU
+func (db *DB) ListStockLevels(filter StockLevelFilter) ([]StockLevel, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	query := "SELECT sku, quantity, COALESCE(updated_at, '') FROM stock_levels WHERE 1 = 1"
+	var args []interface{}
+	if filter.SKUPrefix != "" {
+		query += " AND substr(sku, 1, length(?)) = ?"
+		args = append(args, filter.SKUPrefix, filter.SKUPrefix)
+	}
+	if filter.AtMost != nil {
+		query += " AND quantity <= ?"
+		args = append(args, *filter.AtMost)
+	}
+	query += " ORDER BY sku"
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock levels: %w", err)
+	}
+	defer rows.Close()
+
+	var levels []StockLevel
+	for rows.Next() {
+		var level StockLevel
+		if err := rows.Scan(&level.SKU, &level.Quantity, &level.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock level: %w", err)
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, rows.Err()
+}
+
+// ListStockMovements returns up to limit of a SKU's logged movements,
+// newest first
+func (db *DB) ListStockMovements(sku string, limit int) ([]StockAdjustment, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query(
+		"SELECT id, data FROM stock_adjustments WHERE sku = ? ORDER BY created_at DESC, rowid DESC LIMIT ?",
+		sku, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []StockAdjustment
+	for rows.Next() {
+		var id, encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("stock_adjustments", id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt stock movement %s: %w", id, err)
+		}
+
+		var m StockAdjustment
+		if err := json.Unmarshal(jsonData, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse stock movement: %w", err)
+		}
+		if m.Kind == "" {
+			m.Kind = StockMovementAdjustment
+		}
+		movements = append(movements, m)
+	}
+
+	return movements, rows.Err()
+}
+
+// indexStockMovements fills the sku column of movements logged before it
+// existed. Change capture is suppressed: the payloads are unchanged.
+func (db *DB) indexStockMovements() error {
+	rows, err := db.conn.Query("SELECT id, data FROM stock_adjustments WHERE sku IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query stock movements: %w", err)
+	}
+
+	skus := make(map[string]string)
+	for rows.Next() {
+		var id, encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("stock_adjustments", id))
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decrypt stock movement %s: %w", id, err)
+		}
+		var m StockAdjustment
+		if err := json.Unmarshal(jsonData, &m); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to parse stock movement %s: %w", id, err)
+		}
+		skus[id] = m.SKU
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(skus) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 1 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to suppress change capture: %w", err)
+	}
+	for id, sku := range skus {
+		if _, err := tx.Exec("UPDATE stock_adjustments SET sku = ? WHERE id = ?", sku, id); err != nil {
+			return fmt.Errorf("failed to index stock movement %s: %w", id, err)
+		}
+	}
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 0 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to restore change capture: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,88 @@
+package database
+
+import "testing"
+
+func TestRecordStockMovements_ReceiptsAndCounts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetStockLevel("MILK", 4)
+	before, _ := db.CountPendingOutbox()
+
+	receipt := []*StockAdjustment{
+		{ID: "rcv-1.1", Kind: StockMovementReceipt, SKU: "MILK", Delta: 12, Reference: "PO-77"},
+		{ID: "rcv-1.2", Kind: StockMovementReceipt, SKU: "BREAD", Delta: 5, Reference: "PO-77"},
+	}
+	levels, applied, err := db.RecordStockMovements(receipt)
+	if err != nil {
+		t.Fatalf("RecordStockMovements failed: %v", err)
+	}
+	if applied != 2 || levels["MILK"] != 16 || levels["BREAD"] != 5 {
+		t.Errorf("Expected both lines applied (MILK 16, BREAD 5), got %v (applied=%d)", levels, applied)
+	}
+
+	// A retried receipt is not received twice
+	levels, applied, _ = db.RecordStockMovements(receipt)
+	if applied != 0 || levels["MILK"] != 16 {
+		t.Errorf("Expected retry to be a no-op at 16, got %v (applied=%d)", levels, applied)
+	}
+
+	// A count logs the difference from the level it replaces
+	counted := 13
+	count := &StockAdjustment{ID: "cnt-1", Kind: StockMovementCount, SKU: "MILK", Counted: &counted}
+	levels, _, err = db.RecordStockMovements([]*StockAdjustment{count})
+	if err != nil {
+		t.Fatalf("RecordStockMovements count failed: %v", err)
+	}
+	if count.Delta != -3 || levels["MILK"] != 13 {
+		t.Errorf("Expected count delta -3 to 13, got delta %d level %d", count.Delta, levels["MILK"])
+	}
+
+	// Replaying the count reports the recorded delta, not a new one
+	retry := &StockAdjustment{ID: "cnt-1", Kind: StockMovementCount, SKU: "MILK", Counted: &counted}
+	if _, applied, _ := db.RecordStockMovements([]*StockAdjustment{retry}); applied != 0 || retry.Delta != -3 {
+		t.Errorf("Expected replayed count with delta -3, got %d (applied=%d)", retry.Delta, applied)
+	}
+
+	if after, _ := db.CountPendingOutbox(); after != before+3 {
+		t.Errorf("Expected three outbox entries for the movements, got %d", after-before)
+	}
+
+	movements, err := db.ListStockMovements("MILK", 10)
+	if err != nil {
+		t.Fatalf("ListStockMovements failed: %v", err)
+	}
+	if len(movements) != 2 || movements[0].ID != "cnt-1" || movements[1].Reference != "PO-77" {
+		t.Errorf("Unexpected MILK movements: %+v", movements)
+	}
+
+	if _, _, err := db.RecordStockMovements([]*StockAdjustment{{ID: "rcv-2", Kind: StockMovementReceipt, SKU: "MILK", Delta: -1}}); err == nil {
+		t.Error("Expected error for a negative receipt")
+	}
+	if _, _, err := db.RecordStockMovements([]*StockAdjustment{{ID: "cnt-2", Kind: StockMovementCount, SKU: "MILK"}}); err == nil {
+		t.Error("Expected error for a count without a quantity")
+	}
+}
+
+func TestListStockLevels_Filter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetStockLevel("DAIRY-MILK", 2)
+	db.SetStockLevel("DAIRY-BUTTER", 30)
+	db.SetStockLevel("BREAD", 1)
+
+	levels, err := db.ListStockLevels(StockLevelFilter{})
+	if err != nil {
+		t.Fatalf("ListStockLevels failed: %v", err)
+	}
+	if len(levels) != 3 || levels[0].SKU != "BREAD" {
+		t.Errorf("Expected three levels ordered by SKU, got %+v", levels)
+	}
+
+	low := 5
+	levels, _ = db.ListStockLevels(StockLevelFilter{SKUPrefix: "DAIRY-", AtMost: &low})
+	if len(levels) != 1 || levels[0].SKU != "DAIRY-MILK" || levels[0].Quantity != 2 {
+		t.Errorf("Expected only DAIRY-MILK, got %+v", levels)
+	}
+}
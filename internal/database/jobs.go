@@ -0,0 +1,205 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrJobNotFound is returned when no job matches an ID
+var ErrJobNotFound = errors.New("job not found")
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is the persisted state of an async job. Job bookkeeping holds no
+// business data, so it is stored unencrypted.
+type Job struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"` // e.g. "export", "import", "reencrypt", "bootstrap_sync"
+	Status     string          `json:"status"`
+	Progress   int             `json:"progress"` // 0-100
+	Message    string          `json:"message,omitempty"`
+	ResultURL  string          `json:"result_url,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"` // Kind-specific outcome, e.g. an import's row errors
+	CreatedAt  string          `json:"created_at"`
+	StartedAt  string          `json:"started_at,omitempty"`
+	FinishedAt string          `json:"finished_at,omitempty"`
+}
+
+// --- Jobs Table Methods ---
+
+// jobColumns selects a job with timestamps rendered as ISO 8601
+const jobColumns = `id, kind, status, progress, message, result_url, error, details,
+	strftime('%Y-%m-%dT%H:%M:%SZ', created_at),
+	COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', started_at), ''),
+	COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', finished_at), '')`
+
+// CreateJob inserts a new queued job
+func (db *DB) CreateJob(id, kind string) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	query := "INSERT INTO jobs (id, kind, status) VALUES (?, ?, ?)"
+	if _, err := db.conn.Exec(query, id, kind, JobQueued); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return nil
+}
+
+// StartJob marks a job as running
+func (db *DB) StartJob(id string) error {
+	return db.execJob("UPDATE jobs SET status = ?, started_at = CURRENT_TIMESTAMP WHERE id = ?", JobRunning, id)
+}
+
+// UpdateJobProgress records job progress (0-100) and a status message
+func (db *DB) UpdateJobProgress(id string, progress int, message string) error {
+	return db.execJob("UPDATE jobs SET progress = ?, message = ? WHERE id = ?", progress, message, id)
+}
+
+// SetJobDetails records a job's kind-specific outcome as JSON. Like the
+// rest of the bookkeeping it is stored unencrypted, so details must not
+// carry business data.
+func (db *DB) SetJobDetails(id string, details interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job details: %w", err)
+	}
+	return db.execJob("UPDATE jobs SET details = ? WHERE id = ?", string(data), id)
+}
+
+// FinishJob records the outcome of a job
+func (db *DB) FinishJob(id, resultURL string, jobErr error) error {
+	if jobErr != nil {
+		return db.execJob(
+			"UPDATE jobs SET status = ?, error = ?, finished_at = CURRENT_TIMESTAMP WHERE id = ?",
+			JobFailed, jobErr.Error(), id,
+		)
+	}
+	return db.execJob(
+		"UPDATE jobs SET status = ?, progress = 100, result_url = ?, finished_at = CURRENT_TIMESTAMP WHERE id = ?",
+		JobSucceeded, resultURL, id,
+	)
+}
+
+// FailInterruptedJobs marks jobs left queued/running by a previous process as failed
+func (db *DB) FailInterruptedJobs() (int64, error) {
+	if db.refusesWrites() {
+		return 0, ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	result, err := db.conn.Exec(
+		"UPDATE jobs SET status = ?, error = ?, finished_at = CURRENT_TIMESTAMP WHERE status IN (?, ?)",
+		JobFailed, "interrupted by service restart", JobQueued, JobRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted jobs: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetJob retrieves a job by ID
+func (db *DB) GetJob(id string) (*Job, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	job, err := scanJob(db.conn.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListJobs returns the most recent jobs, newest first
+func (db *DB) ListJobs(limit int) ([]Job, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT "+jobColumns+" FROM jobs ORDER BY created_at DESC, rowid DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job row: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// execJob runs a job bookkeeping statement and checks the job exists
+func (db *DB) execJob(query string, args ...interface{}) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanJob scans a job selected with jobColumns
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var details string
+	err := row.Scan(
+		&job.ID, &job.Kind, &job.Status, &job.Progress, &job.Message, &job.ResultURL, &job.Error, &details,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if details != "" {
+		job.Details = json.RawMessage(details)
+	}
+	return &job, nil
+}
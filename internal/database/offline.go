@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// The last successful sync with head office is kept in the settings, so
+// the sync layer's offline grace period (see sync.RunOfflineWatch)
+// survives restarts and counts syncs applied by another process (bundle
+// imports).
+
+// lastSyncSettingKey holds when the last successful sync finished
+const lastSyncSettingKey = "sync.last_success_at"
+
+// RecordSync notes a sync with head office that succeeded at at
+func (db *DB) RecordSync(at time.Time) error {
+	return db.SetSettingTime(lastSyncSettingKey, at)
+}
+
+// LastSync returns when the last successful sync finished, zero if the
+// terminal never synced
+func (db *DB) LastSync() (time.Time, error) {
+	at, err := db.GetSettingTime(lastSyncSettingKey)
+	if errors.Is(err, ErrSettingNotFound) {
+		return time.Time{}, nil
+	}
+	return at, err
+}
+
+// SetOffline switches offline mode, which the sync layer turns on once the
+// offline grace period lapses. Like read-only mode it makes mutating
+// operations fail with ErrReadOnly, but the two are kept apart: ending one
+// never ends the other. Sign-ins, sign-outs and the audit trail keep
+// working offline (see SetSessionSetting), so staff can sign in to run the
+// sync that ends it and failed attempts are still recorded.
+func (db *DB) SetOffline(offline bool) {
+	db.offline.Store(offline)
+}
+
+// IsOffline reports whether offline mode is on
+func (db *DB) IsOffline() bool {
+	return db.offline.Load()
+}
+
+// SetSessionSetting stores a session setting such as a sign-in token,
+// expiring after ttl if positive. Only read-only mode refuses it.
+func (db *DB) SetSessionSetting(key, value string, ttl time.Duration) error {
+	if db.IsReadOnly() {
+		return ErrReadOnly
+	}
+	var expiresAt interface{}
+	if ttl > 0 {
+		expiresAt = time.Now().UTC().Add(ttl).Format(sqliteTimestampFormat)
+	}
+	return db.storeSetting(key, value, expiresAt)
+}
+
+// DeleteSessionSetting deletes a session setting, as on sign-out. Only
+// read-only mode refuses it.
+func (db *DB) DeleteSessionSetting(key string) error {
+	if db.IsReadOnly() {
+		return ErrReadOnly
+	}
+	return db.removeSetting(key)
+}
+
+// sessionTransaction runs fn as Transaction does, for sign-in bookkeeping
+// and audit entries. Only read-only mode refuses it.
+func (db *DB) sessionTransaction(fn func(*sql.Tx) error) error {
+	if db.IsReadOnly() {
+		return ErrReadOnly
+	}
+	ctx := context.Background()
+	return db.withBusyRetry(ctx, func() error {
+		return db.transaction(ctx, fn, nil)
+	})
+}
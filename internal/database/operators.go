@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// operatorDayLayout is the date format of operator_stats.day
+const operatorDayLayout = "2006-01-02"
+
+// OperatorStats aggregates an operator's performance over a period
+type OperatorStats struct {
+	OperatorID    string  `json:"operator_id"`
+	From          string  `json:"from"` // First day, YYYY-MM-DD
+	To            string  `json:"to"`   // Last day, YYYY-MM-DD
+	SalesCount    int64   `json:"sales_count"`
+	SalesValue    int64   `json:"sales_value"` // Minor currency units
+	RefundCount   int64   `json:"refund_count"`
+	RefundValue   int64   `json:"refund_value"`
+	ItemsSold     int64   `json:"items_sold"`
+	VoidedLines   int64   `json:"voided_lines"`
+	AverageBasket float64 `json:"average_basket"` // Sales value per sale
+	ScanRate      float64 `json:"scan_rate"`      // Items per minute while scanning
+	VoidRatio     float64 `json:"void_ratio"`     // Voided lines per sale
+	RefundRatio   float64 `json:"refund_ratio"`   // Refunds per sale
+}
+
+// applyOperatorRollup adds a sale to its operator's daily rollup
+func applyOperatorRollup(tx *sql.Tx, sale *Sale) error {
+	if sale.OperatorID == "" {
+		return nil
+	}
+
+	day := time.Now().UTC().Format(operatorDayLayout)
+	if t, err := time.Parse(time.RFC3339, sale.CreatedAt); err == nil {
+		day = t.UTC().Format(operatorDayLayout)
+	}
+
+	var items int64
+	for _, line := range sale.Lines {
+		items += int64(line.Quantity)
+	}
+
+	var salesCount, salesValue, refundCount, refundValue, itemsSold, scanSeconds int64
+	if sale.IsRefund() {
+		refundCount, refundValue = 1, sale.Total
+	} else {
+		salesCount, salesValue, itemsSold, scanSeconds = 1, sale.Total, items, int64(sale.ScanSeconds)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO operator_stats (
+			id, operator_id, day, sales_count, sales_value, refund_count, refund_value,
+			items_sold, voided_lines, scan_seconds, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO UPDATE SET
+			sales_count  = sales_count + excluded.sales_count,
+			sales_value  = sales_value + excluded.sales_value,
+			refund_count = refund_count + excluded.refund_count,
+			refund_value = refund_value + excluded.refund_value,
+			items_sold   = items_sold + excluded.items_sold,
+			voided_lines = voided_lines + excluded.voided_lines,
+			scan_seconds = scan_seconds + excluded.scan_seconds,
+			updated_at   = CURRENT_TIMESTAMP
+	`, sale.OperatorID+"/"+day, sale.OperatorID, day, salesCount, salesValue, refundCount, refundValue,
+		itemsSold, sale.VoidedLines, scanSeconds)
+	if err != nil {
+		return fmt.Errorf("failed to update operator stats: %w", err)
+	}
+
+	return nil
+}
+
+// GetOperatorStats aggregates an operator's rollups for the days in [from, to]
+func (db *DB) GetOperatorStats(operatorID string, from, to time.Time) (*OperatorStats, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	stats := &OperatorStats{
+		OperatorID: operatorID,
+		From:       from.UTC().Format(operatorDayLayout),
+		To:         to.UTC().Format(operatorDayLayout),
+	}
+
+	var scanSeconds int64
+	err := db.conn.QueryRow(`
+		SELECT
+			COALESCE(SUM(sales_count), 0), COALESCE(SUM(sales_value), 0),
+			COALESCE(SUM(refund_count), 0), COALESCE(SUM(refund_value), 0),
+			COALESCE(SUM(items_sold), 0), COALESCE(SUM(voided_lines), 0),
+			COALESCE(SUM(scan_seconds), 0)
+		FROM operator_stats
+		WHERE operator_id = ? AND day BETWEEN ? AND ?
+	`, operatorID, stats.From, stats.To).Scan(
+		&stats.SalesCount, &stats.SalesValue,
+		&stats.RefundCount, &stats.RefundValue,
+		&stats.ItemsSold, &stats.VoidedLines,
+		&scanSeconds,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query operator stats: %w", err)
+	}
+
+	if stats.SalesCount > 0 {
+		stats.AverageBasket = float64(stats.SalesValue) / float64(stats.SalesCount)
+		stats.VoidRatio = float64(stats.VoidedLines) / float64(stats.SalesCount)
+		stats.RefundRatio = float64(stats.RefundCount) / float64(stats.SalesCount)
+	}
+	if scanSeconds > 0 {
+		stats.ScanRate = float64(stats.ItemsSold) / (float64(scanSeconds) / 60)
+	}
+
+	return stats, nil
+}
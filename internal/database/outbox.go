@@ -0,0 +1,397 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/professor93/promo-pos/pkg/ids"
+	"modernc.org/sqlite"
+)
+
+// Outbox operations
+const (
+	OutboxInsert = "insert"
+	OutboxUpdate = "update"
+	OutboxDelete = "delete"
+)
+
+// newIDFunction is the SQL function CDC triggers call to give each outbox
+// entry a ULID (see ids.New). The local autoincrement id orders the upload
+// cursor; the ULID identifies the entry once terminals merge server-side.
+const newIDFunction = "pos_new_id"
+
+func init() {
+	sqlite.MustRegisterScalarFunction(newIDFunction, 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return ids.New(), nil
+	})
+}
+
+// cdcTables are the domain tables captured into the outbox
+var cdcTables = []struct {
+	table, idColumn, payloadColumn string
+}{
+	{"products", "id", "data"},
+	{"sales", "id", "data"},
+	{"transaction_voids", "id", "data"},
+	{"loyalty_entries", "id", "data"},
+	{"customers", "id", "data"},
+	{"operator_stats", "id", "payload"},
+	{"stock_adjustments", "id", "data"},
+	{"day_closings", "id", "data"},
+	{"audit_anchors", "id", "data"},
+}
+
+// Outbox priority classes, most urgent first. A backlog drains class by
+// class, so after a long outage sales reach head office before megabytes of
+// telemetry.
+const (
+	OutboxClassTransactions = "transactions" // Sales and their payments
+	OutboxClassStock        = "stock"        // Stock movements, catalog and customer edits
+	OutboxClassTelemetry    = "telemetry"    // Operator statistics and other reporting
+)
+
+// OutboxClasses lists the priority classes in upload order
+var OutboxClasses = []string{OutboxClassTransactions, OutboxClassStock, OutboxClassTelemetry}
+
+// outboxClassOf maps captured tables to their class; anything unlisted is telemetry
+var outboxClassOf = map[string]string{
+	"sales":             OutboxClassTransactions,
+	"transaction_voids": OutboxClassTransactions,
+	"loyalty_entries":   OutboxClassTransactions,
+	"stock_adjustments": OutboxClassStock,
+	"products":          OutboxClassStock,
+	"customers":         OutboxClassStock,
+}
+
+// OutboxClass returns the priority class of changes to entity
+func OutboxClass(entity string) string {
+	if class, ok := outboxClassOf[entity]; ok {
+		return class
+	}
+	return OutboxClassTelemetry
+}
+
+// outboxEntryOverhead approximates the bytes an entry adds to an upload
+// besides its payload (IDs, operation, JSON framing)
+const outboxEntryOverhead = 128
+
+// outboxRankSQL ranks an outbox row by its class's position in OutboxClasses
+func outboxRankSQL() string {
+	rank := make(map[string]int, len(OutboxClasses))
+	for i, class := range OutboxClasses {
+		rank[class] = i
+	}
+
+	var b strings.Builder
+	b.WriteString("CASE entity")
+	for entity, class := range outboxClassOf {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", entity, rank[class])
+	}
+	fmt.Fprintf(&b, " ELSE %d END", rank[OutboxClassTelemetry])
+	return b.String()
+}
+
+// outboxClassSQL selects the entries of class for a WHERE clause
+func outboxClassSQL(class string) string {
+	var members, listed []string
+	for entity, c := range outboxClassOf {
+		listed = append(listed, "'"+entity+"'")
+		if c == class {
+			members = append(members, "'"+entity+"'")
+		}
+	}
+	if class == OutboxClassTelemetry {
+		return "entity NOT IN (" + strings.Join(listed, ", ") + ")"
+	}
+	return "entity IN (" + strings.Join(members, ", ") + ")"
+}
+
+// OutboxEntry is a pending change scheduled for upload to the server
+type OutboxEntry struct {
+	ID        int64  `json:"id"`
+	UID       string `json:"uid"`       // ULID, unique across terminals
+	Class     string `json:"class"`     // Priority class (see OutboxClass)
+	Entity    string `json:"entity"`    // Source table
+	EntityID  string `json:"entity_id"` // Primary key of the changed row
+	Operation string `json:"operation"`
+	Payload   string `json:"payload,omitempty"` // Encrypted row body (empty for deletes)
+	Attempts  int    `json:"attempts"`
+	CreatedAt string `json:"created_at"`
+}
+
+// createCDCTriggers installs change-data-capture triggers that enqueue an
+// outbox entry for every insert, update and delete on table. Writes made
+// through ApplySync are not captured, so server-delivered data is not echoed.
+func (db *DB) createCDCTriggers(table, idColumn, payloadColumn string) error {
+	const cdcEnabled = "(SELECT suppress FROM cdc_state WHERE id = 1) = 0"
+
+	triggers := []struct {
+		event     string
+		operation string
+		row       string
+	}{
+		{"INSERT", OutboxInsert, "NEW"},
+		{"UPDATE", OutboxUpdate, "NEW"},
+		{"DELETE", OutboxDelete, "OLD"},
+	}
+
+	for _, t := range triggers {
+		payload := t.row + "." + payloadColumn
+		if t.event == "DELETE" {
+			payload = "NULL"
+		}
+
+		triggerSQL := fmt.Sprintf(`
+		CREATE TRIGGER IF NOT EXISTS %[1]s_cdc_%[2]s
+		AFTER %[3]s ON %[1]s
+		FOR EACH ROW
+		WHEN %[4]s
+		BEGIN
+			INSERT INTO outbox (uid, entity, entity_id, operation, payload)
+			VALUES (%[8]s(), '%[1]s', %[5]s.%[6]s, '%[2]s', %[7]s);
+		END;
+		`, table, t.operation, t.event, cdcEnabled, t.row, idColumn, payload, newIDFunction)
+
+		if _, err := db.conn.Exec(triggerSQL); err != nil {
+			return fmt.Errorf("failed to create %s trigger on %s: %w", strings.ToLower(t.event), table, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateOutboxUIDs drops CDC triggers created before outbox entries had a
+// ULID, so initSchema recreates them, and gives queued entries one in
+// queue order
+func (db *DB) migrateOutboxUIDs() error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range cdcTables {
+		for _, operation := range []string{OutboxInsert, OutboxUpdate, OutboxDelete} {
+			if _, err := tx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s_cdc_%s", t.table, operation)); err != nil {
+				return fmt.Errorf("failed to drop %s trigger on %s: %w", operation, t.table, err)
+			}
+		}
+	}
+
+	rows, err := tx.Query("SELECT id FROM outbox WHERE uid IS NULL ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to query outbox: %w", err)
+	}
+	var pending []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan outbox row: %w", err)
+		}
+		pending = append(pending, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating outbox: %w", err)
+	}
+
+	for _, id := range pending {
+		if _, err := tx.Exec("UPDATE outbox SET uid = ? WHERE id = ?", ids.New(), id); err != nil {
+			return fmt.Errorf("failed to assign outbox uid: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit outbox uid migration: %w", err)
+	}
+	return nil
+}
+
+// ApplySync executes fn in a transaction with change capture suppressed.
+// Use it for data arriving from the server so it is not queued back.
+func (db *DB) ApplySync(fn func(*sql.Tx) error) error {
+	return db.applySyncThen(fn, nil)
+}
+
+// applySyncThen is ApplySync with a post-commit hook (see transactionThen)
+func (db *DB) applySyncThen(fn func(*sql.Tx) error, committed func()) error {
+	return db.transactionThen(context.Background(), func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE cdc_state SET suppress = 1 WHERE id = 1"); err != nil {
+			return fmt.Errorf("failed to suppress change capture: %w", err)
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE cdc_state SET suppress = 0 WHERE id = 1"); err != nil {
+			return fmt.Errorf("failed to restore change capture: %w", err)
+		}
+		return nil
+	}, committed)
+}
+
+// --- Outbox Methods ---
+
+// GetPendingOutbox returns up to limit unsynced outbox entries in upload
+// order (see NextOutboxBatch)
+func (db *DB) GetPendingOutbox(limit int) ([]OutboxEntry, error) {
+	return db.NextOutboxBatch(limit, 0)
+}
+
+// NextOutboxBatch returns the next unsynced outbox entries to upload: by
+// priority class, oldest first within a class. It stops at limit entries or
+// once the batch would exceed maxBytes (0 means no byte budget), so the
+// uploader can size batches to the link; the first entry is always included.
+func (db *DB) NextOutboxBatch(limit, maxBytes int) ([]OutboxEntry, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	query := fmt.Sprintf(`
+		SELECT id, COALESCE(uid, ''), entity, entity_id, operation, COALESCE(payload, ''), attempts,
+			strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', created_at)
+		FROM outbox WHERE synced_at IS NULL ORDER BY %s, id LIMIT ?
+	`, outboxRankSQL())
+
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	size := 0
+	for rows.Next() {
+		var e OutboxEntry
+		if err := rows.Scan(&e.ID, &e.UID, &e.Entity, &e.EntityID, &e.Operation, &e.Payload, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox row: %w", err)
+		}
+		e.Class = OutboxClass(e.Entity)
+
+		size += len(e.Payload) + outboxEntryOverhead
+		if maxBytes > 0 && size > maxBytes && len(entries) > 0 {
+			break
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CountPendingOutbox returns the number of unsynced outbox entries
+func (db *DB) CountPendingOutbox() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM outbox WHERE synced_at IS NULL").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count outbox: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountPendingOutboxByClass returns the number of unsynced outbox entries
+// per priority class
+func (db *DB) CountPendingOutboxByClass() (map[string]int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	counts := make(map[string]int, len(OutboxClasses))
+	for _, class := range OutboxClasses {
+		var count int
+		query := "SELECT COUNT(*) FROM outbox WHERE synced_at IS NULL AND " + outboxClassSQL(class)
+		if err := db.conn.QueryRow(query).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count outbox: %w", err)
+		}
+		counts[class] = count
+	}
+
+	return counts, nil
+}
+
+// MarkOutboxSynced flags outbox entries as delivered to the server. A sync
+// that reaches the server has the sync layer end offline mode first, so
+// the outbox can be drained.
+func (db *DB) MarkOutboxSynced(ids []int64) error {
+	return db.updateOutbox("UPDATE outbox SET synced_at = CURRENT_TIMESTAMP WHERE id = ?", ids)
+}
+
+// MarkOutboxSyncedThrough flags every pending entry up to and including
+// lastID as delivered (the server acknowledges outbox entries by cursor)
+func (db *DB) MarkOutboxSyncedThrough(lastID int64) (int64, error) {
+	if db.refusesWrites() {
+		return 0, ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	result, err := db.conn.Exec("UPDATE outbox SET synced_at = CURRENT_TIMESTAMP WHERE synced_at IS NULL AND id <= ?", lastID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge outbox: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// MarkOutboxClassSyncedThrough is MarkOutboxSyncedThrough for one priority
+// class. Uploads run ahead in urgent classes, so a single cursor would also
+// acknowledge older entries of other classes that were not sent yet.
+func (db *DB) MarkOutboxClassSyncedThrough(class string, lastID int64) (int64, error) {
+	if db.refusesWrites() {
+		return 0, ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	query := "UPDATE outbox SET synced_at = CURRENT_TIMESTAMP WHERE synced_at IS NULL AND id <= ? AND " + outboxClassSQL(class)
+	result, err := db.conn.Exec(query, lastID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge outbox: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// MarkOutboxAttempted increments the attempt counter of outbox entries
+func (db *DB) MarkOutboxAttempted(ids []int64) error {
+	return db.updateOutbox("UPDATE outbox SET attempts = attempts + 1 WHERE id = ?", ids)
+}
+
+// updateOutbox runs a per-entry outbox statement in one transaction
+func (db *DB) updateOutbox(query string, ids []int64) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("failed to update outbox entry %d: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+func TestCDC_LocalChangesFeedOutbox(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	product := &Product{ID: "p1", Barcode: "111", Name: "Milk", Price: 990}
+	if err := db.UpsertProduct(product, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	product.Price = 1090
+	if err := db.UpsertProduct(product, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	entries, err := db.GetPendingOutbox(10)
+	if err != nil {
+		t.Fatalf("GetPendingOutbox failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 outbox entries, got %d", len(entries))
+	}
+	if entries[0].Operation != OutboxInsert || entries[1].Operation != OutboxUpdate {
+		t.Errorf("Unexpected operations: %s, %s", entries[0].Operation, entries[1].Operation)
+	}
+	if entries[0].Entity != "products" || entries[0].EntityID != "p1" {
+		t.Errorf("Unexpected entity: %s/%s", entries[0].Entity, entries[0].EntityID)
+	}
+	if entries[1].Payload == "" {
+		t.Error("Expected encrypted payload on update entry")
+	}
+	if !ids.Valid(entries[0].UID) || entries[1].UID <= entries[0].UID {
+		t.Errorf("Expected ascending ULIDs, got %q and %q", entries[0].UID, entries[1].UID)
+	}
+
+	if err := db.MarkOutboxSynced([]int64{entries[0].ID, entries[1].ID}); err != nil {
+		t.Fatalf("MarkOutboxSynced failed: %v", err)
+	}
+	if count, _ := db.CountPendingOutbox(); count != 0 {
+		t.Errorf("Expected empty outbox after sync, got %d", count)
+	}
+}
+
+func TestCDC_DirectSQLIsCaptured(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.UpsertProduct(&Product{ID: "p1", Barcode: "111"}, ProductSourceLocal)
+
+	// Changes bypassing the repository are captured too
+	err := db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM products WHERE id = ?", "p1")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	entries, _ := db.GetPendingOutbox(10)
+	if len(entries) != 2 || entries[1].Operation != OutboxDelete {
+		t.Errorf("Expected insert and delete entries, got %+v", entries)
+	}
+}
+
+func TestCDC_SyncAppliedChangesAreNotEchoed(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.UpsertProduct(&Product{ID: "p1", Barcode: "111"}, ProductSourceSync); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	if count, _ := db.CountPendingOutbox(); count != 0 {
+		t.Errorf("Expected no outbox entries for server data, got %d", count)
+	}
+
+	// Capture is re-enabled after the sync transaction
+	db.UpsertProduct(&Product{ID: "p2", Barcode: "222"}, ProductSourceLocal)
+	if count, _ := db.CountPendingOutbox(); count != 1 {
+		t.Errorf("Expected change capture to resume, got %d entries", count)
+	}
+}
+
+func TestOutbox_DrainsByPriority(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A backlog where telemetry queued up first
+	conn := db.GetConnection()
+	for _, entity := range []string{"operator_stats", "operator_stats", "products", "sales", "stock_adjustments", "sales"} {
+		if _, err := conn.Exec("INSERT INTO outbox (entity, entity_id, operation, payload) VALUES (?, 'x', 'insert', ?)", entity, strings.Repeat("a", 1000)); err != nil {
+			t.Fatalf("Failed to queue %s: %v", entity, err)
+		}
+	}
+
+	entries, err := db.NextOutboxBatch(10, 0)
+	if err != nil {
+		t.Fatalf("NextOutboxBatch failed: %v", err)
+	}
+	var classes []string
+	for _, e := range entries {
+		classes = append(classes, e.Class)
+	}
+	want := []string{OutboxClassTransactions, OutboxClassTransactions, OutboxClassStock, OutboxClassStock, OutboxClassTelemetry, OutboxClassTelemetry}
+	if strings.Join(classes, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected upload order %v, got %v", want, classes)
+	}
+	if entries[0].ID > entries[1].ID {
+		t.Error("Expected oldest first within a class")
+	}
+
+	// The byte budget cuts the batch, but never below one entry
+	if batch, _ := db.NextOutboxBatch(10, 2500); len(batch) != 2 {
+		t.Errorf("Expected 2 entries within 2500 bytes, got %d", len(batch))
+	}
+	if batch, _ := db.NextOutboxBatch(10, 10); len(batch) != 1 {
+		t.Errorf("Expected 1 entry with a tiny budget, got %d", len(batch))
+	}
+
+	// Acknowledging the sales class leaves older telemetry pending
+	if _, err := db.MarkOutboxClassSyncedThrough(OutboxClassTransactions, entries[1].ID); err != nil {
+		t.Fatalf("MarkOutboxClassSyncedThrough failed: %v", err)
+	}
+	counts, err := db.CountPendingOutboxByClass()
+	if err != nil {
+		t.Fatalf("CountPendingOutboxByClass failed: %v", err)
+	}
+	if counts[OutboxClassTransactions] != 0 || counts[OutboxClassStock] != 2 || counts[OutboxClassTelemetry] != 2 {
+		t.Errorf("Unexpected pending counts: %v", counts)
+	}
+}
+
+func TestOutbox_MigratesEntriesWithoutUID(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+
+	db, err := New(&Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	// Simulate a version 7 database: queued entries and triggers without ULIDs
+	for _, id := range []string{"p1", "p2"} {
+		if err := db.UpsertProduct(&Product{ID: id, Barcode: id, Name: "Tea", Price: 500}, ProductSourceLocal); err != nil {
+			t.Fatalf("UpsertProduct failed: %v", err)
+		}
+	}
+	conn := db.GetConnection()
+	conn.Exec("UPDATE outbox SET uid = NULL")
+	conn.Exec("DROP TRIGGER products_cdc_insert")
+	conn.Exec(`CREATE TRIGGER products_cdc_insert AFTER INSERT ON products FOR EACH ROW
+		BEGIN INSERT INTO outbox (entity, entity_id, operation, payload) VALUES ('products', NEW.id, 'insert', NEW.data); END`)
+	conn.Exec("PRAGMA user_version = 7")
+	db.Close()
+
+	db, err = New(&Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.UpsertProduct(&Product{ID: "p3", Barcode: "p3", Name: "Tea", Price: 500}, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	entries, err := db.GetPendingOutbox(10)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d (%v)", len(entries), err)
+	}
+	for i, e := range entries {
+		if !ids.Valid(e.UID) {
+			t.Errorf("Entry %d has no ULID after migration: %q", e.ID, e.UID)
+		}
+		if i > 0 && e.UID <= entries[i-1].UID {
+			t.Errorf("Expected ULIDs in queue order, got %q after %q", e.UID, entries[i-1].UID)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// productIndex is the decoded catalog kept in memory and keyed by barcode,
+// so a scan costs a map lookup instead of a query, a decryption and a JSON
+// decode. It is loaded on first use and then updated by the repository
+// methods after each commit, under the database write lock; raw SQL that
+// changes products must call InvalidateProductIndex.
+type productIndex struct {
+	mu        sync.RWMutex
+	loaded    bool
+	byBarcode map[string]*indexedProduct
+	barcodeOf map[string]string // Product ID to barcode, for deletes and barcode changes
+}
+
+// indexedProduct is an immutable catalog entry
+type indexedProduct struct {
+	product Product
+	json    []byte // product encoded once, for responses that embed it as is
+}
+
+// newIndexedProduct snapshots a product and pre-encodes it
+func newIndexedProduct(product *Product) (*indexedProduct, error) {
+	encoded, err := json.Marshal(product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode product: %w", err)
+	}
+	return &indexedProduct{product: *product, json: encoded}, nil
+}
+
+// lookup returns the entry for barcode; ok is false until the index is loaded
+func (idx *productIndex) lookup(barcode string) (entry *indexedProduct, ok bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if !idx.loaded {
+		return nil, false
+	}
+	return idx.byBarcode[barcode], true
+}
+
+// put adds or replaces products; a no-op until the index is loaded
+func (idx *productIndex) put(products ...*Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.loaded {
+		return
+	}
+	for _, product := range products {
+		entry, err := newIndexedProduct(product)
+		if err != nil {
+			// Cannot happen for a plain struct; fall back to a reload
+			idx.loaded = false
+			return
+		}
+		if old, ok := idx.barcodeOf[product.ID]; ok && old != product.Barcode {
+			delete(idx.byBarcode, old)
+		}
+		idx.byBarcode[product.Barcode] = entry
+		idx.barcodeOf[product.ID] = product.Barcode
+	}
+}
+
+// remove drops products by ID
+func (idx *productIndex) remove(ids ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, id := range ids {
+		if barcode, ok := idx.barcodeOf[id]; ok {
+			delete(idx.byBarcode, barcode)
+			delete(idx.barcodeOf, id)
+		}
+	}
+}
+
+// reset forgets the index so the next lookup reloads it
+func (idx *productIndex) reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.loaded = false
+	idx.byBarcode = nil
+	idx.barcodeOf = nil
+}
+
+// productEntry returns the index entry for barcode, loading the index on
+// first use
+func (db *DB) productEntry(barcode string) (*indexedProduct, error) {
+	entry, ok := db.products.lookup(barcode)
+	if !ok {
+		if err := db.loadProductIndex(); err != nil {
+			return nil, err
+		}
+		entry, _ = db.products.lookup(barcode)
+	}
+	if entry == nil {
+		return nil, ErrProductNotFound
+	}
+	return entry, nil
+}
+
+// loadProductIndex decodes the whole catalog into the index. The read lock
+// keeps writers (and their index updates) out until the load is published.
+func (db *DB) loadProductIndex() error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if _, ok := db.products.lookup(""); ok {
+		return nil // Loaded by a concurrent caller
+	}
+
+	rows, err := db.conn.Query("SELECT id, data, version FROM products")
+	if err != nil {
+		return fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	byBarcode := make(map[string]*indexedProduct)
+	barcodeOf := make(map[string]string)
+	for rows.Next() {
+		var id, encryptedData string
+		var version int64
+		if err := rows.Scan(&id, &encryptedData, &version); err != nil {
+			return fmt.Errorf("failed to scan product: %w", err)
+		}
+
+		product, err := db.decryptProduct(id, encryptedData)
+		if err != nil {
+			return err
+		}
+		product.Version = version
+
+		entry, err := newIndexedProduct(product)
+		if err != nil {
+			return err
+		}
+		byBarcode[product.Barcode] = entry
+		barcodeOf[product.ID] = product.Barcode
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read products: %w", err)
+	}
+
+	db.products.mu.Lock()
+	db.products.byBarcode = byBarcode
+	db.products.barcodeOf = barcodeOf
+	db.products.loaded = true
+	db.products.mu.Unlock()
+
+	return nil
+}
+
+// ProductFilter selects products in FindProducts; zero fields match all
+type ProductFilter struct {
+	Query  string // Case-insensitive substring of the name, barcode or SKU
+	Active *bool
+}
+
+// matches reports whether product passes the filter
+func (f ProductFilter) matches(product *Product) bool {
+	if f.Active != nil && product.Active != *f.Active {
+		return false
+	}
+	if f.Query == "" {
+		return true
+	}
+	query := strings.ToLower(f.Query)
+	return strings.Contains(strings.ToLower(product.Name), query) ||
+		strings.Contains(strings.ToLower(product.Barcode), query) ||
+		strings.Contains(strings.ToLower(product.SKU), query)
+}
+
+// FindProducts returns the products matching filter ordered by barcode,
+// searched in the product index rather than decrypting every row
+func (db *DB) FindProducts(filter ProductFilter) ([]Product, error) {
+	if _, ok := db.products.lookup(""); !ok {
+		if err := db.loadProductIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	db.products.mu.RLock()
+	products := []Product{}
+	for _, entry := range db.products.byBarcode {
+		if filter.matches(&entry.product) {
+			products = append(products, entry.product)
+		}
+	}
+	db.products.mu.RUnlock()
+
+	sort.Slice(products, func(i, j int) bool { return products[i].Barcode < products[j].Barcode })
+	return products, nil
+}
+
+// GetProductJSON returns the JSON encoding of the product with barcode,
+// served from the product index without allocating. The slice is shared:
+// callers must not modify it.
+func (db *DB) GetProductJSON(barcode string) ([]byte, error) {
+	entry, err := db.productEntry(barcode)
+	if err != nil {
+		return nil, err
+	}
+	return entry.json, nil
+}
+
+// InvalidateProductIndex makes the next lookup reload the catalog. Call it
+// after changing the products table outside the repository methods.
+func (db *DB) InvalidateProductIndex() {
+	db.products.reset()
+}
@@ -0,0 +1,365 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrProductNotFound is returned when no product matches a lookup
+	ErrProductNotFound = errors.New("product not found")
+
+	// ErrVersionConflict is returned when a record changed since it was read
+	ErrVersionConflict = errors.New("record was modified concurrently")
+)
+
+// Product sources
+const (
+	ProductSourceLocal  = "local"  // Created or edited on this terminal
+	ProductSourceSync   = "sync"   // Delivered by the regular catalog sync
+	ProductSourceRemote = "remote" // Cached from a read-through remote lookup
+)
+
+// Product represents a catalog item
+type Product struct {
+	ID        string `json:"id"`
+	Barcode   string `json:"barcode"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Price     int64  `json:"price"`    // Minor currency units
+	TaxRate   int    `json:"tax_rate"` // Basis points (e.g. 1200 = 12%)
+	Active    bool   `json:"active"`
+	UpdatedAt string `json:"updated_at"` // ISO 8601 timestamp
+	Version   int64  `json:"version"`    // Optimistic lock, maintained by the repository
+}
+
+// CatalogGap records a barcode that was scanned but missing from the local catalog
+type CatalogGap struct {
+	Barcode     string `json:"barcode"`
+	Hits        int    `json:"hits"`
+	Resolved    bool   `json:"resolved"` // Found by the remote lookup
+	FirstSeenAt string `json:"first_seen_at"`
+	LastSeenAt  string `json:"last_seen_at"`
+}
+
+// --- Products Table Methods ---
+
+// GetProductByBarcode retrieves a product by barcode from the product index
+// (see GetProductJSON); the returned product is the caller's copy
+func (db *DB) GetProductByBarcode(barcode string) (*Product, error) {
+	entry, err := db.productEntry(barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	product := entry.product
+	return &product, nil
+}
+
+// ListProducts returns a page of the catalog ordered by barcode
+func (db *DB) ListProducts(offset, limit int) ([]Product, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT id, data, version FROM products ORDER BY barcode LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		var id, encryptedData string
+		var version int64
+		if err := rows.Scan(&id, &encryptedData, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+
+		product, err := db.decryptProduct(id, encryptedData)
+		if err != nil {
+			return nil, err
+		}
+		product.Version = version
+		products = append(products, *product)
+	}
+
+	return products, rows.Err()
+}
+
+// CountProducts returns the number of products in the catalog
+func (db *DB) CountProducts() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM products").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpsertProduct stores a product keyed by ID (encrypts automatically).
+// Local edits are captured into the sync outbox; products delivered by
+// head office (sync or remote lookup) are applied without echoing back.
+func (db *DB) UpsertProduct(product *Product, source string) error {
+	if product.ID == "" || product.Barcode == "" {
+		return fmt.Errorf("product id and barcode are required")
+	}
+
+	write := func(tx *sql.Tx) error {
+		return db.upsertProduct(tx, product, source)
+	}
+	committed := func() {
+		db.products.put(product)
+		db.catalogChanged("products")
+	}
+
+	if source == ProductSourceLocal {
+		return db.transactionThen(context.Background(), write, committed)
+	}
+
+	return db.applySyncThen(write, committed)
+}
+
+// ImportProducts stores a batch of locally imported products in one
+// transaction. Like other local edits they are captured into the sync
+// outbox.
+func (db *DB) ImportProducts(products []*Product) error {
+	for _, product := range products {
+		if product.ID == "" || product.Barcode == "" {
+			return fmt.Errorf("product id and barcode are required")
+		}
+	}
+
+	return db.transactionThen(context.Background(), func(tx *sql.Tx) error {
+		for _, product := range products {
+			if err := db.upsertProduct(tx, product, ProductSourceLocal); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() {
+		for _, product := range products {
+			db.products.put(product)
+		}
+		db.catalogChanged("products")
+	})
+}
+
+// UpdateProduct saves a local edit only if the stored version still matches
+// product.Version, returning ErrVersionConflict when another writer got there
+// first. On success product.Version is advanced.
+func (db *DB) UpdateProduct(product *Product) error {
+	if product.ID == "" || product.Barcode == "" {
+		return fmt.Errorf("product id and barcode are required")
+	}
+
+	expected := product.Version
+
+	err := db.transactionThen(context.Background(), func(tx *sql.Tx) error {
+		product.UpdatedAt = time.Now().Format(time.RFC3339)
+		product.Version = expected + 1
+
+		encryptedData, err := db.encryptProduct(product)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`
+			UPDATE products SET
+				barcode = ?, data = ?, source = ?,
+				version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND version = ?
+		`, product.Barcode, encryptedData, ProductSourceLocal, product.ID, expected)
+		if err != nil {
+			return fmt.Errorf("failed to update product: %w", err)
+		}
+
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			var exists int
+			err := tx.QueryRow("SELECT 1 FROM products WHERE id = ?", product.ID).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return ErrProductNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("failed to query product: %w", err)
+			}
+			return ErrVersionConflict
+		}
+
+		return nil
+	}, func() {
+		db.products.put(product)
+		db.catalogChanged("products")
+	})
+	if err != nil {
+		product.Version = expected
+	}
+
+	return err
+}
+
+// ApplyProducts applies a batch of server-delivered catalog changes in one
+// transaction without feeding them back into the outbox
+func (db *DB) ApplyProducts(products []Product, deletedIDs []string) error {
+	return db.applySyncThen(func(tx *sql.Tx) error {
+		for i := range products {
+			if err := db.upsertProduct(tx, &products[i], ProductSourceSync); err != nil {
+				return err
+			}
+		}
+		for _, id := range deletedIDs {
+			if _, err := tx.Exec("DELETE FROM products WHERE id = ?", id); err != nil {
+				return fmt.Errorf("failed to delete product %s: %w", id, err)
+			}
+		}
+		return nil
+	}, func() {
+		for i := range products {
+			db.products.put(&products[i])
+		}
+		db.products.remove(deletedIDs...)
+		db.catalogChanged("products")
+	})
+}
+
+// upsertProduct writes a product within a transaction
+func (db *DB) upsertProduct(tx *sql.Tx, product *Product, source string) error {
+	if product.UpdatedAt == "" {
+		product.UpdatedAt = time.Now().Format(time.RFC3339)
+	}
+
+	encryptedData, err := db.encryptProduct(product)
+	if err != nil {
+		return err
+	}
+
+	// Unconditional writes still bump the version so in-flight optimistic
+	// updates based on the old record are rejected
+	query := `
+		INSERT INTO products (id, barcode, data, source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO UPDATE SET
+			barcode = excluded.barcode,
+			data = excluded.data,
+			source = excluded.source,
+			version = products.version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING version
+	`
+
+	if err := tx.QueryRow(query, product.ID, product.Barcode, encryptedData, source).Scan(&product.Version); err != nil {
+		return fmt.Errorf("failed to upsert product: %w", err)
+	}
+
+	return nil
+}
+
+// encryptProduct serializes and encrypts a product record
+func (db *DB) encryptProduct(product *Product) (string, error) {
+	jsonData, err := json.Marshal(product)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal product: %w", err)
+	}
+
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("products", product.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt product: %w", err)
+	}
+
+	return encryptedData, nil
+}
+
+// decryptProduct decrypts and parses a stored product record
+func (db *DB) decryptProduct(id, encryptedData string) (*Product, error) {
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("products", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt product: %w", err)
+	}
+
+	var product Product
+	if err := json.Unmarshal(jsonData, &product); err != nil {
+		return nil, fmt.Errorf("failed to parse product: %w", err)
+	}
+
+	return &product, nil
+}
+
+// --- Catalog Gap Methods ---
+
+// RecordCatalogGap notes a barcode missing from the local catalog.
+// Repeated scans increment the hit counter; resolved marks that the
+// remote lookup found the product.
+func (db *DB) RecordCatalogGap(barcode string, resolved bool) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	query := `
+		INSERT INTO catalog_gaps (barcode, hits, resolved, reported, first_seen_at, last_seen_at)
+		VALUES (?, 1, ?, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(barcode) DO UPDATE SET
+			hits = hits + 1,
+			resolved = excluded.resolved,
+			reported = 0,
+			last_seen_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := db.conn.Exec(query, barcode, resolved); err != nil {
+		return fmt.Errorf("failed to record catalog gap: %w", err)
+	}
+
+	return nil
+}
+
+// GetUnreportedCatalogGaps returns catalog gaps not yet sent in sync telemetry
+func (db *DB) GetUnreportedCatalogGaps() ([]CatalogGap, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	query := `
+		SELECT barcode, hits, resolved, first_seen_at, last_seen_at
+		FROM catalog_gaps WHERE reported = 0 ORDER BY last_seen_at
+	`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query catalog gaps: %w", err)
+	}
+	defer rows.Close()
+
+	var gaps []CatalogGap
+	for rows.Next() {
+		var gap CatalogGap
+		if err := rows.Scan(&gap.Barcode, &gap.Hits, &gap.Resolved, &gap.FirstSeenAt, &gap.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog gap row: %w", err)
+		}
+		gaps = append(gaps, gap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating catalog gaps: %w", err)
+	}
+
+	return gaps, nil
+}
+
+// MarkCatalogGapsReported flags catalog gaps as delivered to head office
+func (db *DB) MarkCatalogGapsReported(barcodes []string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		for _, barcode := range barcodes {
+			if _, err := tx.Exec("UPDATE catalog_gaps SET reported = 1 WHERE barcode = ?", barcode); err != nil {
+				return fmt.Errorf("failed to mark catalog gap reported: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestUpdateProduct_OptimisticLocking(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	product := &Product{ID: "p1", Barcode: "111", Name: "Milk", Price: 990}
+	if err := db.UpsertProduct(product, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	if product.Version != 1 {
+		t.Fatalf("Expected version 1 after insert, got %d", product.Version)
+	}
+
+	// Two frontends read the same record
+	first, err := db.GetProductByBarcode("111")
+	if err != nil {
+		t.Fatalf("GetProductByBarcode failed: %v", err)
+	}
+	second, _ := db.GetProductByBarcode("111")
+
+	first.Price = 1090
+	if err := db.UpdateProduct(first); err != nil {
+		t.Fatalf("First update failed: %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Expected version 2 after update, got %d", first.Version)
+	}
+
+	second.Price = 1190
+	if err := db.UpdateProduct(second); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict, got %v", err)
+	}
+	if second.Version != 1 {
+		t.Errorf("Expected version to stay 1 after conflict, got %d", second.Version)
+	}
+
+	stored, _ := db.GetProductByBarcode("111")
+	if stored.Price != 1090 || stored.Version != 2 {
+		t.Errorf("Expected first update to win, got price %d version %d", stored.Price, stored.Version)
+	}
+}
+
+func TestUpdateProduct_NotFound(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	err := db.UpdateProduct(&Product{ID: "missing", Barcode: "000", Version: 1})
+	if !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
+
+func TestUpsertProduct_SyncBumpsVersion(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	product := &Product{ID: "p1", Barcode: "111", Name: "Milk", Price: 990}
+	if err := db.UpsertProduct(product, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	stale, _ := db.GetProductByBarcode("111")
+
+	// A sync overwrite invalidates edits based on the old record
+	synced := &Product{ID: "p1", Barcode: "111", Name: "Milk 1L", Price: 1000}
+	if err := db.UpsertProduct(synced, ProductSourceSync); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	stale.Name = "Local edit"
+	if err := db.UpdateProduct(stale); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict after sync, got %v", err)
+	}
+}
+
+func TestProductIndex_FollowsWrites(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.UpsertProduct(&Product{ID: "p1", Barcode: "111", Name: "Milk"}, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	// First lookup loads the index
+	product, err := db.GetProductByBarcode("111")
+	if err != nil {
+		t.Fatalf("GetProductByBarcode failed: %v", err)
+	}
+
+	// The caller's copy must not leak into the index
+	product.Name = "Scribbled"
+	if again, _ := db.GetProductByBarcode("111"); again.Name != "Milk" {
+		t.Errorf("Index entry was modified through a returned product: %q", again.Name)
+	}
+
+	// A barcode change moves the entry
+	product.Name = "Milk 1L"
+	product.Barcode = "222"
+	if err := db.UpdateProduct(product); err != nil {
+		t.Fatalf("UpdateProduct failed: %v", err)
+	}
+	if _, err := db.GetProductByBarcode("111"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected old barcode to be gone, got %v", err)
+	}
+	moved, err := db.GetProductByBarcode("222")
+	if err != nil || moved.Name != "Milk 1L" || moved.Version != 2 {
+		t.Fatalf("Expected moved product at version 2, got %+v, %v", moved, err)
+	}
+
+	// Sync batches add and delete
+	if err := db.ApplyProducts([]Product{{ID: "p2", Barcode: "333", Name: "Bread"}}, []string{"p1"}); err != nil {
+		t.Fatalf("ApplyProducts failed: %v", err)
+	}
+	if _, err := db.GetProductByBarcode("222"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected deleted product to be gone, got %v", err)
+	}
+	data, err := db.GetProductJSON("333")
+	if err != nil {
+		t.Fatalf("GetProductJSON failed: %v", err)
+	}
+	var decoded Product
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Name != "Bread" {
+		t.Errorf("Expected pre-encoded Bread, got %s (%v)", data, err)
+	}
+
+	// A rebuilt index agrees with the incrementally maintained one
+	db.InvalidateProductIndex()
+	if rebuilt, err := db.GetProductByBarcode("333"); err != nil || rebuilt.Name != "Bread" {
+		t.Errorf("Expected Bread after reload, got %+v, %v", rebuilt, err)
+	}
+	if _, err := db.GetProductByBarcode("222"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected deleted product to stay gone after reload, got %v", err)
+	}
+}
+
+// seedCatalog stores n products with barcodes 0..n-1
+func seedCatalog(b *testing.B, db *DB, n int) {
+	products := make([]Product, n)
+	for i := range products {
+		products[i] = Product{ID: "p" + strconv.Itoa(i), Barcode: strconv.Itoa(i), Name: "Item", Price: 990, Active: true}
+	}
+	if err := db.ApplyProducts(products, nil); err != nil {
+		b.Fatalf("ApplyProducts failed: %v", err)
+	}
+}
+
+func BenchmarkGetProductJSON(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+	seedCatalog(b, db, 10000)
+
+	if _, err := db.GetProductJSON("4242"); err != nil {
+		b.Fatalf("GetProductJSON failed: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.GetProductJSON("4242"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetProductByBarcode(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+	seedCatalog(b, db, 10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.GetProductByBarcode("4242"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestListProducts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for i, barcode := range []string{"333", "111", "222"} {
+		product := &Product{ID: "p" + strconv.Itoa(i), Barcode: barcode, Name: "Item " + barcode, Price: 100}
+		if err := db.UpsertProduct(product, ProductSourceSync); err != nil {
+			t.Fatalf("UpsertProduct failed: %v", err)
+		}
+	}
+
+	page, err := db.ListProducts(1, 2)
+	if err != nil {
+		t.Fatalf("ListProducts failed: %v", err)
+	}
+	if len(page) != 2 || page[0].Barcode != "222" || page[1].Barcode != "333" || page[0].Version != 1 {
+		t.Errorf("Expected the second page ordered by barcode, got %+v", page)
+	}
+	if count, err := db.CountProducts(); err != nil || count != 3 {
+		t.Errorf("Expected 3 products, got %d, %v", count, err)
+	}
+}
+
+func TestFindProducts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	products := []*Product{
+		{ID: "p1", Barcode: "300", SKU: "MILK-1L", Name: "Whole Milk", Active: true},
+		{ID: "p2", Barcode: "100", SKU: "MILK-2L", Name: "Skimmed milk", Active: false},
+		{ID: "p3", Barcode: "200", SKU: "BREAD", Name: "Rye bread", Active: true},
+	}
+	for _, p := range products {
+		if err := db.UpsertProduct(p, ProductSourceSync); err != nil {
+			t.Fatalf("UpsertProduct failed: %v", err)
+		}
+	}
+
+	found, err := db.FindProducts(ProductFilter{Query: "MILK"})
+	if err != nil {
+		t.Fatalf("FindProducts failed: %v", err)
+	}
+	if len(found) != 2 || found[0].Barcode != "100" || found[1].Barcode != "300" {
+		t.Errorf("Expected both milks ordered by barcode, got %+v", found)
+	}
+
+	active := true
+	found, _ = db.FindProducts(ProductFilter{Query: "milk", Active: &active})
+	if len(found) != 1 || found[0].ID != "p1" {
+		t.Errorf("Expected only the active milk, got %+v", found)
+	}
+
+	// Writes are visible through the index
+	products[2].Name = "Rye milk bread"
+	if err := db.UpsertProduct(products[2], ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	if found, _ = db.FindProducts(ProductFilter{Query: "milk"}); len(found) != 3 {
+		t.Errorf("Expected the renamed product to match, got %+v", found)
+	}
+}
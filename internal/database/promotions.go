@@ -0,0 +1,189 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Promotion kinds
+const (
+	PromotionPercent = "percent" // Value is basis points off the price (1000 = 10%)
+	PromotionAmount  = "amount"  // Value is minor currency units off the price
+	PromotionPrice   = "price"   // Value is the promotional price
+)
+
+// Promotion is a price promotion synced from head office. It applies to
+// the listed SKUs within its validity window.
+type Promotion struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"` // PromotionPercent, PromotionAmount or PromotionPrice
+	Value      int64    `json:"value"`
+	SKUs       []string `json:"skus"`
+	ValidFrom  string   `json:"valid_from,omitempty"`  // ISO 8601 timestamp
+	ValidUntil string   `json:"valid_until,omitempty"` // ISO 8601 timestamp, exclusive
+}
+
+// ActiveAt reports whether the promotion runs at now. Unparsable bounds
+// are ignored, like recommendation rule windows.
+func (p *Promotion) ActiveAt(now time.Time) bool {
+	if p.ValidFrom != "" {
+		if from, err := time.Parse(time.RFC3339, p.ValidFrom); err == nil && now.Before(from) {
+			return false
+		}
+	}
+	if p.ValidUntil != "" {
+		if until, err := time.Parse(time.RFC3339, p.ValidUntil); err == nil && !now.Before(until) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns price after the promotion, never below zero
+func (p *Promotion) Apply(price int64) int64 {
+	switch p.Kind {
+	case PromotionPercent:
+		price -= price * p.Value / 10000
+	case PromotionAmount:
+		price -= p.Value
+	case PromotionPrice:
+		if p.Value < price {
+			price = p.Value
+		}
+	}
+	return max(price, 0)
+}
+
+// promotionIndex holds the synced promotions keyed by SKU, loaded on first
+// use and dropped whenever the promotion set is replaced
+type promotionIndex struct {
+	mu     sync.RWMutex
+	loaded bool
+	bySKU  map[string][]Promotion
+}
+
+// ReplacePromotions swaps the whole promotion set in one transaction.
+// Promotions come from head office, so the change is not captured into the
+// outbox.
+func (db *DB) ReplacePromotions(promotions []Promotion) error {
+	return db.applySyncThen(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM promotions"); err != nil {
+			return fmt.Errorf("failed to clear promotions: %w", err)
+		}
+
+		for _, promo := range promotions {
+			if promo.ID == "" || len(promo.SKUs) == 0 {
+				return fmt.Errorf("promotion %q is incomplete", promo.ID)
+			}
+			if promo.Kind != PromotionPercent && promo.Kind != PromotionAmount && promo.Kind != PromotionPrice {
+				return fmt.Errorf("promotion %s has an invalid kind: %s", promo.ID, promo.Kind)
+			}
+
+			data, err := json.Marshal(promo)
+			if err != nil {
+				return fmt.Errorf("failed to marshal promotion: %w", err)
+			}
+			if _, err := tx.Exec("INSERT INTO promotions (id, data) VALUES (?, ?)", promo.ID, string(data)); err != nil {
+				return fmt.Errorf("failed to insert promotion %s: %w", promo.ID, err)
+			}
+		}
+		return nil
+	}, func() {
+		db.resetPromotionIndex()
+		db.catalogChanged("promotions")
+	})
+}
+
+// GetPromotions returns all synced promotions
+func (db *DB) GetPromotions() ([]Promotion, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.queryPromotions()
+}
+
+// ActivePromotions returns the promotions running at now for sku, served
+// from the promotion index
+func (db *DB) ActivePromotions(sku string, now time.Time) ([]Promotion, error) {
+	db.promotions.mu.RLock()
+	loaded := db.promotions.loaded
+	db.promotions.mu.RUnlock()
+	if !loaded {
+		if err := db.loadPromotionIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	db.promotions.mu.RLock()
+	defer db.promotions.mu.RUnlock()
+
+	var active []Promotion
+	for _, promo := range db.promotions.bySKU[sku] {
+		if promo.ActiveAt(now) {
+			active = append(active, promo)
+		}
+	}
+	return active, nil
+}
+
+// loadPromotionIndex reads every promotion into the index. The read lock
+// keeps ReplacePromotions out until the load is published.
+func (db *DB) loadPromotionIndex() error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	promotions, err := db.queryPromotions()
+	if err != nil {
+		return err
+	}
+
+	bySKU := make(map[string][]Promotion)
+	for _, promo := range promotions {
+		for _, sku := range promo.SKUs {
+			bySKU[sku] = append(bySKU[sku], promo)
+		}
+	}
+
+	db.promotions.mu.Lock()
+	db.promotions.bySKU = bySKU
+	db.promotions.loaded = true
+	db.promotions.mu.Unlock()
+	return nil
+}
+
+// resetPromotionIndex makes the next lookup reload the promotions
+func (db *DB) resetPromotionIndex() {
+	db.promotions.mu.Lock()
+	db.promotions.loaded = false
+	db.promotions.bySKU = nil
+	db.promotions.mu.Unlock()
+}
+
+// queryPromotions reads the promotions table; the caller holds db.mu
+func (db *DB) queryPromotions() ([]Promotion, error) {
+	rows, err := db.conn.Query("SELECT data FROM promotions ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []Promotion
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+
+		var promo Promotion
+		if err := json.Unmarshal([]byte(data), &promo); err != nil {
+			return nil, fmt.Errorf("failed to parse promotion: %w", err)
+		}
+		promotions = append(promotions, promo)
+	}
+
+	return promotions, rows.Err()
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPromotions_ActiveAndReplaced(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	promos := []Promotion{
+		{ID: "P1", Kind: PromotionPercent, Value: 1000, SKUs: []string{"MILK"}},
+		{ID: "P2", Kind: PromotionAmount, Value: 50, SKUs: []string{"MILK", "BREAD"}, ValidUntil: "2026-02-01T00:00:00Z"},
+	}
+	if err := db.ReplacePromotions(promos); err != nil {
+		t.Fatalf("ReplacePromotions failed: %v", err)
+	}
+
+	active, err := db.ActivePromotions("MILK", now)
+	if err != nil {
+		t.Fatalf("ActivePromotions failed: %v", err)
+	}
+	if len(active) != 1 || active[0].ID != "P1" {
+		t.Errorf("Expected only the running promotion, got %+v", active)
+	}
+
+	// Replacing the set refreshes the loaded index
+	if err := db.ReplacePromotions([]Promotion{{ID: "P3", Kind: PromotionPrice, Value: 700, SKUs: []string{"BREAD"}}}); err != nil {
+		t.Fatalf("ReplacePromotions failed: %v", err)
+	}
+	if active, _ := db.ActivePromotions("MILK", now); len(active) != 0 {
+		t.Errorf("Expected MILK promotions dropped, got %+v", active)
+	}
+	if active, _ := db.ActivePromotions("BREAD", now); len(active) != 1 || active[0].ID != "P3" {
+		t.Errorf("Expected the new BREAD promotion, got %+v", active)
+	}
+
+	if err := db.ReplacePromotions([]Promotion{{ID: "P4", Kind: "bogus", SKUs: []string{"A"}}}); err == nil {
+		t.Error("Expected an invalid kind to be rejected")
+	}
+	if pending, _ := db.CountPendingOutbox(); pending != 0 {
+		t.Errorf("Synced promotions must not reach the outbox, pending %d", pending)
+	}
+}
+
+func TestPromotion_Apply(t *testing.T) {
+	tests := []struct {
+		promo Promotion
+		want  int64
+	}{
+		{Promotion{Kind: PromotionPercent, Value: 2500}, 750},
+		{Promotion{Kind: PromotionAmount, Value: 200}, 800},
+		{Promotion{Kind: PromotionAmount, Value: 5000}, 0},
+		{Promotion{Kind: PromotionPrice, Value: 600}, 600},
+		{Promotion{Kind: PromotionPrice, Value: 1200}, 1000},
+	}
+	for _, tt := range tests {
+		if got := tt.promo.Apply(1000); got != tt.want {
+			t.Errorf("%s %d: Apply(1000) = %d, want %d", tt.promo.Kind, tt.promo.Value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// RecommendationRule is an association rule synced from head office:
+// carts containing every Antecedent SKU are likely to add Consequent
+type RecommendationRule struct {
+	ID         string   `json:"id"`
+	Antecedent []string `json:"antecedent"` // SKUs that must all be in the cart
+	Consequent string   `json:"consequent"` // SKU to suggest
+	Confidence float64  `json:"confidence"` // P(consequent | antecedent), 0..1
+	Lift       float64  `json:"lift"`
+	Message    string   `json:"message,omitempty"`  // Prompt shown to the cashier
+	PromoID    string   `json:"promo_id,omitempty"` // Promotion that applies to the suggestion
+	ValidFrom  string   `json:"valid_from,omitempty"`
+	ValidUntil string   `json:"valid_until,omitempty"`
+}
+
+// ReplaceRecommendationRules swaps the whole rule set in one transaction.
+// Rules come from head office, so the change is not captured into the outbox.
+func (db *DB) ReplaceRecommendationRules(rules []RecommendationRule) error {
+	return db.ApplySync(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM recommendation_rules"); err != nil {
+			return fmt.Errorf("failed to clear recommendation rules: %w", err)
+		}
+
+		for _, rule := range rules {
+			if rule.ID == "" || rule.Consequent == "" || len(rule.Antecedent) == 0 {
+				return fmt.Errorf("recommendation rule %q is incomplete", rule.ID)
+			}
+
+			data, err := json.Marshal(rule)
+			if err != nil {
+				return fmt.Errorf("failed to marshal recommendation rule: %w", err)
+			}
+
+			if _, err := tx.Exec("INSERT INTO recommendation_rules (id, data) VALUES (?, ?)", rule.ID, string(data)); err != nil {
+				return fmt.Errorf("failed to insert recommendation rule %s: %w", rule.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetRecommendationRules returns all synced recommendation rules
+func (db *DB) GetRecommendationRules() ([]RecommendationRule, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT data FROM recommendation_rules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recommendation rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []RecommendationRule
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation rule: %w", err)
+		}
+
+		var rule RecommendationRule
+		if err := json.Unmarshal([]byte(data), &rule); err != nil {
+			return nil, fmt.Errorf("failed to parse recommendation rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
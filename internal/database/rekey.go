@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// rekeyBatchSize is the number of rows re-encrypted per query
+const rekeyBatchSize = 500
+
+// encryptedColumn is a column holding server-key ciphertext
+type encryptedColumn struct {
+	table  string
+	column string
+	aad    string // SQL expression yielding the row's rowAAD
+	where  string // Extra filter for tables mixing encrypted and plain rows
+}
+
+// encryptedColumns lists every column encrypted with the server key. Keep
+// it in sync with initSchema and the rowAAD calls; Rekey leaves anything
+// missing unreadable.
+var encryptedColumns = []encryptedColumn{
+	{table: "settings", column: "value", aad: "'settings/' || key"},
+	{table: "products", column: "data", aad: "'products/' || id"},
+	{table: "sales", column: "data", aad: "'sales/' || id"},
+	{table: "transaction_voids", column: "data", aad: "'transaction_voids/' || id"},
+	{table: "basket_lines", column: "data", aad: "'basket_lines/' || basket_id"},
+	{table: "devices", column: "data", aad: "'devices/' || id"},
+	{table: "users", column: "data", aad: "'users/' || id"},
+	{table: "customers", column: "data", aad: "'customers/' || id"},
+	{table: "loyalty_entries", column: "data", aad: "'loyalty_entries/' || id"},
+	{table: "device_audit", column: "data", aad: "'device_audit/' || device_id"},
+	{table: "audit_log", column: "data", aad: "'audit_log/' || event"},
+	{table: "audit_anchors", column: "data", aad: "'audit_anchors/' || id"},
+	{table: "stock_adjustments", column: "data", aad: "'stock_adjustments/' || id"},
+	{table: "day_closings", column: "data", aad: "'day_closings/' || id"},
+	{table: "reports", column: "data", aad: "'reports/' || id"},
+	// Quarantined basket lines keep their ciphertext, row_id is the basket
+	{table: "quarantine", column: "data", aad: "'basket_lines/' || row_id", where: "source_table = 'basket_lines'"},
+	// CDC copies encrypted bodies into the outbox, keeping their source
+	// row's AAD; operator_stats payloads are plain
+	{table: "outbox", column: "payload", aad: "entity || '/' || entity_id", where: "entity IN ('products', 'sales', 'transaction_voids', 'customers', 'loyalty_entries', 'stock_adjustments', 'day_closings', 'audit_anchors')"},
+}
+
+// rowAAD binds a ciphertext to the table and key of the row holding it, so
+// it cannot be copied into another row or setting and still decrypt
+func rowAAD(table, key string) []byte {
+	return []byte(table + "/" + key)
+}
+
+// filter returns the WHERE clause selecting the column's ciphertexts
+func (ec encryptedColumn) filter() string {
+	clause := ec.column + " IS NOT NULL"
+	if ec.where != "" {
+		clause += " AND " + ec.where
+	}
+	return clause
+}
+
+// Rekey re-encrypts every encrypted column with newKey in one transaction,
+// then switches the database to it. A failure or crash leaves the database
+// entirely under the old key. Change capture is suppressed so re-encrypted
+// rows are not queued for sync again. progress, if set, is called after
+// each batch with the rows done so far.
+func (db *DB) Rekey(ctx context.Context, newKey []byte, progress func(done, total int)) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	next, err := security.NewDatabaseEncryption(newKey)
+	if err != nil {
+		return fmt.Errorf("failed to create database encryption: %w", err)
+	}
+	defer next.Close()
+	next.SetCompression(db.encryption.CompressionThreshold())
+	if err := next.SetCipher(db.encryption.Cipher()); err != nil {
+		return err
+	}
+
+	// Held across the commit and the key switch so no reader sees new
+	// ciphertext with the old key
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 1 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to suppress change capture: %w", err)
+	}
+
+	total := 0
+	for _, ec := range encryptedColumns {
+		var n int
+		if err := tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", ec.table, ec.filter())).Scan(&n); err != nil {
+			return fmt.Errorf("failed to count %s rows: %w", ec.table, err)
+		}
+		total += n
+	}
+
+	done := 0
+	for _, ec := range encryptedColumns {
+		if err := reencryptColumn(ctx, tx, ec, db.encryption.DecryptWithAAD, next.EncryptWithAAD, func(n int) {
+			done += n
+			if progress != nil {
+				progress(done, total)
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 0 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to restore change capture: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit re-encryption: %w", err)
+	}
+
+	return db.encryption.SetKey(newKey)
+}
+
+// bindRowAAD re-encrypts ciphertexts written before rows were bound to
+// their AAD (schema version 5 and older). It runs once, from initSchema.
+func (db *DB) bindRowAAD() error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 1 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to suppress change capture: %w", err)
+	}
+
+	unbound := func(ciphertext string, _ []byte) ([]byte, error) {
+		return db.encryption.Decrypt(ciphertext)
+	}
+	for _, ec := range encryptedColumns {
+		if err := reencryptColumn(context.Background(), tx, ec, unbound, db.encryption.EncryptWithAAD, func(int) {}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE cdc_state SET suppress = 0 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to restore change capture: %w", err)
+	}
+	// Recorded in the same transaction so the migration never runs twice
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", aadSchemaVersion)); err != nil {
+		return fmt.Errorf("failed to set schema version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit AAD migration: %w", err)
+	}
+	return nil
+}
+
+// reencryptColumn rewrites one column batch by batch in rowid order,
+// opening each value with decrypt and sealing it again with encrypt
+func reencryptColumn(
+	ctx context.Context,
+	tx *sql.Tx,
+	ec encryptedColumn,
+	decrypt func(ciphertext string, aad []byte) ([]byte, error),
+	encrypt func(plaintext, aad []byte) (string, error),
+	batchDone func(n int),
+) error {
+	query := fmt.Sprintf("SELECT rowid, %s, %s FROM %s WHERE %s AND rowid > ? ORDER BY rowid LIMIT ?", ec.aad, ec.column, ec.table, ec.filter())
+	update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", ec.table, ec.column)
+
+	type row struct {
+		rowid int64
+		aad   string
+		value string
+	}
+
+	var last int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(query, last, rekeyBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", ec.table, err)
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.rowid, &r.aad, &r.value); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan %s: %w", ec.table, err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", ec.table, err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, r := range batch {
+			plaintext, err := decrypt(r.value, []byte(r.aad))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s row %d: %w", ec.table, r.rowid, err)
+			}
+			ciphertext, err := encrypt(plaintext, []byte(r.aad))
+			if err != nil {
+				return fmt.Errorf("failed to encrypt %s row %d: %w", ec.table, r.rowid, err)
+			}
+			if _, err := tx.Exec(update, ciphertext, r.rowid); err != nil {
+				return fmt.Errorf("failed to update %s row %d: %w", ec.table, r.rowid, err)
+			}
+		}
+
+		last = batch[len(batch)-1].rowid
+		batchDone(len(batch))
+	}
+}
+
+// UseServerKey switches the database to key without re-encrypting. Use it
+// only when KeyMatches shows the data is already under key, i.e. to finish
+// a rotation interrupted after its re-encryption committed.
+func (db *DB) UseServerKey(key []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.encryption.SetKey(key)
+}
+
+// KeyMatches reports whether key decrypts the database, judged by the first
+// ciphertext found. A database with no encrypted data matches any key.
+func (db *DB) KeyMatches(key []byte) (bool, error) {
+	candidate, err := security.NewDatabaseEncryption(key)
+	if err != nil {
+		return false, err
+	}
+	defer candidate.Close()
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, ec := range encryptedColumns {
+		var aad, value string
+		err := db.conn.QueryRow(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s LIMIT 1", ec.aad, ec.column, ec.table, ec.filter())).Scan(&aad, &value)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to sample %s: %w", ec.table, err)
+		}
+		_, err = candidate.DecryptWithAAD(value, []byte(aad))
+		return err == nil, nil
+	}
+
+	return true, nil
+}
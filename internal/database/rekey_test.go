@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func TestRekey_ReencryptsEverything(t *testing.T) {
+	oldKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+	db, err := New(&Config{ServerKey: oldKey, InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetSetting("store.name", "Corner Shop"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if err := db.UpsertProduct(&Product{ID: "P1", Barcode: "111", SKU: "PEN"}, ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	if _, err := db.AppendBasketLines("B1", "T1", []SaleLine{{SKU: "PEN", Quantity: 1, Price: 100}}, 0); err != nil {
+		t.Fatalf("AppendBasketLines failed: %v", err)
+	}
+	pending, _ := db.CountPendingOutbox()
+
+	newKey, _ := security.GenerateServerKey()
+	var lastDone, lastTotal int
+	if err := db.Rekey(context.Background(), newKey, func(done, total int) {
+		lastDone, lastTotal = done, total
+	}); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	if lastTotal == 0 || lastDone != lastTotal {
+		t.Errorf("Expected progress to reach the total, got %d/%d", lastDone, lastTotal)
+	}
+
+	if value, err := db.GetSetting("store.name"); err != nil || value != "Corner Shop" {
+		t.Errorf("Setting unreadable after rekey: %q, %v", value, err)
+	}
+	if _, err := db.GetProductByBarcode("111"); err != nil {
+		t.Errorf("Product unreadable after rekey: %v", err)
+	}
+	if lines, err := db.GetBasketLines("B1", 0, 10); err != nil || len(lines) != 1 {
+		t.Errorf("Basket lines unreadable after rekey: %v, %v", lines, err)
+	}
+
+	if after, _ := db.CountPendingOutbox(); after != pending {
+		t.Errorf("Rekey queued changes for sync: %d pending, want %d", after, pending)
+	}
+
+	if ok, _ := db.KeyMatches(newKey); !ok {
+		t.Error("Expected the new key to match")
+	}
+	if ok, _ := db.KeyMatches(oldKey); ok {
+		t.Error("Expected the old key to no longer match")
+	}
+}
+
+func TestRowAAD_RejectsReplayedCiphertext(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetSetting("pin.manager", "1234")
+	db.SetSetting("pin.cashier", "0000")
+
+	// Copy the manager PIN's ciphertext over the cashier's
+	if _, err := db.GetConnection().Exec(
+		"UPDATE settings SET value = (SELECT value FROM settings WHERE key = 'pin.manager') WHERE key = 'pin.cashier'",
+	); err != nil {
+		t.Fatalf("Failed to copy ciphertext: %v", err)
+	}
+
+	if value, err := db.GetSetting("pin.cashier"); err == nil {
+		t.Errorf("Expected replayed ciphertext to be rejected, got %q", value)
+	}
+	if value, err := db.GetSetting("pin.manager"); err != nil || value != "1234" {
+		t.Errorf("Original setting unreadable: %q, %v", value, err)
+	}
+}
+
+func TestBindRowAAD_MigratesUnboundRows(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+
+	db, err := New(&Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	// Simulate a version 5 database written before AAD binding
+	legacy, _ := db.encryption.Encrypt([]byte("Corner Shop"))
+	conn := db.GetConnection()
+	conn.Exec("INSERT INTO settings (key, value) VALUES ('store.name', ?)", legacy)
+	conn.Exec("PRAGMA user_version = 5")
+	db.Close()
+
+	db, err = New(&Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if value, err := db.GetSetting("store.name"); err != nil || value != "Corner Shop" {
+		t.Errorf("Expected migrated setting, got %q, %v", value, err)
+	}
+	if pending, _ := db.CountPendingOutbox(); pending != 0 {
+		t.Errorf("Migration queued %d changes for sync", pending)
+	}
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrReportNotFound is returned when no report matches a lookup
+var ErrReportNotFound = errors.New("report not found")
+
+// ReportFile is a rendered report in the local report library
+type ReportFile struct {
+	ID        string `json:"id"` // <day>_<kind>.<format>
+	Kind      string `json:"kind"`
+	Day       string `json:"day"` // Business day covered, YYYY-MM-DD
+	Format    string `json:"format"`
+	Size      int    `json:"size"` // Bytes
+	CreatedAt string `json:"created_at"`
+}
+
+// ReportID returns the ID of the report of kind for day in format
+func ReportID(day, kind, format string) string {
+	return day + "_" + kind + "." + format
+}
+
+// SaveReport stores a rendered report, replacing an earlier rendering of
+// the same kind, day and format
+func (db *DB) SaveReport(report *ReportFile, content []byte) error {
+	report.ID = ReportID(report.Day, report.Kind, report.Format)
+	report.Size = len(content)
+
+	encryptedData, err := db.encryption.EncryptWithAAD(content, rowAAD("reports", report.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt report: %w", err)
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO reports (id, kind, day, format, size, data) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				size = excluded.size,
+				data = excluded.data,
+				created_at = CURRENT_TIMESTAMP
+		`, report.ID, report.Kind, report.Day, report.Format, report.Size, encryptedData)
+		if err != nil {
+			return fmt.Errorf("failed to save report: %w", err)
+		}
+		return nil
+	})
+}
+
+// ReportExists reports whether a report has been rendered
+func (db *DB) ReportExists(id string) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM reports WHERE id = ?", id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check report: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ListReports returns the report library, newest day first. A non-empty
+// kind limits it to reports of that kind.
+func (db *DB) ListReports(kind string) ([]ReportFile, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query(`
+		SELECT id, kind, day, format, size, created_at FROM reports
+		WHERE ? = '' OR kind = ?
+		ORDER BY day DESC, id
+	`, kind, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]ReportFile, 0)
+	for rows.Next() {
+		var report ReportFile
+		if err := rows.Scan(&report.ID, &report.Kind, &report.Day, &report.Format, &report.Size, &report.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// GetReport retrieves a report and its content (decrypts automatically)
+func (db *DB) GetReport(id string) (*ReportFile, []byte, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var report ReportFile
+	var encryptedData string
+	err := db.conn.QueryRow(
+		"SELECT id, kind, day, format, size, created_at, data FROM reports WHERE id = ?", id,
+	).Scan(&report.ID, &report.Kind, &report.Day, &report.Format, &report.Size, &report.CreatedAt, &encryptedData)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query report: %w", err)
+	}
+
+	content, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("reports", id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt report: %w", err)
+	}
+
+	return &report, content, nil
+}
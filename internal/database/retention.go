@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PruneStats reports how many rows a retention pass removed
+type PruneStats struct {
+	Outbox      int64 `json:"outbox"`
+	CatalogGaps int64 `json:"catalog_gaps"`
+	Jobs        int64 `json:"jobs"`
+	Baskets     int64 `json:"baskets"`
+}
+
+// Total returns the number of rows removed across all tables
+func (s PruneStats) Total() int64 {
+	return s.Outbox + s.CatalogGaps + s.Jobs + s.Baskets
+}
+
+// PruneSyncedHistory deletes history that has been delivered to the server
+// and is older than before, along with baskets abandoned before then.
+// Unsynced outbox entries, unreported catalog gaps and unfinished jobs are
+// always kept.
+func (db *DB) PruneSyncedHistory(before time.Time) (PruneStats, error) {
+	var stats PruneStats
+
+	if db.refusesWrites() {
+		// Pruning is deferred until the sync window ends
+		return stats, nil
+	}
+
+	cutoff := before.UTC().Format(sqliteTimestampFormat)
+
+	err := db.Transaction(func(tx *sql.Tx) error {
+		steps := []struct {
+			query string
+			count *int64
+		}{
+			{"DELETE FROM outbox WHERE synced_at IS NOT NULL AND synced_at < ?", &stats.Outbox},
+			{"DELETE FROM catalog_gaps WHERE reported = 1 AND last_seen_at < ?", &stats.CatalogGaps},
+			{"DELETE FROM jobs WHERE finished_at IS NOT NULL AND finished_at < ?", &stats.Jobs},
+			{"DELETE FROM basket_lines WHERE basket_id IN (SELECT id FROM baskets WHERE updated_at < ?)", new(int64)},
+			{"DELETE FROM baskets WHERE updated_at < ?", &stats.Baskets},
+		}
+
+		for _, step := range steps {
+			result, err := tx.Exec(step.query, cutoff)
+			if err != nil {
+				return fmt.Errorf("failed to prune history: %w", err)
+			}
+			if *step.count, err = result.RowsAffected(); err != nil {
+				return fmt.Errorf("failed to count pruned rows: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return PruneStats{}, err
+	}
+
+	return stats, nil
+}
+
+// RunRetentionPruner periodically prunes synced history older than
+// retention until ctx is cancelled
+func (db *DB) RunRetentionPruner(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats, err := db.PruneSyncedHistory(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Warning: retention pruning failed: %v", err)
+				continue
+			}
+			if stats.Total() > 0 {
+				log.Printf("Pruned synced history (outbox: %d, catalog gaps: %d, jobs: %d, baskets: %d)",
+					stats.Outbox, stats.CatalogGaps, stats.Jobs, stats.Baskets)
+
+				// Reclaim the WAL space freed by the deletes
+				if err := db.Checkpoint(); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPruneSyncedHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, p := range []*Product{
+		{ID: "p1", Barcode: "111", Name: "Milk", Price: 990},
+		{ID: "p2", Barcode: "222", Name: "Bread", Price: 450},
+	} {
+		if err := db.UpsertProduct(p, ProductSourceLocal); err != nil {
+			t.Fatalf("UpsertProduct failed: %v", err)
+		}
+	}
+
+	entries, err := db.GetPendingOutbox(10)
+	if err != nil {
+		t.Fatalf("GetPendingOutbox failed: %v", err)
+	}
+	if err := db.MarkOutboxSynced([]int64{entries[0].ID}); err != nil {
+		t.Fatalf("MarkOutboxSynced failed: %v", err)
+	}
+
+	// Nothing is old enough yet
+	stats, err := db.PruneSyncedHistory(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PruneSyncedHistory failed: %v", err)
+	}
+	if stats.Total() != 0 {
+		t.Errorf("Expected nothing pruned, got %+v", stats)
+	}
+
+	// A future cutoff removes synced rows only
+	stats, err = db.PruneSyncedHistory(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PruneSyncedHistory failed: %v", err)
+	}
+	if stats.Outbox != 1 {
+		t.Errorf("Expected 1 outbox row pruned, got %d", stats.Outbox)
+	}
+
+	pending, _ := db.CountPendingOutbox()
+	if pending != 1 {
+		t.Errorf("Expected unsynced entry to survive, got %d pending", pending)
+	}
+}
+
+func TestPruneSyncedHistory_ReadOnly(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetReadOnly(true)
+	stats, err := db.PruneSyncedHistory(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Expected no error while read-only, got %v", err)
+	}
+	if stats.Total() != 0 {
+		t.Errorf("Expected nothing pruned while read-only, got %+v", stats)
+	}
+}
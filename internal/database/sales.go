@@ -0,0 +1,357 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSaleNotFound is returned when no sale matches a lookup
+var ErrSaleNotFound = errors.New("sale not found")
+
+// Sale types
+const (
+	SaleTypeSale   = "sale"
+	SaleTypeRefund = "refund"
+)
+
+// Sale is a completed checkout
+type Sale struct {
+	ID          string     `json:"id"`             // Client-generated, makes commits idempotent
+	Type        string     `json:"type,omitempty"` // SaleTypeSale (default) or SaleTypeRefund
+	TerminalID  string     `json:"terminal_id"`
+	OperatorID  string     `json:"operator_id,omitempty"` // Cashier who rang up the sale
+	Lines       []SaleLine `json:"lines"`
+	Total       int64      `json:"total"`                  // Minor currency units, always positive
+	VoidedLines int        `json:"voided_lines,omitempty"` // Lines scanned then removed before tender
+	ScanSeconds int        `json:"scan_seconds,omitempty"` // First scan to tender
+	Tenders     []Tender   `json:"tenders,omitempty"`      // How the sale was paid (or the refund paid out)
+	RefundOf    string     `json:"refund_of,omitempty"`    // Sale a refund returns goods from
+	CreatedAt   string     `json:"created_at"`             // ISO 8601 timestamp
+}
+
+// Tender methods
+const (
+	TenderCash    = "cash"
+	TenderCard    = "card"
+	TenderVoucher = "voucher"
+)
+
+// Tender is one payment towards a sale
+type Tender struct {
+	Method    string `json:"method" validate:"required,oneof=cash card voucher"`
+	Amount    int64  `json:"amount" validate:"gt=0"` // Minor currency units
+	Reference string `json:"reference,omitempty"`    // Card authorisation or voucher code
+}
+
+// IsRefund reports whether the sale returns goods
+func (s *Sale) IsRefund() bool {
+	return s.Type == SaleTypeRefund
+}
+
+// SaleLine is one item of a sale
+type SaleLine struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0"`
+	Price    int64  `json:"price"`              // Unit price in minor currency units
+	PromoID  string `json:"promo_id,omitempty"` // Promotion applied to the line, if any
+}
+
+// Validate checks that a sale is well formed and its total matches its lines
+func (s *Sale) Validate() error {
+	if s.ID == "" {
+		return fmt.Errorf("sale id is required")
+	}
+	if s.Type != "" && s.Type != SaleTypeSale && s.Type != SaleTypeRefund {
+		return fmt.Errorf("invalid sale type: %s", s.Type)
+	}
+	if len(s.Lines) == 0 {
+		return fmt.Errorf("sale must have at least one line")
+	}
+	if s.VoidedLines < 0 || s.ScanSeconds < 0 {
+		return fmt.Errorf("voided lines and scan seconds cannot be negative")
+	}
+
+	var total int64
+	for i, line := range s.Lines {
+		if line.SKU == "" || line.Quantity <= 0 {
+			return fmt.Errorf("sale line %d must have a sku and positive quantity", i)
+		}
+		total += int64(line.Quantity) * line.Price
+	}
+	if total != s.Total {
+		return fmt.Errorf("sale total %d does not match lines (%d)", s.Total, total)
+	}
+	if s.RefundOf != "" && !s.IsRefund() {
+		return fmt.Errorf("only refunds can reference another sale")
+	}
+
+	return s.validateTenders()
+}
+
+// validateTenders checks the tenders of a sale that lists any. A sale may
+// be overpaid only in cash (the difference is change); a refund pays out
+// exactly its total.
+func (s *Sale) validateTenders() error {
+	if len(s.Tenders) == 0 {
+		return nil
+	}
+
+	var paid, cash int64
+	for i, t := range s.Tenders {
+		if t.Method != TenderCash && t.Method != TenderCard && t.Method != TenderVoucher {
+			return fmt.Errorf("tender %d has an invalid method: %s", i, t.Method)
+		}
+		if t.Amount <= 0 {
+			return fmt.Errorf("tender %d must have a positive amount", i)
+		}
+		paid += t.Amount
+		if t.Method == TenderCash {
+			cash += t.Amount
+		}
+	}
+
+	switch {
+	case s.IsRefund() && paid != s.Total:
+		return fmt.Errorf("refund tenders %d do not match the total (%d)", paid, s.Total)
+	case paid < s.Total:
+		return fmt.Errorf("tenders %d do not cover the total (%d)", paid, s.Total)
+	case paid-s.Total > cash:
+		return fmt.Errorf("change of %d exceeds the cash tendered (%d)", paid-s.Total, cash)
+	}
+	return nil
+}
+
+// Change returns the cash handed back for an overpaid sale
+func (s *Sale) Change() int64 {
+	var paid int64
+	for _, t := range s.Tenders {
+		paid += t.Amount
+	}
+	if paid <= s.Total {
+		return 0
+	}
+	return paid - s.Total
+}
+
+// --- Sales Table Methods ---
+
+// ApplySale records a sale, moves stock (out for sales, back in for refunds)
+// and updates the operator rollup within tx. It is idempotent: if the sale
+// already exists nothing changes and applied is false. The sale and rollup
+// reach the outbox through CDC triggers.
+func (db *DB) ApplySale(tx *sql.Tx, sale *Sale) (applied bool, err error) {
+	if sale.CreatedAt == "" {
+		sale.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	jsonData, err := json.Marshal(sale)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal sale: %w", err)
+	}
+
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("sales", sale.ID))
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt sale: %w", err)
+	}
+
+	var refundOf interface{}
+	if sale.RefundOf != "" {
+		refundOf = sale.RefundOf
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO sales (id, data, total, refund_of) VALUES (?, ?, ?, ?) ON CONFLICT(id) DO NOTHING",
+		sale.ID, encryptedData, sale.Total, refundOf,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert sale: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	direction := -1
+	if sale.IsRefund() {
+		direction = 1
+	}
+
+	for _, line := range sale.Lines {
+		_, err := tx.Exec(`
+			INSERT INTO stock_levels (sku, quantity, updated_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(sku) DO UPDATE SET
+				quantity = quantity + excluded.quantity,
+				updated_at = CURRENT_TIMESTAMP
+		`, line.SKU, direction*line.Quantity)
+		if err != nil {
+			return false, fmt.Errorf("failed to update stock for %s: %w", line.SKU, err)
+		}
+	}
+
+	if err := applyOperatorRollup(tx, sale); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetSale retrieves a sale by ID (decrypts automatically)
+func (db *DB) GetSale(id string) (*Sale, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var encryptedData string
+	err := db.conn.QueryRow("SELECT data FROM sales WHERE id = ?", id).Scan(&encryptedData)
+	if err == sql.ErrNoRows {
+		return nil, ErrSaleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sale: %w", err)
+	}
+
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("sales", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sale: %w", err)
+	}
+
+	var sale Sale
+	if err := json.Unmarshal(jsonData, &sale); err != nil {
+		return nil, fmt.Errorf("failed to parse sale: %w", err)
+	}
+
+	return &sale, nil
+}
+
+// SaleExists checks if a sale has been committed
+func (db *DB) SaleExists(id string) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM sales WHERE id = ?", id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check sale existence: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ListRefunds returns the refunds of a sale, oldest first
+func (db *DB) ListRefunds(saleID string) ([]Sale, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT id, data FROM sales WHERE refund_of = ? ORDER BY created_at, id", saleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	var refunds []Sale
+	for rows.Next() {
+		var id, encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("sales", id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt refund: %w", err)
+		}
+
+		var refund Sale
+		if err := json.Unmarshal(jsonData, &refund); err != nil {
+			return nil, fmt.Errorf("failed to parse refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+// CountSales returns the number of committed sales
+func (db *DB) CountSales() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM sales").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sales: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListSales returns sales committed in [from, to), oldest first
+func (db *DB) ListSales(from, to time.Time) ([]Sale, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query(
+		"SELECT id, data FROM sales WHERE created_at >= ? AND created_at < ? ORDER BY created_at, id",
+		from.UTC().Format(sqliteTimestampFormat), to.UTC().Format(sqliteTimestampFormat),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sales: %w", err)
+	}
+	defer rows.Close()
+
+	var sales []Sale
+	for rows.Next() {
+		var id, encryptedData string
+		if err := rows.Scan(&id, &encryptedData); err != nil {
+			return nil, fmt.Errorf("failed to scan sale: %w", err)
+		}
+
+		jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("sales", id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt sale: %w", err)
+		}
+
+		var sale Sale
+		if err := json.Unmarshal(jsonData, &sale); err != nil {
+			return nil, fmt.Errorf("failed to parse sale: %w", err)
+		}
+		sales = append(sales, sale)
+	}
+
+	return sales, rows.Err()
+}
+
+// --- Stock Level Methods ---
+
+// GetStockLevel returns the on-hand quantity of a SKU (0 if never stocked)
+func (db *DB) GetStockLevel(sku string) (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var quantity int
+	err := db.conn.QueryRow("SELECT quantity FROM stock_levels WHERE sku = ?", sku).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stock level: %w", err)
+	}
+
+	return quantity, nil
+}
+
+// SetStockLevel sets the on-hand quantity of a SKU
+func (db *DB) SetStockLevel(sku string, quantity int) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO stock_levels (sku, quantity, updated_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(sku) DO UPDATE SET
+				quantity = excluded.quantity,
+				updated_at = CURRENT_TIMESTAMP
+		`, sku, quantity)
+		if err != nil {
+			return fmt.Errorf("failed to set stock level: %w", err)
+		}
+		return nil
+	})
+}
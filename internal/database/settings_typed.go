@@ -0,0 +1,139 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// --- Typed Settings Accessors ---
+//
+// Settings are stored as encrypted strings. These helpers parse them into
+// typed values; the *Default variants return def when the key is missing
+// or its value cannot be parsed.
+
+// GetSettingInt retrieves a setting as an int
+func (db *DB) GetSettingInt(key string) (int, error) {
+	value, err := db.GetSetting(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("setting %s is not an integer: %w", key, err)
+	}
+
+	return n, nil
+}
+
+// GetSettingIntDefault retrieves a setting as an int, or def
+func (db *DB) GetSettingIntDefault(key string, def int) int {
+	n, err := db.GetSettingInt(key)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// SetSettingInt stores an int setting
+func (db *DB) SetSettingInt(key string, value int) error {
+	return db.SetSetting(key, strconv.Itoa(value))
+}
+
+// GetSettingBool retrieves a setting as a bool (accepts strconv.ParseBool forms)
+func (db *DB) GetSettingBool(key string) (bool, error) {
+	value, err := db.GetSetting(key)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("setting %s is not a boolean: %w", key, err)
+	}
+
+	return b, nil
+}
+
+// GetSettingBoolDefault retrieves a setting as a bool, or def
+func (db *DB) GetSettingBoolDefault(key string, def bool) bool {
+	b, err := db.GetSettingBool(key)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// SetSettingBool stores a bool setting
+func (db *DB) SetSettingBool(key string, value bool) error {
+	return db.SetSetting(key, strconv.FormatBool(value))
+}
+
+// GetSettingTime retrieves a setting stored as an RFC 3339 timestamp
+func (db *DB) GetSettingTime(key string) (time.Time, error) {
+	value, err := db.GetSetting(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("setting %s is not an RFC 3339 timestamp: %w", key, err)
+	}
+
+	return t, nil
+}
+
+// GetSettingTimeDefault retrieves a setting as a time, or def
+func (db *DB) GetSettingTimeDefault(key string, def time.Time) time.Time {
+	t, err := db.GetSettingTime(key)
+	if err != nil {
+		return def
+	}
+	return t
+}
+
+// SetSettingTime stores a time setting as an RFC 3339 timestamp
+func (db *DB) SetSettingTime(key string, value time.Time) error {
+	return db.SetSetting(key, value.Format(time.RFC3339Nano))
+}
+
+// GetSettingJSON retrieves a JSON setting and unmarshals it into v
+func (db *DB) GetSettingJSON(key string, v interface{}) error {
+	value, err := db.GetSetting(key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return fmt.Errorf("setting %s is not valid JSON: %w", key, err)
+	}
+
+	return nil
+}
+
+// GetSettingJSONDefault unmarshals a JSON setting into v and reports
+// whether it was found; v is left untouched (holding its defaults) otherwise
+func (db *DB) GetSettingJSONDefault(key string, v interface{}) bool {
+	value, err := db.GetSetting(key)
+	if err != nil {
+		return false
+	}
+
+	// Validate first so malformed JSON leaves v untouched
+	if !json.Valid([]byte(value)) {
+		return false
+	}
+	return json.Unmarshal([]byte(value), v) == nil
+}
+
+// SetSettingJSON marshals v and stores it as a setting
+func (db *DB) SetSettingJSON(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal setting %s: %w", key, err)
+	}
+	return db.SetSetting(key, string(data))
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSettingInt(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSettingInt("retries", 42); err != nil {
+		t.Fatalf("SetSettingInt failed: %v", err)
+	}
+
+	n, err := db.GetSettingInt("retries")
+	if err != nil || n != 42 {
+		t.Errorf("Expected 42, got %d (%v)", n, err)
+	}
+
+	if _, err := db.GetSettingInt("missing"); !errors.Is(err, ErrSettingNotFound) {
+		t.Errorf("Expected ErrSettingNotFound, got %v", err)
+	}
+
+	db.SetSetting("not_a_number", "abc")
+	if got := db.GetSettingIntDefault("not_a_number", 7); got != 7 {
+		t.Errorf("Expected default 7 for unparsable value, got %d", got)
+	}
+	if got := db.GetSettingIntDefault("missing", 7); got != 7 {
+		t.Errorf("Expected default 7 for missing key, got %d", got)
+	}
+}
+
+func TestSettingBool(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetSettingBool("enabled", true)
+	if b, err := db.GetSettingBool("enabled"); err != nil || !b {
+		t.Errorf("Expected true, got %v (%v)", b, err)
+	}
+	if !db.GetSettingBoolDefault("missing", true) {
+		t.Error("Expected default true for missing key")
+	}
+}
+
+func TestSettingTime(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	db.SetSettingTime("last_sync", now)
+
+	got, err := db.GetSettingTime("last_sync")
+	if err != nil {
+		t.Fatalf("GetSettingTime failed: %v", err)
+	}
+	if !got.Equal(now) {
+		t.Errorf("Expected %v, got %v", now, got)
+	}
+
+	def := time.Unix(0, 0)
+	if !db.GetSettingTimeDefault("missing", def).Equal(def) {
+		t.Error("Expected default time for missing key")
+	}
+}
+
+func TestSettingJSON(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	type printer struct {
+		Name  string `json:"name"`
+		Width int    `json:"width"`
+	}
+
+	if err := db.SetSettingJSON("printer", printer{Name: "EPSON", Width: 80}); err != nil {
+		t.Fatalf("SetSettingJSON failed: %v", err)
+	}
+
+	var got printer
+	if err := db.GetSettingJSON("printer", &got); err != nil {
+		t.Fatalf("GetSettingJSON failed: %v", err)
+	}
+	if got.Name != "EPSON" || got.Width != 80 {
+		t.Errorf("Unexpected value: %+v", got)
+	}
+
+	db.SetSetting("broken", "{not json")
+	fallback := printer{Name: "default", Width: 58}
+	if db.GetSettingJSONDefault("broken", &fallback) {
+		t.Error("Expected malformed JSON to report not found")
+	}
+	if fallback.Name != "default" {
+		t.Error("Malformed JSON must leave the default untouched")
+	}
+}
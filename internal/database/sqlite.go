@@ -1,30 +1,152 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/professor93/promo-pos/internal/security"
 	"github.com/professor93/promo-pos/pkg/constants"
+	"github.com/professor93/promo-pos/pkg/paths"
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
 )
 
+var (
+	// ErrReadOnly is returned by mutating operations while the database is
+	// read-only or offline
+	ErrReadOnly = errors.New("database is read-only")
+
+	// ErrSettingNotFound is returned when a setting key does not exist
+	ErrSettingNotFound = errors.New("setting not found")
+)
+
 // DB represents the database connection with encryption
 type DB struct {
 	conn       *sql.DB
 	encryption *security.DatabaseEncryption
 	dbPath     string
 	mu         sync.RWMutex
+
+	// readOnly is switched by SetReadOnly, by maintenance tools and tests;
+	// nothing turns it on by itself
+	readOnly atomic.Bool
+
+	// offline is set once the offline grace period lapses (see SetOffline)
+	offline atomic.Bool
+
+	// onReadOnly holds the func(bool) told about read-only mode changes
+	onReadOnly atomic.Value
+
+	// onCatalogChange holds the func(string) told about committed product
+	// and promotion writes
+	onCatalogChange atomic.Value
+
+	// retries counts busy/locked retries (see withBusyRetry)
+	retries retryCounters
+
+	// lastCheckpoint is the UnixNano time of the last explicit WAL checkpoint
+	lastCheckpoint atomic.Int64
+
+	// products is the decoded catalog served to barcode lookups
+	products productIndex
+
+	// promotions are the synced promotions by SKU, served to price lookups
+	promotions promotionIndex
+
+	// integrity is the report of the last orphan repair pass
+	integrity atomic.Pointer[IntegrityReport]
 }
 
 // Config holds database configuration
 type Config struct {
 	ServerKey []byte // 32-byte server key for encryption
 	DataDir   string // Directory for database file
+	InMemory  bool   // Use an in-memory database (tests, demo mode); DataDir is ignored
+
+	// Profile selects an isolated per-store database under DataDir/profiles/<Profile>,
+	// so one machine can host several registers or a test+prod pair. Empty keeps
+	// the single database directly in DataDir.
+	Profile string
+
+	// CompressAbove compresses encrypted values larger than this many bytes
+	// (see compressExisting); 0 disables compression
+	CompressAbove int
+
+	// Cipher seals new values (constants.DBCipher*); empty keeps the
+	// default. Values sealed with another cipher still decrypt.
+	Cipher string
+}
+
+// SchemaVersion is bumped whenever initSchema changes the table layout
+const SchemaVersion = 10
+
+// aadSchemaVersion is the first schema version whose ciphertexts are bound
+// to their row with rowAAD
+const aadSchemaVersion = 6
+
+// outboxUIDSchemaVersion is the first schema version whose outbox entries
+// carry a ULID
+const outboxUIDSchemaVersion = 8
+
+// auditChainSchemaVersion is the first schema version whose audit log
+// entries are hash-chained
+const auditChainSchemaVersion = 9
+
+// stockMovementSchemaVersion is the first schema version whose stock
+// movements are indexed by SKU
+const stockMovementSchemaVersion = 10
+
+// profilesDirName is the DataDir subdirectory holding per-profile databases
+const profilesDirName = "profiles"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ValidateProfileName checks that a profile name is safe to use as a directory name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' or '-' (max 64)", name)
+	}
+	return nil
+}
+
+// resolveDataDir returns the data directory, defaulting to the resolved application data dir
+func resolveDataDir(dataDir string) string {
+	if dataDir == "" {
+		return paths.Default().DataDir
+	}
+	return dataDir
+}
+
+// ListProfiles returns the names of the profile databases present in dataDir
+func ListProfiles(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(resolveDataDir(dataDir), profilesDirName))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	profiles := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateProfileName(entry.Name()) != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(resolveDataDir(dataDir), profilesDirName, entry.Name(), constants.DatabaseFileName)); err == nil {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+
+	return profiles, nil
 }
 
 // New creates a new database instance with server-key encryption
@@ -38,26 +160,44 @@ func New(cfg *Config) (*DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database encryption: %w", err)
 	}
-
-	// Determine database path
-	dataDir := cfg.DataDir
-	if dataDir == "" {
-		dataDir = os.Getenv("PROGRAMDATA")
-		if dataDir == "" {
-			dataDir = "." // Fallback for development
+	if cfg.Cipher != "" {
+		if err := encryption.SetCipher(cfg.Cipher); err != nil {
+			return nil, err
 		}
-		dataDir = filepath.Join(dataDir, "POSService")
 	}
 
-	// Ensure data directory exists
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
+	var dbPath, dsn string
+	if cfg.InMemory {
+		// Each in-memory database gets a unique name so that pooled
+		// connections share one cache without leaking between instances
+		dbPath = ":memory:"
+		dsn = fmt.Sprintf("file:%s?mode=memory&cache=shared", uuid.NewString())
+	} else {
+		// Determine database path
+		dataDir := resolveDataDir(cfg.DataDir)
+		if cfg.Profile != "" {
+			if err := ValidateProfileName(cfg.Profile); err != nil {
+				return nil, err
+			}
+			dataDir = filepath.Join(dataDir, profilesDirName, cfg.Profile)
+		}
+
+		// Ensure data directory exists
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
 
-	dbPath := filepath.Join(dataDir, constants.DatabaseFileName)
+		dbPath = filepath.Join(dataDir, constants.DatabaseFileName)
+		dsn = dbPath
+
+		// A backup restored through StageRestore replaces the file now
+		if err := applyStagedRestore(dbPath); err != nil {
+			return nil, err
+		}
+	}
 
 	// Open SQLite database
-	conn, err := sql.Open("sqlite", dbPath)
+	conn, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -66,6 +206,10 @@ func New(cfg *Config) (*DB, error) {
 	conn.SetMaxOpenConns(25)
 	conn.SetMaxIdleConns(5)
 	conn.SetConnMaxLifetime(5 * time.Minute)
+	if cfg.InMemory {
+		// An in-memory database vanishes with its last connection
+		conn.SetConnMaxLifetime(0)
+	}
 
 	// Set PRAGMA options for performance and reliability
 	pragmas := []string{
@@ -96,6 +240,12 @@ func New(cfg *Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	encryption.SetCompression(cfg.CompressAbove)
+	if err := db.compressExisting(cfg.CompressAbove); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -106,6 +256,7 @@ func (db *DB) initSchema() error {
 	CREATE TABLE IF NOT EXISTS settings (
 		key   VARCHAR(255) PRIMARY KEY,
 		value TEXT NOT NULL,
+		expires_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -122,20 +273,543 @@ func (db *DB) initSchema() error {
 		return fmt.Errorf("failed to create settings table: %w", err)
 	}
 
+	// Databases created before TTL support lack expires_at
+	if err := db.ensureColumn("settings", "expires_at", "DATETIME"); err != nil {
+		return err
+	}
+
+	// Create products table (record body is encrypted, barcode stays queryable)
+	productsTableSQL := `
+	CREATE TABLE IF NOT EXISTS products (
+		id         VARCHAR(64) PRIMARY KEY,
+		barcode    VARCHAR(64) NOT NULL UNIQUE,
+		data       TEXT NOT NULL,
+		source     VARCHAR(16) NOT NULL DEFAULT 'sync',
+		version    INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS catalog_gaps (
+		barcode        VARCHAR(64) PRIMARY KEY,
+		hits           INTEGER NOT NULL DEFAULT 1,
+		resolved       BOOLEAN NOT NULL DEFAULT 0,
+		reported       BOOLEAN NOT NULL DEFAULT 0,
+		first_seen_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.conn.Exec(productsTableSQL); err != nil {
+		return fmt.Errorf("failed to create products tables: %w", err)
+	}
+
+	// Databases created before optimistic locking lack the version column
+	if err := db.ensureColumn("products", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+
+	// Create async jobs table
+	jobsTableSQL := `
+	CREATE TABLE IF NOT EXISTS jobs (
+		id          VARCHAR(64) PRIMARY KEY,
+		kind        VARCHAR(64) NOT NULL,
+		status      VARCHAR(16) NOT NULL,
+		progress    INTEGER NOT NULL DEFAULT 0,
+		message     TEXT NOT NULL DEFAULT '',
+		result_url  TEXT NOT NULL DEFAULT '',
+		error       TEXT NOT NULL DEFAULT '',
+		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+		started_at  DATETIME,
+		finished_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS jobs_created_at ON jobs(created_at);
+	`
+
+	if _, err := db.conn.Exec(jobsTableSQL); err != nil {
+		return fmt.Errorf("failed to create jobs table: %w", err)
+	}
+
+	// Jobs created before jobs reported details lack the details column
+	if err := db.ensureColumn("jobs", "details", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Create boot history (uptime diagnostics; unencrypted like jobs)
+	bootsTableSQL := `
+	CREATE TABLE IF NOT EXISTS boots (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		version     VARCHAR(32) NOT NULL DEFAULT '',
+		booted_at   DATETIME NOT NULL,
+		alive_at    DATETIME NOT NULL,
+		shutdown_at DATETIME,
+		reason      VARCHAR(32) NOT NULL DEFAULT ''
+	);
+	`
+
+	if _, err := db.conn.Exec(bootsTableSQL); err != nil {
+		return fmt.Errorf("failed to create boots table: %w", err)
+	}
+
+	// Create sync outbox and change-data-capture state
+	outboxTableSQL := `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		uid        VARCHAR(26),
+		entity     VARCHAR(64) NOT NULL,
+		entity_id  VARCHAR(64) NOT NULL,
+		operation  VARCHAR(8) NOT NULL,
+		payload    TEXT,
+		attempts   INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		synced_at  DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS outbox_pending ON outbox(synced_at, id);
+
+	CREATE TABLE IF NOT EXISTS cdc_state (
+		id       INTEGER PRIMARY KEY CHECK (id = 1),
+		suppress INTEGER NOT NULL DEFAULT 0
+	);
+
+	INSERT OR IGNORE INTO cdc_state (id, suppress) VALUES (1, 0);
+	`
+
+	if _, err := db.conn.Exec(outboxTableSQL); err != nil {
+		return fmt.Errorf("failed to create outbox table: %w", err)
+	}
+	if err := db.ensureColumn("outbox", "uid", "VARCHAR(26)"); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec("CREATE UNIQUE INDEX IF NOT EXISTS outbox_uid ON outbox(uid)"); err != nil {
+		return fmt.Errorf("failed to create outbox uid index: %w", err)
+	}
+
+	// Create sales and stock tables (sale body is encrypted; stock is
+	// derived locally from sales and is not captured into the outbox)
+	salesTableSQL := `
+	CREATE TABLE IF NOT EXISTS sales (
+		id         VARCHAR(64) PRIMARY KEY,
+		data       TEXT NOT NULL,
+		total      INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS stock_levels (
+		sku        VARCHAR(64) PRIMARY KEY,
+		quantity   INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS operator_stats (
+		id           VARCHAR(128) PRIMARY KEY,
+		operator_id  VARCHAR(64) NOT NULL,
+		day          VARCHAR(10) NOT NULL,
+		sales_count  INTEGER NOT NULL DEFAULT 0,
+		sales_value  INTEGER NOT NULL DEFAULT 0,
+		refund_count INTEGER NOT NULL DEFAULT 0,
+		refund_value INTEGER NOT NULL DEFAULT 0,
+		items_sold   INTEGER NOT NULL DEFAULT 0,
+		voided_lines INTEGER NOT NULL DEFAULT 0,
+		scan_seconds INTEGER NOT NULL DEFAULT 0,
+		updated_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+		-- Serialized rollup captured into the outbox by CDC triggers (plain
+		-- counters with no item or customer data, so left unencrypted)
+		payload      TEXT GENERATED ALWAYS AS (json_object(
+			'operator_id', operator_id, 'day', day,
+			'sales_count', sales_count, 'sales_value', sales_value,
+			'refund_count', refund_count, 'refund_value', refund_value,
+			'items_sold', items_sold, 'voided_lines', voided_lines,
+			'scan_seconds', scan_seconds
+		)) VIRTUAL
+	);
+
+	CREATE INDEX IF NOT EXISTS operator_stats_operator_day ON operator_stats(operator_id, day);
+	`
+
+	if _, err := db.conn.Exec(salesTableSQL); err != nil {
+		return fmt.Errorf("failed to create sales tables: %w", err)
+	}
+
+	// Sales created before refunds were linked lack refund_of
+	if err := db.ensureColumn("sales", "refund_of", "VARCHAR(64)"); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec("CREATE INDEX IF NOT EXISTS sales_refund_of ON sales(refund_of)"); err != nil {
+		return fmt.Errorf("failed to create sales refund index: %w", err)
+	}
+
+	// Create basket tables for large transactions built in chunks. Totals
+	// are kept on the basket row and adjusted per chunk, never recomputed.
+	basketTableSQL := `
+	CREATE TABLE IF NOT EXISTS baskets (
+		id          VARCHAR(64) PRIMARY KEY,
+		terminal_id VARCHAR(64) NOT NULL,
+		line_count  INTEGER NOT NULL DEFAULT 0,
+		quantity    INTEGER NOT NULL DEFAULT 0,
+		total       INTEGER NOT NULL DEFAULT 0,
+		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS basket_lines (
+		basket_id VARCHAR(64) NOT NULL,
+		seq       INTEGER NOT NULL,
+		data      TEXT NOT NULL,
+		PRIMARY KEY (basket_id, seq)
+	);
+	`
+
+	if _, err := db.conn.Exec(basketTableSQL); err != nil {
+		return fmt.Errorf("failed to create basket tables: %w", err)
+	}
+
+	// Voided open transactions (the basket is deleted, the void is synced)
+	voidsTableSQL := `
+	CREATE TABLE IF NOT EXISTS transaction_voids (
+		id         VARCHAR(64) PRIMARY KEY,
+		data       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.conn.Exec(voidsTableSQL); err != nil {
+		return fmt.Errorf("failed to create transaction voids table: %w", err)
+	}
+
+	// Create recommendation rules table (head office catalog metadata,
+	// not sensitive, stored as plain JSON)
+	rulesTableSQL := `
+	CREATE TABLE IF NOT EXISTS recommendation_rules (
+		id         VARCHAR(64) PRIMARY KEY,
+		data       TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.conn.Exec(rulesTableSQL); err != nil {
+		return fmt.Errorf("failed to create recommendation rules table: %w", err)
+	}
+
+	// Create promotions table (head office pricing, stored as plain JSON
+	// like the recommendation rules)
+	promotionsTableSQL := `
+	CREATE TABLE IF NOT EXISTS promotions (
+		id         VARCHAR(64) PRIMARY KEY,
+		data       TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.conn.Exec(promotionsTableSQL); err != nil {
+		return fmt.Errorf("failed to create promotions table: %w", err)
+	}
+
+	// Create handheld device tables: registered devices, their audit trail,
+	// the security audit log and its anchors, stock adjustments (counted on
+	// the shop floor, synced to head office) and the label print queue
+	deviceTableSQL := `
+	CREATE TABLE IF NOT EXISTS devices (
+		id           VARCHAR(64) PRIMARY KEY,
+		data         TEXT NOT NULL,
+		secret_hash  VARCHAR(64) NOT NULL,
+		created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS device_audit (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id  VARCHAR(64) NOT NULL,
+		data       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS device_audit_device ON device_audit(device_id, id);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		event      VARCHAR(64) NOT NULL,
+		data       TEXT NOT NULL,
+		hash       VARCHAR(64) NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_anchors (
+		id         VARCHAR(32) PRIMARY KEY,
+		seq        INTEGER NOT NULL,
+		data       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS stock_adjustments (
+		id         VARCHAR(64) PRIMARY KEY,
+		data       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS label_requests (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id  VARCHAR(64) NOT NULL,
+		sku        VARCHAR(64) NOT NULL,
+		copies     INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		printed_at DATETIME
+	);
+	`
+
+	if _, err := db.conn.Exec(deviceTableSQL); err != nil {
+		return fmt.Errorf("failed to create device tables: %w", err)
+	}
+
+	// Audit logs created before hash chaining lack the hash column
+	if err := db.ensureColumn("audit_log", "hash", "VARCHAR(64) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Stock adjustments logged before the movement log was queryable by SKU
+	// lack the sku column; indexStockMovements fills it in
+	if err := db.ensureColumn("stock_adjustments", "sku", "VARCHAR(64)"); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec("CREATE INDEX IF NOT EXISTS stock_adjustments_sku ON stock_adjustments(sku, created_at)"); err != nil {
+		return fmt.Errorf("failed to create stock movement index: %w", err)
+	}
+
+	// Day-close reports (checklist outcome per terminal and day), sent to
+	// head office through the outbox
+	closingTableSQL := `
+	CREATE TABLE IF NOT EXISTS day_closings (
+		id         VARCHAR(128) PRIMARY KEY,
+		data       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.conn.Exec(closingTableSQL); err != nil {
+		return fmt.Errorf("failed to create day closings table: %w", err)
+	}
+
+	// Rows removed by the orphan repair job are kept here for support
+	quarantineTableSQL := `
+	CREATE TABLE IF NOT EXISTS quarantine (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		source_table VARCHAR(64) NOT NULL,
+		row_id       VARCHAR(128) NOT NULL,
+		data         TEXT,
+		reason       TEXT NOT NULL,
+		created_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.conn.Exec(quarantineTableSQL); err != nil {
+		return fmt.Errorf("failed to create quarantine table: %w", err)
+	}
+
+	// Rendered reports (the local report library); only data is encrypted,
+	// the rest lists the library without decrypting it
+	reportsTableSQL := `
+	CREATE TABLE IF NOT EXISTS reports (
+		id         VARCHAR(128) PRIMARY KEY,
+		kind       VARCHAR(64) NOT NULL,
+		day        VARCHAR(10) NOT NULL,
+		format     VARCHAR(8) NOT NULL,
+		size       INTEGER NOT NULL,
+		data       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS reports_day ON reports(day);
+	`
+
+	if _, err := db.conn.Exec(reportsTableSQL); err != nil {
+		return fmt.Errorf("failed to create reports table: %w", err)
+	}
+
+	// Cashiers signing in at the till with a PIN; only the profile is
+	// encrypted, the argon2id PIN hash is stored beside it
+	usersTableSQL := `
+	CREATE TABLE IF NOT EXISTS users (
+		id            VARCHAR(64) PRIMARY KEY,
+		data          TEXT NOT NULL,
+		pin_hash      TEXT NOT NULL,
+		created_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_login_at DATETIME
+	);
+	`
+
+	if _, err := db.conn.Exec(usersTableSQL); err != nil {
+		return fmt.Errorf("failed to create users table: %w", err)
+	}
+
+	// Loyalty customers (synced from head office; the record is encrypted,
+	// the balance head office reported stays summable) and the append-only
+	// points ledger kept on this terminal
+	customersTableSQL := `
+	CREATE TABLE IF NOT EXISTS customers (
+		id             VARCHAR(64) PRIMARY KEY,
+		data           TEXT NOT NULL,
+		loyalty_points INTEGER NOT NULL DEFAULT 0,
+		updated_at     DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS loyalty_entries (
+		id          VARCHAR(64) PRIMARY KEY,
+		customer_id VARCHAR(64) NOT NULL,
+		kind        VARCHAR(16) NOT NULL,
+		points      INTEGER NOT NULL,
+		data        TEXT NOT NULL,
+		reconciled  BOOLEAN NOT NULL DEFAULT 0,
+		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS loyalty_entries_customer ON loyalty_entries(customer_id, reconciled);
+	`
+
+	if _, err := db.conn.Exec(customersTableSQL); err != nil {
+		return fmt.Errorf("failed to create customer tables: %w", err)
+	}
+
+	var version int
+	if err := db.conn.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	// Version 8 gives outbox entries a ULID; older triggers do not set it
+	if version < outboxUIDSchemaVersion {
+		if err := db.migrateOutboxUIDs(); err != nil {
+			return err
+		}
+	}
+
+	// Every domain table feeds the outbox through CDC triggers
+	for _, t := range cdcTables {
+		if err := db.createCDCTriggers(t.table, t.idColumn, t.payloadColumn); err != nil {
+			return err
+		}
+	}
+
+	// Compression threshold the stored ciphertexts were last rewritten for
+	compressionTableSQL := `
+	CREATE TABLE IF NOT EXISTS compression_state (
+		id        INTEGER PRIMARY KEY CHECK (id = 1),
+		threshold INTEGER NOT NULL DEFAULT 0
+	);
+
+	INSERT OR IGNORE INTO compression_state (id, threshold) VALUES (1, 0);
+	`
+
+	if _, err := db.conn.Exec(compressionTableSQL); err != nil {
+		return fmt.Errorf("failed to create compression state table: %w", err)
+	}
+
+	// Version 6 binds every ciphertext to its row; older rows are unbound
+	if version < aadSchemaVersion {
+		if err := db.bindRowAAD(); err != nil {
+			return err
+		}
+	}
+
+	// Version 9 hash-chains the audit log; older entries are unchained
+	if version < auditChainSchemaVersion {
+		if err := db.chainAuditLog(); err != nil {
+			return err
+		}
+	}
+
+	// Version 10 indexes stock movements by SKU; older rows have none
+	if version < stockMovementSchemaVersion {
+		if err := db.indexStockMovements(); err != nil {
+			return err
+		}
+	}
+
+	// Record the schema version for diagnostics and future migrations
+	if _, err := db.conn.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		return fmt.Errorf("failed to set schema version: %w", err)
+	}
+
 	return nil
 }
 
-// Close closes the database connection
+// ensureColumn adds a column to an existing table if it is missing
+func (db *DB) ensureColumn(table, column, definition string) error {
+	rows, err := db.conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating table info: %w", err)
+	}
+
+	alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.conn.Exec(alter); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
+// Close closes the database connection and wipes the server key
 func (db *DB) Close() error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	db.encryption.Close()
 	if db.conn != nil {
 		return db.conn.Close()
 	}
 	return nil
 }
 
+// Wipe closes the database and securely deletes its file together with
+// the WAL/SHM/journal files and any backups next to it (data.db*). The DB
+// must not be used afterwards.
+func (db *DB) Wipe() error {
+	// Do not keep the decrypted catalog around after the data is gone
+	db.products.reset()
+
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("failed to close database before wipe: %w", err)
+	}
+
+	if db.IsInMemory() {
+		return nil
+	}
+
+	files, err := filepath.Glob(db.dbPath + "*")
+	if err != nil {
+		return fmt.Errorf("failed to list database files: %w", err)
+	}
+
+	for _, file := range files {
+		if err := security.SecureDelete(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Ping checks if the database connection is alive
 func (db *DB) Ping() error {
 	db.mu.RLock()
@@ -148,6 +822,57 @@ func (db *DB) Ping() error {
 	return db.conn.Ping()
 }
 
+// Path returns the database file path (":memory:" for in-memory databases)
+func (db *DB) Path() string {
+	return db.dbPath
+}
+
+// IsInMemory reports whether the database lives only in memory
+func (db *DB) IsInMemory() bool {
+	return db.dbPath == ":memory:"
+}
+
+// SetReadOnly toggles read-only mode. While enabled every mutating
+// operation fails with ErrReadOnly; reads keep working.
+func (db *DB) SetReadOnly(readOnly bool) {
+	if db.readOnly.Swap(readOnly) == readOnly {
+		return
+	}
+	if fn, _ := db.onReadOnly.Load().(func(bool)); fn != nil {
+		fn(readOnly)
+	}
+}
+
+// OnReadOnlyChange sets a function called whenever read-only mode is
+// switched on or off
+func (db *DB) OnReadOnlyChange(fn func(readOnly bool)) {
+	db.onReadOnly.Store(fn)
+}
+
+// OnCatalogChange sets a function called with the table written ("products"
+// or "promotions") after each committed catalog write, local or synced
+func (db *DB) OnCatalogChange(fn func(table string)) {
+	db.onCatalogChange.Store(fn)
+}
+
+// catalogChanged tells the OnCatalogChange function about a committed write
+func (db *DB) catalogChanged(table string) {
+	if fn, _ := db.onCatalogChange.Load().(func(string)); fn != nil {
+		fn(table)
+	}
+}
+
+// IsReadOnly reports whether read-only mode is on
+func (db *DB) IsReadOnly() bool {
+	return db.readOnly.Load()
+}
+
+// refusesWrites reports whether mutating operations are currently
+// rejected, in read-only or offline mode
+func (db *DB) refusesWrites() bool {
+	return db.readOnly.Load() || db.offline.Load()
+}
+
 // GetConnection returns the underlying SQL connection (use with caution)
 func (db *DB) GetConnection() *sql.DB {
 	db.mu.RLock()
@@ -157,24 +882,30 @@ func (db *DB) GetConnection() *sql.DB {
 
 // --- Settings Table Methods ---
 
+// settingNotExpired filters out settings whose TTL has lapsed
+const settingNotExpired = "(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)"
+
+// sqliteTimestampFormat matches the format of SQLite's CURRENT_TIMESTAMP
+const sqliteTimestampFormat = "2006-01-02 15:04:05"
+
 // GetSetting retrieves a setting value by key (decrypts automatically)
 func (db *DB) GetSetting(key string) (string, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
 	var encryptedValue string
-	query := "SELECT value FROM settings WHERE key = ?"
+	query := "SELECT value FROM settings WHERE key = ? AND " + settingNotExpired
 
 	err := db.conn.QueryRow(query, key).Scan(&encryptedValue)
 	if err == sql.ErrNoRows {
-		return "", fmt.Errorf("setting not found: %s", key)
+		return "", fmt.Errorf("%w: %s", ErrSettingNotFound, key)
 	}
 	if err != nil {
 		return "", fmt.Errorf("failed to query setting: %w", err)
 	}
 
 	// Decrypt value
-	decryptedValue, err := db.encryption.Decrypt(encryptedValue)
+	decryptedValue, err := db.encryption.DecryptWithAAD(encryptedValue, rowAAD("settings", key))
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt setting value: %w", err)
 	}
@@ -184,33 +915,103 @@ func (db *DB) GetSetting(key string) (string, error) {
 
 // SetSetting stores a setting value by key (encrypts automatically)
 func (db *DB) SetSetting(key, value string) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	return db.setSetting(key, value, nil)
+}
 
+// setSetting stores a setting with an optional expiry (nil = never)
+func (db *DB) setSetting(key, value string, expiresAt interface{}) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+	return db.storeSetting(key, value, expiresAt)
+}
+
+// storeSetting upserts a setting, whatever the write mode
+func (db *DB) storeSetting(key, value string, expiresAt interface{}) error {
 	// Encrypt value
-	encryptedValue, err := db.encryption.Encrypt([]byte(value))
+	encryptedValue, err := db.encryption.EncryptWithAAD([]byte(value), rowAAD("settings", key))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt setting value: %w", err)
 	}
 
 	// Upsert (INSERT OR REPLACE)
 	query := `
-		INSERT INTO settings (key, value, created_at, updated_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO settings (key, value, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		ON CONFLICT(key) DO UPDATE SET
 			value = excluded.value,
+			expires_at = excluded.expires_at,
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	if _, err := db.conn.Exec(query, key, encryptedValue); err != nil {
-		return fmt.Errorf("failed to set setting: %w", err)
+	return db.withBusyRetry(context.Background(), func() error {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		if _, err := db.conn.Exec(query, key, encryptedValue, expiresAt); err != nil {
+			return fmt.Errorf("failed to set setting: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetSettingWithTTL stores a setting that disappears after ttl
+// (session tokens, temporary provisioning codes)
+func (db *DB) SetSettingWithTTL(key, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
 	}
 
-	return nil
+	// Stored in the same format as CURRENT_TIMESTAMP so SQLite can compare them
+	expiresAt := time.Now().UTC().Add(ttl).Format(sqliteTimestampFormat)
+	return db.setSetting(key, value, expiresAt)
+}
+
+// CleanupExpiredSettings deletes expired settings and returns how many were removed
+func (db *DB) CleanupExpiredSettings() (int64, error) {
+	if db.refusesWrites() {
+		// Expired keys are already invisible to readers
+		return 0, nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	result, err := db.conn.Exec("DELETE FROM settings WHERE NOT " + settingNotExpired)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired settings: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RunSettingsCleanup periodically removes expired settings until ctx is cancelled
+func (db *DB) RunSettingsCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := db.CleanupExpiredSettings(); err != nil {
+				log.Printf("Warning: settings cleanup failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
 // DeleteSetting deletes a setting by key
 func (db *DB) DeleteSetting(key string) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+	return db.removeSetting(key)
+}
+
+// removeSetting deletes a setting, whatever the write mode
+func (db *DB) removeSetting(key string) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
@@ -227,7 +1028,7 @@ func (db *DB) DeleteSetting(key string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("setting not found: %s", key)
+		return fmt.Errorf("%w: %s", ErrSettingNotFound, key)
 	}
 
 	return nil
@@ -235,12 +1036,47 @@ func (db *DB) DeleteSetting(key string) error {
 
 // GetAllSettings retrieves all settings (decrypts automatically)
 func (db *DB) GetAllSettings() (map[string]string, error) {
+	return db.querySettings(settingNotExpired)
+}
+
+// GetSettingsWithPrefix retrieves the settings whose key starts with
+// prefix (decrypts automatically). Only the matching rows are read and
+// decrypted, so listing one family of settings does not touch tokens or
+// secrets stored next to it.
+func (db *DB) GetSettingsWithPrefix(prefix string) (map[string]string, error) {
+	if prefix == "" {
+		return db.GetAllSettings()
+	}
+	// A key range rather than LIKE: it uses the primary key index and is
+	// case-sensitive
+	end, bounded := prefixEnd(prefix)
+	if !bounded {
+		return db.querySettings("key >= ? AND "+settingNotExpired, prefix)
+	}
+	return db.querySettings("key >= ? AND key < ? AND "+settingNotExpired, prefix, end)
+}
+
+// prefixEnd returns the smallest string greater than every string starting
+// with prefix, or false if there is none (prefix is all 0xff bytes)
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// querySettings reads and decrypts the settings matching where
+func (db *DB) querySettings(where string, args ...interface{}) (map[string]string, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
-	query := "SELECT key, value FROM settings"
+	query := "SELECT key, value FROM settings WHERE " + where
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query settings: %w", err)
 	}
@@ -255,7 +1091,7 @@ func (db *DB) GetAllSettings() (map[string]string, error) {
 		}
 
 		// Decrypt value
-		decryptedValue, err := db.encryption.Decrypt(encryptedValue)
+		decryptedValue, err := db.encryption.DecryptWithAAD(encryptedValue, rowAAD("settings", key))
 		if err != nil {
 			// Log error but continue with other settings
 			fmt.Printf("Warning: failed to decrypt setting %s: %v\n", key, err)
@@ -278,7 +1114,7 @@ func (db *DB) SettingExists(key string) (bool, error) {
 	defer db.mu.RUnlock()
 
 	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM settings WHERE key = ?)"
+	query := "SELECT EXISTS(SELECT 1 FROM settings WHERE key = ? AND " + settingNotExpired + ")"
 
 	err := db.conn.QueryRow(query, key).Scan(&exists)
 	if err != nil {
@@ -290,10 +1126,35 @@ func (db *DB) SettingExists(key string) (bool, error) {
 
 // Transaction executes a function within a database transaction
 func (db *DB) Transaction(fn func(*sql.Tx) error) error {
+	return db.TransactionContext(context.Background(), fn)
+}
+
+// TransactionContext executes fn within a transaction, retrying the whole
+// transaction while the database is busy until ctx expires. fn may run more
+// than once, so it must not have side effects outside the transaction.
+func (db *DB) TransactionContext(ctx context.Context, fn func(*sql.Tx) error) error {
+	return db.transactionThen(ctx, fn, nil)
+}
+
+// transactionThen is TransactionContext with a hook run after a successful
+// commit while the write lock is still held, so in-memory state derived
+// from the tables (the product index) changes in step with them
+func (db *DB) transactionThen(ctx context.Context, fn func(*sql.Tx) error, committed func()) error {
+	if db.refusesWrites() {
+		return ErrReadOnly
+	}
+
+	return db.withBusyRetry(ctx, func() error {
+		return db.transaction(ctx, fn, committed)
+	})
+}
+
+// transaction runs one transaction attempt under the write lock
+func (db *DB) transaction(ctx context.Context, fn func(*sql.Tx) error, committed func()) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	tx, err := db.conn.Begin()
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -316,5 +1177,8 @@ func (db *DB) Transaction(fn func(*sql.Tx) error) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	if committed != nil {
+		committed()
+	}
 	return nil
 }
@@ -1,41 +1,38 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
 )
 
-func setupTestDB(t *testing.T) (*DB, func()) {
-	// Create temp directory
-	tmpDir, err := os.MkdirTemp("", "posservice-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
-	}
-
+func setupTestDB(t testing.TB) (*DB, func()) {
 	// Generate server key
 	serverKey, err := security.GenerateServerKey()
 	if err != nil {
 		t.Fatalf("Failed to generate server key: %v", err)
 	}
 
-	// Create database
+	// Create in-memory database
 	db, err := New(&Config{
 		ServerKey: serverKey,
-		DataDir:   tmpDir,
+		InMemory:  true,
 	})
 	if err != nil {
-		os.RemoveAll(tmpDir)
 		t.Fatalf("Failed to create database: %v", err)
 	}
 
 	// Return cleanup function
 	cleanup := func() {
 		db.Close()
-		os.RemoveAll(tmpDir)
 	}
 
 	return db, cleanup
@@ -252,6 +249,30 @@ func TestGetAllSettings(t *testing.T) {
 	}
 }
 
+func TestGetSettingsWithPrefix(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, k := range []string{"hub.cart.1", "hub.cart.2", "hub.carts", "hub.CART.3", "hub.stock.1", "auth.token.x"} {
+		if err := db.SetSetting(k, "v"); err != nil {
+			t.Fatalf("SetSetting failed for %s: %v", k, err)
+		}
+	}
+
+	settings, err := db.GetSettingsWithPrefix("hub.cart.")
+	if err != nil {
+		t.Fatalf("GetSettingsWithPrefix failed: %v", err)
+	}
+	if len(settings) != 2 || settings["hub.cart.1"] != "v" || settings["hub.cart.2"] != "v" {
+		t.Errorf("Expected only the two hub.cart. settings, got %v", settings)
+	}
+
+	// Nothing matches a prefix no key starts with
+	if settings, _ := db.GetSettingsWithPrefix("\xff\xff"); len(settings) != 0 {
+		t.Errorf("Expected no settings, got %v", settings)
+	}
+}
+
 func TestSettingExists(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -368,6 +389,299 @@ func TestDatabasePath(t *testing.T) {
 	}
 }
 
+func TestInMemory_Isolated(t *testing.T) {
+	db1, cleanup1 := setupTestDB(t)
+	defer cleanup1()
+	db2, cleanup2 := setupTestDB(t)
+	defer cleanup2()
+
+	if !db1.IsInMemory() {
+		t.Error("Expected in-memory database")
+	}
+
+	if err := db1.SetSetting("only_in_db1", "value"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+
+	// Second in-memory database must not see the first one's data
+	exists, err := db2.SettingExists("only_in_db1")
+	if err != nil {
+		t.Fatalf("SettingExists failed: %v", err)
+	}
+	if exists {
+		t.Error("In-memory databases share data")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSetting("key", "value"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+
+	db.SetReadOnly(true)
+
+	if err := db.SetSetting("key", "other"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from SetSetting, got %v", err)
+	}
+	if err := db.DeleteSetting("key"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from DeleteSetting, got %v", err)
+	}
+	if err := db.Transaction(func(tx *sql.Tx) error { return nil }); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Transaction, got %v", err)
+	}
+
+	// Reads keep working
+	value, err := db.GetSetting("key")
+	if err != nil || value != "value" {
+		t.Errorf("Expected read to succeed in read-only mode, got %q, %v", value, err)
+	}
+
+	db.SetReadOnly(false)
+	if err := db.SetSetting("key", "other"); err != nil {
+		t.Errorf("SetSetting failed after leaving read-only mode: %v", err)
+	}
+}
+
+func TestReadOnly_RefusesEveryWrite(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	boot, err := db.RecordBoot("1.0.0", time.Hour)
+	if err != nil {
+		t.Fatalf("RecordBoot failed: %v", err)
+	}
+	if err := db.CreateJob("job-1", "import"); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	db.SetReadOnly(true)
+
+	product := &Product{ID: "P1", Barcode: "4006381333931", SKU: "PEN"}
+	writes := map[string]func() error{
+		"UpsertProduct":       func() error { return db.UpsertProduct(product, ProductSourceLocal) },
+		"UpsertProduct(sync)": func() error { return db.UpsertProduct(product, ProductSourceSync) },
+		"ImportProducts":      func() error { return db.ImportProducts([]*Product{product}) },
+		"UpdateProduct":       func() error { return db.UpdateProduct(product) },
+		"ApplyProducts":       func() error { return db.ApplyProducts([]Product{*product}, nil) },
+		"RecordCatalogGap":    func() error { return db.RecordCatalogGap("5012345678900", false) },
+		"MarkCatalogGapsReported": func() error {
+			return db.MarkCatalogGapsReported([]string{"5012345678900"})
+		},
+		"ReplacePromotions": func() error {
+			return db.ReplacePromotions([]Promotion{{ID: "P3", Kind: PromotionPrice, Value: 700, SKUs: []string{"BREAD"}}})
+		},
+		"ReplaceRecommendationRules": func() error { return db.ReplaceRecommendationRules(nil) },
+		"ApplyCustomers":             func() error { return db.ApplyCustomers([]Customer{{ID: "C1", Name: "Dana"}}, nil) },
+		"ImportCustomers":            func() error { return db.ImportCustomers([]Customer{{ID: "C1", Name: "Dana"}}) },
+		"RecordLoyalty": func() error {
+			_, _, err := db.RecordLoyalty(&LoyaltyEntry{ID: "L1", CustomerID: "C1", Kind: LoyaltyAccrual, Points: 10})
+			return err
+		},
+		"AppendBasketLines": func() error {
+			_, err := db.AppendBasketLines("B1", "T1", []SaleLine{{SKU: "PEN", Quantity: 1, Price: 100}}, 0)
+			return err
+		},
+		"DeleteBasket":     func() error { return db.DeleteBasket("B1") },
+		"VoidBasket":       func() error { return db.VoidBasket(&TransactionVoid{ID: "B1", TerminalID: "T1"}) },
+		"RecordAudit":      func() error { return db.RecordAudit(&AuditEntry{Event: "test"}) },
+		"RecordDayClosing": func() error { return db.RecordDayClosing(&DayClosing{Day: "2025-11-16", TerminalID: "T1"}) },
+		"RegisterDevice":   func() error { return db.RegisterDevice(&Device{ID: "D1", Name: "Handheld"}) },
+		"TouchDevice":      func() error { return db.TouchDevice("D1") },
+		"DeleteDevice":     func() error { return db.DeleteDevice("D1") },
+		"RecordDeviceAudit": func() error {
+			return db.RecordDeviceAudit(&DeviceAuditEntry{DeviceID: "D1", Action: "GET /price/:barcode", Status: 200})
+		},
+		"AdjustStock": func() error {
+			_, _, err := db.AdjustStock(&StockAdjustment{ID: "A1", SKU: "PEN", Delta: 1})
+			return err
+		},
+		"RecordStockMovements": func() error {
+			_, _, err := db.RecordStockMovements([]*StockAdjustment{{ID: "A2", SKU: "PEN", Delta: 1}})
+			return err
+		},
+		"SetStockLevel":     func() error { return db.SetStockLevel("PEN", 5) },
+		"RequestLabels":     func() error { return db.RequestLabels(&LabelRequest{DeviceID: "D1", SKU: "PEN", Copies: 1}) },
+		"MarkLabelsPrinted": func() error { return db.MarkLabelsPrinted(1) },
+		"SaveReport": func() error {
+			return db.SaveReport(&ReportFile{ID: "r", Kind: "sales", Day: "2025-11-16", Format: "csv"}, []byte("x"))
+		},
+		"CreateUser":        func() error { return db.CreateUser(&User{ID: "U1", Name: "Dana", Role: "cashier"}) },
+		"SetUserPIN":        func() error { return db.SetUserPIN("U1", "hash") },
+		"TouchUser":         func() error { return db.TouchUser("U1") },
+		"DeleteUser":        func() error { return db.DeleteUser("U1") },
+		"RecordBoot":        func() error { _, err := db.RecordBoot("1.0.0", time.Hour); return err },
+		"TouchBoot":         func() error { return db.TouchBoot(boot.ID) },
+		"RecordShutdown":    func() error { return db.RecordShutdown(boot.ID, ShutdownStopped) },
+		"CreateJob":         func() error { return db.CreateJob("job-2", "import") },
+		"StartJob":          func() error { return db.StartJob("job-1") },
+		"UpdateJobProgress": func() error { return db.UpdateJobProgress("job-1", 50, "halfway") },
+		"SetJobDetails":     func() error { return db.SetJobDetails("job-1", map[string]int{"rows": 1}) },
+		"FinishJob":         func() error { return db.FinishJob("job-1", "", nil) },
+		"FailInterruptedJobs": func() error {
+			_, err := db.FailInterruptedJobs()
+			return err
+		},
+		"MarkOutboxSynced":    func() error { return db.MarkOutboxSynced([]int64{1}) },
+		"MarkOutboxAttempted": func() error { return db.MarkOutboxAttempted([]int64{1}) },
+		"MarkOutboxSyncedThrough": func() error {
+			_, err := db.MarkOutboxSyncedThrough(1)
+			return err
+		},
+		"MarkOutboxClassSyncedThrough": func() error {
+			_, err := db.MarkOutboxClassSyncedThrough(OutboxClassTransactions, 1)
+			return err
+		},
+		"ApplySync":  func() error { return db.ApplySync(func(*sql.Tx) error { return nil }) },
+		"SetSetting": func() error { return db.SetSetting("key", "value") },
+		"SetSettingWithTTL": func() error {
+			return db.SetSettingWithTTL("key", "value", time.Hour)
+		},
+		"DeleteSetting": func() error { return db.DeleteSetting("key") },
+		"Transaction":   func() error { return db.Transaction(func(*sql.Tx) error { return nil }) },
+		"Rekey": func() error {
+			return db.Rekey(context.Background(), make([]byte, 32), nil)
+		},
+	}
+
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: expected ErrReadOnly, got %v", name, err)
+		}
+	}
+}
+
+func TestOnReadOnlyChange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var changes []bool
+	db.OnReadOnlyChange(func(readOnly bool) { changes = append(changes, readOnly) })
+
+	db.SetReadOnly(true)
+	db.SetReadOnly(true) // Unchanged: not reported
+	db.SetReadOnly(false)
+
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("Expected [true false], got %v", changes)
+	}
+}
+
+func TestOnCatalogChange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var tables []string
+	db.OnCatalogChange(func(table string) { tables = append(tables, table) })
+
+	if err := db.UpsertProduct(&Product{ID: "P1", Barcode: "4006381333931"}, ProductSourceSync); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	if err := db.ReplacePromotions([]Promotion{{ID: "P3", Kind: PromotionPrice, Value: 700, SKUs: []string{"BREAD"}}}); err != nil {
+		t.Fatalf("ReplacePromotions failed: %v", err)
+	}
+	db.SetReadOnly(true)
+	db.UpsertProduct(&Product{ID: "P2", Barcode: "5012345678900"}, ProductSourceLocal) // Refused: not reported
+
+	if len(tables) != 2 || tables[0] != "products" || tables[1] != "promotions" {
+		t.Errorf("Expected [products promotions], got %v", tables)
+	}
+}
+
+func TestSetSettingWithTTL(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSettingWithTTL("session", "token", time.Hour); err != nil {
+		t.Fatalf("SetSettingWithTTL failed: %v", err)
+	}
+	if err := db.SetSettingWithTTL("code", "123456", time.Hour); err != nil {
+		t.Fatalf("SetSettingWithTTL failed: %v", err)
+	}
+
+	if value, err := db.GetSetting("session"); err != nil || value != "token" {
+		t.Fatalf("Expected unexpired setting to be readable, got %q (%v)", value, err)
+	}
+
+	// Expire one key
+	_, err := db.GetConnection().Exec("UPDATE settings SET expires_at = '2000-01-01 00:00:00' WHERE key = ?", "code")
+	if err != nil {
+		t.Fatalf("Failed to backdate expiry: %v", err)
+	}
+
+	if _, err := db.GetSetting("code"); !errors.Is(err, ErrSettingNotFound) {
+		t.Errorf("Expected expired setting to be hidden, got %v", err)
+	}
+	if exists, _ := db.SettingExists("code"); exists {
+		t.Error("Expired setting should not exist")
+	}
+	if all, _ := db.GetAllSettings(); len(all) != 1 {
+		t.Errorf("Expected 1 visible setting, got %d", len(all))
+	}
+
+	removed, err := db.CleanupExpiredSettings()
+	if err != nil {
+		t.Fatalf("CleanupExpiredSettings failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 expired setting removed, got %d", removed)
+	}
+
+	// A plain SetSetting clears the TTL
+	db.SetSetting("session", "permanent")
+	var expiresAt sql.NullString
+	db.GetConnection().QueryRow("SELECT expires_at FROM settings WHERE key = ?", "session").Scan(&expiresAt)
+	if expiresAt.Valid {
+		t.Error("SetSetting should clear the TTL")
+	}
+}
+
+func TestProfiles_Isolated(t *testing.T) {
+	tmpDir := t.TempDir()
+	serverKey, _ := security.GenerateServerKey()
+
+	register1, err := New(&Config{ServerKey: serverKey, DataDir: tmpDir, Profile: "store-1"})
+	if err != nil {
+		t.Fatalf("Failed to create profile database: %v", err)
+	}
+	defer register1.Close()
+
+	register2, err := New(&Config{ServerKey: serverKey, DataDir: tmpDir, Profile: "store-2"})
+	if err != nil {
+		t.Fatalf("Failed to create profile database: %v", err)
+	}
+	defer register2.Close()
+
+	expected := filepath.Join(tmpDir, "profiles", "store-1", "data.db")
+	if register1.Path() != expected {
+		t.Errorf("Expected path %s, got %s", expected, register1.Path())
+	}
+
+	register1.SetSetting("till", "1")
+	if exists, _ := register2.SettingExists("till"); exists {
+		t.Error("Profile databases share data")
+	}
+
+	profiles, err := ListProfiles(tmpDir)
+	if err != nil {
+		t.Fatalf("ListProfiles failed: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Errorf("Expected 2 profiles, got %v", profiles)
+	}
+}
+
+func TestProfiles_InvalidName(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+
+	for _, name := range []string{"../escape", ".hidden", "a/b"} {
+		if _, err := New(&Config{ServerKey: serverKey, DataDir: t.TempDir(), Profile: name}); err == nil {
+			t.Errorf("Expected profile name %q to be rejected", name)
+		}
+	}
+}
+
 func BenchmarkSetSetting(b *testing.B) {
 	tmpDir, _ := os.MkdirTemp("", "posservice-bench-*")
 	defer os.RemoveAll(tmpDir)
@@ -403,3 +717,71 @@ func BenchmarkGetSetting(b *testing.B) {
 		db.GetSetting("bench_key")
 	}
 }
+
+func TestWipe(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	tmpDir := t.TempDir()
+
+	db, err := New(&Config{ServerKey: serverKey, DataDir: tmpDir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if err := db.SetSetting("secret", "value"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+
+	// A stray backup next to the database must go too
+	backup := db.Path() + ".bak"
+	if err := os.WriteFile(backup, []byte("backup"), 0600); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	unrelated := filepath.Join(tmpDir, "keep.txt")
+	if err := os.WriteFile(unrelated, []byte("keep"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := db.Wipe(); err != nil {
+		t.Fatalf("Wipe failed: %v", err)
+	}
+
+	remaining, _ := filepath.Glob(db.Path() + "*")
+	if len(remaining) != 0 {
+		t.Errorf("Expected database files to be removed, found %v", remaining)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("Expected unrelated file to survive: %v", err)
+	}
+}
+
+func TestNew_SwitchingCipherKeepsOldValues(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+
+	db, err := New(&Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if err := db.SetSetting("store.name", "Corner Shop"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	db.Close()
+
+	db, err = New(&Config{ServerKey: serverKey, DataDir: dir, Cipher: constants.DBCipherAES256GCM})
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if value, err := db.GetSetting("store.name"); err != nil || value != "Corner Shop" {
+		t.Errorf("ChaCha20 setting unreadable after switching cipher: %q (%v)", value, err)
+	}
+	if err := db.SetSetting("store.city", "Tashkent"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if stored := storedSetting(t, db, "store.city"); !strings.HasPrefix(stored, "a:") {
+		t.Errorf("Expected an AES-256-GCM ciphertext, got %.8s", stored)
+	}
+	if value, err := db.GetSetting("store.city"); err != nil || value != "Tashkent" {
+		t.Errorf("AES setting unreadable: %q (%v)", value, err)
+	}
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches a lookup
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserExists is returned when creating a user with an ID already in use
+	ErrUserExists = errors.New("user already exists")
+)
+
+// User is a cashier (or attendant) signing in at the till with a PIN
+type User struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	PINHash     string `json:"-"`                       // argon2id hash of the PIN
+	CreatedAt   string `json:"created_at"`              // ISO 8601 timestamp
+	LastLoginAt string `json:"last_login_at,omitempty"` // Last successful PIN sign-in
+}
+
+// --- User Methods ---
+
+// CreateUser stores a new user
+func (db *DB) CreateUser(user *User) error {
+	user.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	encryptedData, err := db.encryptUser(user)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"INSERT INTO users (id, data, pin_hash) VALUES (?, ?, ?) ON CONFLICT(id) DO NOTHING",
+			user.ID, encryptedData, user.PINHash,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert user: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrUserExists
+		}
+		return nil
+	})
+}
+
+// GetUser retrieves a user by ID (decrypts automatically)
+func (db *DB) GetUser(id string) (*User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	user, err := db.scanUser(db.conn.QueryRow(
+		"SELECT id, data, pin_hash, COALESCE(last_login_at, '') FROM users WHERE id = ?", id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// ListUsers returns all users ordered by ID
+func (db *DB) ListUsers() ([]User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows, err := db.conn.Query("SELECT id, data, pin_hash, COALESCE(last_login_at, '') FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		user, err := db.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetUserPIN replaces a user's PIN hash
+func (db *DB) SetUserPIN(id, pinHash string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE users SET pin_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", pinHash, id)
+		if err != nil {
+			return fmt.Errorf("failed to update user PIN: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// TouchUser records a successful sign-in, in offline mode too
+func (db *DB) TouchUser(id string) error {
+	return db.sessionTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE users SET last_login_at = ? WHERE id = ?",
+			time.Now().UTC().Format(sqliteTimestampFormat), id)
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteUser removes a user. Sales keep their operator ID.
+func (db *DB) DeleteUser(id string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM users WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// encryptUser seals a user's profile for its row
+func (db *DB) encryptUser(user *User) (string, error) {
+	jsonData, err := json.Marshal(user)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal user: %w", err)
+	}
+	encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("users", user.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt user: %w", err)
+	}
+	return encryptedData, nil
+}
+
+// scanUser decodes an (id, data, pin_hash, last_login_at) row
+func (db *DB) scanUser(row rowScanner) (*User, error) {
+	var id, encryptedData, pinHash, lastLogin string
+	if err := row.Scan(&id, &encryptedData, &pinHash, &lastLogin); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("users", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt user: %w", err)
+	}
+
+	var user User
+	if err := json.Unmarshal(jsonData, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+	user.PINHash = pinHash
+	user.LastLoginAt = lastLogin
+	return &user, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUsers_CreateGetDelete(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := &User{ID: "C-01", Name: "Dana", Role: "cashier", PINHash: "hash-1"}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if err := db.CreateUser(&User{ID: "C-01"}); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+
+	if err := db.SetUserPIN("C-01", "hash-2"); err != nil {
+		t.Fatalf("SetUserPIN failed: %v", err)
+	}
+	if err := db.TouchUser("C-01"); err != nil {
+		t.Fatalf("TouchUser failed: %v", err)
+	}
+	got, err := db.GetUser("C-01")
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if got.Name != "Dana" || got.Role != "cashier" || got.PINHash != "hash-2" || got.LastLoginAt == "" {
+		t.Errorf("Unexpected user: %+v", got)
+	}
+
+	if users, _ := db.ListUsers(); len(users) != 1 {
+		t.Errorf("Expected 1 user, got %d", len(users))
+	}
+
+	if err := db.DeleteUser("C-01"); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if _, err := db.GetUser("C-01"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if err := db.SetUserPIN("C-01", "hash-3"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
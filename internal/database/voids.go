@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrVoidNotFound is returned when no void matches a lookup
+var ErrVoidNotFound = errors.New("void not found")
+
+// TransactionVoid records an open transaction (basket) cancelled before
+// tender. The basket's lines are discarded; the void itself is kept and
+// reaches head office through the outbox for loss prevention.
+type TransactionVoid struct {
+	ID         string `json:"id"` // The voided basket's ID
+	TerminalID string `json:"terminal_id"`
+	OperatorID string `json:"operator_id,omitempty"`
+	LineCount  int    `json:"line_count"`
+	Quantity   int    `json:"quantity"`
+	Total      int64  `json:"total"` // Minor currency units
+	Reason     string `json:"reason,omitempty"`
+	CreatedAt  string `json:"created_at"` // ISO 8601 timestamp
+}
+
+// VoidBasket records a void for a basket and deletes the basket and its
+// lines in one transaction. The void is built from the basket's totals; it
+// returns ErrBasketNotFound if the basket is gone.
+func (db *DB) VoidBasket(void *TransactionVoid) error {
+	if void.ID == "" {
+		return fmt.Errorf("void id is required")
+	}
+	if void.CreatedAt == "" {
+		void.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		totals, err := scanBasketTotals(tx.QueryRow(basketTotalsQuery, void.ID))
+		if err != nil {
+			return err
+		}
+		void.TerminalID = totals.TerminalID
+		void.LineCount = totals.LineCount
+		void.Quantity = totals.Quantity
+		void.Total = totals.Total
+
+		jsonData, err := json.Marshal(void)
+		if err != nil {
+			return fmt.Errorf("failed to marshal void: %w", err)
+		}
+		encryptedData, err := db.encryption.EncryptWithAAD(jsonData, rowAAD("transaction_voids", void.ID))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt void: %w", err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO transaction_voids (id, data) VALUES (?, ?)", void.ID, encryptedData); err != nil {
+			return fmt.Errorf("failed to insert void: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM basket_lines WHERE basket_id = ?", void.ID); err != nil {
+			return fmt.Errorf("failed to delete basket lines: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM baskets WHERE id = ?", void.ID); err != nil {
+			return fmt.Errorf("failed to delete basket: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetVoid retrieves a void by the voided basket's ID
+func (db *DB) GetVoid(id string) (*TransactionVoid, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var encryptedData string
+	err := db.conn.QueryRow("SELECT data FROM transaction_voids WHERE id = ?", id).Scan(&encryptedData)
+	if err == sql.ErrNoRows {
+		return nil, ErrVoidNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query void: %w", err)
+	}
+
+	jsonData, err := db.encryption.DecryptWithAAD(encryptedData, rowAAD("transaction_voids", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt void: %w", err)
+	}
+
+	var void TransactionVoid
+	if err := json.Unmarshal(jsonData, &void); err != nil {
+		return nil, fmt.Errorf("failed to parse void: %w", err)
+	}
+
+	return &void, nil
+}
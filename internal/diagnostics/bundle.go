@@ -0,0 +1,112 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/archive"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+const (
+	// FormatName identifies the bundle format in manifests
+	FormatName = "pos-diagnostics"
+
+	// FormatVersion is the newest bundle format this code writes
+	FormatVersion = 1
+
+	// EncryptedExt is appended to the name of every encrypted member
+	EncryptedExt = ".enc"
+
+	// Bundle member names
+	readmeFile   = "README.txt"
+	manifestFile = "manifest.json"
+)
+
+// File is one file in a bundle, e.g. "logs.jsonl"
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Manifest describes a bundle. It is the only member stored in the clear
+// besides the README, and holds no terminal data.
+type Manifest struct {
+	Format        string                 `json:"format"`
+	FormatVersion int                    `json:"format_version"`
+	CreatedAt     string                 `json:"created_at"`
+	StoreID       string                 `json:"store_id"`
+	Encryption    archive.EncryptionInfo `json:"encryption"`
+	Files         []archive.FileInfo     `json:"files"` // Encrypted members and their SHA-256
+}
+
+// Write returns a ZIP bundle holding files, each encrypted with the store's
+// server key, so support can open it and nobody the file passes through
+// on the way can
+func Write(files []File, serverKey []byte, storeID string) ([]byte, error) {
+	encryption, err := security.NewDatabaseEncryption(serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle encryption: %w", err)
+	}
+	defer encryption.Close()
+
+	manifest := &Manifest{
+		Format:        FormatName,
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		StoreID:       storeID,
+		Encryption:    archive.EncryptionInfo{Algorithm: encryption.Cipher(), KeyID: archive.KeyID(serverKey)},
+	}
+	members := make([]File, 0, len(files)+2)
+	for _, f := range files {
+		encrypted, err := encryption.Encrypt(f.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", f.Name, err)
+		}
+		member := File{Name: f.Name + EncryptedExt, Data: []byte(encrypted)}
+		sum := sha256.Sum256(member.Data)
+		manifest.Files = append(manifest.Files, archive.FileInfo{Name: member.Name, SHA256: hex.EncodeToString(sum[:])})
+		members = append(members, member)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	members = append([]File{{manifestFile, manifestData}, {readmeFile, []byte(readme)}}, members...)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		w, err := zw.Create(m.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to bundle: %w", m.Name, err)
+		}
+		if _, err := w.Write(m.Data); err != nil {
+			return nil, fmt.Errorf("failed to write %s to bundle: %w", m.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readme is embedded in every bundle for support staff
+const readme = `POS diagnostics bundle (format "pos-diagnostics", version 1)
+
+manifest.json  Store, creation time, key ID and the SHA-256 of every
+               encrypted file in this bundle.
+*.enc          One file each, encrypted: the sanitized config, recent log
+               entries, sync history, database statistics and version.
+
+Decrypting a .enc file: base64-decode it; the first 12 bytes are the
+nonce, the rest is ChaCha20-Poly1305 (RFC 8439) ciphertext with its
+16-byte tag, no associated data. The key is the store's 32-byte server
+key; manifest.json "key_id" identifies which key was used.
+`
@@ -0,0 +1,72 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/archive"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func TestWrite_EncryptsEveryFile(t *testing.T) {
+	key, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("GenerateServerKey failed: %v", err)
+	}
+	files := []File{
+		{Name: "logs.jsonl", Data: []byte(`{"msg":"printer offline","customer":"Jane Doe"}`)},
+		{Name: "version.json", Data: []byte(`{"version":"1.2.3"}`)},
+	}
+
+	bundle, err := Write(files, key, "store-7")
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if bytes.Contains(bundle, []byte("Jane Doe")) {
+		t.Fatal("Bundle holds plaintext")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatalf("Bundle is not a ZIP: %v", err)
+	}
+	members := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s failed: %v", f.Name, err)
+		}
+		members[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(members[manifestFile], &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if manifest.Format != FormatName || manifest.StoreID != "store-7" || manifest.Encryption.KeyID != archive.KeyID(key) {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+	if len(manifest.Files) != len(files) || len(members[readmeFile]) == 0 {
+		t.Fatalf("Expected %d files and a README: %+v", len(files), manifest.Files)
+	}
+
+	encryption, _ := security.NewDatabaseEncryption(key)
+	defer encryption.Close()
+	for i, info := range manifest.Files {
+		data := members[info.Name]
+		sum := sha256.Sum256(data)
+		if info.Name != files[i].Name+EncryptedExt || info.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("Unexpected file entry %+v", info)
+		}
+		plaintext, err := encryption.Decrypt(string(data))
+		if err != nil || !bytes.Equal(plaintext, files[i].Data) {
+			t.Errorf("%s did not decrypt with the server key: %v", info.Name, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package directives
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Directives are commands pushed from head office to a terminal (over HTTP
+// or MQTT). Every delivery channel hands them to the same Processor so the
+// behaviour does not depend on how a directive arrived.
+
+// ErrUnknownDirective is returned for directive types without a handler
+var ErrUnknownDirective = errors.New("unknown directive type")
+
+// Directive is a command issued by head office
+type Directive struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	IssuedAt string          `json:"issued_at,omitempty"` // ISO 8601 timestamp
+}
+
+// Result reports the outcome of a directive back to head office
+type Result struct {
+	DirectiveID string `json:"directive_id"`
+	OK          bool   `json:"ok"`
+	Error       string `json:"error,omitempty"`
+	ProcessedAt string `json:"processed_at"` // ISO 8601 timestamp
+}
+
+// Handler executes one directive type
+type Handler func(ctx context.Context, d *Directive) error
+
+// Processor dispatches directives to registered handlers
+type Processor struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewProcessor creates an empty directive processor
+func NewProcessor() *Processor {
+	return &Processor{handlers: make(map[string]Handler)}
+}
+
+// Register installs the handler for a directive type, replacing any previous one
+func (p *Processor) Register(directiveType string, handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[directiveType] = handler
+}
+
+// Types returns the registered directive types
+func (p *Processor) Types() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	types := make([]string, 0, len(p.handlers))
+	for t := range p.handlers {
+		types = append(types, t)
+	}
+	return types
+}
+
+// Process executes a directive and returns its result
+func (p *Processor) Process(ctx context.Context, d *Directive) *Result {
+	result := &Result{DirectiveID: d.ID}
+
+	if err := p.dispatch(ctx, d); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.OK = true
+	}
+
+	result.ProcessedAt = time.Now().UTC().Format(time.RFC3339)
+	return result
+}
+
+// dispatch runs the handler for a directive, recovering from panics
+func (p *Processor) dispatch(ctx context.Context, d *Directive) (err error) {
+	p.mu.RLock()
+	handler, ok := p.handlers[d.Type]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDirective, d.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("directive %s panicked: %v", d.Type, r)
+		}
+	}()
+
+	return handler(ctx, d)
+}
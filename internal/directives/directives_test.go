@@ -0,0 +1,57 @@
+package directives
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProcessor_Process(t *testing.T) {
+	p := NewProcessor()
+
+	var got string
+	p.Register("echo", func(ctx context.Context, d *Directive) error {
+		got = string(d.Payload)
+		return nil
+	})
+
+	result := p.Process(context.Background(), &Directive{ID: "d1", Type: "echo", Payload: []byte(`"hi"`)})
+	if !result.OK || result.DirectiveID != "d1" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if got != `"hi"` {
+		t.Errorf("Handler received %q", got)
+	}
+}
+
+func TestProcessor_Failures(t *testing.T) {
+	p := NewProcessor()
+	p.Register("fail", func(ctx context.Context, d *Directive) error {
+		return errors.New("boom")
+	})
+	p.Register("panic", func(ctx context.Context, d *Directive) error {
+		panic("oops")
+	})
+
+	tests := []struct {
+		directiveType string
+		wantError     string
+	}{
+		{"fail", "boom"},
+		{"panic", "panicked"},
+		{"missing", ErrUnknownDirective.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.directiveType, func(t *testing.T) {
+			result := p.Process(context.Background(), &Directive{ID: "d", Type: tt.directiveType})
+			if result.OK {
+				t.Fatal("Expected failure")
+			}
+			if !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantError, result.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Live service events fan out in process to the frontends watching them
+// (/ws/status, /sync/events) and to webhooks. Delivery is best effort: a
+// subscriber that falls behind loses events rather than slowing down the
+// publisher, and gets a fresh snapshot when it reconnects.
+
+// Event types
+const (
+	SyncStarted        = "sync.started"
+	SyncProgress       = "sync.progress" // One per batch transferred
+	SyncFinished       = "sync.finished"
+	OfflineChanged     = "offline.changed"     // Data: {"offline": bool}
+	PeripheralChanged  = "peripheral.changed"  // Data: the peripheral's new status
+	SaleCompleted      = "sale.completed"      // Data: the committed sale or refund
+	PromotionActivated = "promotion.activated" // Data: the promotion that started running
+)
+
+// Known reports whether eventType is one of the types above
+func Known(eventType string) bool {
+	switch eventType {
+	case SyncStarted, SyncProgress, SyncFinished, OfflineChanged, PeripheralChanged, SaleCompleted, PromotionActivated:
+		return true
+	}
+	return false
+}
+
+// Event is a live service event
+type Event struct {
+	Type string      `json:"type"`
+	Time string      `json:"time"` // ISO 8601 timestamp
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Bus delivers published events to every current subscriber
+type Bus struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subs: make(map[chan Event]struct{})}
+}
+
+// Publish sends an event to every subscriber with room for it
+func (b *Bus) Publish(eventType string, data interface{}) {
+	event := Event{Type: eventType, Time: time.Now().UTC().Format(time.RFC3339), Data: data}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			// Slow subscriber; drop rather than block the publisher
+		}
+	}
+}
+
+// Subscribe returns a channel receiving events published from now on,
+// buffering up to buffer of them, and a function ending the subscription
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Subscribers returns the number of current subscribers
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
@@ -0,0 +1,31 @@
+package events
+
+import "testing"
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Subscribe(1)
+	bus.Publish(SyncStarted, nil)
+	bus.Publish(SyncFinished, nil) // Buffer full: dropped
+
+	event := <-ch
+	if event.Type != SyncStarted || event.Time == "" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	select {
+	case event := <-ch:
+		t.Errorf("Expected the overflowing event to be dropped, got %+v", event)
+	default:
+	}
+
+	cancel()
+	cancel()
+	if bus.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers after cancel, got %d", bus.Subscribers())
+	}
+	if _, open := <-ch; open {
+		t.Errorf("Expected the channel to be closed after cancel")
+	}
+	bus.Publish(SyncStarted, nil) // No subscribers: must not panic
+}
@@ -0,0 +1,168 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/logging"
+)
+
+// ErrConflict is returned when the hub refuses a state change (transfer
+// already claimed or addressed to another terminal, intervention already
+// resolved by someone else)
+var ErrConflict = errors.New("hub conflict")
+
+// headerRequestID forwards the ID of the local API request a hub call is
+// made for, so the hub logs it under the same ID
+const headerRequestID = "X-Request-ID"
+
+// Client talks to the store hub from a terminal
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a hub client. baseURL is the hub API root (…/hub);
+// token is a bearer token the hub issued for this terminal.
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), token: token, httpClient: httpClient}
+}
+
+// OfferTransfer publishes a basket for another terminal
+func (c *Client) OfferTransfer(ctx context.Context, offer *Transfer) (*Transfer, error) {
+	var t Transfer
+	if err := c.do(ctx, http.MethodPost, "/transfers", offer, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ClaimTransfer takes exclusive hold of a transfer; the result carries the claim token
+func (c *Client) ClaimTransfer(ctx context.Context, id, terminalID string) (*Transfer, error) {
+	var t Transfer
+	if err := c.do(ctx, http.MethodPost, "/transfers/"+url.PathEscape(id)+"/claim", TransferAction{TerminalID: terminalID}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ReleaseTransfer gives a claimed transfer back
+func (c *Client) ReleaseTransfer(ctx context.Context, id, terminalID, token string) error {
+	action := TransferAction{TerminalID: terminalID, ClaimToken: token}
+	return c.do(ctx, http.MethodPost, "/transfers/"+url.PathEscape(id)+"/release", action, nil)
+}
+
+// CompleteTransfer records the sale that paid for a claimed transfer
+func (c *Client) CompleteTransfer(ctx context.Context, id, terminalID, token, saleID string) error {
+	action := TransferAction{TerminalID: terminalID, ClaimToken: token, SaleID: saleID}
+	return c.do(ctx, http.MethodPost, "/transfers/"+url.PathEscape(id)+"/complete", action, nil)
+}
+
+// RaiseIntervention posts a lane intervention for the attendants
+func (c *Client) RaiseIntervention(ctx context.Context, in *Intervention) (*Intervention, error) {
+	var raised Intervention
+	if err := c.do(ctx, http.MethodPost, "/interventions", in, &raised); err != nil {
+		return nil, err
+	}
+	return &raised, nil
+}
+
+// GetIntervention returns the current state of an intervention
+func (c *Client) GetIntervention(ctx context.Context, id string) (*Intervention, error) {
+	var in Intervention
+	if err := c.do(ctx, http.MethodGet, "/interventions/"+url.PathEscape(id), nil, &in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// ListInterventions returns interventions in state ("" for all), oldest first
+func (c *Client) ListInterventions(ctx context.Context, state string) ([]Intervention, error) {
+	path := "/interventions"
+	if state != "" {
+		path += "?state=" + url.QueryEscape(state)
+	}
+	interventions := make([]Intervention, 0)
+	if err := c.do(ctx, http.MethodGet, path, nil, &interventions); err != nil {
+		return nil, err
+	}
+	return interventions, nil
+}
+
+// ResolveIntervention records an attendant's decision
+func (c *Client) ResolveIntervention(ctx context.Context, id string, res InterventionResolution) (*Intervention, error) {
+	var in Intervention
+	if err := c.do(ctx, http.MethodPost, "/interventions/"+url.PathEscape(id)+"/resolve", res, &in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// do sends a JSON request and decodes the data of the response envelope
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode hub request: %w", err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create hub request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(headerRequestID, id)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("hub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read hub response: %w", err)
+	}
+
+	var envelope struct {
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	json.Unmarshal(data, &envelope)
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return fmt.Errorf("%w: %s", ErrConflict, envelope.Message)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("hub returned %d: %s", resp.StatusCode, envelope.Message)
+	}
+
+	if out != nil && len(envelope.Result) > 0 {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return fmt.Errorf("failed to decode hub response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package hub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+// Setting key prefixes used to persist hub state (values are encrypted by the database layer)
+const (
+	cartKeyPrefix  = "hub.cart."
+	stockKeyPrefix = "hub.stock."
+)
+
+// syncPaths are the head-office sync endpoints the hub forwards to:
+// /api/sync and the paths below it. Anything else under /hub/sync/ stays
+// on the LAN, so a terminal token cannot reach other head-office routes
+// through the hub. Segments are plain names, which also rules out "..".
+var syncPaths = regexp.MustCompile(`^/api/sync(/[A-Za-z0-9_-]+)*$`)
+
+// Hub is the store-level shared role: it proxies sync for other terminals,
+// hosts the shared parked-cart and stock state and serves catalog blobs over the LAN
+type Hub struct {
+	db         *database.DB
+	serverURL  string
+	blobDir    string
+	httpClient *http.Client
+	metrics    *possync.TransportMetrics
+
+	// stockMu serialises read-modify-write stock adjustments
+	stockMu sync.Mutex
+
+	// transferMu serialises transfer state changes (claims must be exclusive)
+	transferMu sync.Mutex
+
+	// interventionMu serialises intervention resolutions (one decision each)
+	interventionMu sync.Mutex
+}
+
+// Config holds hub configuration
+type Config struct {
+	DB           *database.DB
+	ServerURL    string        // Upstream sync server the hub proxies to
+	BlobDir      string        // Directory holding catalog blobs served to terminals
+	ProxyTimeout time.Duration // Timeout for proxied sync requests
+
+	// HTTPClient is the upstream sync client; nil uses a plain TCP client
+	HTTPClient *http.Client
+	// TransportMetrics are reported by /info when set
+	TransportMetrics *possync.TransportMetrics
+}
+
+// ParkedCart is a cart parked on one terminal that can be resumed on another
+type ParkedCart struct {
+	ID         string          `json:"id"`
+	TerminalID string          `json:"terminal_id"`
+	Payload    json.RawMessage `json:"payload"`
+	ParkedAt   string          `json:"parked_at"` // ISO 8601 timestamp
+}
+
+// StockLevel is the shared on-hand quantity of a SKU
+type StockLevel struct {
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	UpdatedAt string `json:"updated_at"` // ISO 8601 timestamp
+}
+
+// New creates a new hub
+func New(cfg *Config) (*Hub, error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("hub requires a database")
+	}
+
+	timeout := cfg.ProxyTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: timeout}
+	}
+
+	return &Hub{
+		db:         cfg.DB,
+		serverURL:  strings.TrimRight(cfg.ServerURL, "/"),
+		blobDir:    cfg.BlobDir,
+		httpClient: httpClient,
+		metrics:    cfg.TransportMetrics,
+	}, nil
+}
+
+// Register mounts the hub routes on the given router
+func (h *Hub) Register(router fiber.Router) {
+	router.Get("/info", h.handleInfo)
+
+	// Sync proxy
+	router.All("/sync/*", h.handleSyncProxy)
+
+	// Shared parked carts
+	router.Get("/carts", h.handleListCarts)
+	router.Get("/carts/:id", h.handleGetCart)
+	router.Put("/carts/:id", h.handlePutCart)
+	router.Delete("/carts/:id", h.handleDeleteCart)
+
+	// Terminal-to-terminal basket transfers
+	h.registerTransfers(router)
+
+	// Self-checkout interventions routed to attendant terminals
+	h.registerInterventions(router)
+
+	// Shared stock state
+	router.Get("/stock", h.handleListStock)
+	router.Get("/stock/:sku", h.handleGetStock)
+	router.Put("/stock/:sku", h.handleSetStock)
+	router.Post("/stock/:sku/adjust", h.handleAdjustStock)
+
+	// Catalog blobs
+	router.Get("/catalog/:name", h.handleCatalogBlob)
+}
+
+// handleInfo reports the hub role and capabilities
+func (h *Hub) handleInfo(c *fiber.Ctx) error {
+	info := map[string]interface{}{
+		"role":          "hub",
+		"sync_proxy":    h.serverURL != "",
+		"catalog_blobs": h.blobDir != "",
+	}
+	if h.metrics != nil {
+		info["sync_transports"] = h.metrics.Snapshot()
+	}
+
+	return c.JSON(api.NewSuccessResponse(
+		api.CodeDataRetrieved,
+		"Hub information retrieved successfully",
+		info,
+	))
+}
+
+// handleSyncProxy forwards a terminal's sync request to the upstream server
+func (h *Hub) handleSyncProxy(c *fiber.Ctx) error {
+	if h.serverURL == "" {
+		return apperr.Unavailable("Hub has no upstream server configured", time.Minute)
+	}
+
+	path := "/" + c.Params("*")
+	if !syncPaths.MatchString(path) {
+		return apperr.NotFound("Not a sync endpoint")
+	}
+
+	target := h.serverURL + path
+	if query := string(c.Request().URI().QueryString()); query != "" {
+		target += "?" + query
+	}
+
+	req, err := http.NewRequestWithContext(c.UserContext(), c.Method(), target, bytes.NewReader(c.Body()))
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		name := string(key)
+		// The terminal's hub token is no business of head office
+		if name == fiber.HeaderHost || name == fiber.HeaderContentLength || name == fiber.HeaderAuthorization {
+			return
+		}
+		req.Header.Add(name, string(value))
+	})
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return apperr.New(fiber.StatusBadGateway, api.CodeErrorSync, "Upstream sync failed").Wrap(err).Retryable(5 * time.Second)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return apperr.New(fiber.StatusBadGateway, api.CodeErrorSync, "Failed to read upstream response").Wrap(err).Retryable(5 * time.Second)
+	}
+
+	if contentType := resp.Header.Get(fiber.HeaderContentType); contentType != "" {
+		c.Set(fiber.HeaderContentType, contentType)
+	}
+	return c.Status(resp.StatusCode).Send(body)
+}
+
+// handleListCarts lists all parked carts
+func (h *Hub) handleListCarts(c *fiber.Ctx) error {
+	settings, err := h.db.GetSettingsWithPrefix(cartKeyPrefix)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	carts := make([]ParkedCart, 0)
+	for _, value := range settings {
+		var cart ParkedCart
+		if err := json.Unmarshal([]byte(value), &cart); err != nil {
+			continue
+		}
+		carts = append(carts, cart)
+	}
+	sort.Slice(carts, func(i, j int) bool { return carts[i].ParkedAt < carts[j].ParkedAt })
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Parked carts retrieved successfully", carts))
+}
+
+// handleGetCart returns a single parked cart
+func (h *Hub) handleGetCart(c *fiber.Ctx) error {
+	value, err := h.db.GetSetting(cartKeyPrefix + c.Params("id"))
+	if err != nil {
+		return apperr.NotFound("Parked cart not found")
+	}
+
+	var cart ParkedCart
+	if err := json.Unmarshal([]byte(value), &cart); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Parked cart retrieved successfully", cart))
+}
+
+// handlePutCart parks (or re-parks) a cart
+func (h *Hub) handlePutCart(c *fiber.Ctx) error {
+	var cart ParkedCart
+	if err := c.BodyParser(&cart); err != nil {
+		return apperr.BadRequest(api.MessageBadRequest)
+	}
+	if len(cart.Payload) == 0 {
+		return apperr.BadRequest("payload is required")
+	}
+
+	cart.ID = c.Params("id")
+	cart.ParkedAt = time.Now().Format(time.RFC3339)
+
+	data, err := json.Marshal(cart)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+	if err := h.db.SetSetting(cartKeyPrefix+cart.ID, string(data)); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Cart parked successfully", cart))
+}
+
+// handleDeleteCart removes a parked cart (typically when it is resumed)
+func (h *Hub) handleDeleteCart(c *fiber.Ctx) error {
+	if err := h.db.DeleteSetting(cartKeyPrefix + c.Params("id")); err != nil {
+		return apperr.NotFound("Parked cart not found")
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "Parked cart removed successfully", nil))
+}
+
+// handleListStock lists all shared stock levels
+func (h *Hub) handleListStock(c *fiber.Ctx) error {
+	settings, err := h.db.GetSettingsWithPrefix(stockKeyPrefix)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	levels := make([]StockLevel, 0)
+	for _, value := range settings {
+		var level StockLevel
+		if err := json.Unmarshal([]byte(value), &level); err != nil {
+			continue
+		}
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].SKU < levels[j].SKU })
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Stock levels retrieved successfully", levels))
+}
+
+// handleGetStock returns the stock level of a single SKU
+func (h *Hub) handleGetStock(c *fiber.Ctx) error {
+	level, err := h.getStock(c.Params("sku"))
+	if err != nil {
+		return apperr.NotFound("Stock level not found")
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Stock level retrieved successfully", level))
+}
+
+// handleSetStock sets the absolute stock level of a SKU
+func (h *Hub) handleSetStock(c *fiber.Ctx) error {
+	var body struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return apperr.BadRequest(api.MessageBadRequest)
+	}
+
+	h.stockMu.Lock()
+	defer h.stockMu.Unlock()
+
+	level, err := h.putStock(c.Params("sku"), body.Quantity)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Stock level updated successfully", level))
+}
+
+// handleAdjustStock applies a relative change to a SKU's stock level
+func (h *Hub) handleAdjustStock(c *fiber.Ctx) error {
+	var body struct {
+		Delta int `json:"delta"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return apperr.BadRequest(api.MessageBadRequest)
+	}
+
+	sku := c.Params("sku")
+
+	h.stockMu.Lock()
+	defer h.stockMu.Unlock()
+
+	quantity := 0
+	if current, err := h.getStock(sku); err == nil {
+		quantity = current.Quantity
+	}
+
+	level, err := h.putStock(sku, quantity+body.Delta)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Stock level adjusted successfully", level))
+}
+
+// handleCatalogBlob serves a catalog blob file from the blob directory
+func (h *Hub) handleCatalogBlob(c *fiber.Ctx) error {
+	if h.blobDir == "" {
+		return apperr.NotFound("Catalog blobs are not available on this hub")
+	}
+
+	// Reject anything that could escape the blob directory
+	name := c.Params("name")
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return apperr.BadRequest("Invalid catalog blob name")
+	}
+
+	path := filepath.Join(h.blobDir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return apperr.NotFound("Catalog blob not found")
+	}
+
+	// Blobs are replaced whole, so size and modification time version them
+	tag := api.ETag(name, strconv.FormatInt(info.Size(), 10), strconv.FormatInt(info.ModTime().UnixNano(), 10))
+	c.Set(fiber.HeaderETag, tag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" && api.ETagMatches(match, tag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.SendFile(path)
+}
+
+// getStock loads a stock level from the database
+func (h *Hub) getStock(sku string) (*StockLevel, error) {
+	value, err := h.db.GetSetting(stockKeyPrefix + sku)
+	if err != nil {
+		return nil, err
+	}
+
+	var level StockLevel
+	if err := json.Unmarshal([]byte(value), &level); err != nil {
+		return nil, fmt.Errorf("failed to parse stock level: %w", err)
+	}
+	return &level, nil
+}
+
+// putStock stores a stock level in the database
+func (h *Hub) putStock(sku string, quantity int) (*StockLevel, error) {
+	level := &StockLevel{
+		SKU:       sku,
+		Quantity:  quantity,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	data, err := json.Marshal(level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stock level: %w", err)
+	}
+	if err := h.db.SetSetting(stockKeyPrefix+sku, string(data)); err != nil {
+		return nil, err
+	}
+	return level, nil
+}
@@ -0,0 +1,228 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func setupTestHub(t *testing.T, cfg *Config) (*fiber.App, func()) {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	cfg.DB = db
+	h, err := New(cfg)
+	if err != nil {
+		db.Close()
+		t.Fatalf("Failed to create hub: %v", err)
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	h.Register(app.Group("/hub"))
+
+	return app, func() { db.Close() }
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path, body string) (*http.Response, api.APIResponse) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp api.APIResponse
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &apiResp)
+
+	return resp, apiResp
+}
+
+func TestNew_RequiresDB(t *testing.T) {
+	if _, err := New(&Config{}); err == nil {
+		t.Error("Expected error when database is missing")
+	}
+}
+
+func TestParkedCarts(t *testing.T) {
+	app, cleanup := setupTestHub(t, &Config{})
+	defer cleanup()
+
+	resp, _ := doRequest(t, app, "PUT", "/hub/carts/cart-1", `{"terminal_id":"T1","payload":{"items":[1,2]}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp, apiResp := doRequest(t, app, "GET", "/hub/carts/cart-1", "")
+	if resp.StatusCode != http.StatusOK || !apiResp.OK {
+		t.Fatalf("Expected parked cart to be retrievable, got %d", resp.StatusCode)
+	}
+
+	resp, _ = doRequest(t, app, "DELETE", "/hub/carts/cart-1", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp, _ = doRequest(t, app, "GET", "/hub/carts/cart-1", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestStockAdjust(t *testing.T) {
+	app, cleanup := setupTestHub(t, &Config{})
+	defer cleanup()
+
+	doRequest(t, app, "PUT", "/hub/stock/SKU1", `{"quantity":10}`)
+	_, apiResp := doRequest(t, app, "POST", "/hub/stock/SKU1/adjust", `{"delta":-3}`)
+
+	result, ok := apiResp.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Unexpected result: %v", apiResp.Result)
+	}
+	if result["quantity"] != float64(7) {
+		t.Errorf("Expected quantity 7, got %v", result["quantity"])
+	}
+}
+
+func TestSyncProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/sync" {
+			t.Errorf("Unexpected upstream path: %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Expected the hub token to stay on the hub, got %q", auth)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"code":20,"message":"synced"}`))
+	}))
+	defer upstream.Close()
+
+	app, cleanup := setupTestHub(t, &Config{ServerURL: upstream.URL})
+	defer cleanup()
+
+	req := httptest.NewRequest("POST", "/hub/sync/api/sync", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer hub-token")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var apiResp api.APIResponse
+	json.NewDecoder(resp.Body).Decode(&apiResp)
+	if apiResp.Code != api.CodeSyncSuccess {
+		t.Errorf("Expected upstream response to be relayed, got code %d", apiResp.Code)
+	}
+}
+
+func TestSyncProxy_OnlySyncPaths(t *testing.T) {
+	var forwarded []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	app, cleanup := setupTestHub(t, &Config{ServerURL: upstream.URL})
+	defer cleanup()
+
+	for _, path := range []string{
+		"/hub/sync/api/v1/users",
+		"/hub/sync/api/syncx",
+		"/hub/sync/api/sync/..%2F..%2Fadmin",
+		"/hub/sync/",
+	} {
+		if resp, _ := doRequest(t, app, "POST", path, `{}`); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+	if len(forwarded) != 0 {
+		t.Errorf("Expected nothing forwarded upstream, got %v", forwarded)
+	}
+
+	if resp, _ := doRequest(t, app, "GET", "/hub/sync/api/sync/changes", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected a sync sub-path to be forwarded, got %d", resp.StatusCode)
+	}
+}
+
+func TestCatalogBlob_RejectsTraversal(t *testing.T) {
+	blobDir := t.TempDir()
+	os.WriteFile(filepath.Join(blobDir, "catalog.json"), []byte(`[]`), 0644)
+
+	app, cleanup := setupTestHub(t, &Config{BlobDir: blobDir})
+	defer cleanup()
+
+	resp, _ := doRequest(t, app, "GET", "/hub/catalog/catalog.json", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag on catalog blobs")
+	}
+	req := httptest.NewRequest("GET", "/hub/catalog/catalog.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged blob, got %d", resp.StatusCode)
+	}
+
+	resp, _ = doRequest(t, app, "GET", "/hub/catalog/..%2Fsecret", "")
+	if resp.StatusCode == http.StatusOK {
+		t.Error("Expected traversal attempt to be rejected")
+	}
+}
+
+func TestClient_ForwardsRequestID(t *testing.T) {
+	var got, token string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Request-ID")
+		token = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"code":10,"message":"ok","result":[]}`))
+	}))
+	defer upstream.Close()
+
+	client := NewClient(upstream.URL, "hub-token", nil)
+	ctx := logging.WithRequestID(context.Background(), "req-9")
+	if _, err := client.ListInterventions(ctx, InterventionOpen); err != nil {
+		t.Fatalf("ListInterventions failed: %v", err)
+	}
+	if got != "req-9" {
+		t.Errorf("Expected the request ID to reach the hub, got %q", got)
+	}
+	if token != "Bearer hub-token" {
+		t.Errorf("Expected the hub token to be presented, got %q", token)
+	}
+}
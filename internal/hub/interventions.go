@@ -0,0 +1,197 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// interventionKeyPrefix prefixes the settings holding lane interventions
+const interventionKeyPrefix = "hub.intervention."
+
+// Intervention types raised by self-checkout lanes
+const (
+	InterventionWeightCheck = "weight_check" // Bagging-area weight doesn't match the scanned item
+	InterventionAgeCheck    = "age_check"    // Age-restricted item needs ID verification
+)
+
+// Intervention states
+const (
+	InterventionOpen     = "open"     // Waiting for an attendant
+	InterventionResolved = "resolved" // An attendant approved or rejected it
+)
+
+// Intervention is an event a self-checkout lane raises for an attendant.
+// Lanes post them to the hub, attendant terminals list the open ones and
+// resolve them, and the lane blocks checkout until every one is approved.
+type Intervention struct {
+	ID         string `json:"id"`
+	Lane       string `json:"lane"` // Terminal ID of the self-checkout lane
+	CartID     string `json:"cart_id"`
+	Type       string `json:"type"`
+	SKU        string `json:"sku,omitempty"`
+	Detail     string `json:"detail,omitempty"` // e.g. expected vs measured weight
+	State      string `json:"state"`
+	Approved   bool   `json:"approved"`
+	ResolvedBy string `json:"resolved_by,omitempty"` // Attendant terminal
+	Note       string `json:"note,omitempty"`
+	RaisedAt   string `json:"raised_at"`
+	ResolvedAt string `json:"resolved_at,omitempty"`
+}
+
+// InterventionResolution is the body of a resolve request
+type InterventionResolution struct {
+	TerminalID string `json:"terminal_id"`
+	Approved   bool   `json:"approved"`
+	Note       string `json:"note,omitempty"`
+}
+
+// errInterventionNotFound is returned by getIntervention for unknown IDs
+var errInterventionNotFound = errors.New("intervention not found")
+
+// ValidInterventionType reports whether typ is a known intervention type
+func ValidInterventionType(typ string) bool {
+	return typ == InterventionWeightCheck || typ == InterventionAgeCheck
+}
+
+// registerInterventions mounts the intervention routes
+func (h *Hub) registerInterventions(router fiber.Router) {
+	router.Get("/interventions", h.handleListInterventions)
+	router.Post("/interventions", h.handleRaiseIntervention)
+	router.Get("/interventions/:id", h.handleGetIntervention)
+	router.Post("/interventions/:id/resolve", h.handleResolveIntervention)
+}
+
+// handleRaiseIntervention records a new intervention from a lane
+func (h *Hub) handleRaiseIntervention(c *fiber.Ctx) error {
+	var in Intervention
+	if err := json.Unmarshal(c.Body(), &in); err != nil {
+		return apperr.BadRequest(api.MessageBadRequest)
+	}
+	if in.Lane == "" || in.CartID == "" {
+		return apperr.BadRequest("lane and cart_id are required")
+	}
+	if !ValidInterventionType(in.Type) {
+		return apperr.BadRequest("type must be weight_check or age_check")
+	}
+
+	id, err := newClaimToken()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	in.ID = id
+	in.State, in.Approved = InterventionOpen, false
+	in.ResolvedBy, in.Note, in.ResolvedAt = "", "", ""
+	in.RaisedAt = time.Now().Format(time.RFC3339)
+
+	if err := h.putIntervention(&in); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Intervention raised successfully", in))
+}
+
+// handleListInterventions lists interventions, oldest first (?state
+// filters, attendants poll ?state=open; ?lane narrows to one lane)
+func (h *Hub) handleListInterventions(c *fiber.Ctx) error {
+	settings, err := h.db.GetSettingsWithPrefix(interventionKeyPrefix)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	state, lane := c.Query("state"), c.Query("lane")
+	interventions := make([]Intervention, 0)
+	for _, value := range settings {
+		var in Intervention
+		if err := json.Unmarshal([]byte(value), &in); err != nil {
+			continue
+		}
+		if (state == "" || in.State == state) && (lane == "" || in.Lane == lane) {
+			interventions = append(interventions, in)
+		}
+	}
+	sort.Slice(interventions, func(i, j int) bool { return interventions[i].RaisedAt < interventions[j].RaisedAt })
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Interventions retrieved successfully", interventions))
+}
+
+// handleGetIntervention returns a single intervention
+func (h *Hub) handleGetIntervention(c *fiber.Ctx) error {
+	in, err := h.getIntervention(c.Params("id"))
+	if errors.Is(err, errInterventionNotFound) {
+		return apperr.NotFound("Intervention not found")
+	}
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Intervention retrieved successfully", in))
+}
+
+// handleResolveIntervention records an attendant's decision. Repeating the
+// same decision succeeds so a lost response can be retried.
+func (h *Hub) handleResolveIntervention(c *fiber.Ctx) error {
+	var res InterventionResolution
+	if err := json.Unmarshal(c.Body(), &res); err != nil || res.TerminalID == "" {
+		return apperr.BadRequest("terminal_id is required")
+	}
+
+	h.interventionMu.Lock()
+	defer h.interventionMu.Unlock()
+
+	in, err := h.getIntervention(c.Params("id"))
+	if errors.Is(err, errInterventionNotFound) {
+		return apperr.NotFound("Intervention not found")
+	}
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	if in.State == InterventionResolved {
+		if in.ResolvedBy == res.TerminalID && in.Approved == res.Approved {
+			return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Intervention resolved successfully", in))
+		}
+		return apperr.Conflict(fmt.Sprintf("Intervention already resolved by %s", in.ResolvedBy))
+	}
+
+	in.State, in.Approved, in.ResolvedBy, in.Note = InterventionResolved, res.Approved, res.TerminalID, res.Note
+	in.ResolvedAt = time.Now().Format(time.RFC3339)
+	if err := h.putIntervention(in); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Intervention resolved successfully", in))
+}
+
+// getIntervention loads an intervention from the database
+func (h *Hub) getIntervention(id string) (*Intervention, error) {
+	value, err := h.db.GetSetting(interventionKeyPrefix + id)
+	if errors.Is(err, database.ErrSettingNotFound) {
+		return nil, errInterventionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var in Intervention
+	if err := json.Unmarshal([]byte(value), &in); err != nil {
+		return nil, fmt.Errorf("failed to parse intervention: %w", err)
+	}
+	return &in, nil
+}
+
+// putIntervention stores an intervention in the database
+func (h *Hub) putIntervention(in *Intervention) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal intervention: %w", err)
+	}
+	return h.db.SetSetting(interventionKeyPrefix+in.ID, string(data))
+}
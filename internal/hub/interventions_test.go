@@ -0,0 +1,56 @@
+package hub
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInterventions_RaiseListResolve(t *testing.T) {
+	app, cleanup := setupTestHub(t, &Config{})
+	defer cleanup()
+
+	if resp, _ := doRequest(t, app, "POST", "/hub/interventions", `{"lane":"SCO1","cart_id":"c1","type":"fraud"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown type, got %d", resp.StatusCode)
+	}
+
+	resp, apiResp := doRequest(t, app, "POST", "/hub/interventions", `{"lane":"SCO1","cart_id":"c1","type":"age_check","sku":"BEER"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Raise: expected 200, got %d", resp.StatusCode)
+	}
+	raised := apiResp.Result.(map[string]interface{})
+	id, _ := raised["id"].(string)
+	if id == "" || raised["state"] != InterventionOpen {
+		t.Fatalf("Unexpected intervention: %v", raised)
+	}
+
+	_, apiResp = doRequest(t, app, "GET", "/hub/interventions?state=open", "")
+	if open := apiResp.Result.([]interface{}); len(open) != 1 {
+		t.Errorf("Expected 1 open intervention, got %d", len(open))
+	}
+
+	// The first attendant decides; repeating the decision is fine, contradicting it is not
+	resolve := `{"terminal_id":"TILL1","approved":true}`
+	for i := 0; i < 2; i++ {
+		if resp, _ := doRequest(t, app, "POST", "/hub/interventions/"+id+"/resolve", resolve); resp.StatusCode != http.StatusOK {
+			t.Errorf("Resolve attempt %d: expected 200, got %d", i+1, resp.StatusCode)
+		}
+	}
+	if resp, _ := doRequest(t, app, "POST", "/hub/interventions/"+id+"/resolve", `{"terminal_id":"TILL2","approved":false}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for a second attendant, got %d", resp.StatusCode)
+	}
+
+	_, apiResp = doRequest(t, app, "GET", "/hub/interventions/"+id, "")
+	got := apiResp.Result.(map[string]interface{})
+	if got["state"] != InterventionResolved || got["approved"] != true || got["resolved_by"] != "TILL1" {
+		t.Errorf("Unexpected resolved intervention: %v", got)
+	}
+
+	_, apiResp = doRequest(t, app, "GET", "/hub/interventions?state=open", "")
+	if open := apiResp.Result.([]interface{}); len(open) != 0 {
+		t.Errorf("Expected no open interventions, got %d", len(open))
+	}
+
+	if resp, _ := doRequest(t, app, "GET", "/hub/interventions/missing", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown intervention, got %d", resp.StatusCode)
+	}
+}
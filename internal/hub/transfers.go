@@ -0,0 +1,327 @@
+package hub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// transferKeyPrefix prefixes the settings holding basket transfers
+const transferKeyPrefix = "hub.transfer."
+
+// Transfer states
+const (
+	TransferOffered   = "offered"   // Waiting for a terminal to claim it
+	TransferClaimed   = "claimed"   // Exclusively held by ClaimedBy until completed or released
+	TransferCompleted = "completed" // Paid on the claiming terminal
+	TransferCancelled = "cancelled" // Withdrawn by the source before anyone claimed it
+)
+
+// Transfer hands an in-progress basket from one terminal to another (e.g. a
+// handheld scanner to the payment till). Exactly one terminal can hold the
+// claim, only the holder of the claim token can complete it, and a claim
+// never expires on its own, so a basket can't be paid twice.
+type Transfer struct {
+	ID             string              `json:"id"`
+	SourceTerminal string              `json:"source_terminal"`
+	TargetTerminal string              `json:"target_terminal,omitempty"` // Empty: any terminal may claim
+	Lines          []database.SaleLine `json:"lines"`
+	Total          int64               `json:"total"`
+	State          string              `json:"state"`
+	ClaimedBy      string              `json:"claimed_by,omitempty"`
+	ClaimToken     string              `json:"claim_token,omitempty"` // Only returned to the claimer
+	SaleID         string              `json:"sale_id,omitempty"`
+	OfferedAt      string              `json:"offered_at"`
+	UpdatedAt      string              `json:"updated_at"`
+}
+
+// TransferAction is the body of claim, release and complete requests
+type TransferAction struct {
+	TerminalID string `json:"terminal_id"`
+	ClaimToken string `json:"claim_token,omitempty"`
+	SaleID     string `json:"sale_id,omitempty"`
+}
+
+// errTransferNotFound is returned by updateTransfer for unknown IDs
+var errTransferNotFound = errors.New("transfer not found")
+
+// public returns a copy safe to show to terminals other than the claimer
+func (t Transfer) public() Transfer {
+	t.ClaimToken = ""
+	return t
+}
+
+// registerTransfers mounts the transfer routes
+func (h *Hub) registerTransfers(router fiber.Router) {
+	router.Get("/transfers", h.handleListTransfers)
+	router.Post("/transfers", h.handleOfferTransfer)
+	router.Get("/transfers/:id", h.handleGetTransfer)
+	router.Delete("/transfers/:id", h.handleCancelTransfer)
+	router.Post("/transfers/:id/claim", h.handleClaimTransfer)
+	router.Post("/transfers/:id/release", h.handleReleaseTransfer)
+	router.Post("/transfers/:id/complete", h.handleCompleteTransfer)
+}
+
+// handleOfferTransfer publishes a basket for another terminal to pick up
+func (h *Hub) handleOfferTransfer(c *fiber.Ctx) error {
+	var offer Transfer
+	if err := json.Unmarshal(c.Body(), &offer); err != nil {
+		return apperr.BadRequest(api.MessageBadRequest)
+	}
+	if offer.ID == "" || offer.SourceTerminal == "" || len(offer.Lines) == 0 {
+		return apperr.BadRequest("id, source_terminal and lines are required")
+	}
+
+	offer.Total = 0
+	for _, line := range offer.Lines {
+		offer.Total += int64(line.Quantity) * line.Price
+	}
+	now := time.Now().Format(time.RFC3339)
+	offer.State = TransferOffered
+	offer.ClaimedBy, offer.ClaimToken, offer.SaleID = "", "", ""
+	offer.OfferedAt, offer.UpdatedAt = now, now
+
+	h.transferMu.Lock()
+	defer h.transferMu.Unlock()
+
+	if existing, err := h.getTransfer(offer.ID); err == nil {
+		// A retried offer is acknowledged; anything else is a different basket
+		if existing.SourceTerminal == offer.SourceTerminal && existing.State == TransferOffered {
+			return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Transfer offered successfully", existing.public()))
+		}
+		return apperr.Conflict("Transfer ID already in use")
+	}
+
+	if err := h.putTransfer(&offer); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Transfer offered successfully", offer.public()))
+}
+
+// handleListTransfers lists transfers a terminal may act on (?terminal_id):
+// open offers addressed to it or to anyone, and claims it holds
+func (h *Hub) handleListTransfers(c *fiber.Ctx) error {
+	settings, err := h.db.GetSettingsWithPrefix(transferKeyPrefix)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	terminal := c.Query("terminal_id")
+	transfers := make([]Transfer, 0)
+	for _, value := range settings {
+		var t Transfer
+		if err := json.Unmarshal([]byte(value), &t); err != nil {
+			continue
+		}
+
+		open := t.State == TransferOffered && (t.TargetTerminal == "" || terminal == "" || t.TargetTerminal == terminal)
+		held := t.State == TransferClaimed && (terminal == "" || t.ClaimedBy == terminal)
+		if open || held {
+			transfers = append(transfers, t.public())
+		}
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].OfferedAt < transfers[j].OfferedAt })
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Transfers retrieved successfully", transfers))
+}
+
+// handleGetTransfer returns a single transfer
+func (h *Hub) handleGetTransfer(c *fiber.Ctx) error {
+	t, err := h.getTransfer(c.Params("id"))
+	if err != nil {
+		return apperr.NotFound("Transfer not found")
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Transfer retrieved successfully", t.public()))
+}
+
+// handleClaimTransfer gives a terminal exclusive hold of a transfer. A
+// repeated claim by the same terminal returns the same token.
+func (h *Hub) handleClaimTransfer(c *fiber.Ctx) error {
+	action, err := parseTransferAction(c)
+	if err != nil {
+		return err
+	}
+
+	t, err := h.updateTransfer(c.Params("id"), func(t *Transfer) error {
+		switch {
+		case t.State == TransferClaimed && t.ClaimedBy == action.TerminalID:
+			return nil
+		case t.State != TransferOffered:
+			return apperr.Conflict(fmt.Sprintf("Transfer is %s", t.State))
+		case t.TargetTerminal != "" && t.TargetTerminal != action.TerminalID:
+			return apperr.Conflict("Transfer is addressed to another terminal")
+		}
+
+		token, err := newClaimToken()
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		t.State, t.ClaimedBy, t.ClaimToken = TransferClaimed, action.TerminalID, token
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Transfer claimed successfully", t))
+}
+
+// handleReleaseTransfer hands a claimed transfer back for another terminal
+func (h *Hub) handleReleaseTransfer(c *fiber.Ctx) error {
+	action, err := parseTransferAction(c)
+	if err != nil {
+		return err
+	}
+
+	t, err := h.updateTransfer(c.Params("id"), func(t *Transfer) error {
+		if err := checkClaim(t, action); err != nil {
+			return err
+		}
+		t.State, t.ClaimedBy, t.ClaimToken = TransferOffered, "", ""
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Transfer released successfully", t.public()))
+}
+
+// handleCompleteTransfer records that the claimer took payment. Repeating
+// the call with the same sale ID succeeds so a lost response can be retried.
+func (h *Hub) handleCompleteTransfer(c *fiber.Ctx) error {
+	action, err := parseTransferAction(c)
+	if err != nil {
+		return err
+	}
+	if action.SaleID == "" {
+		return apperr.BadRequest("sale_id is required")
+	}
+
+	t, err := h.updateTransfer(c.Params("id"), func(t *Transfer) error {
+		if t.State == TransferCompleted && t.ClaimedBy == action.TerminalID && t.SaleID == action.SaleID {
+			return nil
+		}
+		if err := checkClaim(t, action); err != nil {
+			return err
+		}
+		t.State, t.SaleID, t.ClaimToken = TransferCompleted, action.SaleID, ""
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Transfer completed successfully", t.public()))
+}
+
+// handleCancelTransfer withdraws an unclaimed offer (?terminal_id must be the source)
+func (h *Hub) handleCancelTransfer(c *fiber.Ctx) error {
+	terminal := c.Query("terminal_id")
+
+	t, err := h.updateTransfer(c.Params("id"), func(t *Transfer) error {
+		if t.SourceTerminal != terminal {
+			return apperr.Conflict("Only the source terminal can cancel a transfer")
+		}
+		if t.State != TransferOffered {
+			return apperr.Conflict(fmt.Sprintf("Transfer is %s", t.State))
+		}
+		t.State = TransferCancelled
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Transfer cancelled successfully", t.public()))
+}
+
+// parseTransferAction reads and validates a claim/release/complete body
+func parseTransferAction(c *fiber.Ctx) (*TransferAction, error) {
+	var action TransferAction
+	if err := json.Unmarshal(c.Body(), &action); err != nil || action.TerminalID == "" {
+		return nil, apperr.BadRequest("terminal_id is required")
+	}
+	return &action, nil
+}
+
+// checkClaim verifies the caller holds the claim on t
+func checkClaim(t *Transfer, action *TransferAction) error {
+	if t.State != TransferClaimed {
+		return apperr.Conflict(fmt.Sprintf("Transfer is %s", t.State))
+	}
+	if t.ClaimedBy != action.TerminalID || t.ClaimToken != action.ClaimToken {
+		return apperr.Conflict("Transfer is claimed by another terminal")
+	}
+	return nil
+}
+
+// updateTransfer applies fn to a stored transfer under the transfer lock
+func (h *Hub) updateTransfer(id string, fn func(*Transfer) error) (*Transfer, error) {
+	h.transferMu.Lock()
+	defer h.transferMu.Unlock()
+
+	t, err := h.getTransfer(id)
+	if errors.Is(err, errTransferNotFound) {
+		return nil, apperr.NotFound("Transfer not found")
+	}
+	if err != nil {
+		return nil, apperr.Database(err)
+	}
+
+	if err := fn(t); err != nil {
+		return nil, err
+	}
+
+	t.UpdatedAt = time.Now().Format(time.RFC3339)
+	if err := h.putTransfer(t); err != nil {
+		return nil, apperr.Database(err)
+	}
+	return t, nil
+}
+
+// getTransfer loads a transfer from the database
+func (h *Hub) getTransfer(id string) (*Transfer, error) {
+	value, err := h.db.GetSetting(transferKeyPrefix + id)
+	if errors.Is(err, database.ErrSettingNotFound) {
+		return nil, errTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var t Transfer
+	if err := json.Unmarshal([]byte(value), &t); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer: %w", err)
+	}
+	return &t, nil
+}
+
+// putTransfer stores a transfer in the database
+func (h *Hub) putTransfer(t *Transfer) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transfer: %w", err)
+	}
+	return h.db.SetSetting(transferKeyPrefix+t.ID, string(data))
+}
+
+// newClaimToken returns a random claim token
+func newClaimToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate claim token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
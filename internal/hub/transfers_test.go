@@ -0,0 +1,83 @@
+package hub
+
+import (
+	"net/http"
+	"testing"
+)
+
+const offerBody = `{"id":"H1.cart-1","source_terminal":"H1","lines":[{"sku":"MILK","quantity":2,"price":990}]}`
+
+func TestTransfer_ClaimIsExclusive(t *testing.T) {
+	app, cleanup := setupTestHub(t, &Config{})
+	defer cleanup()
+
+	resp, apiResp := doRequest(t, app, "POST", "/hub/transfers", offerBody)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Offer: expected 200, got %d", resp.StatusCode)
+	}
+	offer := apiResp.Result.(map[string]interface{})
+	if offer["total"] != float64(1980) || offer["state"] != TransferOffered {
+		t.Errorf("Unexpected offer: %v", offer)
+	}
+
+	resp, apiResp = doRequest(t, app, "POST", "/hub/transfers/H1.cart-1/claim", `{"terminal_id":"TILL1"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Claim: expected 200, got %d", resp.StatusCode)
+	}
+	token, _ := apiResp.Result.(map[string]interface{})["claim_token"].(string)
+	if token == "" {
+		t.Fatal("Expected a claim token")
+	}
+
+	// A second till can't take it, and the source can no longer cancel it
+	if resp, _ := doRequest(t, app, "POST", "/hub/transfers/H1.cart-1/claim", `{"terminal_id":"TILL2"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for second claim, got %d", resp.StatusCode)
+	}
+	if resp, _ := doRequest(t, app, "DELETE", "/hub/transfers/H1.cart-1?terminal_id=H1", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling a claimed transfer, got %d", resp.StatusCode)
+	}
+
+	// Retrying the claim returns the same token
+	_, apiResp = doRequest(t, app, "POST", "/hub/transfers/H1.cart-1/claim", `{"terminal_id":"TILL1"}`)
+	if apiResp.Result.(map[string]interface{})["claim_token"] != token {
+		t.Error("Expected repeated claim to return the same token")
+	}
+
+	// Completion needs the token and is idempotent for the same sale
+	if resp, _ := doRequest(t, app, "POST", "/hub/transfers/H1.cart-1/complete", `{"terminal_id":"TILL1","claim_token":"wrong","sale_id":"s1"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 with a wrong token, got %d", resp.StatusCode)
+	}
+	complete := `{"terminal_id":"TILL1","claim_token":"` + token + `","sale_id":"s1"}`
+	for i := 0; i < 2; i++ {
+		if resp, _ := doRequest(t, app, "POST", "/hub/transfers/H1.cart-1/complete", complete); resp.StatusCode != http.StatusOK {
+			t.Errorf("Complete attempt %d: expected 200, got %d", i+1, resp.StatusCode)
+		}
+	}
+	if resp, _ := doRequest(t, app, "POST", "/hub/transfers/H1.cart-1/complete", `{"terminal_id":"TILL1","claim_token":"`+token+`","sale_id":"s2"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 completing with another sale, got %d", resp.StatusCode)
+	}
+}
+
+func TestTransfer_ReleaseAndTarget(t *testing.T) {
+	app, cleanup := setupTestHub(t, &Config{})
+	defer cleanup()
+
+	doRequest(t, app, "POST", "/hub/transfers", `{"id":"H1.cart-2","source_terminal":"H1","target_terminal":"TILL1","lines":[{"sku":"A","quantity":1,"price":100}]}`)
+
+	if resp, _ := doRequest(t, app, "POST", "/hub/transfers/H1.cart-2/claim", `{"terminal_id":"TILL2"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for a transfer addressed elsewhere, got %d", resp.StatusCode)
+	}
+
+	_, apiResp := doRequest(t, app, "POST", "/hub/transfers/H1.cart-2/claim", `{"terminal_id":"TILL1"}`)
+	token := apiResp.Result.(map[string]interface{})["claim_token"].(string)
+
+	resp, _ := doRequest(t, app, "POST", "/hub/transfers/H1.cart-2/release", `{"terminal_id":"TILL1","claim_token":"`+token+`"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Release: expected 200, got %d", resp.StatusCode)
+	}
+
+	// Released transfers are open again and can be cancelled by the source
+	if resp, _ := doRequest(t, app, "DELETE", "/hub/transfers/H1.cart-2?terminal_id=H1", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected cancel to succeed, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,104 @@
+package i18n
+
+// spanish translates API messages into Spanish
+var spanish = map[string]string{
+	// Generic responses and errors
+	"Success":                                  "Operación correcta",
+	"Resource created successfully":            "Recurso creado correctamente",
+	"Resource updated successfully":            "Recurso actualizado correctamente",
+	"Resource deleted successfully":            "Recurso eliminado correctamente",
+	"Invalid request parameters":               "Parámetros de solicitud no válidos",
+	"Request validation failed":                "La validación de la solicitud falló",
+	"Invalid request body":                     "Cuerpo de solicitud no válido",
+	"Request body could not be read":           "No se pudo leer el cuerpo de la solicitud",
+	"Unauthorized":                             "No autorizado",
+	"Forbidden":                                "Prohibido",
+	"Resource not found":                       "Recurso no encontrado",
+	"Resource was modified by another request": "El recurso fue modificado por otra solicitud",
+	"Internal server error":                    "Error interno del servidor",
+	"Database error":                           "Error de base de datos",
+	"Service unavailable":                      "Servicio no disponible",
+	"Service offline for more than 24 hours":   "Servicio sin conexión durante más de 24 horas",
+	"Service is alive":                         "El servicio está activo",
+	"Service is ready":                         "El servicio está listo",
+	"Status retrieved successfully":            "Estado obtenido correctamente",
+	"Sync completed successfully":              "Sincronización completada correctamente",
+
+	// Sign-in
+	"Signed in":                               "Sesión iniciada",
+	"Unknown user or wrong PIN":               "Usuario desconocido o PIN incorrecto",
+	"Invalid or expired token":                "Token no válido o caducado",
+	"Authorization must be a bearer token":    "La autorización debe ser un token bearer",
+	"Admin password required":                 "Se requiere la contraseña de administrador",
+	"Too many failed authentication attempts": "Demasiados intentos de autenticación fallidos",
+	"This endpoint requires a frontend token": "Este recurso requiere un token del frontend",
+	"Frontend token issued":                   "Token del frontend emitido",
+
+	// Carts and checkout
+	"Cart not found":                                       "Carrito no encontrado",
+	"Invalid cart ID":                                      "ID de carrito no válido",
+	"Invalid checkout request":                             "Solicitud de cobro no válida",
+	"Cart lines added successfully":                        "Líneas añadidas al carrito",
+	"Cart totals retrieved successfully":                   "Totales del carrito obtenidos",
+	"Cart checked out successfully":                        "Cobro del carrito completado",
+	"Cart discarded successfully":                          "Carrito descartado",
+	"Cart parked successfully":                             "Carrito aparcado",
+	"Cart offered for transfer":                            "Carrito ofrecido para transferencia",
+	"Parked cart not found":                                "Carrito aparcado no encontrado",
+	"Parked cart retrieved successfully":                   "Carrito aparcado recuperado",
+	"Parked carts retrieved successfully":                  "Carritos aparcados obtenidos",
+	"Parked cart removed successfully":                     "Carrito aparcado eliminado",
+	"Draft saved successfully":                             "Borrador guardado",
+	"Draft retrieved successfully":                         "Borrador recuperado",
+	"Draft discarded successfully":                         "Borrador descartado",
+	"No draft saved for this terminal":                     "No hay ningún borrador guardado para esta caja",
+	"Suggestions retrieved successfully":                   "Sugerencias obtenidas",
+	"Attendant called":                                     "Se ha llamado al asistente",
+	"Intervention raised successfully":                     "Se ha solicitado la intervención",
+	"Intervention resolved successfully":                   "Intervención resuelta",
+	"Intervention not found":                               "Intervención no encontrada",
+	"An attendant declined this cart; please ask for help": "Un asistente rechazó este carrito; pida ayuda",
+
+	// Products and prices
+	"Price retrieved successfully":               "Precio obtenido correctamente",
+	"Product not found":                          "Producto no encontrado",
+	"Product retrieved successfully":             "Producto obtenido correctamente",
+	"Product created successfully":               "Producto creado correctamente",
+	"Product stored successfully":                "Producto guardado correctamente",
+	"Product already inactive":                   "El producto ya está inactivo",
+	"A product with this barcode already exists": "Ya existe un producto con este código de barras",
+
+	// Customers and loyalty
+	"Customer not found":                     "Cliente no encontrado",
+	"Customer retrieved successfully":        "Cliente obtenido correctamente",
+	"Loyalty balance retrieved successfully": "Saldo de puntos obtenido",
+	"Loyalty points recorded successfully":   "Puntos registrados correctamente",
+	"Not enough loyalty points":              "No hay puntos suficientes",
+
+	// Sales, voids and refunds
+	"Sale stored successfully":                    "Venta guardada correctamente",
+	"Sale not found":                              "Venta no encontrada",
+	"Receipt page retrieved successfully":         "Página del ticket obtenida",
+	"Transaction not found":                       "Transacción no encontrada",
+	"Transaction retrieved successfully":          "Transacción obtenida correctamente",
+	"Transaction ID already in use":               "El ID de transacción ya está en uso",
+	"Transaction voided successfully":             "Transacción anulada correctamente",
+	"Transaction already voided":                  "La transacción ya está anulada",
+	"Transaction refunded successfully":           "Transacción devuelta correctamente",
+	"Transaction is already fully refunded":       "La transacción ya está devuelta por completo",
+	"Transaction is completed; refund it instead": "La transacción está completada; haga una devolución",
+	"A refund cannot be refunded":                 "Una devolución no se puede devolver",
+
+	// Transfers between tills
+	"Transfer not found":                        "Transferencia no encontrada",
+	"Transfer accepted successfully":            "Transferencia aceptada",
+	"Transfer already accepted":                 "La transferencia ya fue aceptada",
+	"Transfer is addressed to another terminal": "La transferencia está dirigida a otra caja",
+	"Transfer is claimed by another terminal":   "Otra caja ya tomó la transferencia",
+	"No store hub configured for transfers":     "No hay un hub de tienda configurado para transferencias",
+
+	// Day close
+	"Day closed successfully":     "Día cerrado correctamente",
+	"Day already closed":          "El día ya está cerrado",
+	"Closing checklist evaluated": "Lista de cierre evaluada",
+}
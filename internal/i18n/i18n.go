@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// API messages are written in English in the code. The catalogs map them,
+// by their English text, to the other languages; a message without a
+// translation is sent in English. Cashiers read these messages in the
+// frontend, so the catalogs cover what a till shows first: checkout, carts,
+// products, sign-in and the generic errors.
+
+// Languages, as the primary subtag of a language tag
+const (
+	English = "en"
+	Spanish = "es"
+	Russian = "ru"
+
+	Default = English
+)
+
+// catalogs holds the translations of each language but English
+var catalogs = map[string]map[string]string{
+	Spanish: spanish,
+	Russian: russian,
+}
+
+// Supported reports whether lang is a language messages are available in
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == English
+}
+
+// Translate returns message in lang, or message itself when lang is English
+// or has no translation for it
+func Translate(lang, message string) string {
+	if translated, ok := catalogs[lang][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Negotiate picks the language of a response: the supported language the
+// Accept-Language header ranks highest, else fallback, else Default
+func Negotiate(acceptLanguage, fallback string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(primary) {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			choices = append(choices, choice{primary, q})
+		}
+	}
+	if len(choices) > 0 {
+		// Stable: equal weights keep the client's order
+		sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+		return choices[0].lang
+	}
+
+	if Supported(fallback) {
+		return fallback
+	}
+	return Default
+}
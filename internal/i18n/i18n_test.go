@@ -0,0 +1,51 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		header, fallback, want string
+	}{
+		{"", "", English},
+		{"", Russian, Russian},
+		{"es-ES,es;q=0.9,en;q=0.8", "", Spanish},
+		{"en-US,ru;q=0.9", Spanish, English},
+		{"de-DE,ru;q=0.5", "", Russian},
+		{"en;q=0.2, RU", "", Russian},
+		{"de, fr", Spanish, Spanish},
+		{"es;q=0", "", English},
+		{"*", "", English},
+		{"", "de", English},
+	} {
+		if got := Negotiate(tc.header, tc.fallback); got != tc.want {
+			t.Errorf("Negotiate(%q, %q) = %s, want %s", tc.header, tc.fallback, got, tc.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate(Spanish, "Product not found"); got != "Producto no encontrado" {
+		t.Errorf("Unexpected Spanish: %s", got)
+	}
+	if got := Translate(Russian, "Product not found"); got != "Товар не найден" {
+		t.Errorf("Unexpected Russian: %s", got)
+	}
+	for _, lang := range []string{English, "de", Spanish} {
+		if got := Translate(lang, "Printer \"p1\" is not configured"); got != "Printer \"p1\" is not configured" {
+			t.Errorf("%s: untranslated message changed to %s", lang, got)
+		}
+	}
+}
+
+func TestCatalogsCoverTheSameMessages(t *testing.T) {
+	for message := range spanish {
+		if _, ok := russian[message]; !ok {
+			t.Errorf("Missing Russian for %q", message)
+		}
+	}
+	for message := range russian {
+		if _, ok := spanish[message]; !ok {
+			t.Errorf("Missing Spanish for %q", message)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package i18n
+
+// russian translates API messages into Russian
+var russian = map[string]string{
+	// Generic responses and errors
+	"Success":                                  "Успешно",
+	"Resource created successfully":            "Запись создана",
+	"Resource updated successfully":            "Запись обновлена",
+	"Resource deleted successfully":            "Запись удалена",
+	"Invalid request parameters":               "Неверные параметры запроса",
+	"Request validation failed":                "Запрос не прошёл проверку",
+	"Invalid request body":                     "Неверное тело запроса",
+	"Request body could not be read":           "Не удалось прочитать тело запроса",
+	"Unauthorized":                             "Требуется авторизация",
+	"Forbidden":                                "Доступ запрещён",
+	"Resource not found":                       "Запись не найдена",
+	"Resource was modified by another request": "Запись изменена другим запросом",
+	"Internal server error":                    "Внутренняя ошибка сервера",
+	"Database error":                           "Ошибка базы данных",
+	"Service unavailable":                      "Сервис недоступен",
+	"Service offline for more than 24 hours":   "Сервис не на связи более 24 часов",
+	"Service is alive":                         "Сервис работает",
+	"Service is ready":                         "Сервис готов",
+	"Status retrieved successfully":            "Статус получен",
+	"Sync completed successfully":              "Синхронизация завершена",
+
+	// Sign-in
+	"Signed in":                               "Вход выполнен",
+	"Unknown user or wrong PIN":               "Неизвестный пользователь или неверный PIN",
+	"Invalid or expired token":                "Токен недействителен или истёк",
+	"Authorization must be a bearer token":    "Авторизация должна быть bearer-токеном",
+	"Admin password required":                 "Требуется пароль администратора",
+	"Too many failed authentication attempts": "Слишком много неудачных попыток входа",
+	"This endpoint requires a frontend token": "Этот метод требует токен фронтенда",
+	"Frontend token issued":                   "Токен фронтенда выдан",
+
+	// Carts and checkout
+	"Cart not found":                                       "Корзина не найдена",
+	"Invalid cart ID":                                      "Неверный ID корзины",
+	"Invalid checkout request":                             "Неверный запрос оплаты",
+	"Cart lines added successfully":                        "Позиции добавлены в корзину",
+	"Cart totals retrieved successfully":                   "Итоги корзины получены",
+	"Cart checked out successfully":                        "Корзина оплачена",
+	"Cart discarded successfully":                          "Корзина удалена",
+	"Cart parked successfully":                             "Корзина отложена",
+	"Cart offered for transfer":                            "Корзина предложена к передаче",
+	"Parked cart not found":                                "Отложенная корзина не найдена",
+	"Parked cart retrieved successfully":                   "Отложенная корзина получена",
+	"Parked carts retrieved successfully":                  "Отложенные корзины получены",
+	"Parked cart removed successfully":                     "Отложенная корзина удалена",
+	"Draft saved successfully":                             "Черновик сохранён",
+	"Draft retrieved successfully":                         "Черновик получен",
+	"Draft discarded successfully":                         "Черновик удалён",
+	"No draft saved for this terminal":                     "Для этой кассы нет сохранённого черновика",
+	"Suggestions retrieved successfully":                   "Рекомендации получены",
+	"Attendant called":                                     "Консультант вызван",
+	"Intervention raised successfully":                     "Запрос помощи отправлен",
+	"Intervention resolved successfully":                   "Запрос помощи обработан",
+	"Intervention not found":                               "Запрос помощи не найден",
+	"An attendant declined this cart; please ask for help": "Консультант отклонил эту корзину; обратитесь за помощью",
+
+	// Products and prices
+	"Price retrieved successfully":               "Цена получена",
+	"Product not found":                          "Товар не найден",
+	"Product retrieved successfully":             "Товар получен",
+	"Product created successfully":               "Товар создан",
+	"Product stored successfully":                "Товар сохранён",
+	"Product already inactive":                   "Товар уже неактивен",
+	"A product with this barcode already exists": "Товар с таким штрихкодом уже существует",
+
+	// Customers and loyalty
+	"Customer not found":                     "Покупатель не найден",
+	"Customer retrieved successfully":        "Покупатель получен",
+	"Loyalty balance retrieved successfully": "Баланс баллов получен",
+	"Loyalty points recorded successfully":   "Баллы начислены",
+	"Not enough loyalty points":              "Недостаточно баллов",
+
+	// Sales, voids and refunds
+	"Sale stored successfully":                    "Продажа сохранена",
+	"Sale not found":                              "Продажа не найдена",
+	"Receipt page retrieved successfully":         "Страница чека получена",
+	"Transaction not found":                       "Транзакция не найдена",
+	"Transaction retrieved successfully":          "Транзакция получена",
+	"Transaction ID already in use":               "ID транзакции уже используется",
+	"Transaction voided successfully":             "Транзакция аннулирована",
+	"Transaction already voided":                  "Транзакция уже аннулирована",
+	"Transaction refunded successfully":           "Возврат выполнен",
+	"Transaction is already fully refunded":       "По транзакции уже выполнен полный возврат",
+	"Transaction is completed; refund it instead": "Транзакция завершена; оформите возврат",
+	"A refund cannot be refunded":                 "Возврат нельзя вернуть",
+
+	// Transfers between tills
+	"Transfer not found":                        "Передача не найдена",
+	"Transfer accepted successfully":            "Передача принята",
+	"Transfer already accepted":                 "Передача уже принята",
+	"Transfer is addressed to another terminal": "Передача адресована другой кассе",
+	"Transfer is claimed by another terminal":   "Передачу уже забрала другая касса",
+	"No store hub configured for transfers":     "Для передач не настроен хаб магазина",
+
+	// Day close
+	"Day closed successfully":     "День закрыт",
+	"Day already closed":          "День уже закрыт",
+	"Closing checklist evaluated": "Чек-лист закрытия проверен",
+}
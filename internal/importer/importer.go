@@ -0,0 +1,399 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// What an import loads
+const (
+	EntityProducts  = "products"
+	EntityCustomers = "customers"
+)
+
+// File formats an import accepts
+const (
+	FormatCSV  = "csv"  // Header row naming the columns, then one record per row
+	FormatJSON = "json" // An array of objects keyed by column
+)
+
+// batchSize is how many valid rows are stored per transaction
+const batchSize = 200
+
+// MaxRowErrors bounds the row errors kept in a report; the count of failed
+// rows is always exact
+const MaxRowErrors = 100
+
+// columns lists the columns each entity accepts, required ones first
+var columns = map[string]struct{ required, optional []string }{
+	EntityProducts:  {required: []string{"barcode", "name", "price"}, optional: []string{"id", "sku", "tax_rate", "active"}},
+	EntityCustomers: {required: []string{"name"}, optional: []string{"id", "phone", "email", "loyalty_card"}},
+}
+
+// RowError reports why one row was not imported. Messages never echo the
+// row's values, so reports can be kept with the job bookkeeping.
+type RowError struct {
+	Row     int    `json:"row"` // 1-based data row; a CSV header is not counted
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of an import
+type Report struct {
+	Entity          string     `json:"entity"`
+	Format          string     `json:"format"`
+	Rows            int        `json:"rows"`
+	Imported        int        `json:"imported"`
+	Failed          int        `json:"failed"`
+	Errors          []RowError `json:"errors"`
+	ErrorsTruncated bool       `json:"errors_truncated,omitempty"`
+}
+
+// fail records a rejected row
+func (r *Report) fail(row int, field, message string) {
+	r.Failed++
+	if len(r.Errors) < MaxRowErrors {
+		r.Errors = append(r.Errors, RowError{Row: row, Field: field, Message: message})
+	} else {
+		r.ErrorsTruncated = true
+	}
+}
+
+// Progress receives an import's progress (satisfied by *jobs.Progress)
+type Progress interface {
+	Update(percent int, message string)
+}
+
+// Importer loads products and customers from uploaded files. Rows are
+// validated one by one: a bad row is reported and skipped, and the valid
+// rows are stored in batches through the same repository methods as local
+// edits, so they reach the sync outbox.
+type Importer struct {
+	db *database.DB
+}
+
+// New creates an importer writing to db
+func New(db *database.DB) *Importer {
+	return &Importer{db: db}
+}
+
+// ValidEntity reports whether entity can be imported
+func ValidEntity(entity string) bool {
+	_, ok := columns[entity]
+	return ok
+}
+
+// Run imports data of the given entity and format. File-level problems
+// (unreadable file, unknown or missing columns) fail the whole import;
+// row problems are reported in the returned report.
+func (im *Importer) Run(ctx context.Context, p Progress, entity, format string, data []byte) (*Report, error) {
+	if !ValidEntity(entity) {
+		return nil, fmt.Errorf("cannot import %q", entity)
+	}
+
+	records, err := Parse(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkColumns(entity, records); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Entity: entity, Format: format, Rows: len(records), Errors: []RowError{}}
+	switch entity {
+	case EntityProducts:
+		err = im.importProducts(ctx, p, records, report)
+	case EntityCustomers:
+		err = im.importCustomers(ctx, p, records, report)
+	}
+	return report, err
+}
+
+// Parse decodes a file into records keyed by lower-case column name
+func Parse(format string, data []byte) ([]map[string]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Spreadsheet exports often start with a BOM
+
+	switch format {
+	case FormatCSV:
+		return parseCSV(data)
+	case FormatJSON:
+		return parseJSON(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// parseCSV reads a header row and the records after it
+func parseCSV(data []byte) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1 // Short rows are reported per row, not for the whole file
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var records []map[string]string
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		record := make(map[string]string, len(header))
+		for i, column := range header {
+			if column != "" && i < len(fields) {
+				record[column] = strings.TrimSpace(fields[i])
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseJSON reads an array of objects. Numbers and booleans are kept as
+// their text, so both formats share the row validation.
+func parseJSON(data []byte) ([]map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var objects []map[string]interface{}
+	if err := dec.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("file is not a JSON array of objects: %w", err)
+	}
+
+	records := make([]map[string]string, len(objects))
+	for i, object := range objects {
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			column := strings.ToLower(strings.TrimSpace(key))
+			switch v := value.(type) {
+			case nil:
+				record[column] = ""
+			case string:
+				record[column] = strings.TrimSpace(v)
+			case json.Number:
+				record[column] = v.String()
+			case bool:
+				record[column] = strconv.FormatBool(v)
+			default:
+				return nil, fmt.Errorf("row %d: column %q must be a string, number or boolean", i+1, column)
+			}
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// checkColumns rejects unknown columns, usually a typo that would silently
+// drop a field, and files missing a required column altogether
+func checkColumns(entity string, records []map[string]string) error {
+	spec := columns[entity]
+	known := make(map[string]bool)
+	for _, column := range append(append([]string{}, spec.required...), spec.optional...) {
+		known[column] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, record := range records {
+		for column := range record {
+			if !known[column] {
+				return fmt.Errorf("unknown column %q", column)
+			}
+			seen[column] = true
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	for _, column := range spec.required {
+		if !seen[column] {
+			return fmt.Errorf("missing required column %q", column)
+		}
+	}
+	return nil
+}
+
+// importProducts validates product rows and stores them in batches. A
+// barcode already in the catalog updates that product.
+func (im *Importer) importProducts(ctx context.Context, p Progress, records []map[string]string, report *Report) error {
+	seen := make(map[string]int)
+	var batch []*database.Product
+
+	flush := func(done int) error {
+		if len(batch) > 0 {
+			if err := im.db.ImportProducts(batch); err != nil {
+				return err
+			}
+			report.Imported += len(batch)
+			batch = batch[:0]
+		}
+		reportProgress(p, done, len(records))
+		return ctx.Err()
+	}
+
+	for i, record := range records {
+		row := i + 1
+		product, field, message := im.productRow(record)
+		if message == "" {
+			if first, dup := seen[product.Barcode]; dup {
+				field, message = "barcode", fmt.Sprintf("duplicates row %d", first)
+			}
+		}
+		if message != "" {
+			report.fail(row, field, message)
+			continue
+		}
+		seen[product.Barcode] = row
+
+		batch = append(batch, product)
+		if len(batch) == batchSize {
+			if err := flush(row); err != nil {
+				return err
+			}
+		}
+	}
+	return flush(len(records))
+}
+
+// productRow converts one record, returning the failing field and why
+func (im *Importer) productRow(record map[string]string) (*database.Product, string, string) {
+	product := &database.Product{
+		ID:      record["id"],
+		Barcode: record["barcode"],
+		SKU:     record["sku"],
+		Name:    record["name"],
+		Active:  true,
+	}
+	if product.Barcode == "" {
+		return nil, "barcode", "is required"
+	}
+	if len(product.Barcode) > 64 {
+		return nil, "barcode", "must be at most 64 characters"
+	}
+	if product.Name == "" {
+		return nil, "name", "is required"
+	}
+
+	price, err := strconv.ParseInt(record["price"], 10, 64)
+	if err != nil || price < 0 {
+		return nil, "price", "must be a whole number of minor currency units, zero or more"
+	}
+	product.Price = price
+
+	if v := record["tax_rate"]; v != "" {
+		rate, err := strconv.Atoi(v)
+		if err != nil || rate < 0 || rate > 10000 {
+			return nil, "tax_rate", "must be basis points between 0 and 10000"
+		}
+		product.TaxRate = rate
+	}
+	if v := record["active"]; v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, "active", "must be true or false"
+		}
+		product.Active = active
+	}
+
+	existing, err := im.db.GetProductByBarcode(product.Barcode)
+	switch {
+	case err == nil:
+		if product.ID != "" && product.ID != existing.ID {
+			return nil, "id", "does not match the product with this barcode"
+		}
+		product.ID = existing.ID
+	case errors.Is(err, database.ErrProductNotFound):
+		if product.ID == "" {
+			product.ID = ids.New()
+		}
+	default:
+		return nil, "", "could not be checked against the catalog"
+	}
+	return product, "", ""
+}
+
+// importCustomers validates customer rows and stores them in batches. An
+// ID already on file updates that customer.
+func (im *Importer) importCustomers(ctx context.Context, p Progress, records []map[string]string, report *Report) error {
+	seen := make(map[string]int)
+	var batch []database.Customer
+
+	flush := func(done int) error {
+		if len(batch) > 0 {
+			if err := im.db.ImportCustomers(batch); err != nil {
+				return err
+			}
+			report.Imported += len(batch)
+			batch = batch[:0]
+		}
+		reportProgress(p, done, len(records))
+		return ctx.Err()
+	}
+
+	for i, record := range records {
+		row := i + 1
+		customer := database.Customer{
+			ID:          record["id"],
+			Name:        record["name"],
+			Phone:       record["phone"],
+			Email:       record["email"],
+			LoyaltyCard: record["loyalty_card"],
+		}
+
+		switch {
+		case customer.Name == "":
+			report.fail(row, "name", "is required")
+			continue
+		case customer.Email != "" && !strings.Contains(customer.Email, "@"):
+			report.fail(row, "email", "is not an email address")
+			continue
+		case len(customer.ID) > 64:
+			report.fail(row, "id", "must be at most 64 characters")
+			continue
+		}
+		if customer.ID == "" {
+			customer.ID = ids.New()
+		} else if first, dup := seen[customer.ID]; dup {
+			report.fail(row, "id", fmt.Sprintf("duplicates row %d", first))
+			continue
+		}
+		seen[customer.ID] = row
+
+		batch = append(batch, customer)
+		if len(batch) == batchSize {
+			if err := flush(row); err != nil {
+				return err
+			}
+		}
+	}
+	return flush(len(records))
+}
+
+// reportProgress reports how many rows have been processed
+func reportProgress(p Progress, done, total int) {
+	if p == nil || total == 0 {
+		return
+	}
+	p.Update(done*100/total, fmt.Sprintf("%d of %d rows processed", done, total))
+}
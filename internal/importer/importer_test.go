@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestRun_ProductsCSV(t *testing.T) {
+	db := setupTestDB(t)
+	existing := &database.Product{ID: "P1", Barcode: "111", Name: "Milk", Price: 500, Active: true}
+	if err := db.UpsertProduct(existing, database.ProductSourceSync); err != nil {
+		t.Fatalf("Failed to seed product: %v", err)
+	}
+	before, _ := db.CountPendingOutbox()
+
+	file := "\xef\xbb\xbfBarcode,Name,Price,Tax_Rate,Active\n" +
+		"111,Milk 1L,550,1200,true\n" +
+		"222,Bread,300,,\n" +
+		"333,,100,,\n" +
+		"444,Eggs,cheap,,\n" +
+		"222,Bread again,310,,\n"
+
+	report, err := New(db).Run(context.Background(), nil, EntityProducts, FormatCSV, []byte(file))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Rows != 5 || report.Imported != 2 || report.Failed != 3 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	want := []RowError{
+		{Row: 3, Field: "name", Message: "is required"},
+		{Row: 4, Field: "price"},
+		{Row: 5, Field: "barcode", Message: "duplicates row 2"},
+	}
+	for i, w := range want {
+		got := report.Errors[i]
+		if got.Row != w.Row || got.Field != w.Field || (w.Message != "" && got.Message != w.Message) {
+			t.Errorf("Error %d: got %+v, want %+v", i, got, w)
+		}
+	}
+
+	// The existing barcode kept its ID; both rows reached the outbox
+	product, err := db.GetProductByBarcode("111")
+	if err != nil || product.ID != "P1" || product.Price != 550 || product.TaxRate != 1200 {
+		t.Errorf("Expected P1 updated to 550, got %+v (%v)", product, err)
+	}
+	if _, err := db.GetProductByBarcode("222"); err != nil {
+		t.Errorf("Expected 222 imported: %v", err)
+	}
+	if after, _ := db.CountPendingOutbox(); after != before+2 {
+		t.Errorf("Expected two outbox entries, got %d", after-before)
+	}
+}
+
+func TestRun_CustomersJSON(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.ApplyCustomers([]database.Customer{{ID: "C1", Name: "Dilnoza", LoyaltyPoints: 120}}, nil); err != nil {
+		t.Fatalf("Failed to seed customer: %v", err)
+	}
+
+	file := `[
+		{"id": "C1", "name": "Dilnoza K.", "phone": "+998901234567"},
+		{"name": "Bobur", "email": "bobur@example.com", "loyalty_card": 4001},
+		{"name": "No Mail", "email": "nomail"}
+	]`
+	report, err := New(db).Run(context.Background(), nil, EntityCustomers, FormatJSON, []byte(file))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Imported != 2 || report.Failed != 1 || report.Errors[0].Row != 3 || report.Errors[0].Field != "email" {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	// Imports never touch head office's balance
+	customer, err := db.GetCustomer("C1")
+	if err != nil || customer.Name != "Dilnoza K." || customer.LoyaltyPoints != 120 {
+		t.Errorf("Expected C1 renamed with 120 points, got %+v (%v)", customer, err)
+	}
+	if found, _ := db.FindCustomers("4001"); len(found) != 1 || found[0].Name != "Bobur" {
+		t.Errorf("Expected Bobur imported with card 4001, got %+v", found)
+	}
+}
+
+func TestRun_FileErrors(t *testing.T) {
+	db := setupTestDB(t)
+	im := New(db)
+
+	cases := map[string]struct{ format, file string }{
+		"unknown column": {FormatCSV, "barcode,name,price,colour\n1,A,1,red\n"},
+		"missing column": {FormatCSV, "barcode,name\n1,A\n"},
+		"not an array":   {FormatJSON, `{"barcode": "1"}`},
+		"empty":          {FormatCSV, ""},
+	}
+	for name, tc := range cases {
+		if _, err := im.Run(context.Background(), nil, EntityProducts, tc.format, []byte(tc.file)); err == nil {
+			t.Errorf("%s: expected the import to fail", name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package integrity
+
+import (
+	"log/syslog"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// verifyEmbedded reports that no platform signature exists; ELF binaries
+// rely on the detached signature
+func verifyEmbedded(path string) error {
+	return errNoEmbeddedSignature
+}
+
+// reportFailure writes msg to syslog, where the service manager logs too
+func reportFailure(msg string) {
+	w, err := syslog.New(syslog.LOG_ERR|syslog.LOG_DAEMON, constants.AppName)
+	if err != nil {
+		return
+	}
+	defer w.Close()
+	w.Err(msg)
+}
@@ -0,0 +1,81 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withReleaseKey compiles in a fresh release key for the test and returns
+// its private half
+func withReleaseKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	previous := releasePublicKey
+	releasePublicKey = base64.StdEncoding.EncodeToString(pub)
+	t.Cleanup(func() { releasePublicKey = previous })
+	return priv
+}
+
+func writeBinary(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pos-service")
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	return path
+}
+
+func TestVerify_DetachedSignature(t *testing.T) {
+	priv := withReleaseKey(t)
+	path := writeBinary(t, "release build")
+	if err := os.WriteFile(path+SignatureSuffix, ed25519.Sign(priv, []byte("release build")), 0644); err != nil {
+		t.Fatalf("Failed to write signature: %v", err)
+	}
+
+	result, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Method != MethodDetached {
+		t.Errorf("Expected detached verification, got %s", result.Method)
+	}
+
+	// Patch the binary after signing
+	if err := os.WriteFile(path, []byte("patched build"), 0755); err != nil {
+		t.Fatalf("Failed to modify binary: %v", err)
+	}
+	if _, err := Verify(path); !errors.Is(err, ErrTampered) {
+		t.Errorf("Expected ErrTampered, got %v", err)
+	}
+}
+
+func TestVerify_ReleaseBuildRequiresSignature(t *testing.T) {
+	withReleaseKey(t)
+	path := writeBinary(t, "release build")
+
+	if _, err := Verify(path); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned, got %v", err)
+	}
+}
+
+func TestVerify_DevelopmentBuild(t *testing.T) {
+	previous := releasePublicKey
+	releasePublicKey = ""
+	defer func() { releasePublicKey = previous }()
+
+	result, err := Verify(writeBinary(t, "dev build"))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Method != MethodNone {
+		t.Errorf("Expected no verification for a development build, got %s", result.Method)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build windows
+// +build windows
+
+package integrity
+
+import (
+	"unsafe"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// integrityEventID identifies integrity failures in the Event Log
+const integrityEventID = 100
+
+// verifyEmbedded checks the file's Authenticode signature with
+// WinVerifyTrust. Revocation is not checked, since terminals may start
+// offline.
+func verifyEmbedded(path string) error {
+	path16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	data := &windows.WinTrustData{
+		Size:             uint32(unsafe.Sizeof(windows.WinTrustData{})),
+		UIChoice:         windows.WTD_UI_NONE,
+		RevocationChecks: windows.WTD_REVOKE_NONE,
+		UnionChoice:      windows.WTD_CHOICE_FILE,
+		StateAction:      windows.WTD_STATEACTION_VERIFY,
+		FileOrCatalogOrBlobOrSgnrOrCert: unsafe.Pointer(&windows.WinTrustFileInfo{
+			Size:     uint32(unsafe.Sizeof(windows.WinTrustFileInfo{})),
+			FilePath: path16,
+		}),
+	}
+	verifyErr := windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+	data.StateAction = windows.WTD_STATEACTION_CLOSE
+	windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+
+	if verifyErr == windows.Errno(windows.TRUST_E_NOSIGNATURE) {
+		return errNoEmbeddedSignature
+	}
+	return verifyErr
+}
+
+// reportFailure writes msg to the Event Log under the service's source,
+// registered when the service is installed
+func reportFailure(msg string) {
+	log, err := eventlog.Open(constants.WindowsServiceName)
+	if err != nil {
+		return
+	}
+	defer log.Close()
+	log.Error(integrityEventID, msg)
+}
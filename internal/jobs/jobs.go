@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// RunFunc performs the work of a job. It reports progress through p and
+// returns an optional URL where the result can be fetched.
+type RunFunc func(ctx context.Context, p *Progress) (resultURL string, err error)
+
+// Progress lets a running job report how far it got
+type Progress struct {
+	db    *database.DB
+	jobID string
+}
+
+// Update records progress (clamped to 0-99; 100 is set on success) and a message
+func (p *Progress) Update(percent int, message string) {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 99 {
+		percent = 99
+	}
+	if err := p.db.UpdateJobProgress(p.jobID, percent, message); err != nil {
+		log.Printf("Warning: failed to update job %s progress: %v", p.jobID, err)
+	}
+}
+
+// SetDetails records the job's kind-specific outcome, such as the row
+// errors of an import, for GET /jobs/:id
+func (p *Progress) SetDetails(details interface{}) {
+	if err := p.db.SetJobDetails(p.jobID, details); err != nil {
+		log.Printf("Warning: failed to record job %s details: %v", p.jobID, err)
+	}
+}
+
+// JobID returns the ID of the job being run
+func (p *Progress) JobID() string {
+	return p.jobID
+}
+
+// Manager runs async jobs (exports, imports, re-encryption, bootstrap sync)
+// and persists their state so the UI can follow them via GET /jobs/:id
+type Manager struct {
+	db     *database.DB
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a job manager. Jobs left unfinished by a previous
+// process are marked as failed.
+func NewManager(db *database.DB) (*Manager, error) {
+	if db == nil {
+		return nil, fmt.Errorf("job manager requires a database")
+	}
+
+	if n, err := db.FailInterruptedJobs(); err != nil {
+		return nil, err
+	} else if n > 0 {
+		log.Printf("Marked %d interrupted job(s) as failed", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		db:     db,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Submit queues a job of the given kind and runs it in the background
+func (m *Manager) Submit(kind string, run RunFunc) (*database.Job, error) {
+	if err := m.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("job manager is shut down")
+	}
+
+	id := ids.New()
+	if err := m.db.CreateJob(id, kind); err != nil {
+		return nil, err
+	}
+
+	job, err := m.db.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.wg.Add(1)
+	go m.run(id, run)
+
+	return job, nil
+}
+
+// run executes a job and records its outcome
+func (m *Manager) run(id string, run RunFunc) {
+	defer m.wg.Done()
+
+	if err := m.db.StartJob(id); err != nil {
+		log.Printf("Warning: failed to start job %s: %v", id, err)
+	}
+
+	resultURL, err := m.safeRun(id, run)
+
+	if finishErr := m.db.FinishJob(id, resultURL, err); finishErr != nil {
+		log.Printf("Warning: failed to record job %s outcome: %v", id, finishErr)
+	}
+}
+
+// safeRun calls run, converting a panic into a job failure
+func (m *Manager) safeRun(id string, run RunFunc) (resultURL string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	return run(m.ctx, &Progress{db: m.db, jobID: id})
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (*database.Job, error) {
+	return m.db.GetJob(id)
+}
+
+// Shutdown cancels running jobs and waits for them to return
+func (m *Manager) Shutdown() {
+	m.cancel()
+	m.wg.Wait()
+}
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func setupTestManager(t *testing.T) (*Manager, *database.DB) {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	manager, err := NewManager(db)
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	t.Cleanup(manager.Shutdown)
+
+	return manager, db
+}
+
+// waitForJob polls until the job leaves the queued/running states
+func waitForJob(t *testing.T, m *Manager, id string) *database.Job {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if job.Status == database.JobSucceeded || job.Status == database.JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish in time", id)
+	return nil
+}
+
+func TestSubmit_Success(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	job, err := manager.Submit("export", func(ctx context.Context, p *Progress) (string, error) {
+		p.Update(50, "halfway")
+		return "/exports/" + p.JobID(), nil
+	})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if job.Status != database.JobQueued {
+		t.Errorf("Expected queued status, got %s", job.Status)
+	}
+
+	done := waitForJob(t, manager, job.ID)
+	if done.Status != database.JobSucceeded {
+		t.Errorf("Expected succeeded status, got %s", done.Status)
+	}
+	if done.Progress != 100 {
+		t.Errorf("Expected progress 100, got %d", done.Progress)
+	}
+	if done.ResultURL != "/exports/"+job.ID {
+		t.Errorf("Unexpected result URL: %s", done.ResultURL)
+	}
+}
+
+func TestSubmit_FailureAndPanic(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	failed, _ := manager.Submit("import", func(ctx context.Context, p *Progress) (string, error) {
+		return "", errors.New("bad file")
+	})
+	panicked, _ := manager.Submit("import", func(ctx context.Context, p *Progress) (string, error) {
+		panic("boom")
+	})
+
+	if job := waitForJob(t, manager, failed.ID); job.Status != database.JobFailed || job.Error != "bad file" {
+		t.Errorf("Expected failed job with error, got %+v", job)
+	}
+	if job := waitForJob(t, manager, panicked.ID); job.Status != database.JobFailed {
+		t.Errorf("Expected panicking job to fail, got %+v", job)
+	}
+}
+
+func TestNewManager_FailsInterruptedJobs(t *testing.T) {
+	_, db := setupTestManager(t)
+
+	db.CreateJob("stale", "bootstrap_sync")
+	db.StartJob("stale")
+
+	manager, err := NewManager(db)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	defer manager.Shutdown()
+
+	job, _ := manager.Get("stale")
+	if job.Status != database.JobFailed {
+		t.Errorf("Expected interrupted job to be failed, got %s", job.Status)
+	}
+}
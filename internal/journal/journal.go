@@ -0,0 +1,229 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// The journal is an append-only, fsynced write-ahead log. A record is
+// durable once Append returns; the database is only the materialized view.
+// Each record is one line of JSON with an encrypted body, so a torn write
+// at the tail (power loss mid-append) is detected and discarded on Open.
+
+// Record types
+const (
+	TypeIntent = "intent" // Operation about to be applied
+	TypeAbort  = "abort"  // Operation failed and must not be replayed
+)
+
+// Record is one journal entry
+type Record struct {
+	Seq  int64  `json:"seq"`
+	Type string `json:"type"`
+	Key  string `json:"key"` // Idempotency key of the operation (e.g. sale ID)
+	Body []byte `json:"-"`   // Decrypted payload
+}
+
+// line is the on-disk form of a record
+type line struct {
+	Seq  int64  `json:"seq"`
+	Type string `json:"type"`
+	Key  string `json:"key"`
+	Data string `json:"data,omitempty"` // Encrypted payload
+}
+
+// Journal is an append-only record log
+type Journal struct {
+	mu         sync.Mutex
+	file       *os.File
+	path       string
+	encryption *security.DatabaseEncryption
+	records    []Record
+	nextSeq    int64
+}
+
+// Open opens or creates a journal file, loading its records. A torn or
+// corrupt tail is truncated away.
+func Open(path string, encryption *security.DatabaseEncryption) (*Journal, error) {
+	if path == "" {
+		return nil, fmt.Errorf("journal path is required")
+	}
+	if encryption == nil {
+		return nil, fmt.Errorf("journal requires encryption")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	j := &Journal{file: file, path: path, encryption: encryption, nextSeq: 1}
+	if err := j.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// load reads every valid record and truncates anything after the last one
+func (j *Journal) load() error {
+	data, err := io.ReadAll(j.file)
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var valid int64
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if err != nil {
+			break // EOF or torn final line without newline
+		}
+
+		record, ok := j.decode(raw)
+		if !ok {
+			break
+		}
+
+		j.records = append(j.records, record)
+		j.nextSeq = record.Seq + 1
+		valid += int64(len(raw))
+	}
+
+	if valid < int64(len(data)) {
+		if err := j.file.Truncate(valid); err != nil {
+			return fmt.Errorf("failed to truncate torn journal tail: %w", err)
+		}
+	}
+
+	if _, err := j.file.Seek(valid, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek journal: %w", err)
+	}
+
+	return nil
+}
+
+// decode parses and decrypts one journal line
+func (j *Journal) decode(raw []byte) (Record, bool) {
+	var l line
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return Record{}, false
+	}
+
+	record := Record{Seq: l.Seq, Type: l.Type, Key: l.Key}
+	if l.Data != "" {
+		body, err := j.encryption.Decrypt(l.Data)
+		if err != nil {
+			return Record{}, false
+		}
+		record.Body = body
+	}
+
+	return record, true
+}
+
+// Append durably writes a record and returns it once fsynced
+func (j *Journal) Append(recordType, key string, body []byte) (*Record, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	l := line{Seq: j.nextSeq, Type: recordType, Key: key}
+	if len(body) > 0 {
+		encrypted, err := j.encryption.Encrypt(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt journal record: %w", err)
+		}
+		l.Data = encrypted
+	}
+
+	raw, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+
+	if _, err := j.file.Write(append(raw, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to append journal record: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	record := Record{Seq: l.Seq, Type: recordType, Key: key, Body: body}
+	j.records = append(j.records, record)
+	j.nextSeq++
+
+	return &record, nil
+}
+
+// Records returns a copy of all records in append order
+func (j *Journal) Records() []Record {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records := make([]Record, len(j.records))
+	copy(records, j.records)
+	return records
+}
+
+// Len returns the number of records
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.records)
+}
+
+// Checkpoint discards all records. Call it only once every record is
+// reflected in durable state.
+func (j *Journal) Checkpoint() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	// Sequence numbers keep increasing for the lifetime of this handle
+	j.records = nil
+	return nil
+}
+
+// Rekey discards all records and encrypts later ones with serverKey, so
+// the journal never mixes keys. Like Checkpoint, call it only once every
+// record is reflected in durable state.
+func (j *Journal) Rekey(serverKey []byte) error {
+	if err := j.Checkpoint(); err != nil {
+		return err
+	}
+	return j.encryption.SetKey(serverKey)
+}
+
+// Path returns the journal file path
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
@@ -0,0 +1,132 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func newEncryption(t *testing.T) *security.DatabaseEncryption {
+	key, _ := security.GenerateServerKey()
+	encryption, err := security.NewDatabaseEncryption(key)
+	if err != nil {
+		t.Fatalf("Failed to create encryption: %v", err)
+	}
+	return encryption
+}
+
+func TestJournal_AppendAndReopen(t *testing.T) {
+	encryption := newEncryption(t)
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, err := Open(path, encryption)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	j.Append(TypeIntent, "a", []byte("first"))
+	j.Append(TypeIntent, "b", []byte("second"))
+	j.Close()
+
+	j, err = Open(path, encryption)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer j.Close()
+
+	records := j.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if string(records[1].Body) != "second" || records[1].Seq != 2 {
+		t.Errorf("Unexpected record: %+v", records[1])
+	}
+
+	// Payloads are not stored in plaintext
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "first") {
+		t.Error("Journal payload is stored unencrypted")
+	}
+}
+
+func TestJournal_TruncatesTornTail(t *testing.T) {
+	encryption := newEncryption(t)
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, _ := Open(path, encryption)
+	j.Append(TypeIntent, "a", []byte("complete"))
+	j.Close()
+
+	// Power loss mid-append leaves a partial line
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"seq":2,"type":"intent","key":"b","da`)
+	f.Close()
+
+	j, err := Open(path, encryption)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer j.Close()
+
+	if j.Len() != 1 {
+		t.Fatalf("Expected torn record to be dropped, got %d records", j.Len())
+	}
+
+	// Appends continue cleanly after the truncated tail
+	if _, err := j.Append(TypeIntent, "c", []byte("next")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	j.Close()
+
+	j, _ = Open(path, encryption)
+	if j.Len() != 2 {
+		t.Errorf("Expected 2 records after reopen, got %d", j.Len())
+	}
+	j.Close()
+}
+
+func TestJournal_Checkpoint(t *testing.T) {
+	j, err := Open(filepath.Join(t.TempDir(), "test.journal"), newEncryption(t))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer j.Close()
+
+	j.Append(TypeIntent, "a", []byte("x"))
+	if err := j.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if j.Len() != 0 {
+		t.Errorf("Expected empty journal after checkpoint, got %d", j.Len())
+	}
+}
+
+func TestJournal_Rekey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := Open(path, newEncryption(t))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	j.Append(TypeIntent, "a", []byte("old key"))
+	newKey, _ := security.GenerateServerKey()
+	if err := j.Rekey(newKey); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	j.Append(TypeIntent, "b", []byte("new key"))
+	j.Close()
+
+	reopened, _ := security.NewDatabaseEncryption(newKey)
+	j, err = Open(path, reopened)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer j.Close()
+
+	records := j.Records()
+	if len(records) != 1 || string(records[0].Body) != "new key" {
+		t.Errorf("Expected only the record written after rekey, got %+v", records)
+	}
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+	"log"
+)
+
+// Every local API request gets an ID (X-Request-ID). It travels in the
+// context of the work done for the request, so the HTTP access log, the
+// sale ledger and calls to the store hub log under the same ID and a
+// failed sale can be traced through all of them.
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Printf logs through the standard logger like log.Printf, prefixed with
+// the request ID carried by ctx
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	if id := RequestID(ctx); id != "" {
+		format = "request_id=" + id + " " + format
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Log lines may hold customer data, so the log file is written sealed: each
+// line is encrypted with the config encryption (config key + machine ID)
+// and only decrypts on this terminal, through the "logs decrypt" command.
+
+// encryptedLinePrefix marks a sealed line; lines without it are plaintext
+// written before encryption was enabled
+const encryptedLinePrefix = "enc1:"
+
+// lineAAD binds sealed lines to the log file
+var lineAAD = []byte("pos-service-log-v1")
+
+// maxLineBytes bounds a single log line when decrypting
+const maxLineBytes = 1 << 20
+
+// Cipher seals log lines (security.ConfigEncryption)
+type Cipher interface {
+	EncryptWithAAD(plaintext, aad []byte) (string, error)
+}
+
+// Decipher opens sealed log lines (security.ConfigEncryption)
+type Decipher interface {
+	DecryptWithAAD(ciphertextB64 string, aad []byte) ([]byte, error)
+}
+
+// encryptedWriter seals every write as one log line. zap writes each
+// encoded entry in a single call.
+type encryptedWriter struct {
+	file   *os.File
+	cipher Cipher
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	sealed, err := w.cipher.EncryptWithAAD(bytes.TrimSuffix(p, []byte{'\n'}), lineAAD)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt log line: %w", err)
+	}
+	if _, err := w.file.WriteString(encryptedLinePrefix + sealed + "\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *encryptedWriter) Sync() error {
+	return w.file.Sync()
+}
+
+// Decrypt copies a log file from r to w, opening sealed lines with the
+// first decipher that accepts them (the current config key, then older
+// ones). Plaintext lines are copied as they are. Lines no decipher opens
+// are replaced by a marker and counted.
+func Decrypt(r io.Reader, w io.Writer, deciphers ...Decipher) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+
+	failed := 0
+	for line := 1; scanner.Scan(); line++ {
+		text, ok := openLine(scanner.Text(), deciphers)
+		if !ok {
+			text = fmt.Sprintf("<line %d: cannot be decrypted with this terminal's keys>", line)
+			failed++
+		}
+		if _, err := io.WriteString(w, text+"\n"); err != nil {
+			return failed, fmt.Errorf("failed to write log line: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return failed, fmt.Errorf("failed to read log file: %w", err)
+	}
+	return failed, nil
+}
+
+// openLine returns a log line as plaintext, opening it with the first
+// decipher that accepts it if it is sealed
+func openLine(text string, deciphers []Decipher) (string, bool) {
+	sealed, ok := strings.CutPrefix(text, encryptedLinePrefix)
+	if !ok {
+		return text, true
+	}
+	for _, d := range deciphers {
+		if plaintext, err := d.DecryptWithAAD(sealed, lineAAD); err == nil {
+			return string(plaintext), true
+		}
+	}
+	return "", false
+}
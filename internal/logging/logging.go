@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The service logs through one structured (zap) logger. JSON lines go to
+// the log file, sealed line by line (see encrypt.go), and readable lines
+// to stderr for debug runs. The standard library logger is redirected
+// into the same pipeline, so existing log.Printf calls are captured too.
+
+// New builds the shared logger writing to constants.LogFileName in dir,
+// encrypting each line with cipher. level gates both outputs and can be
+// changed while running.
+func New(dir string, level zap.AtomicLevel, cipher Cipher) (*zap.Logger, error) {
+	file, err := os.OpenFile(filepath.Join(dir, constants.LogFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	core := zapcore.NewTee(
+		zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(&encryptedWriter{file: file, cipher: cipher}), level),
+		zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), zapcore.Lock(os.Stderr), level),
+	)
+
+	return zap.New(core, zap.AddCaller()), nil
+}
+
+// SetLevel changes level to a configured name (debug, info, warn or error)
+func SetLevel(level zap.AtomicLevel, name string) error {
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+	return nil
+}
+
+// RedirectStdLog sends the standard library logger to logger at info level
+// and returns a function restoring it
+func RedirectStdLog(logger *zap.Logger) func() {
+	return zap.RedirectStdLog(logger)
+}
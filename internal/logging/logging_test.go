@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"go.uber.org/zap"
+)
+
+func newTestCipher(t *testing.T, key string) *security.ConfigEncryption {
+	enc, err := security.NewConfigEncryption([]byte(key), "test-machine")
+	if err != nil {
+		t.Fatalf("NewConfigEncryption failed: %v", err)
+	}
+	return enc
+}
+
+func TestNew_EncryptsLogFile(t *testing.T) {
+	dir := t.TempDir()
+	enc := newTestCipher(t, "current-key")
+
+	logger, err := New(dir, zap.NewAtomicLevelAt(zap.InfoLevel), enc)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	logger.Info("Sale completed", zap.String("customer", "Jane Doe"))
+	logger.Debug("Below the level")
+	logger.Sync()
+
+	data, err := os.ReadFile(filepath.Join(dir, constants.LogFileName))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if bytes.Contains(data, []byte("Jane Doe")) {
+		t.Fatalf("Log file holds plaintext: %s", data)
+	}
+
+	var out bytes.Buffer
+	failed, err := Decrypt(bytes.NewReader(data), &out, enc)
+	if err != nil || failed != 0 {
+		t.Fatalf("Decrypt failed: %v (%d failed)", err, failed)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"customer":"Jane Doe"`) {
+		t.Errorf("Unexpected decrypted log: %q", out.String())
+	}
+}
+
+func TestDecrypt_OlderKeysAndPlaintext(t *testing.T) {
+	current := newTestCipher(t, "current-key")
+	previous := newTestCipher(t, "previous-key")
+	foreign := newTestCipher(t, "foreign-key")
+
+	var file bytes.Buffer
+	file.WriteString("{\"msg\":\"written before encryption\"}\n")
+	for _, enc := range []*security.ConfigEncryption{previous, foreign} {
+		sealed, err := enc.EncryptWithAAD([]byte(`{"msg":"sealed"}`), lineAAD)
+		if err != nil {
+			t.Fatalf("EncryptWithAAD failed: %v", err)
+		}
+		file.WriteString(encryptedLinePrefix + sealed + "\n")
+	}
+
+	var out bytes.Buffer
+	failed, err := Decrypt(&file, &out, current, previous)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("Expected the foreign line to fail, got %d failures", failed)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", out.String())
+	}
+	if lines[0] != `{"msg":"written before encryption"}` || lines[1] != `{"msg":"sealed"}` {
+		t.Errorf("Unexpected lines %q", lines[:2])
+	}
+	if !strings.Contains(lines[2], "cannot be decrypted") {
+		t.Errorf("Expected a marker for the foreign line, got %q", lines[2])
+	}
+}
+
+func TestReadTail_FiltersAcrossRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	enc := newTestCipher(t, "current-key")
+	foreign := newTestCipher(t, "foreign-key")
+
+	seal := func(c *security.ConfigEncryption, line string) string {
+		sealed, err := c.EncryptWithAAD([]byte(line), lineAAD)
+		if err != nil {
+			t.Fatalf("EncryptWithAAD failed: %v", err)
+		}
+		return encryptedLinePrefix + sealed + "\n"
+	}
+	write := func(name string, lines ...string) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		// Files last written before a day are skipped for it
+		written := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+		os.Chtimes(path, written, written)
+	}
+	write("service-2026-10-14T23-00-00.000.log",
+		seal(enc, `{"level":"info","ts":"2026-10-14T09:00:00.000+0300","msg":"old"}`),
+		seal(enc, `{"level":"error","ts":"2026-10-15T08:00:00.000+0300","msg":"rotated error"}`),
+	)
+	write(constants.LogFileName,
+		seal(enc, `{"level":"debug","ts":"2026-10-15T09:00:00.000+0300","msg":"debug"}`),
+		seal(foreign, `{"level":"error","ts":"2026-10-15T09:30:00.000+0300","msg":"foreign"}`),
+		"not an entry\n",
+		seal(enc, `{"level":"warn","ts":"2026-10-15T10:00:00.000+0300","msg":"warn"}`),
+		seal(enc, `{"level":"info","ts":"2026-10-16T10:00:00.000+0300","msg":"today"}`),
+	)
+
+	messages := func(q Query) []string {
+		tail, err := ReadTail(dir, q, enc)
+		if err != nil {
+			t.Fatalf("ReadTail failed: %v", err)
+		}
+		if tail.Undecryptable != 1 {
+			t.Errorf("Expected the foreign line to be counted, got %d", tail.Undecryptable)
+		}
+		var msgs []string
+		for _, entry := range tail.Entries {
+			var e struct{ Msg string }
+			json.Unmarshal(entry, &e)
+			msgs = append(msgs, e.Msg)
+		}
+		return msgs
+	}
+
+	// Rotated files come first, then the live file
+	if got := strings.Join(messages(Query{Lines: 3, Level: zap.DebugLevel}), ","); got != "debug,warn,today" {
+		t.Errorf("Unexpected tail: %s", got)
+	}
+	if got := strings.Join(messages(Query{Lines: 10, Level: zap.WarnLevel}), ","); got != "rotated error,warn" {
+		t.Errorf("Unexpected warn tail: %s", got)
+	}
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)
+	if got := strings.Join(messages(Query{Lines: 10, Level: zap.InfoLevel, Date: day}), ","); got != "rotated error,warn" {
+		t.Errorf("Unexpected tail for %s: %s", day.Format(time.DateOnly), got)
+	}
+}
+
+func TestPrintf_PrefixesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+
+	Printf(WithRequestID(context.Background(), "req-1"), "sale %s failed", "S-1")
+	Printf(context.Background(), "no request")
+
+	want := "request_id=req-1 sale S-1 failed\nno request\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+	if RequestID(context.Background()) != "" {
+		t.Errorf("Expected no request ID in a bare context")
+	}
+}
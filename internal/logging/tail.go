@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+	"go.uber.org/zap/zapcore"
+)
+
+// Query selects log entries for ReadTail
+type Query struct {
+	Lines int           // Most recent matching entries to return
+	Level zapcore.Level // Lowest level returned
+	Date  time.Time     // Local day to read; zero reads every day
+}
+
+// Tail is the end of the log returned by ReadTail
+type Tail struct {
+	Entries       []json.RawMessage `json:"entries"`       // JSON entries, oldest first
+	Undecryptable int               `json:"undecryptable"` // Sealed lines no key opened
+}
+
+// ReadTail returns the last q.Lines entries at q.Level or above (and on
+// q.Date if set) from the log file in dir and its rotated copies, opening
+// sealed lines with deciphers as Decrypt does. Lines that are not JSON
+// entries are skipped.
+func ReadTail(dir string, q Query, deciphers ...Decipher) (*Tail, error) {
+	if q.Lines <= 0 {
+		return &Tail{Entries: []json.RawMessage{}}, nil
+	}
+	files, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	day := ""
+	if !q.Date.IsZero() {
+		day = q.Date.Format(time.DateOnly)
+	}
+
+	// Ring of the last q.Lines matches across all files
+	ring := make([]json.RawMessage, 0, q.Lines)
+	next := 0
+	tail := &Tail{}
+	for _, path := range files {
+		// A file last written before the day cannot hold its entries
+		if day != "" {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Format(time.DateOnly) < day {
+				continue
+			}
+		}
+		err := scanFile(path, func(line string) {
+			text, ok := openLine(line, deciphers)
+			if !ok {
+				tail.Undecryptable++
+				return
+			}
+			if !matches(text, q.Level, day) {
+				return
+			}
+			if len(ring) < q.Lines {
+				ring = append(ring, json.RawMessage(text))
+				return
+			}
+			ring[next] = json.RawMessage(text)
+			next = (next + 1) % q.Lines
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tail.Entries = append(ring[next:len(ring):len(ring)], ring[:next]...)
+	return tail, nil
+}
+
+// logFiles lists the log file in dir after its rotated copies (service*.log,
+// oldest name first)
+func logFiles(dir string) ([]string, error) {
+	ext := filepath.Ext(constants.LogFileName)
+	pattern := filepath.Join(dir, strings.TrimSuffix(constants.LogFileName, ext)+"*"+ext)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log files: %w", err)
+	}
+
+	current := filepath.Join(dir, constants.LogFileName)
+	files := make([]string, 0, len(matches))
+	for _, path := range matches {
+		if path != current {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files, nil
+}
+
+// scanFile calls fn with each line of the file at path
+func scanFile(path string, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	return nil
+}
+
+// matches reports whether a plaintext line is a JSON entry at level or
+// above, written on day (2006-01-02, empty for any day). Entry times are
+// ISO 8601 in local time, so the day is their date part.
+func matches(text string, level zapcore.Level, day string) bool {
+	var entry struct {
+		Level string `json:"level"`
+		Time  string `json:"ts"`
+	}
+	if err := json.Unmarshal([]byte(text), &entry); err != nil {
+		return false
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(entry.Level)); err != nil || l < level {
+		return false
+	}
+	return day == "" || strings.HasPrefix(entry.Time, day)
+}
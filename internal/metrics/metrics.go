@@ -0,0 +1,365 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics are exposed in the Prometheus text format (version 0.0.4) so
+// store-fleet monitoring can scrape every terminal. The registry is kept
+// small on purpose: counters and histograms with labels, and gauges read
+// at scrape time.
+
+// ContentType is the Content-Type of the exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency histogram bounds in seconds, tuned for a
+// local API answering in milliseconds
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// collector writes one metric family
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds the metrics of one process
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds a collector; metric names must be unique
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.collectors {
+		if existing.name() == c.name() {
+			panic("metrics: duplicate metric " + c.name())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes every metric, sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler serves the registry to scrapers
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteTo(w)
+	})
+}
+
+// Serve serves the registry at /metrics on addr until ctx is cancelled
+func Serve(ctx context.Context, addr string, r *Registry) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// countingWriter counts bytes for WriteTo
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// --- Counters ---
+
+// CounterVec is a family of counters partitioned by labels
+type CounterVec struct {
+	family
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec registers a counter family
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{family: family{metric: name, help: help, kind: "counter", labels: labels}, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter with the given label values by v (v >= 0)
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Inc increases the counter with the given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	values := make(map[string]float64, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	c.mu.Unlock()
+
+	c.header(w)
+	for _, key := range sortedKeys(values) {
+		c.sample(w, c.metric, key, "", values[key])
+	}
+}
+
+// --- Histograms ---
+
+// HistogramVec is a family of histograms partitioned by labels
+type HistogramVec struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram family with the given upper
+// bounds (DefaultBuckets when nil)
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{
+		family:  family{metric: name, help: help, kind: "histogram", labels: labels},
+		buckets: append([]float64(nil), buckets...),
+		series:  make(map[string]*histogram),
+	}
+	sort.Float64s(h.buckets)
+	r.register(h)
+	return h
+}
+
+// Observe records v in the histogram with the given label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	series := make(map[string]histogram, len(h.series))
+	for k, s := range h.series {
+		series[k] = histogram{counts: append([]uint64(nil), s.counts...), count: s.count, sum: s.sum}
+	}
+	h.mu.Unlock()
+
+	h.header(w)
+	keys := make([]string, 0, len(series))
+	for k := range series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.sample(w, h.metric+"_bucket", key, `le="`+formatFloat(bound)+`"`, float64(cumulative))
+		}
+		h.sample(w, h.metric+"_bucket", key, `le="+Inf"`, float64(s.count))
+		h.sample(w, h.metric+"_sum", key, "", s.sum)
+		h.sample(w, h.metric+"_count", key, "", float64(s.count))
+	}
+}
+
+// --- Gauges and counters read at scrape time ---
+
+// funcMetric is a gauge or counter whose samples are read when scraped,
+// for values another component already keeps
+type funcMetric struct {
+	family
+	read func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge whose value fn returns at scrape time
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{family: family{metric: name, help: help, kind: "gauge"}, read: func() map[string]float64 {
+		return map[string]float64{"": fn()}
+	}})
+}
+
+// NewGaugeVecFunc registers a gauge family with one label; fn returns the
+// value per label value at scrape time. A nil map writes no samples.
+func (r *Registry) NewGaugeVecFunc(name, help, label string, fn func() map[string]float64) {
+	r.register(&funcMetric{family: family{metric: name, help: help, kind: "gauge", labels: []string{label}}, read: labelled(label, fn)})
+}
+
+// NewCounterFunc registers a counter whose total fn returns at scrape
+// time; the total must never decrease
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{family: family{metric: name, help: help, kind: "counter"}, read: func() map[string]float64 {
+		return map[string]float64{"": fn()}
+	}})
+}
+
+// NewCounterVecFunc registers a counter family with one label whose
+// totals fn returns at scrape time; the totals must never decrease
+func (r *Registry) NewCounterVecFunc(name, help, label string, fn func() map[string]float64) {
+	r.register(&funcMetric{family: family{metric: name, help: help, kind: "counter", labels: []string{label}}, read: labelled(label, fn)})
+}
+
+// labelled keys the values fn returns by their label pair
+func labelled(label string, fn func() map[string]float64) func() map[string]float64 {
+	return func() map[string]float64 {
+		values := fn()
+		keyed := make(map[string]float64, len(values))
+		for value, v := range values {
+			keyed[label+`="`+escapeLabel(value)+`"`] = v
+		}
+		return keyed
+	}
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	values := m.read()
+	m.header(w)
+	for _, key := range sortedKeys(values) {
+		m.sample(w, m.metric, key, "", values[key])
+	}
+}
+
+// --- Exposition ---
+
+// family is the name, help and labels shared by every metric kind
+type family struct {
+	metric string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (f *family) name() string { return f.metric }
+
+// key renders label values as the label pairs of a sample
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.metric, len(f.labels), len(values)))
+	}
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = f.labels[i] + `="` + escapeLabel(v) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+// header writes the HELP and TYPE lines
+func (f *family) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metric, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metric, f.kind)
+}
+
+// sample writes one sample line with the label pairs and an extra pair
+func (f *family) sample(w *bufio.Writer, name, labels, extra string, v float64) {
+	switch {
+	case labels != "" && extra != "":
+		labels += "," + extra
+	case extra != "":
+		labels = extra
+	}
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+	} else {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+	}
+}
+
+// escapeLabel escapes a label value
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// formatFloat renders a sample value
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
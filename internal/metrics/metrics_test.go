@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.NewCounterVec("requests_total", "Requests served.", "method")
+	requests.Inc("GET")
+	requests.Add(2, "POST")
+	requests.Add(-1, "GET") // Counters never decrease
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.0625, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(3, "/a")
+
+	reg.NewGaugeFunc("offline_hours", "Hours offline.", func() float64 { return 1.5 })
+	reg.NewGaugeVecFunc("pending", "Pending by class.", "class", func() map[string]float64 {
+		return map[string]float64{`sa"les`: 4}
+	})
+
+	var out bytes.Buffer
+	if _, err := reg.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 1
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 3.5625
+latency_seconds_count{route="/a"} 3
+# HELP offline_hours Hours offline.
+# TYPE offline_hours gauge
+offline_hours 1.5
+# HELP pending Pending by class.
+# TYPE pending gauge
+pending{class="sa\"les"} 4
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET"} 1
+requests_total{method="POST"} 2
+`
+	if out.String() != want {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("x", "X.")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a duplicate metric name to panic")
+		}
+	}()
+	reg.NewGaugeFunc("x", "X.", func() float64 { return 0 })
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewGaugeFunc("up", "Up.", func() float64 { return 1 })
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Header().Get("Content-Type") != ContentType || !strings.Contains(rec.Body.String(), "up 1\n") {
+		t.Errorf("Unexpected response %q: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
@@ -0,0 +1,213 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/professor93/promo-pos/internal/directives"
+)
+
+// Topic layout under the configured prefix:
+//
+//	<prefix>/<store>/<machine>/status      retained "online"/"offline" (last will)
+//	<prefix>/<store>/<machine>/heartbeat   periodic heartbeat
+//	<prefix>/<store>/<machine>/events/<n>  application events
+//	<prefix>/<store>/<machine>/directives  directives from head office (subscribed)
+//	<prefix>/<store>/<machine>/results     directive results
+const (
+	DefaultTopicPrefix       = "pos"
+	DefaultHeartbeatInterval = 60 * time.Second
+
+	// qos is used for every publish and subscription (at-least-once);
+	// directive handlers must therefore be idempotent
+	qos = 1
+
+	connectTimeout = 10 * time.Second
+)
+
+// Config holds MQTT bridge configuration
+type Config struct {
+	BrokerURL         string // e.g. tls://broker.example.com:8883
+	Username          string
+	Password          string
+	TopicPrefix       string
+	StoreID           string
+	MachineID         string
+	HeartbeatInterval time.Duration
+	TLSConfig         *tls.Config
+
+	// Processor executes received directives (shared with the HTTP channel)
+	Processor *directives.Processor
+	// Heartbeat returns the heartbeat payload; nil sends a bare timestamp
+	Heartbeat func() interface{}
+}
+
+// Bridge publishes heartbeats and events to an MQTT broker and executes
+// directives received from it
+type Bridge struct {
+	client    paho.Client
+	processor *directives.Processor
+	heartbeat func() interface{}
+	interval  time.Duration
+	base      string
+}
+
+// New creates an MQTT bridge (call Start to connect)
+func New(cfg *Config) (*Bridge, error) {
+	if cfg.BrokerURL == "" {
+		return nil, fmt.Errorf("mqtt broker URL is required")
+	}
+	if cfg.Processor == nil {
+		return nil, fmt.Errorf("mqtt bridge requires a directive processor")
+	}
+	if cfg.MachineID == "" {
+		return nil, fmt.Errorf("mqtt bridge requires a machine ID")
+	}
+
+	prefix := strings.Trim(cfg.TopicPrefix, "/")
+	if prefix == "" {
+		prefix = DefaultTopicPrefix
+	}
+
+	interval := cfg.HeartbeatInterval
+	if interval == 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	b := &Bridge{
+		processor: cfg.Processor,
+		heartbeat: cfg.Heartbeat,
+		interval:  interval,
+		base:      topicBase(prefix, cfg.StoreID, cfg.MachineID),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID("pos-"+cfg.MachineID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetCleanSession(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(b.topic("status"), "offline", qos, true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("Warning: MQTT connection lost: %v", err)
+		})
+	if cfg.TLSConfig != nil {
+		opts.SetTLSConfig(cfg.TLSConfig)
+	}
+
+	b.client = paho.NewClient(opts)
+	return b, nil
+}
+
+// Start connects to the broker and publishes heartbeats until ctx is cancelled.
+// The client keeps retrying in the background if the broker is unreachable.
+func (b *Bridge) Start(ctx context.Context) error {
+	token := b.client.Connect()
+	if token.WaitTimeout(connectTimeout) && token.Error() != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", token.Error())
+	}
+
+	go b.runHeartbeat(ctx)
+	return nil
+}
+
+// Close publishes the offline status and disconnects
+func (b *Bridge) Close() {
+	if b.client.IsConnected() {
+		b.client.Publish(b.topic("status"), qos, true, "offline").WaitTimeout(time.Second)
+	}
+	b.client.Disconnect(250)
+}
+
+// PublishEvent publishes an application event
+func (b *Bridge) PublishEvent(name string, payload interface{}) error {
+	return b.publishJSON(b.topic("events/"+name), payload)
+}
+
+// onConnect (re)subscribes to directives and announces the terminal online
+func (b *Bridge) onConnect(client paho.Client) {
+	client.Subscribe(b.topic("directives"), qos, func(_ paho.Client, msg paho.Message) {
+		result := b.handleDirective(msg.Payload())
+		if err := b.publishJSON(b.topic("results"), result); err != nil {
+			log.Printf("Warning: failed to publish directive result: %v", err)
+		}
+	})
+	client.Publish(b.topic("status"), qos, true, "online")
+	log.Println("MQTT bridge connected")
+}
+
+// handleDirective decodes and executes one directive message
+func (b *Bridge) handleDirective(payload []byte) *directives.Result {
+	var d directives.Directive
+	if err := json.Unmarshal(payload, &d); err != nil {
+		return &directives.Result{
+			Error:       fmt.Sprintf("invalid directive: %v", err),
+			ProcessedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	return b.processor.Process(context.Background(), &d)
+}
+
+// runHeartbeat publishes heartbeats until ctx is cancelled
+func (b *Bridge) runHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !b.client.IsConnectionOpen() {
+				continue
+			}
+			if err := b.publishJSON(b.topic("heartbeat"), b.heartbeatPayload()); err != nil {
+				log.Printf("Warning: MQTT heartbeat failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// heartbeatPayload builds the heartbeat message
+func (b *Bridge) heartbeatPayload() interface{} {
+	if b.heartbeat != nil {
+		return b.heartbeat()
+	}
+	return map[string]string{"timestamp": time.Now().UTC().Format(time.RFC3339)}
+}
+
+// publishJSON publishes a JSON payload without waiting for delivery
+// (the client queues messages while reconnecting)
+func (b *Bridge) publishJSON(topic string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MQTT payload: %w", err)
+	}
+
+	b.client.Publish(topic, qos, false, data)
+	return nil
+}
+
+// topic returns a topic under this terminal's base
+func (b *Bridge) topic(suffix string) string {
+	return b.base + "/" + suffix
+}
+
+// topicBase builds the per-terminal topic base, stripping MQTT wildcards
+func topicBase(prefix, storeID, machineID string) string {
+	clean := strings.NewReplacer("/", "_", "+", "_", "#", "_")
+	if storeID == "" {
+		storeID = "unassigned"
+	}
+	return prefix + "/" + clean.Replace(storeID) + "/" + clean.Replace(machineID)
+}
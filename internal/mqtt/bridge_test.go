@@ -0,0 +1,55 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/directives"
+)
+
+func TestNew_Validation(t *testing.T) {
+	processor := directives.NewProcessor()
+
+	if _, err := New(&Config{Processor: processor, MachineID: "m1"}); err == nil {
+		t.Error("Expected error without broker URL")
+	}
+	if _, err := New(&Config{BrokerURL: "tcp://localhost:1883", MachineID: "m1"}); err == nil {
+		t.Error("Expected error without processor")
+	}
+}
+
+func TestTopics(t *testing.T) {
+	b, err := New(&Config{
+		BrokerURL:   "tcp://localhost:1883",
+		TopicPrefix: "/fleet/",
+		StoreID:     "store/1",
+		MachineID:   "m#1",
+		Processor:   directives.NewProcessor(),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if got := b.topic("directives"); got != "fleet/store_1/m_1/directives" {
+		t.Errorf("Unexpected topic: %s", got)
+	}
+}
+
+func TestHandleDirective(t *testing.T) {
+	processor := directives.NewProcessor()
+	processor.Register("ping", func(ctx context.Context, d *directives.Directive) error {
+		return nil
+	})
+
+	b, err := New(&Config{BrokerURL: "tcp://localhost:1883", MachineID: "m1", Processor: processor})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if result := b.handleDirective([]byte(`{"id":"d1","type":"ping"}`)); !result.OK {
+		t.Errorf("Expected ping to succeed, got %+v", result)
+	}
+	if result := b.handleDirective([]byte(`not json`)); result.OK {
+		t.Error("Expected malformed directive to fail")
+	}
+}
@@ -0,0 +1,198 @@
+package peripheral
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Peripheral states
+const (
+	StateOnline  = "online"
+	StateOffline = "offline"
+	StateUnknown = "unknown" // Never checked, or the last report went stale
+)
+
+// probeTimeout bounds one TCP probe of a networked peripheral
+const probeTimeout = 2 * time.Second
+
+// ValidKind reports whether kind is a known peripheral kind
+func ValidKind(kind string) bool {
+	switch kind {
+	case constants.PeripheralPrinter, constants.PeripheralEFT,
+		constants.PeripheralScale, constants.PeripheralDisplay:
+		return true
+	}
+	return false
+}
+
+// Status is the last known state of one peripheral
+type Status struct {
+	Name      string
+	Kind      string
+	State     string
+	Detail    string
+	CheckedAt time.Time // Zero if never checked
+}
+
+// device is a monitored peripheral
+type device struct {
+	addr   string // host:port probed over TCP; empty for reported peripherals
+	status Status
+}
+
+// Monitor tracks the health of the peripherals attached to this terminal.
+// Networked peripherals (LAN printers, EFT terminals) are probed over TCP;
+// the others are reported by the peripheral layer that drives them. They
+// are soft dependencies: the terminal works without them, but the frontend
+// warns the cashier before a sale that cannot print or take a card.
+type Monitor struct {
+	mu         sync.RWMutex
+	devices    map[string]*device
+	staleAfter time.Duration
+	dial       func(ctx context.Context, network, addr string) (net.Conn, error)
+	now        func() time.Time
+	onChange   func(Status)
+}
+
+// New creates a monitor whose reports go stale after staleAfter
+func New(staleAfter time.Duration) *Monitor {
+	dialer := &net.Dialer{Timeout: probeTimeout}
+	return &Monitor{
+		devices:    make(map[string]*device),
+		staleAfter: staleAfter,
+		dial:       dialer.DialContext,
+		now:        time.Now,
+	}
+}
+
+// Add registers a peripheral; a non-empty addr is probed over TCP
+func (m *Monitor) Add(name, kind, addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.devices[name] = &device{addr: addr, status: Status{Name: name, Kind: kind, State: StateUnknown}}
+}
+
+// OnChange sets a function called with a peripheral's status whenever its
+// state changes (going online or offline, or reporting a different detail
+// while offline). It runs after the monitor's lock is released.
+func (m *Monitor) OnChange(fn func(Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onChange = fn
+}
+
+// changed reports whether moving from old to updated is worth announcing
+func changed(old, updated Status) bool {
+	return old.State != updated.State || (updated.State == StateOffline && old.Detail != updated.Detail)
+}
+
+// Report records the state of a peripheral as seen by the layer driving it,
+// registering it on first report
+func (m *Monitor) Report(name, kind string, online bool, detail string) {
+	m.mu.Lock()
+	d, ok := m.devices[name]
+	if !ok {
+		d = &device{status: Status{State: StateUnknown}}
+		m.devices[name] = d
+	}
+	old := d.status
+	d.status = Status{Name: name, Kind: kind, State: StateOffline, Detail: detail, CheckedAt: m.now()}
+	if online {
+		d.status.State = StateOnline
+	}
+	updated, onChange := d.status, m.onChange
+	m.mu.Unlock()
+
+	if onChange != nil && changed(old, updated) {
+		onChange(updated)
+	}
+}
+
+// Statuses returns every peripheral by name. Reports older than the stale
+// period are returned as unknown.
+func (m *Monitor) Statuses() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	statuses := make([]Status, 0, len(m.devices))
+	for _, d := range m.devices {
+		status := d.status
+		if !status.CheckedAt.IsZero() && now.Sub(status.CheckedAt) > m.staleAfter {
+			status.State = StateUnknown
+			status.Detail = "no recent report"
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Probe checks every networked peripheral once
+func (m *Monitor) Probe(ctx context.Context) {
+	m.mu.RLock()
+	probes := make(map[string]string)
+	for name, d := range m.devices {
+		if d.addr != "" {
+			probes[name] = d.addr
+		}
+	}
+	m.mu.RUnlock()
+
+	for name, addr := range probes {
+		ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+		conn, err := m.dial(ctx, "tcp", addr)
+		cancel()
+
+		detail := ""
+		if err == nil {
+			conn.Close()
+		} else {
+			detail = err.Error()
+		}
+
+		m.mu.Lock()
+		d, ok := m.devices[name]
+		var old, updated Status
+		if ok {
+			old = d.status
+			d.status.State = StateOffline
+			if err == nil {
+				d.status.State = StateOnline
+			}
+			d.status.Detail = detail
+			d.status.CheckedAt = m.now()
+			updated = d.status
+		}
+		onChange := m.onChange
+		m.mu.Unlock()
+
+		if ok && onChange != nil && changed(old, updated) {
+			onChange(updated)
+		}
+	}
+}
+
+// Run probes networked peripherals every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	m.Probe(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Probe(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
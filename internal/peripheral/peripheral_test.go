@@ -0,0 +1,86 @@
+package peripheral
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestMonitor_ReportAndStale(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := New(2 * time.Minute)
+	m.now = func() time.Time { return now }
+
+	m.Add("front", constants.PeripheralDisplay, "")
+	m.Report("scale", constants.PeripheralScale, true, "")
+	m.Report("receipt", constants.PeripheralPrinter, false, "paper out")
+
+	statuses := m.Statuses()
+	if len(statuses) != 3 {
+		t.Fatalf("Expected 3 peripherals, got %v", statuses)
+	}
+	want := map[string]string{"front": StateUnknown, "receipt": StateOffline, "scale": StateOnline}
+	for _, s := range statuses {
+		if s.State != want[s.Name] {
+			t.Errorf("%s is %s, want %s", s.Name, s.State, want[s.Name])
+		}
+	}
+	if statuses[1].Name != "receipt" || statuses[1].Detail != "paper out" {
+		t.Errorf("Expected peripherals sorted by name with details, got %v", statuses)
+	}
+
+	// Without fresh reports the states become unknown
+	now = now.Add(3 * time.Minute)
+	for _, s := range m.Statuses() {
+		if s.State != StateUnknown {
+			t.Errorf("%s is %s after going stale, want unknown", s.Name, s.State)
+		}
+	}
+}
+
+func TestMonitor_ProbesNetworkedPeripherals(t *testing.T) {
+	m := New(time.Minute)
+	m.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == "10.0.0.20:9100" {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	m.Add("receipt", constants.PeripheralPrinter, "10.0.0.20:9100")
+	m.Add("pinpad", constants.PeripheralEFT, "10.0.0.21:20007")
+	m.Probe(context.Background())
+
+	states := make(map[string]string)
+	for _, s := range m.Statuses() {
+		states[s.Name] = s.State
+	}
+	if states["receipt"] != StateOnline || states["pinpad"] != StateOffline {
+		t.Errorf("Unexpected probe results %v", states)
+	}
+}
+
+func TestMonitor_OnChange(t *testing.T) {
+	m := New(time.Minute)
+
+	var changes []Status
+	m.OnChange(func(s Status) { changes = append(changes, s) })
+
+	m.Report("receipt", constants.PeripheralPrinter, true, "")
+	m.Report("receipt", constants.PeripheralPrinter, true, "") // Unchanged
+	m.Report("receipt", constants.PeripheralPrinter, false, "paper out")
+	m.Report("receipt", constants.PeripheralPrinter, false, "cover open")
+
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", changes)
+	}
+	if changes[0].State != StateOnline || changes[1].Detail != "paper out" || changes[2].Detail != "cover open" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
@@ -0,0 +1,364 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// ServerKeyPath is the backend endpoint handing out a store's database server key
+const ServerKeyPath = "/provision/server-key"
+
+// MachineIDPath is the backend endpoint approving a terminal's new machine ID
+const MachineIDPath = "/provision/machine-id"
+
+// serverKeySize is the length of a ChaCha20-Poly1305 server key
+const serverKeySize = 32
+
+// ErrNotProvisioned is returned on first boot when there is no sealed
+// server key and no store token to fetch one with
+var ErrNotProvisioned = errors.New("server key not provisioned")
+
+// Wrapper encrypts secrets with the machine-bound config key
+// (implemented by config.Manager)
+type Wrapper interface {
+	WrapSecret(secret []byte) (string, error)
+	UnwrapSecret(wrapped string) (secret []byte, migrated bool, err error)
+}
+
+// Config holds provisioning configuration
+type Config struct {
+	ServerURL  string
+	StoreID    string
+	StoreToken string // Issued by the backend when the store is enrolled
+	MachineID  string
+
+	Keys    security.KeyStore // Where the wrapped key is sealed
+	Wrapper Wrapper
+
+	// HTTPClient talks to the backend; nil uses a client with a 30s timeout
+	HTTPClient *http.Client
+
+	// Signer signs the provisioning request and verifies the backend's
+	// response (see security.RequestSigner); nil sends it unsigned
+	Signer *security.RequestSigner
+}
+
+// Provisioner obtains the database server key once and keeps it. The key is
+// wrapped with the config key and then sealed in the key store, so reading
+// it back needs both this machine's config key and its TPM/DPAPI/file key.
+// A client certificate issued alongside the key is kept the same way.
+type Provisioner struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+// keyRequest is the body sent to ServerKeyPath
+type keyRequest struct {
+	StoreID   string `json:"store_id"`
+	MachineID string `json:"machine_id"`
+}
+
+// machineIDRequest is the body sent to MachineIDPath
+type machineIDRequest struct {
+	StoreID      string `json:"store_id"`
+	MachineID    string `json:"machine_id"` // The ID the backend knows the terminal by
+	NewMachineID string `json:"new_machine_id"`
+}
+
+// credentials is what the backend issues on provisioning
+type credentials struct {
+	serverKey  []byte
+	clientCert []byte // PEM certificate chain and key; nil if none was issued
+}
+
+// New creates a provisioner
+func New(cfg *Config) (*Provisioner, error) {
+	if cfg.Keys == nil || cfg.Wrapper == nil {
+		return nil, fmt.Errorf("provisioner requires a key store and a wrapper")
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Provisioner{cfg: *cfg, httpClient: httpClient}, nil
+}
+
+// ServerKey returns the persisted server key, fetching and persisting it
+// on first boot. fetched reports whether the key came from the backend.
+func (p *Provisioner) ServerKey(ctx context.Context) (key []byte, fetched bool, err error) {
+	key, err = p.load(security.ServerKeyName)
+	if err == nil {
+		return key, false, nil
+	}
+	if !errors.Is(err, security.ErrSecretNotFound) {
+		return nil, false, err
+	}
+
+	creds, err := p.fetch(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Seal the certificate first: a server key without it would never be
+	// fetched again, leaving the terminal unable to enroll for mTLS
+	if creds.clientCert != nil {
+		if err := p.persist(security.ClientCertName, creds.clientCert); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := p.persist(security.ServerKeyName, creds.serverKey); err != nil {
+		return nil, false, err
+	}
+	return creds.serverKey, true, nil
+}
+
+// ClientCertificate returns the client certificate issued during
+// provisioning, or security.ErrSecretNotFound if the backend issued none
+func (p *Provisioner) ClientCertificate() (*tls.Certificate, error) {
+	bundle, err := p.loadSecret(security.ClientCertName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The bundle holds both PEM blocks; each parser skips the other's
+	cert, err := tls.X509KeyPair(bundle, bundle)
+	security.Wipe(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed client certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+// Rotate replaces the server key with newKey. The new key is staged in the
+// key store before reencrypt runs and promoted once it succeeds, so a crash
+// at any point leaves a key that opens the database: either the current one
+// or the staged one (see PendingRotation).
+func (p *Provisioner) Rotate(newKey []byte, reencrypt func() error) error {
+	if len(newKey) != serverKeySize {
+		return fmt.Errorf("invalid server key length: %d", len(newKey))
+	}
+
+	if err := p.persist(security.NextServerKeyName, newKey); err != nil {
+		return err
+	}
+
+	if err := reencrypt(); err != nil {
+		if abandonErr := p.AbandonRotation(); abandonErr != nil {
+			return fmt.Errorf("%w (and failed to discard staged key: %v)", err, abandonErr)
+		}
+		return err
+	}
+
+	return p.FinishRotation(newKey)
+}
+
+// PendingRotation returns the key staged by a rotation that did not finish,
+// or security.ErrSecretNotFound. The caller checks which key the database
+// is under and then calls FinishRotation or AbandonRotation.
+func (p *Provisioner) PendingRotation() ([]byte, error) {
+	return p.load(security.NextServerKeyName)
+}
+
+// FinishRotation makes key the server key and drops the staged copy
+func (p *Provisioner) FinishRotation(key []byte) error {
+	if err := p.persist(security.ServerKeyName, key); err != nil {
+		return err
+	}
+	return p.AbandonRotation()
+}
+
+// AbandonRotation drops the staged key, keeping the current one
+func (p *Provisioner) AbandonRotation() error {
+	if err := p.cfg.Keys.Delete(security.NextServerKeyName); err != nil && !errors.Is(err, security.ErrSecretNotFound) {
+		return fmt.Errorf("failed to delete staged server key: %w", err)
+	}
+	return nil
+}
+
+// ApproveMachineID asks the backend to accept newMachineID for this
+// terminal, which is enrolled under cfg.MachineID. The backend answers
+// {"result": {"approved": true}} once head office allows the change.
+func (p *Provisioner) ApproveMachineID(ctx context.Context, newMachineID string) error {
+	if p.cfg.StoreToken == "" || p.cfg.ServerURL == "" {
+		return fmt.Errorf("%w: server_url and store_token are required to change the machine ID", ErrNotProvisioned)
+	}
+
+	status, data, err := p.post(ctx, MachineIDPath, machineIDRequest{
+		StoreID:      p.cfg.StoreID,
+		MachineID:    p.cfg.MachineID,
+		NewMachineID: newMachineID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Message string `json:"message"`
+		Result  struct {
+			Approved bool `json:"approved"`
+		} `json:"result"`
+	}
+	json.Unmarshal(data, &envelope)
+
+	if status != http.StatusOK || !envelope.Result.Approved {
+		return fmt.Errorf("backend did not approve the machine ID change (%d): %s", status, envelope.Message)
+	}
+	return nil
+}
+
+// Rewrap wraps every sealed secret still under an older config key again
+// with the current one, so a migration does not wait for each to be used
+func (p *Provisioner) Rewrap() error {
+	for _, name := range []string{security.ServerKeyName, security.NextServerKeyName, security.ClientCertName} {
+		secret, err := p.loadSecret(name)
+		if err != nil && !errors.Is(err, security.ErrSecretNotFound) {
+			return err
+		}
+		security.Wipe(secret)
+	}
+	return nil
+}
+
+// load unseals and unwraps the server key sealed under name
+func (p *Provisioner) load(name string) ([]byte, error) {
+	key, err := p.loadSecret(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != serverKeySize {
+		return nil, fmt.Errorf("invalid sealed server key length: %d", len(key))
+	}
+	return key, nil
+}
+
+// loadSecret unseals and unwraps the secret sealed under name
+func (p *Provisioner) loadSecret(name string) ([]byte, error) {
+	sealed, err := p.cfg.Keys.Unseal(name)
+	if errors.Is(err, security.ErrSecretNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal %s: %w", name, err)
+	}
+
+	secret, migrated, err := p.cfg.Wrapper.UnwrapSecret(string(sealed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap %s: %w", name, err)
+	}
+
+	// Follow a config key migration so the old key is needed only once
+	if migrated {
+		if err := p.persist(name, secret); err != nil {
+			return nil, err
+		}
+	}
+	return secret, nil
+}
+
+// persist wraps secret with the config key and seals it under name
+func (p *Provisioner) persist(name string, secret []byte) error {
+	wrapped, err := p.cfg.Wrapper.WrapSecret(secret)
+	if err != nil {
+		return fmt.Errorf("failed to wrap %s: %w", name, err)
+	}
+	if err := p.cfg.Keys.Seal(name, []byte(wrapped)); err != nil {
+		return fmt.Errorf("failed to seal %s: %w", name, err)
+	}
+	return nil
+}
+
+// fetch requests the server key (and client certificate, if the backend
+// enforces mTLS) from the backend with the store token
+func (p *Provisioner) fetch(ctx context.Context) (*credentials, error) {
+	if p.cfg.StoreToken == "" || p.cfg.ServerURL == "" {
+		return nil, fmt.Errorf("%w: server_url and store_token are required on first start", ErrNotProvisioned)
+	}
+
+	status, data, err := p.post(ctx, ServerKeyPath, keyRequest{StoreID: p.cfg.StoreID, MachineID: p.cfg.MachineID})
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Message string `json:"message"`
+		Result  struct {
+			ServerKey  string `json:"server_key"`
+			ClientCert string `json:"client_cert"` // PEM certificate chain
+			ClientKey  string `json:"client_key"`  // PEM private key
+		} `json:"result"`
+	}
+	json.Unmarshal(data, &envelope)
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("backend refused provisioning (%d): %s", status, envelope.Message)
+	}
+
+	serverKey, err := security.ServerKeyFromBase64(envelope.Result.ServerKey)
+	if err != nil {
+		return nil, err
+	}
+	creds := &credentials{serverKey: serverKey}
+
+	if envelope.Result.ClientCert != "" {
+		bundle := []byte(envelope.Result.ClientCert + "\n" + envelope.Result.ClientKey)
+		if _, err := tls.X509KeyPair(bundle, bundle); err != nil {
+			return nil, fmt.Errorf("backend issued an invalid client certificate: %w", err)
+		}
+		creds.clientCert = bundle
+	}
+	return creds, nil
+}
+
+// post sends a provisioning request authorized by the store token and
+// returns the response status and body. Signed requests need a signed
+// response: it may carry keys, so one a middlebox could have swapped is
+// never trusted.
+func (p *Provisioner) post(ctx context.Context, path string, payload interface{}) (int, []byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to encode provisioning request: %w", err)
+	}
+
+	url := strings.TrimRight(p.cfg.ServerURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create provisioning request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.cfg.StoreToken)
+	if p.cfg.Signer != nil {
+		if err := p.cfg.Signer.Sign(req); err != nil {
+			return 0, nil, fmt.Errorf("failed to sign provisioning request: %w", err)
+		}
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("provisioning request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if p.cfg.Signer != nil {
+		if err := p.cfg.Signer.VerifyResponse(req, resp); err != nil {
+			return 0, nil, fmt.Errorf("rejected provisioning response: %w", err)
+		}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read provisioning response: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
@@ -0,0 +1,375 @@
+package provision
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// testWrapper wraps with a ConfigEncryption and can fall back to an older one
+type testWrapper struct {
+	current, previous *security.ConfigEncryption
+}
+
+func (w *testWrapper) WrapSecret(secret []byte) (string, error) {
+	return w.current.Encrypt(secret)
+}
+
+func (w *testWrapper) UnwrapSecret(wrapped string) ([]byte, bool, error) {
+	if secret, err := w.current.Decrypt(wrapped); err == nil {
+		return secret, false, nil
+	}
+	if w.previous != nil {
+		if secret, err := w.previous.Decrypt(wrapped); err == nil {
+			return secret, true, nil
+		}
+	}
+	return nil, false, errors.New("cannot unwrap")
+}
+
+func newWrapper(t *testing.T, key string) *security.ConfigEncryption {
+	enc, err := security.NewConfigEncryption([]byte(key), "machine-1")
+	if err != nil {
+		t.Fatalf("Failed to create config encryption: %v", err)
+	}
+	return enc
+}
+
+func newBackend(t *testing.T, key []byte, calls *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if r.URL.Path != ServerKeyPath || r.Header.Get("Authorization") != "Bearer store-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "message": "bad token"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":     true,
+			"result": map[string]string{"server_key": security.ServerKeyToBase64(key)},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerKey_ProvisionsOnceAndReuses(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	calls := 0
+	backend := newBackend(t, serverKey, &calls)
+
+	keys, err := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	if err != nil {
+		t.Fatalf("Failed to open key store: %v", err)
+	}
+	cfg := &Config{
+		ServerURL:  backend.URL,
+		StoreID:    "store-1",
+		StoreToken: "store-token",
+		MachineID:  "machine-1",
+		Keys:       keys,
+		Wrapper:    &testWrapper{current: newWrapper(t, "config-key-0123456789abcdef0123")},
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	key, fetched, err := p.ServerKey(context.Background())
+	if err != nil {
+		t.Fatalf("ServerKey failed: %v", err)
+	}
+	if !fetched || string(key) != string(serverKey) {
+		t.Fatal("Expected the backend key on first boot")
+	}
+
+	// Next boot: no backend call, same key, even without a token
+	cfg.StoreToken = ""
+	p, _ = New(cfg)
+	key, fetched, err = p.ServerKey(context.Background())
+	if err != nil {
+		t.Fatalf("ServerKey on second boot failed: %v", err)
+	}
+	if fetched || string(key) != string(serverKey) || calls != 1 {
+		t.Errorf("Expected sealed key to be reused (fetched=%v calls=%d)", fetched, calls)
+	}
+
+	// The sealed blob is wrapped, not the raw key
+	sealed, _ := keys.Unseal(security.ServerKeyName)
+	if string(sealed) == string(serverKey) {
+		t.Error("Server key sealed without config key wrapping")
+	}
+}
+
+func TestServerKey_RewrapsAfterConfigKeyMigration(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	calls := 0
+	backend := newBackend(t, serverKey, &calls)
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+
+	oldEnc := newWrapper(t, "old-config-key-0123456789abcdef01")
+	p, _ := New(&Config{ServerURL: backend.URL, StoreToken: "store-token", Keys: keys, Wrapper: &testWrapper{current: oldEnc}})
+	if _, _, err := p.ServerKey(context.Background()); err != nil {
+		t.Fatalf("ServerKey failed: %v", err)
+	}
+
+	newEnc := newWrapper(t, "new-config-key-0123456789abcdef01")
+	p, _ = New(&Config{Keys: keys, Wrapper: &testWrapper{current: newEnc, previous: oldEnc}})
+	key, _, err := p.ServerKey(context.Background())
+	if err != nil || string(key) != string(serverKey) {
+		t.Fatalf("Expected key under the previous config key, got err %v", err)
+	}
+
+	// Now readable with the new key alone
+	p, _ = New(&Config{Keys: keys, Wrapper: &testWrapper{current: newEnc}})
+	if key, _, err := p.ServerKey(context.Background()); err != nil || string(key) != string(serverKey) {
+		t.Errorf("Expected key to be re-wrapped, got err %v", err)
+	}
+}
+
+func TestServerKey_Errors(t *testing.T) {
+	calls := 0
+	serverKey, _ := security.GenerateServerKey()
+	backend := newBackend(t, serverKey, &calls)
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	wrapper := &testWrapper{current: newWrapper(t, "config-key-0123456789abcdef0123")}
+
+	p, _ := New(&Config{ServerURL: backend.URL, Keys: keys, Wrapper: wrapper})
+	if _, _, err := p.ServerKey(context.Background()); !errors.Is(err, ErrNotProvisioned) {
+		t.Errorf("Expected ErrNotProvisioned without a token, got %v", err)
+	}
+
+	p, _ = New(&Config{ServerURL: backend.URL, StoreToken: "wrong", Keys: keys, Wrapper: wrapper})
+	if _, _, err := p.ServerKey(context.Background()); err == nil {
+		t.Error("Expected error for a rejected token")
+	}
+	if _, err := keys.Unseal(security.ServerKeyName); !errors.Is(err, security.ErrSecretNotFound) {
+		t.Errorf("Expected nothing sealed after a failed provisioning, got %v", err)
+	}
+
+	if _, err := New(&Config{}); err == nil {
+		t.Error("Expected error without key store and wrapper")
+	}
+}
+
+func TestRotate_StagesThenPromotes(t *testing.T) {
+	calls := 0
+	serverKey, _ := security.GenerateServerKey()
+	backend := newBackend(t, serverKey, &calls)
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	wrapper := &testWrapper{current: newWrapper(t, "config-key-0123456789abcdef0123")}
+
+	p, _ := New(&Config{ServerURL: backend.URL, StoreToken: "store-token", Keys: keys, Wrapper: wrapper})
+	if _, _, err := p.ServerKey(context.Background()); err != nil {
+		t.Fatalf("ServerKey failed: %v", err)
+	}
+
+	newKey, _ := security.GenerateServerKey()
+
+	// A failed re-encryption keeps the current key and drops the staged one
+	failed := errors.New("re-encryption failed")
+	if err := p.Rotate(newKey, func() error {
+		if staged, err := p.PendingRotation(); err != nil || string(staged) != string(newKey) {
+			t.Errorf("Expected the new key to be staged during re-encryption, got %v", err)
+		}
+		return failed
+	}); !errors.Is(err, failed) {
+		t.Fatalf("Expected the re-encryption error, got %v", err)
+	}
+	if _, err := p.PendingRotation(); !errors.Is(err, security.ErrSecretNotFound) {
+		t.Errorf("Expected no staged key after a failed rotation, got %v", err)
+	}
+	if key, _, _ := p.ServerKey(context.Background()); string(key) != string(serverKey) {
+		t.Error("Expected the current key to survive a failed rotation")
+	}
+
+	if err := p.Rotate(newKey, func() error { return nil }); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if key, _, _ := p.ServerKey(context.Background()); string(key) != string(newKey) {
+		t.Error("Expected the rotated key to be persisted")
+	}
+	if _, err := p.PendingRotation(); !errors.Is(err, security.ErrSecretNotFound) {
+		t.Errorf("Expected no staged key after rotation, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected one backend call, got %d", calls)
+	}
+}
+
+// newClientCert returns a self-signed PEM certificate and key
+func newClientCert(t *testing.T) (certPEM, keyPEM string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "machine-1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certPEM, keyPEM
+}
+
+func TestServerKey_StoresClientCertificate(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	certPEM, keyPEM := newClientCert(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok": true,
+			"result": map[string]string{
+				"server_key":  security.ServerKeyToBase64(serverKey),
+				"client_cert": certPEM,
+				"client_key":  keyPEM,
+			},
+		})
+	}))
+	defer backend.Close()
+
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	wrapper := &testWrapper{current: newWrapper(t, "config-key-0123456789abcdef0123")}
+
+	p, _ := New(&Config{ServerURL: backend.URL, StoreToken: "store-token", Keys: keys, Wrapper: wrapper})
+	if _, err := p.ClientCertificate(); !errors.Is(err, security.ErrSecretNotFound) {
+		t.Errorf("Expected no client certificate before provisioning, got %v", err)
+	}
+	if _, _, err := p.ServerKey(context.Background()); err != nil {
+		t.Fatalf("ServerKey failed: %v", err)
+	}
+
+	// The private key is sealed wrapped, never in the clear
+	sealed, err := keys.Unseal(security.ClientCertName)
+	if err != nil {
+		t.Fatalf("Expected the client certificate to be sealed: %v", err)
+	}
+	if strings.Contains(string(sealed), "PRIVATE KEY") {
+		t.Error("Client key sealed without config key wrapping")
+	}
+
+	p, _ = New(&Config{Keys: keys, Wrapper: wrapper})
+	cert, err := p.ClientCertificate()
+	if err != nil {
+		t.Fatalf("ClientCertificate failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || leaf.Subject.CommonName != "machine-1" {
+		t.Errorf("Expected the issued certificate, got %v", err)
+	}
+}
+
+func TestServerKey_RejectsInvalidClientCertificate(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	certPEM, _ := newClientCert(t)
+	_, otherKeyPEM := newClientCert(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok": true,
+			"result": map[string]string{
+				"server_key":  security.ServerKeyToBase64(serverKey),
+				"client_cert": certPEM,
+				"client_key":  otherKeyPEM,
+			},
+		})
+	}))
+	defer backend.Close()
+
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	wrapper := &testWrapper{current: newWrapper(t, "config-key-0123456789abcdef0123")}
+
+	p, _ := New(&Config{ServerURL: backend.URL, StoreToken: "store-token", Keys: keys, Wrapper: wrapper})
+	if _, _, err := p.ServerKey(context.Background()); err == nil {
+		t.Fatal("Expected a mismatched certificate and key to be rejected")
+	}
+	if _, err := keys.Unseal(security.ServerKeyName); !errors.Is(err, security.ErrSecretNotFound) {
+		t.Errorf("Expected nothing sealed after a rejected certificate, got %v", err)
+	}
+}
+
+func TestApproveMachineID(t *testing.T) {
+	approve := true
+	var got machineIDRequest
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != MachineIDPath || r.Header.Get("Authorization") != "Bearer store-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if !approve {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "message": "pending head office approval"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]bool{"approved": true}})
+	}))
+	t.Cleanup(backend.Close)
+
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	p, _ := New(&Config{
+		ServerURL:  backend.URL,
+		StoreID:    "store-1",
+		StoreToken: "store-token",
+		MachineID:  "machine-1",
+		Keys:       keys,
+		Wrapper:    &testWrapper{current: newWrapper(t, "config-key-0123456789abcdef0123")},
+	})
+
+	if err := p.ApproveMachineID(context.Background(), "machine-2"); err != nil {
+		t.Fatalf("ApproveMachineID failed: %v", err)
+	}
+	if got.StoreID != "store-1" || got.MachineID != "machine-1" || got.NewMachineID != "machine-2" {
+		t.Errorf("Unexpected request %+v", got)
+	}
+
+	approve = false
+	err := p.ApproveMachineID(context.Background(), "machine-2")
+	if err == nil || !strings.Contains(err.Error(), "pending head office approval") {
+		t.Errorf("Expected a refusal with the backend's message, got %v", err)
+	}
+}
+
+func TestRewrap_MovesSecretsToCurrentKey(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	calls := 0
+	backend := newBackend(t, serverKey, &calls)
+	keys, _ := security.NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+
+	oldEnc := newWrapper(t, "old-config-key-0123456789abcdef01")
+	p, _ := New(&Config{ServerURL: backend.URL, StoreToken: "store-token", Keys: keys, Wrapper: &testWrapper{current: oldEnc}})
+	if _, _, err := p.ServerKey(context.Background()); err != nil {
+		t.Fatalf("ServerKey failed: %v", err)
+	}
+
+	newEnc := newWrapper(t, "new-config-key-0123456789abcdef01")
+	p, _ = New(&Config{Keys: keys, Wrapper: &testWrapper{current: newEnc, previous: oldEnc}})
+	if err := p.Rewrap(); err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+
+	p, _ = New(&Config{Keys: keys, Wrapper: &testWrapper{current: newEnc}})
+	if key, _, err := p.ServerKey(context.Background()); err != nil || string(key) != string(serverKey) {
+		t.Errorf("Expected key to be re-wrapped, got err %v", err)
+	}
+}
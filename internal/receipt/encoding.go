@@ -0,0 +1,188 @@
+package receipt
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Receipt printer encodings
+const (
+	EncodingASCII  = constants.ReceiptEncodingASCII
+	EncodingCP866  = constants.ReceiptEncodingCP866
+	EncodingCP1251 = constants.ReceiptEncodingCP1251
+	EncodingUTF8   = constants.ReceiptEncodingUTF8
+)
+
+// ESC/POS commands
+var (
+	escInit     = []byte{0x1b, '@'} // ESC @: reset the printer
+	escCodePage = []byte{0x1b, 't'} // ESC t n: select character table n
+)
+
+// Printer describes one receipt printer
+type Printer struct {
+	Width    int    // Characters per line; 0 uses DefaultWidth
+	Encoding string // One of the Encoding constants; "" uses the default
+	CodePage int    // ESC t table number; 0 uses the encoding's usual one
+}
+
+// codePages maps single-byte encodings to their character set and the
+// table number most ESC/POS printers (Epson numbering) use for it
+var codePages = map[string]struct {
+	charmap  *charmap.Charmap
+	codePage int
+}{
+	EncodingCP866:  {charmap.CodePage866, 17},
+	EncodingCP1251: {charmap.Windows1251, 46},
+}
+
+// Encoder converts receipt text to the bytes a printer expects. Characters
+// its table lacks are transliterated (Uzbek Қ becomes К on a CP1251 printer,
+// Ж becomes J on an ASCII one) so a name degrades instead of printing as
+// question marks.
+type Encoder struct {
+	encoding string
+	charmap  *charmap.Charmap // nil for ASCII and UTF-8
+	codePage int
+}
+
+// NewEncoder creates the encoder for a printer
+func NewEncoder(printer Printer) (*Encoder, error) {
+	encoding := printer.Encoding
+	if encoding == "" {
+		encoding = constants.DefaultReceiptEncoding
+	}
+
+	e := &Encoder{encoding: encoding, codePage: printer.CodePage}
+	switch encoding {
+	case EncodingASCII, EncodingUTF8:
+	case EncodingCP866, EncodingCP1251:
+		e.charmap = codePages[encoding].charmap
+		if e.codePage == 0 {
+			e.codePage = codePages[encoding].codePage
+		}
+	default:
+		return nil, fmt.Errorf("unsupported receipt encoding %q", encoding)
+	}
+	if e.codePage < 0 || e.codePage > 255 {
+		return nil, fmt.Errorf("invalid printer code page %d", e.codePage)
+	}
+	return e, nil
+}
+
+// Encode renders lines as an ESC/POS job: reset, character table selection
+// (single-byte encodings only) and one encoded line per row
+func (e *Encoder) Encode(lines []string) []byte {
+	out := append([]byte(nil), escInit...)
+	if e.charmap != nil {
+		out = append(out, escCodePage...)
+		out = append(out, byte(e.codePage))
+	}
+	for _, line := range lines {
+		out = e.appendString(out, line)
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// EncodeString encodes one string without any printer commands
+func (e *Encoder) EncodeString(s string) []byte {
+	return e.appendString(nil, s)
+}
+
+// appendString appends the encoding of s to out
+func (e *Encoder) appendString(out []byte, s string) []byte {
+	if e.encoding == EncodingUTF8 {
+		return append(out, s...)
+	}
+	for _, r := range s {
+		out = e.appendRune(out, r, 0)
+	}
+	return out
+}
+
+// maxTransliterationDepth bounds chained replacements: Ғ reaches an ASCII
+// printer as G via Г
+const maxTransliterationDepth = 2
+
+// appendRune appends r, or its transliteration when the table lacks it
+func (e *Encoder) appendRune(out []byte, r rune, depth int) []byte {
+	if b, ok := e.encodeRune(r); ok {
+		return append(out, b)
+	}
+
+	if depth < maxTransliterationDepth {
+		if replacement, ok := transliterations[r]; ok {
+			for _, rr := range replacement {
+				out = e.appendRune(out, rr, depth+1)
+			}
+			return out
+		}
+
+		// Latin letters with diacritics print as their base letter
+		if base := baseLetter(r); base != r {
+			return e.appendRune(out, base, depth+1)
+		}
+	}
+	return append(out, '?')
+}
+
+// encodeRune maps r to a single byte of the printer's table
+func (e *Encoder) encodeRune(r rune) (byte, bool) {
+	if r < utf8.RuneSelf {
+		return byte(r), true
+	}
+	if e.charmap == nil {
+		return 0, false
+	}
+	return e.charmap.EncodeRune(r)
+}
+
+// baseLetter strips combining marks from r (é -> e), returning r unchanged
+// when it does not decompose
+func baseLetter(r rune) rune {
+	decomposed := norm.NFD.String(string(r))
+	base, size := utf8.DecodeRuneInString(decomposed)
+	for _, mark := range decomposed[size:] {
+		if !unicode.Is(unicode.Mn, mark) {
+			return r
+		}
+	}
+	return base
+}
+
+// transliterations replace characters a printer table may lack. Uzbek and
+// Kazakh Cyrillic letters fall back to the nearest Russian letter; Russian
+// letters fall back to Latin (Uzbek Latin spelling where it exists).
+var transliterations = map[rune]string{
+	// Uzbek Latin apostrophes (oʻ, gʻ, tutuq belgisi)
+	'ʻ': "'", 'ʼ': "'", '‘': "'", '’': "'",
+
+	// Typography
+	'“': `"`, '”': `"`, '«': `"`, '»': `"`, '„': `"`,
+	'–': "-", '—': "-", '…': "...", '№': "N", '•': "*",
+	'\u00a0': " ", // No-break space
+
+	// Uzbek/Kazakh Cyrillic letters missing from Russian tables
+	'Қ': "К", 'қ': "к", 'Ғ': "Г", 'ғ': "г", 'Ҳ': "Х", 'ҳ': "х",
+	'Ў': "У", 'ў': "у", 'Ң': "Н", 'ң': "н", 'Ә': "А", 'ә': "а",
+	'Ө': "О", 'ө': "о", 'Ү': "У", 'ү': "у", 'Ұ': "У", 'ұ': "у",
+	'І': "И", 'і': "и",
+
+	// Russian Cyrillic to Latin
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "Yo",
+	'Ж': "J", 'З': "Z", 'И': "I", 'Й': "Y", 'К': "K", 'Л': "L", 'М': "M",
+	'Н': "N", 'О': "O", 'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'У': "U",
+	'Ф': "F", 'Х': "X", 'Ц': "Ts", 'Ч': "Ch", 'Ш': "Sh", 'Щ': "Sch",
+	'Ъ': "'", 'Ы': "I", 'Ь': "", 'Э': "E", 'Ю': "Yu", 'Я': "Ya",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "j", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "x", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch",
+	'ъ': "'", 'ы': "i", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
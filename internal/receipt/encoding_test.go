@@ -0,0 +1,94 @@
+package receipt
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestEncoder_CodePages(t *testing.T) {
+	name := "Қовун ширин" // Uzbek "sweet melon"
+
+	tests := []struct {
+		encoding string
+		expected []byte
+	}{
+		// Қ is missing from Russian tables and falls back to К
+		{EncodingCP866, mustEncode(t, charmap.CodePage866, "Ковун ширин")},
+		{EncodingCP1251, mustEncode(t, charmap.Windows1251, "Ковун ширин")},
+		{EncodingASCII, []byte("Kovun shirin")},
+		{EncodingUTF8, []byte(name)},
+	}
+
+	for _, tt := range tests {
+		encoder, err := NewEncoder(Printer{Encoding: tt.encoding})
+		if err != nil {
+			t.Fatalf("NewEncoder(%s) failed: %v", tt.encoding, err)
+		}
+		if got := encoder.EncodeString(name); !bytes.Equal(got, tt.expected) {
+			t.Errorf("%s: expected %q, got %q", tt.encoding, tt.expected, got)
+		}
+	}
+}
+
+func TestEncoder_Transliteration(t *testing.T) {
+	encoder, _ := NewEncoder(Printer{Encoding: EncodingASCII})
+
+	tests := map[string]string{
+		"Gʻoʻsht":       "G'o'sht", // Uzbek Latin apostrophes
+		"Ғалла":         "Galla",   // Two steps: Ғ -> Г -> G
+		"Crème brûlée":  "Creme brulee",
+		"«Чай» №1 — 5%": `"Chay" N1 - 5%`,
+		"☕":             "?",
+	}
+	for in, expected := range tests {
+		if got := string(encoder.EncodeString(in)); got != expected {
+			t.Errorf("EncodeString(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	encoder, _ := NewEncoder(Printer{Encoding: EncodingCP1251})
+	job := encoder.Encode([]string{"Итого", "OK"})
+
+	expected := append([]byte{0x1b, '@', 0x1b, 't', 46}, mustEncode(t, charmap.Windows1251, "Итого\nOK\n")...)
+	if !bytes.Equal(job, expected) {
+		t.Errorf("Expected %q, got %q", expected, job)
+	}
+
+	// A per-printer code page overrides the usual table; UTF-8 selects none
+	encoder, _ = NewEncoder(Printer{Encoding: EncodingCP866, CodePage: 6})
+	if job := encoder.Encode(nil); !bytes.Equal(job, []byte{0x1b, '@', 0x1b, 't', 6}) {
+		t.Errorf("Expected code page override, got %q", job)
+	}
+	encoder, _ = NewEncoder(Printer{Encoding: EncodingUTF8})
+	if job := encoder.Encode(nil); !bytes.Equal(job, []byte{0x1b, '@'}) {
+		t.Errorf("Expected no code page selection for UTF-8, got %q", job)
+	}
+
+	if _, err := NewEncoder(Printer{Encoding: "ebcdic"}); err == nil {
+		t.Error("Expected error for an unsupported encoding")
+	}
+}
+
+func TestColumns_CountsCharacters(t *testing.T) {
+	line := columns("Ширин қовун", "12.00", 20)
+	if n := len([]rune(line)); n != 20 {
+		t.Errorf("Expected 20 characters, got %d in %q", n, line)
+	}
+
+	line = columns("Жуда узун маҳсулот номи", "1234.00", 20)
+	if n := len([]rune(line)); n != 20 {
+		t.Errorf("Expected truncation to 20 characters, got %d in %q", n, line)
+	}
+}
+
+func mustEncode(t *testing.T, cm *charmap.Charmap, s string) []byte {
+	out, err := cm.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("Failed to encode %q: %v", s, err)
+	}
+	return out
+}
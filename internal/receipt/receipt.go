@@ -0,0 +1,167 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// DefaultWidth is the character width of an 80mm thermal receipt
+const DefaultWidth = 42
+
+// Options controls receipt layout
+type Options struct {
+	Width     int // Characters per printed line
+	PageBytes int // Upper bound on the printed size of one page
+}
+
+// Page is one printable chunk of a receipt. Long receipts are split so each
+// page fits the printer buffer; every page after the first carries the
+// running subtotal forward so a reprint of any single page stays readable.
+type Page struct {
+	Number     int      `json:"number"`
+	Count      int      `json:"count"`
+	Lines      []string `json:"lines"`
+	BroughtFwd int64    `json:"brought_forward"` // Subtotal of previous pages
+	Subtotal   int64    `json:"subtotal"`        // Running subtotal at the end of this page
+	Bytes      int      `json:"bytes"`
+	Last       bool     `json:"last"`
+
+	// ESCPOS is the page as printer bytes, set for a named printer
+	ESCPOS []byte `json:"escpos,omitempty"`
+}
+
+// Paginate renders a sale and splits it into pages no larger than
+// opts.PageBytes. A single item line never spans pages.
+func Paginate(sale *database.Sale, opts Options) []Page {
+	if opts.Width <= 0 {
+		opts.Width = DefaultWidth
+	}
+	if opts.PageBytes <= 0 {
+		opts.PageBytes = constants.DefaultReceiptPageBytes
+	}
+
+	title := "SALE " + sale.ID
+	if sale.IsRefund() {
+		title = "REFUND " + sale.ID
+	}
+
+	// Budget for page frames: title, date and rule on every page, plus a
+	// brought-forward line, closing rule and total (each at most one line)
+	frame := 6 * (opts.Width + 1)
+	budget := opts.PageBytes - frame
+	if budget < opts.Width+1 {
+		budget = opts.Width + 1 // Always fit at least one item
+	}
+
+	var pages []Page
+	var current Page
+	var running int64
+	used := 0
+
+	flush := func() {
+		current.Subtotal = running
+		pages = append(pages, current)
+		current = Page{BroughtFwd: running}
+		used = 0
+	}
+
+	for _, line := range sale.Lines {
+		amount := int64(line.Quantity) * line.Price
+		text := itemLine(line, amount, opts.Width)
+		if used > 0 && used+len(text)+1 > budget {
+			flush()
+		}
+		current.Lines = append(current.Lines, text)
+		used += len(text) + 1
+		running += amount
+	}
+	flush()
+
+	for i := range pages {
+		p := &pages[i]
+		p.Number = i + 1
+		p.Count = len(pages)
+		p.Last = i == len(pages)-1
+
+		lines := []string{
+			center(fmt.Sprintf("%s (%d/%d)", title, p.Number, p.Count), opts.Width),
+			sale.CreatedAt,
+			strings.Repeat("-", opts.Width),
+		}
+		if i > 0 {
+			lines = append(lines, columns("Brought forward", Money(p.BroughtFwd), opts.Width))
+		}
+		lines = append(lines, p.Lines...)
+		lines = append(lines, strings.Repeat("-", opts.Width))
+		if p.Last {
+			lines = append(lines, columns("TOTAL", Money(sale.Total), opts.Width))
+		} else {
+			lines = append(lines, columns("Carried forward", Money(p.Subtotal), opts.Width))
+		}
+
+		p.Lines = lines
+		p.Bytes = size(lines)
+	}
+
+	return pages
+}
+
+// Money formats minor currency units with two decimals
+func Money(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// itemLine renders one sale line as "SKU  qty x price  amount"
+func itemLine(line database.SaleLine, amount int64, width int) string {
+	left := fmt.Sprintf("%s %dx%s", line.SKU, line.Quantity, Money(line.Price))
+	return columns(left, Money(amount), width)
+}
+
+// columns left-aligns left and right-aligns right within width. Widths
+// count characters, not bytes, so Cyrillic names line up.
+func columns(left, right string, width int) string {
+	space := width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if space < 1 {
+		// Truncate the description, never the amount
+		keep := width - utf8.RuneCountInString(right) - 1
+		if keep < 0 {
+			keep = 0
+		}
+		if runes := []rune(left); keep < len(runes) {
+			left = string(runes[:keep])
+		}
+		space = width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+		if space < 1 {
+			space = 1
+		}
+	}
+	return left + strings.Repeat(" ", space) + right
+}
+
+// center pads s to be centred within width
+func center(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return strings.Repeat(" ", (width-n)/2) + s
+}
+
+// size returns the size of lines including newlines; UTF-8 bytes, so an
+// upper bound for single-byte printer encodings
+func size(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		n += len(l) + 1
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func largeSale(lines int) *database.Sale {
+	sale := &database.Sale{ID: "sale-1", CreatedAt: "2026-05-01T12:00:00Z"}
+	for i := 0; i < lines; i++ {
+		sale.Lines = append(sale.Lines, database.SaleLine{SKU: fmt.Sprintf("SKU-%04d", i), Quantity: 2, Price: 150})
+		sale.Total += 300
+	}
+	return sale
+}
+
+func TestPaginate_RespectsPageBytes(t *testing.T) {
+	sale := largeSale(500)
+
+	pages := Paginate(sale, Options{PageBytes: 2048})
+	if len(pages) < 2 {
+		t.Fatalf("Expected multiple pages, got %d", len(pages))
+	}
+
+	items := 0
+	var carried int64
+	for i, p := range pages {
+		if p.Bytes > 2048 {
+			t.Errorf("Page %d is %d bytes, over budget", p.Number, p.Bytes)
+		}
+		if p.Number != i+1 || p.Count != len(pages) {
+			t.Errorf("Unexpected page numbering %d/%d", p.Number, p.Count)
+		}
+		if p.BroughtFwd != carried {
+			t.Errorf("Page %d brought forward %d, expected %d", p.Number, p.BroughtFwd, carried)
+		}
+		carried = p.Subtotal
+
+		for _, l := range p.Lines {
+			if strings.HasPrefix(l, "SKU-") {
+				items++
+			}
+		}
+	}
+
+	if items != 500 {
+		t.Errorf("Expected all 500 items across pages, got %d", items)
+	}
+	last := pages[len(pages)-1]
+	if !last.Last || carried != sale.Total {
+		t.Errorf("Expected last page to total %d, got %d", sale.Total, carried)
+	}
+	if !strings.Contains(last.Lines[len(last.Lines)-1], Money(sale.Total)) {
+		t.Errorf("Expected grand total on last page, got %q", last.Lines[len(last.Lines)-1])
+	}
+}
+
+func TestPaginate_SmallSaleSinglePage(t *testing.T) {
+	pages := Paginate(largeSale(3), Options{})
+	if len(pages) != 1 || !pages[0].Last {
+		t.Fatalf("Expected one page, got %d", len(pages))
+	}
+}
+
+func TestMoney(t *testing.T) {
+	if got := Money(12345); got != "123.45" {
+		t.Errorf("Expected 123.45, got %s", got)
+	}
+	if got := Money(-5); got != "-0.05" {
+		t.Errorf("Expected -0.05, got %s", got)
+	}
+}
@@ -0,0 +1,111 @@
+package recommend
+
+import (
+	"sort"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// DefaultLimit is the number of suggestions returned when none is requested
+const DefaultLimit = 3
+
+// Suggestion is an upsell prompt for the cashier UI
+type Suggestion struct {
+	SKU        string  `json:"sku"`
+	RuleID     string  `json:"rule_id"`
+	Confidence float64 `json:"confidence"`
+	Lift       float64 `json:"lift"`
+	Score      float64 `json:"score"`
+	Message    string  `json:"message,omitempty"`
+	PromoID    string  `json:"promo_id,omitempty"`
+}
+
+// Suggest evaluates association rules against the SKUs in a cart and
+// returns up to limit suggestions, best first. A rule fires when every
+// antecedent SKU is in the cart, its consequent is not, and it is within
+// its validity window. Each SKU is suggested at most once.
+func Suggest(rules []database.RecommendationRule, cartSKUs []string, now time.Time, limit int) []Suggestion {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	inCart := make(map[string]bool, len(cartSKUs))
+	for _, sku := range cartSKUs {
+		inCart[sku] = true
+	}
+
+	best := make(map[string]Suggestion)
+	for _, rule := range rules {
+		if inCart[rule.Consequent] || !active(rule, now) || !containsAll(inCart, rule.Antecedent) {
+			continue
+		}
+
+		s := Suggestion{
+			SKU:        rule.Consequent,
+			RuleID:     rule.ID,
+			Confidence: rule.Confidence,
+			Lift:       rule.Lift,
+			Score:      score(rule),
+			Message:    rule.Message,
+			PromoID:    rule.PromoID,
+		}
+		if current, ok := best[s.SKU]; !ok || s.Score > current.Score {
+			best[s.SKU] = s
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(best))
+	for _, s := range best {
+		suggestions = append(suggestions, s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].SKU < suggestions[j].SKU
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// score ranks a rule: confident rules with real lift first, promos break ties upward
+func score(rule database.RecommendationRule) float64 {
+	lift := rule.Lift
+	if lift <= 0 {
+		lift = 1
+	}
+	s := rule.Confidence * lift
+	if rule.PromoID != "" {
+		s *= 1.1
+	}
+	return s
+}
+
+// active reports whether now falls inside the rule's validity window
+func active(rule database.RecommendationRule, now time.Time) bool {
+	if rule.ValidFrom != "" {
+		if from, err := time.Parse(time.RFC3339, rule.ValidFrom); err == nil && now.Before(from) {
+			return false
+		}
+	}
+	if rule.ValidUntil != "" {
+		if until, err := time.Parse(time.RFC3339, rule.ValidUntil); err == nil && !now.Before(until) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAll reports whether every SKU is in the set
+func containsAll(set map[string]bool, skus []string) bool {
+	for _, sku := range skus {
+		if !set[sku] {
+			return false
+		}
+	}
+	return true
+}
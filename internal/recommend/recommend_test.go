@@ -0,0 +1,38 @@
+package recommend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestSuggest(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	rules := []database.RecommendationRule{
+		{ID: "r1", Antecedent: []string{"PASTA"}, Consequent: "SAUCE", Confidence: 0.6, Lift: 2},
+		{ID: "r2", Antecedent: []string{"PASTA", "SAUCE"}, Consequent: "CHEESE", Confidence: 0.5, Lift: 3},
+		{ID: "r3", Antecedent: []string{"PASTA"}, Consequent: "WINE", Confidence: 0.2, Lift: 1.2},
+		{ID: "r4", Antecedent: []string{"PASTA"}, Consequent: "WINE", Confidence: 0.3, Lift: 1.5, PromoID: "promo-1"},
+		{ID: "r5", Antecedent: []string{"PASTA"}, Consequent: "BREAD", Confidence: 0.9, Lift: 2,
+			ValidUntil: "2026-04-30T00:00:00Z"},
+	}
+
+	got := Suggest(rules, []string{"PASTA"}, now, 0)
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 suggestions, got %+v", got)
+	}
+	if got[0].SKU != "SAUCE" {
+		t.Errorf("Expected SAUCE first, got %s", got[0].SKU)
+	}
+	if got[1].SKU != "WINE" || got[1].RuleID != "r4" {
+		t.Errorf("Expected best WINE rule r4, got %+v", got[1])
+	}
+
+	// Once sauce is in the cart the two-item rule fires and sauce is no longer suggested
+	got = Suggest(rules, []string{"PASTA", "SAUCE"}, now, 1)
+	if len(got) != 1 || got[0].SKU != "CHEESE" {
+		t.Errorf("Expected CHEESE, got %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Attachment is a file attached to a report email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer sends reports by email over SMTP
+type Mailer struct {
+	Addr     string // host:port
+	Username string // Empty sends without authentication
+	Password string
+	From     string
+	To       []string
+
+	// send delivers a message; smtp.SendMail outside tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewMailer creates a mailer for an SMTP server at addr
+func NewMailer(addr, username, password, from string, to []string) *Mailer {
+	return &Mailer{Addr: addr, Username: username, Password: password, From: from, To: to, send: smtp.SendMail}
+}
+
+// Send mails a plain-text body with attachments to every recipient
+func (m *Mailer) Send(subject, body string, attachments []Attachment) error {
+	msg, err := m.message(subject, body, attachments)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	if err := m.send(m.Addr, auth, m.From, m.To, msg); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
+
+// message builds a multipart/mixed MIME message
+func (m *Mailer) message(subject, body string, attachments []Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", w.Boundary())
+
+	part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build email: %w", err)
+	}
+	part.Write([]byte(body))
+
+	for _, a := range attachments {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build email: %w", err)
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
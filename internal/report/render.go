@@ -0,0 +1,174 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Render renders a table in format
+func Render(table *Table, format string) ([]byte, error) {
+	switch format {
+	case constants.ReportFormatCSV:
+		return RenderCSV(table)
+	case constants.ReportFormatPDF:
+		return RenderPDF(table), nil
+	default:
+		return nil, fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// ContentType returns the MIME type of format
+func ContentType(format string) string {
+	if format == constants.ReportFormatPDF {
+		return "application/pdf"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// RenderCSV renders a table as CSV: the header, the rows and the totals
+func RenderCSV(table *Table) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write(table.Columns)
+	w.WriteAll(table.Rows)
+	if table.Totals != nil {
+		w.Write(table.Totals)
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Text lays a table out as fixed-width lines: title, header, rows and
+// totals with every column padded to its widest cell
+func Text(table *Table) []string {
+	widths := make([]int, len(table.Columns))
+	for _, row := range append([][]string{table.Columns, table.Totals}, table.Rows...) {
+		for i, cell := range row {
+			if i < len(widths) && len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	format := func(row []string) string {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			// Left-align the first column, right-align the figures
+			if i == 0 {
+				cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			} else {
+				cells[i] = fmt.Sprintf("%*s", widths[i], cell)
+			}
+		}
+		return strings.TrimRight(strings.Join(cells, "  "), " ")
+	}
+
+	header := format(table.Columns)
+	rule := strings.Repeat("-", len(header))
+
+	lines := []string{table.Title + " - " + table.Day, "", header, rule}
+	for _, row := range table.Rows {
+		lines = append(lines, format(row))
+	}
+	if len(table.Rows) == 0 {
+		lines = append(lines, "(no activity)")
+	}
+	if table.Totals != nil {
+		lines = append(lines, rule, format(table.Totals))
+	}
+	return lines
+}
+
+// PDF page layout: A4 in points, monospaced text so columns line up
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfFontSize   = 9
+	pdfLeading    = 12
+)
+
+// RenderPDF renders a table as a plain-text PDF, one Courier line per
+// table line, paginated on A4
+func RenderPDF(table *Table) []byte {
+	lines := Text(table)
+	perPage := (pdfPageHeight - 2*pdfMargin) / pdfLeading
+
+	var pages [][]string
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects: 1 catalog, 2 page tree, 3 font, then a page and its content
+	// stream for each page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	)
+	for i, page := range pages {
+		var stream strings.Builder
+		fmt.Fprintf(&stream, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&stream, "(%s) '\n", pdfEscape(line))
+		}
+		stream.WriteString("ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfEscape escapes a line for a PDF string literal; characters outside
+// printable ASCII become '?'
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
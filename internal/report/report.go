@@ -0,0 +1,216 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/receipt"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Kinds lists every predefined report
+var Kinds = []string{
+	constants.ReportDailySales,
+	constants.ReportPromoUptake,
+	constants.ReportRefundSummary,
+}
+
+// Formats lists every format reports render to
+var Formats = []string{
+	constants.ReportFormatPDF,
+	constants.ReportFormatCSV,
+}
+
+// DayFormat is the business day a report covers
+const DayFormat = "2006-01-02"
+
+// ValidKind reports whether kind is a predefined report
+func ValidKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidFormat reports whether reports render to format
+func ValidFormat(format string) bool {
+	return format == constants.ReportFormatPDF || format == constants.ReportFormatCSV
+}
+
+// Table is a generated report: a titled table with an optional totals row
+type Table struct {
+	Kind    string
+	Title   string
+	Day     string
+	Columns []string
+	Rows    [][]string
+	Totals  []string
+}
+
+// Generate builds the report of kind for the local business day containing
+// day from the sales recorded on this terminal
+func Generate(db *database.DB, kind string, day time.Time) (*Table, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	sales, err := db.ListSales(from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{Kind: kind, Day: from.Format(DayFormat)}
+	switch kind {
+	case constants.ReportDailySales:
+		dailySales(table, sales)
+	case constants.ReportPromoUptake:
+		promoUptake(table, sales)
+	case constants.ReportRefundSummary:
+		refundSummary(table, sales)
+	default:
+		return nil, fmt.Errorf("unknown report: %s", kind)
+	}
+	return table, nil
+}
+
+// dailySales totals sales and refunds per terminal
+func dailySales(table *Table, sales []database.Sale) {
+	table.Title = "Daily Sales"
+	table.Columns = []string{"Terminal", "Sales", "Items", "Revenue", "Refunds", "Net"}
+
+	type terminal struct {
+		sales, items     int
+		revenue, refunds int64
+	}
+	terminals := make(map[string]*terminal)
+	var all terminal
+	for _, sale := range sales {
+		t, ok := terminals[sale.TerminalID]
+		if !ok {
+			t = &terminal{}
+			terminals[sale.TerminalID] = t
+		}
+		for _, tt := range []*terminal{t, &all} {
+			if sale.IsRefund() {
+				tt.refunds += sale.Total
+				continue
+			}
+			tt.sales++
+			tt.items += units(sale)
+			tt.revenue += sale.Total
+		}
+	}
+
+	for _, id := range sortedKeys(terminals) {
+		t := terminals[id]
+		table.Rows = append(table.Rows, []string{
+			id, strconv.Itoa(t.sales), strconv.Itoa(t.items),
+			receipt.Money(t.revenue), receipt.Money(t.refunds), receipt.Money(t.revenue - t.refunds),
+		})
+	}
+	table.Totals = []string{
+		"Total", strconv.Itoa(all.sales), strconv.Itoa(all.items),
+		receipt.Money(all.revenue), receipt.Money(all.refunds), receipt.Money(all.revenue - all.refunds),
+	}
+}
+
+// promoUptake totals the lines sold under each promotion
+func promoUptake(table *Table, sales []database.Sale) {
+	table.Title = "Promo Uptake"
+	table.Columns = []string{"Promotion", "Sales", "Units", "Revenue", "Share"}
+
+	type promo struct {
+		sales, units int
+		revenue      int64
+	}
+	promos := make(map[string]*promo)
+	var revenue int64
+	for _, sale := range sales {
+		if sale.IsRefund() {
+			continue
+		}
+		revenue += sale.Total
+		counted := make(map[string]bool)
+		for _, line := range sale.Lines {
+			if line.PromoID == "" {
+				continue
+			}
+			p, ok := promos[line.PromoID]
+			if !ok {
+				p = &promo{}
+				promos[line.PromoID] = p
+			}
+			if !counted[line.PromoID] {
+				p.sales++
+				counted[line.PromoID] = true
+			}
+			p.units += line.Quantity
+			p.revenue += int64(line.Quantity) * line.Price
+		}
+	}
+
+	var all promo
+	for _, id := range sortedKeys(promos) {
+		p := promos[id]
+		all.units += p.units
+		all.revenue += p.revenue
+		table.Rows = append(table.Rows, []string{
+			id, strconv.Itoa(p.sales), strconv.Itoa(p.units), receipt.Money(p.revenue), share(p.revenue, revenue),
+		})
+	}
+	table.Totals = []string{"Total", "", strconv.Itoa(all.units), receipt.Money(all.revenue), share(all.revenue, revenue)}
+}
+
+// refundSummary lists every refund
+func refundSummary(table *Table, sales []database.Sale) {
+	table.Title = "Refund Summary"
+	table.Columns = []string{"Refund", "Time", "Terminal", "Operator", "Items", "Amount"}
+
+	var count, items int
+	var amount int64
+	for _, sale := range sales {
+		if !sale.IsRefund() {
+			continue
+		}
+		at := sale.CreatedAt
+		if t, err := time.Parse(time.RFC3339, sale.CreatedAt); err == nil {
+			at = t.Local().Format("15:04")
+		}
+		table.Rows = append(table.Rows, []string{
+			sale.ID, at, sale.TerminalID, sale.OperatorID, strconv.Itoa(units(sale)), receipt.Money(sale.Total),
+		})
+		count++
+		items += units(sale)
+		amount += sale.Total
+	}
+	table.Totals = []string{fmt.Sprintf("%d refunds", count), "", "", "", strconv.Itoa(items), receipt.Money(amount)}
+}
+
+// units returns the items on a sale
+func units(sale database.Sale) int {
+	var n int
+	for _, line := range sale.Lines {
+		n += line.Quantity
+	}
+	return n
+}
+
+// share formats part as a percentage of whole
+func share(part, whole int64) string {
+	if whole == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(whole))
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,196 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// seedSales commits two sales and a refund today
+func seedSales(t *testing.T, db *database.DB) {
+	sales := []*database.Sale{
+		{ID: "s1", TerminalID: "T1", Total: 1500, Lines: []database.SaleLine{
+			{SKU: "PASTA", Quantity: 2, Price: 500, PromoID: "promo-7"},
+			{SKU: "SAUCE", Quantity: 1, Price: 500},
+		}},
+		{ID: "s2", TerminalID: "T2", Total: 1000, Lines: []database.SaleLine{
+			{SKU: "PASTA", Quantity: 2, Price: 500, PromoID: "promo-7"},
+		}},
+		{ID: "r1", Type: database.SaleTypeRefund, TerminalID: "T1", OperatorID: "op-1", Total: 500, Lines: []database.SaleLine{
+			{SKU: "SAUCE", Quantity: 1, Price: 500},
+		}},
+	}
+	for _, sale := range sales {
+		err := db.TransactionContext(context.Background(), func(tx *sql.Tx) error {
+			_, err := db.ApplySale(tx, sale)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to apply sale %s: %v", sale.ID, err)
+		}
+	}
+}
+
+func TestGenerate_Reports(t *testing.T) {
+	db := setupTestDB(t)
+	seedSales(t, db)
+
+	daily, err := Generate(db, constants.ReportDailySales, time.Now())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(daily.Rows) != 2 {
+		t.Fatalf("Expected a row per terminal, got %v", daily.Rows)
+	}
+	// Sales, items, revenue, refunds, net
+	if got := strings.Join(daily.Totals, ","); got != "Total,2,5,25.00,5.00,20.00" {
+		t.Errorf("Daily totals = %s", got)
+	}
+
+	promo, err := Generate(db, constants.ReportPromoUptake, time.Now())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(promo.Rows) != 1 || strings.Join(promo.Rows[0], ",") != "promo-7,2,4,20.00,80.0%" {
+		t.Errorf("Promo rows = %v", promo.Rows)
+	}
+
+	refunds, err := Generate(db, constants.ReportRefundSummary, time.Now())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(refunds.Rows) != 1 || refunds.Rows[0][0] != "r1" || refunds.Totals[5] != "5.00" {
+		t.Errorf("Refund rows = %v, totals = %v", refunds.Rows, refunds.Totals)
+	}
+
+	// Yesterday has no activity
+	empty, err := Generate(db, constants.ReportDailySales, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(empty.Rows) != 0 {
+		t.Errorf("Expected no rows yesterday, got %v", empty.Rows)
+	}
+
+	if _, err := Generate(db, "bogus", time.Now()); err == nil {
+		t.Errorf("Expected an error for an unknown report")
+	}
+}
+
+func TestRender_Formats(t *testing.T) {
+	table := &Table{
+		Title:   "Refund Summary",
+		Day:     "2026-03-01",
+		Columns: []string{"Refund", "Amount"},
+		Rows:    [][]string{{"r(1)", "5.00"}},
+		Totals:  []string{"1 refunds", "5.00"},
+	}
+
+	csv, err := Render(table, constants.ReportFormatCSV)
+	if err != nil {
+		t.Fatalf("Render CSV failed: %v", err)
+	}
+	if string(csv) != "Refund,Amount\nr(1),5.00\n1 refunds,5.00\n" {
+		t.Errorf("CSV = %q", csv)
+	}
+
+	pdf, err := Render(table, constants.ReportFormatPDF)
+	if err != nil {
+		t.Fatalf("Render PDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Errorf("Not a PDF: %q", pdf[:16])
+	}
+	if !bytes.Contains(pdf, []byte(`r\(1\)`)) {
+		t.Errorf("Expected escaped cell text in the PDF")
+	}
+
+	// Long reports paginate
+	for i := 0; i < 200; i++ {
+		table.Rows = append(table.Rows, []string{"r", "1.00"})
+	}
+	if pdf := RenderPDF(table); !bytes.Contains(pdf, []byte("/Count 4")) {
+		t.Errorf("Expected 4 pages for 207 lines")
+	}
+}
+
+func TestScheduler_RendersPreviousDayAndMails(t *testing.T) {
+	db := setupTestDB(t)
+	seedSales(t, db)
+
+	var sent [][]byte
+	mailer := NewMailer("smtp.example.com:587", "pos", "secret", "pos@example.com", []string{"manager@example.com"})
+	mailer.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, msg)
+		return nil
+	}
+
+	scheduler, err := NewScheduler(db, []string{constants.ReportDailySales}, nil, "06:00", mailer)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	// Tomorrow before report time nothing is due; at 07:00 today's sales are
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	scheduler.now = func() time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 5, 0, 0, 0, time.Local)
+	}
+	if err := scheduler.RunDue(); err != nil {
+		t.Fatalf("RunDue failed: %v", err)
+	}
+	if reports, _ := db.ListReports(""); len(reports) != 0 {
+		t.Fatalf("Expected nothing before report time, got %v", reports)
+	}
+
+	scheduler.now = func() time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 7, 0, 0, 0, time.Local)
+	}
+	if err := scheduler.RunDue(); err != nil {
+		t.Fatalf("RunDue failed: %v", err)
+	}
+	reports, err := db.ListReports(constants.ReportDailySales)
+	if err != nil || len(reports) != 2 {
+		t.Fatalf("Expected a PDF and a CSV, got %v (%v)", reports, err)
+	}
+	if len(sent) != 1 || !bytes.Contains(sent[0], []byte("filename=")) {
+		t.Fatalf("Expected one email with attachments, got %d", len(sent))
+	}
+
+	_, content, err := db.GetReport(database.ReportID(time.Now().Format(DayFormat), constants.ReportDailySales, constants.ReportFormatCSV))
+	if err != nil || !strings.Contains(string(content), "Total,2,5,25.00") {
+		t.Errorf("Unexpected stored report %q (%v)", content, err)
+	}
+
+	// Rendered reports are not rendered or mailed again
+	if err := scheduler.RunDue(); err != nil {
+		t.Fatalf("RunDue failed: %v", err)
+	}
+	if len(sent) != 1 {
+		t.Errorf("Expected no second email, got %d", len(sent))
+	}
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// Save generates the report of kind for day, renders it in format and
+// stores it in the report library
+func Save(db *database.DB, kind, format string, day time.Time) (*database.ReportFile, []byte, error) {
+	table, err := Generate(db, kind, day)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := Render(table, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file := &database.ReportFile{Kind: kind, Day: table.Day, Format: format}
+	if err := db.SaveReport(file, content); err != nil {
+		return nil, nil, err
+	}
+	return file, content, nil
+}
+
+// Scheduler renders the previous day's reports each morning and mails them
+// when a mailer is configured
+type Scheduler struct {
+	db      *database.DB
+	kinds   []string
+	formats []string
+	at      time.Duration // Time of day reports are due
+	mailer  *Mailer
+	now     func() time.Time
+}
+
+// NewScheduler creates a scheduler rendering kinds in formats (nil means
+// all) at the local time at ("HH:MM"). mailer may be nil.
+func NewScheduler(db *database.DB, kinds, formats []string, at string, mailer *Mailer) (*Scheduler, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report time %q: %w", at, err)
+	}
+	if len(kinds) == 0 {
+		kinds = Kinds
+	}
+	if len(formats) == 0 {
+		formats = Formats
+	}
+
+	return &Scheduler{
+		db:      db,
+		kinds:   kinds,
+		formats: formats,
+		at:      time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute,
+		mailer:  mailer,
+		now:     time.Now,
+	}, nil
+}
+
+// Run checks every interval whether the previous day's reports are due,
+// until ctx is cancelled. A terminal that was off at report time catches
+// up when it starts.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	run := func() {
+		if err := s.RunDue(); err != nil {
+			log.Printf("Warning: scheduled reports failed: %v", err)
+		}
+	}
+
+	run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			run()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunDue renders the previous day's missing reports once they are due and
+// mails the ones it rendered
+func (s *Scheduler) RunDue() error {
+	now := s.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if now.Before(today.Add(s.at)) {
+		return nil
+	}
+	day := today.AddDate(0, 0, -1)
+
+	var attachments []Attachment
+	for _, kind := range s.kinds {
+		for _, format := range s.formats {
+			exists, err := s.db.ReportExists(database.ReportID(day.Format(DayFormat), kind, format))
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+
+			file, content, err := Save(s.db, kind, format, day)
+			if err != nil {
+				return fmt.Errorf("failed to render %s report: %w", kind, err)
+			}
+			log.Printf("Rendered report %s", file.ID)
+			attachments = append(attachments, Attachment{Name: file.ID, ContentType: ContentType(format), Data: content})
+		}
+	}
+
+	if s.mailer == nil || len(attachments) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(attachments))
+	for i, a := range attachments {
+		names[i] = a.Name
+	}
+	subject := "Reports for " + day.Format(DayFormat)
+	body := "Reports rendered by the POS service:\n\n" + strings.Join(names, "\n") + "\n"
+	return s.mailer.Send(subject, body, attachments)
+}
@@ -0,0 +1,195 @@
+package sales
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/journal"
+	"github.com/professor93/promo-pos/internal/logging"
+)
+
+// Commit protocol for a sale:
+//
+//  1. Journal: append an intent record with the full sale and fsync it.
+//  2. SQLite: in one transaction insert the sale (idempotent on sale ID),
+//     decrement stock and enqueue the outbox entry (CDC trigger); commit.
+//  3. Ack: return to the caller. Only now may the frontend print a receipt.
+//
+// If step 2 fails without a crash an abort record is journaled so the sale
+// is never replayed. On startup Recover replays every intent that is
+// neither aborted nor present in SQLite, then checkpoints the journal.
+//
+// Guarantees: an acknowledged sale passed step 1 and 2, so it survives a
+// crash even if SQLite lost its last WAL frames (synchronous=NORMAL) — the
+// journal replays it. An unacknowledged sale is applied at most once
+// because the sale ID is the primary key and stock moves in the same
+// transaction as the insert; a frontend retry with the same ID is a no-op.
+
+// CrashPoint names a step of the commit protocol (used by crash tests)
+type CrashPoint string
+
+// Crash points, in protocol order
+const (
+	CrashAfterJournal CrashPoint = "after_journal" // Intent durable, no DB transaction yet
+	CrashBeforeCommit CrashPoint = "before_commit" // Inside the DB transaction, not committed
+	CrashAfterCommit  CrashPoint = "after_commit"  // Committed, caller not yet acknowledged
+)
+
+// checkpointThreshold is how many journal records accumulate before the
+// journal is checkpointed after a successful commit
+const checkpointThreshold = 1000
+
+// Result is the acknowledgement returned for a committed sale
+type Result struct {
+	SaleID    string `json:"sale_id"`
+	Duplicate bool   `json:"duplicate"` // Sale was already committed earlier
+}
+
+// RecoveryStats reports what Recover found in the journal
+type RecoveryStats struct {
+	Replayed  int `json:"replayed"`  // Intents applied during recovery
+	Committed int `json:"committed"` // Intents already present in SQLite
+	Aborted   int `json:"aborted"`   // Intents explicitly aborted
+}
+
+// Ledger commits sales through the journal and the database
+type Ledger struct {
+	db      *database.DB
+	journal *journal.Journal
+
+	// mu serialises commits so a checkpoint never drops an in-flight intent
+	mu sync.Mutex
+
+	// crash simulates a process kill at a protocol step (tests only)
+	crash func(CrashPoint)
+}
+
+// NewLedger creates a sale ledger
+func NewLedger(db *database.DB, j *journal.Journal) (*Ledger, error) {
+	if db == nil || j == nil {
+		return nil, fmt.Errorf("ledger requires a database and a journal")
+	}
+	return &Ledger{db: db, journal: j, crash: func(CrashPoint) {}}, nil
+}
+
+// CommitSale runs the commit protocol for a sale
+func (l *Ledger) CommitSale(ctx context.Context, sale *database.Sale) (*Result, error) {
+	if err := sale.Validate(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Retried sale: already committed, acknowledge without journaling again
+	if exists, err := l.db.SaleExists(sale.ID); err != nil {
+		return nil, err
+	} else if exists {
+		return &Result{SaleID: sale.ID, Duplicate: true}, nil
+	}
+
+	// Step 1: journal
+	body, err := json.Marshal(sale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sale: %w", err)
+	}
+	if _, err := l.journal.Append(journal.TypeIntent, sale.ID, body); err != nil {
+		return nil, err
+	}
+	l.crash(CrashAfterJournal)
+
+	// Step 2: SQLite transaction
+	applied, err := l.apply(ctx, sale)
+	if err != nil {
+		logging.Printf(ctx, "Sale %s was not committed: %v", sale.ID, err)
+		if _, abortErr := l.journal.Append(journal.TypeAbort, sale.ID, nil); abortErr != nil {
+			logging.Printf(ctx, "Warning: failed to journal abort of sale %s: %v", sale.ID, abortErr)
+		}
+		return nil, err
+	}
+	l.crash(CrashAfterCommit)
+
+	if l.journal.Len() >= checkpointThreshold {
+		if err := l.journal.Checkpoint(); err != nil {
+			logging.Printf(ctx, "Warning: journal checkpoint failed: %v", err)
+		}
+	}
+
+	// Step 3: ack
+	return &Result{SaleID: sale.ID, Duplicate: !applied}, nil
+}
+
+// Rekey switches the journal to a rotated server key. Commits are
+// serialised, so with the lock held every journaled intent is already
+// committed or aborted and the journal can be emptied.
+func (l *Ledger) Rekey(serverKey []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.journal.Rekey(serverKey)
+}
+
+// Recover replays journaled sales missing from the database and
+// checkpoints the journal. Run it at startup before accepting sales.
+func (l *Ledger) Recover(ctx context.Context) (*RecoveryStats, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := l.journal.Records()
+
+	aborted := make(map[string]bool)
+	for _, r := range records {
+		if r.Type == journal.TypeAbort {
+			aborted[r.Key] = true
+		}
+	}
+
+	stats := &RecoveryStats{}
+	for _, r := range records {
+		if r.Type != journal.TypeIntent {
+			continue
+		}
+		if aborted[r.Key] {
+			stats.Aborted++
+			continue
+		}
+
+		var sale database.Sale
+		if err := json.Unmarshal(r.Body, &sale); err != nil {
+			return stats, fmt.Errorf("failed to parse journaled sale %s: %w", r.Key, err)
+		}
+
+		applied, err := l.apply(ctx, &sale)
+		if err != nil {
+			return stats, fmt.Errorf("failed to replay sale %s: %w", r.Key, err)
+		}
+		if applied {
+			stats.Replayed++
+		} else {
+			stats.Committed++
+		}
+	}
+
+	if err := l.journal.Checkpoint(); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// apply runs step 2 of the protocol
+func (l *Ledger) apply(ctx context.Context, sale *database.Sale) (bool, error) {
+	var applied bool
+	err := l.db.TransactionContext(ctx, func(tx *sql.Tx) error {
+		var err error
+		if applied, err = l.db.ApplySale(tx, sale); err != nil {
+			return err
+		}
+		l.crash(CrashBeforeCommit)
+		return nil
+	})
+	return applied, err
+}
@@ -0,0 +1,194 @@
+package sales
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/journal"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+// crashSignal is panicked by the crash hook to simulate a process kill
+type crashSignal struct{}
+
+// terminal is one "process lifetime" over the same on-disk state
+type terminal struct {
+	db      *database.DB
+	journal *journal.Journal
+	ledger  *Ledger
+}
+
+func boot(t *testing.T, dir string, key []byte) *terminal {
+	db, err := database.New(&database.Config{ServerKey: key, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	encryption, _ := security.NewDatabaseEncryption(key)
+	j, err := journal.Open(filepath.Join(dir, "sales.journal"), encryption)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+
+	ledger, err := NewLedger(db, j)
+	if err != nil {
+		t.Fatalf("NewLedger failed: %v", err)
+	}
+
+	return &terminal{db: db, journal: j, ledger: ledger}
+}
+
+func (term *terminal) shutdown() {
+	term.journal.Close()
+	term.db.Close()
+}
+
+func testSale() *database.Sale {
+	return &database.Sale{
+		ID:         "sale-1",
+		TerminalID: "till-1",
+		Lines:      []database.SaleLine{{SKU: "MILK", Quantity: 2, Price: 990}},
+		Total:      1980,
+	}
+}
+
+func TestCommitSale(t *testing.T) {
+	key, _ := security.GenerateServerKey()
+	term := boot(t, t.TempDir(), key)
+	defer term.shutdown()
+
+	term.db.SetStockLevel("MILK", 10)
+
+	result, err := term.ledger.CommitSale(context.Background(), testSale())
+	if err != nil {
+		t.Fatalf("CommitSale failed: %v", err)
+	}
+	if result.Duplicate {
+		t.Error("First commit should not be a duplicate")
+	}
+
+	// Frontend retry with the same sale ID
+	result, err = term.ledger.CommitSale(context.Background(), testSale())
+	if err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if !result.Duplicate {
+		t.Error("Retry should be reported as duplicate")
+	}
+
+	if stock, _ := term.db.GetStockLevel("MILK"); stock != 8 {
+		t.Errorf("Expected stock 8, got %d", stock)
+	}
+	if pending, _ := term.db.CountPendingOutbox(); pending != 1 {
+		t.Errorf("Expected 1 outbox entry for the sale, got %d", pending)
+	}
+}
+
+func TestCommitSale_CrashPoints(t *testing.T) {
+	tests := []struct {
+		point CrashPoint
+		acked bool // Whether the caller could have seen an ack (never, for a crash)
+	}{
+		{CrashAfterJournal, false},
+		{CrashBeforeCommit, false},
+		{CrashAfterCommit, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.point), func(t *testing.T) {
+			key, _ := security.GenerateServerKey()
+			dir := t.TempDir()
+
+			// First lifetime: crash at the given step
+			term := boot(t, dir, key)
+			term.db.SetStockLevel("MILK", 10)
+			term.ledger.crash = func(p CrashPoint) {
+				if p == tt.point {
+					panic(crashSignal{})
+				}
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Fatal("Expected simulated crash")
+					}
+				}()
+				term.ledger.CommitSale(context.Background(), testSale())
+			}()
+			term.shutdown()
+
+			// Second lifetime: recover, then the frontend retries the unacked sale
+			term = boot(t, dir, key)
+			defer term.shutdown()
+
+			if _, err := term.ledger.Recover(context.Background()); err != nil {
+				t.Fatalf("Recover failed: %v", err)
+			}
+			if _, err := term.ledger.CommitSale(context.Background(), testSale()); err != nil {
+				t.Fatalf("Retry after recovery failed: %v", err)
+			}
+
+			// Exactly one sale, counted once
+			if count, _ := term.db.CountSales(); count != 1 {
+				t.Errorf("Expected exactly 1 sale, got %d", count)
+			}
+			if stock, _ := term.db.GetStockLevel("MILK"); stock != 8 {
+				t.Errorf("Expected stock 8 (decremented once), got %d", stock)
+			}
+			if pending, _ := term.db.CountPendingOutbox(); pending != 1 {
+				t.Errorf("Expected 1 outbox entry, got %d", pending)
+			}
+			if term.journal.Len() != 0 {
+				t.Errorf("Expected journal to be checkpointed, got %d records", term.journal.Len())
+			}
+		})
+	}
+}
+
+func TestRecover_AcknowledgedSaleSurvivesLostCommit(t *testing.T) {
+	key, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+
+	// Simulate SQLite losing an acknowledged commit: the journal has the
+	// intent but the database never saw it
+	term := boot(t, dir, key)
+	body := `{"id":"sale-1","terminal_id":"till-1","lines":[{"sku":"MILK","quantity":2,"price":990}],"total":1980}`
+	if _, err := term.journal.Append(journal.TypeIntent, "sale-1", []byte(body)); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	term.shutdown()
+
+	term = boot(t, dir, key)
+	defer term.shutdown()
+
+	stats, err := term.ledger.Recover(context.Background())
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if stats.Replayed != 1 {
+		t.Errorf("Expected 1 replayed sale, got %+v", stats)
+	}
+	if exists, _ := term.db.SaleExists("sale-1"); !exists {
+		t.Error("Expected acknowledged sale to be recovered")
+	}
+}
+
+func TestRecover_SkipsAbortedSales(t *testing.T) {
+	key, _ := security.GenerateServerKey()
+	term := boot(t, t.TempDir(), key)
+	defer term.shutdown()
+
+	term.journal.Append(journal.TypeIntent, "sale-1", []byte(`{"id":"sale-1"}`))
+	term.journal.Append(journal.TypeAbort, "sale-1", nil)
+
+	stats, err := term.ledger.Recover(context.Background())
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if stats.Aborted != 1 || stats.Replayed != 0 {
+		t.Errorf("Unexpected recovery stats: %+v", stats)
+	}
+}
@@ -0,0 +1,214 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// configKeySize is the size of a keystore-generated config master key
+	configKeySize = 32
+
+	// minBuildKeyLength is the shortest accepted build-time key
+	minBuildKeyLength = 32
+
+	// configKeyValue names the config master key in the OS keystore
+	configKeyValue = "ConfigKey"
+)
+
+// buildConfigKey is the config master key injected at build time:
+//
+//	go build -ldflags "-X github.com/professor93/promo-pos/internal/security.buildConfigKey=$POS_CONFIG_KEY"
+//
+// When empty, a per-machine key is generated and kept in the OS keystore.
+var buildConfigKey string
+
+// legacyConfigKey is the key every config.enc was encrypted with before the
+// key was removed from source. It is only used to decrypt and migrate old
+// files; nothing is encrypted with it anymore.
+const legacyConfigKey = "YourSuperSecretHardcodedKeyHere-ChangeInProduction!"
+
+// The loaded keys are cached for later managers and wiped by
+// WipeConfigKeys
+var (
+	cachedConfigKey   *SecureBytes
+	cachedPreviousKey []*SecureBytes
+	configKeyMutex    sync.Mutex
+)
+
+// ErrBuildKeyTooShort is returned when the injected build key is too weak
+var ErrBuildKeyTooShort = errors.New("build-time config key is too short")
+
+// LoadConfigKeys returns the master key for config encryption, plus the
+// keys older releases may have used (newest first) so their files can be
+// migrated.
+//
+// Without a build-time key the master key is the OS keystore key, generated
+// on first run. With one, it is used as is, except where the keystore is
+// machine-protected (DPAPI on Windows): there the build key is bound to the
+// keystore key, so the binary and machine ID alone can't open config.enc.
+//
+// The keys returned are copies of the cached ones, which the caller must
+// wipe once it has derived what it needs from them.
+func LoadConfigKeys() (current []byte, previous [][]byte, err error) {
+	configKeyMutex.Lock()
+	defer configKeyMutex.Unlock()
+
+	if cachedConfigKey == nil {
+		current, previous, err := loadConfigKeys()
+		if err != nil {
+			return nil, nil, err
+		}
+		cachedConfigKey = NewSecureBytes(current)
+		Wipe(current)
+		for _, key := range previous {
+			cachedPreviousKey = append(cachedPreviousKey, NewSecureBytes(key))
+			Wipe(key)
+		}
+	}
+
+	current = cachedConfigKey.Copy()
+	for _, key := range cachedPreviousKey {
+		previous = append(previous, key.Copy())
+	}
+	return current, previous, nil
+}
+
+// loadConfigKeys derives the config keys LoadConfigKeys caches
+func loadConfigKeys() (current []byte, previous [][]byte, err error) {
+	previous = [][]byte{LegacyConfigKey()}
+
+	if buildConfigKey != "" {
+		if len(buildConfigKey) < minBuildKeyLength {
+			return nil, nil, fmt.Errorf("%w: need at least %d characters", ErrBuildKeyTooShort, minBuildKeyLength)
+		}
+
+		current = []byte(buildConfigKey)
+		if machineProtectionAvailable() {
+			keystoreKey, err := loadOrCreateKeystoreKey()
+			if err != nil {
+				return nil, nil, err
+			}
+			mac := hmac.New(sha256.New, current)
+			mac.Write(keystoreKey)
+			Wipe(keystoreKey)
+			previous = append([][]byte{current}, previous...)
+			current = mac.Sum(nil)
+		}
+	} else {
+		if current, err = loadOrCreateKeystoreKey(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return current, previous, nil
+}
+
+// WipeConfigKeys wipes the cached config keys; the next LoadConfigKeys
+// reads them again
+func WipeConfigKeys() {
+	configKeyMutex.Lock()
+	defer configKeyMutex.Unlock()
+	wipeConfigKeys()
+}
+
+// wipeConfigKeys wipes the cached config keys, with configKeyMutex held
+func wipeConfigKeys() {
+	if cachedConfigKey != nil {
+		cachedConfigKey.Wipe()
+	}
+	for _, key := range cachedPreviousKey {
+		key.Wipe()
+	}
+	cachedConfigKey, cachedPreviousKey = nil, nil
+}
+
+// loadOrCreateKeystoreKey reads the machine-protected keystore key,
+// generating and storing one on first run
+func loadOrCreateKeystoreKey() ([]byte, error) {
+	if encoded, err := readConfigKeyFromKeystore(); err == nil && encoded != "" {
+		blob, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("config key in keystore is corrupt")
+		}
+
+		key, err := unprotectForMachine(blob)
+		if err != nil && len(blob) == configKeySize {
+			// Stored by a release without machine protection: wrap it now
+			if err := storeKeystoreKey(blob); err != nil {
+				return nil, err
+			}
+			return blob, nil
+		}
+		if err != nil || len(key) != configKeySize {
+			return nil, fmt.Errorf("config key in keystore is corrupt")
+		}
+		return key, nil
+	}
+
+	key := make([]byte, configKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate config key: %w", err)
+	}
+
+	// Unlike the machine ID, a key that can't be persisted is fatal:
+	// the config would be unreadable on the next start
+	if err := storeKeystoreKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// storeKeystoreKey wraps key for this machine and writes it to the keystore
+func storeKeystoreKey(key []byte) error {
+	blob, err := protectForMachine(key)
+	if err != nil {
+		return fmt.Errorf("failed to protect config key: %w", err)
+	}
+	if err := saveConfigKeyToKeystore(base64.StdEncoding.EncodeToString(blob)); err != nil {
+		return fmt.Errorf("failed to store config key: %w", err)
+	}
+	return nil
+}
+
+// LegacyConfigKey returns the retired hard-coded key, for migrating config
+// files written by older releases
+func LegacyConfigKey() []byte {
+	return []byte(legacyConfigKey)
+}
+
+// ProtectForMachine wraps a secret so only this machine can unwrap it
+// (DPAPI LocalMachine scope on Windows). Elsewhere the data is returned
+// unchanged and protection relies on keystore file permissions.
+func ProtectForMachine(data []byte) ([]byte, error) {
+	return protectForMachine(data)
+}
+
+// UnprotectForMachine reverses ProtectForMachine
+func UnprotectForMachine(blob []byte) ([]byte, error) {
+	return unprotectForMachine(blob)
+}
+
+// MachineProtectionAvailable reports whether ProtectForMachine actually
+// encrypts on this platform
+func MachineProtectionAvailable() bool {
+	return machineProtectionAvailable()
+}
+
+// DeleteConfigKey removes the keystore config key and wipes the cache
+func DeleteConfigKey() error {
+	configKeyMutex.Lock()
+	defer configKeyMutex.Unlock()
+
+	if err := deleteConfigKeyFromKeystore(); err != nil {
+		return fmt.Errorf("failed to delete config key: %w", err)
+	}
+
+	wipeConfigKeys()
+	return nil
+}
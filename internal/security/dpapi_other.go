@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package security
+
+// protectForMachine is a pass-through where DPAPI is unavailable; secrets
+// rely on file permissions of the keystore instead
+func protectForMachine(data []byte) ([]byte, error) {
+	return append([]byte(nil), data...), nil
+}
+
+// unprotectForMachine is a pass-through where DPAPI is unavailable
+func unprotectForMachine(blob []byte) ([]byte, error) {
+	return append([]byte(nil), blob...), nil
+}
+
+// machineProtectionAvailable reports whether DPAPI wrapping is in effect
+func machineProtectionAvailable() bool {
+	return false
+}
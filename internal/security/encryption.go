@@ -1,14 +1,21 @@
 package security
 
 import (
+	"bytes"
+	"compress/flate"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 
+	"github.com/professor93/promo-pos/pkg/constants"
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/sha3"
@@ -23,11 +30,49 @@ const (
 
 	// ChaCha20-Poly1305 requires 32 byte key
 	chacha20KeySize = 32
+
+	// compressedHeader prefixes database ciphertexts whose plaintext was
+	// deflated before sealing; it is also bound as additional data so it
+	// cannot be stripped or added
+	compressedHeader = "z:"
 )
 
-// IMPORTANT: This hard-coded key should be replaced with an environment variable
-// or secure key management system in production builds
-const hardcodedEncryptionKey = "YourSuperSecretHardcodedKeyHere-ChangeInProduction!"
+// databaseCipher is an AEAD database values can be sealed with
+type databaseCipher struct {
+	// header tags ciphertexts sealed with this cipher, ahead of any other
+	// header; bound as additional data like compressedHeader
+	header string
+	new    func(key []byte) (cipher.AEAD, error)
+}
+
+// databaseCiphers maps db_cipher names to their ciphers. ChaCha20-Poly1305
+// ciphertexts are untagged, so everything written before ciphers were
+// tagged still decrypts. XChaCha20-Poly1305's 24-byte random nonces stay
+// clear of collisions however many values one server key seals.
+var databaseCiphers = map[string]databaseCipher{
+	constants.DBCipherChaCha20:  {header: "", new: chacha20poly1305.New},
+	constants.DBCipherAES256GCM: {header: "a:", new: newAESGCM},
+	constants.DBCipherXChaCha20: {header: "x:", new: chacha20poly1305.NewX},
+}
+
+// defaultDatabaseCipher is the cipher new handlers seal with
+func defaultDatabaseCipher() string {
+	if FIPSRequired() {
+		return constants.DBCipherAES256GCM
+	}
+	return constants.DefaultDBCipher
+}
+
+// newAESGCM returns AES-256-GCM with nonces drawn inside the FIPS module
+// (NonceSize 0: the nonce is part of the sealed output, as with the
+// other ciphers)
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithRandomNonce(block)
+}
 
 var (
 	ErrInvalidCiphertext = errors.New("invalid ciphertext")
@@ -35,47 +80,73 @@ var (
 )
 
 // ConfigEncryption handles TYPE 1 encryption (Medium Importance)
-// Uses AES-256-GCM with hard-coded key + machine ID as salt
+// Uses AES-256-GCM with the config master key + machine ID as salt
 type ConfigEncryption struct {
 	machineID string
-	key       []byte
+	key       *SecureBytes
 }
 
 // NewConfigEncryption creates a new config encryption handler
-// It derives the encryption key from the hard-coded key and machine ID salt
-func NewConfigEncryption(machineID string) (*ConfigEncryption, error) {
+// It derives the encryption key from masterKey (see LoadConfigKey) and machine ID salt
+func NewConfigEncryption(masterKey []byte, machineID string) (*ConfigEncryption, error) {
 	if machineID == "" {
 		return nil, errors.New("machine ID cannot be empty")
 	}
+	if len(masterKey) == 0 {
+		return nil, fmt.Errorf("%w: config master key cannot be empty", ErrInvalidKey)
+	}
 
-	// Derive key using PBKDF2 with hard-coded key and machine ID as salt
+	// Derive key using PBKDF2 with the master key and machine ID as salt
 	key := pbkdf2.Key(
-		[]byte(hardcodedEncryptionKey),
+		masterKey,
 		[]byte(machineID),
 		pbkdf2Iterations,
 		aes256KeySize,
 		sha3.New256,
 	)
 
+	defer Wipe(key)
+
 	return &ConfigEncryption{
 		machineID: machineID,
-		key:       key,
+		key:       NewSecureBytes(key),
 	}, nil
 }
 
+// Close wipes the derived key; the handler cannot be used afterwards
+func (ce *ConfigEncryption) Close() {
+	ce.key.Wipe()
+}
+
+// gcm returns an AES-256-GCM AEAD for the derived key
+func (ce *ConfigEncryption) gcm() (cipher.AEAD, error) {
+	var gcm cipher.AEAD
+	err := ce.key.Use(func(key []byte) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("failed to create cipher: %w", err)
+		}
+		gcm, err = cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("failed to create GCM: %w", err)
+		}
+		return nil
+	})
+	return gcm, err
+}
+
 // Encrypt encrypts data using AES-256-GCM
 // Returns base64-encoded ciphertext
 func (ce *ConfigEncryption) Encrypt(plaintext []byte) (string, error) {
-	// Create AES cipher
-	block, err := aes.NewCipher(ce.key)
-	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
-	}
+	return ce.EncryptWithAAD(plaintext, nil)
+}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+// EncryptWithAAD encrypts data bound to additional data; decryption needs
+// the same aad, so the ciphertext is useless in any other context
+func (ce *ConfigEncryption) EncryptWithAAD(plaintext, aad []byte) (string, error) {
+	gcm, err := ce.gcm()
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return "", err
 	}
 
 	// Generate nonce
@@ -85,7 +156,7 @@ func (ce *ConfigEncryption) Encrypt(plaintext []byte) (string, error) {
 	}
 
 	// Encrypt and seal
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, aad)
 
 	// Return base64 encoded
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
@@ -93,22 +164,20 @@ func (ce *ConfigEncryption) Encrypt(plaintext []byte) (string, error) {
 
 // Decrypt decrypts base64-encoded ciphertext using AES-256-GCM
 func (ce *ConfigEncryption) Decrypt(ciphertextB64 string) ([]byte, error) {
+	return ce.DecryptWithAAD(ciphertextB64, nil)
+}
+
+// DecryptWithAAD decrypts a ciphertext made by EncryptWithAAD with aad
+func (ce *ConfigEncryption) DecryptWithAAD(ciphertextB64 string, aad []byte) ([]byte, error) {
 	// Decode base64
 	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	// Create AES cipher
-	block, err := aes.NewCipher(ce.key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
-
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := ce.gcm()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
 
 	// Check minimum length
@@ -121,7 +190,7 @@ func (ce *ConfigEncryption) Decrypt(ciphertextB64 string) ([]byte, error) {
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 
 	// Decrypt and open
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt: %w", err)
 	}
@@ -130,9 +199,18 @@ func (ce *ConfigEncryption) Decrypt(ciphertextB64 string) ([]byte, error) {
 }
 
 // DatabaseEncryption handles TYPE 2 encryption (Very Important)
-// Uses ChaCha20-Poly1305 with server key ONLY
+// Uses ChaCha20-Poly1305 (or AES-256-GCM/XChaCha20-Poly1305, see SetCipher)
+// with server key ONLY
 type DatabaseEncryption struct {
-	serverKey []byte
+	mu        sync.RWMutex
+	serverKey *SecureBytes
+
+	// cipher names the databaseCiphers entry new values are sealed with
+	cipher string
+
+	// compressAbove is the plaintext size above which values are deflated
+	// before encryption; 0 disables compression
+	compressAbove int
 }
 
 // NewDatabaseEncryption creates a new database encryption handler
@@ -143,17 +221,114 @@ func NewDatabaseEncryption(serverKey []byte) (*DatabaseEncryption, error) {
 	}
 
 	return &DatabaseEncryption{
-		serverKey: serverKey,
+		serverKey: NewSecureBytes(serverKey),
+		cipher:    defaultDatabaseCipher(),
 	}, nil
 }
 
+// SetKey switches to a new server key (after a key rotation) and wipes
+// the previous one. The handler keeps its own copy of serverKey.
+func (de *DatabaseEncryption) SetKey(serverKey []byte) error {
+	if len(serverKey) != chacha20KeySize {
+		return fmt.Errorf("%w: server key must be %d bytes", ErrInvalidKey, chacha20KeySize)
+	}
+
+	de.serverKey.Replace(serverKey)
+	return nil
+}
+
+// Close wipes the server key; the handler cannot be used afterwards
+func (de *DatabaseEncryption) Close() {
+	de.serverKey.Wipe()
+}
+
+// SetCompression compresses plaintexts larger than threshold bytes before
+// encryption (ciphertext does not compress, so this is the only chance);
+// 0 disables it. Values are decrypted correctly either way.
+func (de *DatabaseEncryption) SetCompression(threshold int) {
+	if threshold < 0 {
+		threshold = 0
+	}
+
+	de.mu.Lock()
+	defer de.mu.Unlock()
+	de.compressAbove = threshold
+}
+
+// CompressionThreshold returns the threshold set by SetCompression
+func (de *DatabaseEncryption) CompressionThreshold() int {
+	de.mu.RLock()
+	defer de.mu.RUnlock()
+	return de.compressAbove
+}
+
+// SetCipher seals new values with the named cipher (constants.DBCipher*).
+// Ciphertexts carry a tag naming their cipher, so values sealed with any
+// cipher keep decrypting. In FIPS mode (see FIPSRequired) only AES-256-GCM
+// is accepted.
+func (de *DatabaseEncryption) SetCipher(name string) error {
+	if _, ok := databaseCiphers[name]; !ok {
+		return fmt.Errorf("unknown database cipher %q", name)
+	}
+	if FIPSRequired() && name != constants.DBCipherAES256GCM {
+		return fmt.Errorf("database cipher %s is not allowed in FIPS mode", name)
+	}
+
+	de.mu.Lock()
+	defer de.mu.Unlock()
+	de.cipher = name
+	return nil
+}
+
+// Cipher returns the cipher new values are sealed with
+func (de *DatabaseEncryption) Cipher() string {
+	de.mu.RLock()
+	defer de.mu.RUnlock()
+	return de.cipher
+}
+
+// aead returns the named cipher's AEAD for the current server key
+func (de *DatabaseEncryption) aead(name string) (cipher.AEAD, error) {
+	var aead cipher.AEAD
+	err := de.serverKey.Use(func(key []byte) error {
+		var err error
+		aead, err = databaseCiphers[name].new(key)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		return nil
+	})
+	return aead, err
+}
+
 // Encrypt encrypts data using ChaCha20-Poly1305
 // Returns base64-encoded ciphertext
 func (de *DatabaseEncryption) Encrypt(plaintext []byte) (string, error) {
-	// Create ChaCha20-Poly1305 AEAD
-	aead, err := chacha20poly1305.New(de.serverKey)
+	return de.EncryptWithAAD(plaintext, nil)
+}
+
+// EncryptWithAAD encrypts data bound to additional data (e.g. the table and
+// key of the row holding it); decryption needs the same aad, so the
+// ciphertext cannot be replayed into another row. Plaintexts above the
+// compression threshold are deflated first when that makes them smaller.
+func (de *DatabaseEncryption) EncryptWithAAD(plaintext, aad []byte) (string, error) {
+	name := de.Cipher()
+	header := databaseCiphers[name].header
+	if threshold := de.CompressionThreshold(); threshold > 0 && len(plaintext) > threshold {
+		compressed, err := deflate(plaintext)
+		if err != nil {
+			return "", err
+		}
+		if len(compressed) < len(plaintext) {
+			plaintext = compressed
+			header += compressedHeader
+		}
+	}
+	aad = append([]byte(header), aad...)
+
+	aead, err := de.aead(name)
 	if err != nil {
-		return "", fmt.Errorf("failed to create chacha20poly1305: %w", err)
+		return "", err
 	}
 
 	// Generate nonce
@@ -163,24 +338,45 @@ func (de *DatabaseEncryption) Encrypt(plaintext []byte) (string, error) {
 	}
 
 	// Encrypt and seal
-	ciphertext := aead.Seal(nonce, nonce, plaintext, nil)
+	ciphertext := aead.Seal(nonce, nonce, plaintext, aad)
 
-	// Return base64 encoded
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	// Return base64 encoded, behind the format headers if any
+	return header + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decrypts base64-encoded ciphertext using ChaCha20-Poly1305
+// Decrypt decrypts base64-encoded ciphertext with the cipher it names
 func (de *DatabaseEncryption) Decrypt(ciphertextB64 string) ([]byte, error) {
+	return de.DecryptWithAAD(ciphertextB64, nil)
+}
+
+// DecryptWithAAD decrypts a ciphertext made by EncryptWithAAD with aad
+func (de *DatabaseEncryption) DecryptWithAAD(ciphertextB64 string, aad []byte) ([]byte, error) {
+	// Headers are not base64, so they never clash with a plain ciphertext
+	name, header := constants.DBCipherChaCha20, ""
+	for cipherName, c := range databaseCiphers {
+		if c.header != "" && strings.HasPrefix(ciphertextB64, c.header) {
+			name, header = cipherName, c.header
+			break
+		}
+	}
+	ciphertextB64 = strings.TrimPrefix(ciphertextB64, header)
+
+	compressed := strings.HasPrefix(ciphertextB64, compressedHeader)
+	if compressed {
+		ciphertextB64 = strings.TrimPrefix(ciphertextB64, compressedHeader)
+		header += compressedHeader
+	}
+	aad = append([]byte(header), aad...)
+
 	// Decode base64
 	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	// Create ChaCha20-Poly1305 AEAD
-	aead, err := chacha20poly1305.New(de.serverKey)
+	aead, err := de.aead(name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chacha20poly1305: %w", err)
+		return nil, err
 	}
 
 	// Check minimum length
@@ -193,11 +389,39 @@ func (de *DatabaseEncryption) Decrypt(ciphertextB64 string) ([]byte, error) {
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 
 	// Decrypt and open
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt: %w", err)
 	}
 
+	if compressed {
+		return inflate(plaintext)
+	}
+	return plaintext, nil
+}
+
+// deflate compresses data with DEFLATE
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// inflate reverses deflate
+func inflate(data []byte) ([]byte, error) {
+	plaintext, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
 	return plaintext, nil
 }
 
@@ -211,6 +435,14 @@ func GenerateServerKey() ([]byte, error) {
 	return key, nil
 }
 
+// DeriveSubkey derives an independent 32-byte key for a specific purpose
+// (e.g. signing) from a master key, so one key is never used for two jobs
+func DeriveSubkey(masterKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
 // ServerKeyToBase64 converts a server key to base64 string for transmission
 func ServerKeyToBase64(key []byte) string {
 	return base64.StdEncoding.EncodeToString(key)
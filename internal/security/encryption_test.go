@@ -2,14 +2,20 @@ package security
 
 import (
 	"bytes"
+	"encoding/base64"
+	"strings"
 	"testing"
+
+	"github.com/professor93/promo-pos/pkg/constants"
 )
 
+var testConfigKey = []byte("test-config-master-key-0123456789abcdef")
+
 func TestConfigEncryption_EncryptDecrypt(t *testing.T) {
 	machineID := "test-machine-id-12345"
 
 	// Create config encryption
-	ce, err := NewConfigEncryption(machineID)
+	ce, err := NewConfigEncryption(testConfigKey, machineID)
 	if err != nil {
 		t.Fatalf("Failed to create config encryption: %v", err)
 	}
@@ -56,7 +62,7 @@ func TestConfigEncryption_DifferentMachineIDs(t *testing.T) {
 	plaintext := []byte("sensitive config data")
 
 	// Encrypt with first machine ID
-	ce1, err := NewConfigEncryption("machine-1")
+	ce1, err := NewConfigEncryption(testConfigKey, "machine-1")
 	if err != nil {
 		t.Fatalf("Failed to create config encryption 1: %v", err)
 	}
@@ -67,7 +73,7 @@ func TestConfigEncryption_DifferentMachineIDs(t *testing.T) {
 	}
 
 	// Try to decrypt with different machine ID
-	ce2, err := NewConfigEncryption("machine-2")
+	ce2, err := NewConfigEncryption(testConfigKey, "machine-2")
 	if err != nil {
 		t.Fatalf("Failed to create config encryption 2: %v", err)
 	}
@@ -79,7 +85,7 @@ func TestConfigEncryption_DifferentMachineIDs(t *testing.T) {
 }
 
 func TestConfigEncryption_InvalidInput(t *testing.T) {
-	ce, err := NewConfigEncryption("test-machine")
+	ce, err := NewConfigEncryption(testConfigKey, "test-machine")
 	if err != nil {
 		t.Fatalf("Failed to create config encryption: %v", err)
 	}
@@ -104,6 +110,50 @@ func TestConfigEncryption_InvalidInput(t *testing.T) {
 	}
 }
 
+func TestConfigEncryption_DifferentMasterKeys(t *testing.T) {
+	current, err := NewConfigEncryption(testConfigKey, "machine-1")
+	if err != nil {
+		t.Fatalf("Failed to create config encryption: %v", err)
+	}
+	legacy, err := NewConfigEncryption(LegacyConfigKey(), "machine-1")
+	if err != nil {
+		t.Fatalf("Failed to create legacy config encryption: %v", err)
+	}
+
+	encrypted, err := legacy.Encrypt([]byte("old config"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	if _, err := current.Decrypt(encrypted); err == nil {
+		t.Error("Expected legacy ciphertext to be unreadable with the new key")
+	}
+
+	if _, err := NewConfigEncryption(nil, "machine-1"); err == nil {
+		t.Error("Expected empty master key to be rejected")
+	}
+}
+
+func TestProtectForMachine_RoundTrip(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	blob, err := ProtectForMachine(secret)
+	if err != nil {
+		t.Fatalf("ProtectForMachine failed: %v", err)
+	}
+	if MachineProtectionAvailable() && bytes.Equal(blob, secret) {
+		t.Error("Expected protected blob to differ from the secret")
+	}
+
+	unwrapped, err := UnprotectForMachine(blob)
+	if err != nil {
+		t.Fatalf("UnprotectForMachine failed: %v", err)
+	}
+	if !bytes.Equal(unwrapped, secret) {
+		t.Error("Unwrapped secret does not match")
+	}
+}
+
 func TestDatabaseEncryption_EncryptDecrypt(t *testing.T) {
 	// Generate a server key
 	serverKey, err := GenerateServerKey()
@@ -178,6 +228,76 @@ func TestDatabaseEncryption_DifferentKeys(t *testing.T) {
 	}
 }
 
+func TestDatabaseEncryption_AAD(t *testing.T) {
+	serverKey, _ := GenerateServerKey()
+	de, _ := NewDatabaseEncryption(serverKey)
+
+	ciphertext, err := de.EncryptWithAAD([]byte("secret"), []byte("settings/a"))
+	if err != nil {
+		t.Fatalf("EncryptWithAAD failed: %v", err)
+	}
+
+	plaintext, err := de.DecryptWithAAD(ciphertext, []byte("settings/a"))
+	if err != nil || string(plaintext) != "secret" {
+		t.Errorf("DecryptWithAAD failed: %q, %v", plaintext, err)
+	}
+
+	// Replayed into another row, or read without context, it must not open
+	if _, err := de.DecryptWithAAD(ciphertext, []byte("settings/b")); err == nil {
+		t.Error("Expected decryption with a different AAD to fail")
+	}
+	if _, err := de.Decrypt(ciphertext); err == nil {
+		t.Error("Expected decryption without AAD to fail")
+	}
+}
+
+func TestDatabaseEncryption_Compression(t *testing.T) {
+	serverKey, _ := GenerateServerKey()
+	de, _ := NewDatabaseEncryption(serverKey)
+	large := bytes.Repeat([]byte(`{"line":"receipt template"}`), 200)
+
+	plain, _ := de.EncryptWithAAD(large, []byte("settings/t"))
+
+	de.SetCompression(1024)
+	small, _ := de.EncryptWithAAD([]byte("short"), []byte("settings/s"))
+	if strings.HasPrefix(small, compressedHeader) {
+		t.Error("Expected a value under the threshold to stay uncompressed")
+	}
+	compressed, err := de.EncryptWithAAD(large, []byte("settings/t"))
+	if err != nil {
+		t.Fatalf("EncryptWithAAD failed: %v", err)
+	}
+	if !strings.HasPrefix(compressed, compressedHeader) || len(compressed) >= len(plain)/2 {
+		t.Errorf("Expected a compressed ciphertext, got %d bytes (uncompressed %d)", len(compressed), len(plain))
+	}
+
+	// Both formats open regardless of the current setting
+	de.SetCompression(0)
+	for _, ciphertext := range []string{plain, compressed} {
+		got, err := de.DecryptWithAAD(ciphertext, []byte("settings/t"))
+		if err != nil || !bytes.Equal(got, large) {
+			t.Errorf("DecryptWithAAD failed: %v", err)
+		}
+	}
+
+	// The header is authenticated: stripping it must fail
+	if _, err := de.DecryptWithAAD(strings.TrimPrefix(compressed, compressedHeader), []byte("settings/t")); err == nil {
+		t.Error("Expected decryption without the compression header to fail")
+	}
+}
+
+func TestConfigEncryption_AAD(t *testing.T) {
+	ce, _ := NewConfigEncryption(testConfigKey, "machine-1")
+
+	ciphertext, _ := ce.EncryptWithAAD([]byte("secret"), []byte("a"))
+	if plaintext, err := ce.DecryptWithAAD(ciphertext, []byte("a")); err != nil || string(plaintext) != "secret" {
+		t.Errorf("DecryptWithAAD failed: %q, %v", plaintext, err)
+	}
+	if _, err := ce.DecryptWithAAD(ciphertext, []byte("b")); err == nil {
+		t.Error("Expected decryption with a different AAD to fail")
+	}
+}
+
 func TestDatabaseEncryption_InvalidKeySize(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -268,7 +388,7 @@ func TestEncryptionSeparation(t *testing.T) {
 	plaintext := []byte("test data")
 
 	// Type 1: Config encryption
-	ce, err := NewConfigEncryption("machine-123")
+	ce, err := NewConfigEncryption(testConfigKey, "machine-123")
 	if err != nil {
 		t.Fatalf("Failed to create config encryption: %v", err)
 	}
@@ -309,7 +429,7 @@ func TestEncryptionSeparation(t *testing.T) {
 }
 
 func BenchmarkConfigEncryption(b *testing.B) {
-	ce, _ := NewConfigEncryption("benchmark-machine")
+	ce, _ := NewConfigEncryption(testConfigKey, "benchmark-machine")
 	plaintext := []byte("benchmark data for config encryption testing")
 
 	b.Run("Encrypt", func(b *testing.B) {
@@ -344,3 +464,66 @@ func BenchmarkDatabaseEncryption(b *testing.B) {
 		}
 	})
 }
+
+func TestDatabaseEncryption_CiphersInteroperate(t *testing.T) {
+	key, _ := GenerateServerKey()
+	de, err := NewDatabaseEncryption(key)
+	if err != nil {
+		t.Fatalf("NewDatabaseEncryption failed: %v", err)
+	}
+	de.SetCompression(16)
+
+	large := []byte(strings.Repeat("receipt line ", 20))
+	chachaSmall, _ := de.EncryptWithAAD([]byte("small"), []byte("settings/a"))
+	chachaLarge, _ := de.EncryptWithAAD(large, []byte("settings/b"))
+
+	if err := de.SetCipher(constants.DBCipherAES256GCM); err != nil {
+		t.Fatalf("SetCipher failed: %v", err)
+	}
+	aesSmall, _ := de.EncryptWithAAD([]byte("small"), []byte("settings/a"))
+	aesLarge, _ := de.EncryptWithAAD(large, []byte("settings/b"))
+	if !strings.HasPrefix(aesSmall, "a:") || !strings.HasPrefix(aesLarge, "a:z:") {
+		t.Fatalf("Expected AES ciphertexts to be tagged, got %.8s and %.8s", aesSmall, aesLarge)
+	}
+
+	// Either cipher's values decrypt whichever cipher is current
+	for _, c := range []struct {
+		ciphertext, aad, want string
+	}{
+		{chachaSmall, "settings/a", "small"},
+		{chachaLarge, "settings/b", string(large)},
+		{aesSmall, "settings/a", "small"},
+		{aesLarge, "settings/b", string(large)},
+	} {
+		got, err := de.DecryptWithAAD(c.ciphertext, []byte(c.aad))
+		if err != nil || string(got) != c.want {
+			t.Errorf("Decrypt %.8s returned %q (%v)", c.ciphertext, got, err)
+		}
+	}
+
+	// The tag is authenticated: stripping it fails
+	if _, err := de.DecryptWithAAD(strings.TrimPrefix(aesSmall, "a:"), []byte("settings/a")); err == nil {
+		t.Error("Expected an untagged AES ciphertext to fail")
+	}
+
+	if err := de.SetCipher(constants.DBCipherXChaCha20); err != nil {
+		t.Fatalf("SetCipher failed: %v", err)
+	}
+	xchacha, _ := de.EncryptWithAAD([]byte("small"), []byte("settings/a"))
+	if !strings.HasPrefix(xchacha, "x:") {
+		t.Fatalf("Expected an XChaCha20 ciphertext to be tagged, got %.8s", xchacha)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(xchacha, "x:"))
+	if len(raw) != 24+len("small")+16 {
+		t.Errorf("Expected a 24-byte nonce, got %d bytes in total", len(raw))
+	}
+	for _, ciphertext := range []string{chachaSmall, aesSmall, xchacha} {
+		if got, err := de.DecryptWithAAD(ciphertext, []byte("settings/a")); err != nil || string(got) != "small" {
+			t.Errorf("Decrypt %.8s returned %q (%v)", ciphertext, got, err)
+		}
+	}
+
+	if err := de.SetCipher("rot13"); err == nil {
+		t.Error("Expected an unknown cipher to be rejected")
+	}
+}
@@ -0,0 +1,11 @@
+package security
+
+import "crypto/fips140"
+
+// FIPSRequired reports whether only FIPS-validated algorithms may be used:
+// the binary was built with the fips tag, or the Go FIPS 140-3 module is
+// enabled (GOFIPS140 at build time, GODEBUG=fips140=on at run time).
+// Database encryption then uses AES-256-GCM whatever the config says.
+func FIPSRequired() bool {
+	return fipsBuild || fips140.Enabled()
+}
@@ -0,0 +1,7 @@
+//go:build fips
+// +build fips
+
+package security
+
+// fipsBuild forces FIPS mode in binaries built with -tags fips
+const fipsBuild = true
@@ -0,0 +1,160 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// ServerKeyName names the sealed database server key
+const ServerKeyName = "server_key"
+
+// NextServerKeyName names a rotated server key staged while the database
+// is re-encrypted; it replaces ServerKeyName once re-encryption commits
+const NextServerKeyName = "server_key_next"
+
+// ClientCertName names the sealed PEM bundle (certificate chain and private
+// key) a terminal presents for mutual TLS with the sync API
+const ClientCertName = "client_cert"
+
+// Key store backend names
+const (
+	BackendTPM     = "tpm"
+	BackendMachine = "machine" // DPAPI on Windows, permission-protected file elsewhere
+)
+
+var (
+	// ErrSecretNotFound is returned when no secret is sealed under a name
+	ErrSecretNotFound = errors.New("sealed secret not found")
+
+	// ErrTPMUnavailable is returned when a TPM is required but not present
+	ErrTPMUnavailable = errors.New("TPM not available")
+)
+
+var secretNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// KeyStore seals secrets to this machine. Sealed blobs live in a directory
+// but are useless without the machine: a TPM-sealed blob only unseals on the
+// TPM that created it, a DPAPI blob only on the Windows install that wrapped it.
+type KeyStore interface {
+	Seal(name string, secret []byte) error
+	Unseal(name string) ([]byte, error)
+	Delete(name string) error
+	Backend() string
+}
+
+// NewKeyStore opens the key store in dir for the requested mode
+// (constants.KeyStorage*). Auto mode uses the TPM when one answers and
+// falls back to machine protection otherwise.
+func NewKeyStore(dir, mode string) (KeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key store directory: %w", err)
+	}
+
+	switch mode {
+	case constants.KeyStorageFile:
+		return &machineKeyStore{dir: dir}, nil
+	case constants.KeyStorageTPM:
+		store, err := newTPMKeyStore(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTPMUnavailable, err)
+		}
+		return store, nil
+	case "", constants.KeyStorageAuto:
+		if store, err := newTPMKeyStore(dir); err == nil {
+			return store, nil
+		}
+		return &machineKeyStore{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown key storage mode: %s", mode)
+	}
+}
+
+// sealedPath returns the file holding a sealed secret
+func sealedPath(dir, name, ext string) (string, error) {
+	if !secretNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name: %q", name)
+	}
+	return filepath.Join(dir, name+ext), nil
+}
+
+// writeSealed atomically writes a sealed blob readable only by the service
+func writeSealed(path string, data []byte) error {
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write sealed secret: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to store sealed secret: %w", err)
+	}
+	return nil
+}
+
+// readSealed reads a sealed blob, mapping a missing file to ErrSecretNotFound
+func readSealed(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sealed secret: %w", err)
+	}
+	return data, nil
+}
+
+// machineKeyStore wraps secrets with ProtectForMachine
+type machineKeyStore struct {
+	dir string
+}
+
+// Seal stores secret wrapped for this machine
+func (s *machineKeyStore) Seal(name string, secret []byte) error {
+	path, err := sealedPath(s.dir, name, ".key")
+	if err != nil {
+		return err
+	}
+
+	blob, err := ProtectForMachine(secret)
+	if err != nil {
+		return fmt.Errorf("failed to protect %s: %w", name, err)
+	}
+	return writeSealed(path, blob)
+}
+
+// Unseal returns a secret stored with Seal
+func (s *machineKeyStore) Unseal(name string) ([]byte, error) {
+	path, err := sealedPath(s.dir, name, ".key")
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := readSealed(path)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := UnprotectForMachine(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unprotect %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// Delete securely removes a sealed secret
+func (s *machineKeyStore) Delete(name string) error {
+	path, err := sealedPath(s.dir, name, ".key")
+	if err != nil {
+		return err
+	}
+	return SecureDelete(path)
+}
+
+// Backend returns BackendMachine
+func (s *machineKeyStore) Backend() string {
+	return BackendMachine
+}
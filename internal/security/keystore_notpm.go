@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package security
+
+import "errors"
+
+// tpmKeyStore is not supported on this platform
+type tpmKeyStore = machineKeyStore
+
+// newTPMKeyStore always fails where go-tpm can't open a TPM
+func newTPMKeyStore(dir string) (*tpmKeyStore, error) {
+	return nil, errors.New("TPM support is not available on this platform")
+}
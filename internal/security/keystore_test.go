@@ -0,0 +1,66 @@
+package security
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestMachineKeyStore_SealUnseal(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewKeyStore(dir, constants.KeyStorageFile)
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+	if store.Backend() != BackendMachine {
+		t.Errorf("Expected machine backend, got %s", store.Backend())
+	}
+
+	if _, err := store.Unseal(ServerKeyName); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got %v", err)
+	}
+
+	key, _ := GenerateServerKey()
+	if err := store.Seal(ServerKeyName, key); err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, ServerKeyName+".key"))
+	if err != nil {
+		t.Fatalf("Sealed file missing: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected sealed file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	unsealed, err := store.Unseal(ServerKeyName)
+	if err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+	if !bytes.Equal(unsealed, key) {
+		t.Error("Unsealed key does not match")
+	}
+
+	if err := store.Delete(ServerKeyName); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Unseal(ServerKeyName); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound after delete, got %v", err)
+	}
+}
+
+func TestKeyStore_InvalidInput(t *testing.T) {
+	if _, err := NewKeyStore(t.TempDir(), "vault"); err == nil {
+		t.Error("Expected unknown mode to be rejected")
+	}
+
+	store, _ := NewKeyStore(t.TempDir(), constants.KeyStorageFile)
+	if err := store.Seal("../escape", []byte("x")); err == nil {
+		t.Error("Expected path-like secret name to be rejected")
+	}
+}
@@ -0,0 +1,158 @@
+//go:build linux || windows
+// +build linux windows
+
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// srkTemplate is the storage root key template. Primary keys are derived
+// deterministically from the owner seed, so the same SRK is recreated on
+// every boot without persisting a handle.
+var srkTemplate = tpm2.Public{
+	Type:    tpm2.AlgRSA,
+	NameAlg: tpm2.AlgSHA256,
+	Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin |
+		tpm2.FlagUserWithAuth | tpm2.FlagRestricted | tpm2.FlagDecrypt | tpm2.FlagNoDA,
+	RSAParameters: &tpm2.RSAParams{
+		Symmetric: &tpm2.SymScheme{Alg: tpm2.AlgAES, KeyBits: 128, Mode: tpm2.AlgCFB},
+		KeyBits:   2048,
+	},
+}
+
+// sealedTemplate describes a sealed data object bound to this TPM
+var sealedTemplate = tpm2.Public{
+	Type:       tpm2.AlgKeyedHash,
+	NameAlg:    tpm2.AlgSHA256,
+	Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagUserWithAuth,
+	KeyedHashParameters: &tpm2.KeyedHashParams{
+		Alg: tpm2.AlgNull,
+	},
+}
+
+// tpmBlob is the on-disk form of a sealed object
+type tpmBlob struct {
+	Public  []byte `json:"public"`
+	Private []byte `json:"private"`
+}
+
+// tpmKeyStore seals secrets under the TPM storage root key
+type tpmKeyStore struct {
+	dir string
+	mu  sync.Mutex // The TPM resource manager serialises anyway; keep one session at a time
+}
+
+// newTPMKeyStore opens the TPM once to confirm it answers
+func newTPMKeyStore(dir string) (*tpmKeyStore, error) {
+	store := &tpmKeyStore{dir: dir}
+	err := store.withSRK(func(io.ReadWriter, tpmutil.Handle) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// withSRK opens the TPM, creates the SRK and runs fn with it
+func (s *tpmKeyStore) withSRK(fn func(rw io.ReadWriter, srk tpmutil.Handle) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rw, err := tpm2.OpenTPM()
+	if err != nil {
+		return fmt.Errorf("failed to open TPM: %w", err)
+	}
+	defer rw.Close()
+
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to create TPM storage key: %w", err)
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	return fn(rw, srk)
+}
+
+// Seal seals secret to this TPM
+func (s *tpmKeyStore) Seal(name string, secret []byte) error {
+	path, err := sealedPath(s.dir, name, ".tpm")
+	if err != nil {
+		return err
+	}
+
+	var blob tpmBlob
+	err = s.withSRK(func(rw io.ReadWriter, srk tpmutil.Handle) error {
+		var err error
+		blob.Private, blob.Public, _, _, _, err = tpm2.CreateKeyWithSensitive(rw, srk, tpm2.PCRSelection{}, "", "", sealedTemplate, secret)
+		if err != nil {
+			return fmt.Errorf("failed to seal %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(blob)
+	if err != nil {
+		return fmt.Errorf("failed to encode sealed %s: %w", name, err)
+	}
+	return writeSealed(path, data)
+}
+
+// Unseal loads a sealed object into the TPM and unseals it
+func (s *tpmKeyStore) Unseal(name string) ([]byte, error) {
+	path, err := sealedPath(s.dir, name, ".tpm")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readSealed(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var blob tpmBlob
+	if err := json.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode sealed %s: %w", name, err)
+	}
+
+	var secret []byte
+	err = s.withSRK(func(rw io.ReadWriter, srk tpmutil.Handle) error {
+		handle, _, err := tpm2.Load(rw, srk, "", blob.Public, blob.Private)
+		if err != nil {
+			return fmt.Errorf("failed to load sealed %s: %w", name, err)
+		}
+		defer tpm2.FlushContext(rw, handle)
+
+		if secret, err = tpm2.Unseal(rw, handle, ""); err != nil {
+			return fmt.Errorf("failed to unseal %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Delete removes a sealed object. The blob is worthless off this TPM, but
+// it is overwritten anyway so a wiped terminal leaves nothing behind.
+func (s *tpmKeyStore) Delete(name string) error {
+	path, err := sealedPath(s.dir, name, ".tpm")
+	if err != nil {
+		return err
+	}
+	return SecureDelete(path)
+}
+
+// Backend returns BackendTPM
+func (s *tpmKeyStore) Backend() string {
+	return BackendTPM
+}
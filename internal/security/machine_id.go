@@ -1,19 +1,39 @@
 package security
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net"
 	"os"
+	"strings"
 	"sync"
 )
 
 const (
-	registryPath = `SOFTWARE\POSService` // Windows only
-	machineIDKey = "MachineID"
+	registryPath          = `SOFTWARE\POSService` // Windows only
+	machineIDKey          = "MachineID"
+	machineSaltKey        = "MachineSalt"        // Random component of the machine ID
+	machineFingerprintKey = "MachineFingerprint" // What the hardware hashed to when the ID was last confirmed
 )
 
+// placeholderHardwareIDs are values firmware ships instead of a real serial
+// or UUID; they identify nothing and are skipped
+var placeholderHardwareIDs = map[string]bool{
+	"none":                                 true,
+	"default string":                       true,
+	"to be filled by o.e.m.":               true,
+	"system serial number":                 true,
+	"base board serial number":             true,
+	"not applicable":                       true,
+	"not specified":                        true,
+	"0":                                    true,
+	"00000000-0000-0000-0000-000000000000": true,
+	"ffffffff-ffff-ffff-ffff-ffffffffffff": true,
+	"03000200-0400-0500-0006-000700080009": true, // common on unbranded boards
+}
+
 var (
 	cachedMachineID string
 	machineIDMutex  sync.RWMutex
@@ -62,26 +82,114 @@ func GetMachineID() (string, error) {
 	return machineID, nil
 }
 
+// DeleteMachineID removes the persisted machine ID and clears the cache.
+// Config encrypted with the old ID becomes unreadable, which is the point
+// when a terminal is decommissioned or transferred.
+func DeleteMachineID() error {
+	machineIDMutex.Lock()
+	defer machineIDMutex.Unlock()
+
+	if err := deleteMachineIDFromRegistry(); err != nil {
+		return fmt.Errorf("failed to delete machine ID: %w", err)
+	}
+	for _, name := range []string{machineSaltKey, machineFingerprintKey} {
+		if err := deleteMachineValue(name); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", name, err)
+		}
+	}
+
+	cachedMachineID = ""
+	return nil
+}
+
+// MachineIDCheck compares the machine ID in use with the current hardware
+type MachineIDCheck struct {
+	MachineID   string // The stored machine ID, which config.enc is bound to
+	Fingerprint string // What the hardware hashes to now; the ID a migration moves to
+	Changed     bool   // The hardware changed since the ID was last confirmed
+}
+
+// CheckMachineID detects hardware changes since the machine ID was last
+// confirmed. The first check of an ID only records the fingerprint, since
+// IDs generated by older releases hash other sources.
+func CheckMachineID() (*MachineIDCheck, error) {
+	machineID, err := GetMachineID()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := generateMachineID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hardware fingerprint: %w", err)
+	}
+
+	check := &MachineIDCheck{MachineID: machineID, Fingerprint: fingerprint}
+	confirmed, err := readMachineValue(machineFingerprintKey)
+	if err != nil || confirmed == "" {
+		if err := saveMachineValue(machineFingerprintKey, fingerprint); err != nil {
+			return nil, fmt.Errorf("failed to save hardware fingerprint: %w", err)
+		}
+		return check, nil
+	}
+
+	check.Changed = confirmed != fingerprint
+	return check, nil
+}
+
+// ReplaceMachineID stores machineID in place of the current one and
+// confirms the current hardware. Only a completed migration calls it:
+// config.enc must already be encrypted for the new ID.
+func ReplaceMachineID(machineID string) error {
+	fingerprint, err := generateMachineID()
+	if err != nil {
+		return fmt.Errorf("failed to compute hardware fingerprint: %w", err)
+	}
+
+	machineIDMutex.Lock()
+	defer machineIDMutex.Unlock()
+
+	if err := saveMachineIDToRegistry(machineID); err != nil {
+		return fmt.Errorf("failed to save machine ID: %w", err)
+	}
+	if err := saveMachineValue(machineFingerprintKey, fingerprint); err != nil {
+		return fmt.Errorf("failed to save hardware fingerprint: %w", err)
+	}
+
+	cachedMachineID = machineID
+	return nil
+}
+
 // generateMachineID creates a unique machine identifier
 func generateMachineID() (string, error) {
 	var data []string
 
-	// Get platform-specific data (implemented in machine_id_windows.go and machine_id_linux.go)
+	// Get platform-specific data: SMBIOS UUID, board serial, system volume
+	// serial, OS install ID and CPU (machine_id_windows.go, machine_id_linux.go)
 	platformData, err := platformSpecificID()
 	if err == nil {
 		data = append(data, platformData...)
 	}
 
-	// MAC Address of primary network interface (cross-platform)
-	macAddr, err := getPrimaryMACAddress()
-	if err == nil {
-		data = append(data, macAddr)
+	// NICs get replaced and VMs clone MAC addresses, so the MAC address and
+	// hostname are only used when no platform identifier could be read
+	if len(data) == 0 {
+		macAddr, err := getPrimaryMACAddress()
+		if err == nil {
+			data = append(data, macAddr)
+		}
+
+		hostname, err := os.Hostname()
+		if err == nil {
+			data = append(data, hostname)
+		}
 	}
 
-	// Hostname (cross-platform)
-	hostname, err := os.Hostname()
+	// A persisted random component keeps machines apart whose hardware
+	// reports the same (or no) identifiers
+	salt, err := machineSalt()
 	if err == nil {
-		data = append(data, hostname)
+		data = append(data, salt)
+	} else {
+		fmt.Printf("Warning: %v\n", err)
 	}
 
 	if len(data) == 0 {
@@ -98,6 +206,34 @@ func generateMachineID() (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
+// machineSalt returns the random component of the machine ID, creating and
+// persisting it on first use
+func machineSalt() (string, error) {
+	if salt, err := readMachineValue(machineSaltKey); err == nil && salt != "" {
+		return salt, nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate machine salt: %w", err)
+	}
+	salt := hex.EncodeToString(buf)
+	if err := saveMachineValue(machineSaltKey, salt); err != nil {
+		return "", fmt.Errorf("failed to save machine salt: %w", err)
+	}
+	return salt, nil
+}
+
+// hardwareID cleans up a serial or UUID read from firmware, returning ""
+// for blanks and vendor placeholders
+func hardwareID(value string) string {
+	value = strings.TrimSpace(strings.Trim(value, "\x00"))
+	if placeholderHardwareIDs[strings.ToLower(value)] {
+		return ""
+	}
+	return value
+}
+
 // getPrimaryMACAddress gets the MAC address of the first non-loopback interface
 func getPrimaryMACAddress() (string, error) {
 	interfaces, err := net.Interfaces()
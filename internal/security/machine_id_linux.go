@@ -4,11 +4,30 @@
 package security
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+// machineStateDir holds the machine ID and the values stored with it on Linux
+const machineStateDir = "/var/lib/posservice"
+
+// machineValuePath returns the file holding a value stored with the machine
+// ID: MachineSalt is machine_salt
+func machineValuePath(name string) string {
+	file := strings.ToLower(strings.TrimPrefix(name, "Machine"))
+	return filepath.Join(machineStateDir, "machine_"+file)
+}
+
+// DMI attributes exported by the kernel; product_uuid and board_serial are
+// readable by root only
+const (
+	dmiProductUUIDPath = "/sys/class/dmi/id/product_uuid"
+	dmiBoardSerialPath = "/sys/class/dmi/id/board_serial"
+)
+
 // getWindowsProductID is not available on Linux
 func getWindowsProductID() (string, error) {
 	return "", fmt.Errorf("not on Windows")
@@ -82,6 +101,127 @@ func saveMachineIDToRegistry(machineID string) error {
 	return nil
 }
 
+// deleteMachineIDFromRegistry securely removes the machine ID file on Linux
+func deleteMachineIDFromRegistry() error {
+	return SecureDelete("/var/lib/posservice/machine_id")
+}
+
+// readMachineValue reads a value stored with the machine ID on Linux
+func readMachineValue(name string) (string, error) {
+	data, err := os.ReadFile(machineValuePath(name))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveMachineValue stores a value with the machine ID in a root-only file on Linux
+func saveMachineValue(name, value string) error {
+	if err := os.MkdirAll(machineStateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(machineValuePath(name), []byte(value), 0600); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", name, err)
+	}
+
+	return nil
+}
+
+// deleteMachineValue securely removes a value stored with the machine ID on Linux
+func deleteMachineValue(name string) error {
+	return SecureDelete(machineValuePath(name))
+}
+
+// readDMI reads a DMI attribute, skipping placeholders
+func readDMI(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	value := hardwareID(string(data))
+	if value == "" {
+		return "", fmt.Errorf("no identifier in %s", path)
+	}
+	return strings.ToLower(value), nil
+}
+
+// getRootVolumeUUID returns the filesystem UUID of the device mounted at /
+func getRootVolumeUUID() (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// mountinfo: ID parent major:minor root mountpoint options... - fstype source superoptions
+	var device string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[4] != "/" {
+			continue
+		}
+		for i, field := range fields {
+			if field == "-" && i+2 < len(fields) {
+				device = fields[i+2]
+			}
+		}
+	}
+	if !strings.HasPrefix(device, "/dev/") {
+		return "", fmt.Errorf("root filesystem is not on a block device")
+	}
+	device, err = filepath.EvalSymlinks(device)
+	if err != nil {
+		return "", err
+	}
+
+	links, err := filepath.Glob("/dev/disk/by-uuid/*")
+	if err != nil {
+		return "", err
+	}
+	for _, link := range links {
+		if target, err := filepath.EvalSymlinks(link); err == nil && target == device {
+			return strings.ToLower(filepath.Base(link)), nil
+		}
+	}
+
+	return "", fmt.Errorf("no filesystem UUID for %s", device)
+}
+
+// configKeyPath holds the config master key on Linux (root-only)
+const configKeyPath = "/var/lib/posservice/config_key"
+
+// readConfigKeyFromKeystore reads the config key file on Linux
+func readConfigKeyFromKeystore() (string, error) {
+	data, err := os.ReadFile(configKeyPath)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveConfigKeyToKeystore stores the config key in a root-only file on Linux
+func saveConfigKeyToKeystore(key string) error {
+	if err := os.MkdirAll("/var/lib/posservice", 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(configKeyPath, []byte(key), 0600); err != nil {
+		return fmt.Errorf("failed to write config key file: %w", err)
+	}
+
+	return nil
+}
+
+// deleteConfigKeyFromKeystore securely removes the config key file on Linux
+func deleteConfigKeyFromKeystore() error {
+	return SecureDelete(configKeyPath)
+}
+
 // platformSpecificID generates platform-specific machine ID data for Linux
 func platformSpecificID() ([]string, error) {
 	var data []string
@@ -100,6 +240,20 @@ func platformSpecificID() ([]string, error) {
 		}
 	}
 
+	// SMBIOS system UUID and motherboard serial (root only; often absent in
+	// containers and on boards without DMI)
+	if uuid, err := readDMI(dmiProductUUIDPath); err == nil {
+		data = append(data, "smbios:"+uuid)
+	}
+	if serial, err := readDMI(dmiBoardSerialPath); err == nil {
+		data = append(data, "board:"+serial)
+	}
+
+	// Filesystem UUID of the system volume
+	if volume, err := getRootVolumeUUID(); err == nil {
+		data = append(data, "volume:"+volume)
+	}
+
 	// CPU Information
 	cpuInfo, err := getCPUInfo()
 	if err == nil {
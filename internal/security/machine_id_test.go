@@ -93,6 +93,50 @@ func TestGenerateMachineID(t *testing.T) {
 	}
 }
 
+func TestParseSMBIOS(t *testing.T) {
+	// Type 1 (System) with a UUID, type 2 (Baseboard) whose serial is the
+	// second string, then end-of-table
+	system := []byte{1, 0x1b, 0x01, 0x00, 1, 2, 3, 4}
+	system = append(system, 0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff)
+	system = append(system, 0, 0, 0)
+	system = append(system, "Vendor\x00Model\x00\x00"...)
+	board := []byte{2, 0x08, 0x02, 0x00, 1, 2, 3, 2}
+	board = append(board, "Maker\x00MB-12345\x00Rev A\x00\x00"...)
+	end := []byte{127, 4, 0x03, 0x00, 0, 0}
+	table := append(append(system, board...), end...)
+
+	ids, err := parseSMBIOS(table, 3, 4)
+	if err != nil {
+		t.Fatalf("parseSMBIOS failed: %v", err)
+	}
+	if ids.SystemUUID != "00112233-4455-6677-8899-aabbccddeeff" {
+		t.Errorf("Unexpected system UUID %q", ids.SystemUUID)
+	}
+	if ids.BoardSerial != "MB-12345" {
+		t.Errorf("Unexpected board serial %q", ids.BoardSerial)
+	}
+
+	// Before SMBIOS 2.6 the UUID is read in byte order
+	if ids, _ := parseSMBIOS(table, 2, 4); ids.SystemUUID != "33221100-5544-7766-8899-aabbccddeeff" {
+		t.Errorf("Unexpected pre-2.6 system UUID %q", ids.SystemUUID)
+	}
+
+	if _, err := parseSMBIOS(table[:20], 3, 4); err == nil {
+		t.Error("Expected a truncated table to be rejected")
+	}
+}
+
+func TestHardwareID_SkipsPlaceholders(t *testing.T) {
+	for _, value := range []string{"", "  ", "To be filled by O.E.M.", "Default string\n", "00000000-0000-0000-0000-000000000000"} {
+		if id := hardwareID(value); id != "" {
+			t.Errorf("Expected %q to be skipped, got %q", value, id)
+		}
+	}
+	if id := hardwareID(" PF2XK9ZQ\n"); id != "PF2XK9ZQ" {
+		t.Errorf("Expected a trimmed serial, got %q", id)
+	}
+}
+
 func BenchmarkGetMachineID(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		GetMachineID()
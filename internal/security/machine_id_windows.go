@@ -4,11 +4,72 @@
 package security
 
 import (
+	"encoding/binary"
 	"fmt"
+	"os"
+	"unsafe"
 
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
 
+// rsmbProvider is the 'RSMB' firmware table provider: the raw SMBIOS tables
+const rsmbProvider = 'R'<<24 | 'S'<<16 | 'M'<<8 | 'B'
+
+var (
+	modKernel32                = windows.NewLazySystemDLL("kernel32.dll")
+	procGetSystemFirmwareTable = modKernel32.NewProc("GetSystemFirmwareTable")
+)
+
+// getSMBIOSIDs reads the system UUID and baseboard serial from the raw
+// SMBIOS data (RawSMBIOSData: 8-byte header, then the structure table)
+func getSMBIOSIDs() (*smbiosIDs, error) {
+	if err := procGetSystemFirmwareTable.Find(); err != nil {
+		return nil, err
+	}
+
+	size, _, err := procGetSystemFirmwareTable.Call(rsmbProvider, 0, 0, 0)
+	if size == 0 {
+		return nil, fmt.Errorf("GetSystemFirmwareTable failed: %w", err)
+	}
+	buf := make([]byte, size)
+	n, _, err := procGetSystemFirmwareTable.Call(rsmbProvider, 0, uintptr(unsafe.Pointer(&buf[0])), size)
+	if n == 0 || n > size {
+		return nil, fmt.Errorf("GetSystemFirmwareTable failed: %w", err)
+	}
+	if n < 8 {
+		return nil, fmt.Errorf("SMBIOS data too short")
+	}
+
+	major, minor := buf[1], buf[2]
+	length := int(binary.LittleEndian.Uint32(buf[4:8]))
+	if 8+length > int(n) {
+		return nil, fmt.Errorf("SMBIOS table truncated")
+	}
+	return parseSMBIOS(buf[8:8+length], major, minor)
+}
+
+// getSystemVolumeSerial returns the serial number of the system drive's volume
+func getSystemVolumeSerial() (string, error) {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	root, err := windows.UTF16PtrFromString(drive + `\`)
+	if err != nil {
+		return "", err
+	}
+
+	var serial uint32
+	if err := windows.GetVolumeInformation(root, nil, 0, &serial, nil, nil, nil, 0); err != nil {
+		return "", err
+	}
+	if serial == 0 {
+		return "", fmt.Errorf("system volume has no serial number")
+	}
+	return fmt.Sprintf("%08x", serial), nil
+}
+
 // getWindowsProductID retrieves the Windows Product ID from registry
 func getWindowsProductID() (string, error) {
 	k, err := registry.OpenKey(registry.LOCAL_MACHINE,
@@ -83,6 +144,115 @@ func saveMachineIDToRegistry(machineID string) error {
 	return k.SetStringValue(machineIDKey, machineID)
 }
 
+// deleteMachineIDFromRegistry removes the machine ID value from Windows registry
+func deleteMachineIDFromRegistry() error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, registryPath, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	if err := k.DeleteValue(machineIDKey); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+
+	return nil
+}
+
+// readMachineValue reads a value stored with the machine ID from Windows registry
+func readMachineValue(name string) (string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, registryPath, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+
+	value, _, err := k.GetStringValue(name)
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// saveMachineValue stores a value with the machine ID in Windows registry
+func saveMachineValue(name, value string) error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, registryPath, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	return k.SetStringValue(name, value)
+}
+
+// deleteMachineValue removes a value stored with the machine ID from Windows registry
+func deleteMachineValue(name string) error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, registryPath, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	if err := k.DeleteValue(name); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+
+	return nil
+}
+
+// readConfigKeyFromKeystore reads the config key from the HKLM service key,
+// which only administrators and SYSTEM can read
+func readConfigKeyFromKeystore() (string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, registryPath, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+
+	key, _, err := k.GetStringValue(configKeyValue)
+	if err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// saveConfigKeyToKeystore stores the config key under the HKLM service key
+func saveConfigKeyToKeystore(key string) error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, registryPath, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	return k.SetStringValue(configKeyValue, key)
+}
+
+// deleteConfigKeyFromKeystore removes the config key value from the registry
+func deleteConfigKeyFromKeystore() error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, registryPath, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	if err := k.DeleteValue(configKeyValue); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+
+	return nil
+}
+
 // platformSpecificID generates platform-specific machine ID data
 func platformSpecificID() ([]string, error) {
 	var data []string
@@ -93,6 +263,21 @@ func platformSpecificID() ([]string, error) {
 		data = append(data, productID)
 	}
 
+	// SMBIOS system UUID and motherboard serial
+	if ids, err := getSMBIOSIDs(); err == nil {
+		if ids.SystemUUID != "" {
+			data = append(data, "smbios:"+ids.SystemUUID)
+		}
+		if ids.BoardSerial != "" {
+			data = append(data, "board:"+ids.BoardSerial)
+		}
+	}
+
+	// Serial number of the system volume
+	if volume, err := getSystemVolumeSerial(); err == nil {
+		data = append(data, "volume:"+volume)
+	}
+
 	// CPU Information
 	cpuInfo, err := getCPUInfo()
 	if err == nil {
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// spkiPinPrefix marks a pin as the base64 SHA-256 of a certificate's DER
+// SubjectPublicKeyInfo (the HPKP pin format)
+const spkiPinPrefix = "sha256/"
+
+// ErrPinMismatch is returned when no certificate in the backend's chain
+// matches a configured pin
+var ErrPinMismatch = errors.New("server certificate does not match any pinned key")
+
+// SPKIPin returns the pin of cert's public key ("sha256/<base64>")
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParseSPKIPin decodes a "sha256/<base64>" pin into its digest
+func ParseSPKIPin(pin string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(pin, spkiPinPrefix)
+	if !ok {
+		return nil, fmt.Errorf("pin %q must start with %q", pin, spkiPinPrefix)
+	}
+
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("pin %q is not a base64 SHA-256 digest", pin)
+	}
+	return digest, nil
+}
+
+// PinnedTLSConfig returns a copy of base that, on top of normal chain
+// verification, only accepts a server whose verified chain contains one of
+// pins. A leaf, intermediate or root key may be pinned; pinning a backup key
+// as well lets the backend rotate certificates without locking terminals
+// out. No pins returns base unchanged.
+func PinnedTLSConfig(base *tls.Config, pins []string) (*tls.Config, error) {
+	if len(pins) == 0 {
+		return base, nil
+	}
+
+	digests := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		digest, err := ParseSPKIPin(pin)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+
+	cfg := &tls.Config{}
+	if base != nil {
+		cfg = base.Clone()
+	}
+
+	// VerifyConnection runs on resumed sessions too, unlike VerifyPeerCertificate
+	verifyNext := cfg.VerifyConnection
+	cfg.VerifyConnection = func(state tls.ConnectionState) error {
+		if verifyNext != nil {
+			if err := verifyNext(state); err != nil {
+				return err
+			}
+		}
+
+		chains := state.VerifiedChains
+		if len(chains) == 0 {
+			// InsecureSkipVerify: the pin is the only check left
+			chains = [][]*x509.Certificate{state.PeerCertificates}
+		}
+		for _, chain := range chains {
+			for _, cert := range chain {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				for _, digest := range digests {
+					if bytes.Equal(sum[:], digest) {
+						return nil
+					}
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+	return cfg, nil
+}
@@ -0,0 +1,63 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPinnedTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	base := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	get := func(pins []string) error {
+		cfg, err := PinnedTLSConfig(base, pins)
+		if err != nil {
+			t.Fatalf("PinnedTLSConfig failed: %v", err)
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	otherSum := sha256.Sum256([]byte("another key"))
+	otherPin := "sha256/" + base64.StdEncoding.EncodeToString(otherSum[:])
+	serverPin := SPKIPin(server.Certificate())
+
+	if err := get([]string{otherPin, serverPin}); err != nil {
+		t.Errorf("Expected the pinned server to be accepted, got %v", err)
+	}
+	if err := get([]string{otherPin}); !errors.Is(err, ErrPinMismatch) {
+		t.Errorf("Expected ErrPinMismatch for an unpinned key, got %v", err)
+	}
+	if err := get(nil); err != nil {
+		t.Errorf("Expected no pinning without pins, got %v", err)
+	}
+}
+
+func TestParseSPKIPin(t *testing.T) {
+	sum := sha256.Sum256([]byte("key"))
+	valid := "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+	if digest, err := ParseSPKIPin(valid); err != nil || string(digest) != string(sum[:]) {
+		t.Errorf("ParseSPKIPin(%q) failed: %v", valid, err)
+	}
+
+	for _, pin := range []string{
+		base64.StdEncoding.EncodeToString(sum[:]),               // missing prefix
+		"sha256/not-base64!",                                    // bad encoding
+		"sha256/" + base64.StdEncoding.EncodeToString(sum[:16]), // wrong length
+		"sha1/" + strings.Repeat("A", 28),                       // other hash
+	} {
+		if _, err := ParseSPKIPin(pin); err == nil {
+			t.Errorf("Expected ParseSPKIPin(%q) to fail", pin)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// rotationPurpose derives the key that wraps a rotated server key
+const rotationPurpose = "server-key-rotation/v1"
+
+// ErrSameServerKey is returned when a rotation would keep the current key
+var ErrSameServerKey = errors.New("new server key equals the current key")
+
+// WrapServerKey wraps newKey for delivery to a terminal holding currentKey.
+// The backend does this during rotation; only the holder of the current key
+// can unwrap it, and a forged or corrupted wrap fails authentication.
+func WrapServerKey(currentKey, newKey []byte) (string, error) {
+	if len(currentKey) != chacha20KeySize || len(newKey) != chacha20KeySize {
+		return "", fmt.Errorf("%w: server keys must be %d bytes", ErrInvalidKey, chacha20KeySize)
+	}
+
+	wrapKey := DeriveSubkey(currentKey, rotationPurpose)
+	aead, err := chacha20poly1305.New(wrapKey)
+	Wipe(wrapKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to create chacha20poly1305: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, newKey, nil)), nil
+}
+
+// RotateServerKey unwraps a new server key wrapped by WrapServerKey under
+// currentKey. The caller re-encrypts the database and persists the result.
+func RotateServerKey(currentKey []byte, wrappedKey string) ([]byte, error) {
+	if len(currentKey) != chacha20KeySize {
+		return nil, fmt.Errorf("%w: server key must be %d bytes", ErrInvalidKey, chacha20KeySize)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped key: %w", err)
+	}
+
+	wrapKey := DeriveSubkey(currentKey, rotationPurpose)
+	aead, err := chacha20poly1305.New(wrapKey)
+	Wipe(wrapKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chacha20poly1305: %w", err)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	newKey, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap server key: %w", err)
+	}
+
+	if len(newKey) != chacha20KeySize {
+		Wipe(newKey)
+		return nil, fmt.Errorf("invalid rotated key length: expected %d, got %d", chacha20KeySize, len(newKey))
+	}
+	if bytes.Equal(newKey, currentKey) {
+		Wipe(newKey)
+		return nil, ErrSameServerKey
+	}
+
+	return newKey, nil
+}
@@ -0,0 +1,57 @@
+package security
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRotateServerKey(t *testing.T) {
+	current, _ := GenerateServerKey()
+	next, _ := GenerateServerKey()
+
+	wrapped, err := WrapServerKey(current, next)
+	if err != nil {
+		t.Fatalf("WrapServerKey failed: %v", err)
+	}
+
+	got, err := RotateServerKey(current, wrapped)
+	if err != nil {
+		t.Fatalf("RotateServerKey failed: %v", err)
+	}
+	if !bytes.Equal(got, next) {
+		t.Error("Unwrapped key does not match")
+	}
+
+	// Only the holder of the current key can unwrap
+	other, _ := GenerateServerKey()
+	if _, err := RotateServerKey(other, wrapped); err == nil {
+		t.Error("Expected unwrap with the wrong key to fail")
+	}
+
+	same, _ := WrapServerKey(current, current)
+	if _, err := RotateServerKey(current, same); !errors.Is(err, ErrSameServerKey) {
+		t.Errorf("Expected ErrSameServerKey, got %v", err)
+	}
+}
+
+func TestDatabaseEncryption_SetKey(t *testing.T) {
+	oldKey, _ := GenerateServerKey()
+	newKey, _ := GenerateServerKey()
+
+	enc, _ := NewDatabaseEncryption(oldKey)
+	before, _ := enc.Encrypt([]byte("data"))
+
+	if err := enc.SetKey(newKey); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	if _, err := enc.Decrypt(before); err == nil {
+		t.Error("Expected old ciphertext to fail under the new key")
+	}
+
+	after, _ := enc.Encrypt([]byte("data"))
+	fresh, _ := NewDatabaseEncryption(newKey)
+	if plain, err := fresh.Decrypt(after); err != nil || string(plain) != "data" {
+		t.Errorf("Expected new ciphertext to decrypt under the new key, got %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,66 @@
+package security
+
+import (
+	"runtime"
+	"sync"
+)
+
+// SecureBytes holds key material in a buffer it owns and zeroes it on
+// Wipe, so keys do not linger in heap or crash dumps once they are no
+// longer needed. Ciphers built from the key keep their own expanded copy
+// until collected, so retire keys early and keep copies few.
+type SecureBytes struct {
+	mu  sync.RWMutex
+	buf []byte
+}
+
+// NewSecureBytes copies b into a new SecureBytes; the caller remains
+// responsible for wiping b
+func NewSecureBytes(b []byte) *SecureBytes {
+	return &SecureBytes{buf: append(make([]byte, 0, len(b)), b...)}
+}
+
+// Use calls fn with the bytes while holding them, so a concurrent Wipe or
+// Replace waits until fn returns. fn must not keep the slice.
+func (s *SecureBytes) Use(fn func(b []byte) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.buf)
+}
+
+// Copy returns a copy of the bytes, which the caller must wipe
+func (s *SecureBytes) Copy() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append(make([]byte, 0, len(s.buf)), s.buf...)
+}
+
+// Len returns the length of the bytes (0 once wiped)
+func (s *SecureBytes) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.buf)
+}
+
+// Replace wipes the held bytes and takes a copy of b
+func (s *SecureBytes) Replace(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	Wipe(s.buf)
+	s.buf = append(make([]byte, 0, len(b)), b...)
+}
+
+// Wipe zeroes the held bytes and releases them. Wiping twice is harmless.
+func (s *SecureBytes) Wipe() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	Wipe(s.buf)
+	s.buf = nil
+}
+
+// Wipe zeroes b in place
+func Wipe(b []byte) {
+	clear(b)
+	// Keep the writes from being optimized away as dead stores
+	runtime.KeepAlive(b)
+}
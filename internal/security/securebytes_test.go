@@ -0,0 +1,104 @@
+package security
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSecureBytes_Wipe(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	secure := NewSecureBytes(key)
+
+	// The SecureBytes owns a copy; wiping the caller's slice leaves it intact
+	Wipe(key)
+	if !bytes.Equal(key, make([]byte, 32)) {
+		t.Fatalf("Wipe left %x", key)
+	}
+	if secure.Len() != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", secure.Len())
+	}
+
+	var held []byte
+	secure.Use(func(b []byte) error {
+		held = b
+		return nil
+	})
+	secure.Wipe()
+	if !bytes.Equal(held, make([]byte, 32)) {
+		t.Errorf("Buffer not zeroed after Wipe: %x", held)
+	}
+	if secure.Len() != 0 {
+		t.Errorf("Expected no bytes after Wipe, got %d", secure.Len())
+	}
+	secure.Wipe()
+}
+
+func TestDatabaseEncryption_SetKeyWipesPrevious(t *testing.T) {
+	oldKey, _ := GenerateServerKey()
+	newKey, _ := GenerateServerKey()
+
+	de, err := NewDatabaseEncryption(oldKey)
+	if err != nil {
+		t.Fatalf("NewDatabaseEncryption failed: %v", err)
+	}
+	var held []byte
+	de.serverKey.Use(func(b []byte) error {
+		held = b
+		return nil
+	})
+
+	if err := de.SetKey(newKey); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	if !bytes.Equal(held, make([]byte, len(held))) {
+		t.Errorf("Previous key not wiped: %x", held)
+	}
+
+	ciphertext, err := de.Encrypt([]byte("sale"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	de.Close()
+	if _, err := de.Decrypt(ciphertext); err == nil {
+		t.Error("Expected decryption to fail after Close")
+	}
+}
+
+func TestConfigKeys_Wipe(t *testing.T) {
+	saved := buildConfigKey
+	buildConfigKey = "0123456789abcdef0123456789abcdef"
+	t.Cleanup(func() {
+		buildConfigKey = saved
+		WipeConfigKeys()
+	})
+	WipeConfigKeys()
+
+	current, previous, err := LoadConfigKeys()
+	if err != nil {
+		t.Fatalf("LoadConfigKeys failed: %v", err)
+	}
+
+	// Callers get copies: wiping them leaves the cache intact
+	Wipe(current)
+	again, _, err := LoadConfigKeys()
+	if err != nil {
+		t.Fatalf("LoadConfigKeys failed: %v", err)
+	}
+	if !machineProtectionAvailable() && string(again) != buildConfigKey {
+		t.Errorf("Cached key damaged by wiping a copy: %q", again)
+	}
+
+	cached := append([]*SecureBytes{cachedConfigKey}, cachedPreviousKey...)
+	if len(cached) != len(previous)+1 {
+		t.Fatalf("Expected %d cached keys, got %d", len(previous)+1, len(cached))
+	}
+	WipeConfigKeys()
+	for i, key := range cached {
+		if key.Len() != 0 {
+			t.Errorf("Cached key %d not wiped", i)
+		}
+	}
+	if cachedConfigKey != nil || cachedPreviousKey != nil {
+		t.Error("Cache not cleared")
+	}
+}
@@ -0,0 +1,103 @@
+package security
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T) *RequestSigner {
+	signer, err := NewRequestSigner("store-1", []byte("store-token"))
+	if err != nil {
+		t.Fatalf("NewRequestSigner failed: %v", err)
+	}
+	return signer
+}
+
+func TestRequestSigner_RequestRoundTrip(t *testing.T) {
+	signer := newTestSigner(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/sync/push?batch=1", strings.NewReader(`{"entries":[]}`))
+	if err := signer.Sign(req); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"entries":[]}` {
+		t.Errorf("Sign consumed the body: %q", body)
+	}
+	req.Body = io.NopCloser(strings.NewReader(`{"entries":[]}`))
+	req.GetBody = nil
+	if err := signer.VerifyRequest(req); err != nil {
+		t.Errorf("VerifyRequest failed: %v", err)
+	}
+
+	// A middlebox rewriting the body or the path is caught
+	req.Body = io.NopCloser(strings.NewReader(`{"entries":[1]}`))
+	if err := signer.VerifyRequest(req); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Expected ErrSignatureInvalid for a modified body, got %v", err)
+	}
+	req.Body = io.NopCloser(strings.NewReader(`{"entries":[]}`))
+	req.URL.RawQuery = "batch=2"
+	if err := signer.VerifyRequest(req); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Expected ErrSignatureInvalid for a modified query, got %v", err)
+	}
+
+	// Another store's secret cannot verify it
+	other, _ := NewRequestSigner("store-1", []byte("other-token"))
+	req.URL.RawQuery = "batch=1"
+	req.Body = io.NopCloser(strings.NewReader(`{"entries":[]}`))
+	if err := other.VerifyRequest(req); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Expected ErrSignatureInvalid with another key, got %v", err)
+	}
+}
+
+func TestRequestSigner_RejectsStaleTimestamps(t *testing.T) {
+	signer := newTestSigner(t)
+	signer.now = func() time.Time { return time.Now().Add(-time.Hour) }
+
+	req := httptest.NewRequest(http.MethodGet, "/sync/pull", nil)
+	signer.Sign(req)
+
+	signer.now = time.Now
+	if err := signer.VerifyRequest(req); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Expected ErrSignatureExpired, got %v", err)
+	}
+}
+
+func TestRequestSigner_ResponseBoundToRequest(t *testing.T) {
+	signer := newTestSigner(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/sync/pull", nil)
+	signer.Sign(req)
+
+	body := `{"ok":true}`
+	response := func(header http.Header, body string) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	header := signer.SignResponse(req.Header.Get(HeaderSignature), http.StatusOK, []byte(body))
+	resp := response(header, body)
+	if err := signer.VerifyResponse(req, resp); err != nil {
+		t.Fatalf("VerifyResponse failed: %v", err)
+	}
+	if data, _ := io.ReadAll(resp.Body); string(data) != body {
+		t.Errorf("VerifyResponse consumed the body: %q", data)
+	}
+
+	if err := signer.VerifyResponse(req, response(header, `{"ok":false}`)); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Expected ErrSignatureInvalid for a modified response, got %v", err)
+	}
+	if err := signer.VerifyResponse(req, response(http.Header{}, body)); !errors.Is(err, ErrSignatureMissing) {
+		t.Errorf("Expected ErrSignatureMissing for an unsigned response, got %v", err)
+	}
+
+	// A response to one request cannot answer another
+	other := httptest.NewRequest(http.MethodGet, "/sync/pull?again=1", nil)
+	signer.Sign(other)
+	if err := signer.VerifyResponse(other, response(header, body)); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Expected ErrSignatureInvalid for a replayed response, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package security
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// SMBIOS structure types read for the machine ID
+const (
+	smbiosTypeSystem    = 1   // System Information: UUID
+	smbiosTypeBaseboard = 2   // Baseboard Information: serial number
+	smbiosTypeEnd       = 127 // End-of-table
+)
+
+// smbiosIDs are the identifiers found in an SMBIOS table
+type smbiosIDs struct {
+	SystemUUID  string
+	BoardSerial string
+}
+
+// parseSMBIOS reads the system UUID and baseboard serial from the raw
+// structure table of an SMBIOS major.minor implementation. Placeholder
+// values are returned empty.
+func parseSMBIOS(table []byte, major, minor byte) (*smbiosIDs, error) {
+	ids := &smbiosIDs{}
+
+	for len(table) >= 4 {
+		kind, length := table[0], int(table[1])
+		if length < 4 || length > len(table) {
+			return nil, fmt.Errorf("malformed SMBIOS structure of type %d", kind)
+		}
+		formatted := table[:length]
+
+		// Strings follow the formatted area, ended by a double NUL
+		end := length
+		for end+1 < len(table) && (table[end] != 0 || table[end+1] != 0) {
+			end++
+		}
+		if end+1 >= len(table) {
+			return nil, fmt.Errorf("unterminated SMBIOS structure of type %d", kind)
+		}
+		strs := table[length:end]
+		table = table[end+2:]
+
+		switch kind {
+		case smbiosTypeSystem:
+			if len(formatted) >= 0x18 && ids.SystemUUID == "" {
+				ids.SystemUUID = hardwareID(smbiosUUID(formatted[0x08:0x18], major, minor))
+			}
+		case smbiosTypeBaseboard:
+			if len(formatted) > 0x07 && ids.BoardSerial == "" {
+				ids.BoardSerial = hardwareID(smbiosString(strs, formatted[0x07]))
+			}
+		case smbiosTypeEnd:
+			return ids, nil
+		}
+	}
+
+	return ids, nil
+}
+
+// smbiosUUID formats a system UUID. Since SMBIOS 2.6 its first three
+// fields are little-endian.
+func smbiosUUID(b []byte, major, minor byte) string {
+	if major > 2 || (major == 2 && minor >= 6) {
+		return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+			binary.LittleEndian.Uint32(b[0:4]), binary.LittleEndian.Uint16(b[4:6]),
+			binary.LittleEndian.Uint16(b[6:8]), b[8:10], b[10:16])
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// smbiosString returns the index'th (1-based) NUL-separated string of a
+// structure; 0 means no string
+func smbiosString(strs []byte, index byte) string {
+	if index == 0 {
+		return ""
+	}
+	start := 0
+	for i := 0; i <= len(strs); i++ {
+		if i == len(strs) || strs[i] == 0 {
+			index--
+			if index == 0 {
+				return string(strs[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return ""
+}
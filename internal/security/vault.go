@@ -0,0 +1,234 @@
+package security
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// VaultFileName is the encrypted file vault in the key store directory
+const VaultFileName = "vault.enc"
+
+// EnvSecretPrefix prefixes the environment variables read by the env vault:
+// the secret "store_token" is POS_SECRET_STORE_TOKEN
+const EnvSecretPrefix = "POS_SECRET_"
+
+// ErrVaultReadOnly is returned when writing to a vault that only reads
+var ErrVaultReadOnly = errors.New("secrets vault is read-only")
+
+// vaultAAD binds the file vault's ciphertext to its purpose
+var vaultAAD = []byte("pos-secret-vault-v1")
+
+// Vault holds named secrets such as tokens and passwords. Unlike KeyStore,
+// which seals key material to the machine, a vault keeps credentials where
+// the platform expects them, or reads them from the environment.
+type Vault interface {
+	// Get returns the secret stored under name, or ErrSecretNotFound
+	Get(name string) ([]byte, error)
+	Put(name string, secret []byte) error
+	// Delete removes a secret; deleting a missing secret is not an error
+	Delete(name string) error
+	Backend() string
+}
+
+// VaultCipher encrypts the file vault (security.ConfigEncryption)
+type VaultCipher interface {
+	EncryptWithAAD(plaintext, aad []byte) (string, error)
+	DecryptWithAAD(ciphertextB64 string, aad []byte) ([]byte, error)
+}
+
+// NewVault opens the vault for mode (constants.SecretVault*). The file
+// vault lives in dir and is encrypted with the first cipher; later ciphers
+// (older config keys) are tried when reading, and the file is rewritten
+// under the first one on the next change.
+func NewVault(mode, dir string, ciphers ...VaultCipher) (Vault, error) {
+	switch mode {
+	case constants.SecretVaultFile:
+		vault, err := newFileVault(dir, ciphers)
+		if err != nil {
+			return nil, err
+		}
+		return vault, nil
+	case constants.SecretVaultCredential:
+		vault, err := newCredentialVault()
+		if err != nil {
+			return nil, err
+		}
+		return vault, nil
+	case constants.SecretVaultEnv:
+		return envVault{}, nil
+	case "", constants.SecretVaultAuto:
+		if runtime.GOOS == "windows" {
+			return NewVault(constants.SecretVaultCredential, dir, ciphers...)
+		}
+		return NewVault(constants.SecretVaultFile, dir, ciphers...)
+	default:
+		return nil, fmt.Errorf("unknown secret vault: %s", mode)
+	}
+}
+
+// fileVault keeps secrets in one file encrypted with the config key
+type fileVault struct {
+	path    string
+	ciphers []VaultCipher
+	mu      sync.Mutex
+}
+
+// newFileVault opens the file vault in dir
+func newFileVault(dir string, ciphers []VaultCipher) (*fileVault, error) {
+	if len(ciphers) == 0 {
+		return nil, fmt.Errorf("file vault requires a cipher")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	return &fileVault{path: filepath.Join(dir, VaultFileName), ciphers: ciphers}, nil
+}
+
+// Get returns a secret from the file
+func (v *fileVault) Get(name string) ([]byte, error) {
+	if !secretNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid secret name: %q", name)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	secrets, err := v.read()
+	if err != nil {
+		return nil, err
+	}
+	secret, ok := secrets[name]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+// Put stores a secret in the file
+func (v *fileVault) Put(name string, secret []byte) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name: %q", name)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	secrets, err := v.read()
+	if err != nil {
+		return err
+	}
+	secrets[name] = secret
+	return v.write(secrets)
+}
+
+// Delete removes a secret from the file
+func (v *fileVault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	secrets, err := v.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return v.write(secrets)
+}
+
+// Backend returns constants.SecretVaultFile
+func (v *fileVault) Backend() string {
+	return constants.SecretVaultFile
+}
+
+// read decrypts the vault file; a missing file is an empty vault
+func (v *fileVault) read() (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	var plaintext []byte
+	for _, cipher := range v.ciphers {
+		if plaintext, err = cipher.DecryptWithAAD(string(data), vaultAAD); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt vault: %w", err)
+	}
+
+	var encoded map[string]string
+	if err := json.Unmarshal(plaintext, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	}
+	for name, value := range encoded {
+		secret, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode vault secret %s: %w", name, err)
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+// write encrypts secrets with the current cipher and replaces the file
+func (v *fileVault) write(secrets map[string][]byte) error {
+	encoded := make(map[string]string, len(secrets))
+	for name, secret := range secrets {
+		encoded[name] = base64.StdEncoding.EncodeToString(secret)
+	}
+	plaintext, err := json.Marshal(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+
+	data, err := v.ciphers[0].EncryptWithAAD(plaintext, vaultAAD)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt vault: %w", err)
+	}
+	return writeSealed(v.path, []byte(data))
+}
+
+// envVault reads secrets from EnvSecretPrefix variables, for deployments
+// that inject credentials at start; it cannot store them
+type envVault struct{}
+
+// Get returns a secret from the environment
+func (envVault) Get(name string) ([]byte, error) {
+	value, ok := os.LookupEnv(EnvSecretPrefix + strings.ToUpper(name))
+	if !ok || value == "" {
+		return nil, ErrSecretNotFound
+	}
+	return []byte(value), nil
+}
+
+// Put fails with ErrVaultReadOnly
+func (envVault) Put(name string, secret []byte) error {
+	return ErrVaultReadOnly
+}
+
+// Delete fails with ErrVaultReadOnly
+func (envVault) Delete(name string) error {
+	return ErrVaultReadOnly
+}
+
+// Backend returns constants.SecretVaultEnv
+func (envVault) Backend() string {
+	return constants.SecretVaultEnv
+}
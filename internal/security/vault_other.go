@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package security
+
+import "errors"
+
+// credentialVault is not supported on this platform
+type credentialVault = fileVault
+
+// newCredentialVault always fails where there is no Credential Manager
+func newCredentialVault() (*credentialVault, error) {
+	return nil, errors.New("Windows Credential Manager is not available on this platform")
+}
@@ -0,0 +1,99 @@
+package security
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestFileVault_PutGetDelete(t *testing.T) {
+	dir := t.TempDir()
+	enc, _ := NewConfigEncryption([]byte("config-key"), "machine-1")
+
+	vault, err := NewVault(constants.SecretVaultFile, dir, enc)
+	if err != nil {
+		t.Fatalf("NewVault failed: %v", err)
+	}
+	if vault.Backend() != constants.SecretVaultFile {
+		t.Errorf("Expected file backend, got %s", vault.Backend())
+	}
+
+	if _, err := vault.Get("store_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got %v", err)
+	}
+	if err := vault.Put("store_token", []byte("tok-123")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := vault.Put("../escape", []byte("x")); err == nil {
+		t.Error("Expected invalid names to be rejected")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, VaultFileName))
+	if err != nil {
+		t.Fatalf("Failed to read vault file: %v", err)
+	}
+	if strings.Contains(string(data), "tok-123") {
+		t.Error("Vault file holds the secret in plaintext")
+	}
+
+	secret, err := vault.Get("store_token")
+	if err != nil || string(secret) != "tok-123" {
+		t.Fatalf("Get returned %q (%v)", secret, err)
+	}
+
+	if err := vault.Delete("store_token"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := vault.Delete("store_token"); err != nil {
+		t.Errorf("Deleting a missing secret failed: %v", err)
+	}
+	if _, err := vault.Get("store_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound after delete, got %v", err)
+	}
+}
+
+func TestFileVault_ReadsUnderPreviousKey(t *testing.T) {
+	dir := t.TempDir()
+	previous, _ := NewConfigEncryption([]byte("old-config-key"), "machine-1")
+	current, _ := NewConfigEncryption([]byte("new-config-key"), "machine-1")
+
+	old, _ := NewVault(constants.SecretVaultFile, dir, previous)
+	if err := old.Put("api_secret", []byte("s3cret")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	vault, _ := NewVault(constants.SecretVaultFile, dir, current, previous)
+	if secret, err := vault.Get("api_secret"); err != nil || string(secret) != "s3cret" {
+		t.Fatalf("Get returned %q (%v)", secret, err)
+	}
+
+	// The next change rewrites the file under the current key
+	if err := vault.Put("mqtt_password", []byte("pw")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, err := old.Get("api_secret"); err == nil {
+		t.Error("Expected the vault to no longer open with the previous key")
+	}
+}
+
+func TestEnvVault_ReadOnly(t *testing.T) {
+	t.Setenv(EnvSecretPrefix+"STORE_TOKEN", "from-env")
+
+	vault, err := NewVault(constants.SecretVaultEnv, "")
+	if err != nil {
+		t.Fatalf("NewVault failed: %v", err)
+	}
+	if secret, err := vault.Get("store_token"); err != nil || string(secret) != "from-env" {
+		t.Errorf("Get returned %q (%v)", secret, err)
+	}
+	if _, err := vault.Get("api_secret"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got %v", err)
+	}
+	if err := vault.Put("store_token", []byte("x")); !errors.Is(err, ErrVaultReadOnly) {
+		t.Errorf("Expected ErrVaultReadOnly, got %v", err)
+	}
+}
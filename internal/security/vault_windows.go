@@ -0,0 +1,129 @@
+//go:build windows
+// +build windows
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+	"golang.org/x/sys/windows"
+)
+
+// credentialTargetPrefix namespaces our entries in Credential Manager
+const credentialTargetPrefix = "POSService/"
+
+// Credential Manager constants (wincred.h)
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	credMaxGenericBlobSize  = 5 * 512
+)
+
+var (
+	modAdvapi32    = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW  = modAdvapi32.NewProc("CredReadW")
+	procCredWriteW = modAdvapi32.NewProc("CredWriteW")
+	procCredDelete = modAdvapi32.NewProc("CredDeleteW")
+	procCredFree   = modAdvapi32.NewProc("CredFree")
+)
+
+// credential mirrors CREDENTIALW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialVault keeps secrets as generic credentials of the service
+// account in Windows Credential Manager
+type credentialVault struct{}
+
+// newCredentialVault opens Credential Manager
+func newCredentialVault() (*credentialVault, error) {
+	if err := procCredReadW.Find(); err != nil {
+		return nil, fmt.Errorf("Credential Manager is not available: %w", err)
+	}
+	return &credentialVault{}, nil
+}
+
+// target returns the Credential Manager target name for a secret
+func (v *credentialVault) target(name string) (*uint16, error) {
+	if !secretNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid secret name: %q", name)
+	}
+	return windows.UTF16PtrFromString(credentialTargetPrefix + name)
+}
+
+// Get reads a secret from Credential Manager
+func (v *credentialVault) Get(name string) ([]byte, error) {
+	target, err := v.target(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *credential
+	ret, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		if errors.Is(callErr, windows.ERROR_NOT_FOUND) {
+			return nil, ErrSecretNotFound
+		}
+		return nil, fmt.Errorf("CredReadW failed: %w", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	return append([]byte(nil), unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)...), nil
+}
+
+// Put writes a secret to Credential Manager
+func (v *credentialVault) Put(name string, secret []byte) error {
+	target, err := v.target(name)
+	if err != nil {
+		return err
+	}
+	if len(secret) == 0 || len(secret) > credMaxGenericBlobSize {
+		return fmt.Errorf("secret %s must be 1 to %d bytes", name, credMaxGenericBlobSize)
+	}
+
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(secret)),
+		CredentialBlob:     &secret[0],
+		Persist:            credPersistLocalMachine,
+	}
+	if ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ret == 0 {
+		return fmt.Errorf("CredWriteW failed: %w", callErr)
+	}
+	return nil
+}
+
+// Delete removes a secret from Credential Manager
+func (v *credentialVault) Delete(name string) error {
+	target, err := v.target(name)
+	if err != nil {
+		return err
+	}
+
+	ret, _, callErr := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 && !errors.Is(callErr, windows.ERROR_NOT_FOUND) {
+		return fmt.Errorf("CredDeleteW failed: %w", callErr)
+	}
+	return nil
+}
+
+// Backend returns constants.SecretVaultCredential
+func (v *credentialVault) Backend() string {
+	return constants.SecretVaultCredential
+}
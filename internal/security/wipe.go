@@ -0,0 +1,59 @@
+package security
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SecureDelete overwrites a file with random data, syncs it to disk and
+// removes it. Missing files are not an error.
+//
+// On SSDs and journaling filesystems overwritten blocks may survive in
+// remapped sectors; the data is encrypted at rest, so destroying the key
+// material (see DeleteMachineID) is what makes remnants unrecoverable.
+func SecureDelete(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if !info.IsDir() && info.Size() > 0 {
+		if err := overwriteFile(path, info.Size()); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// overwriteFile replaces the contents of a file with random bytes in place
+func overwriteFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for wiping: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := io.CopyN(f, rand.Reader, size); err != nil {
+		return fmt.Errorf("failed to overwrite %s: %w", path, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecureDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.db")
+	if err := os.WriteFile(path, []byte("sensitive data"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := SecureDelete(path); err != nil {
+		t.Fatalf("SecureDelete failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be removed, got %v", err)
+	}
+
+	// Deleting a missing file is a no-op
+	if err := SecureDelete(path); err != nil {
+		t.Errorf("Expected no error for missing file, got %v", err)
+	}
+}
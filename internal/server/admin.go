@@ -0,0 +1,57 @@
+package server
+
+import (
+	"embed"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+// adminUIPath serves the embedded admin dashboard, so store managers can
+// check and configure the terminal from a browser
+const adminUIPath = "/admin"
+
+//go:embed admin
+var adminAssets embed.FS
+
+// setupAdminUI mounts the dashboard ahead of the API middleware. The page
+// itself holds no data: every panel calls the API with the token entered
+// in it. Other paths under /admin (the pre-versioning /admin/uptime) fall
+// through to the API.
+func (s *Server) setupAdminUI() {
+	s.app.Get(adminUIPath, handleAdminAsset)
+	s.app.Get(adminUIPath+"/:file", handleAdminAsset)
+}
+
+// handleAdminAsset serves one file of the dashboard, index.html by default
+func handleAdminAsset(c *fiber.Ctx) error {
+	name := c.Params("file", "index.html")
+	data, err := adminAssets.ReadFile(path.Join("admin", name))
+	if err != nil {
+		return c.Next()
+	}
+
+	c.Type(path.Ext(name))
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	return c.Send(data)
+}
+
+// SyncHistory is the most recent sync runs since startup
+type SyncHistory struct {
+	Runs []possync.Run `json:"runs"` // Oldest first
+}
+
+// handleSyncHistory returns the recent sync runs, for the dashboard
+func (s *Server) handleSyncHistory(c *fiber.Ctx) error {
+	history := SyncHistory{Runs: s.syncStats.History()}
+	if history.Runs == nil {
+		history.Runs = []possync.Run{}
+	}
+	return c.JSON(api.NewSuccessResponse(
+		api.CodeDataRetrieved,
+		"Sync history retrieved successfully",
+		history,
+	))
+}
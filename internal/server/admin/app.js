@@ -0,0 +1,124 @@
+// Admin dashboard for the POS service. Every panel reads the local API
+// (/api/v1) with the token entered in the header; on a till no token is
+// needed, since tokenless callers are staff.
+"use strict";
+
+const API = "/api/v1";
+const LOG_FOLLOW_MS = 5000;
+const STATUS_REFRESH_MS = 30000;
+
+const $ = id => document.getElementById(id);
+let token = sessionStorage.getItem("pos-admin-token") || "";
+let followTimer = null;
+
+// call requests an API path and returns the envelope's result, throwing the
+// envelope's message on failure
+async function call(path, options = {}) {
+  const headers = Object.assign({}, options.headers);
+  if (token) headers["Authorization"] = "Bearer " + token;
+  const resp = await fetch(API + path, Object.assign({}, options, { headers }));
+  const body = await resp.json().catch(() => ({}));
+  if (!resp.ok || body.ok === false) {
+    throw new Error(body.message || resp.status + " " + resp.statusText);
+  }
+  return body.result;
+}
+
+function showError(err) {
+  const box = $("error");
+  box.textContent = err ? err.message : "";
+  box.hidden = !err;
+}
+
+function row(cells, className) {
+  const tr = document.createElement("tr");
+  for (const text of cells) {
+    const td = document.createElement("td");
+    td.textContent = text == null ? "" : text;
+    tr.appendChild(td);
+  }
+  if (className) tr.lastChild.className = className;
+  return tr;
+}
+
+async function loadHealth() {
+  const health = await call("/health");
+  const table = $("health");
+  table.replaceChildren(row(["Version", health.version]),
+    row(["Overall", health.healthy ? "healthy" : "unhealthy"], health.healthy ? "ok" : "failed"));
+  for (const [name, check] of Object.entries(health.checks || {})) {
+    table.appendChild(row([name.replace("_", " "), check.status + (check.message ? ": " + check.message : "")], check.status));
+  }
+}
+
+async function loadHistory() {
+  const history = await call("/sync/history");
+  const body = $("history").tBodies[0];
+  body.replaceChildren();
+  for (const run of history.runs.slice().reverse()) {
+    const when = new Date(run.at).toLocaleString();
+    body.appendChild(row([when, run.mode, run.records, run.error || "ok"], run.error ? "failed" : "ok"));
+  }
+  if (!history.runs.length) body.appendChild(row(["No sync since startup"]));
+}
+
+async function loadLogs() {
+  const query = new URLSearchParams({ level: $("level").value, tail: $("tail").value });
+  const tail = await call("/logs?" + query);
+  let text = tail.entries.map(e => {
+    const ts = e.ts ? new Date(e.ts).toLocaleString() : "";
+    const rest = Object.assign({}, e);
+    delete rest.ts; delete rest.level; delete rest.msg;
+    const fields = Object.keys(rest).length ? " " + JSON.stringify(rest) : "";
+    return ts + " " + (e.level || "").toUpperCase() + " " + (e.msg || "") + fields;
+  }).join("\n");
+  if (tail.undecryptable) text += "\n(" + tail.undecryptable + " lines could not be decrypted)";
+  const log = $("log");
+  log.textContent = text;
+  log.scrollTop = log.scrollHeight;
+}
+
+async function saveConfig(event) {
+  event.preventDefault();
+  const headers = { "Content-Type": "application/json" };
+  if ($("password").value) headers["X-Admin-Password"] = $("password").value;
+  try {
+    JSON.parse($("patch").value);
+    const report = await call("/config", { method: "PUT", headers, body: $("patch").value });
+    $("report").textContent = JSON.stringify(report, null, 2);
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+async function refresh() {
+  try {
+    await Promise.all([loadHealth(), loadHistory(), loadLogs()]);
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function follow() {
+  clearInterval(followTimer);
+  followTimer = $("follow").checked ? setInterval(() => loadLogs().catch(showError), LOG_FOLLOW_MS) : null;
+}
+
+$("token").value = token;
+$("login").addEventListener("submit", event => {
+  event.preventDefault();
+  token = $("token").value.trim();
+  sessionStorage.setItem("pos-admin-token", token);
+  refresh();
+});
+$("config").addEventListener("submit", saveConfig);
+$("logs").addEventListener("submit", event => {
+  event.preventDefault();
+  loadLogs().then(() => showError(null), showError);
+});
+$("follow").addEventListener("change", follow);
+
+refresh();
+setInterval(() => Promise.all([loadHealth(), loadHistory()]).catch(showError), STATUS_REFRESH_MS);
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+func TestAdminUI_ServesEmbeddedAssets(t *testing.T) {
+	server := newTestServerWithDB(t)
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "c1", 0)
+
+	admin := adminBearer(t, server)
+	get := func(path, token string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := send(server, req, -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	for path, contentType := range map[string]string{
+		"/admin":           "text/html",
+		"/admin/":          "text/html",
+		"/admin/app.js":    "javascript",
+		"/admin/style.css": "text/css",
+	} {
+		resp, body := get(path, "")
+		if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), contentType) || body == "" {
+			t.Errorf("%s: expected 200 %s, got %d %q", path, contentType, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if resp.Header.Get(HeaderDeprecation) != "" {
+			t.Errorf("%s: dashboard flagged as a legacy path", path)
+		}
+	}
+
+	// The page needs no token; the API calls it makes do
+	if resp, _ := get("/admin", cashier); resp.StatusCode != http.StatusOK {
+		t.Errorf("Cashier could not load the page: %d", resp.StatusCode)
+	}
+
+	// Other /admin paths are still the pre-versioning API
+	resp, _ := get("/admin/uptime", admin)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(HeaderDeprecation) != "true" {
+		t.Errorf("Legacy /admin/uptime: expected 200 flagged deprecated, got %d", resp.StatusCode)
+	}
+	if resp, _ := get("/admin/missing.js", admin); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown asset: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestSyncHistory(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	_, data := laneRequest(t, server, http.MethodGet, "/sync/history", adminBearer(t, server), "")
+	var history SyncHistory
+	if err := json.Unmarshal(data, &history); err != nil || history.Runs == nil || len(history.Runs) != 0 {
+		t.Fatalf("Expected an empty list before any sync: %s", data)
+	}
+
+	server.syncStats.Record(possync.ProgressReport{Mode: possync.ModeRegular, Records: 12})
+	server.syncStats.Record(possync.ProgressReport{Mode: possync.ModeRegular, Error: "backend unreachable"})
+
+	_, data = laneRequest(t, server, http.MethodGet, "/sync/history", adminBearer(t, server), "")
+	json.Unmarshal(data, &history)
+	if len(history.Runs) != 2 || history.Runs[0].Records != 12 || history.Runs[1].Error != "backend unreachable" {
+		t.Errorf("Unexpected history: %s", data)
+	}
+
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "c1", 0)
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/sync/history", cashier, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier reached /sync/history with %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+)
+
+// Third-party integrations (scales, kiosk apps) authenticate with an API key
+// in the X-API-Key header. Staff create keys with a set of scopes; each scope
+// opens a fixed group of routes (see scopeRoutes) and everything else is
+// forbidden to the key.
+
+// APIKeyCreation is returned once when an API key is created
+type APIKeyCreation struct {
+	Key    *auth.APIKey `json:"key"`
+	Secret string       `json:"secret"` // Shown once; the full X-API-Key value
+}
+
+// handleCreateAPIKey issues an API key with the requested scopes
+func (s *Server) handleCreateAPIKey(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Name   string   `json:"name" validate:"required"`
+		Scopes []string `json:"scopes" validate:"required,min=1"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+	for _, scope := range body.Scopes {
+		if !auth.ValidScope(scope) {
+			return apperr.BadRequest("Unknown scope: " + scope)
+		}
+	}
+
+	secret, key, err := auth.CreateAPIKey(db, body.Name, body.Scopes)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "API key created successfully", APIKeyCreation{
+		Key:    key,
+		Secret: secret,
+	}))
+}
+
+// handleListAPIKeys lists issued API keys without their secrets, paginated
+func (s *Server) handleListAPIKeys(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	keys, err := auth.ListAPIKeys(db)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "API keys retrieved successfully", keys, page)
+}
+
+// handleRevokeAPIKey revokes an API key; it stops working at once
+func (s *Server) handleRevokeAPIKey(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	if err := auth.RevokeAPIKey(db, c.Params("id")); err != nil {
+		if errors.Is(err, auth.ErrInvalidAPIKey) {
+			return apperr.NotFound("API key not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "API key revoked successfully", nil))
+}
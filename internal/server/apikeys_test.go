@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// keyRequest sends a request authenticated with an API key
+func keyRequest(t *testing.T, server *Server, method, path, key string) *http.Response {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(HeaderAPIKey, key)
+
+	resp, err := send(server, req, -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestAPIKeys_ScopesConfineIntegrations(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if err := server.db.UpsertProduct(&database.Product{ID: "P1", Barcode: "4006381333931", SKU: "PEN"}, database.ProductSourceLocal); err != nil {
+		t.Fatalf("Failed to seed product: %v", err)
+	}
+
+	resp, _ := laneRequest(t, server, http.MethodPost, "/api-keys", adminBearer(t, server), `{"name":"Scale","scopes":["root"]}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown scope returned %d, want 400", resp.StatusCode)
+	}
+
+	resp, result := laneRequest(t, server, http.MethodPost, "/api-keys", adminBearer(t, server), `{"name":"Scale","scopes":["catalog:read"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Create returned %d", resp.StatusCode)
+	}
+	var created APIKeyCreation
+	json.Unmarshal(result, &created)
+	if created.Secret == "" || created.Key == nil {
+		t.Fatalf("Expected a key and its secret, got %s", result)
+	}
+
+	if resp := keyRequest(t, server, http.MethodGet, "/products/4006381333931", created.Secret); resp.StatusCode != http.StatusOK {
+		t.Errorf("Scoped route returned %d, want 200", resp.StatusCode)
+	}
+	if resp := keyRequest(t, server, http.MethodGet, "/health", created.Secret); resp.StatusCode != http.StatusOK {
+		t.Errorf("Health returned %d, want 200", resp.StatusCode)
+	}
+	for _, path := range []string{"/status", "/api-keys", "/devices"} {
+		if resp := keyRequest(t, server, http.MethodGet, path, created.Secret); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s returned %d, want 403", path, resp.StatusCode)
+		}
+	}
+	if resp := keyRequest(t, server, http.MethodGet, "/products/4006381333931", "posk_bogus_key"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Unknown key returned %d, want 401", resp.StatusCode)
+	}
+
+	resp, result = laneRequest(t, server, http.MethodGet, "/api-keys", adminBearer(t, server), "")
+	var keys []json.RawMessage
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(result, &keys) != nil || len(keys) != 1 {
+		t.Errorf("List returned %d: %s", resp.StatusCode, result)
+	}
+
+	if resp, _ := guardedRequest(t, server, http.MethodDelete, "/api-keys/"+created.Key.ID, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Revoke returned %d", resp.StatusCode)
+	}
+	if resp := keyRequest(t, server, http.MethodGet, "/products/4006381333931", created.Secret); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Revoked key returned %d, want 401", resp.StatusCode)
+	}
+	if resp, _ := guardedRequest(t, server, http.MethodDelete, "/api-keys/"+created.Key.ID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Revoking twice returned %d, want 404", resp.StatusCode)
+	}
+}
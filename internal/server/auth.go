@@ -0,0 +1,488 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// fiber.Ctx locals keys set by authenticate
+const (
+	localsRole   = "role"
+	localsToken  = "token"  // *auth.Token of callers presenting one
+	localsDevice = "device" // Device ID of handheld callers
+	localsAPIKey = "apikey" // *auth.APIKey of integrations presenting one
+)
+
+// HeaderAPIKey carries the API key of third-party integrations
+const HeaderAPIKey = "X-API-Key"
+
+// HeaderAdminPassword carries the admin password destructive routes ask for
+const HeaderAdminPassword = "X-Admin-Password"
+
+// routeRule allows one method on paths matching pattern
+type routeRule struct {
+	method  string
+	pattern *regexp.Regexp
+}
+
+// selfCheckoutRoutes is the API surface open to self-checkout callers:
+// scanning into their own cart, paying, printing the receipt, calling for
+// an attendant and reporting the lane's peripherals. Everything else needs
+// a staff or attendant token.
+var selfCheckoutRoutes = []routeRule{
+	{http.MethodGet, probeRoutes},
+	{http.MethodPost, regexp.MustCompile(`^/auth/token$`)},
+	{http.MethodGet, regexp.MustCompile(`^/price/[^/]+$`)},
+	{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/lines$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/lines$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/totals$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/suggestions$`)},
+	{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/checkout$`)},
+	{http.MethodDelete, regexp.MustCompile(`^/carts/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/sales/[^/]+/receipt$`)},
+	{http.MethodPost, regexp.MustCompile(`^/sco/interventions$`)},
+	{http.MethodGet, regexp.MustCompile(`^/sco/interventions/[^/]+$`)},
+	{http.MethodPut, regexp.MustCompile(`^/peripherals/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/ws/status$`)},
+	{http.MethodPost, regexp.MustCompile(`^/auth/stream-ticket$`)},
+	{http.MethodGet, regexp.MustCompile(`^/changes$`)},
+}
+
+// cashierRoutes is the API surface open to cashier tokens: building carts,
+// checking out, voiding, printing receipts and reporting the till's
+// peripherals. Refunds need a staff token.
+var cashierRoutes = []routeRule{
+	{http.MethodGet, probeRoutes},
+	{http.MethodPost, regexp.MustCompile(`^/auth/token$`)},
+	{http.MethodPost, regexp.MustCompile(`^/auth/pin$`)},
+	{http.MethodGet, regexp.MustCompile(`^/products$`)},
+	{http.MethodGet, regexp.MustCompile(`^/products/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/price/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/draft$`)},
+	{http.MethodPut, regexp.MustCompile(`^/carts/draft$`)},
+	{http.MethodDelete, regexp.MustCompile(`^/carts/draft$`)},
+	{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/lines$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/lines$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/totals$`)},
+	{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/suggestions$`)},
+	{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/checkout$`)},
+	{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/transfer$`)},
+	{http.MethodDelete, regexp.MustCompile(`^/carts/[^/]+$`)},
+	{http.MethodPost, regexp.MustCompile(`^/transfers/[^/]+/accept$`)},
+	{http.MethodGet, regexp.MustCompile(`^/sales/[^/]+/receipt$`)},
+	{http.MethodPost, regexp.MustCompile(`^/transactions$`)},
+	{http.MethodGet, regexp.MustCompile(`^/transactions/[^/]+$`)},
+	{http.MethodPost, regexp.MustCompile(`^/transactions/[^/]+/void$`)},
+	{http.MethodGet, regexp.MustCompile(`^/customers$`)},
+	{http.MethodGet, regexp.MustCompile(`^/customers/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/customers/[^/]+/loyalty$`)},
+	{http.MethodPost, regexp.MustCompile(`^/customers/[^/]+/loyalty$`)},
+	{http.MethodPut, regexp.MustCompile(`^/peripherals/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/ws/status$`)},
+	{http.MethodPost, regexp.MustCompile(`^/auth/stream-ticket$`)},
+	{http.MethodGet, regexp.MustCompile(`^/changes$`)},
+}
+
+// handheldRoutes is the API surface open to handheld stock-taking devices
+var handheldRoutes = []routeRule{
+	{http.MethodGet, probeRoutes},
+	{http.MethodGet, regexp.MustCompile(`^/products/[^/]+$`)},
+	{http.MethodGet, regexp.MustCompile(`^/price/[^/]+$`)},
+	{http.MethodPost, regexp.MustCompile(`^/stock/[^/]+/adjust$`)},
+	{http.MethodGet, regexp.MustCompile(`^/inventory/[^/]+$`)},
+	{http.MethodPost, regexp.MustCompile(`^/inventory/(receipts|adjustments|counts)$`)},
+	{http.MethodPost, regexp.MustCompile(`^/labels$`)},
+}
+
+// terminalRoutes is the API surface open to other lanes' services: the
+// store hub's API
+var terminalRoutes = regexp.MustCompile(`^/hub/.+$`)
+
+// scopeRoutes is the API surface each API key scope opens
+var scopeRoutes = map[string][]routeRule{
+	auth.ScopeCatalogRead: {
+		{http.MethodGet, regexp.MustCompile(`^/products$`)},
+		{http.MethodGet, regexp.MustCompile(`^/products/[^/]+$`)},
+		{http.MethodGet, regexp.MustCompile(`^/price/[^/]+$`)},
+	},
+	auth.ScopeStockWrite: {
+		{http.MethodPost, regexp.MustCompile(`^/stock/[^/]+/adjust$`)},
+		{http.MethodGet, regexp.MustCompile(`^/inventory$`)},
+		{http.MethodGet, regexp.MustCompile(`^/inventory/[^/]+$`)},
+		{http.MethodPost, regexp.MustCompile(`^/inventory/(receipts|adjustments|counts)$`)},
+	},
+	auth.ScopeLabelsWrite: {
+		{http.MethodPost, regexp.MustCompile(`^/labels$`)},
+	},
+	auth.ScopeCartsWrite: {
+		{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/lines$`)},
+		{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/lines$`)},
+		{http.MethodGet, regexp.MustCompile(`^/carts/[^/]+/totals$`)},
+		{http.MethodPost, regexp.MustCompile(`^/carts/[^/]+/checkout$`)},
+		{http.MethodDelete, regexp.MustCompile(`^/carts/[^/]+$`)},
+	},
+	auth.ScopeReceiptsRead: {
+		{http.MethodGet, regexp.MustCompile(`^/sales/[^/]+/receipt$`)},
+	},
+	auth.ScopeStatusRead: {
+		{http.MethodGet, regexp.MustCompile(`^/status$`)},
+		{http.MethodGet, regexp.MustCompile(`^/ws/status$`)},
+	},
+	auth.ScopeDataRead: {
+		{http.MethodGet, regexp.MustCompile(`^/graphql$`)},
+		{http.MethodPost, regexp.MustCompile(`^/graphql$`)},
+	},
+}
+
+// probeRoutes are the health, liveness and readiness probes, open to
+// every caller
+var probeRoutes = regexp.MustCompile(`^/(health|live|ready)$`)
+
+// streamRoutes accept a one-time ticket in a ticket query parameter, since
+// browsers cannot set headers on WebSocket or EventSource requests
+var streamRoutes = regexp.MustCompile(`^/(ws/status|sync/events)$`)
+
+// publicRoutes are open to callers without credentials: the probes and
+// the sign-in routes that hand out tokens
+var publicRoutes = []routeRule{
+	{http.MethodGet, probeRoutes},
+	{http.MethodPost, regexp.MustCompile(`^/auth/(token|pin)$`)},
+}
+
+// authenticate resolves the caller's role from an API key, a bearer token
+// or a frontend JWT. Callers without one only reach publicRoutes.
+// Self-checkout and handheld callers are confined to their route lists,
+// integrations to the routes their key's scopes open. Addresses presenting
+// bad credentials too often are locked out.
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	role := ""
+
+	if key := c.Get(HeaderAPIKey); key != "" {
+		if err := s.checkLockout(c, credentialAPIKey); err != nil {
+			return err
+		}
+		db, err := s.requireDB()
+		if err != nil {
+			return err
+		}
+		k, err := auth.LookupAPIKey(db, key)
+		if errors.Is(err, auth.ErrInvalidAPIKey) {
+			return s.authFailed(c, credentialAPIKey, "invalid API key", apperr.Unauthorized("Invalid or revoked API key"))
+		}
+		if err != nil {
+			return apperr.Database(err)
+		}
+		c.Locals(localsAPIKey, k)
+		return s.authorize(c, auth.RoleIntegration, nil)
+	}
+
+	var token *auth.Token
+	if ticket := c.Query("ticket"); ticket != "" && c.Get(fiber.HeaderAuthorization) == "" && streamRoutes.MatchString(routePath(c)) {
+		if err := s.checkLockout(c, credentialToken); err != nil {
+			return err
+		}
+		token = s.tickets.redeem(ticket)
+		if token == nil {
+			return s.authFailed(c, credentialToken, "invalid stream ticket", apperr.Unauthorized("Invalid, used or expired ticket"))
+		}
+		c.Locals(localsToken, token)
+		return s.authorize(c, token.Role, token)
+	}
+
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		if err := s.checkLockout(c, credentialToken); err != nil {
+			return err
+		}
+		bearer, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return apperr.Unauthorized("Authorization must be a bearer token")
+		}
+		t, reason, err := s.lookupBearer(bearer)
+		if reason != "" {
+			return s.authFailed(c, credentialToken, reason, apperr.Unauthorized("Invalid or expired token"))
+		}
+		if err != nil {
+			return err
+		}
+		role, token = t.Role, t
+		c.Locals(localsToken, t)
+	}
+
+	if token == nil && !allowed(publicRoutes, c.Method(), routePath(c)) {
+		return apperr.Unauthorized("Authentication required")
+	}
+	return s.authorize(c, role, token)
+}
+
+// lookupBearer resolves a bearer token or frontend JWT. A token that is
+// unknown, expired or forged is reported as the reason it failed rather
+// than an error. Anyone holding api_secret can sign a JWT, the frontend of
+// a self-checkout kiosk included, so a JWT only ever grants this lane's
+// role; one claiming any other is refused.
+func (s *Server) lookupBearer(bearer string) (*auth.Token, string, error) {
+	if len(s.config.APISecret) > 0 && auth.IsJWT(bearer) {
+		claims, err := auth.ParseJWT(s.config.APISecret, bearer)
+		if err != nil {
+			return nil, "invalid JWT", nil
+		}
+		if claims.Role != s.laneRole() {
+			return nil, "JWT claims role " + claims.Role, nil
+		}
+		return &auth.Token{Role: claims.Role, Label: claims.Subject, IssuedAt: claims.IssuedAt.Format(time.RFC3339)}, "", nil
+	}
+	db, err := s.requireDB()
+	if err != nil {
+		return nil, "", err
+	}
+	t, err := auth.Lookup(db, bearer)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, "invalid token", nil
+	}
+	if err != nil {
+		return nil, "", apperr.Database(err)
+	}
+	return t, "", nil
+}
+
+// authorize confines the resolved role to the routes it may call
+func (s *Server) authorize(c *fiber.Ctx, role string, token *auth.Token) error {
+	c.Locals(localsRole, role)
+
+	if err := routeForbidden(role, callerAPIKey(c), c.Method(), routePath(c)); err != nil {
+		if role == auth.RoleHandheld {
+			s.recordDeviceAudit(c.UserContext(), token.Label, c.Method()+" "+routePath(c), fiber.StatusForbidden, "outside handheld profile")
+		}
+		return err
+	}
+	if role == auth.RoleHandheld {
+		return s.auditDevice(c, token.Label)
+	}
+
+	return c.Next()
+}
+
+// routeForbidden rejects a call to method and path outside the route list
+// of role; key is the API key of integrations
+func routeForbidden(role string, key *auth.APIKey, method, path string) error {
+	switch role {
+	case auth.RoleSelfCheckout:
+		if !allowed(selfCheckoutRoutes, method, path) {
+			return apperr.Forbidden(auth.RoleAttendant)
+		}
+	case auth.RoleCashier:
+		if !allowed(cashierRoutes, method, path) {
+			return apperr.Forbidden(auth.RoleStaff)
+		}
+	case auth.RoleHandheld:
+		if !allowed(handheldRoutes, method, path) {
+			return apperr.Forbidden(auth.RoleStaff)
+		}
+	case auth.RoleTerminal:
+		if !terminalRoutes.MatchString(path) {
+			return apperr.Forbidden(auth.RoleStaff)
+		}
+	case auth.RoleIntegration:
+		if !keyAllowed(key, method, path) {
+			return apperr.Forbidden(auth.RoleStaff)
+		}
+	}
+	return nil
+}
+
+// adminRoles may call routes annotated with requireAdmin
+var adminRoles = []string{auth.RoleAdmin}
+
+// requireRole annotates a route with the roles allowed to call it, on top
+// of the route lists authorize applies
+func requireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role := callerRole(c)
+		for _, r := range roles {
+			if r == role {
+				return c.Next()
+			}
+		}
+		return apperr.Forbidden(roles[0])
+	}
+}
+
+// requireAdmin limits a route to admin callers
+var requireAdmin = requireRole(adminRoles...)
+
+// requireAdminPassword guards destructive routes (service control,
+// configuration changes, backups and restores, deleting users, devices and
+// API keys, rotating the server key) with the admin password, on top of the
+// caller's token. They refuse every caller until a password is set
+// (pos-service -set-admin-password). Wrong passwords count towards the
+// lockout like bad tokens.
+func (s *Server) requireAdminPassword(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	if err := s.checkLockout(c, credentialAdminPassword); err != nil {
+		return err
+	}
+
+	err = auth.CheckAdminPassword(db, c.Get(HeaderAdminPassword))
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrNoAdminPassword):
+		return apperr.New(fiber.StatusForbidden, api.CodeErrorForbidden, "Set an admin password first (pos-service -set-admin-password)")
+	case errors.Is(err, auth.ErrInvalidPassword):
+		return s.authFailed(c, credentialAdminPassword, "invalid admin password", apperr.Unauthorized("Admin password required"))
+	default:
+		return apperr.Database(err)
+	}
+	return c.Next()
+}
+
+// laneRole is the role of the frontend JWTs /auth/token issues: staff on a
+// till, the restricted self-checkout role on an SCO lane
+func (s *Server) laneRole() string {
+	if s.config.Profile == constants.LaneProfileSelfCheckout {
+		return auth.RoleSelfCheckout
+	}
+	return auth.RoleStaff
+}
+
+// FrontendToken is a frontend JWT and its expiry
+type FrontendToken struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"` // ISO 8601 timestamp
+}
+
+// handleIssueFrontendToken trades the shared api_secret for a frontend JWT
+// carrying this lane's role
+func (s *Server) handleIssueFrontendToken(c *fiber.Ctx) error {
+	if len(s.config.APISecret) == 0 {
+		return apperr.NotFound("Frontend tokens are not enabled")
+	}
+
+	var body struct {
+		Secret string `json:"secret" validate:"required"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+	if err := s.checkLockout(c, credentialAPISecret); err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(body.Secret), s.config.APISecret) != 1 {
+		return s.authFailed(c, credentialAPISecret, "wrong api_secret", apperr.Unauthorized("Wrong secret"))
+	}
+
+	token, expires, err := auth.IssueJWT(s.config.APISecret, s.laneRole(), auth.FrontendSubject, auth.DefaultJWTTTL)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Frontend token issued", FrontendToken{
+		Token:     token,
+		ExpiresAt: expires.UTC().Format(time.RFC3339),
+	}))
+}
+
+// auditDevice runs a handheld request and records it in the device's audit
+// trail. Tokens of unregistered devices stop working immediately.
+func (s *Server) auditDevice(c *fiber.Ctx, deviceID string) error {
+	if err := s.checkDevice(deviceID); err != nil {
+		return err
+	}
+	c.Locals(localsDevice, deviceID)
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = apperr.From(err).Status
+	}
+	s.recordDeviceAudit(c.UserContext(), deviceID, c.Method()+" "+unversioned(c.Route().Path), status, routePath(c))
+
+	return err
+}
+
+// checkDevice rejects handheld tokens of devices no longer registered
+func (s *Server) checkDevice(deviceID string) error {
+	if _, err := s.db.GetDevice(deviceID); err != nil {
+		if errors.Is(err, database.ErrDeviceNotFound) {
+			return apperr.Unauthorized("Device is no longer registered")
+		}
+		return apperr.Database(err)
+	}
+	return nil
+}
+
+// recordDeviceAudit appends to a device's audit trail, logging failures
+// rather than failing the request that was already served
+func (s *Server) recordDeviceAudit(ctx context.Context, deviceID, action string, status int, detail string) {
+	entry := &database.DeviceAuditEntry{DeviceID: deviceID, Action: action, Status: status, Detail: detail}
+	if err := s.db.RecordDeviceAudit(entry); err != nil {
+		logging.Printf(ctx, "Warning: failed to record audit for device %s: %v", deviceID, err)
+	}
+}
+
+// allowed reports whether a rule matches method and path
+func allowed(rules []routeRule, method, path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, rule := range rules {
+		if rule.method == method && rule.pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyAllowed reports whether any scope of an API key opens method and path
+func keyAllowed(key *auth.APIKey, method, path string) bool {
+	if key == nil {
+		return false
+	}
+	if method == http.MethodGet && probeRoutes.MatchString(strings.TrimSuffix(path, "/")) {
+		return true
+	}
+	for _, scope := range key.Scopes {
+		if allowed(scopeRoutes[scope], method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// callerRole returns the role resolved by authenticate
+func callerRole(c *fiber.Ctx) string {
+	role, _ := c.Locals(localsRole).(string)
+	return role
+}
+
+// callerToken returns the bearer token presented by the caller, or nil
+// for API key and anonymous callers
+func callerToken(c *fiber.Ctx) *auth.Token {
+	token, _ := c.Locals(localsToken).(*auth.Token)
+	return token
+}
+
+// callerAPIKey returns the API key presented by an integration, or nil
+func callerAPIKey(c *fiber.Ctx) *auth.APIKey {
+	key, _ := c.Locals(localsAPIKey).(*auth.APIKey)
+	return key
+}
+
+// callerDevice returns the device ID of a handheld caller ("" otherwise)
+func callerDevice(c *fiber.Ctx) string {
+	device, _ := c.Locals(localsDevice).(string)
+	return device
+}
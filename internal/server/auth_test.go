@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// anonymousRequest sends a request without credentials and returns the
+// response and its result
+func anonymousRequest(t *testing.T, server *Server, method, path, body string) (*http.Response, json.RawMessage) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.GetApp().Test(req, -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	json.NewDecoder(resp.Body).Decode(&envelope)
+	return resp, envelope.Result
+}
+
+func TestAuthenticate_RequiresCredentials(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APISecret = []byte("0123456789abcdef0123456789abcdef")
+	server := New(cfg)
+
+	// Only the probes and the sign-in routes are public
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/health"},
+		{http.MethodGet, "/live"},
+		{http.MethodGet, "/ready"},
+		{http.MethodPost, "/auth/token"},
+		{http.MethodPost, "/auth/pin"},
+	} {
+		if resp, _ := anonymousRequest(t, server, tc.method, tc.path, "{}"); resp.StatusCode == http.StatusUnauthorized {
+			t.Errorf("%s %s: expected a public route, got 401", tc.method, tc.path)
+		}
+	}
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/status"},
+		{http.MethodGet, "/config"},
+		{http.MethodPost, "/data"},
+		{http.MethodGet, "/products"},
+		{http.MethodPost, "/carts/C1/lines"},
+		{http.MethodPost, "/service/restart"},
+		{http.MethodGet, "/hub/transfers"},
+		{http.MethodGet, "/no-such-route"},
+		{http.MethodGet, "/status/"},
+		{http.MethodGet, APIPrefix + "/status"},
+	} {
+		if resp, _ := anonymousRequest(t, server, tc.method, tc.path, ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without credentials, got %d", tc.method, tc.path, resp.StatusCode)
+		}
+	}
+}
+
+func TestRoles_AdminAndCashierRoutes(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.Service = &fakeService{}
+	server.config.APISecret = []byte("0123456789abcdef0123456789abcdef")
+
+	tokens := make(map[string]string)
+	for _, role := range []string{auth.RoleAdmin, auth.RoleStaff, auth.RoleAttendant, auth.RoleCashier} {
+		token, err := auth.Issue(server.db, role, role, 0)
+		if err != nil {
+			t.Fatalf("Issue %s failed: %v", role, err)
+		}
+		tokens[role] = token
+	}
+
+	// The frontend JWT /auth/token issues carries the till's staff role
+	frontend, _, err := auth.IssueJWT(server.config.APISecret, server.laneRole(), auth.FrontendSubject, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+
+	// API keys and the audit log need an admin
+	for _, tc := range []struct {
+		token string
+		want  int
+	}{
+		{tokens[auth.RoleAdmin], http.StatusOK},
+		{tokens[auth.RoleStaff], http.StatusForbidden},
+		{frontend, http.StatusForbidden},
+		{tokens[auth.RoleAttendant], http.StatusForbidden},
+		{tokens[auth.RoleCashier], http.StatusForbidden},
+	} {
+		for _, path := range []string{"/api-keys", "/audit"} {
+			if resp, _ := laneRequest(t, server, http.MethodGet, path, tc.token, ""); resp.StatusCode != tc.want {
+				t.Errorf("%s: expected %d, got %d", path, tc.want, resp.StatusCode)
+			}
+		}
+	}
+
+	// Cashiers may ring up sales and nothing else
+	cashier := tokens[auth.RoleCashier]
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/carts/C1/lines", cashier, `{"lines":[{"sku":"MILK","quantity":1,"price":990}]}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Cashier cart append returned %d, want 200", resp.StatusCode)
+	}
+	for _, path := range []string{"/status", "/config", "/devices", "/operators/op1/stats"} {
+		if resp, _ := laneRequest(t, server, http.MethodGet, path, cashier, ""); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Cashier reached %s with %d, want 403", path, resp.StatusCode)
+		}
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/status", tokens[auth.RoleAttendant], ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Attendant /status returned %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestRoles_AdminOnlyRoutes(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.Service = &fakeService{}
+	if err := server.db.RegisterDevice(&database.Device{ID: "HH1", Name: "Aisle scanner"}); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+
+	labels := map[string]string{
+		auth.RoleStaff:        "till",
+		auth.RoleSelfCheckout: "kiosk",
+		auth.RoleHandheld:     "HH1",
+		auth.RoleCashier:      "cashier",
+	}
+	for role, label := range labels {
+		token, err := auth.Issue(server.db, role, label, 0)
+		if err != nil {
+			t.Fatalf("Issue %s failed: %v", role, err)
+		}
+		for _, tc := range []struct{ method, path, body string }{
+			{http.MethodPost, "/service/stop", ""},
+			{http.MethodPost, "/service/start", ""},
+			{http.MethodPut, "/config", `{}`},
+			{http.MethodPost, "/users", `{}`},
+			{http.MethodDelete, "/users/u1", ""},
+			{http.MethodPost, "/backups", ""},
+			{http.MethodGet, "/api-keys", ""},
+			{http.MethodGet, "/audit", ""},
+			{http.MethodPost, "/devices", `{"id":"HH2"}`},
+			{http.MethodDelete, "/devices/HH1", ""},
+			{http.MethodGet, "/devices/HH1/audit", ""},
+			{http.MethodPost, "/day/close", `{"drawer_reconciled":true}`},
+		} {
+			if resp, _ := laneRequest(t, server, tc.method, tc.path, token, tc.body); resp.StatusCode != http.StatusForbidden {
+				t.Errorf("%s: %s %s returned %d, want 403", role, tc.method, tc.path, resp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestAdminPassword_GuardsDestructiveRoutes(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.Service = &fakeService{}
+	admin, _ := auth.Issue(server.db, auth.RoleAdmin, "owner", 0)
+
+	stop := func(password string) int {
+		req := httptest.NewRequest(http.MethodPost, "/service/stop", nil)
+		req.Header.Set("Authorization", "Bearer "+admin)
+		if password != "" {
+			req.Header.Set(HeaderAdminPassword, password)
+		}
+		resp, err := send(server, req, -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Refused to everyone until a password is set
+	if code := stop(""); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 before a password is set, got %d", code)
+	}
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/service/start"},
+		{http.MethodPut, "/config"},
+		{http.MethodPost, "/backups"},
+		{http.MethodPost, "/backups/data-20000101-000000.db/restore"},
+		{http.MethodDelete, "/users/u1"},
+		{http.MethodDelete, "/devices/HH1"},
+		{http.MethodDelete, "/api-keys/k1"},
+	} {
+		if resp, _ := laneRequest(t, server, tc.method, tc.path, admin, `{}`); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403 before a password is set, got %d", tc.method, tc.path, resp.StatusCode)
+		}
+	}
+
+	if err := auth.SetAdminPassword(server.db, "correct horse battery"); err != nil {
+		t.Fatalf("SetAdminPassword failed: %v", err)
+	}
+	if code := stop(""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the password, got %d", code)
+	}
+	if code := stop("wrong horse battery"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong password, got %d", code)
+	}
+	if code := stop("correct horse battery"); code != http.StatusAccepted {
+		t.Errorf("Expected 202 with the password, got %d", code)
+	}
+
+	// The admin token alone no longer rotates the server key
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/security/rotate-key", admin, `{"wrapped_key":"x"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected key rotation to need the password, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/receipt"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Large baskets (wholesale orders of hundreds of lines) are built in chunks
+// with POST /carts/:id/lines so no single request carries the whole order.
+// Totals are kept incrementally, checkout commits the stored lines through
+// the sale ledger, and receipts are served one printer-sized page at a time.
+
+// CheckoutRequest finalizes a basket into a sale
+type CheckoutRequest struct {
+	SaleID      string `json:"sale_id,omitempty"` // Defaults to the basket ID so retries stay idempotent
+	Type        string `json:"type,omitempty"`
+	OperatorID  string `json:"operator_id,omitempty"` // Ignored in PIN sessions, which use the signed-in user
+	VoidedLines int    `json:"voided_lines,omitempty"`
+	ScanSeconds int    `json:"scan_seconds,omitempty"`
+}
+
+// CheckoutResponse reports a committed basket
+type CheckoutResponse struct {
+	SaleID       string `json:"sale_id"`
+	Duplicate    bool   `json:"duplicate"`
+	LineCount    int    `json:"line_count"`
+	Total        int64  `json:"total"`
+	ReceiptPages int    `json:"receipt_pages"`
+}
+
+// basketTooLarge creates the 413 error for oversized chunks and baskets
+func basketTooLarge(message string) *apperr.Error {
+	return apperr.New(http.StatusRequestEntityTooLarge, api.CodeErrorBadRequest, message)
+}
+
+// basketID returns the validated basket ID from the path
+func basketID(c *fiber.Ctx) (string, error) {
+	id := c.Params("id")
+	if id == cartIDDraft || !terminalIDPattern.MatchString(id) {
+		return "", apperr.BadRequest("Invalid cart ID")
+	}
+	return id, nil
+}
+
+// ownedBasket returns the totals of the caller's basket. Baskets of other
+// terminals are reported as missing.
+func ownedBasket(c *fiber.Ctx, db *database.DB) (*database.BasketTotals, error) {
+	id, err := basketID(c)
+	if err != nil {
+		return nil, err
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := db.GetBasketTotals(id)
+	if errors.Is(err, database.ErrBasketNotFound) || (err == nil && totals.TerminalID != terminal) {
+		return nil, apperr.NotFound("Cart not found")
+	}
+	if err != nil {
+		return nil, apperr.Database(err)
+	}
+	return totals, nil
+}
+
+// handleAppendBasketLines adds a chunk of lines and returns running totals
+func (s *Server) handleAppendBasketLines(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	id, err := basketID(c)
+	if err != nil {
+		return err
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Lines []database.SaleLine `json:"lines" validate:"required,min=1,dive"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+	if len(body.Lines) > constants.MaxBasketChunkLines {
+		return basketTooLarge(fmt.Sprintf("At most %d lines per request; send the basket in chunks", constants.MaxBasketChunkLines))
+	}
+	selfCheckout := callerRole(c) == auth.RoleSelfCheckout
+	if selfCheckout {
+		if err := s.checkSelfCheckoutLimit(db, id, body.Lines); err != nil {
+			return err
+		}
+	}
+
+	totals, err := db.AppendBasketLines(id, terminal, body.Lines, constants.MaxBasketLines)
+	switch {
+	case errors.Is(err, database.ErrBasketNotFound):
+		return apperr.NotFound("Cart not found")
+	case errors.Is(err, database.ErrBasketTooLarge):
+		return basketTooLarge(fmt.Sprintf("A cart may hold at most %d lines", constants.MaxBasketLines))
+	case errors.Is(err, database.ErrInvalidBasketLine):
+		return apperr.BadRequest(err.Error())
+	case err != nil:
+		return apperr.Database(err)
+	}
+
+	// Call the attendant early; checkout re-checks if this fails
+	if selfCheckout {
+		if err := s.raiseAgeChecks(c.UserContext(), id, terminal, body.Lines); err != nil {
+			logging.Printf(c.UserContext(), "Warning: failed to raise age check on cart %s: %v", id, err)
+		}
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Cart lines added successfully", totals))
+}
+
+// handleGetBasketTotals returns the basket's running totals
+func (s *Server) handleGetBasketTotals(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	totals, err := ownedBasket(c, db)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Cart totals retrieved successfully", totals))
+}
+
+// handleGetBasketLines returns one page of basket lines, in scan order
+func (s *Server) handleGetBasketLines(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	totals, err := ownedBasket(c, db)
+	if err != nil {
+		return err
+	}
+
+	page, err := pageRequest(c, constants.MaxBasketChunkLines, constants.MaxBasketChunkLines)
+	if err != nil {
+		return err
+	}
+
+	lines, err := db.GetBasketLines(totals.BasketID, page.Offset(), page.PerPage)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	if lines == nil {
+		lines = []database.SaleLine{}
+	}
+
+	return c.JSON(api.NewListResponse("Cart lines retrieved successfully", lines, api.NewPagination(page, totals.LineCount)))
+}
+
+// handleCheckoutBasket commits the basket as a sale through the ledger
+func (s *Server) handleCheckoutBasket(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	if s.ledger == nil {
+		return apperr.Unavailable(api.MessageServiceUnavailable, 5*time.Second)
+	}
+	totals, err := ownedBasket(c, db)
+	if err != nil {
+		return err
+	}
+
+	var req CheckoutRequest
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &req); err != nil {
+			return apperr.BadRequest("Invalid checkout request")
+		}
+	}
+	if req.SaleID == "" {
+		req.SaleID = totals.BasketID
+	}
+	// Sales rung up in a PIN session belong to the signed-in cashier
+	if token := callerToken(c); token != nil && token.UserID != "" {
+		req.OperatorID = token.UserID
+	}
+
+	lines, err := db.GetBasketLines(totals.BasketID, 0, 0)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	if callerRole(c) == auth.RoleSelfCheckout {
+		if err := s.checkInterventions(c.UserContext(), totals.BasketID, totals.TerminalID, lines); err != nil {
+			return err
+		}
+	}
+
+	sale := &database.Sale{
+		ID:          req.SaleID,
+		Type:        req.Type,
+		TerminalID:  totals.TerminalID,
+		OperatorID:  req.OperatorID,
+		Lines:       lines,
+		Total:       totals.Total,
+		VoidedLines: req.VoidedLines,
+		ScanSeconds: req.ScanSeconds,
+	}
+	if err := sale.Validate(); err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	result, err := s.commitSale(c.UserContext(), sale)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	// The sale is durable; a leftover basket is pruned by retention
+	if err := db.DeleteBasket(totals.BasketID); err != nil {
+		logging.Printf(c.UserContext(), "Warning: failed to delete checked-out cart %s: %v", totals.BasketID, err)
+	}
+	s.completeTransfer(c.UserContext(), totals.BasketID, totals.TerminalID, result.SaleID)
+	s.clearSCOHold(totals.BasketID)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Cart checked out successfully", CheckoutResponse{
+		SaleID:       result.SaleID,
+		Duplicate:    result.Duplicate,
+		LineCount:    len(lines),
+		Total:        sale.Total,
+		ReceiptPages: len(receipt.Paginate(sale, receipt.Options{})),
+	}))
+}
+
+// handleDeleteBasket abandons a basket
+func (s *Server) handleDeleteBasket(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	totals, err := ownedBasket(c, db)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteBasket(totals.BasketID); err != nil {
+		if errors.Is(err, database.ErrBasketNotFound) {
+			return apperr.NotFound("Cart not found")
+		}
+		return apperr.Database(err)
+	}
+	s.releaseTransfer(c.UserContext(), totals.BasketID, totals.TerminalID)
+	s.clearSCOHold(totals.BasketID)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "Cart discarded successfully", nil))
+}
+
+// handleGetReceiptPage returns one printer-sized page of a sale's receipt
+// (?page, default 1; ?page_bytes caps the page for small printer buffers;
+// ?printer adds the page encoded as ESC/POS for a configured printer)
+func (s *Server) handleGetReceiptPage(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var printer receipt.Printer
+	name := c.Query("printer")
+	if name != "" {
+		var ok bool
+		if printer, ok = s.config.Printers[name]; !ok {
+			return apperr.NotFound(fmt.Sprintf("Printer %q is not configured", name))
+		}
+	}
+	width := printer.Width
+	if width <= 0 {
+		width = receipt.DefaultWidth
+	}
+
+	sale, err := db.GetSale(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrSaleNotFound) {
+			return apperr.NotFound("Sale not found")
+		}
+		return apperr.Database(err)
+	}
+
+	pages := receipt.Paginate(sale, receipt.Options{
+		Width:     c.QueryInt("width", width),
+		PageBytes: c.QueryInt("page_bytes", constants.DefaultReceiptPageBytes),
+	})
+
+	page := c.QueryInt("page", 1)
+	if page < 1 || page > len(pages) {
+		return apperr.NotFound(fmt.Sprintf("Receipt has %d page(s)", len(pages)))
+	}
+
+	result := pages[page-1]
+	if name != "" {
+		encoder, err := receipt.NewEncoder(printer)
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		result.ESCPOS = encoder.Encode(result.Lines)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Receipt page retrieved successfully", result))
+}
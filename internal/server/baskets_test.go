@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/hub"
+	"github.com/professor93/promo-pos/internal/journal"
+	"github.com/professor93/promo-pos/internal/receipt"
+	"github.com/professor93/promo-pos/internal/sales"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func newTestServerWithLedger(t *testing.T) *Server {
+	serverKey, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+
+	db, err := database.New(&database.Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	encryption, _ := security.NewDatabaseEncryption(serverKey)
+	j, err := journal.Open(filepath.Join(dir, "sales.journal"), encryption)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+
+	ledger, err := sales.NewLedger(db, j)
+	if err != nil {
+		t.Fatalf("Failed to create ledger: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.DB = db
+	cfg.Ledger = ledger
+	return New(cfg)
+}
+
+func cartRequest(t *testing.T, server *Server, method, path, body string) (*http.Response, json.RawMessage) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTerminalID, "T1")
+
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &envelope)
+
+	return resp, envelope.Result
+}
+
+func chunkBody(chunk, size int) string {
+	lines := make([]database.SaleLine, size)
+	for i := range lines {
+		lines[i] = database.SaleLine{SKU: fmt.Sprintf("SKU-%d-%d", chunk, i), Quantity: 1, Price: 250}
+	}
+	body, _ := json.Marshal(map[string]interface{}{"lines": lines})
+	return string(body)
+}
+
+func TestBasket_ChunkedCheckoutAndReceipt(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	for chunk := 0; chunk < 3; chunk++ {
+		resp, _ := cartRequest(t, server, "POST", "/carts/order-1/lines", chunkBody(chunk, 200))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Chunk %d: expected 200, got %d", chunk, resp.StatusCode)
+		}
+	}
+
+	resp, data := cartRequest(t, server, "GET", "/carts/order-1/totals", "")
+	var totals database.BasketTotals
+	json.Unmarshal(data, &totals)
+	if resp.StatusCode != http.StatusOK || totals.LineCount != 600 || totals.Total != 150000 {
+		t.Fatalf("Unexpected totals (%d): %+v", resp.StatusCode, totals)
+	}
+
+	resp, data = cartRequest(t, server, "POST", "/carts/order-1/checkout", `{"operator_id":"op-1"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Checkout: expected 200, got %d", resp.StatusCode)
+	}
+	var checkout CheckoutResponse
+	json.Unmarshal(data, &checkout)
+	if checkout.SaleID != "order-1" || checkout.LineCount != 600 || checkout.ReceiptPages < 2 {
+		t.Errorf("Unexpected checkout result: %+v", checkout)
+	}
+
+	// The cart is gone once the sale is committed
+	if resp, _ := cartRequest(t, server, "GET", "/carts/order-1/totals", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected checked-out cart to be removed, got %d", resp.StatusCode)
+	}
+
+	resp, data = cartRequest(t, server, "GET", fmt.Sprintf("/sales/order-1/receipt?page=%d", checkout.ReceiptPages), "")
+	var page receipt.Page
+	json.Unmarshal(data, &page)
+	if resp.StatusCode != http.StatusOK || !page.Last || page.Subtotal != 150000 {
+		t.Errorf("Unexpected last receipt page (%d): %+v", resp.StatusCode, page)
+	}
+
+	// A configured printer gets the page as ESC/POS in its code page
+	server.config.Printers = map[string]receipt.Printer{"front": {Encoding: receipt.EncodingCP866}}
+	resp, data = cartRequest(t, server, "GET", "/sales/order-1/receipt?printer=front", "")
+	page = receipt.Page{}
+	json.Unmarshal(data, &page)
+	if resp.StatusCode != http.StatusOK || !bytes.HasPrefix(page.ESCPOS, []byte{0x1b, '@', 0x1b, 't', 17}) {
+		t.Errorf("Expected an ESC/POS page for the printer (%d): %q", resp.StatusCode, page.ESCPOS)
+	}
+	if resp, _ := cartRequest(t, server, "GET", "/sales/order-1/receipt?printer=back", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown printer, got %d", resp.StatusCode)
+	}
+}
+
+func TestBasket_RejectsOversizedChunk(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	resp, _ := cartRequest(t, server, "POST", "/carts/order-1/lines", chunkBody(0, 201))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", resp.StatusCode)
+	}
+}
+
+func TestBasket_TransferBetweenTerminals(t *testing.T) {
+	hubServer := newTestServerWithDB(t)
+	storeHub, err := hub.New(&hub.Config{DB: hubServer.db})
+	if err != nil {
+		t.Fatalf("Failed to create hub: %v", err)
+	}
+	storeHub.Register(hubServer.API().Group("/hub"))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go hubServer.GetApp().Listener(ln)
+	t.Cleanup(func() { hubServer.Shutdown() })
+
+	hubToken, err := auth.Issue(hubServer.db, auth.RoleTerminal, "TILL1", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	server := newTestServerWithLedger(t)
+	server.hub = hub.NewClient("http://"+ln.Addr().String()+"/hub", hubToken, nil)
+
+	// Handheld H1 scans, then hands the cart over
+	req := func(method, path, terminal, body string) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(HeaderTerminalID, terminal)
+		resp, err := send(server, asAdmin(t, server, r), -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	if resp := req("POST", "/carts/cart-1/lines", "H1", chunkBody(0, 5)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Append: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := req("POST", "/carts/cart-1/transfer", "H1", `{"target_terminal":"TILL1"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Transfer: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := req("POST", "/carts/cart-1/checkout", "H1", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected source cart to be gone after handoff, got %d", resp.StatusCode)
+	}
+
+	// Only the addressed till can accept, and only once
+	if resp := req("POST", "/transfers/H1.cart-1/accept", "TILL2", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for another till, got %d", resp.StatusCode)
+	}
+	if resp := req("POST", "/transfers/H1.cart-1/accept", "TILL1", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Accept: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := req("POST", "/carts/H1.cart-1/checkout", "TILL1", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Checkout: expected 200, got %d", resp.StatusCode)
+	}
+
+	r := httptest.NewRequest("GET", "/hub/transfers/H1.cart-1", nil)
+	resp, err := send(hubServer, asAdmin(t, hubServer, r), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var envelope struct {
+		Result hub.Transfer `json:"result"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &envelope)
+	transfer := envelope.Result
+	if transfer.State != hub.TransferCompleted || transfer.SaleID != "H1.cart-1" || transfer.ClaimedBy != "TILL1" {
+		t.Errorf("Expected completed transfer, got %+v", transfer)
+	}
+}
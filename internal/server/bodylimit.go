@@ -0,0 +1,51 @@
+package server
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/valyala/fasthttp"
+)
+
+// importRoute takes file uploads, allowed the larger ImportBodyLimit
+const importRoute = "/import"
+
+// limitBody refuses request bodies over the route's limit. Bodies are
+// streamed (fiber.Config.StreamRequestBody), so this runs on the headers:
+// a declared Content-Length is checked before anything is read, a chunked
+// body is read only up to the limit. Fiber's own BodyLimit, set to the
+// larger of the two limits, rejects anything over both earlier still.
+func (s *Server) limitBody(c *fiber.Ctx) error {
+	limit := s.bodyLimit
+	if routePath(c) == importRoute {
+		limit = s.importBodyLimit
+	}
+
+	req := c.Request()
+	length := req.Header.ContentLength()
+	if length > limit {
+		return bodyTooLarge(c, limit)
+	}
+
+	// Chunked: no length up front, so read one byte past the limit
+	if length == -1 && req.IsBodyStream() {
+		body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+		if err != nil && !errors.Is(err, fasthttp.ErrBodyTooLarge) {
+			return apperr.BadRequest("Request body could not be read")
+		}
+		if err != nil || len(body) > limit {
+			return bodyTooLarge(c, limit)
+		}
+		req.SetBody(body)
+	}
+	return c.Next()
+}
+
+// bodyTooLarge answers 413 and closes the connection, discarding the
+// rest of the body instead of reading it
+func bodyTooLarge(c *fiber.Ctx, limit int) error {
+	c.Context().SetConnectionClose()
+	return apperr.TooLarge(limit)
+}
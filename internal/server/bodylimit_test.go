@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+)
+
+func TestLimitBody(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BodyLimit = 64
+	cfg.ImportBodyLimit = 256
+	server := New(cfg)
+
+	post := func(path string, size int, chunked bool) (*http.Response, api.APIResponse) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(strings.Repeat("x", size)))
+		if chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		resp, err := send(server, asLane(t, server, req), -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var envelope api.APIResponse
+		data, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(data, &envelope)
+		return resp, envelope
+	}
+
+	resp, envelope := post("/data", 100, false)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || envelope.Code != api.CodeErrorBadRequest {
+		t.Fatalf("Oversized body: expected 413, got %d %+v", resp.StatusCode, envelope)
+	}
+	if meta, _ := envelope.Meta.(map[string]interface{}); meta["doc_code"] != apperr.DocTooLarge {
+		t.Errorf("Expected doc code %s, got %v", apperr.DocTooLarge, envelope.Meta)
+	}
+	if resp, _ := post("/data", 100, true); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Oversized chunked body: expected 413, got %d", resp.StatusCode)
+	}
+
+	// Within the limit the handler answers (no database here)
+	if resp, _ := post("/data", 10, false); resp.StatusCode == http.StatusRequestEntityTooLarge {
+		t.Error("Small body refused")
+	}
+	if resp, _ := post("/data", 10, true); resp.StatusCode == http.StatusRequestEntityTooLarge {
+		t.Error("Small chunked body refused")
+	}
+
+	// Imports get the larger limit
+	if resp, _ := post("/import", 100, false); resp.StatusCode == http.StatusRequestEntityTooLarge {
+		t.Error("Import under its limit refused")
+	}
+	if resp, _ := post("/import", 300, true); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Oversized import: expected 413, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// GET /changes?since=<cursor> is a long poll for frontends that can't keep
+// a WebSocket open: it answers as soon as local data changed after the
+// cursor (a pushed price update, a promotion starting, a sale), or after
+// ChangesWaitSeconds with nothing new. The caller reacts to the kinds that
+// changed, refetching what it shows, and polls again with the new cursor.
+
+// Kinds of change
+const (
+	ChangeProducts   = "products"
+	ChangePromotions = "promotions"
+	ChangeSales      = "sales"
+)
+
+// changeFeedSize is how many changes the feed remembers; a caller further
+// behind gets a reset and refetches everything
+const changeFeedSize = 256
+
+// Change is one committed change to local data
+type Change struct {
+	Kind string `json:"kind"`
+	At   string `json:"at"` // ISO 8601 timestamp
+}
+
+// Changes is the response of GET /changes
+type Changes struct {
+	// Cursor is the since of the next poll
+	Cursor string `json:"cursor"`
+
+	// Changes are those after since, oldest first
+	Changes []Change `json:"changes"`
+
+	// Reset is set when since is unknown (too old, or from before a
+	// restart): changes may have been missed, refetch everything
+	Reset bool `json:"reset,omitempty"`
+}
+
+// changeFeed numbers changes and wakes the polls waiting for one.
+// Sequence numbers start at the feed's creation time in nanoseconds, so
+// the cursors of an earlier run are recognised as too old.
+type changeFeed struct {
+	mu     sync.Mutex
+	seq    int64
+	recent []Change // The last changeFeedSize changes, up to seq
+	wake   chan struct{}
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{seq: time.Now().UnixNano(), wake: make(chan struct{})}
+}
+
+// add records a change and wakes every waiting poll
+func (f *changeFeed) add(kind string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	f.recent = append(f.recent, Change{Kind: kind, At: time.Now().UTC().Format(time.RFC3339)})
+	if len(f.recent) > changeFeedSize {
+		f.recent = f.recent[len(f.recent)-changeFeedSize:]
+	}
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+// current returns the cursor of the latest change
+func (f *changeFeed) current() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// since returns the changes after cursor since, and a channel closed on
+// the next change
+func (f *changeFeed) since(since int64) (Changes, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	changes := Changes{Cursor: strconv.FormatInt(f.seq, 10), Changes: []Change{}}
+	oldest := f.seq - int64(len(f.recent))
+	if since < oldest || since > f.seq {
+		changes.Reset = true
+		return changes, f.wake
+	}
+	changes.Changes = append(changes.Changes, f.recent[len(f.recent)-int(f.seq-since):]...)
+	return changes, f.wake
+}
+
+// handleChanges answers once there are changes after the since cursor, or
+// with none after ChangesWaitSeconds. Without since it returns the current
+// cursor at once.
+func (s *Server) handleChanges(c *fiber.Ctx) error {
+	respond := func(changes Changes) error {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(api.NewSuccessResponse(
+			api.CodeDataRetrieved,
+			"Changes retrieved successfully",
+			changes,
+		))
+	}
+
+	param := c.Query("since")
+	if param == "" {
+		changes, _ := s.changes.since(s.changes.current())
+		return respond(changes)
+	}
+	since, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return apperr.Invalid([]api.FieldError{{Field: "since", Rule: "numeric", Message: "must be a cursor returned by /changes"}})
+	}
+
+	changes, wake := s.changes.since(since)
+	if changes.Reset || len(changes.Changes) > 0 {
+		return respond(changes)
+	}
+
+	timer := time.NewTimer(constants.ChangesWaitSeconds * time.Second)
+	defer timer.Stop()
+	select {
+	case <-wake:
+		changes, _ = s.changes.since(since)
+	case <-timer.C:
+	case <-c.UserContext().Done():
+	case <-s.streamsDone:
+	}
+	return respond(changes)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestChanges_LongPoll(t *testing.T) {
+	server := newTestServerWithDB(t)
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "c1", 0)
+
+	poll := func(query string) (int, Changes) {
+		resp, data := laneRequest(t, server, http.MethodGet, "/changes"+query, cashier, "")
+		var changes Changes
+		json.Unmarshal(data, &changes)
+		return resp.StatusCode, changes
+	}
+
+	status, start := poll("")
+	if status != http.StatusOK || start.Cursor == "" || len(start.Changes) != 0 || start.Reset {
+		t.Fatalf("Expected the current cursor, got %d %+v", status, start)
+	}
+
+	// A synced price update wakes the waiting poll
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.db.UpsertProduct(&database.Product{ID: "P1", Barcode: "4006381333931", SKU: "PEN"}, database.ProductSourceSync)
+	}()
+	began := time.Now()
+	_, changes := poll("?since=" + start.Cursor)
+	if time.Since(began) > 5*time.Second {
+		t.Errorf("Poll took %s to see the change", time.Since(began))
+	}
+	if len(changes.Changes) != 1 || changes.Changes[0].Kind != ChangeProducts || changes.Cursor == start.Cursor {
+		t.Fatalf("Expected one products change, got %+v", changes)
+	}
+
+	// Changes already made are returned at once
+	server.changes.add(ChangeSales)
+	if _, more := poll("?since=" + start.Cursor); len(more.Changes) != 2 || more.Changes[1].Kind != ChangeSales {
+		t.Errorf("Expected products then sales, got %+v", more)
+	}
+
+	// A cursor from an earlier run is reset
+	if _, stale := poll("?since=1"); !stale.Reset || len(stale.Changes) != 0 {
+		t.Errorf("Expected a reset for a stale cursor, got %+v", stale)
+	}
+	if status, _ := poll("?since=soon"); status != http.StatusBadRequest {
+		t.Errorf("Invalid cursor: expected 400, got %d", status)
+	}
+}
+
+func TestChangeFeed_ForgetsOldChanges(t *testing.T) {
+	feed := newChangeFeed()
+	start := feed.current()
+	for i := 0; i <= changeFeedSize; i++ {
+		feed.add(ChangeProducts)
+	}
+
+	if changes, _ := feed.since(start); !changes.Reset {
+		t.Errorf("Expected a reset once the cursor's changes are forgotten, got %d changes", len(changes.Changes))
+	}
+	changes, _ := feed.since(start + 1)
+	if changes.Reset || len(changes.Changes) != changeFeedSize || changes.Cursor != strconv.FormatInt(feed.current(), 10) {
+		t.Errorf("Expected the last %d changes, got reset=%v and %d", changeFeedSize, changes.Reset, len(changes.Changes))
+	}
+}
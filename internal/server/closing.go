@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/closing"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/logging"
+)
+
+// Day close evaluates the closing checklist for the calling terminal. Unmet
+// items block the close unless a manager (an admin token) approves it; the
+// report reaches head office through the outbox either way.
+
+// dayFormat is the local business day a closing belongs to
+const dayFormat = "2006-01-02"
+
+// checklist builds the configured closing checklist
+func (s *Server) checklist(db *database.DB) *closing.Checklist {
+	return closing.New(db, s.config.ClosingChecklist, s.config.ClosingMaxPendingSync)
+}
+
+// handleGetChecklist evaluates the closing checklist without closing
+// (?drawer_reconciled=true as the POS would report it)
+func (s *Server) handleGetChecklist(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.checklist(db).Evaluate(closing.Inputs{DrawerReconciled: c.QueryBool("drawer_reconciled")})
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Closing checklist evaluated", result))
+}
+
+// handleCloseDay closes the terminal's business day
+func (s *Server) handleCloseDay(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		DrawerReconciled bool   `json:"drawer_reconciled"`
+		ManagerToken     string `json:"manager_token"` // Admin token approving a close with unmet items
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return apperr.BadRequest("Invalid request body")
+		}
+	}
+
+	result, err := s.checklist(db).Evaluate(closing.Inputs{DrawerReconciled: body.DrawerReconciled})
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	report := &database.DayClosing{
+		Day:        time.Now().Format(dayFormat),
+		TerminalID: terminal,
+		Items:      result.Items,
+	}
+
+	if !result.Met {
+		if body.ManagerToken == "" {
+			return apperr.Conflict("Closing checklist not met: " + strings.Join(result.Unmet(), ", "))
+		}
+		if err := s.checkLockout(c, credentialToken); err != nil {
+			return err
+		}
+		manager, err := auth.Lookup(db, body.ManagerToken)
+		if err != nil || manager.Role != auth.RoleAdmin {
+			return s.authFailed(c, credentialToken, "invalid manager token", apperr.Forbidden(auth.RoleAdmin))
+		}
+		report.Overridden = true
+		report.ApprovedBy = manager.Label
+	}
+
+	if err := db.RecordDayClosing(report); err != nil {
+		if errors.Is(err, database.ErrDayClosed) {
+			return apperr.Conflict("Day already closed")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Day closed successfully", report))
+}
+
+// handleBackup writes a copy of the database next to it under backups/
+func (s *Server) handleBackup(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	if db.IsInMemory() {
+		return apperr.Conflict("In-memory databases cannot be backed up")
+	}
+
+	dir := backupDir(db)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return apperr.Internal(err)
+	}
+	name := fmt.Sprintf("data-%s.db", time.Now().UTC().Format("20060102-150405"))
+	if err := db.Backup(filepath.Join(dir, name)); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Backup created successfully", map[string]string{
+		"file": name,
+	}))
+}
+
+// backupNames matches the files handleBackup writes
+var backupNames = regexp.MustCompile(`^data-\d{8}-\d{6}\.db$`)
+
+// backupDir is where handleBackup writes backups, next to the database
+func backupDir(db *database.DB) string {
+	return filepath.Join(filepath.Dir(db.Path()), "backups")
+}
+
+// handleRestoreBackup stages a backup written by POST /backups to replace
+// the database and restarts the service, which swaps it in on startup.
+// Without service control the restore waits for the next start.
+func (s *Server) handleRestoreBackup(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	if db.IsInMemory() {
+		return apperr.Conflict("In-memory databases cannot be restored")
+	}
+
+	name := c.Params("file")
+	path := filepath.Join(backupDir(db), name)
+	if !backupNames.MatchString(name) {
+		return apperr.NotFound("Backup not found")
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return apperr.NotFound("Backup not found")
+	}
+	if err := db.StageRestore(path); err != nil {
+		if errors.Is(err, database.ErrNotBackup) {
+			return apperr.Conflict("Backup is not a database")
+		}
+		return apperr.Internal(err)
+	}
+
+	status := "staged"
+	if s.config.Service != nil {
+		if err := s.config.Service.ScheduleRestart(serviceActionDelay); err != nil {
+			logging.Printf(c.UserContext(), "Backup %s staged, but the restart failed: %v", name, err)
+		} else {
+			status = "restarting"
+		}
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Backup restored; it takes effect when the service restarts", map[string]string{
+		"file":   name,
+		"status": status,
+	}))
+}
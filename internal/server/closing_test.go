@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestCloseDay_ChecklistAndOverride(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.ClosingChecklist = []string{constants.ClosingTabsClosed, constants.ClosingDrawerReconciled}
+
+	if _, err := server.db.AppendBasketLines("C1", "SCO1", []database.SaleLine{{SKU: "MILK", Quantity: 1, Price: 990}}, 0); err != nil {
+		t.Fatalf("AppendBasketLines failed: %v", err)
+	}
+
+	resp, result := laneRequest(t, server, http.MethodGet, "/day/checklist?drawer_reconciled=true", adminBearer(t, server), "")
+	var checklist struct {
+		Met   bool `json:"met"`
+		Items []database.ClosingItem
+	}
+	json.Unmarshal(result, &checklist)
+	if resp.StatusCode != http.StatusOK || checklist.Met || len(checklist.Items) != 2 {
+		t.Errorf("Checklist returned %d: %s", resp.StatusCode, result)
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/day/close", adminBearer(t, server), `{"drawer_reconciled":true}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Close with an open cart returned %d, want 409", resp.StatusCode)
+	}
+
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "Ann", 0)
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/day/close", adminBearer(t, server), `{"drawer_reconciled":true,"manager_token":"`+cashier+`"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier approval returned %d, want 403", resp.StatusCode)
+	}
+
+	manager, _ := auth.Issue(server.db, auth.RoleAdmin, "Store manager", 0)
+	resp, result = laneRequest(t, server, http.MethodPost, "/day/close", adminBearer(t, server), `{"drawer_reconciled":true,"manager_token":"`+manager+`"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Approved close returned %d", resp.StatusCode)
+	}
+	var report database.DayClosing
+	json.Unmarshal(result, &report)
+	if !report.Overridden || report.ApprovedBy != "Store manager" || report.TerminalID != "SCO1" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/day/close", adminBearer(t, server), `{"drawer_reconciled":true,"manager_token":"`+manager+`"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Second close returned %d, want 409", resp.StatusCode)
+	}
+
+	// The report is queued for head office
+	entries, err := server.db.GetPendingOutbox(100)
+	if err != nil {
+		t.Fatalf("GetPendingOutbox failed: %v", err)
+	}
+	found := false
+	for _, entry := range entries {
+		found = found || (entry.Entity == "day_closings" && entry.EntityID == report.ID)
+	}
+	if !found {
+		t.Error("Expected the day closing in the outbox")
+	}
+}
+
+func TestBackup_Restore(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	dir := t.TempDir()
+	db, err := database.New(&database.Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	svc := &fakeService{}
+	cfg := DefaultConfig()
+	cfg.DB = db
+	cfg.Service = svc
+	server := New(cfg)
+
+	db.SetSetting("store.name", "Corner Shop")
+	resp, result := guardedRequest(t, server, http.MethodPost, "/backups", "")
+	var backup struct {
+		File string `json:"file"`
+	}
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(result, &backup) != nil {
+		t.Fatalf("Backup returned %d: %s", resp.StatusCode, result)
+	}
+	db.SetSetting("store.name", "Renamed Shop")
+
+	if resp, _ := guardedRequest(t, server, http.MethodPost, "/backups/data-20000101-000000.db/restore", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Restoring a missing backup returned %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/backups/"+backup.File+"/restore", adminBearer(t, server), ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Restoring without the admin password returned %d, want 401", resp.StatusCode)
+	}
+	if resp, _ := guardedRequest(t, server, http.MethodPost, "/backups/"+backup.File+"/restore", ""); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Restore returned %d, want 202", resp.StatusCode)
+	}
+	if len(svc.actions) != 1 || svc.actions[0] != "restart" {
+		t.Errorf("Expected the restore to restart the service, got %v", svc.actions)
+	}
+
+	// The running database is untouched until the restart reopens it
+	if name, _ := db.GetSetting("store.name"); name != "Renamed Shop" {
+		t.Errorf("Restore changed the running database: %q", name)
+	}
+	db.Close()
+	reopened, err := database.New(&database.Config{ServerKey: serverKey, DataDir: dir})
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer reopened.Close()
+	if name, _ := reopened.GetSetting("store.name"); name != "Corner Shop" {
+		t.Errorf("Expected the backup's store name after restart, got %q", name)
+	}
+}
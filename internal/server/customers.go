@@ -0,0 +1,147 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// /customers serves loyalty customers from the local encrypted store, so
+// lookups and points keep working offline. Customers and their balances
+// come from head office; points earned or spent here are appended to the
+// local loyalty ledger and reach head office through the outbox, which
+// reconciles them into the balance it sends back.
+
+// loyaltyStatementEntries is the number of recent entries in a statement
+const loyaltyStatementEntries = 20
+
+// LoyaltyRequest records points earned or spent
+type LoyaltyRequest struct {
+	ID     string `json:"id,omitempty"` // Assigned when empty; send one so retries stay idempotent
+	Kind   string `json:"kind" validate:"required,oneof=accrual redemption"`
+	Points int64  `json:"points" validate:"gt=0"`
+	SaleID string `json:"sale_id,omitempty"`
+	Reason string `json:"reason,omitempty" validate:"max=255"`
+}
+
+// LoyaltyStatement is a customer's balance and most recent local entries
+type LoyaltyStatement struct {
+	*database.LoyaltyBalance
+	Entries []database.LoyaltyEntry `json:"entries"`
+}
+
+// LoyaltyResult reports a recorded loyalty entry and the new balance
+type LoyaltyResult struct {
+	Entry     *database.LoyaltyEntry   `json:"entry"`
+	Balance   *database.LoyaltyBalance `json:"balance"`
+	Duplicate bool                     `json:"duplicate,omitempty"` // Recorded by an earlier request with the same ID
+}
+
+// customerError maps customer lookup errors to application errors
+func customerError(err error) error {
+	if errors.Is(err, database.ErrCustomerNotFound) {
+		return apperr.NotFound("Customer not found")
+	}
+	return apperr.Database(err)
+}
+
+// handleSearchCustomers lists customers, paginated; ?q= matches the name,
+// phone, email or loyalty card
+func (s *Server) handleSearchCustomers(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	customers, err := db.FindCustomers(c.Query("q"))
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Customers retrieved successfully", customers, page)
+}
+
+// handleGetCustomer returns one customer
+func (s *Server) handleGetCustomer(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	customer, err := db.GetCustomer(c.Params("id"))
+	if err != nil {
+		return customerError(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Customer retrieved successfully", customer))
+}
+
+// handleGetLoyalty returns a customer's balance and recent local entries
+func (s *Server) handleGetLoyalty(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	balance, err := db.GetLoyaltyBalance(c.Params("id"))
+	if err != nil {
+		return customerError(err)
+	}
+	entries, err := db.ListLoyaltyEntries(balance.CustomerID, loyaltyStatementEntries)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Loyalty balance retrieved successfully", LoyaltyStatement{
+		LoyaltyBalance: balance,
+		Entries:        entries,
+	}))
+}
+
+// handleRecordLoyalty records a points accrual or redemption (idempotent
+// on the entry ID). Redemptions may not exceed the balance.
+func (s *Server) handleRecordLoyalty(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var in LoyaltyRequest
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+
+	entry := &database.LoyaltyEntry{
+		ID:         in.ID,
+		CustomerID: c.Params("id"),
+		Kind:       in.Kind,
+		Points:     in.Points,
+		SaleID:     in.SaleID,
+		Reason:     in.Reason,
+	}
+	if entry.ID == "" {
+		entry.ID = ids.New()
+	}
+
+	balance, applied, err := db.RecordLoyalty(entry)
+	if err != nil {
+		if errors.Is(err, database.ErrInsufficientPoints) {
+			return apperr.Conflict("Not enough loyalty points")
+		}
+		return customerError(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Loyalty points recorded successfully", LoyaltyResult{
+		Entry:     entry,
+		Balance:   balance,
+		Duplicate: !applied,
+	}))
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestCustomers_Loyalty(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if err := server.db.ApplyCustomers([]database.Customer{
+		{ID: "C1", Name: "Dilnoza", LoyaltyCard: "LC-001", LoyaltyPoints: 100},
+		{ID: "C2", Name: "Bobur", LoyaltyCard: "LC-002"},
+	}, nil); err != nil {
+		t.Fatalf("Failed to seed customers: %v", err)
+	}
+
+	resp, out := productRequest(t, server, http.MethodGet, "/customers?q=lc-001", "")
+	if resp.StatusCode != http.StatusOK || len(out.Result.([]interface{})) != 1 {
+		t.Fatalf("Search returned %d: %+v", resp.StatusCode, out)
+	}
+
+	resp, out = productRequest(t, server, http.MethodPost, "/customers/C1/loyalty", `{"id":"L1","kind":"redemption","points":40,"sale_id":"S1"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Redemption returned %d: %+v", resp.StatusCode, out)
+	}
+	balance := out.Result.(map[string]interface{})["balance"].(map[string]interface{})
+	if balance["loyalty_points"].(float64) != 60 {
+		t.Errorf("Expected 60 points left, got %+v", balance)
+	}
+
+	if resp, _ := productRequest(t, server, http.MethodPost, "/customers/C1/loyalty", `{"kind":"redemption","points":61}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Overdrawn redemption returned %d, want 409", resp.StatusCode)
+	}
+	if resp, _ := productRequest(t, server, http.MethodPost, "/customers/C1/loyalty", `{"kind":"gift","points":1}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid kind returned %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := productRequest(t, server, http.MethodGet, "/customers/missing/loyalty", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown customer returned %d, want 404", resp.StatusCode)
+	}
+
+	resp, out = productRequest(t, server, http.MethodGet, "/customers/C1/loyalty", "")
+	statement := out.Result.(map[string]interface{})
+	if resp.StatusCode != http.StatusOK || statement["pending_points"].(float64) != -40 || len(statement["entries"].([]interface{})) != 1 {
+		t.Errorf("Statement returned %d: %+v", resp.StatusCode, statement)
+	}
+}
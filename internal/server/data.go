@@ -0,0 +1,256 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// POST /data stores a record pushed by the POS frontend in its domain
+// table. Products, sales and stock adjustments all reach the sync outbox
+// through the tables' CDC triggers, so nothing is queued here by hand.
+
+// Record types accepted by POST /data
+const (
+	DataTypeProduct         = "product"
+	DataTypeSale            = "sale"
+	DataTypeStockAdjustment = "stock_adjustment"
+)
+
+// DataRequest is a record pushed by the POS frontend
+type DataRequest struct {
+	Type string          `json:"type" validate:"required,oneof=product sale stock_adjustment"`
+	Data json.RawMessage `json:"data" validate:"required"`
+}
+
+// DataResponse reports a stored record
+type DataResponse struct {
+	Type      string      `json:"type"`
+	ID        string      `json:"id"`
+	Record    interface{} `json:"record"`              // The record as stored
+	Duplicate bool        `json:"duplicate,omitempty"` // Stored by an earlier request with the same ID
+}
+
+// ProductData is the schema of product records
+type ProductData struct {
+	ID      string `json:"id,omitempty"` // Assigned when empty
+	Barcode string `json:"barcode" validate:"required"`
+	SKU     string `json:"sku,omitempty"`
+	Name    string `json:"name" validate:"required"`
+	Price   int64  `json:"price" validate:"gte=0"`              // Minor currency units
+	TaxRate int    `json:"tax_rate" validate:"gte=0,lte=10000"` // Basis points (10000 = 100%)
+	Active  *bool  `json:"active,omitempty"`                    // Defaults to true
+}
+
+// SaleData is the schema of sale records
+type SaleData struct {
+	ID          string              `json:"id,omitempty"` // Assigned when empty; send one so retries stay idempotent
+	Type        string              `json:"type,omitempty"`
+	OperatorID  string              `json:"operator_id,omitempty"` // Ignored in PIN sessions, which use the signed-in user
+	Lines       []database.SaleLine `json:"lines" validate:"required,min=1,dive"`
+	Total       int64               `json:"total"`
+	VoidedLines int                 `json:"voided_lines,omitempty"`
+	ScanSeconds int                 `json:"scan_seconds,omitempty"`
+}
+
+// StockAdjustmentData is the schema of stock adjustment records
+type StockAdjustmentData struct {
+	ID     string `json:"id,omitempty"` // Assigned when empty
+	SKU    string `json:"sku" validate:"required"`
+	Delta  int    `json:"delta" validate:"ne=0"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// StoredStockAdjustment is a stock adjustment and the resulting level
+type StoredStockAdjustment struct {
+	*database.StockAdjustment
+	Quantity int `json:"quantity"` // On-hand quantity after the adjustment
+}
+
+// decodeStrict decodes data into v, rejecting unknown fields and trailing data
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the record")
+	}
+	return nil
+}
+
+// handleData validates a record and persists it in its domain table
+func (s *Server) handleData(c *fiber.Ctx) error {
+	var req DataRequest
+	if err := decodeStrict(c.Body(), &req); err != nil {
+		return decodeError(err)
+	}
+	if bytes.Equal(req.Data, []byte("null")) {
+		req.Data = nil
+	}
+	if err := validateStruct(&req); err != nil {
+		return err
+	}
+
+	switch req.Type {
+	case DataTypeProduct:
+		return s.storeProduct(c, req.Data)
+	case DataTypeSale:
+		return s.storeSale(c, req.Data)
+	case DataTypeStockAdjustment:
+		return s.storeStockAdjustment(c, req.Data)
+	}
+	return apperr.BadRequest("Unsupported data type: " + req.Type)
+}
+
+// storeProduct creates or replaces a product as a local edit
+func (s *Server) storeProduct(c *fiber.Ctx, data json.RawMessage) error {
+	var in ProductData
+	if err := decodeStrict(data, &in); err != nil {
+		return decodeError(err)
+	}
+	if err := validateStruct(&in); err != nil {
+		return err
+	}
+
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	product := &database.Product{
+		ID:        in.ID,
+		Barcode:   in.Barcode,
+		SKU:       in.SKU,
+		Name:      in.Name,
+		Price:     in.Price,
+		TaxRate:   in.TaxRate,
+		Active:    in.Active == nil || *in.Active,
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if product.ID == "" {
+		product.ID = ids.New()
+	}
+	if err := db.UpsertProduct(product, database.ProductSourceLocal); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Product stored successfully", DataResponse{
+		Type:   DataTypeProduct,
+		ID:     product.ID,
+		Record: product,
+	}))
+}
+
+// storeSale commits a sale through the sale ledger, like a cart checkout
+func (s *Server) storeSale(c *fiber.Ctx, data json.RawMessage) error {
+	var in SaleData
+	if err := decodeStrict(data, &in); err != nil {
+		return decodeError(err)
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	stored, duplicate, err := s.recordSale(c.UserContext(), &in, terminal, callerToken(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Sale stored successfully", DataResponse{
+		Type:      DataTypeSale,
+		ID:        stored.ID,
+		Record:    stored,
+		Duplicate: duplicate,
+	}))
+}
+
+// recordSale validates a sale from terminal and commits it through the sale
+// ledger, returning the stored sale and whether an earlier request already
+// committed it. Sales made in a PIN session are attributed to its user.
+func (s *Server) recordSale(ctx context.Context, in *SaleData, terminal string, token *auth.Token) (*database.Sale, bool, error) {
+	if err := validateStruct(in); err != nil {
+		return nil, false, err
+	}
+
+	sale := &database.Sale{
+		ID:          in.ID,
+		Type:        in.Type,
+		TerminalID:  terminal,
+		OperatorID:  in.OperatorID,
+		Lines:       in.Lines,
+		Total:       in.Total,
+		VoidedLines: in.VoidedLines,
+		ScanSeconds: in.ScanSeconds,
+	}
+	if sale.ID == "" {
+		sale.ID = ids.New()
+	}
+	if token != nil && token.UserID != "" {
+		sale.OperatorID = token.UserID
+	}
+	if err := sale.Validate(); err != nil {
+		return nil, false, apperr.BadRequest(err.Error())
+	}
+
+	db, err := s.requireDB()
+	if err != nil {
+		return nil, false, err
+	}
+	if s.ledger == nil {
+		return nil, false, apperr.Unavailable(api.MessageServiceUnavailable, 5*time.Second)
+	}
+
+	result, err := s.commitSale(ctx, sale)
+	if err != nil {
+		return nil, false, apperr.Database(err)
+	}
+	stored, err := db.GetSale(result.SaleID)
+	if err != nil {
+		return nil, false, apperr.Database(err)
+	}
+	return stored, result.Duplicate, nil
+}
+
+// storeStockAdjustment applies a counted stock correction
+func (s *Server) storeStockAdjustment(c *fiber.Ctx, data json.RawMessage) error {
+	var in StockAdjustmentData
+	if err := decodeStrict(data, &in); err != nil {
+		return decodeError(err)
+	}
+	if err := validateStruct(&in); err != nil {
+		return err
+	}
+
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	adj := &database.StockAdjustment{ID: in.ID, SKU: in.SKU, Delta: in.Delta, Reason: in.Reason}
+	if adj.ID == "" {
+		adj.ID = ids.New()
+	}
+	quantity, applied, err := db.AdjustStock(adj)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Stock adjustment stored successfully", DataResponse{
+		Type:      DataTypeStockAdjustment,
+		ID:        adj.ID,
+		Record:    StoredStockAdjustment{StockAdjustment: adj, Quantity: quantity},
+		Duplicate: !applied,
+	}))
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/professor93/promo-pos/internal/apperr"
+)
+
+// pprofHandlers are the net/http/pprof endpoints that are not named
+// runtime profiles
+var pprofHandlers = map[string]fiber.Handler{
+	"cmdline": adaptor.HTTPHandlerFunc(pprof.Cmdline),
+	"profile": adaptor.HTTPHandlerFunc(pprof.Profile),
+	"symbol":  adaptor.HTTPHandlerFunc(pprof.Symbol),
+	"trace":   adaptor.HTTPHandlerFunc(pprof.Trace),
+}
+
+// setupDebugRoutes mounts the net/http/pprof handlers under /debug/pprof
+// for admins, so CPU and heap profiles can be captured from terminals
+// with performance problems in the field
+func (s *Server) setupDebugRoutes(r fiber.Router) {
+	r.Get("/debug/pprof", requireAdmin, adaptor.HTTPHandlerFunc(pprof.Index))
+	r.Get("/debug/pprof/:profile", requireAdmin, handlePprof)
+	r.Post("/debug/pprof/symbol", requireAdmin, pprofHandlers["symbol"])
+}
+
+// handlePprof serves one profile (heap, goroutine, allocs, ...) or one of
+// the CPU profile, execution trace, symbol and command line endpoints.
+// pprof.Index finds the profile name from a fixed /debug/pprof/ prefix,
+// which the API version prefix breaks, so names are dispatched here.
+func handlePprof(c *fiber.Ctx) error {
+	name := c.Params("profile")
+	if h, ok := pprofHandlers[name]; ok {
+		return h(c)
+	}
+	if runtimepprof.Lookup(name) == nil {
+		return apperr.NotFound("Unknown profile")
+	}
+	return adaptor.HTTPHandler(pprof.Handler(name))(c)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+)
+
+func TestPprof_AdminOnlyWhenEnabled(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/debug/pprof/goroutine", adminBearer(t, server), ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Disabled pprof returned %d, want 404", resp.StatusCode)
+	}
+
+	server.config.Pprof = true
+	server = New(server.config)
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "c1", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/debug/pprof/goroutine?debug=1", cashier, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier reached pprof with %d, want 403", resp.StatusCode)
+	}
+	for path, want := range map[string]int{
+		"/debug/pprof/":                  http.StatusOK,
+		"/debug/pprof/goroutine?debug=1": http.StatusOK,
+		"/debug/pprof/cmdline":           http.StatusOK,
+		"/debug/pprof/nonsense":          http.StatusNotFound,
+	} {
+		if resp, _ := laneRequest(t, server, http.MethodGet, path, adminBearer(t, server), ""); resp.StatusCode != want {
+			t.Errorf("%s returned %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
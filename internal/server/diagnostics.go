@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/diagnostics"
+	"github.com/professor93/promo-pos/internal/logging"
+	possync "github.com/professor93/promo-pos/internal/sync"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"go.uber.org/zap/zapcore"
+)
+
+// syncDiagnostics is the sync.json member of a diagnostics bundle
+type syncDiagnostics struct {
+	Succeeded      int64         `json:"succeeded"`
+	Failed         int64         `json:"failed"`
+	Records        int64         `json:"records"`
+	LastSuccess    *time.Time    `json:"last_success,omitempty"`
+	LastFailure    *time.Time    `json:"last_failure,omitempty"`
+	LastContact    *time.Time    `json:"last_contact,omitempty"`
+	BootstrappedAt *time.Time    `json:"bootstrapped_at,omitempty"`
+	Runs           []possync.Run `json:"runs"` // Since startup, oldest first
+}
+
+// handleGetDiagnostics returns an encrypted ZIP for support tickets: recent
+// logs, sync history, database statistics and health, plus the sanitized
+// config and version added by SealDiagnostics. Whatever cannot be collected
+// is listed in errors.json instead of failing the bundle, since it is most
+// needed when something is broken.
+func (s *Server) handleGetDiagnostics(c *fiber.Ctx) error {
+	if s.config.SealDiagnostics == nil {
+		return apperr.Unavailable("Diagnostics bundles are not available", time.Minute)
+	}
+
+	now := time.Now()
+	files, failures := s.collectDiagnostics(now)
+	if len(failures) > 0 {
+		data, _ := json.MarshalIndent(failures, "", "  ")
+		files = append(files, diagnostics.File{Name: "errors.json", Data: data})
+	}
+
+	bundle, err := s.config.SealDiagnostics(files)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="pos-diagnostics-%s.zip"`, now.UTC().Format("20060102T150405Z")))
+	return c.Send(bundle)
+}
+
+// collectDiagnostics gathers the bundle files the server holds, and what
+// failed to be collected by file name
+func (s *Server) collectDiagnostics(now time.Time) ([]diagnostics.File, map[string]string) {
+	var files []diagnostics.File
+	failures := make(map[string]string)
+	add := func(name string, v any, err error) {
+		if err == nil {
+			var data []byte
+			if data, err = json.MarshalIndent(v, "", "  "); err == nil {
+				files = append(files, diagnostics.File{Name: name, Data: data})
+				return
+			}
+		}
+		failures[name] = err.Error()
+	}
+
+	if s.config.ReadLogs != nil {
+		tail, err := s.config.ReadLogs(logging.Query{Lines: constants.DiagnosticsLogLines, Level: zapcore.DebugLevel})
+		if err != nil {
+			failures["logs.jsonl"] = err.Error()
+		} else {
+			var buf bytes.Buffer
+			for _, entry := range tail.Entries {
+				buf.Write(entry)
+				buf.WriteByte('\n')
+			}
+			files = append(files, diagnostics.File{Name: "logs.jsonl", Data: buf.Bytes()})
+		}
+	}
+
+	stats := s.syncStats.Snapshot()
+	add("sync.json", syncDiagnostics{
+		Succeeded:      stats.Succeeded,
+		Failed:         stats.Failed,
+		Records:        stats.Records,
+		LastSuccess:    timeOrNil(stats.LastSuccess),
+		LastFailure:    timeOrNil(stats.LastFailure),
+		LastContact:    timeOrNil(stats.LastContact),
+		BootstrappedAt: s.bootstrapped.Load(),
+		Runs:           s.syncStats.History(),
+	}, nil)
+
+	if s.db != nil {
+		snapshot, err := s.db.DiagnosticsSnapshot()
+		add("database.json", snapshot, err)
+	}
+	add("health.json", s.healthChecks(now), nil)
+
+	return files, failures
+}
+
+// timeOrNil returns nil for the zero time, so it is left out of JSON
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/diagnostics"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/security"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+func TestGetDiagnostics_Bundle(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/diagnostics", adminBearer(t, server), ""); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a sealer, got %d", resp.StatusCode)
+	}
+
+	key, _ := security.GenerateServerKey()
+	server.config.SealDiagnostics = func(files []diagnostics.File) ([]byte, error) {
+		return diagnostics.Write(files, key, "store-7")
+	}
+	server.config.ReadLogs = func(q logging.Query) (*logging.Tail, error) {
+		return nil, errors.New("log directory missing")
+	}
+	server.syncStats.Record(possync.ProgressReport{Mode: possync.ModeRegular, Records: 4})
+
+	resp, err := send(server, asAdmin(t, server, httptest.NewRequest(http.MethodGet, "/diagnostics", nil)), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	bundle, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected a ZIP (%d %s): %s", resp.StatusCode, resp.Header.Get("Content-Type"), bundle)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), "pos-diagnostics-") {
+		t.Errorf("Expected a download, got %q", resp.Header.Get("Content-Disposition"))
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatalf("Bundle is not a ZIP: %v", err)
+	}
+	encryption, _ := security.NewDatabaseEncryption(key)
+	defer encryption.Close()
+	members := make(map[string][]byte)
+	for _, f := range zr.File {
+		name, ok := strings.CutSuffix(f.Name, diagnostics.EncryptedExt)
+		if !ok {
+			continue
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if members[name], err = encryption.Decrypt(string(data)); err != nil {
+			t.Fatalf("%s did not decrypt: %v", f.Name, err)
+		}
+	}
+
+	for _, name := range []string{"sync.json", "database.json", "health.json", "errors.json"} {
+		if _, ok := members[name]; !ok {
+			t.Errorf("Bundle is missing %s", name)
+		}
+	}
+	var history syncDiagnostics
+	json.Unmarshal(members["sync.json"], &history)
+	if history.Succeeded != 1 || len(history.Runs) != 1 || history.Runs[0].Records != 4 {
+		t.Errorf("Unexpected sync history %s", members["sync.json"])
+	}
+	if !strings.Contains(string(members["errors.json"]), "log directory missing") {
+		t.Errorf("Expected the log failure to be reported, got %s", members["errors.json"])
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+const (
+	// HeaderTerminalID identifies the calling terminal/frontend instance
+	HeaderTerminalID = "X-Terminal-ID"
+
+	// defaultTerminalID is used by single-terminal installations
+	defaultTerminalID = "default"
+
+	// draftKeyPrefix prefixes the encrypted settings holding drafts
+	draftKeyPrefix = "draft."
+
+	// draftTTL bounds how long an abandoned draft is kept
+	draftTTL = 24 * time.Hour
+)
+
+var terminalIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Draft is the autosaved state of an in-progress sale
+type Draft struct {
+	TerminalID string          `json:"terminal_id"`
+	Payload    json.RawMessage `json:"payload"`
+	SavedAt    string          `json:"saved_at"` // ISO 8601 timestamp
+}
+
+// terminalID returns the validated terminal ID of the request
+func terminalID(c *fiber.Ctx) (string, error) {
+	id := c.Get(HeaderTerminalID)
+	if id == "" {
+		return defaultTerminalID, nil
+	}
+	if !terminalIDPattern.MatchString(id) {
+		return "", apperr.BadRequest("Invalid " + HeaderTerminalID + " header")
+	}
+	return id, nil
+}
+
+// requireDB returns the database or a 503 error when none is configured
+func (s *Server) requireDB() (*database.DB, error) {
+	if s.db == nil {
+		return nil, apperr.Unavailable(api.MessageServiceUnavailable, 5*time.Second)
+	}
+	return s.db, nil
+}
+
+// handleGetDraft returns the terminal's autosaved draft (on frontend restart)
+func (s *Server) handleGetDraft(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	id, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	var draft Draft
+	if err := db.GetSettingJSON(draftKeyPrefix+id, &draft); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return apperr.NotFound("No draft saved for this terminal")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Draft retrieved successfully", draft))
+}
+
+// handlePutDraft autosaves the terminal's in-progress sale
+func (s *Server) handlePutDraft(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	id, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Payload json.RawMessage `json:"payload" validate:"required"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+
+	draft := Draft{
+		TerminalID: id,
+		Payload:    body.Payload,
+		SavedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	data, err := json.Marshal(draft)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if err := db.SetSettingWithTTL(draftKeyPrefix+id, string(data), draftTTL); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Draft saved successfully", draft))
+}
+
+// handleDeleteDraft discards the terminal's draft once the sale completes
+func (s *Server) handleDeleteDraft(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	id, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteSetting(draftKeyPrefix + id); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return apperr.NotFound("No draft saved for this terminal")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "Draft discarded successfully", nil))
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func newTestServerWithDB(t testing.TB) *Server {
+	cfg := DefaultConfig()
+	cfg.DB = newTestDB(t)
+	return New(cfg)
+}
+
+// newTestDB opens an in-memory database closed when t ends
+func newTestDB(t testing.TB) *database.DB {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{
+		ServerKey: serverKey,
+		InMemory:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func draftRequest(t *testing.T, server *Server, method, terminal, body string) (*http.Response, api.APIResponse) {
+	req := httptest.NewRequest(method, "/carts/draft", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if terminal != "" {
+		req.Header.Set(HeaderTerminalID, terminal)
+	}
+
+	resp, err := send(server, asAdmin(t, server, req))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp api.APIResponse
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &apiResp)
+
+	return resp, apiResp
+}
+
+func TestDraft_SaveAndRecover(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	resp, _ := draftRequest(t, server, "PUT", "T1", `{"payload":{"lines":[{"barcode":"123","qty":2}]}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// Simulated frontend restart: fetch the draft back
+	resp, apiResp := draftRequest(t, server, "GET", "T1", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	result := apiResp.Result.(map[string]interface{})
+	if result["terminal_id"] != "T1" {
+		t.Errorf("Expected terminal T1, got %v", result["terminal_id"])
+	}
+
+	// Drafts are per terminal
+	resp, _ = draftRequest(t, server, "GET", "T2", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another terminal, got %d", resp.StatusCode)
+	}
+
+	resp, _ = draftRequest(t, server, "DELETE", "T1", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestDraft_Validation(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	resp, apiResp := draftRequest(t, server, "PUT", "T1", `{}`)
+	if resp.StatusCode != http.StatusBadRequest || apiResp.Code != api.CodeErrorBadRequest {
+		t.Errorf("Expected bad request for missing payload, got %d", resp.StatusCode)
+	}
+
+	resp, _ = draftRequest(t, server, "PUT", "../etc", `{"payload":{}}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected bad request for invalid terminal ID, got %d", resp.StatusCode)
+	}
+}
+
+func TestDraft_NoDatabase(t *testing.T) {
+	server := New(nil)
+	resp, err := send(server, asLane(t, server, httptest.NewRequest("GET", "/carts/draft", nil)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without database, got %d", resp.StatusCode)
+	}
+}
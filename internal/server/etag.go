@@ -0,0 +1,25 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+)
+
+// notModified tags the response with an ETag over the version parts of the
+// data behind it and reports whether the client already holds that
+// version, in which case the response is a bodiless 304. Masked responses
+// get their own ETag, so privacy mode never serves a cached unmasked copy.
+func (s *Server) notModified(c *fiber.Ctx, parts ...string) bool {
+	if !s.privacyUntil().IsZero() {
+		parts = append(parts, "masked")
+	}
+	tag := api.ETag(parts...)
+	c.Set(fiber.HeaderETag, tag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" && api.ETagMatches(match, tag) {
+		c.Status(fiber.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func conditionalGet(t *testing.T, server *Server, path, etag string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestETag_Config(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	resp := conditionalGet(t, server, "/config", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d %q", resp.StatusCode, etag)
+	}
+	if resp := conditionalGet(t, server, "/config", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged config, got %d", resp.StatusCode)
+	}
+}
+
+func TestETag_ProductChanges(t *testing.T) {
+	server := newTestServerWithDB(t)
+	product := &database.Product{ID: "P1", Barcode: "4006381333931", SKU: "PEN", Name: "Pen", Price: 250, UpdatedAt: "2026-01-01T00:00:00Z"}
+	if err := server.db.UpsertProduct(product, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	resp := conditionalGet(t, server, "/products/4006381333931", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d %q", resp.StatusCode, etag)
+	}
+	if resp := conditionalGet(t, server, "/products/4006381333931", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged product, got %d", resp.StatusCode)
+	}
+
+	product.Price = 300
+	product.UpdatedAt = "2026-01-02T00:00:00Z"
+	if err := server.db.UpsertProduct(product, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	resp = conditionalGet(t, server, "/products/4006381333931", etag)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("Expected a changed product to be sent again with a new ETag, got %d", resp.StatusCode)
+	}
+
+	// Privacy mode serves a differently tagged (masked) representation
+	until := time.Now().Add(time.Minute)
+	server.privacy.Store(&until)
+	if resp := conditionalGet(t, server, "/products/4006381333931", resp.Header.Get("ETag")); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected privacy mode to invalidate the ETag, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,340 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// defaultSalesWindow is the period sales queries cover without a from
+const defaultSalesWindow = 24 * time.Hour
+
+// Bounds on a query, so one request cannot tie up the database
+const (
+	graphQLMaxDepth  = 8
+	graphQLMaxLength = 8 << 10
+)
+
+// graphQLSchemaSDL is the read-only schema /graphql serves. Objects have
+// the fields of their JSON encoding in the REST API.
+const graphQLSchemaSDL = `
+schema {
+	query: Query
+}
+
+"A 64-bit integer, such as an amount in minor currency units"
+scalar Long
+
+type Query {
+	"One product, or null"
+	product(barcode: String!): Product
+	"Products ordered by barcode"
+	products(first: Int = 50, offset: Int = 0): [Product!]!
+	product_count: Int!
+	"One sale, or null"
+	sale(id: String!): Sale
+	"Sales committed in [from, to), ISO 8601, oldest first; the last day by default"
+	sales(from: String, to: String, first: Int = 50, offset: Int = 0): [Sale!]!
+	sale_count: Int!
+}
+
+type Product {
+	id: String!
+	barcode: String!
+	sku: String!
+	name: String!
+	price: Long!
+	tax_rate: Int!
+	active: Boolean!
+	updated_at: String!
+	version: Long!
+}
+
+type Sale {
+	id: String!
+	type: String
+	terminal_id: String!
+	operator_id: String
+	lines: [SaleLine!]!
+	total: Long!
+	voided_lines: Int!
+	scan_seconds: Int!
+	tenders: [Tender!]!
+	refund_of: String
+	created_at: String!
+}
+
+type SaleLine {
+	sku: String!
+	quantity: Int!
+	price: Long!
+	promo_id: String
+}
+
+type Tender {
+	method: String!
+	amount: Long!
+	reference: String
+}
+`
+
+// graphQLQuery is the body of a GraphQL request
+type graphQLQuery struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// newGraphQLSchema parses the schema with resolvers reading s's database
+func newGraphQLSchema(s *Server) *graphql.Schema {
+	return graphql.MustParseSchema(graphQLSchemaSDL, &queryResolver{s: s},
+		graphql.MaxDepth(graphQLMaxDepth),
+		graphql.MaxQueryLength(graphQLMaxLength),
+	)
+}
+
+// handleGraphQL answers a read-only GraphQL query over the local catalog
+// and sales. Like other GraphQL servers it answers 200 with the standard
+// {data, errors} body rather than the API envelope, so off-the-shelf
+// clients and dashboards can use it.
+func (s *Server) handleGraphQL(c *fiber.Ctx) error {
+	var req graphQLQuery
+	if c.Method() == fiber.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return apperr.BadRequest("variables must be a JSON object")
+			}
+		}
+	} else if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return apperr.BadRequest("Request body must be a JSON object with a query")
+	}
+	if req.Query == "" {
+		return apperr.BadRequest("query is required")
+	}
+
+	if _, err := s.requireDB(); err != nil {
+		return err
+	}
+	return c.JSON(s.graphQL.Exec(c.UserContext(), req.Query, req.OperationName, req.Variables))
+}
+
+// queryResolver resolves the Query root
+type queryResolver struct {
+	s *Server
+}
+
+func (q *queryResolver) Product(ctx context.Context, args struct{ Barcode string }) (*productResolver, error) {
+	product, err := q.s.db.GetProductByBarcode(args.Barcode)
+	if errors.Is(err, database.ErrProductNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &productResolver{product}, nil
+}
+
+// pageArgs are the arguments of list fields
+type pageArgs struct {
+	First  int32
+	Offset int32
+}
+
+// bounds checks the arguments and returns them as ints
+func (p pageArgs) bounds() (first, offset int, err error) {
+	if p.First < 1 || p.First > api.MaxPerPage {
+		return 0, 0, fmt.Errorf("Argument \"first\" must be between 1 and %d", api.MaxPerPage)
+	}
+	if p.Offset < 0 {
+		return 0, 0, fmt.Errorf("Argument \"offset\" cannot be negative")
+	}
+	return int(p.First), int(p.Offset), nil
+}
+
+func (q *queryResolver) Products(ctx context.Context, args pageArgs) ([]*productResolver, error) {
+	first, offset, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+	products, err := q.s.db.ListProducts(offset, first)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*productResolver, len(products))
+	for i := range products {
+		out[i] = &productResolver{&products[i]}
+	}
+	return out, nil
+}
+
+func (q *queryResolver) ProductCount(ctx context.Context) (int32, error) {
+	n, err := q.s.db.CountProducts()
+	return int32(n), err
+}
+
+func (q *queryResolver) Sale(ctx context.Context, args struct{ ID string }) (*saleResolver, error) {
+	sale, err := q.s.db.GetSale(args.ID)
+	if errors.Is(err, database.ErrSaleNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &saleResolver{sale}, nil
+}
+
+func (q *queryResolver) Sales(ctx context.Context, args struct {
+	From   *string
+	To     *string
+	First  int32
+	Offset int32
+}) ([]*saleResolver, error) {
+	first, offset, err := pageArgs{args.First, args.Offset}.bounds()
+	if err != nil {
+		return nil, err
+	}
+	to, err := timeArg("to", args.To, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	from, err := timeArg("from", args.From, to.Add(-defaultSalesWindow))
+	if err != nil {
+		return nil, err
+	}
+	sales, err := q.s.db.ListSales(from, to)
+	if err != nil {
+		return nil, err
+	}
+	start := min(offset, len(sales))
+	sales = sales[start:min(start+first, len(sales))]
+
+	out := make([]*saleResolver, len(sales))
+	for i := range sales {
+		out[i] = &saleResolver{&sales[i]}
+	}
+	return out, nil
+}
+
+func (q *queryResolver) SaleCount(ctx context.Context) (int32, error) {
+	n, err := q.s.db.CountSales()
+	return int32(n), err
+}
+
+// timeArg reads an ISO 8601 timestamp argument
+func timeArg(name string, value *string, def time.Time) (time.Time, error) {
+	if value == nil || *value == "" {
+		return def, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Argument %q must be an ISO 8601 timestamp", name)
+	}
+	return t, nil
+}
+
+// long is the Long scalar: GraphQL's Int is 32 bits, too few for amounts
+// in minor currency units
+type long int64
+
+func (long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+func (l *long) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		*l = long(v)
+	case float64:
+		if v != math.Trunc(v) {
+			return fmt.Errorf("Long must be an integer")
+		}
+		*l = long(v)
+	default:
+		return fmt.Errorf("Long must be an integer")
+	}
+	return nil
+}
+
+// optional resolves an empty string to null, as the JSON encoding omits it
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// productResolver resolves a Product
+type productResolver struct {
+	p *database.Product
+}
+
+func (r *productResolver) ID() string        { return r.p.ID }
+func (r *productResolver) Barcode() string   { return r.p.Barcode }
+func (r *productResolver) SKU() string       { return r.p.SKU }
+func (r *productResolver) Name() string      { return r.p.Name }
+func (r *productResolver) Price() long       { return long(r.p.Price) }
+func (r *productResolver) TaxRate() int32    { return int32(r.p.TaxRate) }
+func (r *productResolver) Active() bool      { return r.p.Active }
+func (r *productResolver) UpdatedAt() string { return r.p.UpdatedAt }
+func (r *productResolver) Version() long     { return long(r.p.Version) }
+
+// saleResolver resolves a Sale
+type saleResolver struct {
+	s *database.Sale
+}
+
+func (r *saleResolver) ID() string          { return r.s.ID }
+func (r *saleResolver) Type() *string       { return optional(r.s.Type) }
+func (r *saleResolver) TerminalID() string  { return r.s.TerminalID }
+func (r *saleResolver) OperatorID() *string { return optional(r.s.OperatorID) }
+func (r *saleResolver) Total() long         { return long(r.s.Total) }
+func (r *saleResolver) VoidedLines() int32  { return int32(r.s.VoidedLines) }
+func (r *saleResolver) ScanSeconds() int32  { return int32(r.s.ScanSeconds) }
+func (r *saleResolver) RefundOf() *string   { return optional(r.s.RefundOf) }
+func (r *saleResolver) CreatedAt() string   { return r.s.CreatedAt }
+
+func (r *saleResolver) Lines() []*saleLineResolver {
+	out := make([]*saleLineResolver, len(r.s.Lines))
+	for i := range r.s.Lines {
+		out[i] = &saleLineResolver{&r.s.Lines[i]}
+	}
+	return out
+}
+
+func (r *saleResolver) Tenders() []*tenderResolver {
+	out := make([]*tenderResolver, len(r.s.Tenders))
+	for i := range r.s.Tenders {
+		out[i] = &tenderResolver{&r.s.Tenders[i]}
+	}
+	return out
+}
+
+// saleLineResolver resolves a SaleLine
+type saleLineResolver struct {
+	l *database.SaleLine
+}
+
+func (r *saleLineResolver) SKU() string      { return r.l.SKU }
+func (r *saleLineResolver) Quantity() int32  { return int32(r.l.Quantity) }
+func (r *saleLineResolver) Price() long      { return long(r.l.Price) }
+func (r *saleLineResolver) PromoID() *string { return optional(r.l.PromoID) }
+
+// tenderResolver resolves a Tender
+type tenderResolver struct {
+	t *database.Tender
+}
+
+func (r *tenderResolver) Method() string     { return r.t.Method }
+func (r *tenderResolver) Amount() long       { return long(r.t.Amount) }
+func (r *tenderResolver) Reference() *string { return optional(r.t.Reference) }
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// graphQLResponse is the standard GraphQL response body
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// newGraphQLServer returns a server with /graphql enabled
+func newGraphQLServer(t *testing.T) *Server {
+	cfg := *newTestServerWithDB(t).config
+	cfg.GraphQL = true
+	return New(&cfg)
+}
+
+func graphQLRequest(t *testing.T, server *Server, req *http.Request) (*http.Response, graphQLResponse) {
+	resp, err := send(server, req, -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	var out graphQLResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(body, &out); err != nil {
+			t.Fatalf("Failed to parse response %s: %v", body, err)
+		}
+	}
+	return resp, out
+}
+
+func TestGraphQL_QueriesLocalData(t *testing.T) {
+	server := newGraphQLServer(t)
+	for _, p := range []database.Product{
+		{ID: "P1", Barcode: "111", SKU: "MILK", Name: "Milk", Price: 990},
+		{ID: "P2", Barcode: "222", SKU: "BREAD", Name: "Bread", Price: 450},
+	} {
+		if err := server.db.UpsertProduct(&p, database.ProductSourceSync); err != nil {
+			t.Fatalf("Failed to seed product: %v", err)
+		}
+	}
+
+	body := `{"query":"query ($b: String!) { product(barcode: $b) { name price } products(offset: 1) { sku } product_count sales { id } }","variables":{"b":"111"}}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, out := graphQLRequest(t, server, asAdmin(t, server, req))
+	if resp.StatusCode != http.StatusOK || len(out.Errors) != 0 {
+		t.Fatalf("Query returned %d with errors %v", resp.StatusCode, out.Errors)
+	}
+	want := `{"product":{"name":"Milk","price":990},"products":[{"sku":"BREAD"}],"product_count":2,"sales":[]}`
+	if string(out.Data) != want {
+		t.Errorf("Got %s\nwant %s", out.Data, want)
+	}
+
+	// The schema is typed: unknown fields fail validation
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(barcode: \"111\") { colour } }"}`))
+	if _, out := graphQLRequest(t, server, asAdmin(t, server, req)); len(out.Errors) != 1 || !strings.Contains(out.Errors[0].Message, "colour") {
+		t.Errorf("Expected the unknown field to be reported, got %v", out.Errors)
+	}
+
+	// GET with the query in the URL, as GraphiQL-style tools send it
+	req = httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ products(first: 5000) { sku } }`), nil)
+	if _, out := graphQLRequest(t, server, asAdmin(t, server, req)); len(out.Errors) != 1 || !strings.Contains(out.Errors[0].Message, "between 1 and") {
+		t.Errorf("Expected the page size to be bounded, got %v", out.Errors)
+	}
+}
+
+func TestGraphQL_AccessAndToggle(t *testing.T) {
+	// Off unless configured
+	server := newTestServerWithDB(t)
+	if resp, _ := graphQLRequest(t, server, asAdmin(t, server, httptest.NewRequest(http.MethodGet, "/graphql?query={product_count}", nil))); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Disabled endpoint returned %d, want 404", resp.StatusCode)
+	}
+
+	server = newGraphQLServer(t)
+	if resp, _ := graphQLRequest(t, server, asAdmin(t, server, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{}`)))); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Missing query returned %d, want 400", resp.StatusCode)
+	}
+
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query={product_count}", nil)
+	req.Header.Set("Authorization", "Bearer "+cashier)
+	if resp, _ := graphQLRequest(t, server, req); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier got %d, want 403", resp.StatusCode)
+	}
+
+	secret, _, err := auth.CreateAPIKey(server.db, "Dashboard", []string{auth.ScopeDataRead})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if resp := keyRequest(t, server, http.MethodGet, "/graphql?query={product_count}", secret); resp.StatusCode != http.StatusOK {
+		t.Errorf("data:read key got %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestGraphQL_LongAmounts(t *testing.T) {
+	cfg := *newTestServerWithLedger(t).config
+	cfg.GraphQL = true
+	server := New(&cfg)
+
+	// 50 million in minor units overflows GraphQL's 32-bit Int
+	sale := &database.Sale{ID: "S1", TerminalID: "T1", Lines: []database.SaleLine{{SKU: "TV", Quantity: 1, Price: 5_000_000_000}}, Total: 5_000_000_000}
+	if _, err := server.ledger.CommitSale(context.Background(), sale); err != nil {
+		t.Fatalf("CommitSale failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ sale(id: "S1") { total operator_id lines { sku price } } }`), nil)
+	resp, out := graphQLRequest(t, server, asAdmin(t, server, req))
+	if resp.StatusCode != http.StatusOK || len(out.Errors) != 0 {
+		t.Fatalf("Query returned %d with errors %v", resp.StatusCode, out.Errors)
+	}
+	want := `{"sale":{"total":5000000000,"operator_id":null,"lines":[{"sku":"TV","price":5000000000}]}}`
+	if string(out.Data) != want {
+		t.Errorf("Got %s\nwant %s", out.Data, want)
+	}
+}
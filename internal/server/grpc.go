@@ -0,0 +1,473 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	posv1 "github.com/professor93/promo-pos/api/proto/pos/v1"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/events"
+	possync "github.com/professor93/promo-pos/internal/sync"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC interface (api/proto/pos/v1) serves the core operations on its
+// own port (grpc_port) for frontends that prefer protobuf and streaming
+// over JSON. Every RPC mirrors one HTTP route and shares its handler code,
+// credentials, route lists and lockout, so a caller can do the same over
+// gRPC as over HTTP and nothing more.
+
+// Metadata keys carrying what HTTP callers send as headers
+const (
+	metadataAuthorization = "authorization"
+	metadataAPIKey        = "x-api-key"
+	metadataTerminalID    = "x-terminal-id"
+)
+
+// grpcShutdownTimeout bounds how long open calls may finish on shutdown
+const grpcShutdownTimeout = 5 * time.Second
+
+// rpcRoute is the HTTP route an RPC mirrors
+type rpcRoute struct {
+	method string
+	path   string
+}
+
+// rpcRoutes maps each RPC to its route, which decides who may call it
+var rpcRoutes = map[string]rpcRoute{
+	posv1.POS_RecordSale_FullMethodName:  {http.MethodPost, "/data"},
+	posv1.POS_LookupPrice_FullMethodName: {http.MethodGet, "/price/barcode"},
+	posv1.POS_GetStatus_FullMethodName:   {http.MethodGet, "/status"},
+	posv1.POS_WatchStatus_FullMethodName: {http.MethodGet, "/ws/status"},
+	posv1.POS_TriggerSync_FullMethodName: {http.MethodPost, "/sync"},
+}
+
+// rpcCallerKey is the context key of the token an RPC was called with
+type rpcCallerKey struct{}
+
+// rpcToken returns the bearer token an RPC was called with, or nil for
+// integrations
+func rpcToken(ctx context.Context) *auth.Token {
+	token, _ := ctx.Value(rpcCallerKey{}).(*auth.Token)
+	return token
+}
+
+// ServeGRPC serves the gRPC interface on addr until ctx is done
+func (s *Server) ServeGRPC(ctx context.Context, addr string) error {
+	if s.db == nil {
+		return fmt.Errorf("refusing to serve gRPC without a database to check credentials against")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.serveGRPC(ctx, ln)
+}
+
+// serveGRPC serves the gRPC interface on ln until ctx is done
+func (s *Server) serveGRPC(ctx context.Context, ln net.Listener) error {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.authenticateUnary),
+		grpc.ChainStreamInterceptor(s.authenticateStream),
+	)
+	posv1.RegisterPOSServer(srv, &posService{s: s})
+
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(grpcShutdownTimeout):
+			srv.Stop()
+		}
+	}()
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// authenticateUnary authenticates and authorizes a unary call
+func (s *Server) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, done, err := s.authenticateRPC(ctx, info.FullMethod)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	resp, err := handler(ctx, req)
+	done(err)
+	return resp, rpcError(err)
+}
+
+// authenticateStream authenticates and authorizes a streaming call
+func (s *Server) authenticateStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, done, err := s.authenticateRPC(ss.Context(), info.FullMethod)
+	if err != nil {
+		return rpcError(err)
+	}
+	err = handler(srv, &rpcStream{ServerStream: ss, ctx: ctx})
+	done(err)
+	return rpcError(err)
+}
+
+// authenticateRPC resolves the credentials in the call's metadata like
+// authenticate does for HTTP and checks them against the route the RPC
+// mirrors. done is called with the outcome, for the handheld audit trail.
+func (s *Server) authenticateRPC(ctx context.Context, fullMethod string) (context.Context, func(error), error) {
+	nothing := func(error) {}
+	route, ok := rpcRoutes[fullMethod]
+	if !ok {
+		return nil, nil, apperr.Forbidden(auth.RoleStaff)
+	}
+	source := rpcSource(ctx)
+	action := "RPC " + fullMethod
+
+	if key := rpcMetadata(ctx, metadataAPIKey); key != "" {
+		if err := s.lockedOut(credentialAPIKey, source); err != nil {
+			return nil, nil, err
+		}
+		db, err := s.requireDB()
+		if err != nil {
+			return nil, nil, err
+		}
+		k, err := auth.LookupAPIKey(db, key)
+		if errors.Is(err, auth.ErrInvalidAPIKey) {
+			return nil, nil, s.failedFrom(source, credentialAPIKey, action, "invalid API key", apperr.Unauthorized("Invalid or revoked API key"))
+		}
+		if err != nil {
+			return nil, nil, apperr.Database(err)
+		}
+		if err := routeForbidden(auth.RoleIntegration, k, route.method, route.path); err != nil {
+			return nil, nil, err
+		}
+		return ctx, nothing, nil
+	}
+
+	header := rpcMetadata(ctx, metadataAuthorization)
+	if header == "" {
+		return nil, nil, apperr.Unauthorized("Authentication required")
+	}
+	if err := s.lockedOut(credentialToken, source); err != nil {
+		return nil, nil, err
+	}
+	bearer, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, nil, apperr.Unauthorized("Authorization must be a bearer token")
+	}
+	token, reason, err := s.lookupBearer(bearer)
+	if reason != "" {
+		return nil, nil, s.failedFrom(source, credentialToken, action, reason, apperr.Unauthorized("Invalid or expired token"))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = context.WithValue(ctx, rpcCallerKey{}, token)
+
+	if err := routeForbidden(token.Role, nil, route.method, route.path); err != nil {
+		if token.Role == auth.RoleHandheld {
+			s.recordDeviceAudit(ctx, token.Label, action, fiber.StatusForbidden, "outside handheld profile")
+		}
+		return nil, nil, err
+	}
+	if token.Role != auth.RoleHandheld {
+		return ctx, nothing, nil
+	}
+	if err := s.checkDevice(token.Label); err != nil {
+		return nil, nil, err
+	}
+	return ctx, func(err error) {
+		status := fiber.StatusOK
+		if err != nil {
+			status = apperr.From(err).Status
+		}
+		s.recordDeviceAudit(ctx, token.Label, action, status, "")
+	}, nil
+}
+
+// rpcMetadata returns the first value of key in the call's metadata
+func rpcMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// rpcSource returns the caller's address, without the port, as the
+// lockout and audit log track it
+func rpcSource(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// rpcTerminalID returns the validated terminal ID of the call
+func rpcTerminalID(ctx context.Context) (string, error) {
+	id := rpcMetadata(ctx, metadataTerminalID)
+	if id == "" {
+		return defaultTerminalID, nil
+	}
+	if !terminalIDPattern.MatchString(id) {
+		return "", apperr.BadRequest("Invalid " + metadataTerminalID + " metadata")
+	}
+	return id, nil
+}
+
+// rpcCodes maps the HTTP status of an error to a gRPC code
+var rpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.Aborted,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// rpcError renders err as a gRPC status with the message HTTP callers
+// would get. Statuses from gRPC itself, such as a cancelled stream, pass
+// through.
+func rpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	appErr := apperr.From(err)
+	code, ok := rpcCodes[appErr.Status]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, appErr.Message)
+}
+
+// rpcStream is a server stream carrying the authenticated context
+type rpcStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (r *rpcStream) Context() context.Context {
+	return r.ctx
+}
+
+// posService implements the POS gRPC service on top of the server
+type posService struct {
+	posv1.UnimplementedPOSServer
+	s *Server
+}
+
+// RecordSale commits a sale like POST /data with type sale
+func (p *posService) RecordSale(ctx context.Context, req *posv1.RecordSaleRequest) (*posv1.RecordSaleResponse, error) {
+	terminal, err := rpcTerminalID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.s.OfflineMode() {
+		return nil, apperr.Offline()
+	}
+
+	in := SaleData{
+		ID:          req.GetId(),
+		Type:        req.GetType(),
+		OperatorID:  req.GetOperatorId(),
+		Total:       req.GetTotal(),
+		VoidedLines: int(req.GetVoidedLines()),
+		ScanSeconds: int(req.GetScanSeconds()),
+	}
+	for _, line := range req.GetLines() {
+		in.Lines = append(in.Lines, database.SaleLine{
+			SKU:      line.GetSku(),
+			Quantity: int(line.GetQuantity()),
+			Price:    line.GetPrice(),
+			PromoID:  line.GetPromoId(),
+		})
+	}
+
+	sale, duplicate, err := p.s.recordSale(ctx, &in, terminal, rpcToken(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &posv1.RecordSaleResponse{Id: sale.ID, CreatedAt: sale.CreatedAt, Duplicate: duplicate}, nil
+}
+
+// LookupPrice prices a barcode like GET /price/:barcode
+func (p *posService) LookupPrice(ctx context.Context, req *posv1.LookupPriceRequest) (*posv1.PriceLookup, error) {
+	lookup, err := p.s.lookupPrice(req.GetBarcode())
+	if err != nil {
+		return nil, err
+	}
+	msg := &posv1.PriceLookup{
+		Barcode:    lookup.Barcode,
+		Sku:        lookup.SKU,
+		Name:       lookup.Name,
+		Active:     lookup.Active,
+		Price:      lookup.Price,
+		TaxRate:    int32(lookup.TaxRate),
+		Tax:        lookup.Tax,
+		PromoPrice: lookup.PromoPrice,
+		PromoTax:   lookup.PromoTax,
+	}
+	for _, promo := range lookup.Promotions {
+		msg.Promotions = append(msg.Promotions, &posv1.Promotion{
+			Id:         promo.ID,
+			Name:       promo.Name,
+			Kind:       promo.Kind,
+			Value:      promo.Value,
+			Skus:       promo.SKUs,
+			ValidFrom:  promo.ValidFrom,
+			ValidUntil: promo.ValidUntil,
+		})
+	}
+	return msg, nil
+}
+
+// GetStatus returns the service status like GET /status
+func (p *posService) GetStatus(ctx context.Context, req *posv1.GetStatusRequest) (*posv1.ServiceStatus, error) {
+	return rpcServiceStatus(p.s.serviceStatus()), nil
+}
+
+// WatchStatus streams the status snapshot and then every event, like
+// GET /ws/status, until the caller goes away or the server shuts down
+func (p *posService) WatchStatus(req *posv1.WatchStatusRequest, stream posv1.POS_WatchStatusServer) error {
+	events, unsubscribe := p.s.events.Subscribe(websocketEventBuffer)
+	defer unsubscribe()
+
+	snapshot := &posv1.StatusEvent{
+		Type: StatusSnapshot,
+		Time: time.Now().UTC().Format(time.RFC3339),
+		Data: &posv1.StatusEvent_Snapshot{Snapshot: rpcServiceStatus(p.s.serviceStatus())},
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(rpcStatusEvent(event)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-p.s.streamsDone:
+			return apperr.Unavailable("Service is shutting down", 5*time.Second)
+		}
+	}
+}
+
+// TriggerSync runs a sync like POST /sync and streams its progress until
+// it finishes. Other runs publish on the same bus, so only the events of
+// the run this call started are sent.
+func (p *posService) TriggerSync(req *posv1.TriggerSyncRequest, stream posv1.POS_TriggerSyncServer) error {
+	progress, unsubscribe := p.s.events.Subscribe(websocketEventBuffer)
+	defer unsubscribe()
+
+	tracker := possync.StartTracker(p.s.events, possync.ModeRegular, 0)
+	go p.s.runSync(tracker)
+
+	for {
+		select {
+		case event, ok := <-progress:
+			if !ok {
+				return nil
+			}
+			report, ok := event.Data.(possync.ProgressReport)
+			if !ok || report.Run != tracker.Run() {
+				continue
+			}
+			msg := rpcSyncProgress(report)
+			msg.Finished = event.Type == events.SyncFinished
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			if msg.Finished {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-p.s.streamsDone:
+			return apperr.Unavailable("Service is shutting down", 5*time.Second)
+		}
+	}
+}
+
+// rpcStatusEvent converts a published event to its protobuf form
+func rpcStatusEvent(event events.Event) *posv1.StatusEvent {
+	msg := &posv1.StatusEvent{Type: event.Type, Time: event.Time}
+	switch data := event.Data.(type) {
+	case fiber.Map:
+		if offline, ok := data["offline"].(bool); ok {
+			msg.Data = &posv1.StatusEvent_Offline{Offline: offline}
+		}
+	case api.PeripheralStatus:
+		msg.Data = &posv1.StatusEvent_Peripheral{Peripheral: rpcPeripheralStatus(data)}
+	case possync.ProgressReport:
+		progress := rpcSyncProgress(data)
+		progress.Finished = event.Type == events.SyncFinished
+		msg.Data = &posv1.StatusEvent_Sync{Sync: progress}
+	}
+	return msg
+}
+
+// rpcServiceStatus converts the service status to its protobuf form
+func rpcServiceStatus(st api.ServiceStatus) *posv1.ServiceStatus {
+	msg := &posv1.ServiceStatus{
+		Status:         st.Status,
+		LastSyncTime:   st.LastSyncTime,
+		OfflineHours:   int32(st.OfflineHours),
+		IsHealthy:      st.IsHealthy,
+		WindowsService: st.WindowsService,
+		SyncOffsetMs:   st.SyncOffsetMs,
+		NextSyncTime:   st.NextSyncTime,
+	}
+	for _, p := range st.Peripherals {
+		msg.Peripherals = append(msg.Peripherals, rpcPeripheralStatus(p))
+	}
+	return msg
+}
+
+// rpcPeripheralStatus converts a peripheral's status to its protobuf form
+func rpcPeripheralStatus(p api.PeripheralStatus) *posv1.PeripheralStatus {
+	return &posv1.PeripheralStatus{Name: p.Name, Kind: p.Kind, State: p.State, Detail: p.Detail, CheckedAt: p.CheckedAt}
+}
+
+// rpcSyncProgress converts a sync progress report to its protobuf form
+func rpcSyncProgress(r possync.ProgressReport) *posv1.SyncProgress {
+	return &posv1.SyncProgress{
+		Run:     r.Run,
+		Mode:    r.Mode,
+		Batches: int32(r.Batches),
+		Records: int32(r.Records),
+		Total:   int32(r.Total),
+		Percent: int32(r.Percent),
+		Error:   r.Error,
+	}
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	posv1 "github.com/professor93/promo-pos/api/proto/pos/v1"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/events"
+	possync "github.com/professor93/promo-pos/internal/sync"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the gRPC interface of server in memory and returns a
+// client for it
+func dialGRPC(t *testing.T, server *Server) posv1.POSClient {
+	ln := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.serveGRPC(ctx, ln)
+	t.Cleanup(cancel)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return posv1.NewPOSClient(conn)
+}
+
+// withToken returns a call context presenting token
+func withToken(t *testing.T, token string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, metadataAuthorization, "Bearer "+token)
+}
+
+func TestGRPC_CoreOperations(t *testing.T) {
+	server := newTestServerWithLedger(t)
+	if err := server.db.UpsertProduct(&database.Product{ID: "P1", Barcode: "4006381333931", SKU: "PEN", Name: "Pen", Price: 150, TaxRate: 2000, Active: true}, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	if err := server.db.ReplacePromotions([]database.Promotion{{ID: "PR1", Kind: database.PromotionPrice, Value: 120, SKUs: []string{"PEN"}}}); err != nil {
+		t.Fatalf("ReplacePromotions failed: %v", err)
+	}
+	client := dialGRPC(t, server)
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	ctx := withToken(t, token)
+
+	st, err := client.GetStatus(ctx, &posv1.GetStatusRequest{})
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if st.GetStatus() == "" {
+		t.Errorf("Expected a status, got %+v", st)
+	}
+
+	// Priced like GET /price/:barcode, tax and promotions included
+	price, err := client.LookupPrice(ctx, &posv1.LookupPriceRequest{Barcode: "4006381333931"})
+	if err != nil {
+		t.Fatalf("LookupPrice failed: %v", err)
+	}
+	if price.GetSku() != "PEN" || price.GetPrice() != 150 || price.GetTax() != 25 || price.GetPromoPrice() != 120 || price.GetPromoTax() != 20 {
+		t.Errorf("Unexpected price %+v", price)
+	}
+	if len(price.GetPromotions()) != 1 || price.GetPromotions()[0].GetId() != "PR1" {
+		t.Errorf("Expected promotion PR1, got %+v", price.GetPromotions())
+	}
+	if _, err := client.LookupPrice(ctx, &posv1.LookupPriceRequest{Barcode: "0000"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Unknown barcode: expected NotFound, got %v", err)
+	}
+
+	sale := &posv1.RecordSaleRequest{
+		Id:    "S1",
+		Lines: []*posv1.SaleLine{{Sku: "PEN", Quantity: 2, Price: 150}},
+		Total: 300,
+	}
+	recorded, err := client.RecordSale(metadata.AppendToOutgoingContext(ctx, metadataTerminalID, "T1"), sale)
+	if err != nil {
+		t.Fatalf("RecordSale failed: %v", err)
+	}
+	if recorded.GetId() != "S1" || recorded.GetDuplicate() {
+		t.Errorf("Unexpected result %+v", recorded)
+	}
+	if stored, err := server.db.GetSale("S1"); err != nil || stored.TerminalID != "T1" {
+		t.Errorf("Expected the sale stored for T1, got %+v (%v)", stored, err)
+	}
+	if again, err := client.RecordSale(ctx, sale); err != nil || !again.GetDuplicate() {
+		t.Errorf("Retry: expected a duplicate, got %+v (%v)", again, err)
+	}
+	sale.Total = 1
+	sale.Id = "S2"
+	if _, err := client.RecordSale(ctx, sale); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Wrong total: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestGRPC_Credentials(t *testing.T) {
+	server := newTestServerWithDB(t)
+	client := dialGRPC(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.GetStatus(ctx, &posv1.GetStatusRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("No credentials: expected Unauthenticated, got %v", err)
+	}
+	if _, err := client.GetStatus(withToken(t, "wrong"), &posv1.GetStatusRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Unknown token: expected Unauthenticated, got %v", err)
+	}
+
+	// Cashiers look up prices but read the status over the stream only, as
+	// over HTTP
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if _, err := client.LookupPrice(withToken(t, cashier), &posv1.LookupPriceRequest{Barcode: "0000"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Cashier lookup: expected NotFound, got %v", err)
+	}
+	if _, err := client.GetStatus(withToken(t, cashier), &posv1.GetStatusRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Cashier status: expected PermissionDenied, got %v", err)
+	}
+
+	// Self-checkout lanes price scans, but may not trigger a sync over
+	// HTTP, nor over gRPC
+	sco, err := auth.Issue(server.db, auth.RoleSelfCheckout, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if _, err := client.LookupPrice(withToken(t, sco), &posv1.LookupPriceRequest{Barcode: "0000"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Self-checkout lookup: expected NotFound, got %v", err)
+	}
+	stream, err := client.TriggerSync(withToken(t, sco), &posv1.TriggerSyncRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Self-checkout sync: expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestGRPC_Streams(t *testing.T) {
+	server := newTestServerWithDB(t)
+	client := dialGRPC(t, server)
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	watch, err := client.WatchStatus(withToken(t, token), &posv1.WatchStatusRequest{})
+	if err != nil {
+		t.Fatalf("WatchStatus failed: %v", err)
+	}
+	snapshot, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if snapshot.GetType() != StatusSnapshot || snapshot.GetSnapshot() == nil {
+		t.Fatalf("Unexpected snapshot %+v", snapshot)
+	}
+
+	sync, err := client.TriggerSync(withToken(t, token), &posv1.TriggerSyncRequest{})
+	if err != nil {
+		t.Fatalf("TriggerSync failed: %v", err)
+	}
+	var last *posv1.SyncProgress
+	for {
+		progress, err := sync.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if last = progress; progress.GetFinished() {
+			break
+		}
+	}
+	if last.GetError() != "" {
+		t.Errorf("Unexpected sync error %q", last.GetError())
+	}
+
+	// The sync also shows on the status stream
+	for _, want := range []string{events.SyncStarted, events.SyncFinished} {
+		event, err := watch.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if event.GetType() != want || event.GetSync() == nil {
+			t.Errorf("Expected %s with progress, got %+v", want, event)
+		}
+	}
+}
+
+func TestGRPC_TriggerSyncStreamsOwnRun(t *testing.T) {
+	server := newTestServerWithDB(t)
+	client := dialGRPC(t, server)
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	// Other runs publish on the same bus while the call streams its own
+	others, stopOthers := context.WithCancel(context.Background())
+	othersDone := make(chan struct{})
+	go func() {
+		defer close(othersDone)
+		for others.Err() == nil {
+			possync.StartTracker(server.events, possync.ModeRegular, 0).Finish(errors.New("other run"))
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	sync, err := client.TriggerSync(withToken(t, token), &posv1.TriggerSyncRequest{})
+	if err != nil {
+		t.Fatalf("TriggerSync failed: %v", err)
+	}
+	var first, last *posv1.SyncProgress
+	for {
+		progress, err := sync.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if first == nil {
+			first = progress
+		}
+		if progress.GetRun() == "" || progress.GetRun() != first.GetRun() {
+			t.Errorf("Expected every message from run %q, got %+v", first.GetRun(), progress)
+		}
+		if last = progress; progress.GetFinished() {
+			break
+		}
+	}
+	stopOthers()
+	<-othersDone
+	if last.GetError() != "" {
+		t.Errorf("Got the outcome of another run: %q", last.GetError())
+	}
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Handheld stock-taking devices are registered by staff and receive a
+// long-lived enrollment secret. They trade it for a short-lived handheld
+// token (POST /devices/:id/token) that only reaches catalog lookup, stock
+// adjustment and label printing; every handheld request is audited per device.
+
+// DeviceRegistration is returned once when a device is registered
+type DeviceRegistration struct {
+	Device *database.Device `json:"device"`
+	Secret string           `json:"secret"` // Shown once; store it on the device
+}
+
+// DeviceToken is a short-lived handheld session token
+type DeviceToken struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"` // ISO 8601 timestamp
+}
+
+// hashSecret returns the stored form of an enrollment secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleRegisterDevice enrolls a handheld and returns its secret
+func (s *Server) handleRegisterDevice(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		ID   string `json:"id" validate:"required,terminal_id"`
+		Name string `json:"name"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return apperr.Internal(err)
+	}
+	secret := hex.EncodeToString(b)
+
+	device := &database.Device{ID: body.ID, Name: body.Name, SecretHash: hashSecret(secret)}
+	if err := db.RegisterDevice(device); err != nil {
+		if errors.Is(err, database.ErrDeviceExists) {
+			return apperr.Conflict("Device already registered")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Device registered successfully", DeviceRegistration{
+		Device: device,
+		Secret: secret,
+	}))
+}
+
+// handleListDevices lists registered devices, paginated
+func (s *Server) handleListDevices(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	devices, err := db.ListDevices()
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Devices retrieved successfully", devices, page)
+}
+
+// handleDeleteDevice unregisters a device; its tokens stop working at once
+func (s *Server) handleDeleteDevice(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteDevice(c.Params("id")); err != nil {
+		if errors.Is(err, database.ErrDeviceNotFound) {
+			return apperr.NotFound("Device not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "Device unregistered successfully", nil))
+}
+
+// handleGetDeviceAudit returns a device's requests, newest first, paginated
+func (s *Server) handleGetDeviceAudit(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, constants.DefaultDeviceAuditLimit, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	total, err := db.CountDeviceAudit(id)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	entries, err := db.ListDeviceAuditPage(id, page.Offset(), page.PerPage)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewListResponse("Device audit retrieved successfully", entries, api.NewPagination(page, total)))
+}
+
+// handleDeviceToken trades a device's enrollment secret for a short-lived
+// handheld token. Attempts are audited whether or not they succeed.
+func (s *Server) handleDeviceToken(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Secret string `json:"secret" validate:"required"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+
+	if err := s.checkLockout(c, credentialDeviceSecret); err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	device, err := db.GetDevice(id)
+	if err != nil && !errors.Is(err, database.ErrDeviceNotFound) {
+		return apperr.Database(err)
+	}
+	if device == nil || subtle.ConstantTimeCompare([]byte(device.SecretHash), []byte(hashSecret(body.Secret))) != 1 {
+		if device != nil {
+			s.recordDeviceAudit(c.UserContext(), id, "POST /devices/:id/token", fiber.StatusUnauthorized, "invalid secret")
+		}
+		return s.authFailed(c, credentialDeviceSecret, "wrong device secret", apperr.Unauthorized("Unknown device or wrong secret"))
+	}
+
+	ttl := constants.HandheldTokenTTLMinutes * time.Minute
+	token, err := auth.Issue(db, auth.RoleHandheld, id, ttl)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	if err := db.TouchDevice(id); err != nil {
+		return apperr.Database(err)
+	}
+	s.recordDeviceAudit(c.UserContext(), id, "POST /devices/:id/token", fiber.StatusOK, "token issued")
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Device token issued", DeviceToken{
+		Token:     token,
+		ExpiresAt: time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}))
+}
+
+// productEnvelope is the success response for a product lookup up to its
+// result, which is spliced in pre-encoded from the product index
+var productEnvelope = func() []byte {
+	body, err := json.Marshal(api.NewSuccessResponse(api.CodeDataRetrieved, "Product retrieved successfully", json.RawMessage("null")))
+	if err != nil {
+		panic(err)
+	}
+	return body[:len(body)-len("null}")]
+}()
+
+// handleGetProduct looks up a product by barcode. It is the hot path of a
+// scan, so the response is assembled from pre-encoded bytes instead of
+// encoding the envelope per request.
+func (s *Server) handleGetProduct(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	product, err := db.GetProductJSON(c.Params("barcode"))
+	if err != nil {
+		if errors.Is(err, database.ErrProductNotFound) {
+			return apperr.NotFound("Product not found")
+		}
+		return apperr.Database(err)
+	}
+	// The stored record (with its updated_at) is the product's version
+	if s.notModified(c, "product", string(product)) {
+		return nil
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Response().AppendBody(productEnvelope)
+	c.Response().AppendBody(product)
+	c.Response().AppendBodyString("}")
+	return nil
+}
+
+// handleAdjustStock applies a counted stock correction (idempotent on id)
+func (s *Server) handleAdjustStock(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var adj database.StockAdjustment
+	if err := json.Unmarshal(c.Body(), &adj); err != nil {
+		return apperr.BadRequest("Invalid stock adjustment")
+	}
+	if adj.ID == "" || adj.Delta == 0 {
+		return apperr.BadRequest("id and a non-zero delta are required")
+	}
+	adj.SKU = c.Params("sku")
+	adj.DeviceID = callerDevice(c)
+	adj.CreatedAt = ""
+
+	quantity, applied, err := db.AdjustStock(&adj)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Stock adjusted successfully", map[string]interface{}{
+		"sku":      adj.SKU,
+		"quantity": quantity,
+		"applied":  applied,
+	}))
+}
+
+// handleRequestLabels queues shelf labels for the back office printer
+func (s *Server) handleRequestLabels(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var req database.LabelRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return apperr.BadRequest("Invalid label request")
+	}
+	if req.SKU == "" || req.Copies < 1 || req.Copies > constants.MaxLabelCopies {
+		return apperr.BadRequest(fmt.Sprintf("sku and between 1 and %d copies are required", constants.MaxLabelCopies))
+	}
+	req.DeviceID = callerDevice(c)
+
+	if err := db.RequestLabels(&req); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Label print requested", req))
+}
+
+// handleListLabels returns label requests waiting to be printed, paginated
+func (s *Server) handleListLabels(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	labels, err := db.ListPendingLabels()
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Label requests retrieved successfully", labels, page)
+}
+
+// handleLabelsPrinted marks a label request as printed
+func (s *Server) handleLabelsPrinted(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return apperr.BadRequest("Invalid label request ID")
+	}
+
+	if err := db.MarkLabelsPrinted(id); err != nil {
+		if errors.Is(err, database.ErrLabelRequestNotFound) {
+			return apperr.NotFound("Label request not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Label request marked printed", nil))
+}
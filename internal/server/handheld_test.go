@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandheld_RegisterTokenAndAudit(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if err := server.db.UpsertProduct(&database.Product{ID: "P1", Barcode: "4006381333931", SKU: "PEN"}, database.ProductSourceLocal); err != nil {
+		t.Fatalf("Failed to seed product: %v", err)
+	}
+
+	resp, result := laneRequest(t, server, http.MethodPost, "/devices", adminBearer(t, server), `{"id":"HH1","name":"Aisle scanner"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Register returned %d", resp.StatusCode)
+	}
+	var reg struct {
+		Secret string `json:"secret"`
+	}
+	json.Unmarshal(result, &reg)
+	if reg.Secret == "" {
+		t.Fatal("Expected an enrollment secret")
+	}
+
+	resp, _ = laneRequest(t, server, http.MethodPost, "/devices", adminBearer(t, server), `{"id":"HH1"}`)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Duplicate register returned %d, want 409", resp.StatusCode)
+	}
+
+	resp, _ = laneRequest(t, server, http.MethodPost, "/devices/HH1/token", adminBearer(t, server), `{"secret":"wrong"}`)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Wrong secret returned %d, want 401", resp.StatusCode)
+	}
+
+	resp, result = laneRequest(t, server, http.MethodPost, "/devices/HH1/token", adminBearer(t, server), `{"secret":"`+reg.Secret+`"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Token exchange returned %d", resp.StatusCode)
+	}
+	var tok DeviceToken
+	json.Unmarshal(result, &tok)
+
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/status", tok.Token, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Handheld reached /status with %d, want 403", resp.StatusCode)
+	}
+	resp, result = laneRequest(t, server, http.MethodGet, "/products/4006381333931", tok.Token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Product lookup returned %d", resp.StatusCode)
+	}
+	var product database.Product
+	if err := json.Unmarshal(result, &product); err != nil || product.SKU != "PEN" {
+		t.Errorf("Expected product PEN, got %s (%v)", result, err)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/products/0000000000000", tok.Token, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown barcode returned %d, want 404", resp.StatusCode)
+	}
+
+	body := `{"id":"ADJ-1","delta":-2,"reason":"damaged"}`
+	for i := 0; i < 2; i++ {
+		resp, result = laneRequest(t, server, http.MethodPost, "/stock/PEN/adjust", tok.Token, body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Adjust returned %d", resp.StatusCode)
+		}
+		var adjusted struct {
+			Quantity int  `json:"quantity"`
+			Applied  bool `json:"applied"`
+		}
+		json.Unmarshal(result, &adjusted)
+		if adjusted.Quantity != -2 || adjusted.Applied != (i == 0) {
+			t.Errorf("Attempt %d: got quantity %d applied %v", i, adjusted.Quantity, adjusted.Applied)
+		}
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/labels", tok.Token, `{"sku":"PEN","copies":3}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Label request returned %d", resp.StatusCode)
+	}
+
+	entries, err := server.db.ListDeviceAudit("HH1", 100)
+	if err != nil {
+		t.Fatalf("Failed to list audit: %v", err)
+	}
+	// wrong secret, token, /status, two lookups, two adjustments, labels
+	if len(entries) != 8 {
+		t.Errorf("Expected 8 audit entries, got %d", len(entries))
+	}
+
+	if resp, _ := guardedRequest(t, server, http.MethodDelete, "/devices/HH1", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Delete returned %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/products/4006381333931", tok.Token, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Token of deleted device returned %d, want 401", resp.StatusCode)
+	}
+}
+
+// BenchmarkGetProduct measures a handheld's barcode scan through the full
+// handler stack (without the network) and reports its p99 latency. The
+// lookup itself stays well under 100µs; the token check and the device
+// audit entry written for every request make up most of the rest.
+func BenchmarkGetProduct(b *testing.B) {
+	server := newTestServerWithDB(b)
+	products := make([]database.Product, 10000)
+	for i := range products {
+		products[i] = database.Product{ID: "P" + strconv.Itoa(i), Barcode: strconv.Itoa(4006381000000 + i), SKU: "SKU" + strconv.Itoa(i), Price: 990}
+	}
+	if err := server.db.ApplyProducts(products, nil); err != nil {
+		b.Fatalf("Failed to seed catalog: %v", err)
+	}
+
+	// Scans come from a registered handheld, audited like in the store
+	if err := server.db.RegisterDevice(&database.Device{ID: "HH1", Name: "Aisle scanner"}); err != nil {
+		b.Fatalf("Failed to register device: %v", err)
+	}
+	token, err := auth.Issue(server.db, auth.RoleHandheld, "HH1", 0)
+	if err != nil {
+		b.Fatalf("Failed to issue token: %v", err)
+	}
+
+	handler := server.GetApp().Handler()
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(http.MethodGet)
+	ctx.Request.SetRequestURI("/products/4006381004242")
+	ctx.Request.Header.Set(HeaderTerminalID, "HH1")
+	ctx.Request.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+
+	handler(&ctx) // Load the product index
+	if ctx.Response.StatusCode() != http.StatusOK {
+		b.Fatalf("Product lookup returned %d", ctx.Response.StatusCode())
+	}
+
+	latencies := make([]time.Duration, b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		ctx.Response.Reset()
+		handler(&ctx)
+		latencies[i] = time.Since(start)
+	}
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)*99/100])/float64(time.Microsecond), "p99-µs")
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// quickCheckCache holds the last database quick check: pings are cheap
+// enough for every /health call, a quick check of a large store is not
+type quickCheckCache struct {
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+// healthChecks runs every /health probe
+func (s *Server) healthChecks(now time.Time) api.HealthChecks {
+	stats := s.syncStats.Snapshot()
+	last := s.lastSync()
+	return api.HealthChecks{
+		Database:       s.probeDatabase(now),
+		Config:         s.probeConfig(),
+		LastSync:       s.probeAge(now, last, sinceOrStartup(now, last, stats.Started), "The terminal has not synced yet"),
+		DiskSpace:      s.probeDiskSpace(),
+		BackendContact: s.probeAge(now, stats.LastContact, sinceOrStartup(now, stats.LastContact, stats.Started), "No contact with the backend since startup"),
+	}
+}
+
+// probeDatabase pings the database and runs the quick integrity check
+// every HealthQuickCheckSeconds
+func (s *Server) probeDatabase(now time.Time) api.HealthProbe {
+	if s.db == nil {
+		return api.HealthProbe{Status: api.ProbeFailed, Message: "Database is not open"}
+	}
+	if err := s.db.Ping(); err != nil {
+		return api.HealthProbe{Status: api.ProbeFailed, Message: "Database does not answer"}
+	}
+
+	s.quickCheck.mu.Lock()
+	if s.quickCheck.at.IsZero() || now.Sub(s.quickCheck.at) >= constants.HealthQuickCheckSeconds*time.Second {
+		s.quickCheck.err = s.db.QuickCheck()
+		s.quickCheck.at = now
+	}
+	at, err := s.quickCheck.at, s.quickCheck.err
+	s.quickCheck.mu.Unlock()
+
+	probe := api.HealthProbe{Status: api.ProbeOK, At: at.UTC().Format(time.RFC3339)}
+	switch {
+	case err != nil:
+		probe.Status, probe.Message = api.ProbeFailed, "Integrity check found corruption"
+	case s.db.IsReadOnly():
+		probe.Status, probe.Message = api.ProbeDegraded, "Database is read-only"
+	}
+	return probe
+}
+
+// probeConfig checks the config file can still be read and decrypted
+func (s *Server) probeConfig() api.HealthProbe {
+	if s.config.CheckConfig == nil {
+		return api.HealthProbe{Status: api.ProbeOK}
+	}
+	if err := s.config.CheckConfig(); err != nil {
+		return api.HealthProbe{Status: api.ProbeFailed, Message: "Config file cannot be read or decrypted"}
+	}
+	return api.HealthProbe{Status: api.ProbeOK}
+}
+
+// probeAge grades the time since an event (the last sync, the last backend
+// contact): degraded after HealthSyncLateIntervals sync intervals, failed
+// once the terminal has been offline for longer than it may be. An event
+// that never happened is aged from startup but never fails: uptime alone
+// does not make a terminal offline.
+func (s *Server) probeAge(now, at time.Time, age time.Duration, never string) api.HealthProbe {
+	seconds := int64(age / time.Second)
+	probe := api.HealthProbe{Status: api.ProbeOK, AgeSeconds: &seconds}
+	if at.IsZero() {
+		probe.Message = never
+	} else {
+		probe.At = at.UTC().Format(time.RFC3339)
+	}
+
+	interval := s.config.SyncSchedule.Interval
+	switch {
+	case s.config.MaxOffline > 0 && age >= s.config.MaxOffline && !at.IsZero():
+		probe.Status = api.ProbeFailed
+		probe.Message = fmt.Sprintf("Offline for longer than the allowed %s", s.config.MaxOffline)
+	case interval > 0 && age >= constants.HealthSyncLateIntervals*interval:
+		probe.Status = api.ProbeDegraded
+		if probe.Message == "" {
+			probe.Message = fmt.Sprintf("Nothing for %d sync intervals", constants.HealthSyncLateIntervals)
+		}
+	}
+	return probe
+}
+
+// probeDiskSpace checks the free space on the volume holding the database
+func (s *Server) probeDiskSpace() api.HealthProbe {
+	if s.db == nil {
+		return api.HealthProbe{Status: api.ProbeDegraded, Message: "No database to check"}
+	}
+	free, err := s.db.FreeSpace()
+	if err != nil {
+		return api.HealthProbe{Status: api.ProbeDegraded, Message: "Free space could not be read"}
+	}
+
+	probe := api.HealthProbe{Status: api.ProbeOK, FreeBytes: &free}
+	switch {
+	case free < constants.HealthDiskCriticalBytes:
+		probe.Status, probe.Message = api.ProbeFailed, "Data volume is almost full"
+	case free < constants.HealthDiskLowBytes:
+		probe.Status, probe.Message = api.ProbeDegraded, "Data volume is low on space"
+	}
+	return probe
+}
+
+// sinceOrStartup returns the time since at, or since startup when at is zero
+func sinceOrStartup(now, at, started time.Time) time.Duration {
+	if at.IsZero() {
+		at = started
+	}
+	return max(now.Sub(at), 0)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/logging"
+)
+
+// Frontends retry mutating requests after a timeout without knowing whether
+// the first attempt went through. With an Idempotency-Key header the first
+// successful response is kept (encrypted, in an expiring setting) and sent
+// again for every retry with the same key, instead of running the handler
+// twice. Errors are not kept, so a failed request can be retried as is.
+
+const (
+	// HeaderIdempotencyKey carries the client's key for a mutating request
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplayed marks a response replayed from the cache
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// idempotencyKeyPrefix prefixes the encrypted settings holding responses
+	idempotencyKeyPrefix = "idempotency."
+
+	// idempotencyTTL bounds how long a response is replayed; retries come
+	// within seconds, offline frontends replay their queue within a day
+	idempotencyTTL = 24 * time.Hour
+)
+
+var idempotencyKeyPattern = regexp.MustCompile(`^[\x21-\x7e]{1,255}$`)
+
+// idempotentResponse is a cached response and the request it answered
+type idempotentResponse struct {
+	RequestHash string `json:"request_hash"` // SHA-256 of the request body
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// idempotencyLocks holds the keys of requests currently being handled
+type idempotencyLocks struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// acquire claims key, reporting false if another request holds it
+func (l *idempotencyLocks) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.keys == nil {
+		l.keys = make(map[string]struct{})
+	}
+	if _, held := l.keys[key]; held {
+		return false
+	}
+	l.keys[key] = struct{}{}
+	return true
+}
+
+// release frees key
+func (l *idempotencyLocks) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.keys, key)
+}
+
+// idempotent replays the cached response of a request repeated with the
+// same Idempotency-Key, or runs the handler and caches its response.
+// Requests without the header pass through unchanged.
+func (s *Server) idempotent(c *fiber.Ctx) error {
+	key := c.Get(HeaderIdempotencyKey)
+	if key == "" || s.db == nil {
+		return c.Next()
+	}
+	if !idempotencyKeyPattern.MatchString(key) {
+		return apperr.BadRequest("Invalid " + HeaderIdempotencyKey + " header")
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	// Keys are scoped to the terminal and route, so two tills picking the
+	// same key (or one key sent to two routes) never collide
+	scope := sha256.Sum256([]byte(terminal + "\x00" + c.Method() + "\x00" + routePath(c) + "\x00" + key))
+	settingKey := idempotencyKeyPrefix + hex.EncodeToString(scope[:])
+	bodyHash := sha256.Sum256(c.Body())
+	requestHash := hex.EncodeToString(bodyHash[:])
+
+	if !s.idempotency.acquire(settingKey) {
+		return apperr.Conflict("A request with this " + HeaderIdempotencyKey + " is still in progress").Retryable(time.Second)
+	}
+	defer s.idempotency.release(settingKey)
+
+	cached, err := s.db.GetSetting(settingKey)
+	switch {
+	case err == nil:
+		var resp idempotentResponse
+		if err := json.Unmarshal([]byte(cached), &resp); err != nil {
+			return apperr.Internal(err)
+		}
+		if resp.RequestHash != requestHash {
+			return apperr.BadRequest(HeaderIdempotencyKey + " was already used for a different request")
+		}
+		c.Set(HeaderIdempotentReplayed, "true")
+		c.Set(fiber.HeaderContentType, resp.ContentType)
+		return c.Status(resp.Status).Send(resp.Body)
+	case !errors.Is(err, database.ErrSettingNotFound):
+		return apperr.Database(err)
+	}
+
+	if err := c.Next(); err != nil {
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if status < fiber.StatusOK || status >= fiber.StatusMultipleChoices {
+		return nil
+	}
+	data, err := json.Marshal(idempotentResponse{
+		RequestHash: requestHash,
+		Status:      status,
+		ContentType: string(c.Response().Header.ContentType()),
+		Body:        bytes.Clone(c.Response().Body()),
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	// The request succeeded; failing to cache it only loses the replay
+	if err := s.db.SetSettingWithTTL(settingKey, string(data), idempotencyTTL); err != nil {
+		logging.Printf(c.UserContext(), "Warning: failed to cache idempotent response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func idempotentRequest(t *testing.T, server *Server, key, body string) (*http.Response, DataResponse) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/data", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTerminalID, "T1")
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result DataResponse `json:"result"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &envelope)
+	return resp, envelope.Result
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	server := newTestServerWithLedger(t)
+	adjust := `{"type":"stock_adjustment","data":{"sku":"PEN","delta":5}}`
+
+	resp, first := idempotentRequest(t, server, "retry-1", adjust)
+	if resp.StatusCode != http.StatusOK || first.ID == "" {
+		t.Fatalf("Expected the adjustment to be stored, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Errorf("First response should not be marked as replayed")
+	}
+
+	resp, retry := idempotentRequest(t, server, "retry-1", adjust)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("Expected a replayed 200, got %d", resp.StatusCode)
+	}
+	if retry.ID != first.ID {
+		t.Errorf("Expected the replay to return adjustment %s, got %s", first.ID, retry.ID)
+	}
+	if level, err := server.db.GetStockLevel("PEN"); err != nil || level != 5 {
+		t.Errorf("Expected the stock to move once (5), got %d (%v)", level, err)
+	}
+
+	// Without a key every request is handled
+	idempotentRequest(t, server, "", adjust)
+	idempotentRequest(t, server, "", adjust)
+	if level, _ := server.db.GetStockLevel("PEN"); level != 15 {
+		t.Errorf("Expected requests without a key to apply, got stock %d", level)
+	}
+}
+
+func TestIdempotency_KeyReusedForDifferentRequest(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	idempotentRequest(t, server, "retry-2", `{"type":"stock_adjustment","data":{"sku":"PEN","delta":1}}`)
+	resp, _ := idempotentRequest(t, server, "retry-2", `{"type":"stock_adjustment","data":{"sku":"PEN","delta":2}}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a reused key, got %d", resp.StatusCode)
+	}
+}
+
+func TestIdempotency_ErrorsAreNotCached(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	resp, _ := idempotentRequest(t, server, "retry-3", `{"type":"stock_adjustment","data":{"sku":"PEN","delta":0}}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an invalid adjustment, got %d", resp.StatusCode)
+	}
+	resp, _ = idempotentRequest(t, server, "retry-3", `{"type":"stock_adjustment","data":{"sku":"PEN","delta":3}}`)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Errorf("Expected the corrected request to run, got %d", resp.StatusCode)
+	}
+}
+
+func TestIdempotency_InvalidKey(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	resp, _ := idempotentRequest(t, server, strings.Repeat("k", 256), `{"type":"stock_adjustment","data":{"sku":"PEN","delta":1}}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an oversized key, got %d", resp.StatusCode)
+	}
+}
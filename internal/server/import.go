@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/importer"
+	"github.com/professor93/promo-pos/internal/jobs"
+)
+
+// JobKindImport is the job kind of a product or customer file import
+const JobKindImport = "import"
+
+// handleImport accepts a CSV or JSON file upload (multipart field "file")
+// of products or customers (field "entity") and imports it in the
+// background. The format is taken from the "format" field, else the file
+// extension. Progress, and once finished the row errors, are reported on
+// /jobs/:id.
+func (s *Server) handleImport(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	manager, err := s.requireJobs()
+	if err != nil {
+		return err
+	}
+
+	entity := c.FormValue("entity")
+	if !importer.ValidEntity(entity) {
+		return apperr.Invalid([]api.FieldError{{Field: "entity", Rule: "oneof", Param: "products customers", Message: "must be products or customers"}})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return apperr.Invalid([]api.FieldError{{Field: "file", Rule: "required", Message: "is required"}})
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	if format != importer.FormatCSV && format != importer.FormatJSON {
+		return apperr.Invalid([]api.FieldError{{Field: "format", Rule: "oneof", Param: "csv json", Message: "must be csv or json"}})
+	}
+
+	// The upload does not outlive the request, so the job gets a copy
+	file, err := header.Open()
+	if err != nil {
+		return apperr.BadRequest("Uploaded file could not be read")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return apperr.BadRequest("Uploaded file could not be read")
+	}
+	if len(data) == 0 {
+		return apperr.BadRequest("Uploaded file is empty")
+	}
+
+	imp := importer.New(db)
+	job, err := manager.Submit(JobKindImport, func(ctx context.Context, p *jobs.Progress) (string, error) {
+		report, err := imp.Run(ctx, p, entity, format, data)
+		if report != nil {
+			p.SetDetails(report)
+		}
+		return "", err
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return acceptJob(c, job, "Import started")
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/importer"
+	"github.com/professor93/promo-pos/internal/jobs"
+)
+
+// importRequest uploads file as a multipart import of entity
+func importRequest(t *testing.T, server *Server, entity, filename, file string) *http.Response {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("entity", entity)
+	part, _ := w.CreateFormFile("file", filename)
+	part.Write([]byte(file))
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/import", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	return resp
+}
+
+func TestImport_ProductsJob(t *testing.T) {
+	server := newTestServerWithDB(t)
+	manager, err := jobs.NewManager(server.db)
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	t.Cleanup(manager.Shutdown)
+	server.jobs = manager
+
+	if resp := importRequest(t, server, "suppliers", "s.csv", "id\n1\n"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown entity returned %d, want 400", resp.StatusCode)
+	}
+	if resp := importRequest(t, server, "products", "p.xlsx", "x"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown format returned %d, want 400", resp.StatusCode)
+	}
+
+	resp := importRequest(t, server, "products", "catalog.csv", "barcode,name,price\n111,Milk,500\n222,,300\n")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Import returned %d, want 202", resp.StatusCode)
+	}
+
+	jobID := resp.Header.Get("Location")[len("/jobs/"):]
+	deadline := time.Now().Add(5 * time.Second)
+	var job *database.Job
+	for {
+		job, err = manager.Get(jobID)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if job.Status == database.JobSucceeded {
+			break
+		}
+		if job.Status == database.JobFailed || time.Now().After(deadline) {
+			t.Fatalf("Import job did not succeed: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var report importer.Report
+	if err := json.Unmarshal(job.Details, &report); err != nil {
+		t.Fatalf("Failed to parse import report: %v", err)
+	}
+	if report.Imported != 1 || report.Failed != 1 || report.Errors[0].Row != 2 {
+		t.Errorf("Unexpected import report: %+v", report)
+	}
+	if _, err := server.db.GetProductByBarcode("111"); err != nil {
+		t.Errorf("Expected 111 imported: %v", err)
+	}
+}
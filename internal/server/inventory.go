@@ -0,0 +1,219 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// /inventory queries stock levels and records goods receipts, adjustments
+// and physical counts. Every movement is written to the stock movement log
+// (the stock_adjustments table) in the same transaction as the level it
+// changes, and reaches the sync outbox through the table's CDC triggers.
+// Movements are idempotent on their client-generated ID.
+
+// ReceiptLine is one SKU of a goods receipt
+type ReceiptLine struct {
+	SKU      string `json:"sku" validate:"required,max=64"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+// ReceiptRequest records goods delivered to the store
+type ReceiptRequest struct {
+	ID        string        `json:"id" validate:"required,max=60"` // Line movements are logged as <id>.<n>
+	Reference string        `json:"reference,omitempty" validate:"max=255"`
+	Reason    string        `json:"reason,omitempty" validate:"max=255"`
+	Lines     []ReceiptLine `json:"lines" validate:"required,min=1,dive"`
+}
+
+// AdjustmentRequest corrects a SKU's stock by a delta
+type AdjustmentRequest struct {
+	ID     string `json:"id" validate:"required,max=64"`
+	SKU    string `json:"sku" validate:"required,max=64"`
+	Delta  int    `json:"delta" validate:"ne=0"`
+	Reason string `json:"reason,omitempty" validate:"max=255"`
+}
+
+// CountRequest sets a SKU's stock to the quantity found on the shelf
+type CountRequest struct {
+	ID      string `json:"id" validate:"required,max=64"`
+	SKU     string `json:"sku" validate:"required,max=64"`
+	Counted *int   `json:"counted" validate:"required,min=0"`
+	Reason  string `json:"reason,omitempty" validate:"max=255"`
+}
+
+// StockMovementResult reports recorded movements and the resulting levels
+type StockMovementResult struct {
+	Movements []*database.StockAdjustment `json:"movements"`
+	Levels    []database.StockLevel       `json:"levels"`
+	Applied   int                         `json:"applied"` // Movements not recorded by an earlier request
+}
+
+// InventoryDetail is a SKU's stock level and its latest movements
+type InventoryDetail struct {
+	SKU       string                     `json:"sku"`
+	Quantity  int                        `json:"quantity"`
+	Movements []database.StockAdjustment `json:"movements"`
+}
+
+// handleListInventory lists stock levels, paginated. ?sku_prefix= narrows
+// the SKUs and ?at_most= keeps those at or below a quantity (low stock).
+func (s *Server) handleListInventory(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	filter := database.StockLevelFilter{SKUPrefix: c.Query("sku_prefix")}
+	if v := c.Query("at_most"); v != "" {
+		atMost, err := strconv.Atoi(v)
+		if err != nil {
+			return apperr.Invalid([]api.FieldError{{Field: "at_most", Rule: "numeric", Message: "must be a whole number"}})
+		}
+		filter.AtMost = &atMost
+	}
+
+	levels, err := db.ListStockLevels(filter)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Stock levels retrieved successfully", levels, page)
+}
+
+// handleGetInventory returns a SKU's stock level and latest movements
+// (?movements=, default constants.DefaultStockMovementLimit)
+func (s *Server) handleGetInventory(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	limit := constants.DefaultStockMovementLimit
+	if v := c.Query("movements"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > constants.MaxStockMovementLimit {
+			return apperr.Invalid([]api.FieldError{{
+				Field:   "movements",
+				Rule:    "range",
+				Param:   "0-" + strconv.Itoa(constants.MaxStockMovementLimit),
+				Message: fmt.Sprintf("must be between 0 and %d", constants.MaxStockMovementLimit),
+			}})
+		}
+		limit = n
+	}
+
+	sku := c.Params("sku")
+	quantity, err := db.GetStockLevel(sku)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	movements, err := db.ListStockMovements(sku, limit)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	if movements == nil {
+		movements = []database.StockAdjustment{}
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Stock level retrieved successfully", InventoryDetail{
+		SKU:       sku,
+		Quantity:  quantity,
+		Movements: movements,
+	}))
+}
+
+// handleRecordReceipt adds delivered goods to stock, one movement per line
+func (s *Server) handleRecordReceipt(c *fiber.Ctx) error {
+	var in ReceiptRequest
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+	if len(in.Lines) > constants.MaxReceiptLines {
+		return apperr.BadRequest(fmt.Sprintf("A receipt can have at most %d lines", constants.MaxReceiptLines))
+	}
+
+	movements := make([]*database.StockAdjustment, len(in.Lines))
+	for i, line := range in.Lines {
+		movements[i] = &database.StockAdjustment{
+			ID:        fmt.Sprintf("%s.%d", in.ID, i+1),
+			Kind:      database.StockMovementReceipt,
+			SKU:       line.SKU,
+			Delta:     line.Quantity,
+			Reference: in.Reference,
+			Reason:    in.Reason,
+		}
+	}
+
+	return s.recordStockMovements(c, movements, "Goods receipt recorded successfully")
+}
+
+// handleRecordAdjustment corrects a SKU's stock by a delta
+func (s *Server) handleRecordAdjustment(c *fiber.Ctx) error {
+	var in AdjustmentRequest
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+
+	return s.recordStockMovements(c, []*database.StockAdjustment{{
+		ID:     in.ID,
+		Kind:   database.StockMovementAdjustment,
+		SKU:    in.SKU,
+		Delta:  in.Delta,
+		Reason: in.Reason,
+	}}, "Stock adjusted successfully")
+}
+
+// handleRecordCount replaces a SKU's stock with a counted quantity; the
+// logged delta is the difference from the level it replaced
+func (s *Server) handleRecordCount(c *fiber.Ctx) error {
+	var in CountRequest
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+
+	return s.recordStockMovements(c, []*database.StockAdjustment{{
+		ID:      in.ID,
+		Kind:    database.StockMovementCount,
+		SKU:     in.SKU,
+		Counted: in.Counted,
+		Reason:  in.Reason,
+	}}, "Stock count recorded successfully")
+}
+
+// recordStockMovements logs movements made by the caller's device and
+// reports the resulting levels
+func (s *Server) recordStockMovements(c *fiber.Ctx, movements []*database.StockAdjustment, message string) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	device := callerDevice(c)
+	for _, m := range movements {
+		m.DeviceID = device
+	}
+
+	levels, applied, err := db.RecordStockMovements(movements)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	result := StockMovementResult{Movements: movements, Applied: applied}
+	for sku, quantity := range levels {
+		result.Levels = append(result.Levels, database.StockLevel{SKU: sku, Quantity: quantity})
+	}
+	sort.Slice(result.Levels, func(i, j int) bool { return result.Levels[i].SKU < result.Levels[j].SKU })
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, message, result))
+}
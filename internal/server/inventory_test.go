@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInventory_ReceiptAdjustCount(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	resp, out := productRequest(t, server, http.MethodPost, "/inventory/receipts", `{"id":"RCV-1","reference":"PO-9","lines":[{"sku":"MILK","quantity":24},{"sku":"BREAD","quantity":10}]}`)
+	if resp.StatusCode != http.StatusOK || out.Result.(map[string]interface{})["applied"].(float64) != 2 {
+		t.Fatalf("Receipt returned %d: %+v", resp.StatusCode, out)
+	}
+
+	resp, out = productRequest(t, server, http.MethodPost, "/inventory/adjustments", `{"id":"ADJ-1","sku":"MILK","delta":-4,"reason":"damaged"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Adjustment returned %d: %+v", resp.StatusCode, out)
+	}
+
+	resp, out = productRequest(t, server, http.MethodPost, "/inventory/counts", `{"id":"CNT-1","sku":"MILK","counted":18}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Count returned %d: %+v", resp.StatusCode, out)
+	}
+	movement := out.Result.(map[string]interface{})["movements"].([]interface{})[0].(map[string]interface{})
+	if movement["delta"].(float64) != -2 || movement["kind"] != "count" {
+		t.Errorf("Expected count logged with delta -2, got %+v", movement)
+	}
+
+	resp, out = productRequest(t, server, http.MethodGet, "/inventory/MILK", "")
+	detail := out.Result.(map[string]interface{})
+	if resp.StatusCode != http.StatusOK || detail["quantity"].(float64) != 18 || len(detail["movements"].([]interface{})) != 3 {
+		t.Errorf("Stock level returned %d: %+v", resp.StatusCode, detail)
+	}
+
+	resp, out = productRequest(t, server, http.MethodGet, "/inventory?at_most=15", "")
+	if resp.StatusCode != http.StatusOK || len(out.Result.([]interface{})) != 1 {
+		t.Errorf("Low stock list returned %d: %+v", resp.StatusCode, out)
+	}
+
+	if resp, _ := productRequest(t, server, http.MethodPost, "/inventory/adjustments", `{"id":"ADJ-2","sku":"MILK","delta":0}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Zero adjustment returned %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := productRequest(t, server, http.MethodPost, "/inventory/counts", `{"id":"CNT-2","sku":"MILK"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Count without quantity returned %d, want 400", resp.StatusCode)
+	}
+}
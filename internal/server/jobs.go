@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/jobs"
+)
+
+// maxListedJobs bounds GET /jobs
+const maxListedJobs = 50
+
+// requireJobs returns the job manager or a 503 error when none is configured
+func (s *Server) requireJobs() (*jobs.Manager, error) {
+	if s.jobs == nil {
+		return nil, apperr.Unavailable(api.MessageServiceUnavailable, 5*time.Second)
+	}
+	return s.jobs, nil
+}
+
+// acceptJob answers 202 with the job and where to poll it
+func acceptJob(c *fiber.Ctx, job *database.Job, message string) error {
+	c.Location("/jobs/" + job.ID)
+	return c.Status(fiber.StatusAccepted).JSON(api.NewSuccessResponse(api.CodeJobAccepted, message, job))
+}
+
+// handleListJobs lists the most recent jobs, paginated
+func (s *Server) handleListJobs(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, maxListedJobs, maxListedJobs)
+	if err != nil {
+		return err
+	}
+
+	list, err := db.ListJobs(maxListedJobs)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Jobs retrieved successfully", list, page)
+}
+
+// handleGetJob returns the status and progress of a job
+func (s *Server) handleGetJob(c *fiber.Ctx) error {
+	manager, err := s.requireJobs()
+	if err != nil {
+		return err
+	}
+
+	job, err := manager.Get(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrJobNotFound) {
+			return apperr.NotFound("Job not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Job retrieved successfully", job))
+}
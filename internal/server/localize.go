@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/i18n"
+)
+
+// SetLocale sets the language of responses to callers whose Accept-Language
+// names none the service speaks (see i18n.Negotiate)
+func (s *Server) SetLocale(lang string) {
+	s.locale.Store(&lang)
+}
+
+// localize sends the message of APIResponses in the caller's language.
+// Errors are rendered here rather than by the app's error handler, so their
+// messages are translated too.
+func (s *Server) localize(c *fiber.Ctx) error {
+	var fallback string
+	if locale := s.locale.Load(); locale != nil {
+		fallback = *locale
+	}
+	lang := i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage), fallback)
+	c.Vary(fiber.HeaderAcceptLanguage)
+	c.Set(fiber.HeaderContentLanguage, lang)
+
+	if err := c.Next(); err != nil {
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			return err
+		}
+	}
+	if lang == i18n.English {
+		return nil
+	}
+
+	resp := c.Response()
+	if resp.IsBodyStream() || !strings.HasPrefix(string(resp.Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return nil
+	}
+	if body, ok := translateMessage(resp.Body(), lang); ok {
+		resp.SetBodyRaw(body)
+	}
+	return nil
+}
+
+// translateMessage replaces the message of an encoded APIResponse with its
+// translation. The envelope encodes ok, code and message ahead of the
+// result, so the result is never parsed; other JSON is left alone.
+func translateMessage(body []byte, lang string) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		start := dec.InputOffset()
+		switch key {
+		case "ok", "code":
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, false
+			}
+			continue
+		case "message":
+		default:
+			return nil, false
+		}
+
+		var message string
+		if err := dec.Decode(&message); err != nil {
+			return nil, false
+		}
+		translated := i18n.Translate(lang, message)
+		if translated == message {
+			return nil, false
+		}
+		encoded, err := json.Marshal(translated)
+		if err != nil {
+			return nil, false
+		}
+
+		// The value starts after the colon following the key
+		end := dec.InputOffset()
+		start += int64(bytes.IndexByte(body[start:end], '"'))
+		out := make([]byte, 0, len(body)+len(encoded))
+		out = append(out, body[:start]...)
+		out = append(out, encoded...)
+		return append(out, body[end:]...), true
+	}
+	return nil, false
+}
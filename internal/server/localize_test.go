@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/i18n"
+)
+
+func TestLocalize_Messages(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	get := func(path, language string) (*http.Response, api.APIResponse) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if language != "" {
+			req.Header.Set("Accept-Language", language)
+		}
+		resp, err := send(server, asAdmin(t, server, req), -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var envelope api.APIResponse
+		data, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			t.Fatalf("Invalid JSON after translation: %s", data)
+		}
+		return resp, envelope
+	}
+
+	resp, envelope := get("/price/0000", "es-ES,es;q=0.9")
+	if resp.StatusCode != http.StatusNotFound || envelope.Message != "Producto no encontrado" {
+		t.Errorf("Spanish error: got %d %q", resp.StatusCode, envelope.Message)
+	}
+	if resp.Header.Get("Content-Language") != i18n.Spanish {
+		t.Errorf("Expected Content-Language es, got %q", resp.Header.Get("Content-Language"))
+	}
+
+	resp, envelope = get("/status", "ru")
+	if envelope.Message != "Статус получен" || envelope.Result == nil {
+		t.Errorf("Russian success: got %q with result %v", envelope.Message, envelope.Result)
+	}
+
+	if _, envelope = get("/status", ""); envelope.Message != "Status retrieved successfully" {
+		t.Errorf("Default: expected English, got %q", envelope.Message)
+	}
+
+	// The configured locale covers callers without a supported language
+	server.SetLocale(i18n.Russian)
+	if _, envelope = get("/price/0000", "de-DE"); envelope.Message != "Товар не найден" {
+		t.Errorf("Configured locale: got %q", envelope.Message)
+	}
+	if _, envelope = get("/price/0000", "en-US"); envelope.Message != "Product not found" {
+		t.Errorf("Accept-Language over locale: got %q", envelope.Message)
+	}
+}
+
+func TestTranslateMessage(t *testing.T) {
+	body := []byte(`{"ok":false,"code":-13,"message":"Product not found","meta":{"doc_code":"ERR_NOT_FOUND"}}`)
+	out, ok := translateMessage(body, i18n.Spanish)
+	if !ok || string(out) != `{"ok":false,"code":-13,"message":"Producto no encontrado","meta":{"doc_code":"ERR_NOT_FOUND"}}` {
+		t.Errorf("Unexpected translation: %s", out)
+	}
+
+	for _, body := range []string{
+		`{"ok":true,"code":1,"message":"Something new"}`,
+		`{"data":{"message":"Product not found"}}`,
+		`[1,2]`,
+		`not json`,
+	} {
+		if _, ok := translateMessage([]byte(body), i18n.Spanish); ok {
+			t.Errorf("Translated %s", body)
+		}
+	}
+}
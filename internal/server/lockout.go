@@ -0,0 +1,217 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Failed authentication attempts are tracked per source address and kind
+// of credential. After AuthLockoutThreshold failures within the failure
+// window the source is locked out of that kind of credential, for twice as
+// long on each further lockout, so nobody on the store LAN can brute-force
+// a token, API key, PIN or secret. Failures only age out of the window: a
+// credential that works does not clear them, or anyone holding one could
+// keep guessing between successes.
+
+// Kinds of credential whose failures are counted separately, so a cashier
+// mistyping PINs does not lock the till's frontend token out
+const (
+	credentialToken         = "token" // Bearer tokens and frontend JWTs
+	credentialAPIKey        = "api_key"
+	credentialAPISecret     = "api_secret"
+	credentialAdminPassword = "admin_password"
+	credentialPIN           = "pin"
+	credentialDeviceSecret  = "device_secret"
+)
+
+// lockoutMemory is how long a source's lockout count is kept after its
+// last failure
+const lockoutMemory = 24 * time.Hour
+
+// maxTrackedSources bounds the memory held for failing sources
+const maxTrackedSources = 10000
+
+// authAttempts is the failure history of one source and kind
+type authAttempts struct {
+	failures    int       // Failures in the current window
+	firstAt     time.Time // First failure of the current window
+	lastAt      time.Time // Most recent failure
+	lockouts    int       // Lockouts so far, driving the escalation
+	lockedUntil time.Time
+}
+
+// authLimiter locks out sources failing authentication too often
+type authLimiter struct {
+	mu      sync.Mutex
+	sources map[string]*authAttempts
+	now     func() time.Time
+}
+
+// newAuthLimiter creates an empty limiter
+func newAuthLimiter() *authLimiter {
+	return &authLimiter{sources: make(map[string]*authAttempts), now: time.Now}
+}
+
+// lockedFor returns how long source remains locked out (0 if it is not)
+func (l *authLimiter) lockedFor(source string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts, ok := l.sources[source]
+	if !ok {
+		return 0
+	}
+	if remaining := attempts.lockedUntil.Sub(l.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// fail records a failed attempt from source and returns the lockout it
+// triggered (0 if none)
+func (l *authLimiter) fail(source string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.sources) >= maxTrackedSources {
+		l.prune(now)
+	}
+
+	attempts, ok := l.sources[source]
+	if !ok {
+		attempts = &authAttempts{}
+		l.sources[source] = attempts
+	}
+	if now.Sub(attempts.lastAt) > lockoutMemory {
+		attempts.lockouts = 0
+	}
+	if now.Sub(attempts.firstAt) > constants.AuthFailureWindowMinutes*time.Minute {
+		attempts.failures = 0
+		attempts.firstAt = now
+	}
+	attempts.failures++
+	attempts.lastAt = now
+
+	if attempts.failures < constants.AuthLockoutThreshold {
+		return 0
+	}
+
+	lockout := lockoutDuration(attempts.lockouts)
+	attempts.lockouts++
+	attempts.failures = 0
+	attempts.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// prune drops sources that are neither locked out nor remembered
+func (l *authLimiter) prune(now time.Time) {
+	for source, attempts := range l.sources {
+		if now.After(attempts.lockedUntil) && now.Sub(attempts.lastAt) > lockoutMemory {
+			delete(l.sources, source)
+		}
+	}
+}
+
+// lockoutDuration returns the length of a source's next lockout after
+// previous earlier ones: the base period doubled each time, capped
+func lockoutDuration(previous int) time.Duration {
+	lockout := constants.AuthLockoutBaseSeconds * time.Second
+	limit := constants.AuthLockoutMaxMinutes * time.Minute
+	for i := 0; i < previous && lockout < limit; i++ {
+		lockout *= 2
+	}
+	if lockout > limit {
+		lockout = limit
+	}
+	return lockout
+}
+
+// checkLockout rejects callers whose address is locked out of kind
+func (s *Server) checkLockout(c *fiber.Ctx, kind string) error {
+	return s.lockedOut(kind, c.IP())
+}
+
+// lockedOut rejects source if it is locked out of kind
+func (s *Server) lockedOut(kind, source string) error {
+	if remaining := s.lockout.lockedFor(kind + " " + source); remaining > 0 {
+		return apperr.TooManyRequests("Too many failed authentication attempts", remaining)
+	}
+	return nil
+}
+
+// authFailed records a failed authentication attempt with a credential of
+// kind in the audit log, locking the caller's address out of kind once it
+// failed too often, and returns err
+func (s *Server) authFailed(c *fiber.Ctx, kind, reason string, err *apperr.Error) error {
+	return s.failedFrom(c.IP(), kind, c.Method()+" "+routePath(c), reason, err)
+}
+
+// failedFrom is authFailed for a call to action from source
+func (s *Server) failedFrom(source, kind, action, reason string, err *apperr.Error) error {
+	lockout := s.lockout.fail(kind + " " + source)
+
+	s.recordAudit(database.AuditAuthFailure, source, action+": "+reason)
+	if lockout > 0 {
+		s.recordAudit(database.AuditAuthLockout, source, fmt.Sprintf("locked out of %s for %s", kind, lockout))
+	}
+	return err
+}
+
+// recordAudit appends to the audit log, logging failures rather than
+// failing the request
+func (s *Server) recordAudit(event, source, detail string) {
+	if s.db == nil {
+		return
+	}
+	entry := &database.AuditEntry{Event: event, Source: source, Detail: detail}
+	if err := s.db.RecordAudit(entry); err != nil {
+		log.Printf("Warning: failed to record audit event %s: %v", event, err)
+	}
+}
+
+// handleListAudit returns audit log entries, newest first, paginated
+func (s *Server) handleListAudit(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, constants.DefaultAuditLimit, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	total, err := db.CountAudit()
+	if err != nil {
+		return apperr.Database(err)
+	}
+	entries, err := db.ListAuditPage(page.Offset(), page.PerPage)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewListResponse("Audit log retrieved successfully", entries, api.NewPagination(page, total)))
+}
+
+// handleVerifyAudit checks the audit log's hash chain and its last anchor
+func (s *Server) handleVerifyAudit(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	result, err := db.VerifyAudit()
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Audit log verified", result))
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestAuthLimiter_EscalatesLockouts(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newAuthLimiter()
+	limiter.now = func() time.Time { return now }
+
+	base := constants.AuthLockoutBaseSeconds * time.Second
+	for lockout := 1; lockout <= 3; lockout++ {
+		for i := 1; i < constants.AuthLockoutThreshold; i++ {
+			if got := limiter.fail("10.0.0.9"); got != 0 {
+				t.Fatalf("Failure %d locked out for %s", i, got)
+			}
+		}
+		got := limiter.fail("10.0.0.9")
+		if want := base << (lockout - 1); got != want {
+			t.Fatalf("Lockout %d lasted %s, want %s", lockout, got, want)
+		}
+		if limiter.lockedFor("10.0.0.9") != got {
+			t.Errorf("Expected source locked for %s", got)
+		}
+		if limiter.lockedFor("10.0.0.10") != 0 {
+			t.Errorf("Other sources must not be locked out")
+		}
+		now = now.Add(got)
+	}
+
+	if got := lockoutDuration(100); got != constants.AuthLockoutMaxMinutes*time.Minute {
+		t.Errorf("Lockouts must be capped, got %s", got)
+	}
+
+	// A quiet day resets the escalation
+	now = now.Add(lockoutMemory + time.Minute)
+	for i := 1; i < constants.AuthLockoutThreshold; i++ {
+		limiter.fail("10.0.0.9")
+	}
+	if got := limiter.fail("10.0.0.9"); got != base {
+		t.Errorf("Lockout after a quiet day lasted %s, want %s", got, base)
+	}
+}
+
+func TestLockout_BlocksAfterFailedTokens(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	for i := 0; i < constants.AuthLockoutThreshold; i++ {
+		if resp, _ := laneRequest(t, server, http.MethodGet, "/status", "not-a-token", ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d returned %d, want 401", i+1, resp.StatusCode)
+		}
+	}
+
+	// Locked out: further guesses are refused without being checked
+	resp, _ := laneRequest(t, server, http.MethodGet, "/status", "another-guess", "")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once locked out, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected a Retry-After header")
+	}
+
+	// The public routes stay open to callers without credentials
+	if resp, _ := anonymousRequest(t, server, http.MethodGet, "/health", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /health to stay open, got %d", resp.StatusCode)
+	}
+
+	entries, err := server.db.ListAudit(100)
+	if err != nil {
+		t.Fatalf("ListAudit failed: %v", err)
+	}
+	var failures, lockouts int
+	for _, entry := range entries {
+		switch entry.Event {
+		case database.AuditAuthFailure:
+			failures++
+		case database.AuditAuthLockout:
+			lockouts++
+		}
+	}
+	if failures != constants.AuthLockoutThreshold || lockouts != 1 {
+		t.Errorf("Expected %d failures and 1 lockout audited, got %d and %d", constants.AuthLockoutThreshold, failures, lockouts)
+	}
+
+	verification, err := server.db.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit failed: %v", err)
+	}
+	if !verification.OK || verification.Entries != len(entries) {
+		t.Errorf("Expected an intact chain of %d entries, got %+v", len(entries), verification)
+	}
+}
+
+func TestLockout_SuccessKeepsFailures(t *testing.T) {
+	server := newTestServerWithDB(t)
+	secret := "s3cret-s3cret-s3cret-s3cret-s3cret"
+	server.config.APISecret = []byte(secret)
+
+	// Knowing the secret must not buy more guesses at it
+	for i := 1; i < constants.AuthLockoutThreshold; i++ {
+		laneRequest(t, server, http.MethodPost, "/auth/token", adminBearer(t, server), `{"secret":"wrong"}`)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/auth/token", adminBearer(t, server), `{"secret":"`+secret+`"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Correct secret returned %d, want 200", resp.StatusCode)
+	}
+	laneRequest(t, server, http.MethodPost, "/auth/token", adminBearer(t, server), `{"secret":"wrong"}`)
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/auth/token", adminBearer(t, server), `{"secret":"`+secret+`"}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after %d failures, got %d", constants.AuthLockoutThreshold, resp.StatusCode)
+	}
+
+	// Failures with one kind of credential leave the others usable
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/status", adminBearer(t, server), ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected tokens to keep working, got %d", resp.StatusCode)
+	}
+}
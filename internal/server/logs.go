@@ -0,0 +1,72 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"go.uber.org/zap/zapcore"
+)
+
+// handleGetLogs returns the end of the service log, decrypted, so support
+// can read it without remote desktop access. ?tail= sets how many entries
+// (default 200), ?level= the lowest level (default debug) and ?date= a
+// single day (2006-01-02, terminal local time).
+func (s *Server) handleGetLogs(c *fiber.Ctx) error {
+	if s.config.ReadLogs == nil {
+		return apperr.Unavailable("Logs are not available", time.Minute)
+	}
+
+	q, err := logQuery(c)
+	if err != nil {
+		return err
+	}
+	tail, err := s.config.ReadLogs(q)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(
+		api.CodeSuccess,
+		"Log entries retrieved",
+		tail,
+	))
+}
+
+// logQuery reads the GET /logs query parameters
+func logQuery(c *fiber.Ctx) (logging.Query, error) {
+	var fields []api.FieldError
+	q := logging.Query{Lines: constants.LogTailDefaultLines, Level: zapcore.DebugLevel}
+
+	if v := c.Query("tail"); v != "" {
+		lines, err := strconv.Atoi(v)
+		if err != nil || lines < 1 || lines > constants.LogTailMaxLines {
+			limit := strconv.Itoa(constants.LogTailMaxLines)
+			fields = append(fields, api.FieldError{Field: "tail", Rule: "range", Param: "1-" + limit, Message: "must be between 1 and " + limit})
+		}
+		q.Lines = lines
+	}
+	if v := c.Query("level"); v != "" {
+		level, err := zapcore.ParseLevel(v)
+		if err != nil || level > zapcore.ErrorLevel {
+			fields = append(fields, api.FieldError{Field: "level", Rule: "oneof", Param: "debug info warn error", Message: "must be debug, info, warn or error"})
+		}
+		q.Level = level
+	}
+	if v := c.Query("date"); v != "" {
+		date, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			fields = append(fields, api.FieldError{Field: "date", Rule: "date", Param: time.DateOnly, Message: "must be a date like 2026-01-31"})
+		}
+		q.Date = date
+	}
+
+	if len(fields) > 0 {
+		return logging.Query{}, apperr.Invalid(fields)
+	}
+	return q, nil
+}
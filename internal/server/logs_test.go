@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGetLogs_QueryAndAccess(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/logs", adminBearer(t, server), ""); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a log reader, got %d", resp.StatusCode)
+	}
+
+	var got logging.Query
+	server.config.ReadLogs = func(q logging.Query) (*logging.Tail, error) {
+		got = q
+		return &logging.Tail{Entries: []json.RawMessage{json.RawMessage(`{"level":"error","msg":"printer offline"}`)}}, nil
+	}
+
+	resp, data := laneRequest(t, server, http.MethodGet, "/logs?tail=50&level=warn&date=2026-10-15", adminBearer(t, server), "")
+	var tail logging.Tail
+	json.Unmarshal(data, &tail)
+	if resp.StatusCode != http.StatusOK || len(tail.Entries) != 1 {
+		t.Fatalf("Expected one entry (%d): %s", resp.StatusCode, data)
+	}
+	if got.Lines != 50 || got.Level != zapcore.WarnLevel || got.Date.Format(time.DateOnly) != "2026-10-15" {
+		t.Errorf("Unexpected query %+v", got)
+	}
+
+	// Defaults: the last 200 entries at any level and day
+	laneRequest(t, server, http.MethodGet, "/logs", adminBearer(t, server), "")
+	if got.Lines != 200 || got.Level != zapcore.DebugLevel || !got.Date.IsZero() {
+		t.Errorf("Unexpected default query %+v", got)
+	}
+
+	for _, query := range []string{"tail=0", "tail=5001", "level=fatal", "level=loud", "date=15.10.2026"} {
+		if resp, _ := laneRequest(t, server, http.MethodGet, "/logs?"+query, adminBearer(t, server), ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "c1", 0)
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/logs", cashier, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier reached /logs with %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/metrics"
+)
+
+// routeUnmatched labels requests that matched no route, so scans of
+// unknown paths cannot grow the latency series without bound
+const routeUnmatched = "unmatched"
+
+// registerMetrics adds the request latency, sync, offline and database
+// metrics to reg and installs the middleware timing requests
+func (s *Server) registerMetrics(reg *metrics.Registry) {
+	s.httpLatency = reg.NewHistogramVec("pos_http_request_duration_seconds",
+		"Local API request latency by method, route pattern and status.", nil,
+		"method", "route", "status")
+	s.app.Use(s.measureLatency)
+
+	reg.NewCounterVecFunc("pos_sync_runs_total", "Sync runs by result.", "result", func() map[string]float64 {
+		snap := s.syncStats.Snapshot()
+		return map[string]float64{"success": float64(snap.Succeeded), "failure": float64(snap.Failed)}
+	})
+	reg.NewCounterFunc("pos_sync_records_total", "Records transferred by successful sync runs.", func() float64 {
+		return float64(s.syncStats.Snapshot().Records)
+	})
+	reg.NewGaugeFunc("pos_sync_last_success_timestamp_seconds", "Unix time of the last successful sync (0 if none).", func() float64 {
+		return unixSeconds(s.syncStats.Snapshot().LastSuccess)
+	})
+	reg.NewGaugeFunc("pos_backend_last_contact_timestamp_seconds", "Unix time the backend last answered (0 if never).", func() float64 {
+		return unixSeconds(s.syncStats.Snapshot().LastContact)
+	})
+	reg.NewGaugeFunc("pos_offline_hours", "Hours since the last successful sync (0 if none).", func() float64 {
+		return s.offlineFor(time.Now()).Hours()
+	})
+
+	if s.db == nil {
+		return
+	}
+	reg.NewGaugeVecFunc("pos_outbox_pending", "Local changes waiting to sync, by outbox class.", "class", func() map[string]float64 {
+		counts, err := s.db.CountPendingOutboxByClass()
+		if err != nil {
+			return nil
+		}
+		pending := make(map[string]float64, len(counts))
+		for class, n := range counts {
+			pending[class] = float64(n)
+		}
+		return pending
+	})
+	reg.NewGaugeFunc("pos_db_size_bytes", "Size of the database file with its WAL and shared-memory files.", func() float64 {
+		return float64(s.db.SizeOnDisk())
+	})
+	reg.NewGaugeFunc("pos_db_read_only", "1 while the database is in read-only mode.", func() float64 {
+		if s.db.IsReadOnly() {
+			return 1
+		}
+		return 0
+	})
+	reg.NewCounterVecFunc("pos_db_busy_retries_total", "Database busy retries by outcome.", "outcome", func() map[string]float64 {
+		stats := s.db.RetryStats()
+		return map[string]float64{
+			"retried":   float64(stats.Retries),
+			"recovered": float64(stats.Recovered),
+			"exhausted": float64(stats.Exhausted),
+		}
+	})
+}
+
+// measureLatency records how long each request took, labelled by the
+// route pattern rather than the path so IDs do not split the series
+func (s *Server) measureLatency(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	// Errors become responses in the error handler, after this returns
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = apperr.From(err).Status
+	}
+	route := c.Route().Path
+	if route == "" || route == "/" {
+		route = routeUnmatched
+	}
+	s.httpLatency.Observe(time.Since(start).Seconds(), c.Method(), route, strconv.Itoa(status))
+	return err
+}
+
+// unixSeconds returns t as Unix seconds, 0 for the zero time
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
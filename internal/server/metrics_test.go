@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/metrics"
+	"github.com/professor93/promo-pos/internal/security"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+func TestMetrics_LatencyAndSync(t *testing.T) {
+	serverKey, _ := security.GenerateServerKey()
+	db, err := database.New(&database.Config{ServerKey: serverKey, InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	reg := metrics.NewRegistry()
+	cfg := DefaultConfig()
+	cfg.DB = db
+	cfg.Metrics = reg
+	server := New(cfg)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/health", nil),
+		httptest.NewRequest(http.MethodGet, "/no/such/path", nil),
+	} {
+		if _, err := send(server, asAdmin(t, server, req), -1); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+	server.recordSync(possync.ProgressReport{})
+
+	var out bytes.Buffer
+	if _, err := reg.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	scrape := out.String()
+	for _, want := range []string{
+		`pos_http_request_duration_seconds_count{method="GET",route="` + APIPrefix + `/health",status="200"} 1`,
+		`pos_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`pos_sync_runs_total{result="success"} 1`,
+		`pos_db_read_only 0`,
+		`pos_outbox_pending{class="`,
+		"# TYPE pos_offline_hours gauge",
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("Scrape is missing %q:\n%s", want, scrape)
+		}
+	}
+}
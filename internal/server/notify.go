@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/sales"
+)
+
+// commitSale commits a sale or refund through the ledger and announces it
+// on the event bus; retries of a committed sale are not announced again
+func (s *Server) commitSale(ctx context.Context, sale *database.Sale) (*sales.Result, error) {
+	result, err := s.ledger.CommitSale(ctx, sale)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Duplicate {
+		s.events.Publish(events.SaleCompleted, sale)
+		s.changes.add(ChangeSales)
+	}
+	return result, nil
+}
+
+// RunPromotionWatch publishes promotion.activated, and a promotions change
+// for /changes, each time a synced promotion starts running, checking every
+// interval until ctx is done.
+// Promotions already running when it starts are not announced.
+func (s *Server) RunPromotionWatch(ctx context.Context, interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	active, _ := s.activePromotions(time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			running, err := s.activePromotions(now)
+			if err != nil {
+				logging.Printf(ctx, "Warning: failed to check promotions: %v", err)
+				continue
+			}
+			for id, promo := range running {
+				if _, ok := active[id]; !ok {
+					s.events.Publish(events.PromotionActivated, promo)
+					s.changes.add(ChangePromotions)
+				}
+			}
+			active = running
+		}
+	}
+}
+
+// activePromotions returns the promotions running at now by ID
+func (s *Server) activePromotions(now time.Time) (map[string]database.Promotion, error) {
+	promotions, err := s.db.GetPromotions()
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[string]database.Promotion)
+	for _, promo := range promotions {
+		if promo.ActiveAt(now) {
+			active[promo.ID] = promo
+		}
+	}
+	return active, nil
+}
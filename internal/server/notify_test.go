@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/events"
+)
+
+func TestCommitSale_PublishesOnce(t *testing.T) {
+	server := newTestServerWithLedger(t)
+	stream, cancel := server.events.Subscribe(4)
+	defer cancel()
+
+	body := `{"id":"TX-9","lines":[{"sku":"MILK","quantity":1,"price":500}],"total":500,"tenders":[{"method":"cash","amount":500}]}`
+	for i := 0; i < 2; i++ {
+		if resp, result := cartRequest(t, server, http.MethodPost, "/transactions", body); resp.StatusCode != http.StatusOK {
+			t.Fatalf("Create returned %d: %s", resp.StatusCode, result)
+		}
+	}
+
+	event := <-stream
+	if sale, ok := event.Data.(*database.Sale); event.Type != events.SaleCompleted || !ok || sale.ID != "TX-9" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	select {
+	case event := <-stream:
+		t.Errorf("Expected the retried sale not to be announced, got %+v", event)
+	default:
+	}
+}
+
+func TestRunPromotionWatch_AnnouncesStartedPromotions(t *testing.T) {
+	server := newTestServerWithDB(t)
+	now := time.Now()
+	err := server.db.ReplacePromotions([]database.Promotion{
+		{ID: "RUNNING", Kind: database.PromotionPercent, Value: 1000, SKUs: []string{"MILK"}},
+		{ID: "SOON", Kind: database.PromotionAmount, Value: 50, SKUs: []string{"BREAD"}, ValidFrom: now.Add(500 * time.Millisecond).Format(time.RFC3339Nano)},
+	})
+	if err != nil {
+		t.Fatalf("ReplacePromotions failed: %v", err)
+	}
+
+	stream, cancel := server.events.Subscribe(4)
+	defer cancel()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go server.RunPromotionWatch(ctx, 20*time.Millisecond)
+
+	select {
+	case event := <-stream:
+		if promo, ok := event.Data.(database.Promotion); event.Type != events.PromotionActivated || !ok || promo.ID != "SOON" {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected promotion.activated")
+	}
+}
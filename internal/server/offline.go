@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/logging"
+)
+
+// Once the terminal has gone longer than MaxOffline without a successful
+// sync, the sync layer switches it to offline mode (see
+// sync.RunOfflineWatch and SetOfflineMode): prices and promotions may be
+// stale and unsynced sales pile up, so writes are refused until a sync gets
+// through. Mutating requests answer 503 offline here, and the database
+// refuses writes from anywhere else (see database.SetOffline). Reads keep
+// working (GraphQL queries are POSTs), and so do the routes that bring the
+// terminal back or keep its data safe: sync, sign-in, the config, the
+// service controls and backups. Only a real sync with head office ends it,
+// a bundle import or a backend run passed to recordSync; POST /sync alone
+// does not.
+
+// offlineWritableRoutes may be called with any method in offline mode
+var offlineWritableRoutes = regexp.MustCompile(`^/(sync|auth/[^/]+|config|service/[^/]+|graphql|backups(/[^/]+/restore)?)$`)
+
+// SetOfflineMode turns offline mode on or off. Changes are published as
+// offline.changed events.
+func (s *Server) SetOfflineMode(offline bool) {
+	if s.db != nil {
+		s.db.SetOffline(offline)
+	}
+	if s.offline.Swap(offline) == offline {
+		return
+	}
+	if offline {
+		logging.Printf(context.Background(), "Offline grace period exceeded: refusing writes until a sync succeeds")
+	} else {
+		logging.Printf(context.Background(), "Offline mode ended")
+	}
+	s.events.Publish(events.OfflineChanged, fiber.Map{"offline": offline})
+}
+
+// OfflineMode reports whether writes are refused for want of a sync
+func (s *Server) OfflineMode() bool {
+	return s.offline.Load()
+}
+
+// offlineMode reports whether writes are refused, for want of a sync or
+// because the database was put in read-only mode
+func (s *Server) offlineMode() bool {
+	return s.offline.Load() || (s.db != nil && s.db.IsReadOnly())
+}
+
+// blockOfflineWrites refuses mutating requests in offline mode
+func (s *Server) blockOfflineWrites(c *fiber.Ctx) error {
+	if !s.offline.Load() {
+		return c.Next()
+	}
+	switch c.Method() {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return c.Next()
+	}
+	if offlineWritableRoutes.MatchString(routePath(c)) {
+		return c.Next()
+	}
+	return apperr.Offline()
+}
+
+// lastSync returns when the last successful sync with head office
+// finished, zero if the terminal never synced. The database keeps it, so it
+// survives restarts and counts bundles imported by another process.
+func (s *Server) lastSync() time.Time {
+	if s.db == nil {
+		return time.Time{}
+	}
+	at, err := s.db.LastSync()
+	if err != nil {
+		logging.Printf(context.Background(), "Failed to read the last sync time: %v", err)
+	}
+	return at
+}
+
+// offlineFor returns how long the terminal has gone without a successful
+// sync as of now. A terminal that never synced has nothing to go stale
+// (/ready reports it as not bootstrapped), so that is 0 however long it
+// has been up.
+func (s *Server) offlineFor(now time.Time) time.Duration {
+	at := s.lastSync()
+	if at.IsZero() {
+		return 0
+	}
+	return max(now.Sub(at), 0)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/events"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+func TestOfflineMode_BlocksWrites(t *testing.T) {
+	server := newTestServerWithDB(t)
+	stream, cancel := server.events.Subscribe(4)
+	defer cancel()
+
+	// The last sync is older than the grace period
+	if err := server.db.RecordSync(time.Now().Add(-48 * time.Hour)); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go possync.RunOfflineWatch(ctx, server.db, time.Hour, 24*time.Hour, server.SetOfflineMode)
+	if event := <-stream; event.Type != events.OfflineChanged {
+		t.Errorf("Expected an offline.changed event, got %+v", event)
+	}
+	if !server.OfflineMode() || !server.db.IsOffline() {
+		t.Fatal("Expected the watch to put the terminal offline")
+	}
+
+	product := `{"barcode":"4600000000001","name":"Milk","price":500}`
+	resp, _ := laneRequest(t, server, http.MethodPost, "/products", adminBearer(t, server), product)
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(fiber.HeaderRetryAfter) != "60" {
+		t.Errorf("Write while offline: expected 503 with Retry-After, got %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/products", adminBearer(t, server), ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Read while offline: expected 200, got %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/graphql", adminBearer(t, server), `{"query":"{ products { barcode } }"}`); resp.StatusCode == http.StatusServiceUnavailable {
+		t.Error("GraphQL query refused while offline")
+	}
+
+	// POST /sync reaches no backend yet, so it does not end offline mode
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/sync", adminBearer(t, server), ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Sync while offline: expected 200, got %d", resp.StatusCode)
+	}
+	if !server.offlineMode() {
+		t.Fatal("POST /sync ended offline mode")
+	}
+
+	// A real sync with the backend does
+	server.recordSync(possync.ProgressReport{})
+	if server.OfflineMode() || server.db.IsOffline() {
+		t.Fatal("Expected a successful sync to end offline mode")
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/products", adminBearer(t, server), product); resp.StatusCode == http.StatusServiceUnavailable {
+		t.Errorf("Write after sync still refused")
+	}
+}
+
+func TestOfflineMode_SignIn(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/users", adminBearer(t, server), `{"id":"C-01","name":"Dana","pin":"4821"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Create user returned %d", resp.StatusCode)
+	}
+	server.SetOfflineMode(true)
+
+	// Staff can still sign in, to run the sync that ends offline mode
+	resp, result := laneRequest(t, server, http.MethodPost, "/auth/pin", laneBearer(t, server), `{"user_id":"C-01","pin":"4821"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PIN sign-in while offline returned %d", resp.StatusCode)
+	}
+	var session PINSession
+	json.Unmarshal(result, &session)
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/products", session.Token, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Token issued while offline: expected 200, got %d", resp.StatusCode)
+	}
+
+	// Failed attempts are still audited
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/products", "not-a-token", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a bad token, got %d", resp.StatusCode)
+	}
+	entries, err := server.db.ListAudit(10)
+	if err != nil || len(entries) == 0 || entries[0].Event != database.AuditAuthFailure {
+		t.Errorf("Expected the failed sign-in audited while offline, got %+v (%v)", entries, err)
+	}
+
+	// Other writes stay refused by the database itself
+	if err := server.db.SetSetting("key", "value"); !errors.Is(err, database.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly while offline, got %v", err)
+	}
+	if err := auth.Revoke(server.db, session.Token); err != nil {
+		t.Errorf("Revoke while offline failed: %v", err)
+	}
+}
+
+func TestOfflineMode_KeepsReadOnly(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.db.SetReadOnly(true)
+
+	// Ending offline mode leaves read-only mode set for another reason alone
+	server.SetOfflineMode(true)
+	server.recordSync(possync.ProgressReport{})
+	if server.OfflineMode() || !server.db.IsReadOnly() {
+		t.Fatalf("Expected only offline mode to end, got offline %v, read-only %v", server.OfflineMode(), server.db.IsReadOnly())
+	}
+
+	// Read-only mode refuses sign-ins too
+	if _, err := auth.Issue(server.db, auth.RoleStaff, "lane 1", time.Hour); !errors.Is(err, database.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly for a sign-in while read-only, got %v", err)
+	}
+}
+
+func TestOfflineMode_Error(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if _, err := adminToken(server); err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	server.db.SetReadOnly(true)
+
+	resp, envelope := draftRequest(t, server, http.MethodPut, "T1", `{"payload":{}}`)
+	if resp.StatusCode != http.StatusServiceUnavailable || envelope.Code != api.CodeErrorOffline || envelope.Message != api.MessageOfflineTooLong {
+		t.Errorf("Unexpected offline error: %d %+v", resp.StatusCode, envelope)
+	}
+}
+
+func TestOfflineFor(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.MaxOffline = time.Hour
+	now := time.Now()
+
+	// Uptime alone never counts as time offline
+	if offline := server.offlineFor(now.Add(48 * time.Hour)); offline != 0 {
+		t.Errorf("Expected no offline time before the first sync, got %v", offline)
+	}
+	if probe := server.healthChecks(now.Add(48 * time.Hour)).LastSync; probe.Status == api.ProbeFailed {
+		t.Errorf("Expected a terminal that never synced to pass, got %+v", probe)
+	}
+
+	if err := server.db.RecordSync(now.Add(-2 * time.Hour)); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+	if offline := server.offlineFor(now); offline < 2*time.Hour {
+		t.Errorf("Expected 2h offline, got %v", offline)
+	}
+	if probe := server.healthChecks(now).LastSync; probe.Status != api.ProbeFailed {
+		t.Errorf("Expected a sync older than max_offline to fail, got %+v", probe)
+	}
+}
+
+func TestServiceStatus_LastSync(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	if status := server.serviceStatus(); status.LastSyncTime != "" || status.OfflineHours != 0 {
+		t.Errorf("Expected no sync time before the first sync, got %+v", status)
+	}
+
+	at := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	if err := server.db.RecordSync(at); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+	status := server.serviceStatus()
+	if status.LastSyncTime != at.UTC().Format(time.RFC3339) {
+		t.Errorf("Expected the recorded sync time %s, got %q", at.UTC().Format(time.RFC3339), status.LastSyncTime)
+	}
+	if status.OfflineHours != 3 {
+		t.Errorf("Expected 3 hours offline, got %d", status.OfflineHours)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+)
+
+const (
+	// statsDateLayout is the date format of the from/to query parameters
+	statsDateLayout = "2006-01-02"
+
+	// defaultStatsDays is the period reported when no range is given
+	defaultStatsDays = 7
+)
+
+// handleGetOperatorStats returns an operator's performance rollup.
+// Query: from, to (YYYY-MM-DD, inclusive); defaults to the last 7 days.
+func (s *Server) handleGetOperatorStats(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	operatorID := c.Params("id")
+	if !terminalIDPattern.MatchString(operatorID) {
+		return apperr.BadRequest("Invalid operator ID")
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -(defaultStatsDays - 1))
+
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(statsDateLayout, v); err != nil {
+			return apperr.BadRequest("Invalid from date, expected YYYY-MM-DD")
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(statsDateLayout, v); err != nil {
+			return apperr.BadRequest("Invalid to date, expected YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return apperr.BadRequest("from must not be after to")
+	}
+
+	stats, err := db.GetOperatorStats(operatorID, from, to)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Operator statistics retrieved successfully", stats))
+}
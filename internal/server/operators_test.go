@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestGetOperatorStats(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	sales := []*database.Sale{
+		{ID: "s1", OperatorID: "op-1", Total: 1000, ScanSeconds: 30, VoidedLines: 1, CreatedAt: "2026-03-02T10:00:00Z",
+			Lines: []database.SaleLine{{SKU: "A", Quantity: 2, Price: 500}}},
+		{ID: "s2", OperatorID: "op-1", Total: 3000, ScanSeconds: 30, CreatedAt: "2026-03-02T11:00:00Z",
+			Lines: []database.SaleLine{{SKU: "B", Quantity: 3, Price: 1000}}},
+		{ID: "r1", Type: database.SaleTypeRefund, OperatorID: "op-1", Total: 500, CreatedAt: "2026-03-03T09:00:00Z",
+			Lines: []database.SaleLine{{SKU: "A", Quantity: 1, Price: 500}}},
+		{ID: "s3", OperatorID: "op-2", Total: 700, CreatedAt: "2026-03-02T12:00:00Z",
+			Lines: []database.SaleLine{{SKU: "C", Quantity: 1, Price: 700}}},
+	}
+	for _, sale := range sales {
+		err := server.db.TransactionContext(context.Background(), func(tx *sql.Tx) error {
+			_, err := server.db.ApplySale(tx, sale)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("ApplySale failed: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/operators/op-1/stats?from=2026-03-01&to=2026-03-31", nil)
+	resp, err := send(server, asAdmin(t, server, req))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var apiResp struct {
+		api.APIResponse
+		Result database.OperatorStats `json:"result"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	stats := apiResp.Result
+	if stats.SalesCount != 2 || stats.SalesValue != 4000 {
+		t.Errorf("Unexpected sales totals: %+v", stats)
+	}
+	if stats.AverageBasket != 2000 {
+		t.Errorf("Expected average basket 2000, got %v", stats.AverageBasket)
+	}
+	if stats.ScanRate != 5 { // 5 items in 60 seconds
+		t.Errorf("Expected scan rate 5/min, got %v", stats.ScanRate)
+	}
+	if stats.RefundRatio != 0.5 || stats.VoidRatio != 0.5 {
+		t.Errorf("Unexpected ratios: refund %v, void %v", stats.RefundRatio, stats.VoidRatio)
+	}
+}
+
+func TestGetOperatorStats_InvalidRange(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	req := httptest.NewRequest("GET", "/operators/op-1/stats?from=2026-03-10&to=2026-03-01", nil)
+	resp, err := send(server, asAdmin(t, server, req))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+)
+
+// pageRequest reads the requested page of a list endpoint: ?cursor= (from
+// a previous response's next_cursor) or ?page=, and ?per_page= (?limit= is
+// accepted too, as older clients sent it), between 1 and maxPerPage
+func pageRequest(c *fiber.Ctx, defaultPerPage, maxPerPage int) (api.PageRequest, error) {
+	var fields []api.FieldError
+	r := api.PageRequest{Page: 1, PerPage: defaultPerPage}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		page, err := api.DecodeCursor(cursor)
+		if err != nil {
+			fields = append(fields, api.FieldError{Field: "cursor", Rule: "cursor", Message: "is not a cursor from a previous page"})
+		}
+		r.Page = page
+	} else if v := c.Query("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			fields = append(fields, api.FieldError{Field: "page", Rule: "min", Param: "1", Message: "must be at least 1"})
+		}
+		r.Page = page
+	}
+
+	v := c.Query("per_page", c.Query("limit"))
+	if v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			fields = append(fields, api.FieldError{
+				Field:   "per_page",
+				Rule:    "range",
+				Param:   "1-" + strconv.Itoa(maxPerPage),
+				Message: "must be between 1 and " + strconv.Itoa(maxPerPage),
+			})
+		}
+		r.PerPage = perPage
+	}
+
+	if len(fields) > 0 {
+		return api.PageRequest{}, apperr.Invalid(fields)
+	}
+	return r, nil
+}
+
+// listPage answers page r of items
+func listPage[T any](c *fiber.Ctx, message string, items []T, r api.PageRequest) error {
+	page, meta := api.Paginate(items, r)
+	return c.JSON(api.NewListResponse(message, page, meta))
+}
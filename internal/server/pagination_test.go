@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// listRequest fetches one page of a list endpoint as terminal T1
+func listRequest(t *testing.T, server *Server, path string) (int, []database.SaleLine, api.Pagination) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set(HeaderTerminalID, "T1")
+
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result []database.SaleLine `json:"result"`
+		Meta   api.Pagination      `json:"meta"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &envelope)
+	return resp.StatusCode, envelope.Result, envelope.Meta
+}
+
+func TestPagination_FollowsCursors(t *testing.T) {
+	server := newTestServerWithLedger(t)
+	cartRequest(t, server, "POST", "/carts/order-1/lines", chunkBody(0, 5))
+
+	var lines []database.SaleLine
+	path := "/carts/order-1/lines?per_page=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatalf("Expected 3 pages, still paging")
+		}
+		status, page, meta := listRequest(t, server, path)
+		if status != http.StatusOK || meta.Total != 5 || meta.PerPage != 2 || meta.Page != pages+1 {
+			t.Fatalf("Unexpected page %d (%d): %+v", pages+1, status, meta)
+		}
+		lines = append(lines, page...)
+		path = ""
+		if meta.NextCursor != "" {
+			path = "/carts/order-1/lines?per_page=2&cursor=" + meta.NextCursor
+		}
+	}
+	if len(lines) != 5 {
+		t.Errorf("Expected all 5 lines across pages, got %d", len(lines))
+	}
+
+	if _, page, meta := listRequest(t, server, "/carts/order-1/lines?page=3&per_page=2"); len(page) != 1 || meta.NextCursor != "" {
+		t.Errorf("Expected a last page of 1 line, got %d (%+v)", len(page), meta)
+	}
+}
+
+func TestPagination_RejectsInvalidParameters(t *testing.T) {
+	server := newTestServerWithLedger(t)
+	cartRequest(t, server, "POST", "/carts/order-1/lines", chunkBody(0, 1))
+
+	for _, query := range []string{"page=0", "per_page=0", "per_page=201", "limit=abc", "cursor=bogus"} {
+		if status, _, _ := listRequest(t, server, "/carts/order-1/lines?"+query); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, status)
+		}
+	}
+}
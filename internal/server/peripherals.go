@@ -0,0 +1,29 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/peripheral"
+)
+
+// handleReportPeripheral records a peripheral's state as seen by the layer
+// driving it: {"kind": "printer", "online": false, "detail": "paper out"}.
+// Reports must be repeated; without one for a while the state turns unknown.
+func (s *Server) handleReportPeripheral(c *fiber.Ctx) error {
+	var body struct {
+		Kind   string `json:"kind" validate:"required"`
+		Online bool   `json:"online"`
+		Detail string `json:"detail"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+	if !peripheral.ValidKind(body.Kind) {
+		return apperr.BadRequest("kind must be printer, eft, scale or display")
+	}
+
+	s.peripherals.Report(c.Params("name"), body.Kind, body.Online, body.Detail)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeSuccess, "Peripheral status recorded", nil))
+}
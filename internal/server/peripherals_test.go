@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/auth"
+)
+
+func TestPeripherals_ReportedInStatus(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodPut, "/peripherals/receipt", cashier, `{"kind":"toaster"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown kind returned %d, want 400", resp.StatusCode)
+	}
+	body := `{"kind":"printer","online":false,"detail":"paper out"}`
+	if resp, _ := laneRequest(t, server, http.MethodPut, "/peripherals/receipt", cashier, body); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Report returned %d", resp.StatusCode)
+	}
+
+	resp, result := laneRequest(t, server, http.MethodGet, "/status", adminBearer(t, server), "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status returned %d", resp.StatusCode)
+	}
+	var status api.ServiceStatus
+	if err := json.Unmarshal(result, &status); err != nil {
+		t.Fatalf("Failed to parse status: %v", err)
+	}
+	if len(status.Peripherals) != 1 {
+		t.Fatalf("Expected one peripheral, got %v", status.Peripherals)
+	}
+	if p := status.Peripherals[0]; p.Name != "receipt" || p.State != "offline" || p.Detail != "paper out" || p.CheckedAt == "" {
+		t.Errorf("Unexpected peripheral %+v", p)
+	}
+	if !status.IsHealthy {
+		t.Errorf("An offline peripheral must not make the service unhealthy")
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// GET /price/:barcode is called for every scan at checkout. It reads only
+// in-memory indexes (the product index and the promotion index), so a
+// lookup never touches SQLite once they are loaded.
+
+// PriceLookup is the price of one item at the moment of the scan. Prices
+// include tax; Tax is the tax contained in Price.
+type PriceLookup struct {
+	Barcode    string               `json:"barcode"`
+	SKU        string               `json:"sku"`
+	Name       string               `json:"name"`
+	Active     bool                 `json:"active"`
+	Price      int64                `json:"price"`       // Regular price, minor currency units
+	TaxRate    int                  `json:"tax_rate"`    // Basis points
+	Tax        int64                `json:"tax"`         // Tax included in Price
+	PromoPrice int64                `json:"promo_price"` // Best price after active promotions (Price when none)
+	PromoTax   int64                `json:"promo_tax"`   // Tax included in PromoPrice
+	Promotions []database.Promotion `json:"promotions"`  // Promotions running now
+}
+
+// includedTax returns the tax contained in a tax-inclusive price, rounded
+// half up
+func includedTax(price int64, rate int) int64 {
+	if price <= 0 || rate <= 0 {
+		return 0
+	}
+	gross := int64(10000 + rate)
+	return (2*price*int64(rate) + gross) / (2 * gross)
+}
+
+// handleGetPrice returns the price, tax and active promotions of a barcode
+func (s *Server) handleGetPrice(c *fiber.Ctx) error {
+	lookup, err := s.lookupPrice(c.Params("barcode"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Price retrieved successfully", lookup))
+}
+
+// lookupPrice prices a barcode at the moment of the scan, for
+// GET /price/:barcode and the LookupPrice RPC
+func (s *Server) lookupPrice(barcode string) (*PriceLookup, error) {
+	db, err := s.requireDB()
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := db.GetProductByBarcode(barcode)
+	if err != nil {
+		if errors.Is(err, database.ErrProductNotFound) {
+			return nil, apperr.NotFound("Product not found")
+		}
+		return nil, apperr.Database(err)
+	}
+
+	// Promotions target SKUs; products without one are matched by barcode
+	key := product.SKU
+	if key == "" {
+		key = product.Barcode
+	}
+	promotions, err := db.ActivePromotions(key, time.Now())
+	if err != nil {
+		return nil, apperr.Database(err)
+	}
+	if promotions == nil {
+		promotions = []database.Promotion{}
+	}
+
+	lookup := &PriceLookup{
+		Barcode:    product.Barcode,
+		SKU:        product.SKU,
+		Name:       product.Name,
+		Active:     product.Active,
+		Price:      product.Price,
+		TaxRate:    product.TaxRate,
+		Tax:        includedTax(product.Price, product.TaxRate),
+		PromoPrice: product.Price,
+		Promotions: promotions,
+	}
+	for i := range promotions {
+		lookup.PromoPrice = min(lookup.PromoPrice, promotions[i].Apply(product.Price))
+	}
+	lookup.PromoTax = includedTax(lookup.PromoPrice, product.TaxRate)
+	return lookup, nil
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestPrice_Lookup(t *testing.T) {
+	server := newTestServerWithDB(t)
+	product := &database.Product{ID: "P1", Barcode: "4780001", SKU: "MILK", Name: "Milk", Price: 1120, TaxRate: 1200, Active: true}
+	if err := server.db.UpsertProduct(product, database.ProductSourceSync); err != nil {
+		t.Fatalf("Failed to seed product: %v", err)
+	}
+	if err := server.db.ReplacePromotions([]database.Promotion{
+		{ID: "PR1", Kind: database.PromotionPercent, Value: 1000, SKUs: []string{"MILK"}},
+		{ID: "PR2", Kind: database.PromotionAmount, Value: 20, SKUs: []string{"MILK"}},
+		{ID: "PR3", Kind: database.PromotionPrice, Value: 1, SKUs: []string{"MILK"}, ValidFrom: "2999-01-01T00:00:00Z"},
+	}); err != nil {
+		t.Fatalf("Failed to seed promotions: %v", err)
+	}
+
+	resp, out := productRequest(t, server, http.MethodGet, "/price/4780001", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Lookup returned %d: %+v", resp.StatusCode, out)
+	}
+	result := out.Result.(map[string]interface{})
+	// 1120 includes 12% tax: 1120 * 1200 / 11200 = 120
+	if result["price"].(float64) != 1120 || result["tax"].(float64) != 120 {
+		t.Errorf("Unexpected price and tax: %+v", result)
+	}
+	// 10% off beats 20 off; the future promotion does not run yet
+	if result["promo_price"].(float64) != 1008 || result["promo_tax"].(float64) != 108 {
+		t.Errorf("Unexpected promotional price and tax: %+v", result)
+	}
+	if promos := result["promotions"].([]interface{}); len(promos) != 2 {
+		t.Errorf("Expected 2 active promotions, got %d", len(promos))
+	}
+
+	if resp, _ := productRequest(t, server, http.MethodGet, "/price/missing", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown barcode returned %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Privacy mode masks customer names, amounts and loyalty data in API
+// responses for a limited time, so staff can share their screen with
+// support. It lives in a TTL setting, so it survives a restart and ends on
+// its own.
+
+// HeaderPrivacyMode is set on responses while privacy mode is on
+const HeaderPrivacyMode = "X-Privacy-Mode"
+
+// privacySettingKey holds the end of the current privacy window
+const privacySettingKey = "privacy.until"
+
+// privateFields are the JSON keys masked in privacy mode, wherever they
+// appear in a response
+var privateFields = map[string]bool{
+	// Amounts
+	"total":        true,
+	"subtotal":     true,
+	"amount":       true,
+	"sales_value":  true,
+	"refund_value": true,
+	"tendered":     true,
+	"change":       true,
+	// Customers
+	"customer":      true,
+	"customer_name": true,
+	"email":         true,
+	"phone":         true,
+	// Loyalty
+	"loyalty":        true,
+	"loyalty_card":   true,
+	"loyalty_points": true,
+	"synced_points":  true,
+	"pending_points": true,
+	"points":         true,
+}
+
+// privacyExempt are routes whose output must stay exact: printed receipts
+var privacyExempt = []routeRule{
+	{fiber.MethodGet, regexp.MustCompile(`^/sales/[^/]+/receipt$`)},
+}
+
+// maskedValue replaces private strings and objects
+const maskedValue = "***"
+
+// PrivacyStatus reports whether privacy mode is on and until when
+type PrivacyStatus struct {
+	Enabled bool   `json:"enabled"`
+	Until   string `json:"until,omitempty"` // ISO 8601 timestamp
+}
+
+// privacyUntil returns the end of the privacy window, or the zero time
+func (s *Server) privacyUntil() time.Time {
+	if until := s.privacy.Load(); until != nil && time.Now().Before(*until) {
+		return *until
+	}
+	return time.Time{}
+}
+
+// loadPrivacy restores a privacy window that outlived a restart
+func (s *Server) loadPrivacy() {
+	if s.db == nil {
+		return
+	}
+	value, err := s.db.GetSetting(privacySettingKey)
+	if err != nil {
+		return
+	}
+	if until, err := time.Parse(time.RFC3339, value); err == nil {
+		s.privacy.Store(&until)
+	}
+}
+
+// maskPrivate masks private fields in JSON responses while privacy mode is on
+func (s *Server) maskPrivate(c *fiber.Ctx) error {
+	if err := c.Next(); err != nil {
+		return err
+	}
+	if s.privacyUntil().IsZero() || allowed(privacyExempt, c.Method(), routePath(c)) {
+		return nil
+	}
+
+	c.Set(HeaderPrivacyMode, "on")
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return nil
+	}
+
+	// Keep numbers as written so large IDs survive the round trip
+	decoder := json.NewDecoder(bytes.NewReader(c.Response().Body()))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil
+	}
+	masked, err := json.Marshal(maskFields(body))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	c.Response().SetBodyRaw(masked)
+	return nil
+}
+
+// maskFields walks a decoded JSON value, masking private fields
+func maskFields(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if privateFields[key] {
+				v[key] = maskValue(value)
+			} else {
+				v[key] = maskFields(value)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = maskFields(v[i])
+		}
+	}
+	return v
+}
+
+// maskValue masks one private value. Numbers become null rather than a
+// string or zero, so clients neither break on the type nor show a fake amount.
+func maskValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, json.Number, bool:
+		return nil
+	default:
+		return maskedValue
+	}
+}
+
+// handleGetPrivacy reports the privacy mode
+func (s *Server) handleGetPrivacy(c *fiber.Ctx) error {
+	status := PrivacyStatus{}
+	if until := s.privacyUntil(); !until.IsZero() {
+		status = PrivacyStatus{Enabled: true, Until: until.UTC().Format(time.RFC3339)}
+	}
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Privacy mode retrieved", status))
+}
+
+// handleEnablePrivacy turns privacy mode on for {"minutes": n} (default 30)
+func (s *Server) handleEnablePrivacy(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Minutes int `json:"minutes"`
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return apperr.BadRequest("Invalid request body")
+		}
+	}
+	if body.Minutes == 0 {
+		body.Minutes = constants.DefaultPrivacyMinutes
+	}
+	if body.Minutes < 1 || body.Minutes > constants.MaxPrivacyMinutes {
+		return apperr.BadRequest(fmt.Sprintf("minutes must be between 1 and %d", constants.MaxPrivacyMinutes))
+	}
+
+	ttl := time.Duration(body.Minutes) * time.Minute
+	until := time.Now().Add(ttl).Truncate(time.Second)
+	if err := db.SetSettingWithTTL(privacySettingKey, until.UTC().Format(time.RFC3339), ttl); err != nil {
+		return apperr.Database(err)
+	}
+	s.privacy.Store(&until)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Privacy mode enabled", PrivacyStatus{
+		Enabled: true,
+		Until:   until.UTC().Format(time.RFC3339),
+	}))
+}
+
+// handleDisablePrivacy turns privacy mode off early
+func (s *Server) handleDisablePrivacy(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteSetting(privacySettingKey); err != nil && !errors.Is(err, database.ErrSettingNotFound) {
+		return apperr.Database(err)
+	}
+	s.privacy.Store(nil)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "Privacy mode disabled", PrivacyStatus{}))
+}
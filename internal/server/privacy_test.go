@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+)
+
+func TestPrivacyMode_MasksAmounts(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	body := `{"lines":[{"sku":"MILK","quantity":2,"price":990}]}`
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/carts/C1/lines", adminBearer(t, server), body); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Append returned %d", resp.StatusCode)
+	}
+
+	totals := func() map[string]interface{} {
+		resp, result := laneRequest(t, server, http.MethodGet, "/carts/C1/totals", adminBearer(t, server), "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Totals returned %d", resp.StatusCode)
+		}
+		var got map[string]interface{}
+		json.Unmarshal(result, &got)
+		return got
+	}
+
+	if got := totals(); got["total"] != float64(1980) {
+		t.Fatalf("Expected the real total before privacy mode, got %v", got["total"])
+	}
+
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/privacy", cashier, `{"minutes":10}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier enabled privacy mode with %d, want 403", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/privacy", adminBearer(t, server), `{"minutes":999}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Overlong window returned %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/privacy", adminBearer(t, server), `{"minutes":10}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Enable returned %d", resp.StatusCode)
+	}
+
+	got := totals()
+	if _, present := got["total"]; !present || got["total"] != nil {
+		t.Errorf("Expected total to be masked, got %v", got["total"])
+	}
+	if got["quantity"] != float64(2) {
+		t.Errorf("Expected quantity to stay visible, got %v", got["quantity"])
+	}
+
+	// A restart keeps the window
+	restarted := &Server{db: server.db}
+	restarted.loadPrivacy()
+	if restarted.privacyUntil().IsZero() {
+		t.Error("Expected privacy mode to survive a restart")
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodDelete, "/privacy", adminBearer(t, server), ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Disable returned %d", resp.StatusCode)
+	}
+	if got := totals(); got["total"] != float64(1980) {
+		t.Errorf("Expected the real total after privacy mode, got %v", got["total"])
+	}
+}
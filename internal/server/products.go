@@ -0,0 +1,163 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// /products manages the local catalog. Every write is a local edit, so it
+// reaches the sync outbox through the products table's CDC triggers.
+// Products are never deleted locally: head office owns the catalog, and
+// DELETE only deactivates the product.
+
+// ProductUpdate is the body of PUT /products/:barcode
+type ProductUpdate struct {
+	SKU     string `json:"sku,omitempty"`
+	Name    string `json:"name" validate:"required"`
+	Price   int64  `json:"price" validate:"gte=0"`              // Minor currency units
+	TaxRate int    `json:"tax_rate" validate:"gte=0,lte=10000"` // Basis points (10000 = 100%)
+	Active  *bool  `json:"active,omitempty"`                    // Unchanged when omitted
+	Version int64  `json:"version" validate:"required"`         // The version the edit is based on
+}
+
+// handleListProducts lists products by barcode, paginated; ?q= matches the
+// name, barcode or SKU and ?active= filters on the active flag
+func (s *Server) handleListProducts(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	filter := database.ProductFilter{Query: c.Query("q")}
+	if v := c.Query("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return apperr.Invalid([]api.FieldError{{Field: "active", Rule: "boolean", Message: "must be true or false"}})
+		}
+		filter.Active = &active
+	}
+
+	products, err := db.FindProducts(filter)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Products retrieved successfully", products, page)
+}
+
+// handleCreateProduct adds a product to the catalog (idempotent on the
+// Idempotency-Key header)
+func (s *Server) handleCreateProduct(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var in ProductData
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+
+	if _, err := db.GetProductByBarcode(in.Barcode); err == nil {
+		return apperr.Conflict("A product with this barcode already exists")
+	} else if !errors.Is(err, database.ErrProductNotFound) {
+		return apperr.Database(err)
+	}
+
+	product := &database.Product{
+		ID:        in.ID,
+		Barcode:   in.Barcode,
+		SKU:       in.SKU,
+		Name:      in.Name,
+		Price:     in.Price,
+		TaxRate:   in.TaxRate,
+		Active:    in.Active == nil || *in.Active,
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if product.ID == "" {
+		product.ID = ids.New()
+	}
+	if err := db.UpsertProduct(product, database.ProductSourceLocal); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Product created successfully", product))
+}
+
+// handleUpdateProduct edits a product. The body carries the version it was
+// based on; a concurrent edit makes it fail with 409.
+func (s *Server) handleUpdateProduct(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var in ProductUpdate
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+
+	product, err := db.GetProductByBarcode(c.Params("barcode"))
+	if err != nil {
+		if errors.Is(err, database.ErrProductNotFound) {
+			return apperr.NotFound("Product not found")
+		}
+		return apperr.Database(err)
+	}
+
+	product.SKU = in.SKU
+	product.Name = in.Name
+	product.Price = in.Price
+	product.TaxRate = in.TaxRate
+	if in.Active != nil {
+		product.Active = *in.Active
+	}
+	product.Version = in.Version
+
+	return s.saveProduct(c, db, product, "Product updated successfully")
+}
+
+// handleDeactivateProduct takes a product off sale, keeping its record
+func (s *Server) handleDeactivateProduct(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	product, err := db.GetProductByBarcode(c.Params("barcode"))
+	if err != nil {
+		if errors.Is(err, database.ErrProductNotFound) {
+			return apperr.NotFound("Product not found")
+		}
+		return apperr.Database(err)
+	}
+	if !product.Active {
+		return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Product already inactive", product))
+	}
+
+	product.Active = false
+	return s.saveProduct(c, db, product, "Product deactivated successfully")
+}
+
+// saveProduct stores an edited product with optimistic locking
+func (s *Server) saveProduct(c *fiber.Ctx, db *database.DB, product *database.Product, message string) error {
+	if err := db.UpdateProduct(product); err != nil {
+		if errors.Is(err, database.ErrProductNotFound) {
+			return apperr.NotFound("Product not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, message, product))
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func productRequest(t *testing.T, server *Server, method, path, body string) (*http.Response, api.APIResponse) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out api.APIResponse
+	raw, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("Failed to parse response %s: %v", raw, err)
+	}
+	return resp, out
+}
+
+// decodeProduct re-decodes a response's data as a product
+func decodeProduct(t *testing.T, data interface{}) database.Product {
+	raw, _ := json.Marshal(data)
+	var p database.Product
+	if err := json.Unmarshal(raw, &p); err != nil {
+		t.Fatalf("Failed to decode product: %v", err)
+	}
+	return p
+}
+
+func TestProducts_CRUD(t *testing.T) {
+	server := newTestServerWithDB(t)
+	before, _ := server.db.CountPendingOutbox()
+
+	resp, out := productRequest(t, server, http.MethodPost, "/products", `{"barcode":"4780001","sku":"MILK-1L","name":"Milk","price":990,"tax_rate":1200}`)
+	if resp.StatusCode != http.StatusOK || out.Code != api.CodeDataCreated {
+		t.Fatalf("Create returned %d: %+v", resp.StatusCode, out)
+	}
+	created := decodeProduct(t, out.Result)
+	if created.ID == "" || !created.Active {
+		t.Errorf("Expected an assigned ID and an active product, got %+v", created)
+	}
+	if after, _ := server.db.CountPendingOutbox(); after != before+1 {
+		t.Errorf("Expected the create in the outbox, pending %d -> %d", before, after)
+	}
+
+	if resp, _ := productRequest(t, server, http.MethodPost, "/products", `{"barcode":"4780001","name":"Other"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Duplicate barcode returned %d, want 409", resp.StatusCode)
+	}
+
+	// Update based on the current version, then replay the stale one
+	body := `{"name":"Whole milk","price":1090,"tax_rate":1200,"version":` + strconv.FormatInt(created.Version, 10) + `}`
+	resp, out = productRequest(t, server, http.MethodPut, "/products/4780001", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Update returned %d: %+v", resp.StatusCode, out)
+	}
+	if updated := decodeProduct(t, out.Result); updated.Name != "Whole milk" || updated.Version != created.Version+1 {
+		t.Errorf("Unexpected updated product %+v", updated)
+	}
+	if resp, _ := productRequest(t, server, http.MethodPut, "/products/4780001", body); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Stale update returned %d, want 409", resp.StatusCode)
+	}
+	if resp, _ := productRequest(t, server, http.MethodPut, "/products/missing", body); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Update of a missing product returned %d, want 404", resp.StatusCode)
+	}
+
+	resp, out = productRequest(t, server, http.MethodDelete, "/products/4780001", "")
+	if resp.StatusCode != http.StatusOK || decodeProduct(t, out.Result).Active {
+		t.Errorf("Deactivate returned %d: %+v", resp.StatusCode, out)
+	}
+	if after, _ := server.db.CountPendingOutbox(); after != before+3 {
+		t.Errorf("Expected every write in the outbox, pending %d -> %d", before, after)
+	}
+}
+
+func TestProducts_List(t *testing.T) {
+	server := newTestServerWithDB(t)
+	for _, p := range []database.Product{
+		{ID: "P1", Barcode: "111", SKU: "MILK", Name: "Milk", Active: true},
+		{ID: "P2", Barcode: "222", SKU: "BREAD", Name: "Bread", Active: true},
+		{ID: "P3", Barcode: "333", SKU: "OLD-MILK", Name: "Goat milk", Active: false},
+	} {
+		if err := server.db.UpsertProduct(&p, database.ProductSourceSync); err != nil {
+			t.Fatalf("Failed to seed product: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"111", "222", "333"}},
+		{"?q=milk", []string{"111", "333"}},
+		{"?q=milk&active=true", []string{"111"}},
+		{"?per_page=1&page=2", []string{"222"}},
+	}
+	for _, tt := range tests {
+		resp, out := productRequest(t, server, http.MethodGet, "/products"+tt.query, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%q returned %d", tt.query, resp.StatusCode)
+		}
+		var got []string
+		for _, item := range out.Result.([]interface{}) {
+			got = append(got, item.(map[string]interface{})["barcode"].(string))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q listed %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if resp, _ := productRequest(t, server, http.MethodGet, "/products?active=maybe", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid active filter returned %d, want 400", resp.StatusCode)
+	}
+}
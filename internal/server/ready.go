@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/logging"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+// Liveness and readiness are split so supervisors and the POS frontend can
+// tell "starting up" from "broken": /live answers as long as the process
+// serves requests, /ready once the terminal can take sales.
+
+// bootstrapSettingKey holds when the terminal's first successful sync,
+// which pulls the full catalog, finished
+const bootstrapSettingKey = "sync.bootstrapped_at"
+
+// readyRetryAfter is how soon callers should ask /ready again
+const readyRetryAfter = 5 * time.Second
+
+// Readiness reports what /ready waits for
+type Readiness struct {
+	Ready          bool   `json:"ready"`
+	DatabaseOpen   bool   `json:"database_open"`
+	ConfigLoaded   bool   `json:"config_loaded"`
+	Bootstrapped   bool   `json:"bootstrapped"`
+	BootstrappedAt string `json:"bootstrapped_at,omitempty"` // ISO 8601 timestamp
+}
+
+// loadBootstrap restores the first sync's time recorded by an earlier run,
+// or takes the last sync's when the first one came from a bundle import
+func (s *Server) loadBootstrap() {
+	if s.db == nil {
+		return
+	}
+	if at, err := s.db.GetSettingTime(bootstrapSettingKey); err == nil {
+		s.bootstrapped.Store(&at)
+	} else if at := s.lastSync(); !at.IsZero() {
+		s.bootstrapped.Store(&at)
+	}
+}
+
+// recordSync adds a finished run with the backend to the stats. A
+// successful one resets the offline clock, and the sync layer's check then
+// ends offline mode; the first marks the terminal bootstrapped.
+func (s *Server) recordSync(report possync.ProgressReport) {
+	s.syncStats.Record(report)
+	if report.Error != "" {
+		return
+	}
+
+	at := time.Now().UTC()
+	if s.db != nil {
+		if err := s.db.RecordSync(at); err != nil {
+			logging.Printf(context.Background(), "Failed to record the sync: %v", err)
+		}
+		if offline, _, err := possync.CheckOffline(s.db, at, s.config.MaxOffline); err == nil {
+			s.SetOfflineMode(offline)
+		}
+	}
+	if s.bootstrapped.Load() != nil {
+		return
+	}
+	s.bootstrapped.Store(&at)
+	if s.db == nil {
+		return
+	}
+	// Ready for this run even if unrecorded; the next run waits for a sync
+	if err := s.db.SetSettingTime(bootstrapSettingKey, at); err != nil {
+		logging.Printf(context.Background(), "Failed to record the bootstrap sync: %v", err)
+	}
+}
+
+// readiness checks the database, the config and the bootstrap sync
+func (s *Server) readiness() Readiness {
+	// A bundle imported by another process may have bootstrapped it since
+	if s.bootstrapped.Load() == nil {
+		s.loadBootstrap()
+	}
+	r := Readiness{
+		DatabaseOpen: s.db != nil && s.db.Ping() == nil,
+		ConfigLoaded: s.config.ConfigLoaded == nil || s.config.ConfigLoaded(),
+	}
+	if at := s.bootstrapped.Load(); at != nil {
+		r.Bootstrapped = true
+		r.BootstrappedAt = at.Format(time.RFC3339)
+	}
+	r.Ready = r.DatabaseOpen && r.ConfigLoaded && r.Bootstrapped
+	return r
+}
+
+// handleLive answers as long as the process serves requests
+func (s *Server) handleLive(c *fiber.Ctx) error {
+	return c.JSON(api.NewSuccessResponse(
+		api.CodeSuccess,
+		"Service is alive",
+		map[string]bool{"alive": true},
+	))
+}
+
+// handleReady answers 200 once the database is open, the config loaded and
+// the bootstrap sync done, else a retryable 503 naming what is missing
+func (s *Server) handleReady(c *fiber.Ctx) error {
+	r := s.readiness()
+	if !r.Ready {
+		var waiting []string
+		if !r.DatabaseOpen {
+			waiting = append(waiting, "the database")
+		}
+		if !r.ConfigLoaded {
+			waiting = append(waiting, "the configuration")
+		}
+		if !r.Bootstrapped {
+			waiting = append(waiting, "the bootstrap sync")
+		}
+		return apperr.Unavailable("Service is not ready: waiting for "+strings.Join(waiting, ", "), readyRetryAfter)
+	}
+
+	return c.JSON(api.NewSuccessResponse(
+		api.CodeSuccess,
+		"Service is ready",
+		r,
+	))
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReady_WaitsForBootstrapSync(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/live", adminBearer(t, server), ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("/live returned %d, want 200", resp.StatusCode)
+	}
+	resp, _ := laneRequest(t, server, http.MethodGet, "/ready", adminBearer(t, server), "")
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Errorf("/ready before the first sync returned %d, want a retryable 503", resp.StatusCode)
+	}
+
+	// POST /sync does not reach head office yet, so it bootstraps nothing
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/sync", adminBearer(t, server), ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Sync returned %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/ready", adminBearer(t, server), ""); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("/ready after a sync that reached nothing returned %d, want 503", resp.StatusCode)
+	}
+
+	// A bundle import bootstraps it, even from another process
+	if err := server.db.RecordSync(time.Now()); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+	resp, result := laneRequest(t, server, http.MethodGet, "/ready", adminBearer(t, server), "")
+	var ready Readiness
+	json.Unmarshal(result, &ready)
+	if resp.StatusCode != http.StatusOK || !ready.Ready || ready.BootstrappedAt == "" {
+		t.Errorf("/ready after the first sync returned %d: %+v", resp.StatusCode, ready)
+	}
+
+	// The bootstrap outlives a restart; an unloaded config does not pass
+	cfg := *server.config
+	cfg.ConfigLoaded = func() bool { return false }
+	restarted := New(&cfg)
+	if at := restarted.bootstrapped.Load(); at == nil {
+		t.Error("Expected the bootstrap sync restored after a restart")
+	}
+	if resp, _ := laneRequest(t, restarted, http.MethodGet, "/ready", adminBearer(t, restarted), ""); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("/ready without a config returned %d, want 503", resp.StatusCode)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/report"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// handleListReports lists the local report library (?kind), paginated
+func (s *Server) handleListReports(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && !report.ValidKind(kind) {
+		return apperr.BadRequest("Unknown report kind: " + kind)
+	}
+
+	reports, err := db.ListReports(kind)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Reports retrieved successfully", reports, page)
+}
+
+// handleGetReport downloads a rendered report
+func (s *Server) handleGetReport(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	file, content, err := db.GetReport(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrReportNotFound) {
+			return apperr.NotFound("Report not found")
+		}
+		return apperr.Database(err)
+	}
+
+	c.Set(fiber.HeaderContentType, report.ContentType(file.Format))
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+file.ID+`"`)
+	return c.Send(content)
+}
+
+// handleGenerateReport renders a report on demand:
+// {"kind": "...", "day": "YYYY-MM-DD" (default today), "format": "pdf"|"csv" (default pdf)}
+func (s *Server) handleGenerateReport(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Kind   string `json:"kind"`
+		Day    string `json:"day"`
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return apperr.BadRequest("Invalid request body")
+	}
+	if !report.ValidKind(body.Kind) {
+		return apperr.BadRequest("kind must be daily_sales, promo_uptake or refund_summary")
+	}
+	if body.Format == "" {
+		body.Format = constants.ReportFormatPDF
+	}
+	if !report.ValidFormat(body.Format) {
+		return apperr.BadRequest("format must be pdf or csv")
+	}
+
+	day := time.Now()
+	if body.Day != "" {
+		if day, err = time.ParseInLocation(report.DayFormat, body.Day, time.Local); err != nil {
+			return apperr.BadRequest("day must be YYYY-MM-DD")
+		}
+	}
+
+	file, _, err := report.Save(db, body.Kind, body.Format, day)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Report generated successfully", file))
+}
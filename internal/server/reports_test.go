@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestReports_GenerateListDownload(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/reports", adminBearer(t, server), `{"kind":"bogus"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown kind returned %d, want 400", resp.StatusCode)
+	}
+
+	resp, result := laneRequest(t, server, http.MethodPost, "/reports", adminBearer(t, server), `{"kind":"daily_sales","day":"2026-03-01","format":"csv"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Generate returned %d", resp.StatusCode)
+	}
+	var file database.ReportFile
+	json.Unmarshal(result, &file)
+	if file.ID != "2026-03-01_daily_sales.csv" || file.Size == 0 {
+		t.Fatalf("Unexpected report %+v", file)
+	}
+
+	resp, result = laneRequest(t, server, http.MethodGet, "/reports?kind=daily_sales", adminBearer(t, server), "")
+	var reports []database.ReportFile
+	json.Unmarshal(result, &reports)
+	if resp.StatusCode != http.StatusOK || len(reports) != 1 {
+		t.Fatalf("List returned %d with %v", resp.StatusCode, reports)
+	}
+
+	download, err := send(server, asAdmin(t, server, httptest.NewRequest(http.MethodGet, "/reports/"+file.ID, nil)), -1)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer download.Body.Close()
+	content, _ := io.ReadAll(download.Body)
+	if download.StatusCode != http.StatusOK || !strings.HasPrefix(download.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("Download returned %d (%s)", download.StatusCode, download.Header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(string(content), "Terminal,Sales,Items") {
+		t.Errorf("Unexpected report content %q", content)
+	}
+
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/reports", cashier, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier listed reports with %d, want 403", resp.StatusCode)
+	}
+}
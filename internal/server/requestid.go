@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// HeaderRequestID carries the ID correlating a request's log lines. A
+// well-formed ID sent by the client is kept, so a frontend can trace its
+// own retries; otherwise one is generated.
+const HeaderRequestID = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestID assigns the request its ID: in the response header, in the
+// Meta of API responses and in the context handlers pass down
+func requestID(c *fiber.Ctx) error {
+	id := c.Get(HeaderRequestID)
+	if !requestIDPattern.MatchString(id) {
+		id = ids.New()
+	}
+	c.Set(HeaderRequestID, id)
+	c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+
+	if err := c.Next(); err != nil {
+		// Render the error now, so its response carries the ID too
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			return err
+		}
+	}
+
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return nil
+	}
+	if body, ok := withRequestID(c.Response().Body(), id); ok {
+		c.Response().SetBodyRaw(body)
+	}
+	return nil
+}
+
+// withRequestID adds request_id to the Meta of an API response body.
+// Other bodies (receipts, reports, non-envelope JSON) are left alone.
+func withRequestID(body []byte, id string) ([]byte, bool) {
+	// Decode the envelope only; result is copied through as written
+	var envelope struct {
+		OK      *bool           `json:"ok"`
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		Meta    json.RawMessage `json:"meta,omitempty"`
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+	if err := json.Unmarshal(trimmed, &envelope); err != nil || envelope.OK == nil {
+		return nil, false
+	}
+
+	field := `"request_id":` + strconv.Quote(id)
+	switch meta := bytes.TrimSpace(envelope.Meta); {
+	case len(meta) == 0 || bytes.Equal(meta, []byte("null")):
+		envelope.Meta = json.RawMessage("{" + field + "}")
+	case meta[0] == '{':
+		inner := bytes.TrimSpace(meta[1 : len(meta)-1])
+		if len(inner) == 0 {
+			envelope.Meta = json.RawMessage("{" + field + "}")
+		} else {
+			envelope.Meta = json.RawMessage("{" + string(inner) + "," + field + "}")
+		}
+	default:
+		// Meta that is not an object has no room for the ID
+		return nil, false
+	}
+
+	out, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
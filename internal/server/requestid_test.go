@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func requestIDResponse(t *testing.T, server *Server, method, path, requestID, body string) (*http.Response, map[string]json.RawMessage) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		req.Header.Set(HeaderRequestID, requestID)
+	}
+
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Meta map[string]json.RawMessage `json:"meta"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp, envelope.Meta
+}
+
+func TestRequestID(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	// Generated when absent, echoed in the header and in Meta
+	resp, meta := requestIDResponse(t, server, "GET", "/health", "", "")
+	id := resp.Header.Get(HeaderRequestID)
+	if id == "" || string(meta["request_id"]) != `"`+id+`"` {
+		t.Fatalf("Expected a generated request ID in header and meta, got %q / %s", id, meta["request_id"])
+	}
+
+	// A client ID is kept, and joins the existing Meta of errors
+	resp, meta = requestIDResponse(t, server, "PUT", "/carts/draft", "till-7:42", `{}`)
+	if resp.Header.Get(HeaderRequestID) != "till-7:42" || string(meta["request_id"]) != `"till-7:42"` {
+		t.Errorf("Expected the client's request ID, got %q / %s", resp.Header.Get(HeaderRequestID), meta["request_id"])
+	}
+	if meta["fields"] == nil {
+		t.Errorf("Expected the validation fields to be kept in meta: %v", meta)
+	}
+
+	// Malformed IDs are replaced
+	resp, _ = requestIDResponse(t, server, "GET", "/health", "bad id/with{braces}", "")
+	if got := resp.Header.Get(HeaderRequestID); got == "" || strings.Contains(got, "{") {
+		t.Errorf("Expected a malformed request ID to be replaced, got %q", got)
+	}
+}
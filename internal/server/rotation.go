@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/jobs"
+)
+
+// JobKindRotateKey is the job kind of a server key rotation
+const JobKindRotateKey = "rotate_key"
+
+// RotateKeyRequest is the body of POST /security/rotate-key
+type RotateKeyRequest struct {
+	// WrappedKey is the new server key wrapped under the current one
+	// (security.WrapServerKey), base64 encoded
+	WrappedKey string `json:"wrapped_key" validate:"required"`
+}
+
+// handleRotateKey starts re-encrypting the database under a new server key.
+// It is an admin route, and the wrapped key itself only unwraps for the
+// holder of the current key. Progress is reported on /jobs/:id.
+func (s *Server) handleRotateKey(c *fiber.Ctx) error {
+	if s.config.RotateKey == nil {
+		return apperr.Unavailable(api.MessageServiceUnavailable, 5*time.Second)
+	}
+	manager, err := s.requireJobs()
+	if err != nil {
+		return err
+	}
+
+	var req RotateKeyRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+
+	// Claimed before unwrapping so the current key is not read mid-rotation
+	if !s.rotating.CompareAndSwap(false, true) {
+		return apperr.Conflict("A server key rotation is already running")
+	}
+
+	run, err := s.config.RotateKey(req.WrappedKey)
+	if err != nil {
+		s.rotating.Store(false)
+		log.Printf("Rejected server key rotation: %v", err)
+		return apperr.BadRequest("wrapped_key does not unwrap under the current server key")
+	}
+
+	job, err := manager.Submit(JobKindRotateKey, func(ctx context.Context, p *jobs.Progress) (string, error) {
+		defer s.rotating.Store(false)
+		return run(ctx, p)
+	})
+	if err != nil {
+		s.rotating.Store(false)
+		return apperr.Internal(err)
+	}
+
+	return acceptJob(c, job, "Server key rotation started")
+}
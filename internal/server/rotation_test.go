@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/jobs"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func TestRotateKey(t *testing.T) {
+	currentKey, _ := security.GenerateServerKey()
+	db, err := database.New(&database.Config{ServerKey: currentKey, InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	manager, err := jobs.NewManager(db)
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	t.Cleanup(manager.Shutdown)
+
+	cfg := DefaultConfig()
+	cfg.DB = db
+	cfg.Jobs = manager
+	cfg.RotateKey = func(wrappedKey string) (jobs.RunFunc, error) {
+		newKey, err := security.RotateServerKey(currentKey, wrappedKey)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, p *jobs.Progress) (string, error) {
+			return "", db.Rekey(ctx, newKey, nil)
+		}, nil
+	}
+	server := New(cfg)
+
+	db.SetSetting("store.name", "Corner Shop")
+	staff, _ := auth.Issue(db, auth.RoleStaff, "till", 0)
+	newKey, _ := security.GenerateServerKey()
+	wrapped, _ := security.WrapServerKey(currentKey, newKey)
+	body := `{"wrapped_key":"` + wrapped + `"}`
+
+	if resp, _ := anonymousRequest(t, server, http.MethodPost, "/security/rotate-key", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Tokenless rotation returned %d, want 401", resp.StatusCode)
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/security/rotate-key", staff, body); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Staff rotation returned %d, want 403", resp.StatusCode)
+	}
+
+	forged, _ := security.WrapServerKey(newKey, newKey)
+	if resp, _ := guardedRequest(t, server, http.MethodPost, "/security/rotate-key", `{"wrapped_key":"`+forged+`"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Foreign wrapped key returned %d, want 400", resp.StatusCode)
+	}
+
+	resp, _ := guardedRequest(t, server, http.MethodPost, "/security/rotate-key", body)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Rotation returned %d, want 202", resp.StatusCode)
+	}
+
+	jobID := resp.Header.Get("Location")[len("/jobs/"):]
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := manager.Get(jobID)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if job.Status == database.JobSucceeded {
+			break
+		}
+		if job.Status == database.JobFailed || time.Now().After(deadline) {
+			t.Fatalf("Rotation job did not succeed: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if ok, _ := db.KeyMatches(newKey); !ok {
+		t.Error("Expected the database to be under the new key")
+	}
+	if value, err := db.GetSetting("store.name"); err != nil || value != "Corner Shop" {
+		t.Errorf("Setting unreadable after rotation: %q, %v", value, err)
+	}
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/hub"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Self-checkout lanes run the same service with lane_profile
+// "self_checkout". Customers scan into chunked carts under an item limit;
+// weight mismatches (reported by the lane) and age-restricted items raise
+// interventions on the store hub, where attendant terminals resolve them.
+// Checkout stays blocked until every intervention on the cart is approved.
+
+// scoHoldPrefix prefixes the settings listing a cart's interventions
+const scoHoldPrefix = "sco.hold."
+
+// scoHold tracks the interventions raised for a self-checkout cart
+type scoHold struct {
+	Interventions  []string `json:"interventions"`              // Hub intervention IDs
+	AgeCheckedSKUs []string `json:"age_checked_skus,omitempty"` // SKUs already covered by an age check
+}
+
+// InterventionRequest is the body of POST /sco/interventions
+type InterventionRequest struct {
+	CartID string `json:"cart_id" validate:"required"`
+	Type   string `json:"type" validate:"required"`
+	SKU    string `json:"sku,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// checkSelfCheckoutLimit rejects a chunk that would take a self-checkout
+// cart past the configured item limit
+func (s *Server) checkSelfCheckoutLimit(db *database.DB, id string, lines []database.SaleLine) error {
+	limit := s.config.SCOMaxItems
+	if limit <= 0 {
+		limit = constants.DefaultSCOMaxItems
+	}
+
+	quantity := 0
+	if totals, err := db.GetBasketTotals(id); err == nil {
+		quantity = totals.Quantity
+	} else if !errors.Is(err, database.ErrBasketNotFound) {
+		return apperr.Database(err)
+	}
+	for _, line := range lines {
+		quantity += line.Quantity
+	}
+
+	if quantity > limit {
+		return basketTooLarge(fmt.Sprintf("Self-checkout carts are limited to %d items; please ask an attendant", limit))
+	}
+	return nil
+}
+
+// raiseAgeChecks raises an age-check intervention for each age-restricted
+// SKU in lines not yet covered on the cart. Checkout re-runs it, so a hub
+// outage here only delays the attendant's notification.
+func (s *Server) raiseAgeChecks(ctx context.Context, cartID, lane string, lines []database.SaleLine) error {
+	restricted := make(map[string]bool, len(s.config.AgeRestrictedSKUs))
+	for _, sku := range s.config.AgeRestrictedSKUs {
+		restricted[sku] = true
+	}
+	if len(restricted) == 0 {
+		return nil
+	}
+
+	hold := s.scoHold(cartID)
+	covered := make(map[string]bool, len(hold.AgeCheckedSKUs))
+	for _, sku := range hold.AgeCheckedSKUs {
+		covered[sku] = true
+	}
+
+	changed := false
+	for _, line := range lines {
+		if !restricted[line.SKU] || covered[line.SKU] {
+			continue
+		}
+		hubClient, err := s.requireHub()
+		if err != nil {
+			return err
+		}
+		in, err := hubClient.RaiseIntervention(ctx, &hub.Intervention{
+			Lane:   lane,
+			CartID: cartID,
+			Type:   hub.InterventionAgeCheck,
+			SKU:    line.SKU,
+		})
+		if err != nil {
+			return hubError(err)
+		}
+		hold.Interventions = append(hold.Interventions, in.ID)
+		hold.AgeCheckedSKUs = append(hold.AgeCheckedSKUs, line.SKU)
+		covered[line.SKU] = true
+		changed = true
+	}
+
+	if changed {
+		if err := s.db.SetSettingJSON(scoHoldPrefix+cartID, hold); err != nil {
+			return apperr.Database(err)
+		}
+	}
+	return nil
+}
+
+// checkInterventions blocks a self-checkout payment until every
+// intervention on the cart has been approved by an attendant
+func (s *Server) checkInterventions(ctx context.Context, cartID, lane string, lines []database.SaleLine) error {
+	if err := s.raiseAgeChecks(ctx, cartID, lane, lines); err != nil {
+		return err
+	}
+
+	hold := s.scoHold(cartID)
+	if len(hold.Interventions) == 0 {
+		return nil
+	}
+
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+
+	open := 0
+	for _, id := range hold.Interventions {
+		in, err := hubClient.GetIntervention(ctx, id)
+		if err != nil {
+			return hubError(err)
+		}
+		switch {
+		case in.State == hub.InterventionOpen:
+			open++
+		case !in.Approved:
+			return apperr.Conflict("An attendant declined this cart; please ask for help")
+		}
+	}
+
+	if open > 0 {
+		return apperr.Conflict(fmt.Sprintf("Waiting for an attendant (%d open intervention(s))", open)).Retryable(5 * time.Second)
+	}
+	return nil
+}
+
+// scoHold returns the interventions recorded for a cart (empty if none)
+func (s *Server) scoHold(cartID string) *scoHold {
+	var hold scoHold
+	s.db.GetSettingJSON(scoHoldPrefix+cartID, &hold)
+	return &hold
+}
+
+// clearSCOHold forgets a cart's interventions once it is paid or discarded
+func (s *Server) clearSCOHold(cartID string) {
+	if err := s.db.DeleteSetting(scoHoldPrefix + cartID); err != nil && !errors.Is(err, database.ErrSettingNotFound) {
+		log.Printf("Warning: failed to clear self-checkout hold on cart %s: %v", cartID, err)
+	}
+}
+
+// handleRaiseIntervention lets a lane call an attendant for one of its
+// carts, e.g. when the bagging-area scale disagrees with the scanned item
+func (s *Server) handleRaiseIntervention(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+	lane, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	var req InterventionRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+	if !hub.ValidInterventionType(req.Type) {
+		return apperr.BadRequest("type must be weight_check or age_check")
+	}
+
+	totals, err := db.GetBasketTotals(req.CartID)
+	if errors.Is(err, database.ErrBasketNotFound) || (err == nil && totals.TerminalID != lane) {
+		return apperr.NotFound("Cart not found")
+	}
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	in, err := hubClient.RaiseIntervention(c.UserContext(), &hub.Intervention{
+		Lane:   lane,
+		CartID: totals.BasketID,
+		Type:   req.Type,
+		SKU:    req.SKU,
+		Detail: req.Detail,
+	})
+	if err != nil {
+		return hubError(err)
+	}
+
+	hold := s.scoHold(totals.BasketID)
+	hold.Interventions = append(hold.Interventions, in.ID)
+	if err := db.SetSettingJSON(scoHoldPrefix+totals.BasketID, hold); err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Attendant called", in))
+}
+
+// handleGetIntervention reports the state of one of the lane's interventions
+func (s *Server) handleGetIntervention(c *fiber.Ctx) error {
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+	lane, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	in, err := hubClient.GetIntervention(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return hubError(err)
+	}
+	if in.Lane != lane {
+		return apperr.NotFound("Intervention not found")
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Intervention retrieved successfully", in))
+}
+
+// handleListOpenInterventions lists interventions waiting for an
+// attendant, paginated
+func (s *Server) handleListOpenInterventions(c *fiber.Ctx) error {
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	interventions, err := hubClient.ListInterventions(c.UserContext(), hub.InterventionOpen)
+	if err != nil {
+		return hubError(err)
+	}
+
+	return listPage(c, "Interventions retrieved successfully", interventions, page)
+}
+
+// handleResolveIntervention records the attendant's decision
+func (s *Server) handleResolveIntervention(c *fiber.Ctx) error {
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Approved bool   `json:"approved"`
+		Note     string `json:"note,omitempty"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return apperr.BadRequest("Invalid resolution")
+	}
+
+	in, err := hubClient.ResolveIntervention(c.UserContext(), c.Params("id"), hub.InterventionResolution{
+		TerminalID: terminal,
+		Approved:   body.Approved,
+		Note:       body.Note,
+	})
+	if err != nil {
+		return hubError(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Intervention resolved successfully", in))
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/hub"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// newTestLane returns a self-checkout lane wired to a listening store hub
+// newTestLane returns a self-checkout lane on a store hub, and the token
+// of its kiosk frontend
+func newTestLane(t *testing.T) (*Server, string) {
+	hubServer := newTestServerWithDB(t)
+	storeHub, err := hub.New(&hub.Config{DB: hubServer.db})
+	if err != nil {
+		t.Fatalf("Failed to create hub: %v", err)
+	}
+	storeHub.Register(hubServer.API().Group("/hub"))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go hubServer.GetApp().Listener(ln)
+	t.Cleanup(func() { hubServer.Shutdown() })
+
+	hubToken, err := auth.Issue(hubServer.db, auth.RoleTerminal, "SCO1", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	lane := newTestServerWithLedger(t)
+	lane.hub = hub.NewClient("http://"+ln.Addr().String()+"/hub", hubToken, nil)
+	lane.config.Profile = constants.LaneProfileSelfCheckout
+	lane.config.SCOMaxItems = 5
+	lane.config.AgeRestrictedSKUs = []string{"BEER"}
+
+	kiosk, err := auth.Issue(lane.db, auth.RoleSelfCheckout, "SCO1", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	return lane, kiosk
+}
+
+// laneRequest sends a request from lane SCO1 presenting token, or no
+// credentials when token is empty, and returns the response and its result
+func laneRequest(t *testing.T, server *Server, method, path, token, body string) (*http.Response, json.RawMessage) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTerminalID, "SCO1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return sendForResult(t, server, req)
+}
+
+// guardedRequest sends a request from lane SCO1 with an admin token and
+// the admin password, for the routes requireAdminPassword guards
+func guardedRequest(t *testing.T, server *Server, method, path, body string) (*http.Response, json.RawMessage) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTerminalID, "SCO1")
+	return sendForResult(t, server, withAdminPassword(t, server, req))
+}
+
+// sendForResult sends req and returns the response and its result
+func sendForResult(t *testing.T, server *Server, req *http.Request) (*http.Response, json.RawMessage) {
+	resp, err := send(server, req, -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &envelope)
+
+	return resp, envelope.Result
+}
+
+func TestSelfCheckout_RestrictedSurface(t *testing.T) {
+	lane, kiosk := newTestLane(t)
+
+	if resp, _ := laneRequest(t, lane, "GET", "/health", kiosk, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Health: expected 200, got %d", resp.StatusCode)
+	}
+	for _, path := range []string{"/status", "/config", "/operators/op1/stats", "/attendant/interventions"} {
+		if resp, _ := laneRequest(t, lane, "GET", path, kiosk, ""); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for the kiosk, got %d", path, resp.StatusCode)
+		}
+	}
+	if resp, _ := laneRequest(t, lane, "POST", "/service/stop", kiosk, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for service control, got %d", resp.StatusCode)
+	}
+
+	// An attendant token unlocks the full API; a bad token is rejected outright
+	token, err := auth.Issue(lane.db, auth.RoleAttendant, "front", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if resp, _ := laneRequest(t, lane, "GET", "/status", token, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with an attendant token, got %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, lane, "GET", "/health", "bogus", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown token, got %d", resp.StatusCode)
+	}
+}
+
+func TestSelfCheckout_ItemLimit(t *testing.T) {
+	lane, kiosk := newTestLane(t)
+
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/lines", kiosk, chunkBody(0, 4)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Append: expected 200, got %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/lines", kiosk, chunkBody(1, 2)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 past the item limit, got %d", resp.StatusCode)
+	}
+
+	// Staff are not limited
+	staff, _ := auth.Issue(lane.db, auth.RoleStaff, "", 0)
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/lines", staff, chunkBody(1, 2)); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for staff, got %d", resp.StatusCode)
+	}
+}
+
+func TestSelfCheckout_InterventionsBlockCheckout(t *testing.T) {
+	lane, kiosk := newTestLane(t)
+
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/lines", kiosk, `{"lines":[{"sku":"BEER","quantity":1,"price":450}]}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Append: expected 200, got %d", resp.StatusCode)
+	}
+	resp, data := laneRequest(t, lane, "POST", "/sco/interventions", kiosk, `{"cart_id":"c1","type":"weight_check","detail":"expected 500g, got 820g"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Raise: expected 200, got %d", resp.StatusCode)
+	}
+	var weight hub.Intervention
+	json.Unmarshal(data, &weight)
+
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/checkout", kiosk, ""); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected 409 while interventions are open, got %d", resp.StatusCode)
+	}
+
+	// An attendant sees both (age check raised automatically) and approves them
+	token, _ := auth.Issue(lane.db, auth.RoleAttendant, "", 0)
+	resp, data = laneRequest(t, lane, "GET", "/attendant/interventions", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("List: expected 200, got %d", resp.StatusCode)
+	}
+	var open []hub.Intervention
+	json.Unmarshal(data, &open)
+	if len(open) != 2 {
+		t.Fatalf("Expected 2 open interventions, got %d", len(open))
+	}
+	for _, in := range open {
+		if resp, _ := laneRequest(t, lane, "POST", "/attendant/interventions/"+in.ID+"/resolve", token, `{"approved":true}`); resp.StatusCode != http.StatusOK {
+			t.Errorf("Resolve %s: expected 200, got %d", in.Type, resp.StatusCode)
+		}
+	}
+
+	resp, data = laneRequest(t, lane, "GET", "/sco/interventions/"+weight.ID, kiosk, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Get: expected 200, got %d", resp.StatusCode)
+	}
+	json.Unmarshal(data, &weight)
+	if weight.State != hub.InterventionResolved || !weight.Approved {
+		t.Errorf("Expected approved weight check, got %+v", weight)
+	}
+
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/checkout", kiosk, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Checkout: expected 200 after approval, got %d", resp.StatusCode)
+	}
+	if hold := lane.scoHold("c1"); len(hold.Interventions) != 0 {
+		t.Errorf("Expected hold to be cleared after checkout, got %+v", hold)
+	}
+}
+
+func TestSelfCheckout_DeclinedInterventionBlocksCheckout(t *testing.T) {
+	lane, kiosk := newTestLane(t)
+
+	laneRequest(t, lane, "POST", "/carts/c1/lines", kiosk, `{"lines":[{"sku":"BEER","quantity":1,"price":450}]}`)
+	token, _ := auth.Issue(lane.db, auth.RoleAttendant, "", 0)
+	_, data := laneRequest(t, lane, "GET", "/attendant/interventions", token, "")
+	var open []hub.Intervention
+	json.Unmarshal(data, &open)
+	if len(open) != 1 {
+		t.Fatalf("Expected 1 open intervention, got %d", len(open))
+	}
+	laneRequest(t, lane, "POST", "/attendant/interventions/"+open[0].ID+"/resolve", token, `{"approved":false,"note":"no ID"}`)
+
+	if resp, _ := laneRequest(t, lane, "POST", "/carts/c1/checkout", kiosk, ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 after a declined age check, got %d", resp.StatusCode)
+	}
+}
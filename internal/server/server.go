@@ -2,39 +2,257 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/config"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/diagnostics"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/hub"
+	"github.com/professor93/promo-pos/internal/jobs"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/metrics"
+	"github.com/professor93/promo-pos/internal/peripheral"
+	"github.com/professor93/promo-pos/internal/receipt"
+	"github.com/professor93/promo-pos/internal/sales"
+	"github.com/professor93/promo-pos/internal/service"
+	possync "github.com/professor93/promo-pos/internal/sync"
 	"github.com/professor93/promo-pos/pkg/constants"
 )
 
 // Server represents the HTTP server
 type Server struct {
 	app    *fiber.App
+	api    fiber.Router // Routes under APIPrefix
 	port   int
 	config *Config
+	db     *database.DB
+	jobs   *jobs.Manager
+	ledger *sales.Ledger
+	hub    *hub.Client
+
+	// rotating is set while a server key rotation job runs
+	rotating atomic.Bool
+
+	// privacy is the end of the current privacy mode window, if any
+	privacy atomic.Pointer[time.Time]
+
+	// lockout tracks failed authentication attempts per source address
+	lockout *authLimiter
+
+	// peripherals tracks the health of external peripherals for /status
+	peripherals *peripheral.Monitor
+
+	// idempotency holds the Idempotency-Keys of requests being handled
+	idempotency idempotencyLocks
+
+	// refunds serialises refund checks and commits
+	refunds sync.Mutex
+
+	// events carries live service events to /ws/status and /sync/events;
+	// streamsDone is closed on shutdown to end those streams, which outlive
+	// their request
+	events      *events.Bus
+	streamsDone chan struct{}
+	closeOnce   sync.Once
+
+	// syncStats tracks sync outcomes for /metrics; httpLatency is nil
+	// unless metrics are enabled
+	syncStats   *possync.Stats
+	httpLatency *metrics.HistogramVec
+
+	// quickCheck caches the database integrity probe of /health
+	quickCheck quickCheckCache
+
+	// bootstrapped is when the first successful sync finished, nil before
+	bootstrapped atomic.Pointer[time.Time]
+
+	// offline is set once the offline grace period is exceeded; writes are
+	// refused until a sync succeeds (see SetOfflineMode)
+	offline atomic.Bool
+
+	// bodyLimit and importBodyLimit are the request body limits limitBody
+	// applies, defaults filled in
+	bodyLimit       int
+	importBodyLimit int
+
+	// locale is the response language when Accept-Language names none the
+	// service speaks (see SetLocale)
+	locale atomic.Pointer[string]
+
+	// changes numbers local data changes for the /changes long poll
+	changes *changeFeed
+
+	// tickets holds the one-time tickets opening /ws/status and
+	// /sync/events from a browser
+	tickets *ticketStore
+
+	// statusSocket upgrades /ws/status requests and streams to them
+	statusSocket fiber.Handler
+
+	// graphQL answers /graphql when it is enabled
+	graphQL *graphql.Schema
 }
 
 // Config holds server configuration
 type Config struct {
-	Port                int
-	MaxConcurrentConns  int
-	RateLimitPerMinute  int
-	ReadTimeout         time.Duration
-	WriteTimeout        time.Duration
-	IdleTimeout         time.Duration
+	Port                  int
+	BindAddress           string // Listener address; empty binds loopback
+	MaxConcurrentConns    int
+	RateLimitPerMinute    int
+	ReadTimeout           time.Duration
+	WriteTimeout          time.Duration
+	IdleTimeout           time.Duration
 	DisableStartupMessage bool
+
+	// Locale is the language of response messages for callers whose
+	// Accept-Language names none the service speaks; empty means English
+	Locale string
+
+	// BodyLimit caps request bodies and ImportBodyLimit those of POST
+	// /import, in bytes (0 uses the defaults); larger requests answer 413
+	// before they are read
+	BodyLimit       int
+	ImportBodyLimit int
+
+	// DB backs the data endpoints; they answer 503 when it is nil
+	DB *database.DB
+
+	// Jobs runs async bulk operations tracked via /jobs
+	Jobs *jobs.Manager
+
+	// Ledger commits checked-out carts; checkout answers 503 when it is nil
+	Ledger *sales.Ledger
+
+	// Hub reaches the store hub for terminal-to-terminal cart transfers
+	// and self-checkout interventions
+	Hub *hub.Client
+
+	// Profile is the lane profile; on constants.LaneProfileSelfCheckout the
+	// frontend JWTs /auth/token issues are confined to the SCO routes
+	Profile string
+
+	// SCOMaxItems caps the items in a self-checkout cart (0 uses the default)
+	SCOMaxItems int
+
+	// AgeRestrictedSKUs raise an age-check intervention at self-checkout
+	AgeRestrictedSKUs []string
+
+	// APISecret, if set, is shared with the POS frontend, which trades it
+	// at /auth/token for a JWT signed with it (see auth.IssueJWT)
+	APISecret []byte
+
+	// AllowedOrigins are the browser origins of the POS frontends, such as
+	// "http://localhost:3000". Pages from other origins, bar the service's
+	// own, get no CORS headers and cannot open /ws/status.
+	AllowedOrigins []string
+
+	// Printers are the receipt printers by name; GET /sales/:id/receipt
+	// ?printer=<name> returns the page encoded for that printer
+	Printers map[string]receipt.Printer
+
+	// RotateKey unwraps a rotated server key for POST /security/rotate-key
+	// and returns the job that re-encrypts with it; nil answers 503
+	RotateKey func(wrappedKey string) (jobs.RunFunc, error)
+
+	// SyncSchedule is this terminal's staggered sync slot, reported by /status
+	SyncSchedule possync.Schedule
+
+	// ClosingChecklist lists the day-close checklist items (nil means all);
+	// ClosingMaxPendingSync is the unsynced changes pending_sync tolerates
+	ClosingChecklist      []string
+	ClosingMaxPendingSync int
+
+	// Peripherals tracks external peripherals for /status; nil creates a
+	// monitor fed only by PUT /peripherals/:name
+	Peripherals *peripheral.Monitor
+
+	// Events carries live service events to /ws/status and /sync/events;
+	// nil creates a bus fed by this server's own sources (sync, read-only
+	// mode, peripherals)
+	Events *events.Bus
+
+	// GraphQL serves read-only GraphQL queries over local data at /graphql
+	GraphQL bool
+
+	// Pprof serves the net/http/pprof profiles to admins at /debug/pprof
+	Pprof bool
+
+	// SafeMode is set when the service started in safe mode after a crash
+	// loop; /health reports it
+	SafeMode bool
+
+	// Metrics receives the request latency, sync and database metrics,
+	// served by the caller on its own port; nil disables them
+	Metrics *metrics.Registry
+
+	// SyncStats accumulates sync outcomes; nil creates stats fed only by
+	// POST /sync
+	SyncStats *possync.Stats
+
+	// CheckConfig reads and decrypts the config file for /health; nil
+	// skips the check
+	CheckConfig func() error
+
+	// MaxOffline is how long the terminal may go without syncing; /health
+	// fails last_sync and backend_contact beyond it (0 never fails them)
+	MaxOffline time.Duration
+
+	// ConfigLoaded reports whether the config has been loaded, for /ready;
+	// nil counts as loaded
+	ConfigLoaded func() bool
+
+	// UpdateConfig applies a partial JSON config update for PUT /config
+	// (see config.Manager.Patch); nil answers 503
+	UpdateConfig func(patch []byte) (*config.UpdateReport, error)
+
+	// Service controls the Windows service for /service/*; nil answers 503
+	Service ServiceControl
+
+	// ReadLogs reads the end of the service log for GET /logs (see
+	// logging.ReadTail); nil answers 503
+	ReadLogs func(q logging.Query) (*logging.Tail, error)
+
+	// SealDiagnostics adds the sanitized config and version to the files
+	// collected for GET /diagnostics and returns the encrypted bundle (see
+	// diagnostics.Write); nil answers 503
+	SealDiagnostics func(files []diagnostics.File) ([]byte, error)
 }
 
+// ServiceControl starts, stops and restarts the service hosting the API
+// (see service.Manager). Stop and restart are scheduled so the request
+// asking for them is answered first.
+type ServiceControl interface {
+	Start() error
+	ScheduleStop(delay time.Duration) error
+	ScheduleRestart(delay time.Duration) error
+}
+
+// serviceActionDelay gives the response to /service/stop and
+// /service/restart time to reach the client before the service goes down
+const serviceActionDelay = time.Second
+
 // DefaultConfig returns the default server configuration
 func DefaultConfig() *Config {
 	return &Config{
 		Port:               constants.DefaultPort,
+		BindAddress:        constants.BindLoopback,
 		MaxConcurrentConns: constants.DefaultMaxConcurrentConnections,
 		RateLimitPerMinute: constants.DefaultRateLimitPerMinute,
 		ReadTimeout:        30 * time.Second,
@@ -49,33 +267,106 @@ func New(cfg *Config) *Server {
 		cfg = DefaultConfig()
 	}
 
+	bodyLimit := cfg.BodyLimit
+	if bodyLimit <= 0 {
+		bodyLimit = constants.DefaultBodyLimit
+	}
+	importBodyLimit := cfg.ImportBodyLimit
+	if importBodyLimit <= 0 {
+		importBodyLimit = constants.DefaultImportBodyLimit
+	}
+	importBodyLimit = max(importBodyLimit, bodyLimit)
+
 	// Create Fiber app with custom config
 	app := fiber.New(fiber.Config{
-		AppName:      constants.AppName,
-		ServerHeader: constants.AppDisplayName,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		IdleTimeout:  cfg.IdleTimeout,
-		Concurrency:  cfg.MaxConcurrentConns,
+		AppName:               constants.AppName,
+		ServerHeader:          constants.AppDisplayName,
+		ReadTimeout:           cfg.ReadTimeout,
+		WriteTimeout:          cfg.WriteTimeout,
+		IdleTimeout:           cfg.IdleTimeout,
+		Concurrency:           cfg.MaxConcurrentConns,
 		DisableStartupMessage: cfg.DisableStartupMessage,
+		// Bodies are read by handlers, after limitBody checked them
+		BodyLimit:         importBodyLimit,
+		StreamRequestBody: true,
 		// Custom error handler
 		ErrorHandler: customErrorHandler,
 	})
 
 	// Add middleware
 	app.Use(recover.New())
+	app.Use(requestID)
 	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${latency} ${method} ${path}\n",
+		Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${respHeader:" + HeaderRequestID + "}\n",
 	}))
-	app.Use(cors.New())
-
 	server := &Server{
-		app:    app,
-		port:   cfg.Port,
-		config: cfg,
+		app:         app,
+		port:        cfg.Port,
+		config:      cfg,
+		db:          cfg.DB,
+		jobs:        cfg.Jobs,
+		ledger:      cfg.Ledger,
+		hub:         cfg.Hub,
+		lockout:     newAuthLimiter(),
+		peripherals: cfg.Peripherals,
+		events:      cfg.Events,
+		streamsDone: make(chan struct{}),
+		syncStats:   cfg.SyncStats,
+		changes:     newChangeFeed(),
+		tickets:     newTicketStore(),
+
+		bodyLimit:       bodyLimit,
+		importBodyLimit: importBodyLimit,
 	}
+	if server.peripherals == nil {
+		server.peripherals = peripheral.New(constants.PeripheralStaleSeconds * time.Second)
+	}
+	if server.events == nil {
+		server.events = events.NewBus()
+	}
+	server.statusSocket = websocket.New(server.streamStatus, websocket.Config{ReadBufferSize: websocketMaxMessage})
+	server.publishEvents()
+	server.SetLocale(cfg.Locale)
+	if server.syncStats == nil {
+		server.syncStats = possync.NewStats()
+	}
+
+	// Browsers only share responses with the configured frontends
+	app.Use(cors.New(cors.Config{AllowOriginsFunc: server.originAllowed}))
+
+	// Time every request once metrics are enabled
+	if cfg.Metrics != nil {
+		server.registerMetrics(cfg.Metrics)
+	}
+
+	// Admin dashboard, served as is (no rewrite, no token needed for the page)
+	server.setupAdminUI()
+
+	// Serve pre-versioning paths from the current API version
+	app.Use(legacyPaths)
+
+	// Send response messages in the caller's language
+	app.Use(server.localize)
+
+	// Refuse oversized bodies before reading them
+	app.Use(server.limitBody)
+
+	// Bound how long a handler may hold its connection
+	app.Use(requestTimeout)
+
+	// Resolve the caller's role before any route runs
+	app.Use(server.authenticate)
+
+	// Refuse writes while offline for too long
+	app.Use(server.blockOfflineWrites)
+
+	// Mask customer data while privacy mode is on
+	server.loadPrivacy()
+	server.loadBootstrap()
+	app.Use(server.maskPrivate)
 
 	// Setup routes
+	server.api = app.Group(APIPrefix)
 	server.setupRoutes()
 
 	return server
@@ -83,31 +374,189 @@ func New(cfg *Config) *Server {
 
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
+	r := s.api
+
 	// Health check endpoint
-	s.app.Get("/health", s.handleHealth)
+	r.Get("/health", s.handleHealth)
+
+	// Liveness (process up) and readiness (able to take sales) probes
+	r.Get("/live", s.handleLive)
+	r.Get("/ready", s.handleReady)
 
 	// Status endpoint
-	s.app.Get("/status", s.handleStatus)
+	r.Get("/status", s.handleStatus)
+
+	// Live status events over a WebSocket
+	r.Get("/ws/status", s.handleStatusSocket)
+
+	// Frontend token issuance (api_secret) and stream tickets
+	r.Post("/auth/token", s.handleIssueFrontendToken)
+	r.Post("/auth/stream-ticket", s.handleIssueStreamTicket)
+
+	// Cashier PIN sign-in and user management (admin only)
+	r.Post("/auth/pin", s.handlePINSignIn)
+	r.Post("/users", requireAdmin, s.handleCreateUser)
+	r.Get("/users", requireAdmin, s.handleListUsers)
+	r.Put("/users/:id/pin", requireAdmin, s.handleSetUserPIN)
+	r.Delete("/users/:id", requireAdmin, s.requireAdminPassword, s.handleDeleteUser)
 
 	// Config endpoint
-	s.app.Get("/config", s.handleGetConfig)
+	r.Get("/config", s.handleGetConfig)
+	r.Put("/config", requireAdmin, s.requireAdminPassword, s.handleUpdateConfig)
+
+	// Data endpoint: frontend records persisted into their domain tables
+	r.Post("/data", s.idempotent, s.handleData)
 
-	// Data endpoint
-	s.app.Post("/data", s.handleData)
+	// Peripheral health reported by the layer driving them
+	r.Put("/peripherals/:name", s.handleReportPeripheral)
 
 	// Sync endpoint
-	s.app.Post("/sync", s.handleSync)
+	r.Post("/sync", s.handleSync)
+	r.Get("/sync/events", s.handleSyncEvents)
+	r.Get("/sync/history", requireAdmin, s.handleSyncHistory)
+
+	// Long poll for local data changes, for frontends without WebSockets
+	r.Get("/changes", s.handleChanges)
+
+	// Read-only GraphQL over local data, when enabled
+	if s.config.GraphQL {
+		s.graphQL = newGraphQLSchema(s)
+		r.Get("/graphql", s.handleGraphQL)
+		r.Post("/graphql", s.handleGraphQL)
+	}
 
-	// Service control endpoints
-	s.app.Post("/service/start", s.handleServiceStart)
-	s.app.Post("/service/stop", s.handleServiceStop)
-	s.app.Post("/service/restart", s.handleServiceRestart)
+	// Runtime profiles for field diagnosis (admin only), when enabled
+	if s.config.Pprof {
+		s.setupDebugRoutes(r)
+	}
+
+	// Local catalog (writes feed the sync outbox)
+	r.Get("/products", s.handleListProducts)
+	r.Post("/products", s.idempotent, s.handleCreateProduct)
+	r.Put("/products/:barcode", s.handleUpdateProduct)
+	r.Delete("/products/:barcode", s.handleDeactivateProduct)
+
+	// Price lookup, the hot path of checkout scanning
+	r.Get("/price/:barcode", s.handleGetPrice)
+
+	// Loyalty customers and points, served offline from the local store
+	r.Get("/customers", s.handleSearchCustomers)
+	r.Get("/customers/:id", s.handleGetCustomer)
+	r.Get("/customers/:id/loyalty", s.handleGetLoyalty)
+	r.Post("/customers/:id/loyalty", s.idempotent, s.handleRecordLoyalty)
+
+	// Tendered sales, voids of open transactions and refunds
+	r.Post("/transactions", s.idempotent, s.handleCreateTransaction)
+	r.Get("/transactions/:id", s.handleGetTransaction)
+	r.Post("/transactions/:id/void", s.idempotent, s.handleVoidTransaction)
+	r.Post("/transactions/:id/refund", s.idempotent, s.handleRefundTransaction)
+
+	// Transaction draft autosave
+	r.Get("/carts/draft", s.handleGetDraft)
+	r.Put("/carts/draft", s.handlePutDraft)
+	r.Delete("/carts/draft", s.handleDeleteDraft)
+	r.Get("/carts/:id/suggestions", s.handleGetSuggestions)
+
+	// Chunked carts for large (wholesale) transactions
+	r.Post("/carts/:id/lines", s.idempotent, s.handleAppendBasketLines)
+	r.Get("/carts/:id/lines", s.handleGetBasketLines)
+	r.Get("/carts/:id/totals", s.handleGetBasketTotals)
+	r.Post("/carts/:id/checkout", s.idempotent, s.handleCheckoutBasket)
+	r.Delete("/carts/:id", s.handleDeleteBasket)
+	r.Post("/carts/:id/transfer", s.idempotent, s.handleTransferBasket)
+	r.Post("/transfers/:id/accept", s.idempotent, s.handleAcceptTransfer)
+	r.Get("/sales/:id/receipt", s.handleGetReceiptPage)
+
+	// Self-checkout interventions (lanes raise, attendants resolve)
+	r.Post("/sco/interventions", s.handleRaiseIntervention)
+	r.Get("/sco/interventions/:id", s.handleGetIntervention)
+	r.Get("/attendant/interventions", s.handleListOpenInterventions)
+	r.Post("/attendant/interventions/:id/resolve", s.handleResolveIntervention)
+
+	// Handheld stock-taking devices (registering, removing and auditing
+	// them is admin only)
+	r.Post("/devices", requireAdmin, s.handleRegisterDevice)
+	r.Get("/devices", s.handleListDevices)
+	r.Delete("/devices/:id", requireAdmin, s.requireAdminPassword, s.handleDeleteDevice)
+	r.Get("/devices/:id/audit", requireAdmin, s.handleGetDeviceAudit)
+	r.Post("/devices/:id/token", s.handleDeviceToken)
+	r.Get("/products/:barcode", s.handleGetProduct)
+	r.Post("/stock/:sku/adjust", s.idempotent, s.handleAdjustStock)
+	r.Post("/labels", s.handleRequestLabels)
+	r.Get("/labels", s.handleListLabels)
+	r.Post("/labels/:id/printed", s.handleLabelsPrinted)
+
+	// Inventory: stock levels and the stock movement log
+	r.Get("/inventory", s.handleListInventory)
+	r.Get("/inventory/:sku", s.handleGetInventory)
+	r.Post("/inventory/receipts", s.idempotent, s.handleRecordReceipt)
+	r.Post("/inventory/adjustments", s.idempotent, s.handleRecordAdjustment)
+	r.Post("/inventory/counts", s.idempotent, s.handleRecordCount)
+
+	// API keys for third-party integrations (admin only)
+	r.Post("/api-keys", requireAdmin, s.handleCreateAPIKey)
+	r.Get("/api-keys", requireAdmin, s.handleListAPIKeys)
+	r.Delete("/api-keys/:id", requireAdmin, s.requireAdminPassword, s.handleRevokeAPIKey)
+
+	// Privacy mode for screen sharing
+	r.Get("/privacy", s.handleGetPrivacy)
+	r.Post("/privacy", requireAdmin, s.handleEnablePrivacy)
+	r.Delete("/privacy", requireAdmin, s.handleDisablePrivacy)
+
+	// Security audit log (failed and locked-out authentication), hash-chained
+	r.Get("/audit", requireAdmin, s.handleListAudit)
+	r.Get("/audit/verify", requireAdmin, s.handleVerifyAudit)
+
+	// Local report library (admin only)
+	r.Get("/reports", requireAdmin, s.handleListReports)
+	r.Post("/reports", requireAdmin, s.handleGenerateReport)
+	r.Get("/reports/:id", requireAdmin, s.handleGetReport)
+
+	// Boot counter and uptime history (admin only)
+	r.Get("/admin/uptime", requireAdmin, s.handleGetUptime)
+
+	// Day close checklist, backups and restores (closing the day is admin
+	// only; backups and restores need the admin password)
+	r.Get("/day/checklist", s.handleGetChecklist)
+	r.Post("/day/close", requireAdmin, s.handleCloseDay)
+	r.Post("/backups", requireAdmin, s.requireAdminPassword, s.handleBackup)
+	r.Post("/backups/:file/restore", requireAdmin, s.requireAdminPassword, s.handleRestoreBackup)
+
+	// Server key rotation (backend key rotation policy)
+	r.Post("/security/rotate-key", requireAdmin, s.requireAdminPassword, s.handleRotateKey)
+
+	// Async job tracking
+	r.Get("/jobs", s.handleListJobs)
+	r.Get("/jobs/:id", s.handleGetJob)
+
+	// Product and customer file imports, run as jobs
+	r.Post("/import", s.handleImport)
+
+	// Operator performance (manager app)
+	r.Get("/operators/:id/stats", s.handleGetOperatorStats)
+
+	// Service log and diagnostics bundles for remote support (admin only)
+	r.Get("/logs", requireAdmin, s.handleGetLogs)
+	r.Get("/diagnostics", requireAdmin, s.handleGetDiagnostics)
+
+	// Service control endpoints (admin only, with the admin password)
+	r.Post("/service/start", requireAdmin, s.requireAdminPassword, s.handleServiceStart)
+	r.Post("/service/stop", requireAdmin, s.requireAdminPassword, s.handleServiceStop)
+	r.Post("/service/restart", requireAdmin, s.requireAdminPassword, s.handleServiceRestart)
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server. It refuses to listen beyond loopback
+// without a database, as it could then neither check tokens and API keys
+// nor lock out and audit whoever guesses at them.
 func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%d", s.port)
-	return s.app.Listen(addr)
+	host := s.config.BindAddress
+	if host == "" {
+		host = constants.BindLoopback
+	}
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && s.db == nil {
+		return fmt.Errorf("refusing to listen on %s without a database to check credentials against", host)
+	}
+	return s.app.Listen(net.JoinHostPort(host, strconv.Itoa(s.port)))
 }
 
 // StartWithContext starts the server with graceful shutdown support
@@ -130,6 +579,7 @@ func (s *Server) StartWithContext(ctx context.Context) error {
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown() error {
+	s.closeStreams()
 	return s.app.Shutdown()
 }
 
@@ -138,57 +588,85 @@ func (s *Server) ShutdownWithTimeout(timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	s.closeStreams()
 	return s.app.ShutdownWithContext(ctx)
 }
 
+// closeStreams ends the /ws/status and /sync/events streams, which the HTTP server no
+// longer tracks once they are upgraded
+func (s *Server) closeStreams() {
+	s.closeOnce.Do(func() { close(s.streamsDone) })
+}
+
+// Events returns the bus feeding /ws/status and /sync/events, for
+// publishing events (sync progress) from outside the server
+func (s *Server) Events() *events.Bus {
+	return s.events
+}
+
 // GetApp returns the underlying Fiber app
 func (s *Server) GetApp() *fiber.App {
 	return s.app
 }
 
+// API returns the router for the current API version (APIPrefix), for
+// mounting routes served by other packages such as the store hub
+func (s *Server) API() fiber.Router {
+	return s.api
+}
+
 // customErrorHandler handles errors and returns standardized API responses
 func customErrorHandler(c *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
-	message := err.Error()
-
-	// Check if it's a Fiber error
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
-		message = e.Message
-	}
-
-	// Map HTTP status to application code
-	var appCode int
-	switch code {
-	case fiber.StatusBadRequest:
-		appCode = api.CodeErrorBadRequest
-	case fiber.StatusUnauthorized:
-		appCode = api.CodeErrorUnauthorized
-	case fiber.StatusForbidden:
-		appCode = api.CodeErrorForbidden
-	case fiber.StatusNotFound:
-		appCode = api.CodeErrorNotFound
-	default:
-		appCode = api.CodeErrorInternal
+	if appErr := apperr.From(err); appErr.Status >= fiber.StatusInternalServerError {
+		logging.Printf(c.UserContext(), "%s %s failed: %v", c.Method(), c.Path(), err)
 	}
-
-	// Return standardized error response
-	return c.Status(code).JSON(api.NewErrorResponse(appCode, message))
+	return apperr.Handler(c, err)
 }
 
 // handleHealth handles health check requests
 func (s *Server) handleHealth(c *fiber.Ctx) error {
+	now := time.Now()
+	checks := s.healthChecks(now)
 	health := api.HealthCheck{
-		Healthy:    true,
 		Version:    "1.0.0", // TODO: Get from build info
-		Timestamp:  time.Now().Format(time.RFC3339),
-		DatabaseOK: true, // TODO: Check actual database
-		ConfigOK:   true, // TODO: Check actual config
+		Timestamp:  now.Format(time.RFC3339),
+		DatabaseOK: checks.Database.Status != api.ProbeFailed,
+		ConfigOK:   checks.Config.Status != api.ProbeFailed,
+		Checks:     checks,
+	}
+	health.Healthy = true
+	for _, probe := range []api.HealthProbe{checks.Database, checks.Config, checks.LastSync, checks.DiskSpace, checks.BackendContact} {
+		if probe.Status == api.ProbeFailed {
+			health.Healthy = false
+		}
+	}
+
+	if s.db != nil {
+		if report := s.db.LastIntegrityReport(); report != nil {
+			health.Integrity = &api.IntegrityStatus{
+				CheckedAt:           report.CheckedAt,
+				OrphanedBasketLines: report.OrphanedBasketLines,
+				BasketMismatches:    report.BasketMismatches,
+				InvalidSales:        report.InvalidSales,
+			}
+			if quarantined, err := s.db.CountQuarantined(); err == nil {
+				health.Integrity.Quarantined = quarantined
+			}
+		}
+	}
+
+	message := "Service is healthy"
+	if !health.Healthy {
+		message = "Service is unhealthy: see the failed checks"
+	}
+	if s.config.SafeMode {
+		health.SafeMode = true
+		message = "Service is running in safe mode after repeated crashes"
 	}
 
 	response := api.NewSuccessResponse(
 		api.CodeSuccess,
-		"Service is healthy",
+		message,
 		health,
 	)
 
@@ -197,23 +675,42 @@ func (s *Server) handleHealth(c *fiber.Ctx) error {
 
 // handleStatus handles status requests
 func (s *Server) handleStatus(c *fiber.Ctx) error {
-	status := api.ServiceStatus{
-		Status:         "running",
-		LastSyncTime:   time.Now().Add(-5 * time.Minute).Format(time.RFC3339),
-		OfflineHours:   0,
-		IsHealthy:      true,
-		WindowsService: "running",
-	}
-
 	response := api.NewSuccessResponse(
 		api.CodeSuccess,
 		"Status retrieved successfully",
-		status,
+		s.serviceStatus(),
 	)
 
 	return c.JSON(response)
 }
 
+// serviceStatus is the state GET /status reports
+func (s *Server) serviceStatus() api.ServiceStatus {
+	now := time.Now()
+	status := api.ServiceStatus{
+		Status:         "running",
+		OfflineHours:   int(s.offlineFor(now).Hours()),
+		IsHealthy:      true,
+		WindowsService: "running",
+		SyncOffsetMs:   s.config.SyncSchedule.Offset.Milliseconds(),
+	}
+	// Left empty until the first successful sync
+	if at := s.lastSync(); !at.IsZero() {
+		status.LastSyncTime = at.UTC().Format(time.RFC3339)
+	}
+	if s.config.SyncSchedule.Interval > 0 {
+		status.NextSyncTime = s.config.SyncSchedule.Next(now).UTC().Format(time.RFC3339)
+	}
+	if s.offlineMode() {
+		status.Status = "offline"
+		status.IsHealthy = false
+	}
+	for _, p := range s.peripherals.Statuses() {
+		status.Peripherals = append(status.Peripherals, peripheralStatus(p))
+	}
+	return status
+}
+
 // handleGetConfig handles config retrieval requests
 func (s *Server) handleGetConfig(c *fiber.Ctx) error {
 	config := map[string]interface{}{
@@ -222,6 +719,15 @@ func (s *Server) handleGetConfig(c *fiber.Ctx) error {
 		"read_timeout": s.config.ReadTimeout.String(),
 	}
 
+	// The config is its own version
+	version, err := json.Marshal(config)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if s.notModified(c, "config", string(version)) {
+		return nil
+	}
+
 	response := api.NewSuccessResponse(
 		api.CodeDataRetrieved,
 		"Configuration retrieved successfully",
@@ -231,40 +737,69 @@ func (s *Server) handleGetConfig(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-// handleData handles data endpoint requests
-func (s *Server) handleData(c *fiber.Ctx) error {
-	// TODO: Implement actual data handling
+// handleUpdateConfig applies a partial config update. Only the keys in the
+// body change; the result is validated and saved, hot-reloadable settings
+// take effect at once and the rest are reported as needing a restart.
+func (s *Server) handleUpdateConfig(c *fiber.Ctx) error {
+	if s.config.UpdateConfig == nil {
+		return apperr.Unavailable("Configuration updates are not available", time.Minute)
+	}
 
-	response := api.NewSuccessResponse(
-		api.CodeDataCreated,
-		"Data processed successfully",
-		map[string]string{
-			"status": "processed",
-		},
-	)
+	report, err := s.config.UpdateConfig(c.Body())
+	if errors.Is(err, config.ErrInvalid) {
+		return apperr.New(fiber.StatusBadRequest, api.CodeErrorConfig, err.Error())
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
 
-	return c.JSON(response)
+	message := "Configuration updated"
+	if len(report.RestartRequired) > 0 {
+		message = "Configuration updated; restart the service to apply the remaining changes"
+	}
+	return c.JSON(api.NewSuccessResponse(api.CodeConfigUpdated, message, report))
 }
 
 // handleSync handles sync requests
 func (s *Server) handleSync(c *fiber.Ctx) error {
-	// TODO: Implement actual sync logic
+	report := s.syncNow()
+	result := map[string]interface{}{
+		"synced_at":      time.Now().Format(time.RFC3339),
+		"records_synced": report.Records,
+	}
 
 	response := api.NewSuccessResponse(
 		api.CodeSyncSuccess,
 		"Sync completed successfully",
-		map[string]interface{}{
-			"synced_at":    time.Now().Format(time.RFC3339),
-			"records_synced": 0,
-		},
+		result,
 	)
 
 	return c.JSON(response)
 }
 
-// handleServiceStart handles service start requests
+// syncNow runs a sync, publishing its progress on the events bus
+func (s *Server) syncNow() possync.ProgressReport {
+	return s.runSync(possync.StartTracker(s.events, possync.ModeRegular, 0))
+}
+
+// runSync runs the sync tracker reports the progress of
+func (s *Server) runSync(tracker *possync.Tracker) possync.ProgressReport {
+	// TODO: Implement actual sync logic, reporting each uploaded batch
+	// through tracker.Batch, and pass its result to recordSync. Until then
+	// nothing reaches head office, so the run is not recorded: it must not
+	// end offline mode or count as the bootstrap sync.
+
+	return tracker.Finish(nil)
+}
+
+// handleServiceStart starts the installed service
 func (s *Server) handleServiceStart(c *fiber.Ctx) error {
-	// TODO: Implement actual service start logic
+	if s.config.Service == nil {
+		return apperr.Unavailable("Service control is not available", time.Minute)
+	}
+	if err := s.config.Service.Start(); err != nil {
+		return serviceError(err)
+	}
 
 	response := api.NewSuccessResponse(
 		api.CodeServiceStarted,
@@ -277,32 +812,57 @@ func (s *Server) handleServiceStart(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-// handleServiceStop handles service stop requests
+// handleServiceStop schedules a service stop and answers 202 before it
+// happens
 func (s *Server) handleServiceStop(c *fiber.Ctx) error {
-	// TODO: Implement actual service stop logic
+	if s.config.Service == nil {
+		return apperr.Unavailable("Service control is not available", time.Minute)
+	}
+	if err := s.config.Service.ScheduleStop(serviceActionDelay); err != nil {
+		return serviceError(err)
+	}
 
 	response := api.NewSuccessResponse(
 		api.CodeServiceStopped,
-		"Service stopped successfully",
+		"Service is stopping",
 		map[string]string{
-			"status": "stopped",
+			"status": "stopping",
 		},
 	)
 
-	return c.JSON(response)
+	return c.Status(fiber.StatusAccepted).JSON(response)
 }
 
-// handleServiceRestart handles service restart requests
+// handleServiceRestart schedules a service restart and answers 202 before
+// it happens
 func (s *Server) handleServiceRestart(c *fiber.Ctx) error {
-	// TODO: Implement actual service restart logic
+	if s.config.Service == nil {
+		return apperr.Unavailable("Service control is not available", time.Minute)
+	}
+	if err := s.config.Service.ScheduleRestart(serviceActionDelay); err != nil {
+		return serviceError(err)
+	}
 
 	response := api.NewSuccessResponse(
 		api.CodeServiceRestarted,
-		"Service restarted successfully",
+		"Service is restarting",
 		map[string]string{
-			"status": "restarted",
+			"status": "restarting",
 		},
 	)
 
-	return c.JSON(response)
+	return c.Status(fiber.StatusAccepted).JSON(response)
+}
+
+// serviceError maps a ServiceControl error to a response: 409 when the
+// service is not in a state to act on, 403 when the OS refused
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInteractive), errors.Is(err, service.ErrNotInstalled), errors.Is(err, service.ErrRunning):
+		return apperr.New(fiber.StatusConflict, api.CodeErrorService, "Service action not permitted: "+err.Error())
+	case errors.Is(err, os.ErrPermission):
+		return apperr.New(fiber.StatusForbidden, api.CodeErrorService, "Service action denied by the operating system")
+	default:
+		return apperr.New(fiber.StatusInternalServerError, api.CodeErrorService, "Service action failed: "+err.Error())
+	}
 }
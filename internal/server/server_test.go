@@ -3,15 +3,129 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/config"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/service"
+	possync "github.com/professor93/promo-pos/internal/sync"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// testSecret signs the frontend JWTs of test servers without a database
+const testSecret = "test-api-secret-0123456789abcdef"
+
+// adminTokens caches the admin token of each server
+var adminTokens sync.Map
+
+// send runs req through server's app with the credentials it carries, if
+// any (see asAdmin and asLane)
+func send(server *Server, req *http.Request, msTimeout ...int) (*http.Response, error) {
+	return server.GetApp().Test(req, msTimeout...)
+}
+
+// asAdmin presents an admin token of server on req
+func asAdmin(t testing.TB, server *Server, req *http.Request) *http.Request {
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+adminBearer(t, server))
+	return req
+}
+
+// testAdminPassword is the admin password withAdminPassword sets
+const testAdminPassword = "correct horse battery"
+
+// testAdminPasswordHash is testAdminPassword hashed at the lowest bcrypt
+// cost, stored directly so tests skip the full cost auth.SetAdminPassword
+// pays
+var testAdminPasswordHash, _ = bcrypt.GenerateFromPassword([]byte(testAdminPassword), bcrypt.MinCost)
+
+// withAdminPassword presents an admin token and the admin password on req,
+// setting the password on server first if it has none: destructive routes
+// refuse to run without one
+func withAdminPassword(t testing.TB, server *Server, req *http.Request) *http.Request {
+	t.Helper()
+	set, err := auth.HasAdminPassword(server.db)
+	if err != nil {
+		t.Fatalf("HasAdminPassword failed: %v", err)
+	}
+	if !set {
+		if err := server.db.SetSetting("auth.admin_password", string(testAdminPasswordHash)); err != nil {
+			t.Fatalf("Failed to set the admin password: %v", err)
+		}
+	}
+	req.Header.Set(HeaderAdminPassword, testAdminPassword)
+	return asAdmin(t, server, req)
+}
+
+// asLane presents a frontend JWT with server's lane role on req, for
+// servers without a database
+func asLane(t testing.TB, server *Server, req *http.Request) *http.Request {
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+laneBearer(t, server))
+	return req
+}
+
+// adminBearer returns an admin token of server, failing t if none can be
+// issued
+func adminBearer(t testing.TB, server *Server) string {
+	t.Helper()
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Failed to issue an admin token: %v", err)
+	}
+	return token
+}
+
+// laneBearer returns a frontend JWT with server's lane role, failing t if
+// none can be signed
+func laneBearer(t testing.TB, server *Server) string {
+	t.Helper()
+	token, err := laneToken(server)
+	if err != nil {
+		t.Fatalf("Failed to sign a frontend token: %v", err)
+	}
+	return token
+}
+
+// adminToken issues a stored admin token on server. A JWT cannot carry
+// the admin role, so server needs a database.
+func adminToken(server *Server) (string, error) {
+	if token, ok := adminTokens.Load(server); ok {
+		return token.(string), nil
+	}
+	if server.db == nil {
+		return "", errors.New("admin tokens need a database")
+	}
+
+	token, err := auth.Issue(server.db, auth.RoleAdmin, "test", 0)
+	if err != nil {
+		return "", err
+	}
+	adminTokens.Store(server, token)
+	return token, nil
+}
+
+// laneToken signs a frontend JWT with server's lane role, enabling JWTs
+// on servers without a secret
+func laneToken(server *Server) (string, error) {
+	if len(server.config.APISecret) == 0 {
+		server.config.APISecret = []byte(testSecret)
+	}
+	token, _, err := auth.IssueJWT(server.config.APISecret, server.laneRole(), "test", time.Hour)
+	return token, err
+}
+
 func TestNew(t *testing.T) {
 	server := New(nil)
 	if server == nil {
@@ -44,12 +158,19 @@ func TestNew_CustomConfig(t *testing.T) {
 	}
 }
 
+func TestStart_RefusesLANWithoutDatabase(t *testing.T) {
+	server := New(&Config{Port: 9091, BindAddress: constants.BindAll})
+	if err := server.Start(); err == nil {
+		server.Shutdown()
+		t.Fatal("Expected a LAN listener without a database to be refused")
+	}
+}
+
 func TestHealthEndpoint(t *testing.T) {
 	server := New(nil)
-	app := server.GetApp()
 
 	req := httptest.NewRequest("GET", "/health", nil)
-	resp, err := app.Test(req)
+	resp, err := send(server, asLane(t, server, req))
 	if err != nil {
 		t.Fatalf("Request failed: %v", err)
 	}
@@ -86,12 +207,71 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestHealthEndpoint_Checks(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.CheckConfig = func() error { return errors.New("cipher: message authentication failed") }
+	server.config.SyncSchedule.Interval = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+
+	resp, result := laneRequest(t, server, "GET", "/health", adminBearer(t, server), "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Health returned %d", resp.StatusCode)
+	}
+	var health api.HealthCheck
+	if err := json.Unmarshal(result, &health); err != nil {
+		t.Fatalf("Failed to parse health: %v", err)
+	}
+
+	checks := health.Checks
+	if checks.Database.Status != api.ProbeOK || !health.DatabaseOK || checks.Database.At == "" {
+		t.Errorf("Expected the database probe to pass: %+v", checks.Database)
+	}
+	if checks.Config.Status != api.ProbeFailed || health.ConfigOK || health.Healthy {
+		t.Errorf("Expected an undecryptable config to fail the service: %+v", health)
+	}
+	if checks.LastSync.Status != api.ProbeDegraded || checks.LastSync.AgeSeconds == nil || checks.LastSync.At != "" {
+		t.Errorf("Expected a late first sync to degrade last_sync: %+v", checks.LastSync)
+	}
+	if checks.DiskSpace.FreeBytes == nil {
+		t.Errorf("Expected free space reported: %+v", checks.DiskSpace)
+	}
+
+	// A successful sync brings last_sync and backend_contact back
+	server.recordSync(possync.ProgressReport{})
+	server.config.SyncSchedule.Interval = time.Hour
+	_, result = laneRequest(t, server, "GET", "/health", adminBearer(t, server), "")
+	json.Unmarshal(result, &health)
+	if health.Checks.LastSync.Status != api.ProbeOK || health.Checks.BackendContact.Status != api.ProbeOK {
+		t.Errorf("Expected sync probes to pass after a sync: %+v", health.Checks)
+	}
+}
+
+func TestHealthEndpoint_SafeMode(t *testing.T) {
+	server := New(&Config{SafeMode: true})
+
+	resp, err := send(server, asLane(t, server, httptest.NewRequest("GET", "/health", nil)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		Result api.HealthCheck `json:"result"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !apiResp.Result.SafeMode {
+		t.Errorf("Expected /health to report safe mode: %s", body)
+	}
+}
+
 func TestStatusEndpoint(t *testing.T) {
 	server := New(nil)
-	app := server.GetApp()
 
 	req := httptest.NewRequest("GET", "/status", nil)
-	resp, err := app.Test(req)
+	resp, err := send(server, asLane(t, server, req))
 	if err != nil {
 		t.Fatalf("Request failed: %v", err)
 	}
@@ -119,40 +299,39 @@ func TestStatusEndpoint(t *testing.T) {
 	}
 }
 
-func TestConfigEndpoint(t *testing.T) {
-	server := New(nil)
-	app := server.GetApp()
+func TestStatusEndpoint_SyncSchedule(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyncSchedule = possync.NewSchedule("machine-1", time.Minute)
+	server := New(cfg)
 
-	req := httptest.NewRequest("GET", "/config", nil)
-	resp, err := app.Test(req)
+	resp, err := send(server, asLane(t, server, httptest.NewRequest("GET", "/status", nil)))
 	if err != nil {
 		t.Fatalf("Request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Parse response
-	var apiResp api.APIResponse
+	var envelope struct {
+		Result api.ServiceStatus `json:"result"`
+	}
 	body, _ := io.ReadAll(resp.Body)
-	if err := json.Unmarshal(body, &apiResp); err != nil {
+	if err := json.Unmarshal(body, &envelope); err != nil {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	// Verify response
-	if !apiResp.OK {
-		t.Error("Expected OK to be true")
+	if envelope.Result.SyncOffsetMs != cfg.SyncSchedule.Offset.Milliseconds() {
+		t.Errorf("Expected offset %d ms, got %d", cfg.SyncSchedule.Offset.Milliseconds(), envelope.Result.SyncOffsetMs)
 	}
-
-	if apiResp.Code != api.CodeDataRetrieved {
-		t.Errorf("Expected code %d, got %d", api.CodeDataRetrieved, apiResp.Code)
+	next, err := time.Parse(time.RFC3339, envelope.Result.NextSyncTime)
+	if err != nil || time.Until(next) > time.Minute {
+		t.Errorf("Expected the next sync within a minute, got %q", envelope.Result.NextSyncTime)
 	}
 }
 
-func TestDataEndpoint(t *testing.T) {
+func TestConfigEndpoint(t *testing.T) {
 	server := New(nil)
-	app := server.GetApp()
 
-	req := httptest.NewRequest("POST", "/data", nil)
-	resp, err := app.Test(req)
+	req := httptest.NewRequest("GET", "/config", nil)
+	resp, err := send(server, asLane(t, server, req))
 	if err != nil {
 		t.Fatalf("Request failed: %v", err)
 	}
@@ -170,45 +349,93 @@ func TestDataEndpoint(t *testing.T) {
 		t.Error("Expected OK to be true")
 	}
 
-	if apiResp.Code != api.CodeDataCreated {
-		t.Errorf("Expected code %d, got %d", api.CodeDataCreated, apiResp.Code)
+	if apiResp.Code != api.CodeDataRetrieved {
+		t.Errorf("Expected code %d, got %d", api.CodeDataRetrieved, apiResp.Code)
 	}
 }
 
-func TestSyncEndpoint(t *testing.T) {
-	server := New(nil)
-	app := server.GetApp()
+func TestDataEndpoint(t *testing.T) {
+	server := newTestServerWithLedger(t)
 
-	req := httptest.NewRequest("POST", "/sync", nil)
-	resp, err := app.Test(req)
-	if err != nil {
-		t.Fatalf("Request failed: %v", err)
+	resp, data := cartRequest(t, server, "POST", "/data", `{"type":"product","data":{"barcode":"4006381333931","sku":"PEN","name":"Pen","price":250,"tax_rate":1200}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for a product, got %d", resp.StatusCode)
+	}
+	var stored struct {
+		Type   string           `json:"type"`
+		ID     string           `json:"id"`
+		Record database.Product `json:"record"`
+	}
+	json.Unmarshal(data, &stored)
+	if stored.Type != DataTypeProduct || stored.ID == "" || stored.Record.ID != stored.ID || !stored.Record.Active {
+		t.Errorf("Unexpected stored product: %s", data)
+	}
+	if product, err := server.db.GetProductByBarcode("4006381333931"); err != nil || product.Name != "Pen" {
+		t.Errorf("Expected the product to be persisted (%v)", err)
 	}
-	defer resp.Body.Close()
 
-	// Parse response
-	var apiResp api.APIResponse
-	body, _ := io.ReadAll(resp.Body)
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
+	sale := `{"type":"sale","data":{"id":"S-1","lines":[{"sku":"PEN","quantity":2,"price":250}],"total":500}}`
+	resp, data = cartRequest(t, server, "POST", "/data", sale)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for a sale, got %d: %s", resp.StatusCode, data)
+	}
+	if got, err := server.db.GetSale("S-1"); err != nil || got.TerminalID != "T1" {
+		t.Errorf("Expected the sale to be persisted for T1 (%v)", err)
+	}
+	resp, data = cartRequest(t, server, "POST", "/data", sale)
+	var retry DataResponse
+	json.Unmarshal(data, &retry)
+	if resp.StatusCode != http.StatusOK || !retry.Duplicate {
+		t.Errorf("Expected a retried sale to be reported as a duplicate (%d): %s", resp.StatusCode, data)
 	}
 
-	// Verify response
-	if !apiResp.OK {
-		t.Error("Expected OK to be true")
+	resp, data = cartRequest(t, server, "POST", "/data", `{"type":"stock_adjustment","data":{"sku":"PEN","delta":10,"reason":"count"}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for a stock adjustment, got %d", resp.StatusCode)
+	}
+	var adj struct {
+		Record StoredStockAdjustment `json:"record"`
+	}
+	json.Unmarshal(data, &adj)
+	if adj.Record.StockAdjustment == nil || adj.Record.ID == "" || adj.Record.Quantity != 8 {
+		t.Errorf("Expected 8 on hand after selling 2 and counting 10 in: %s", data)
 	}
 
-	if apiResp.Code != api.CodeSyncSuccess {
-		t.Errorf("Expected code %d, got %d", api.CodeSyncSuccess, apiResp.Code)
+	// Every record reached the sync outbox
+	entries, err := server.db.GetPendingOutbox(100)
+	if err != nil {
+		t.Fatalf("GetPendingOutbox failed: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		seen[e.Entity] = true
+	}
+	for _, entity := range []string{"products", "sales", "stock_adjustments"} {
+		if !seen[entity] {
+			t.Errorf("Expected a %s entry in the outbox", entity)
+		}
+	}
+
+	for _, body := range []string{
+		``,
+		`{"type":"coupon","data":{}}`,
+		`{"type":"product"}`,
+		`{"type":"product","data":{"barcode":"1","name":"X","price":-1}}`,
+		`{"type":"product","data":{"barcode":"1","name":"X","colour":"red"}}`,
+		`{"type":"sale","data":{"lines":[{"sku":"PEN","quantity":1,"price":250}],"total":1}}`,
+		`{"type":"stock_adjustment","data":{"sku":"PEN","delta":0}}`,
+	} {
+		if resp, _ := cartRequest(t, server, "POST", "/data", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", body, resp.StatusCode)
+		}
 	}
 }
 
-func TestServiceStartEndpoint(t *testing.T) {
+func TestSyncEndpoint(t *testing.T) {
 	server := New(nil)
-	app := server.GetApp()
 
-	req := httptest.NewRequest("POST", "/service/start", nil)
-	resp, err := app.Test(req)
+	req := httptest.NewRequest("POST", "/sync", nil)
+	resp, err := send(server, asLane(t, server, req))
 	if err != nil {
 		t.Fatalf("Request failed: %v", err)
 	}
@@ -226,73 +453,151 @@ func TestServiceStartEndpoint(t *testing.T) {
 		t.Error("Expected OK to be true")
 	}
 
-	if apiResp.Code != api.CodeServiceStarted {
-		t.Errorf("Expected code %d, got %d", api.CodeServiceStarted, apiResp.Code)
+	if apiResp.Code != api.CodeSyncSuccess {
+		t.Errorf("Expected code %d, got %d", api.CodeSyncSuccess, apiResp.Code)
 	}
 }
 
-func TestServiceStopEndpoint(t *testing.T) {
-	server := New(nil)
-	app := server.GetApp()
+// fakeService records service actions instead of performing them
+type fakeService struct {
+	err     error
+	actions []string
+}
 
-	req := httptest.NewRequest("POST", "/service/stop", nil)
-	resp, err := app.Test(req)
-	if err != nil {
-		t.Fatalf("Request failed: %v", err)
-	}
-	defer resp.Body.Close()
+func (f *fakeService) Start() error {
+	f.actions = append(f.actions, "start")
+	return f.err
+}
 
-	// Parse response
-	var apiResp api.APIResponse
-	body, _ := io.ReadAll(resp.Body)
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
+func (f *fakeService) ScheduleStop(time.Duration) error {
+	f.actions = append(f.actions, "stop")
+	return f.err
+}
+
+func (f *fakeService) ScheduleRestart(time.Duration) error {
+	f.actions = append(f.actions, "restart")
+	return f.err
+}
+
+func TestServiceEndpoints(t *testing.T) {
+	svc := &fakeService{}
+	cfg := DefaultConfig()
+	cfg.DB = newTestDB(t)
+	cfg.Service = svc
+	server := New(cfg)
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		code   int
+	}{
+		{"/service/start", http.StatusOK, api.CodeServiceStarted},
+		{"/service/stop", http.StatusAccepted, api.CodeServiceStopped},
+		{"/service/restart", http.StatusAccepted, api.CodeServiceRestarted},
+	} {
+		req := httptest.NewRequest("POST", tc.path, nil)
+		resp, err := send(server, withAdminPassword(t, server, req))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		var apiResp api.APIResponse
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err := json.Unmarshal(body, &apiResp); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if resp.StatusCode != tc.status || !apiResp.OK || apiResp.Code != tc.code {
+			t.Errorf("%s: expected %d with code %d, got %d: %s", tc.path, tc.status, tc.code, resp.StatusCode, body)
+		}
+	}
+
+	if strings.Join(svc.actions, ",") != "start,stop,restart" {
+		t.Errorf("Expected start, stop and restart to reach the service, got %v", svc.actions)
 	}
+}
 
-	// Verify response
-	if !apiResp.OK {
-		t.Error("Expected OK to be true")
+func TestServiceEndpoints_Errors(t *testing.T) {
+	// Without a service manager the endpoints are unavailable
+	server := newTestServerWithDB(t)
+	resp, _ := guardedRequest(t, server, "POST", "/service/stop", "")
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a service manager, got %d", resp.StatusCode)
 	}
 
-	if apiResp.Code != api.CodeServiceStopped {
-		t.Errorf("Expected code %d, got %d", api.CodeServiceStopped, apiResp.Code)
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{service.ErrRunning, http.StatusConflict},
+		{service.ErrInteractive, http.StatusConflict},
+		{service.ErrNotInstalled, http.StatusConflict},
+		{fmt.Errorf("open service: %w", os.ErrPermission), http.StatusForbidden},
+		{errors.New("timeout"), http.StatusInternalServerError},
+	} {
+		cfg := DefaultConfig()
+		cfg.DB = newTestDB(t)
+		cfg.Service = &fakeService{err: tc.err}
+		server := New(cfg)
+		resp, err := send(server, withAdminPassword(t, server, httptest.NewRequest("POST", "/service/start", nil)))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		var apiResp api.APIResponse
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		json.Unmarshal(body, &apiResp)
+		if resp.StatusCode != tc.status || apiResp.Code != api.CodeErrorService {
+			t.Errorf("%v: expected %d with code %d, got %d: %s", tc.err, tc.status, api.CodeErrorService, resp.StatusCode, body)
+		}
 	}
 }
 
-func TestServiceRestartEndpoint(t *testing.T) {
-	server := New(nil)
-	app := server.GetApp()
+func TestFrontendToken_Issue(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	cfg := DefaultConfig()
+	cfg.APISecret = []byte(secret)
+	server := New(cfg)
 
-	req := httptest.NewRequest("POST", "/service/restart", nil)
-	resp, err := app.Test(req)
-	if err != nil {
-		t.Fatalf("Request failed: %v", err)
+	if resp, _ := anonymousRequest(t, server, "POST", "/auth/token", `{"secret":"wrong"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong secret, got %d", resp.StatusCode)
 	}
-	defer resp.Body.Close()
 
-	// Parse response
-	var apiResp api.APIResponse
-	body, _ := io.ReadAll(resp.Body)
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
+	resp, data := anonymousRequest(t, server, "POST", "/auth/token", `{"secret":"`+secret+`"}`)
+	var issued FrontendToken
+	json.Unmarshal(data, &issued)
+	if resp.StatusCode != http.StatusOK || issued.Token == "" {
+		t.Fatalf("Expected a frontend token (%d): %s", resp.StatusCode, data)
 	}
 
-	// Verify response
-	if !apiResp.OK {
-		t.Error("Expected OK to be true")
+	if resp, _ := laneRequest(t, server, "GET", "/config", issued.Token, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with a frontend token, got %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, "GET", "/config", issued.Token+"x", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a tampered token, got %d", resp.StatusCode)
 	}
 
-	if apiResp.Code != api.CodeServiceRestarted {
-		t.Errorf("Expected code %d, got %d", api.CodeServiceRestarted, apiResp.Code)
+	// Anyone with the secret can sign claims, so a JWT never grants more
+	// than the lane's role
+	forged, _, err := auth.IssueJWT([]byte(secret), auth.RoleAdmin, auth.FrontendSubject, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	if resp, _ := laneRequest(t, server, "POST", "/service/stop", forged, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a self-signed admin JWT, got %d", resp.StatusCode)
+	}
+	server.config.Profile = constants.LaneProfileSelfCheckout
+	if resp, _ := laneRequest(t, server, "GET", "/config", issued.Token, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a staff JWT on a self-checkout lane, got %d", resp.StatusCode)
 	}
 }
 
 func TestNotFoundEndpoint(t *testing.T) {
 	server := New(nil)
-	app := server.GetApp()
 
 	req := httptest.NewRequest("GET", "/nonexistent", nil)
-	resp, err := app.Test(req)
+	resp, err := send(server, asLane(t, server, req))
 	if err != nil {
 		t.Fatalf("Request failed: %v", err)
 	}
@@ -322,7 +627,6 @@ func TestNotFoundEndpoint(t *testing.T) {
 
 func TestAPIResponseFormat(t *testing.T) {
 	server := New(nil)
-	app := server.GetApp()
 
 	endpoints := []struct {
 		method string
@@ -341,7 +645,7 @@ func TestAPIResponseFormat(t *testing.T) {
 	for _, ep := range endpoints {
 		t.Run(ep.path, func(t *testing.T) {
 			req := httptest.NewRequest(ep.method, ep.path, nil)
-			resp, err := app.Test(req)
+			resp, err := send(server, asLane(t, server, req))
 			if err != nil {
 				t.Fatalf("Request failed: %v", err)
 			}
@@ -401,22 +705,76 @@ func TestGracefulShutdown(t *testing.T) {
 
 func BenchmarkHealthEndpoint(b *testing.B) {
 	server := New(nil)
-	app := server.GetApp()
+
+	token := laneBearer(b, server)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		req := httptest.NewRequest("GET", "/health", nil)
-		app.Test(req)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		send(server, req)
 	}
 }
 
 func BenchmarkStatusEndpoint(b *testing.B) {
 	server := New(nil)
-	app := server.GetApp()
+
+	token := laneBearer(b, server)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		req := httptest.NewRequest("GET", "/status", nil)
-		app.Test(req)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		send(server, req)
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	server := newTestServerWithDB(t)
+	if resp, _ := guardedRequest(t, server, "PUT", "/config", `{"log_level": "debug"}`); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("PUT /config without a config manager returned %d, want 503", resp.StatusCode)
+	}
+
+	var got string
+	server.config.UpdateConfig = func(patch []byte) (*config.UpdateReport, error) {
+		got = string(patch)
+		if strings.Contains(got, "loud") {
+			return nil, fmt.Errorf("%w: invalid log_level", config.ErrInvalid)
+		}
+		return &config.UpdateReport{Changed: []string{"log_level", "port"}, Applied: []string{"log_level"}, RestartRequired: []string{"port"}}, nil
+	}
+
+	resp, result := guardedRequest(t, server, "PUT", "/config", `{"log_level": "debug", "port": 9090}`)
+	var report config.UpdateReport
+	json.Unmarshal(result, &report)
+	if resp.StatusCode != http.StatusOK || len(report.RestartRequired) != 1 || got != `{"log_level": "debug", "port": 9090}` {
+		t.Errorf("PUT /config returned %d with %+v", resp.StatusCode, report)
+	}
+	if resp, _ := guardedRequest(t, server, "PUT", "/config", `{"log_level": "loud"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid update returned %d, want 400", resp.StatusCode)
+	}
+
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "c1", 0)
+	if resp, _ := laneRequest(t, server, "PUT", "/config", cashier, `{"log_level": "debug"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier PUT /config returned %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestCORS_OnlyConfiguredFrontends(t *testing.T) {
+	server := New(&Config{AllowedOrigins: []string{"http://localhost:3000"}})
+
+	for origin, want := range map[string]string{
+		"http://localhost:3000": "http://localhost:3000",
+		"http://evil.example":   "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := send(server, asLane(t, server, req))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("Origin %s: expected Access-Control-Allow-Origin %q, got %q", origin, want, got)
+		}
 	}
 }
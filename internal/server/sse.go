@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /sync/events streams sync progress as Server-Sent Events, for the
+// admin UI's progress bar: sync.started, one sync.progress per batch and
+// sync.finished, each carrying a sync.ProgressReport. Other bus events stay
+// on /ws/status. Plain HTTP keeps it usable from an EventSource without a
+// WebSocket.
+
+const (
+	// sseKeepAlive paces the comments that keep idle proxies from closing
+	// the stream; a client gone this long is noticed on the next one
+	sseKeepAlive = 15 * time.Second
+
+	// sseRetry is the reconnect delay suggested to EventSource clients
+	sseRetry = 3 * time.Second
+
+	// sseEventBuffer is the events a slow client may fall behind by; a
+	// bootstrap sync publishes one per batch
+	sseEventBuffer = 256
+)
+
+// handleSyncEvents streams sync events until the client goes away or the
+// server shuts down
+func (s *Server) handleSyncEvents(c *fiber.Ctx) error {
+	// The HTTP server's write timeout would cut the stream; each write
+	// extends the deadline instead
+	conn := c.Context().Conn()
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		stream, unsubscribe := s.events.Subscribe(sseEventBuffer)
+		defer unsubscribe()
+
+		write := func(chunk string) bool {
+			conn.SetWriteDeadline(time.Now().Add(2 * sseKeepAlive))
+			if _, err := w.WriteString(chunk); err != nil {
+				return false
+			}
+			return w.Flush() == nil
+		}
+
+		if !write("retry: " + strconv.FormatInt(sseRetry.Milliseconds(), 10) + "\n\n") {
+			return
+		}
+
+		ticker := time.NewTicker(sseKeepAlive)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event, ok := <-stream:
+				if !ok {
+					return
+				}
+				if !strings.HasPrefix(event.Type, "sync.") {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				if !write("event: " + event.Type + "\ndata: " + string(data) + "\n\n") {
+					return
+				}
+			case <-ticker.C:
+				if !write(": keep-alive\n\n") {
+					return
+				}
+			case <-s.streamsDone:
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/events"
+	possync "github.com/professor93/promo-pos/internal/sync"
+)
+
+// readSSE reads the next server-sent event, skipping comments and fields
+// other than event and data
+func readSSE(t *testing.T, r *bufio.Reader) (string, string) {
+	var eventType, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && eventType != "":
+			return eventType, data
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestSyncEvents_StreamsProgress(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.GetApp().Listener(ln)
+	t.Cleanup(func() { server.Shutdown() })
+
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + ln.Addr().String() + APIPrefix + "/sync/events?ticket=" + issueStreamTicket(t, server, token))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Got %d %q, want 200 text/event-stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+	// The retry hint is written once the subscription is in place
+	if line, err := reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, "retry: ") {
+		t.Fatalf("Expected a retry hint first, got %q, %v", line, err)
+	}
+
+	// Only sync events are streamed
+	server.events.Publish(events.OfflineChanged, nil)
+	tracker := possync.StartTracker(server.events, possync.ModeBootstrap, 200)
+	tracker.Batch(150)
+	tracker.Batch(50)
+	tracker.Finish(nil)
+
+	want := []string{events.SyncStarted, events.SyncProgress, events.SyncProgress, events.SyncFinished}
+	for i, w := range want {
+		eventType, data := readSSE(t, reader)
+		var event struct {
+			Type string                 `json:"type"`
+			Data possync.ProgressReport `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Failed to parse event %q: %v", data, err)
+		}
+		if eventType != w || event.Type != w || event.Data.Mode != possync.ModeBootstrap {
+			t.Errorf("Event %d: got %s %+v, want %s", i, eventType, event, w)
+		}
+		if i == 1 && (event.Data.Batches != 1 || event.Data.Percent != 75) {
+			t.Errorf("Unexpected first batch %+v", event.Data)
+		}
+	}
+
+	// POST /sync reports through the same stream
+	if _, err := send(server, asAdmin(t, server, httptest.NewRequest(http.MethodPost, "/sync", nil))); err != nil {
+		t.Fatalf("Sync request failed: %v", err)
+	}
+	if eventType, data := readSSE(t, reader); eventType != events.SyncStarted || !strings.Contains(data, possync.ModeRegular) {
+		t.Errorf("Expected a regular sync to start, got %s %s", eventType, data)
+	}
+	if eventType, _ := readSSE(t, reader); eventType != events.SyncFinished {
+		t.Errorf("Expected the regular sync to finish, got %s", eventType)
+	}
+
+	// Shutdown ends the stream
+	server.closeStreams()
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("Expected the stream to end cleanly on shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/recommend"
+)
+
+// cartIDDraft selects the calling terminal's draft as the cart
+const cartIDDraft = "draft"
+
+// handleGetSuggestions evaluates synced association rules against a cart.
+// The cart is the caller's chunked cart :id if one exists, otherwise the
+// autosaved draft of terminal :id ("draft" means the caller);
+// ?skus=A,B evaluates an explicit list instead. Everything is answered
+// locally so suggestions keep working offline.
+func (s *Server) handleGetSuggestions(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	skus, err := s.cartSKUs(c, db)
+	if err != nil {
+		return err
+	}
+
+	rules, err := db.GetRecommendationRules()
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	suggestions := recommend.Suggest(rules, skus, time.Now(), c.QueryInt("limit", recommend.DefaultLimit))
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Suggestions retrieved successfully", suggestions))
+}
+
+// cartSKUs returns the SKUs in the requested cart
+func (s *Server) cartSKUs(c *fiber.Ctx, db *database.DB) ([]string, error) {
+	if list := c.Query("skus"); list != "" {
+		var skus []string
+		for _, sku := range strings.Split(list, ",") {
+			if sku = strings.TrimSpace(sku); sku != "" {
+				skus = append(skus, sku)
+			}
+		}
+		return skus, nil
+	}
+
+	id := c.Params("id")
+	if id == cartIDDraft {
+		var err error
+		if id, err = terminalID(c); err != nil {
+			return nil, err
+		}
+	} else if !terminalIDPattern.MatchString(id) {
+		return nil, apperr.BadRequest("Invalid cart ID")
+	} else if totals, err := ownedBasket(c, db); err == nil {
+		lines, err := db.GetBasketLines(totals.BasketID, 0, 0)
+		if err != nil {
+			return nil, apperr.Database(err)
+		}
+		skus := make([]string, len(lines))
+		for i, line := range lines {
+			skus[i] = line.SKU
+		}
+		return skus, nil
+	}
+
+	var draft Draft
+	if err := db.GetSettingJSON(draftKeyPrefix+id, &draft); err != nil {
+		if errors.Is(err, database.ErrSettingNotFound) {
+			return nil, apperr.NotFound("No draft saved for this terminal")
+		}
+		return nil, apperr.Database(err)
+	}
+
+	return draftSKUs(draft.Payload), nil
+}
+
+// draftSKUs extracts line SKUs from a draft payload. The payload is owned by
+// the frontend, so anything not shaped like {"lines":[{"sku":...}]} yields
+// an empty cart rather than an error.
+func draftSKUs(payload json.RawMessage) []string {
+	var cart struct {
+		Lines []struct {
+			SKU string `json:"sku"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal(payload, &cart); err != nil {
+		return nil
+	}
+
+	skus := make([]string, 0, len(cart.Lines))
+	for _, line := range cart.Lines {
+		if line.SKU != "" {
+			skus = append(skus, line.SKU)
+		}
+	}
+	return skus
+}
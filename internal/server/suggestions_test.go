@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/recommend"
+)
+
+func TestSuggestions_FromDraft(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	rules := []database.RecommendationRule{
+		{ID: "r1", Antecedent: []string{"PASTA"}, Consequent: "SAUCE", Confidence: 0.6, Lift: 2, PromoID: "promo-7"},
+		{ID: "r2", Antecedent: []string{"COFFEE"}, Consequent: "MILK", Confidence: 0.8, Lift: 1.5},
+	}
+	if err := server.db.ReplaceRecommendationRules(rules); err != nil {
+		t.Fatalf("Failed to store rules: %v", err)
+	}
+
+	resp, _ := draftRequest(t, server, "PUT", "T1", `{"payload":{"lines":[{"sku":"PASTA","qty":1}]}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected draft save to succeed, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/carts/draft/suggestions", nil)
+	req.Header.Set(HeaderTerminalID, "T1")
+	resp, err := send(server, asAdmin(t, server, req))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Result []recommend.Suggestion `json:"result"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(body.Result) != 1 || body.Result[0].SKU != "SAUCE" || body.Result[0].PromoID != "promo-7" {
+		t.Errorf("Expected SAUCE suggestion, got %+v", body.Result)
+	}
+}
+
+func TestSuggestions_NoDraft(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	resp, err := send(server, asAdmin(t, server, httptest.NewRequest("GET", "/carts/T9/suggestions", nil)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
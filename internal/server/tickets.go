@@ -0,0 +1,103 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Browsers cannot set headers on WebSocket or EventSource requests, and a
+// token in the query string ends up in proxy and browser history logs. A
+// frontend instead trades its token for a ticket at POST
+// /auth/stream-ticket and opens /ws/status or /sync/events with ?ticket=.
+// A ticket works once, within StreamTicketTTLSeconds, so a logged one is
+// worthless.
+
+// StreamTicket is a one-time ticket opening a stream and its expiry
+type StreamTicket struct {
+	Ticket    string `json:"ticket"`
+	ExpiresAt string `json:"expires_at"` // ISO 8601 timestamp
+}
+
+// streamTicket is an unused ticket and the token it stands for
+type streamTicket struct {
+	token   *auth.Token
+	expires time.Time
+}
+
+// ticketStore holds the tickets not used yet
+type ticketStore struct {
+	mu      sync.Mutex
+	tickets map[string]streamTicket
+	now     func() time.Time
+}
+
+func newTicketStore() *ticketStore {
+	return &ticketStore{tickets: make(map[string]streamTicket), now: time.Now}
+}
+
+// issue returns a new ticket standing for token and when it expires
+func (ts *ticketStore) issue(token *auth.Token) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate stream ticket: %w", err)
+	}
+	ticket := hex.EncodeToString(raw)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := ts.now()
+	for t, st := range ts.tickets {
+		if !now.Before(st.expires) {
+			delete(ts.tickets, t)
+		}
+	}
+	expires := now.Add(constants.StreamTicketTTLSeconds * time.Second)
+	ts.tickets[ticket] = streamTicket{token: token, expires: expires}
+	return ticket, expires, nil
+}
+
+// redeem returns the token ticket stands for and forgets the ticket, or
+// nil if it is unknown, used or expired
+func (ts *ticketStore) redeem(ticket string) *auth.Token {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	st, ok := ts.tickets[ticket]
+	if !ok {
+		return nil
+	}
+	delete(ts.tickets, ticket)
+	if !ts.now().Before(st.expires) {
+		return nil
+	}
+	return st.token
+}
+
+// handleIssueStreamTicket trades the caller's token for a stream ticket.
+// Integrations present their API key in a header on the stream itself.
+func (s *Server) handleIssueStreamTicket(c *fiber.Ctx) error {
+	token := callerToken(c)
+	if token == nil {
+		return apperr.BadRequest("Stream tickets are issued for tokens; send the API key with the stream request")
+	}
+
+	ticket, expires, err := s.tickets.issue(token)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Stream ticket issued", StreamTicket{
+		Ticket:    ticket,
+		ExpiresAt: expires.UTC().Format(time.RFC3339),
+	}))
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// issueStreamTicket trades token for a stream ticket
+func issueStreamTicket(t *testing.T, server *Server, token string) string {
+	t.Helper()
+	resp, data := laneRequest(t, server, http.MethodPost, "/auth/stream-ticket", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Stream ticket returned %d: %s", resp.StatusCode, data)
+	}
+	var ticket StreamTicket
+	if err := json.Unmarshal(data, &ticket); err != nil || ticket.Ticket == "" {
+		t.Fatalf("Unexpected stream ticket %s: %v", data, err)
+	}
+	return ticket.Ticket
+}
+
+func TestTicketStore_OneTimeAndExpiring(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTicketStore()
+	store.now = func() time.Time { return now }
+	token := &auth.Token{Role: auth.RoleCashier}
+
+	ticket, expires, err := store.issue(token)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+	if want := now.Add(constants.StreamTicketTTLSeconds * time.Second); !expires.Equal(want) {
+		t.Errorf("Expected expiry %s, got %s", want, expires)
+	}
+	if got := store.redeem(ticket); got != token {
+		t.Fatalf("Expected the ticket's token, got %+v", got)
+	}
+	if store.redeem(ticket) != nil {
+		t.Error("Expected a used ticket to be refused")
+	}
+
+	ticket, _, _ = store.issue(token)
+	now = now.Add(constants.StreamTicketTTLSeconds * time.Second)
+	if store.redeem(ticket) != nil {
+		t.Error("Expected an expired ticket to be refused")
+	}
+}
+
+func TestStreamTicket_NeedsToken(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	if resp, _ := anonymousRequest(t, server, http.MethodPost, "/auth/stream-ticket", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Anonymous: expected 401, got %d", resp.StatusCode)
+	}
+	handheld, _ := auth.Issue(server.db, auth.RoleHandheld, "HH-1", 0)
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/auth/stream-ticket", handheld, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Handheld: expected 403, got %d", resp.StatusCode)
+	}
+}
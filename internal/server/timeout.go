@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Every request runs under a deadline on its user context, so database
+// busy retries, ledger commits and hub calls give up instead of holding a
+// connection slot that checkout needs. Handlers not waiting on the context
+// run to completion; their result is kept if they succeed.
+
+// timeoutRetryAfter is the Retry-After of a request that timed out
+const timeoutRetryAfter = 5 * time.Second
+
+// routeTimeout overrides the default deadline of the routes it matches
+type routeTimeout struct {
+	pattern *regexp.Regexp
+	timeout time.Duration // 0: no deadline
+}
+
+// routeTimeouts are checked in order; other routes get DefaultRequestTimeout.
+// Streams outlive their handler and keep no deadline.
+var routeTimeouts = []routeTimeout{
+	{regexp.MustCompile(`^/price/[^/]+$`), constants.PriceRequestTimeout * time.Second},
+	{regexp.MustCompile(`^/(sync|import)$`), constants.BulkRequestTimeout * time.Second},
+	{regexp.MustCompile(`^/changes$`), (constants.ChangesWaitSeconds + 5) * time.Second},
+	{streamRoutes, 0},
+}
+
+// timeoutFor returns the deadline of a route path (see routePath)
+func timeoutFor(path string) time.Duration {
+	for _, rt := range routeTimeouts {
+		if rt.pattern.MatchString(path) {
+			return rt.timeout
+		}
+	}
+	return constants.DefaultRequestTimeout * time.Second
+}
+
+// requestTimeout applies the route's deadline to the rest of the chain
+func requestTimeout(c *fiber.Ctx) error {
+	timeout := timeoutFor(routePath(c))
+	if timeout == 0 {
+		return c.Next()
+	}
+	return withDeadline(c, timeout)
+}
+
+// withDeadline runs the rest of the chain with a deadline on the user
+// context. A request that fails because the deadline passed answers a
+// retryable 503.
+func withDeadline(c *fiber.Ctx, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+	defer cancel()
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && apperr.From(err).Status >= fiber.StatusInternalServerError {
+		return apperr.Unavailable(fmt.Sprintf("Request timed out after %s", timeout), timeoutRetryAfter).Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/apperr"
+)
+
+func TestTimeoutFor(t *testing.T) {
+	for path, want := range map[string]time.Duration{
+		"/price/4600000000001": 2 * time.Second,
+		"/sync":                120 * time.Second,
+		"/import":              120 * time.Second,
+		"/sync/events":         0,
+		"/ws/status":           0,
+		"/carts/c1/checkout":   30 * time.Second,
+	} {
+		if got := timeoutFor(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: customErrorHandler})
+	app.Use(func(c *fiber.Ctx) error { return withDeadline(c, 20*time.Millisecond) })
+
+	// Waits on the context, as busy retries and hub calls do
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return apperr.Database(c.UserContext().Err())
+	})
+	// Ignores the context but succeeds: its response stands
+	app.Get("/late", func(c *fiber.Ctx) error {
+		time.Sleep(40 * time.Millisecond)
+		return c.SendString("done")
+	})
+	// Fails on its own before the deadline
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return apperr.NotFound("Product not found")
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		if _, ok := c.UserContext().Deadline(); !ok {
+			t.Error("Handler saw no deadline")
+		}
+		return apperr.Internal(errors.New("boom"))
+	})
+
+	get := func(path string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/slow")
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(fiber.HeaderRetryAfter) != "5" {
+		t.Errorf("Timed out: expected 503 with Retry-After 5, got %d %q", resp.StatusCode, resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+	if resp := get("/late"); resp.StatusCode != http.StatusOK {
+		t.Errorf("Late success: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := get("/missing"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Client error: expected 404, got %d", resp.StatusCode)
+	}
+	if resp := get("/broken"); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Failure within the deadline: expected 500, got %d", resp.StatusCode)
+	}
+}
+
+func TestRequestTimeout_AppliedToRoutes(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	var deadline time.Time
+	server.api.Get("/test/deadline", func(c *fiber.Ctx) error {
+		deadline, _ = c.UserContext().Deadline()
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	start := time.Now()
+	laneRequest(t, server, http.MethodGet, "/test/deadline", adminBearer(t, server), "")
+	if left := deadline.Sub(start); left < 30*time.Second || left > 31*time.Second {
+		t.Errorf("Expected the default 30s deadline, %s left", left)
+	}
+}
@@ -0,0 +1,340 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// /transactions rings up sales paid in one request, voids open transactions
+// and refunds completed ones. An open transaction is a cart being built
+// with /carts/:id/lines; a completed one is a sale committed through the
+// ledger. Sales, refunds and voids all reach the sync outbox through their
+// tables' CDC triggers.
+
+// TransactionRequest is a sale with its lines and tenders
+type TransactionRequest struct {
+	ID          string              `json:"id,omitempty"`          // Assigned when empty; send one so retries stay idempotent
+	OperatorID  string              `json:"operator_id,omitempty"` // Ignored in PIN sessions, which use the signed-in user
+	Lines       []database.SaleLine `json:"lines" validate:"required,min=1,dive"`
+	Tenders     []database.Tender   `json:"tenders" validate:"required,min=1,dive"`
+	Total       int64               `json:"total"`
+	VoidedLines int                 `json:"voided_lines,omitempty"`
+	ScanSeconds int                 `json:"scan_seconds,omitempty"`
+}
+
+// VoidRequest is the optional body of POST /transactions/:id/void
+type VoidRequest struct {
+	Reason string `json:"reason,omitempty" validate:"max=255"`
+}
+
+// RefundRequest returns goods from a completed sale
+type RefundRequest struct {
+	ID         string `json:"id,omitempty"`          // Assigned when empty
+	OperatorID string `json:"operator_id,omitempty"` // Ignored in PIN sessions, which use the signed-in user
+
+	// Lines to return (SKU and quantity; prices come from the sale).
+	// Omitted, everything not yet refunded is returned.
+	Lines   []database.SaleLine `json:"lines,omitempty" validate:"omitempty,dive"`
+	Tenders []database.Tender   `json:"tenders,omitempty" validate:"omitempty,dive"` // How the refund is paid out
+}
+
+// TransactionResponse reports a committed sale or refund
+type TransactionResponse struct {
+	*database.Sale
+	Change    int64 `json:"change"`              // Cash handed back
+	Duplicate bool  `json:"duplicate,omitempty"` // Committed by an earlier request with the same ID
+}
+
+// TransactionDetail is a completed sale and the refunds made against it
+type TransactionDetail struct {
+	*database.Sale
+	Refunds []database.Sale `json:"refunds"`
+}
+
+// handleCreateTransaction commits a tendered sale through the ledger
+func (s *Server) handleCreateTransaction(c *fiber.Ctx) error {
+	var in TransactionRequest
+	if err := bind(c, &in); err != nil {
+		return err
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	sale := &database.Sale{
+		ID:          in.ID,
+		Type:        database.SaleTypeSale,
+		TerminalID:  terminal,
+		OperatorID:  in.OperatorID,
+		Lines:       in.Lines,
+		Total:       in.Total,
+		VoidedLines: in.VoidedLines,
+		ScanSeconds: in.ScanSeconds,
+		Tenders:     in.Tenders,
+	}
+	if sale.ID == "" {
+		sale.ID = ids.New()
+	}
+	if token := callerToken(c); token != nil && token.UserID != "" {
+		sale.OperatorID = token.UserID
+	}
+	if err := sale.Validate(); err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	return s.commitTransaction(c, sale, "Transaction completed successfully")
+}
+
+// handleGetTransaction returns a completed sale and its refunds
+func (s *Server) handleGetTransaction(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	sale, err := db.GetSale(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrSaleNotFound) {
+			return apperr.NotFound("Transaction not found")
+		}
+		return apperr.Database(err)
+	}
+	refunds, err := db.ListRefunds(sale.ID)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	if refunds == nil {
+		refunds = []database.Sale{}
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Transaction retrieved successfully", TransactionDetail{Sale: sale, Refunds: refunds}))
+}
+
+// handleVoidTransaction cancels an open transaction: the cart is discarded
+// and the void recorded for head office. Completed sales are refunded
+// instead.
+func (s *Server) handleVoidTransaction(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var in VoidRequest
+	if len(c.Body()) > 0 {
+		if err := bind(c, &in); err != nil {
+			return err
+		}
+	}
+
+	id := c.Params("id")
+	if exists, err := db.SaleExists(id); err != nil {
+		return apperr.Database(err)
+	} else if exists {
+		return apperr.Conflict("Transaction is completed; refund it instead")
+	}
+	if void, err := db.GetVoid(id); err == nil {
+		return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Transaction already voided", void))
+	} else if !errors.Is(err, database.ErrVoidNotFound) {
+		return apperr.Database(err)
+	}
+
+	totals, err := ownedBasket(c, db)
+	if err != nil {
+		return err
+	}
+
+	void := &database.TransactionVoid{ID: totals.BasketID, Reason: in.Reason}
+	if token := callerToken(c); token != nil && token.UserID != "" {
+		void.OperatorID = token.UserID
+	}
+	if err := db.VoidBasket(void); err != nil {
+		if errors.Is(err, database.ErrBasketNotFound) {
+			return apperr.NotFound("Cart not found")
+		}
+		return apperr.Database(err)
+	}
+	s.releaseTransfer(c.UserContext(), totals.BasketID, totals.TerminalID)
+	s.clearSCOHold(totals.BasketID)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "Transaction voided successfully", void))
+}
+
+// handleRefundTransaction returns goods from a completed sale at the
+// prices they sold for. Refunds are checked against what earlier refunds
+// already returned, so a line is never refunded twice.
+func (s *Server) handleRefundTransaction(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var in RefundRequest
+	if len(c.Body()) > 0 {
+		if err := bind(c, &in); err != nil {
+			return err
+		}
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	// Serialise refunds so two requests cannot both return the same goods
+	s.refunds.Lock()
+	defer s.refunds.Unlock()
+
+	original, err := db.GetSale(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrSaleNotFound) {
+			return apperr.NotFound("Transaction not found")
+		}
+		return apperr.Database(err)
+	}
+	if original.IsRefund() {
+		return apperr.BadRequest("A refund cannot be refunded")
+	}
+	if in.ID != "" {
+		if exists, err := db.SaleExists(in.ID); err != nil {
+			return apperr.Database(err)
+		} else if exists {
+			// A retry of a committed refund: acknowledge it again
+			refund, err := db.GetSale(in.ID)
+			if err != nil {
+				return apperr.Database(err)
+			}
+			if refund.RefundOf != original.ID {
+				return apperr.Conflict("Transaction ID already in use")
+			}
+			return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Transaction refunded successfully", TransactionResponse{Sale: refund, Duplicate: true}))
+		}
+	}
+
+	previous, err := db.ListRefunds(original.ID)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	lines, err := refundLines(original, previous, in.Lines)
+	if err != nil {
+		return err
+	}
+
+	refund := &database.Sale{
+		ID:         in.ID,
+		Type:       database.SaleTypeRefund,
+		TerminalID: terminal,
+		OperatorID: in.OperatorID,
+		Lines:      lines,
+		Tenders:    in.Tenders,
+		RefundOf:   original.ID,
+	}
+	for _, line := range lines {
+		refund.Total += int64(line.Quantity) * line.Price
+	}
+	if refund.ID == "" {
+		refund.ID = ids.New()
+	}
+	if token := callerToken(c); token != nil && token.UserID != "" {
+		refund.OperatorID = token.UserID
+	}
+	if err := refund.Validate(); err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	return s.commitTransaction(c, refund, "Transaction refunded successfully")
+}
+
+// commitTransaction commits a validated sale or refund through the ledger
+func (s *Server) commitTransaction(c *fiber.Ctx, sale *database.Sale, message string) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	if s.ledger == nil {
+		return apperr.Unavailable(api.MessageServiceUnavailable, 5*time.Second)
+	}
+
+	result, err := s.commitSale(c.UserContext(), sale)
+	if err != nil {
+		return apperr.Database(err)
+	}
+	stored, err := db.GetSale(result.SaleID)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, message, TransactionResponse{
+		Sale:      stored,
+		Change:    stored.Change(),
+		Duplicate: result.Duplicate,
+	}))
+}
+
+// refundLines prices the requested returns from the original sale's lines,
+// consuming each line's quantity in order after the earlier refunds. With
+// no request every remaining quantity is returned.
+func refundLines(original *database.Sale, previous []database.Sale, requested []database.SaleLine) ([]database.SaleLine, error) {
+	remaining := make([]int, len(original.Lines))
+	for i, line := range original.Lines {
+		remaining[i] = line.Quantity
+	}
+	consume := func(sku string, quantity int, take func(i, n int)) int {
+		for i, line := range original.Lines {
+			if quantity == 0 {
+				break
+			}
+			if line.SKU != sku || remaining[i] == 0 {
+				continue
+			}
+			n := min(quantity, remaining[i])
+			remaining[i] -= n
+			quantity -= n
+			if take != nil {
+				take(i, n)
+			}
+		}
+		return quantity
+	}
+
+	for _, refund := range previous {
+		for _, line := range refund.Lines {
+			consume(line.SKU, line.Quantity, nil)
+		}
+	}
+
+	var lines []database.SaleLine
+	take := func(i, n int) {
+		line := original.Lines[i]
+		line.Quantity = n
+		lines = append(lines, line)
+	}
+
+	if len(requested) == 0 {
+		for i := range original.Lines {
+			if remaining[i] > 0 {
+				take(i, remaining[i])
+			}
+		}
+		if len(lines) == 0 {
+			return nil, apperr.Conflict("Transaction is already fully refunded")
+		}
+		return lines, nil
+	}
+
+	for i, line := range requested {
+		if line.SKU == "" || line.Quantity <= 0 {
+			return nil, apperr.Invalid([]api.FieldError{{Field: fmt.Sprintf("lines[%d]", i), Rule: "required", Message: "must have a sku and positive quantity"}})
+		}
+		if left := consume(line.SKU, line.Quantity, take); left > 0 {
+			return nil, apperr.Conflict(fmt.Sprintf("Only %d of %s can still be refunded", line.Quantity-left, line.SKU))
+		}
+	}
+	return lines, nil
+}
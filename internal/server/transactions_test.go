@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestTransactions_SaleAndRefund(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	body := `{"id":"TX-1","lines":[{"sku":"MILK","quantity":2,"price":500},{"sku":"BREAD","quantity":1,"price":300}],
+		"total":1300,"tenders":[{"method":"card","amount":1000},{"method":"cash","amount":500}]}`
+	resp, result := cartRequest(t, server, http.MethodPost, "/transactions", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Create returned %d: %s", resp.StatusCode, result)
+	}
+	var sale TransactionResponse
+	json.Unmarshal(result, &sale)
+	if sale.ID != "TX-1" || sale.Change != 200 || len(sale.Tenders) != 2 {
+		t.Errorf("Unexpected sale %+v", sale)
+	}
+
+	short := `{"lines":[{"sku":"MILK","quantity":1,"price":500}],"total":500,"tenders":[{"method":"card","amount":400}]}`
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/transactions", short); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Underpaid sale returned %d, want 400", resp.StatusCode)
+	}
+
+	// Return one milk, then everything left
+	resp, result = cartRequest(t, server, http.MethodPost, "/transactions/TX-1/refund",
+		`{"id":"RF-1","lines":[{"sku":"MILK","quantity":1}],"tenders":[{"method":"cash","amount":500}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Refund returned %d: %s", resp.StatusCode, result)
+	}
+	var refund TransactionResponse
+	json.Unmarshal(result, &refund)
+	if refund.Type != database.SaleTypeRefund || refund.RefundOf != "TX-1" || refund.Total != 500 {
+		t.Errorf("Unexpected refund %+v", refund)
+	}
+
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/transactions/TX-1/refund", `{"lines":[{"sku":"MILK","quantity":2}]}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Over-refund returned %d, want 409", resp.StatusCode)
+	}
+
+	resp, result = cartRequest(t, server, http.MethodPost, "/transactions/TX-1/refund", "")
+	json.Unmarshal(result, &refund)
+	if resp.StatusCode != http.StatusOK || refund.Total != 800 {
+		t.Errorf("Refund of the rest returned %d: %s", resp.StatusCode, result)
+	}
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/transactions/TX-1/refund", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Refund of a fully refunded sale returned %d, want 409", resp.StatusCode)
+	}
+
+	resp, result = cartRequest(t, server, http.MethodGet, "/transactions/TX-1", "")
+	var detail TransactionDetail
+	json.Unmarshal(result, &detail)
+	if resp.StatusCode != http.StatusOK || len(detail.Refunds) != 2 {
+		t.Errorf("Get returned %d with %d refunds", resp.StatusCode, len(detail.Refunds))
+	}
+	if level, _ := server.db.GetStockLevel("MILK"); level != 0 {
+		t.Errorf("Expected refunded milk back in stock, level %d", level)
+	}
+}
+
+func TestTransactions_Void(t *testing.T) {
+	server := newTestServerWithLedger(t)
+	before, _ := server.db.CountPendingOutbox()
+
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/carts/CART-1/lines", chunkBody(0, 3)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Append returned %d", resp.StatusCode)
+	}
+
+	resp, result := cartRequest(t, server, http.MethodPost, "/transactions/CART-1/void", `{"reason":"customer left"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Void returned %d: %s", resp.StatusCode, result)
+	}
+	var void database.TransactionVoid
+	json.Unmarshal(result, &void)
+	if void.LineCount != 3 || void.Total != 750 || void.Reason != "customer left" {
+		t.Errorf("Unexpected void %+v", void)
+	}
+	if after, _ := server.db.CountPendingOutbox(); after != before+1 {
+		t.Errorf("Expected the void in the outbox, pending %d -> %d", before, after)
+	}
+	if resp, _ := cartRequest(t, server, http.MethodGet, "/carts/CART-1/totals", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Voided cart still answered %d", resp.StatusCode)
+	}
+
+	// A repeated void acknowledges the recorded one
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/transactions/CART-1/void", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Repeated void returned %d", resp.StatusCode)
+	}
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/transactions/MISSING/void", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Void of an unknown cart returned %d, want 404", resp.StatusCode)
+	}
+
+	// Completed sales are refunded, not voided
+	cartRequest(t, server, http.MethodPost, "/transactions", `{"id":"TX-2","lines":[{"sku":"A","quantity":1,"price":100}],"total":100,"tenders":[{"method":"cash","amount":100}]}`)
+	if resp, _ := cartRequest(t, server, http.MethodPost, "/transactions/TX-2/void", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Void of a completed sale returned %d, want 409", resp.StatusCode)
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/hub"
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// transferLinkPrefix prefixes the settings linking a local cart to the hub
+// transfer it was claimed from, so checkout can complete the handoff
+const transferLinkPrefix = "transfer."
+
+// transferLink records the claim held on a transferred cart
+type transferLink struct {
+	TransferID string `json:"transfer_id"`
+	ClaimToken string `json:"claim_token"`
+}
+
+// requireHub returns the hub client or a 503 error when no hub is configured
+func (s *Server) requireHub() (*hub.Client, error) {
+	if s.hub == nil {
+		return nil, apperr.Unavailable("No store hub configured for transfers", time.Minute)
+	}
+	return s.hub, nil
+}
+
+// handleTransferBasket offers the caller's cart to another terminal via the
+// hub and removes it locally, so the source can no longer check it out
+func (s *Server) handleTransferBasket(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+	totals, err := ownedBasket(c, db)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		TargetTerminal string `json:"target_terminal"`
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return apperr.BadRequest("Invalid transfer request")
+		}
+	}
+
+	// Transfer IDs become cart IDs on the receiving terminal
+	id := totals.TerminalID + "." + totals.BasketID
+	if !terminalIDPattern.MatchString(id) {
+		return apperr.BadRequest("Cart ID too long to transfer")
+	}
+
+	lines, err := db.GetBasketLines(totals.BasketID, 0, 0)
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	transfer, err := hubClient.OfferTransfer(c.UserContext(), &hub.Transfer{
+		ID:             id,
+		SourceTerminal: totals.TerminalID,
+		TargetTerminal: body.TargetTerminal,
+		Lines:          lines,
+	})
+	if err != nil {
+		return hubError(err)
+	}
+
+	if err := db.DeleteBasket(totals.BasketID); err != nil && !errors.Is(err, database.ErrBasketNotFound) {
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Cart offered for transfer", transfer))
+}
+
+// handleAcceptTransfer claims a transfer from the hub and loads it into a
+// local cart with the transfer's ID. Accepting again is a no-op.
+func (s *Server) handleAcceptTransfer(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	hubClient, err := s.requireHub()
+	if err != nil {
+		return err
+	}
+	terminal, err := terminalID(c)
+	if err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	if !terminalIDPattern.MatchString(id) {
+		return apperr.BadRequest("Invalid transfer ID")
+	}
+
+	if totals, err := db.GetBasketTotals(id); err == nil && totals.TerminalID == terminal {
+		return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Transfer already accepted", totals))
+	}
+
+	transfer, err := hubClient.ClaimTransfer(c.UserContext(), id, terminal)
+	if err != nil {
+		return hubError(err)
+	}
+
+	// Persist the claim before the cart so checkout can always complete it
+	link, err := json.Marshal(transferLink{TransferID: transfer.ID, ClaimToken: transfer.ClaimToken})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if err := db.SetSetting(transferLinkPrefix+id, string(link)); err != nil {
+		return apperr.Database(err)
+	}
+
+	totals, err := db.AppendBasketLines(id, terminal, transfer.Lines, constants.MaxBasketLines)
+	if err != nil {
+		s.releaseTransfer(c.UserContext(), id, terminal)
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Transfer accepted successfully", totals))
+}
+
+// completeTransfer tells the hub a transferred cart was paid. Failures are
+// logged: the claim is exclusive and never expires, so no other terminal
+// can complete the basket in the meantime.
+func (s *Server) completeTransfer(ctx context.Context, basketID, terminal, saleID string) {
+	link, ok := s.transferLink(basketID)
+	if !ok || s.hub == nil {
+		return
+	}
+
+	if err := s.hub.CompleteTransfer(ctx, link.TransferID, terminal, link.ClaimToken, saleID); err != nil {
+		logging.Printf(ctx, "Warning: failed to complete transfer %s: %v", link.TransferID, err)
+		return
+	}
+	s.db.DeleteSetting(transferLinkPrefix + basketID)
+}
+
+// releaseTransfer hands a transferred cart back to the hub when it is abandoned
+func (s *Server) releaseTransfer(ctx context.Context, basketID, terminal string) {
+	link, ok := s.transferLink(basketID)
+	if !ok || s.hub == nil {
+		return
+	}
+
+	if err := s.hub.ReleaseTransfer(ctx, link.TransferID, terminal, link.ClaimToken); err != nil {
+		logging.Printf(ctx, "Warning: failed to release transfer %s: %v", link.TransferID, err)
+		return
+	}
+	s.db.DeleteSetting(transferLinkPrefix + basketID)
+}
+
+// transferLink returns the claim held on a cart, if it came from a transfer
+func (s *Server) transferLink(basketID string) (*transferLink, bool) {
+	var link transferLink
+	if err := s.db.GetSettingJSON(transferLinkPrefix+basketID, &link); err != nil {
+		return nil, false
+	}
+	return &link, true
+}
+
+// hubError maps a hub client error to an application error
+func hubError(err error) error {
+	if errors.Is(err, hub.ErrConflict) {
+		return apperr.Conflict(err.Error())
+	}
+	return apperr.New(fiber.StatusBadGateway, api.CodeErrorSync, "Store hub request failed").Wrap(err).Retryable(5 * time.Second)
+}
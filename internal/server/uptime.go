@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// UptimeHistory is the boot counter and the retained boot history
+type UptimeHistory struct {
+	BootCount int64           `json:"boot_count"` // Starts since the database was created
+	Crashes   int             `json:"crashes"`    // Retained boots that ended without a shutdown
+	Boots     []database.Boot `json:"boots"`      // Newest first
+}
+
+// handleGetUptime returns the boot history, to diagnose terminals that
+// restart on their own
+func (s *Server) handleGetUptime(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	count, err := db.BootCount()
+	if err != nil {
+		return apperr.Database(err)
+	}
+	boots, err := db.ListBoots()
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	history := UptimeHistory{BootCount: count, Boots: boots}
+	for _, boot := range boots {
+		if boot.Reason == database.ShutdownCrash {
+			history.Crashes++
+		}
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataRetrieved, "Uptime history retrieved successfully", history))
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+func TestUptime_History(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := server.db.RecordBoot("1.0.0", time.Hour); err != nil {
+			t.Fatalf("RecordBoot failed: %v", err)
+		}
+	}
+
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/admin/uptime", cashier, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier got %d, want 403", resp.StatusCode)
+	}
+
+	resp, result := laneRequest(t, server, http.MethodGet, "/admin/uptime", adminBearer(t, server), "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Uptime returned %d", resp.StatusCode)
+	}
+	var history UptimeHistory
+	if err := json.Unmarshal(result, &history); err != nil {
+		t.Fatalf("Failed to parse uptime: %v", err)
+	}
+	if history.BootCount != 2 || history.Crashes != 1 || len(history.Boots) != 2 {
+		t.Errorf("Unexpected history %+v", history)
+	}
+	if history.Boots[0].Reason != "" || history.Boots[1].Reason != database.ShutdownCrash {
+		t.Errorf("Expected the running boot first, then a crash: %+v", history.Boots)
+	}
+}
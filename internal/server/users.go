@@ -0,0 +1,159 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/database"
+)
+
+// Cashiers are created by an admin with a numeric PIN. At the till they
+// trade their ID and PIN for a short-lived session token (POST /auth/pin)
+// carrying their role and user ID; sales checked out with it are
+// attributed to them.
+
+// PINSession is a cashier session token and the user it belongs to
+type PINSession struct {
+	Token     string         `json:"token"`
+	ExpiresAt string         `json:"expires_at"` // ISO 8601 timestamp
+	User      *database.User `json:"user"`
+}
+
+// handleCreateUser creates a cashier (or attendant) with a PIN
+func (s *Server) handleCreateUser(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		ID   string `json:"id" validate:"required,terminal_id"`
+		Name string `json:"name" validate:"required"`
+		Role string `json:"role" validate:"omitempty,oneof=cashier attendant"`
+		PIN  string `json:"pin" validate:"required,number"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+	if body.Role == "" {
+		body.Role = auth.RoleCashier
+	}
+	hash, err := auth.HashPIN(body.PIN)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	user := &database.User{ID: body.ID, Name: body.Name, Role: body.Role, PINHash: hash}
+	if err := db.CreateUser(user); err != nil {
+		if errors.Is(err, database.ErrUserExists) {
+			return apperr.Conflict("User already exists")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "User created successfully", user))
+}
+
+// handleListUsers lists users (without their PIN hashes), paginated
+func (s *Server) handleListUsers(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+	page, err := pageRequest(c, api.DefaultPerPage, api.MaxPerPage)
+	if err != nil {
+		return err
+	}
+
+	users, err := db.ListUsers()
+	if err != nil {
+		return apperr.Database(err)
+	}
+
+	return listPage(c, "Users retrieved successfully", users, page)
+}
+
+// handleSetUserPIN replaces a user's PIN
+func (s *Server) handleSetUserPIN(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		PIN string `json:"pin" validate:"required,number"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+	hash, err := auth.HashPIN(body.PIN)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	if err := db.SetUserPIN(c.Params("id"), hash); err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			return apperr.NotFound("User not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataUpdated, "PIN changed successfully", nil))
+}
+
+// handleDeleteUser removes a user; their open sessions stop working
+func (s *Server) handleDeleteUser(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteUser(c.Params("id")); err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			return apperr.NotFound("User not found")
+		}
+		return apperr.Database(err)
+	}
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataDeleted, "User deleted successfully", nil))
+}
+
+// handlePINSignIn trades a user ID and PIN for a cashier session token.
+// Wrong PINs count towards the lockout like bad tokens.
+func (s *Server) handlePINSignIn(c *fiber.Ctx) error {
+	db, err := s.requireDB()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		UserID string `json:"user_id" validate:"required"`
+		PIN    string `json:"pin" validate:"required"`
+	}
+	if err := bind(c, &body); err != nil {
+		return err
+	}
+
+	if err := s.checkLockout(c, credentialPIN); err != nil {
+		return err
+	}
+
+	session, err := auth.SignInWithPIN(db, body.UserID, body.PIN)
+	if errors.Is(err, auth.ErrInvalidPIN) {
+		return s.authFailed(c, credentialPIN, "wrong PIN for "+body.UserID, apperr.Unauthorized("Unknown user or wrong PIN"))
+	}
+	if err != nil {
+		return apperr.Database(err)
+	}
+	s.recordAudit(database.AuditPINSignIn, c.IP(), session.User.ID)
+
+	return c.JSON(api.NewSuccessResponse(api.CodeDataCreated, "Signed in", PINSession{
+		Token:     session.Token,
+		ExpiresAt: session.ExpiresAt.UTC().Format(time.RFC3339),
+		User:      session.User,
+	}))
+}
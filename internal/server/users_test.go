@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestUsers_PINSignInAttributesSales(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/users", adminBearer(t, server), `{"id":"C-01","name":"Dana","pin":"12"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Short PIN returned %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/users", adminBearer(t, server), `{"id":"C-01","name":"Dana","role":"admin","pin":"4821"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Admin PIN user returned %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/users", adminBearer(t, server), `{"id":"C-01","name":"Dana","pin":"4821"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Create user returned %d", resp.StatusCode)
+	}
+
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/auth/pin", adminBearer(t, server), `{"user_id":"C-01","pin":"0000"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Wrong PIN returned %d, want 401", resp.StatusCode)
+	}
+	resp, result := laneRequest(t, server, http.MethodPost, "/auth/pin", adminBearer(t, server), `{"user_id":"C-01","pin":"4821"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PIN sign-in returned %d", resp.StatusCode)
+	}
+	var session PINSession
+	json.Unmarshal(result, &session)
+	if session.Token == "" || session.User == nil || session.User.Role != "cashier" {
+		t.Fatalf("Unexpected session: %s", result)
+	}
+
+	// The session is a cashier: no user management
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/users", session.Token, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier listed users with %d, want 403", resp.StatusCode)
+	}
+
+	// Sales are attributed to the signed-in cashier, whatever the body says
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/carts/cart-1/lines", session.Token, chunkBody(0, 2)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Append lines returned %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodPost, "/carts/cart-1/checkout", session.Token, `{"operator_id":"someone-else"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Checkout returned %d", resp.StatusCode)
+	}
+	sale, err := server.db.GetSale("cart-1")
+	if err != nil {
+		t.Fatalf("GetSale failed: %v", err)
+	}
+	if sale.OperatorID != "C-01" {
+		t.Errorf("Expected the sale to be attributed to C-01, got %q", sale.OperatorID)
+	}
+
+	// Deleting the user ends their session
+	if resp, _ := guardedRequest(t, server, http.MethodDelete, "/users/C-01", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Delete user returned %d", resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, "/carts/draft", session.Token, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Deleted user's session returned %d, want 401", resp.StatusCode)
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+)
+
+// Request bodies are structs with `validate` tags (go-playground/validator
+// rules plus the custom ones below). bind decodes and validates them;
+// failures answer 400 with CodeErrorBadRequest and one api.FieldError per
+// invalid field in Meta.
+
+// validate checks request structs; fields are named by their JSON names
+var validate = newValidator()
+
+// newValidator creates the request validator with the custom rules
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// terminal_id: IDs of terminals, carts, devices and users
+	v.RegisterValidation("terminal_id", func(fl validator.FieldLevel) bool {
+		return terminalIDPattern.MatchString(fl.Field().String())
+	})
+	return v
+}
+
+// bind decodes the JSON body into v and validates it. An empty or
+// malformed body is a validation error too.
+func bind(c *fiber.Ctx, v interface{}) error {
+	body := bytes.TrimSpace(c.Body())
+	if len(body) == 0 {
+		return apperr.Invalid([]api.FieldError{{Field: "body", Rule: "required", Message: "request body is required"}})
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return decodeError(err)
+	}
+	return validateStruct(v)
+}
+
+// validateStruct checks v against its validate tags
+func validateStruct(v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return apperr.Internal(err)
+	}
+	fields := make([]api.FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		fields = append(fields, fieldError(fe))
+	}
+	return apperr.Invalid(fields)
+}
+
+// decodeError reports a body that is not valid JSON for its schema
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return apperr.Invalid([]api.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("must be of type %s", jsonType(typeErr.Type)),
+		}})
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apperr.Invalid([]api.FieldError{{
+			Field:   strings.Trim(field, `"`),
+			Rule:    "unknown",
+			Message: "is not a known field",
+		}})
+	}
+	return apperr.Invalid([]api.FieldError{{Field: "body", Rule: "json", Message: "must be valid JSON: " + err.Error()}})
+}
+
+// fieldError converts a validator failure into an api.FieldError
+func fieldError(fe validator.FieldError) api.FieldError {
+	// Drop the top-level struct name: "DataRequest.lines[0].sku" -> "lines[0].sku"
+	field := fe.Namespace()
+	if _, rest, ok := strings.Cut(field, "."); ok {
+		field = rest
+	}
+	return api.FieldError{Field: field, Rule: fe.Tag(), Param: fe.Param(), Message: ruleMessage(fe)}
+}
+
+// ruleMessage explains a failed rule in words
+func ruleMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s%s", fe.Param(), unit)
+	case "lt":
+		return fmt.Sprintf("must be less than %s%s", fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", fe.Param(), unit)
+	case "ne":
+		return fmt.Sprintf("must not be %s", fe.Param())
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "number":
+		return "must contain only digits"
+	case "terminal_id":
+		return "must be 1-64 letters, digits, '.', '_' or '-'"
+	}
+	return "is invalid"
+}
+
+// jsonType names the JSON type expected for a Go type
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return t.String()
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/api"
+)
+
+// invalidFields sends a request expected to fail validation and returns
+// the reported field errors
+func invalidFields(t *testing.T, server *Server, method, path, body string) []api.FieldError {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTerminalID, "T1")
+
+	resp, err := send(server, asAdmin(t, server, req), -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Code    int           `json:"code"`
+		Message string        `json:"message"`
+		Meta    api.ErrorHint `json:"meta"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || envelope.Message != api.MessageValidationFailed {
+		t.Fatalf("Expected a 400 validation error, got %d: %s", resp.StatusCode, data)
+	}
+	if envelope.Meta.Retryable {
+		t.Errorf("Validation errors should not be retryable")
+	}
+	return envelope.Meta.Fields
+}
+
+func hasField(fields []api.FieldError, field, rule string) bool {
+	for _, f := range fields {
+		if f.Field == field && f.Rule == rule {
+			return f.Message != ""
+		}
+	}
+	return false
+}
+
+func TestValidation_FieldErrors(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		field string
+		rule  string
+	}{
+		{"empty body", "/carts/draft", ``, "body", "required"},
+		{"malformed JSON", "/carts/draft", `{"payload":`, "body", "json"},
+		{"missing field", "/carts/draft", `{}`, "payload", "required"},
+		{"wrong type", "/data", `{"type":1,"data":{}}`, "type", "type"},
+		{"unknown field", "/data", `{"type":"product","data":{},"extra":true}`, "extra", "unknown"},
+		{"unsupported type", "/data", `{"type":"coupon","data":{}}`, "type", "oneof"},
+		{"nested field", "/data", `{"type":"sale","data":{"lines":[{"quantity":1,"price":1}],"total":1}}`, "lines[0].sku", "required"},
+		{"range", "/data", `{"type":"product","data":{"barcode":"1","name":"Pen","price":1,"tax_rate":20000}}`, "tax_rate", "lte"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := "POST"
+			if tt.path == "/carts/draft" {
+				method = "PUT"
+			}
+			fields := invalidFields(t, server, method, tt.path, tt.body)
+			if !hasField(fields, tt.field, tt.rule) {
+				t.Errorf("Expected %s to fail %s, got %+v", tt.field, tt.rule, fields)
+			}
+		})
+	}
+}
+
+func TestValidation_ReportsEveryField(t *testing.T) {
+	server := newTestServerWithLedger(t)
+
+	fields := invalidFields(t, server, "POST", "/data", `{"type":"stock_adjustment","data":{"delta":0}}`)
+	if len(fields) != 2 || !hasField(fields, "sku", "required") || !hasField(fields, "delta", "ne") {
+		t.Errorf("Expected sku and delta errors, got %+v", fields)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Routes live under a versioned prefix so breaking changes to APIResponse or
+// endpoints can ship as /api/v2 next to /api/v1. Paths from before
+// versioning (/health, /carts/:id/lines, ...) are rewritten to the current
+// version and flagged as deprecated, so deployed frontends keep working.
+
+// APIPrefix is the path prefix of the current API version
+const APIPrefix = "/api/v1"
+
+// versionedPrefix starts every versioned path
+const versionedPrefix = "/api/"
+
+// HeaderDeprecation marks responses to pre-versioning paths
+const HeaderDeprecation = "Deprecation"
+
+// legacyPaths rewrites pre-versioning paths to APIPrefix. Responses carry
+// Deprecation and a Link to the versioned path.
+func legacyPaths(c *fiber.Ctx) error {
+	path := c.Path()
+	if strings.HasPrefix(path, versionedPrefix) {
+		return c.Next()
+	}
+
+	c.Set(HeaderDeprecation, "true")
+	c.Set(fiber.HeaderLink, "<"+APIPrefix+path+`>; rel="successor-version"`)
+	c.Path(APIPrefix + path)
+	return c.Next()
+}
+
+// routePath returns the request path without the API version prefix, as
+// matched by the route lists
+func routePath(c *fiber.Ctx) string {
+	return unversioned(c.Path())
+}
+
+// unversioned strips APIPrefix from path
+func unversioned(path string) string {
+	rest, ok := strings.CutPrefix(path, APIPrefix)
+	switch {
+	case !ok:
+		return path
+	case rest == "":
+		return "/"
+	case rest[0] == '/':
+		return rest
+	}
+	return path
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/auth"
+)
+
+func TestVersioning_LegacyPathsShim(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	get := func(path string) *http.Response {
+		resp, err := send(server, asAdmin(t, server, httptest.NewRequest(http.MethodGet, path, nil)), -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get(APIPrefix + "/health")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(HeaderDeprecation) != "" {
+		t.Errorf("Versioned path: expected 200 without deprecation, got %d (%q)", resp.StatusCode, resp.Header.Get(HeaderDeprecation))
+	}
+
+	resp = get("/health")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(HeaderDeprecation) != "true" {
+		t.Errorf("Legacy path: expected 200 flagged deprecated, got %d (%q)", resp.StatusCode, resp.Header.Get(HeaderDeprecation))
+	}
+	if link := resp.Header.Get("Link"); link != `</api/v1/health>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header: %s", link)
+	}
+
+	if resp := get("/api/v2/health"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown version: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestVersioning_RouteListsMatchVersionedPaths(t *testing.T) {
+	server := newTestServerWithDB(t)
+	cashier, _ := auth.Issue(server.db, auth.RoleCashier, "till 1", 0)
+
+	if resp, _ := laneRequest(t, server, http.MethodPut, APIPrefix+"/carts/draft", cashier, `{"payload":{"lines":[]}}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Cashier draft under %s: expected 200, got %d", APIPrefix, resp.StatusCode)
+	}
+	if resp, _ := laneRequest(t, server, http.MethodGet, APIPrefix+"/status", cashier, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Cashier status under %s: expected 403, got %d", APIPrefix, resp.StatusCode)
+	}
+}
+
+func TestUnversioned(t *testing.T) {
+	for path, want := range map[string]string{
+		"/api/v1/health":  "/health",
+		"/api/v1":         "/",
+		"/api/v10/health": "/api/v10/health",
+		"/health":         "/health",
+	} {
+		if got := unversioned(path); got != want {
+			t.Errorf("unversioned(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/professor93/promo-pos/internal/api"
+	"github.com/professor93/promo-pos/internal/apperr"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/peripheral"
+)
+
+// GET /ws/status streams live service events to the frontend over a
+// WebSocket, so it no longer polls /status to notice a sync, the terminal
+// going offline or a printer failing. The first message is a
+// status.snapshot with the current state; every later one is an
+// events.Event as published. The stream is one way: client messages other
+// than ping and close are ignored. Browsers open it with a stream ticket
+// (see tickets.go) from the service's own pages or a configured frontend.
+
+const (
+	// websocketPingInterval paces server pings; a client silent for two
+	// intervals is dropped
+	websocketPingInterval = 30 * time.Second
+
+	// websocketMaxMessage bounds a client message; clients only send
+	// control frames, which are limited to 125 bytes
+	websocketMaxMessage = 4096
+
+	// websocketEventBuffer is the events a slow client may fall behind by
+	websocketEventBuffer = 64
+
+	// StatusSnapshot is the type of the first message on /ws/status
+	StatusSnapshot = "status.snapshot"
+)
+
+// handleStatusSocket upgrades the request to a WebSocket streaming events
+func (s *Server) handleStatusSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		c.Set(fiber.HeaderUpgrade, "websocket")
+		return apperr.New(fiber.StatusUpgradeRequired, api.CodeErrorBadRequest, "This endpoint requires a WebSocket upgrade")
+	}
+	if !s.socketOriginAllowed(c) {
+		return apperr.New(fiber.StatusForbidden, api.CodeErrorForbidden, "WebSockets are not accepted from this origin")
+	}
+	return s.statusSocket(c)
+}
+
+// originAllowed reports whether pages from origin are one of the
+// configured frontends
+func (s *Server) originAllowed(origin string) bool {
+	for _, allowed := range s.config.AllowedOrigins {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// socketOriginAllowed reports whether the request may open a WebSocket:
+// callers other than browsers send no Origin, and browsers must come from
+// the service's own pages (the admin dashboard) or a configured frontend
+func (s *Server) socketOriginAllowed(c *fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" || s.originAllowed(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, string(c.Request().Host()))
+}
+
+// streamStatus writes the status snapshot and then every published event
+// to conn until the client goes away or the server shuts down
+func (s *Server) streamStatus(conn *websocket.Conn) {
+	stream, unsubscribe := s.events.Subscribe(websocketEventBuffer)
+	defer unsubscribe()
+
+	write := func(v interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(websocketPingInterval))
+		return conn.WriteJSON(v)
+	}
+	if err := write(events.Event{Type: StatusSnapshot, Time: time.Now().UTC().Format(time.RFC3339), Data: s.liveStatus()}); err != nil {
+		return
+	}
+
+	// The library answers pings and closes while reading
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(websocketMaxMessage)
+		extend := func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * websocketPingInterval))
+		}
+		conn.SetPongHandler(extend)
+		for extend("") == nil {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(websocketPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			if err := write(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketPingInterval)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-s.streamsDone:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// publishEvents feeds the bus from the server's own event sources
+func (s *Server) publishEvents() {
+	s.peripherals.OnChange(func(p peripheral.Status) {
+		s.events.Publish(events.PeripheralChanged, peripheralStatus(p))
+	})
+	if s.db != nil {
+		s.db.OnReadOnlyChange(func(bool) {
+			s.events.Publish(events.OfflineChanged, fiber.Map{"offline": s.offlineMode()})
+		})
+		s.db.OnCatalogChange(s.changes.add)
+	}
+}
+
+// liveStatus is the state the status.snapshot message carries
+func (s *Server) liveStatus() fiber.Map {
+	peripherals := []api.PeripheralStatus{}
+	for _, p := range s.peripherals.Statuses() {
+		peripherals = append(peripherals, peripheralStatus(p))
+	}
+	return fiber.Map{
+		"offline":     s.offlineMode(),
+		"peripherals": peripherals,
+	}
+}
+
+// peripheralStatus renders a peripheral's status for the API
+func peripheralStatus(p peripheral.Status) api.PeripheralStatus {
+	ps := api.PeripheralStatus{Name: p.Name, Kind: p.Kind, State: p.State, Detail: p.Detail}
+	if !p.CheckedAt.IsZero() {
+		ps.CheckedAt = p.CheckedAt.UTC().Format(time.RFC3339)
+	}
+	return ps
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/professor93/promo-pos/internal/auth"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// listen serves server on a loopback port and returns its address
+func listen(t *testing.T, server *Server) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.GetApp().Listener(ln)
+	t.Cleanup(func() { server.Shutdown() })
+	return ln.Addr().String()
+}
+
+// dialStatusSocket opens /ws/status on addr from origin with a ticket for
+// token, as a browser would
+func dialStatusSocket(t *testing.T, server *Server, addr, origin, token string) (*websocket.Conn, *http.Response, error) {
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, resp, err := dialer.Dial("ws://"+addr+APIPrefix+"/ws/status?ticket="+issueStreamTicket(t, server, token), header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+// readEvent reads the next message as an event
+func readEvent(t *testing.T, conn *websocket.Conn) events.Event {
+	var event events.Event
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	return event
+}
+
+func TestStatusSocket_StreamsEvents(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.peripherals.Report("receipt", constants.PeripheralPrinter, true, "")
+	addr := listen(t, server)
+
+	cashier, err := auth.Issue(server.db, auth.RoleCashier, "", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	conn, _, err := dialStatusSocket(t, server, addr, "", cashier)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	// The token the POST /sync below presents, issued before the database
+	// turns read-only
+	if _, err := adminToken(server); err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	snapshot := readEvent(t, conn)
+	data, _ := snapshot.Data.(map[string]interface{})
+	if snapshot.Type != StatusSnapshot || data["offline"] != false {
+		t.Fatalf("Unexpected snapshot %+v", snapshot)
+	}
+	if peripherals, _ := data["peripherals"].([]interface{}); len(peripherals) != 1 {
+		t.Errorf("Expected the receipt printer in the snapshot, got %v", data["peripherals"])
+	}
+
+	server.db.SetReadOnly(true)
+	if event := readEvent(t, conn); event.Type != events.OfflineChanged {
+		t.Errorf("Expected %s, got %+v", events.OfflineChanged, event)
+	}
+
+	server.peripherals.Report("receipt", constants.PeripheralPrinter, false, "paper out")
+	event := readEvent(t, conn)
+	data, _ = event.Data.(map[string]interface{})
+	if event.Type != events.PeripheralChanged || data["state"] != "offline" || data["detail"] != "paper out" {
+		t.Errorf("Unexpected printer event %+v", event)
+	}
+
+	if _, err := send(server, asAdmin(t, server, httptest.NewRequest(http.MethodPost, "/sync", nil))); err != nil {
+		t.Fatalf("Sync request failed: %v", err)
+	}
+	if event := readEvent(t, conn); event.Type != events.SyncStarted {
+		t.Errorf("Expected %s, got %+v", events.SyncStarted, event)
+	}
+	if event := readEvent(t, conn); event.Type != events.SyncFinished {
+		t.Errorf("Expected %s, got %+v", events.SyncFinished, event)
+	}
+
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Expected the close to be echoed, got %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for server.events.Subscribers() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := server.events.Subscribers(); n != 0 {
+		t.Errorf("Expected the subscription to end with the connection, got %d", n)
+	}
+}
+
+func TestStatusSocket_ChecksOriginAndTicket(t *testing.T) {
+	server := newTestServerWithDB(t)
+	server.config.AllowedOrigins = []string{"http://localhost:3000"}
+	addr := listen(t, server)
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	if _, _, err := dialStatusSocket(t, server, addr, "http://localhost:3000", token); err != nil {
+		t.Errorf("Configured frontend: dial failed: %v", err)
+	}
+	if _, _, err := dialStatusSocket(t, server, addr, "http://"+addr, token); err != nil {
+		t.Errorf("Own pages: dial failed: %v", err)
+	}
+	if _, resp, err := dialStatusSocket(t, server, addr, "http://evil.example", token); err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Foreign origin: expected 403, got %v", err)
+	}
+
+	// A ticket opens one stream only
+	ticket := issueStreamTicket(t, server, token)
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	url := "ws://" + addr + APIPrefix + "/ws/status?ticket=" + ticket
+	if conn, _, err := dialer.Dial(url, nil); err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	} else {
+		conn.Close()
+	}
+	if _, resp, err := dialer.Dial(url, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Reused ticket: expected 401, got %v", err)
+	}
+	// Tokens are no longer taken from the query
+	if _, resp, err := dialer.Dial("ws://"+addr+APIPrefix+"/ws/status?access_token="+token, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Token in the query: expected 401, got %v", err)
+	}
+}
+
+func TestStatusSocket_RequiresUpgrade(t *testing.T) {
+	server := newTestServerWithDB(t)
+
+	resp, err := send(server, asAdmin(t, server, httptest.NewRequest(http.MethodGet, "/ws/status", nil)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Upgrade") != "websocket" {
+		t.Errorf("Plain GET returned %d (Upgrade %q), want 426 websocket", resp.StatusCode, resp.Header.Get("Upgrade"))
+	}
+}
+
+func TestStatusSocket_ClosedOnShutdown(t *testing.T) {
+	server := newTestServerWithDB(t)
+	addr := listen(t, server)
+	token, err := adminToken(server)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	conn, _, err := dialStatusSocket(t, server, addr, "", token)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	readEvent(t, conn)
+
+	server.closeStreams()
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a going-away close, got %v", err)
+	}
+}
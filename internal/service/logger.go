@@ -0,0 +1,81 @@
+package service
+
+import (
+	"github.com/kardianos/service"
+	"go.uber.org/zap"
+)
+
+// zapLogger adapts the shared structured logger to service.Logger, so
+// lifecycle messages are tagged component=service in the same pipeline.
+// Each message is also written to the system logger (Event Log, syslog),
+// which is where service managers look first.
+type zapLogger struct {
+	sugar  *zap.SugaredLogger
+	system service.Logger
+}
+
+// newZapLogger wraps logger, forwarding to the system logger as well
+func newZapLogger(logger *zap.Logger, system service.Logger) *zapLogger {
+	return &zapLogger{
+		sugar:  logger.With(zap.String("component", "service")).WithOptions(zap.AddCallerSkip(1)).Sugar(),
+		system: system,
+	}
+}
+
+// Error implements service.Logger
+func (l *zapLogger) Error(v ...interface{}) error {
+	l.sugar.Error(v...)
+	return l.forward(l.system.Error(v...))
+}
+
+// Warning implements service.Logger
+func (l *zapLogger) Warning(v ...interface{}) error {
+	l.sugar.Warn(v...)
+	return l.forward(l.system.Warning(v...))
+}
+
+// Info implements service.Logger
+func (l *zapLogger) Info(v ...interface{}) error {
+	l.sugar.Info(v...)
+	return l.forward(l.system.Info(v...))
+}
+
+// Errorf implements service.Logger
+func (l *zapLogger) Errorf(format string, a ...interface{}) error {
+	l.sugar.Errorf(format, a...)
+	return l.forward(l.system.Errorf(format, a...))
+}
+
+// Warningf implements service.Logger
+func (l *zapLogger) Warningf(format string, a ...interface{}) error {
+	l.sugar.Warnf(format, a...)
+	return l.forward(l.system.Warningf(format, a...))
+}
+
+// Infof implements service.Logger
+func (l *zapLogger) Infof(format string, a ...interface{}) error {
+	l.sugar.Infof(format, a...)
+	return l.forward(l.system.Infof(format, a...))
+}
+
+// forward records a failed system logger write in the structured log
+func (l *zapLogger) forward(err error) error {
+	if err != nil {
+		l.sugar.Warnw("System logger write failed", zap.Error(err))
+	}
+	return err
+}
+
+// captureErrors logs errors kardianos reports asynchronously (from its
+// system logger) until the program's context ends
+func (p *Program) captureErrors(errs <-chan error, logger *zap.Logger) {
+	logger = logger.With(zap.String("component", "service"))
+	for {
+		select {
+		case err := <-errs:
+			logger.Error("Service manager error", zap.Error(err))
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
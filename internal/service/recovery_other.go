@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package service
+
+// ensureRecovery is a no-op: systemd units restart the service on failure
+// (Restart=always in units installed before Restart=on-failure) and
+// launchd keeps it alive
+func ensureRecovery(name string) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package service
+
+import "golang.org/x/sys/windows/svc/mgr"
+
+// ensureRecovery has the SCM start the service again when it exits without
+// being stopped. Services installed by releases before the recovery action
+// was part of the install lack it.
+func ensureRecovery(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.SetRecoveryActions([]mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: restartDelay}}, recoveryResetSeconds)
+}
@@ -2,18 +2,41 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/kardianos/service"
 	"github.com/professor93/promo-pos/pkg/constants"
+	"go.uber.org/zap"
 )
 
+// Errors returned by the Manager's remote control actions
+var (
+	ErrInteractive  = errors.New("not running under the service manager")
+	ErrNotInstalled = errors.New("service is not installed")
+	ErrRunning      = errors.New("service is already running")
+)
+
+// restartExitCode is the exit code of a service restarting itself. It is
+// not 0, so systemd's Restart=on-failure applies.
+const restartExitCode = 3
+
+// restartDelay is how long the service manager waits before starting the
+// service again after it exited without being stopped
+const restartDelay = 2 * time.Second
+
+// recoveryResetSeconds is how long the service must run before Windows
+// forgets earlier failures
+const recoveryResetSeconds = 60
+
 // Program implements the service.Interface from kardianos/service
 type Program struct {
 	ctx    context.Context
 	cancel context.CancelFunc
+	name   string
 
 	// Callbacks for lifecycle events
 	onStart func(ctx context.Context) error
@@ -35,6 +58,10 @@ type Config struct {
 	// Lifecycle callbacks
 	OnStart func(ctx context.Context) error
 	OnStop  func() error
+
+	// Logger receives lifecycle logs and service manager errors; nil logs
+	// to the system logger only
+	Logger *zap.Logger
 }
 
 // New creates a new service program
@@ -54,6 +81,7 @@ func New(cfg *Config) (*Program, error) {
 	p := &Program{
 		ctx:     ctx,
 		cancel:  cancel,
+		name:    cfg.Name,
 		onStart: cfg.OnStart,
 		onStop:  cfg.OnStop,
 	}
@@ -63,6 +91,14 @@ func New(cfg *Config) (*Program, error) {
 		Name:        cfg.Name,
 		DisplayName: cfg.DisplayName,
 		Description: cfg.Description,
+		Option: service.KeyValue{
+			// Start the service again when it exits without being
+			// stopped: after a crash, and to restart (see ScheduleRestart)
+			"OnFailure":              "restart",
+			"OnFailureDelayDuration": restartDelay.String(),
+			"OnFailureResetPeriod":   recoveryResetSeconds,
+			"Restart":                "on-failure",
+		},
 	}
 
 	// Create service
@@ -74,11 +110,22 @@ func New(cfg *Config) (*Program, error) {
 	p.svc = svc
 
 	// Get logger
-	logger, err := svc.Logger(nil)
+	if cfg.Logger == nil {
+		logger, err := svc.Logger(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get logger: %w", err)
+		}
+		p.logger = logger
+		return p, nil
+	}
+
+	errs := make(chan error, 8)
+	system, err := svc.Logger(errs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get logger: %w", err)
 	}
-	p.logger = logger
+	p.logger = newZapLogger(cfg.Logger, system)
+	go p.captureErrors(errs, cfg.Logger)
 
 	return p, nil
 }
@@ -119,6 +166,22 @@ func (p *Program) Stop(s service.Service) error {
 	return nil
 }
 
+// exitForRestart shuts the service down like Stop, then exits with
+// restartExitCode without telling the service manager, which starts the
+// service again
+func (p *Program) exitForRestart() {
+	p.logger.Info("Service restarting...")
+
+	p.cancel()
+	if p.onStop != nil {
+		if err := p.onStop(); err != nil {
+			p.logger.Errorf("Service stop callback failed: %v", err)
+		}
+	}
+
+	os.Exit(restartExitCode)
+}
+
 // Run starts the service and blocks until it's stopped
 func (p *Program) Run() error {
 	return p.svc.Run()
@@ -285,3 +348,58 @@ func (m *Manager) GetStatus() (string, bool, error) {
 func (m *Manager) GetProgram() *Program {
 	return m.program
 }
+
+// Start starts the installed service
+func (m *Manager) Start() error {
+	if err := m.checkControl(); err != nil {
+		return err
+	}
+	if m.program.IsRunning() {
+		return ErrRunning
+	}
+	if err := m.program.StartService(); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	return nil
+}
+
+// ScheduleStop stops the service after delay, so the caller can answer
+// the request that asked for it first
+func (m *Manager) ScheduleStop(delay time.Duration) error {
+	if err := m.checkControl(); err != nil {
+		return err
+	}
+	time.AfterFunc(delay, func() {
+		if err := m.program.StopService(); err != nil {
+			m.program.logger.Errorf("Scheduled stop failed: %v", err)
+		}
+	})
+	return nil
+}
+
+// ScheduleRestart restarts the service after delay. Asking the service
+// manager to restart would stop this process before it could start again,
+// so the service exits instead and the manager's recovery action starts
+// it again.
+func (m *Manager) ScheduleRestart(delay time.Duration) error {
+	if err := m.checkControl(); err != nil {
+		return err
+	}
+	if err := ensureRecovery(m.program.name); err != nil {
+		return fmt.Errorf("failed to configure service recovery: %w", err)
+	}
+	time.AfterFunc(delay, m.program.exitForRestart)
+	return nil
+}
+
+// checkControl reports whether this process can control the service: it
+// must be the installed service, not a console or debug run
+func (m *Manager) checkControl() error {
+	if service.Interactive() {
+		return ErrInteractive
+	}
+	if !m.program.IsInstalled() {
+		return ErrNotInstalled
+	}
+	return nil
+}
@@ -5,6 +5,10 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestNew(t *testing.T) {
@@ -302,6 +306,42 @@ func TestProgram_Logger(t *testing.T) {
 	logger.Error("Test error message")
 }
 
+func TestProgram_StructuredLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	program, err := New(&Config{
+		Name:   "TestStructuredLoggerService",
+		Logger: zap.New(core),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	defer program.cancel()
+
+	program.Logger().Warningf("Lane %d offline", 3)
+
+	entries := logs.FilterField(zap.String("component", "service")).All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one service entry, got %d", len(entries))
+	}
+	if entries[0].Message != "Lane 3 offline" || entries[0].Level != zapcore.WarnLevel {
+		t.Errorf("Unexpected entry %q at %v", entries[0].Message, entries[0].Level)
+	}
+
+	// Errors kardianos reports asynchronously land in the same pipeline
+	errs := make(chan error, 1)
+	go program.captureErrors(errs, zap.New(core))
+	errs <- errors.New("event log unavailable")
+
+	deadline := time.Now().Add(time.Second)
+	for logs.FilterMessage("Service manager error").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Service manager error was not logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestProgram_StartStop_Concurrent(t *testing.T) {
 	cfg := &Config{
 		Name: "TestConcurrentService",
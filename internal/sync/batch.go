@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// BatchSizer sizes outbox uploads (see database.NextOutboxBatch) to the
+// measured throughput of the link, so a batch takes about the target time
+// whether the store is on fibre or a congested mobile uplink. Failures halve
+// the estimate, backing off until the link recovers.
+type BatchSizer struct {
+	target   time.Duration
+	minBytes int
+	maxBytes int
+	rate     atomic.Int64 // Estimated bytes per second; 0 until the first upload
+}
+
+// NewBatchSizer creates a sizer aiming for uploads of target duration,
+// with budgets clamped to [minBytes, maxBytes]
+func NewBatchSizer(target time.Duration, minBytes, maxBytes int) *BatchSizer {
+	return &BatchSizer{target: target, minBytes: minBytes, maxBytes: maxBytes}
+}
+
+// Budget returns the byte budget for the next batch. Until an upload has
+// been measured it is the minimum, so the first batch after an outage
+// cannot stall on an unknown link.
+func (s *BatchSizer) Budget() int {
+	rate := s.rate.Load()
+	if rate == 0 {
+		return s.minBytes
+	}
+
+	budget := int(float64(rate) * s.target.Seconds())
+	if budget < s.minBytes {
+		return s.minBytes
+	}
+	if budget > s.maxBytes {
+		return s.maxBytes
+	}
+	return budget
+}
+
+// Observe records a successful upload of n bytes that took elapsed
+func (s *BatchSizer) Observe(n int, elapsed time.Duration) {
+	if n <= 0 || elapsed <= 0 {
+		return
+	}
+
+	measured := int64(float64(n) / elapsed.Seconds())
+	previous := s.rate.Load()
+	if previous == 0 {
+		s.rate.Store(measured)
+		return
+	}
+	// Exponential moving average, weighting the newest sample by a quarter
+	s.rate.Store((3*previous + measured) / 4)
+}
+
+// Failed records a failed or timed-out upload
+func (s *BatchSizer) Failed() {
+	s.rate.Store(s.rate.Load() / 2)
+}
@@ -0,0 +1,37 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchSizer_FollowsThroughput(t *testing.T) {
+	sizer := NewBatchSizer(2*time.Second, 16<<10, 4<<20)
+
+	if budget := sizer.Budget(); budget != 16<<10 {
+		t.Errorf("Expected the minimum before any upload, got %d", budget)
+	}
+
+	// 100 KB/s link: two seconds' worth is 200 KB
+	sizer.Observe(100_000, time.Second)
+	if budget := sizer.Budget(); budget != 200_000 {
+		t.Errorf("Expected 200000 bytes, got %d", budget)
+	}
+
+	// A fast link is capped
+	for i := 0; i < 20; i++ {
+		sizer.Observe(10<<20, time.Second)
+	}
+	if budget := sizer.Budget(); budget != 4<<20 {
+		t.Errorf("Expected the maximum on a fast link, got %d", budget)
+	}
+
+	// Failures back off
+	before := sizer.Budget()
+	for i := 0; i < 12; i++ {
+		sizer.Failed()
+	}
+	if budget := sizer.Budget(); budget >= before || budget != 16<<10 {
+		t.Errorf("Expected failures to back off to the minimum, got %d", budget)
+	}
+}
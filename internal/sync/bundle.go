@@ -0,0 +1,360 @@
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// Air-gapped sync moves data on removable media for kiosks without network.
+// A bundle is a JSON envelope whose body is encrypted with the server key
+// and signed with a key derived from it, so only head office and enrolled
+// terminals can read or forge bundles.
+
+const (
+	// BundleVersion is the current bundle envelope version
+	BundleVersion = 1
+
+	// BundleFileExt is the extension of sync bundle files
+	BundleFileExt = ".posbundle"
+
+	// Bundle directions
+	DirectionOutbound = "outbound" // Terminal -> head office
+	DirectionInbound  = "inbound"  // Head office -> terminal
+
+	// signingPurpose derives the bundle signing key from the server key
+	signingPurpose = "pos-sync-bundle-signature-v1"
+
+	// Settings keys tracking bundle cursors
+	settingOutboundSeq = "airgap.outbound_seq"
+	settingInboundSeq  = "airgap.inbound_seq"
+)
+
+var (
+	ErrBundleSignature = errors.New("bundle signature is invalid")
+	ErrBundleReplay    = errors.New("bundle has already been applied")
+	ErrBundleForeign   = errors.New("bundle is addressed to another store")
+)
+
+// Bundle is the signed envelope written to removable media
+type Bundle struct {
+	Version   int    `json:"version"`
+	BundleID  string `json:"bundle_id"`
+	Direction string `json:"direction"`
+	StoreID   string `json:"store_id"`
+	MachineID string `json:"machine_id,omitempty"`
+	Sequence  int64  `json:"sequence"` // Monotonic per direction; guards against replay
+	CreatedAt string `json:"created_at"`
+	Body      string `json:"body"`      // Encrypted JSON body
+	Signature string `json:"signature"` // Base64 HMAC-SHA256 over the other fields
+}
+
+// OutboundBody is what a terminal sends to head office
+type OutboundBody struct {
+	Entries       []database.OutboxEntry `json:"entries"`        // In priority order (see database.OutboxClass)
+	LastOutboxID  int64                  `json:"last_outbox_id"` // Set only when Entries holds the whole queue
+	ClassCursors  map[string]int64       `json:"class_cursors"`  // Last entry ID sent per priority class
+	InboundCursor int64                  `json:"inbound_cursor"` // Last inbound sequence applied
+}
+
+// InboundBody is what head office sends to a terminal
+type InboundBody struct {
+	Products          []database.Product `json:"products"`
+	DeletedProductIDs []string           `json:"deleted_product_ids,omitempty"`
+	AckedOutboxID     int64              `json:"acked_outbox_id"` // Outbox entries received from this terminal
+
+	// AckedClassCursors acknowledges the outbox per priority class (echo ClassCursors)
+	AckedClassCursors map[string]int64 `json:"acked_class_cursors,omitempty"`
+
+	// RecommendationRules replaces the local rule set when present (nil leaves it unchanged)
+	RecommendationRules []database.RecommendationRule `json:"recommendation_rules,omitempty"`
+
+	// Promotions replaces the local promotion set when present (nil leaves it unchanged)
+	Promotions []database.Promotion `json:"promotions,omitempty"`
+
+	// Customers carry head office's loyalty balances, which include every
+	// outbox entry acknowledged in the same bundle
+	Customers          []database.Customer `json:"customers,omitempty"`
+	DeletedCustomerIDs []string            `json:"deleted_customer_ids,omitempty"`
+}
+
+// BundleSyncer exports and imports air-gapped sync bundles
+type BundleSyncer struct {
+	db         *database.DB
+	encryption *security.DatabaseEncryption
+	signingKey atomic.Pointer[[]byte]
+	storeID    string
+	machineID  string
+}
+
+// NewBundleSyncer creates a bundle syncer keyed by the server key
+func NewBundleSyncer(db *database.DB, serverKey []byte, storeID, machineID string) (*BundleSyncer, error) {
+	encryption, err := security.NewDatabaseEncryption(serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle encryption: %w", err)
+	}
+
+	b := &BundleSyncer{
+		db:         db,
+		encryption: encryption,
+		storeID:    storeID,
+		machineID:  machineID,
+	}
+	signingKey := security.DeriveSubkey(serverKey, signingPurpose)
+	b.signingKey.Store(&signingKey)
+	return b, nil
+}
+
+// Rekey switches bundle encryption and signing to a rotated server key
+func (b *BundleSyncer) Rekey(serverKey []byte) error {
+	if err := b.encryption.SetKey(serverKey); err != nil {
+		return err
+	}
+	signingKey := security.DeriveSubkey(serverKey, signingPurpose)
+	b.signingKey.Store(&signingKey)
+	return nil
+}
+
+// Close wipes the bundle keys; the syncer cannot be used afterwards
+func (b *BundleSyncer) Close() {
+	b.encryption.Close()
+	if signingKey := b.signingKey.Load(); signingKey != nil {
+		security.Wipe(*signingKey)
+	}
+}
+
+// Export writes the pending outbound queue, most urgent class first, and
+// sync state to a bundle in dir and returns its path. Entries stay pending until head office
+// acknowledges them through an inbound bundle.
+func (b *BundleSyncer) Export(dir string, limit int) (string, error) {
+	// Anchor the audit log's head so head office can detect local
+	// tampering; a read-only (offline too long) database still exports
+	if _, err := b.db.AnchorAudit(); err != nil && !errors.Is(err, database.ErrReadOnly) {
+		return "", err
+	}
+
+	entries, err := b.db.GetPendingOutbox(limit)
+	if err != nil {
+		return "", err
+	}
+
+	pending, err := b.db.CountPendingOutbox()
+	if err != nil {
+		return "", err
+	}
+
+	body := OutboundBody{
+		Entries:       entries,
+		ClassCursors:  make(map[string]int64),
+		InboundCursor: int64(b.db.GetSettingIntDefault(settingInboundSeq, 0)),
+	}
+	for _, entry := range entries {
+		body.ClassCursors[entry.Class] = entry.ID
+		// A plain cursor is only safe when nothing older was held back
+		if len(entries) == pending && entry.ID > body.LastOutboxID {
+			body.LastOutboxID = entry.ID
+		}
+	}
+
+	sequence := int64(b.db.GetSettingIntDefault(settingOutboundSeq, 0)) + 1
+
+	bundle, err := b.seal(DirectionOutbound, sequence, body)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%06d%s", DirectionOutbound, b.storeID, sequence, BundleFileExt)
+	path := filepath.Join(dir, name)
+	if err := writeBundle(path, bundle); err != nil {
+		return "", err
+	}
+
+	if err := b.db.SetSettingInt(settingOutboundSeq, int(sequence)); err != nil {
+		return "", fmt.Errorf("failed to advance outbound sequence: %w", err)
+	}
+
+	return path, nil
+}
+
+// Import verifies and applies an inbound bundle from head office
+func (b *BundleSyncer) Import(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	var body InboundBody
+	if err := b.Open(&bundle, DirectionInbound, &body); err != nil {
+		return nil, err
+	}
+
+	// Replay protection: sequences must strictly increase
+	lastApplied := int64(b.db.GetSettingIntDefault(settingInboundSeq, 0))
+	if bundle.Sequence <= lastApplied {
+		return nil, fmt.Errorf("%w: sequence %d, last applied %d", ErrBundleReplay, bundle.Sequence, lastApplied)
+	}
+
+	if err := b.db.ApplyProducts(body.Products, body.DeletedProductIDs); err != nil {
+		return nil, fmt.Errorf("failed to apply bundle: %w", err)
+	}
+
+	if body.RecommendationRules != nil {
+		if err := b.db.ReplaceRecommendationRules(body.RecommendationRules); err != nil {
+			return nil, fmt.Errorf("failed to apply bundle: %w", err)
+		}
+	}
+	if body.Promotions != nil {
+		if err := b.db.ReplacePromotions(body.Promotions); err != nil {
+			return nil, fmt.Errorf("failed to apply bundle: %w", err)
+		}
+	}
+
+	// Cursor reconciliation: head office tells us how far it received our outbox
+	if body.AckedOutboxID > 0 {
+		if _, err := b.db.MarkOutboxSyncedThrough(body.AckedOutboxID); err != nil {
+			return nil, err
+		}
+	}
+	for class, lastID := range body.AckedClassCursors {
+		if _, err := b.db.MarkOutboxClassSyncedThrough(class, lastID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Customers go last: their balances include the entries acknowledged above
+	if len(body.Customers) > 0 || len(body.DeletedCustomerIDs) > 0 {
+		if err := b.db.ApplyCustomers(body.Customers, body.DeletedCustomerIDs); err != nil {
+			return nil, fmt.Errorf("failed to apply bundle: %w", err)
+		}
+	}
+
+	if err := b.db.SetSettingInt(settingInboundSeq, int(bundle.Sequence)); err != nil {
+		return nil, fmt.Errorf("failed to advance inbound sequence: %w", err)
+	}
+
+	// A bundle fully applied is a successful sync with head office: it
+	// resets the offline clock the service's watch goes by
+	if err := b.db.RecordSync(time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to record the sync: %w", err)
+	}
+
+	return &bundle, nil
+}
+
+// Seal builds a signed bundle around body (used by head office tooling and tests)
+func (b *BundleSyncer) Seal(direction string, sequence int64, body interface{}) (*Bundle, error) {
+	return b.seal(direction, sequence, body)
+}
+
+// Open verifies a bundle's signature, store and direction and decrypts its body into v
+func (b *BundleSyncer) Open(bundle *Bundle, direction string, v interface{}) error {
+	if bundle.Version != BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(bundle.Signature)
+	if err != nil || !hmac.Equal(expected, b.sign(bundle)) {
+		return ErrBundleSignature
+	}
+
+	if bundle.Direction != direction {
+		return fmt.Errorf("expected %s bundle, got %s", direction, bundle.Direction)
+	}
+	if bundle.StoreID != b.storeID {
+		return fmt.Errorf("%w: %s", ErrBundleForeign, bundle.StoreID)
+	}
+
+	plaintext, err := b.encryption.Decrypt(bundle.Body)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt bundle: %w", err)
+	}
+
+	if err := json.Unmarshal(plaintext, v); err != nil {
+		return fmt.Errorf("failed to parse bundle body: %w", err)
+	}
+
+	return nil
+}
+
+// seal encrypts and signs a bundle body
+func (b *BundleSyncer) seal(direction string, sequence int64, body interface{}) (*Bundle, error) {
+	plaintext, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle body: %w", err)
+	}
+
+	encrypted, err := b.encryption.Encrypt(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+
+	bundle := &Bundle{
+		Version:   BundleVersion,
+		BundleID:  ids.New(),
+		Direction: direction,
+		StoreID:   b.storeID,
+		MachineID: b.machineID,
+		Sequence:  sequence,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Body:      encrypted,
+	}
+	bundle.Signature = base64.StdEncoding.EncodeToString(b.sign(bundle))
+
+	return bundle, nil
+}
+
+// sign computes the bundle signature over every field except Signature
+func (b *BundleSyncer) sign(bundle *Bundle) []byte {
+	mac := hmac.New(sha256.New, *b.signingKey.Load())
+	for _, field := range []string{
+		strconv.Itoa(bundle.Version),
+		bundle.BundleID,
+		bundle.Direction,
+		bundle.StoreID,
+		bundle.MachineID,
+		strconv.FormatInt(bundle.Sequence, 10),
+		bundle.CreatedAt,
+		bundle.Body,
+	} {
+		// Length-prefix each field so boundaries cannot be shifted
+		mac.Write([]byte(strconv.Itoa(len(field)) + ":" + field + "\n"))
+	}
+	return mac.Sum(nil)
+}
+
+// writeBundle writes a bundle atomically (removable media may be pulled mid-write)
+func writeBundle(path string, bundle *Bundle) error {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename bundle: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+func setupSyncer(t *testing.T) (*BundleSyncer, *database.DB) {
+	serverKey, err := security.GenerateServerKey()
+	if err != nil {
+		t.Fatalf("Failed to generate server key: %v", err)
+	}
+
+	db, err := database.New(&database.Config{ServerKey: serverKey, InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	syncer, err := NewBundleSyncer(db, serverKey, "store-1", "machine-1")
+	if err != nil {
+		t.Fatalf("NewBundleSyncer failed: %v", err)
+	}
+	return syncer, db
+}
+
+func writeInbound(t *testing.T, syncer *BundleSyncer, sequence int64, body *InboundBody) string {
+	bundle, err := syncer.Seal(DirectionInbound, sequence, body)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "inbound"+BundleFileExt)
+	if err := writeBundle(path, bundle); err != nil {
+		t.Fatalf("writeBundle failed: %v", err)
+	}
+	return path
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	syncer, db := setupSyncer(t)
+
+	product := &database.Product{ID: "p1", Barcode: "111", Name: "Milk", Price: 990}
+	if err := db.UpsertProduct(product, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	path, err := syncer.Export(t.TempDir(), 100)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		t.Fatalf("Failed to parse bundle: %v", err)
+	}
+	if bundle.Sequence != 1 {
+		t.Errorf("Expected sequence 1, got %d", bundle.Sequence)
+	}
+
+	var body OutboundBody
+	if err := syncer.Open(&bundle, DirectionOutbound, &body); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if len(body.Entries) != 1 || body.LastOutboxID != body.Entries[0].ID {
+		t.Errorf("Unexpected outbound body: %+v", body)
+	}
+
+	// Entries stay pending until acknowledged
+	if count, _ := db.CountPendingOutbox(); count != 1 {
+		t.Errorf("Expected 1 pending entry after export, got %d", count)
+	}
+}
+
+func TestOpen_RejectsTampering(t *testing.T) {
+	syncer, _ := setupSyncer(t)
+
+	bundle, err := syncer.Seal(DirectionInbound, 1, &InboundBody{})
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	bundle.Sequence = 99
+
+	var body InboundBody
+	if err := syncer.Open(bundle, DirectionInbound, &body); !errors.Is(err, ErrBundleSignature) {
+		t.Errorf("Expected ErrBundleSignature, got %v", err)
+	}
+}
+
+func TestImport_AppliesAndReconciles(t *testing.T) {
+	syncer, db := setupSyncer(t)
+
+	local := &database.Product{ID: "p1", Barcode: "111", Name: "Milk", Price: 990}
+	if err := db.UpsertProduct(local, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+	pending, _ := db.GetPendingOutbox(10)
+
+	path := writeInbound(t, syncer, 1, &InboundBody{
+		Products:      []database.Product{{ID: "p2", Barcode: "222", Name: "Bread", Price: 450}},
+		AckedOutboxID: pending[len(pending)-1].ID,
+	})
+
+	if _, err := syncer.Import(path); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if _, err := db.GetProductByBarcode("222"); err != nil {
+		t.Errorf("Expected imported product: %v", err)
+	}
+	if count, _ := db.CountPendingOutbox(); count != 0 {
+		t.Errorf("Expected acknowledged outbox to be drained, got %d pending", count)
+	}
+	if last, err := db.LastSync(); err != nil || last.IsZero() {
+		t.Errorf("Expected the import recorded as a sync, got %v (%v)", last, err)
+	}
+
+	// Replaying the same bundle must fail
+	if _, err := syncer.Import(path); !errors.Is(err, ErrBundleReplay) {
+		t.Errorf("Expected ErrBundleReplay, got %v", err)
+	}
+}
+
+func TestImport_RejectsForeignStore(t *testing.T) {
+	syncer, db := setupSyncer(t)
+
+	other := &BundleSyncer{db: db, encryption: syncer.encryption, storeID: "store-2", machineID: syncer.machineID}
+	other.signingKey.Store(syncer.signingKey.Load())
+	path := writeInbound(t, other, 1, &InboundBody{})
+
+	if _, err := syncer.Import(path); !errors.Is(err, ErrBundleForeign) {
+		t.Errorf("Expected ErrBundleForeign, got %v", err)
+	}
+	if seq := db.GetSettingIntDefault(settingInboundSeq, 0); seq != 0 {
+		t.Errorf("Expected inbound sequence unchanged, got %d", seq)
+	}
+}
+
+func TestExport_PartialBundleUsesClassCursors(t *testing.T) {
+	syncer, db := setupSyncer(t)
+
+	// Telemetry queued before a catalog edit
+	db.GetConnection().Exec("INSERT INTO outbox (entity, entity_id, operation, payload) VALUES ('operator_stats', 'op1', 'insert', '{}')")
+	if err := db.UpsertProduct(&database.Product{ID: "p1", Barcode: "111"}, database.ProductSourceLocal); err != nil {
+		t.Fatalf("UpsertProduct failed: %v", err)
+	}
+
+	path, err := syncer.Export(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var bundle Bundle
+	json.Unmarshal(data, &bundle)
+	var body OutboundBody
+	if err := syncer.Open(&bundle, DirectionOutbound, &body); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if len(body.Entries) != 1 || body.Entries[0].Entity != "products" {
+		t.Fatalf("Expected the catalog edit ahead of telemetry, got %+v", body.Entries)
+	}
+	// A plain cursor would acknowledge the older telemetry entry too
+	if body.LastOutboxID != 0 {
+		t.Errorf("Expected no plain cursor for a partial bundle, got %d", body.LastOutboxID)
+	}
+
+	inbound := writeInbound(t, syncer, 1, &InboundBody{AckedClassCursors: body.ClassCursors})
+	if _, err := syncer.Import(inbound); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if count, _ := db.CountPendingOutbox(); count != 1 {
+		t.Errorf("Expected the telemetry entry to stay pending, got %d pending", count)
+	}
+}
@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/database"
+	"github.com/professor93/promo-pos/internal/logging"
+)
+
+// Once a terminal has gone longer than the offline grace period without a
+// successful sync, its prices and promotions may be stale and unsynced
+// sales pile up, so the sync layer switches the terminal to offline mode
+// until a sync gets through. It goes by the last successful sync the
+// database keeps (see database.RecordSync), so the grace period survives
+// restarts and syncs applied by another process (bundle imports) count. A
+// terminal that has never synced has nothing to go stale; /ready reports it
+// as not bootstrapped instead.
+
+// RunOfflineWatch tells set whether the terminal is offline, checking at
+// once and then every interval until ctx is cancelled. A maxOffline of 0
+// never puts it offline.
+func RunOfflineWatch(ctx context.Context, db *database.DB, interval, maxOffline time.Duration, set func(offline bool)) {
+	if maxOffline <= 0 {
+		return
+	}
+
+	offline := false
+	check := func(now time.Time) {
+		expired, last, err := CheckOffline(db, now, maxOffline)
+		if err != nil {
+			logging.Printf(ctx, "Failed to read the last sync time: %v", err)
+			return
+		}
+		if expired != offline {
+			if expired {
+				logging.Printf(ctx, "Offline grace period exceeded (last sync %s): refusing writes until a sync succeeds", last.Format(time.RFC3339))
+			} else {
+				logging.Printf(ctx, "Offline mode ended")
+			}
+			offline = expired
+		}
+		set(expired)
+	}
+
+	check(time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			check(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CheckOffline reports whether the last successful sync, also returned, is
+// maxOffline old or more as of now. A terminal that never synced, or a
+// maxOffline of 0, is never offline.
+func CheckOffline(db *database.DB, now time.Time, maxOffline time.Duration) (bool, time.Time, error) {
+	last, err := db.LastSync()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if last.IsZero() || maxOffline <= 0 {
+		return false, last, nil
+	}
+	return now.Sub(last) >= maxOffline, last, nil
+}
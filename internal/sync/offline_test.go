@@ -0,0 +1,58 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCheckOffline(t *testing.T) {
+	_, db := setupSyncer(t)
+	now := time.Now()
+
+	// A terminal that never synced stays online
+	if offline, _, err := CheckOffline(db, now.Add(1000*time.Hour), 72*time.Hour); err != nil || offline {
+		t.Fatalf("Expected a never-synced terminal to stay online, got %v (%v)", offline, err)
+	}
+
+	if err := db.RecordSync(now); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+	if offline, last, _ := CheckOffline(db, now.Add(71*time.Hour), 72*time.Hour); offline || !last.Equal(now) {
+		t.Errorf("Expected online within the grace period since %s, got %v since %s", now, offline, last)
+	}
+	if offline, _, _ := CheckOffline(db, now.Add(72*time.Hour), 72*time.Hour); !offline {
+		t.Error("Expected offline once the grace period lapsed")
+	}
+	if offline, _, _ := CheckOffline(db, now.Add(1000*time.Hour), 0); offline {
+		t.Error("Expected a maxOffline of 0 never to go offline")
+	}
+
+	// A sync that gets through restarts the clock
+	if err := db.RecordSync(now.Add(72 * time.Hour)); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+	if offline, _, _ := CheckOffline(db, now.Add(100*time.Hour), 72*time.Hour); offline {
+		t.Error("Expected the grace period to restart from the last sync")
+	}
+}
+
+func TestRunOfflineWatch(t *testing.T) {
+	_, db := setupSyncer(t)
+	if err := db.RecordSync(time.Now().Add(-48 * time.Hour)); err != nil {
+		t.Fatalf("RecordSync failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan bool, 1)
+	go RunOfflineWatch(ctx, db, time.Hour, 24*time.Hour, func(offline bool) { changes <- offline })
+
+	if offline := <-changes; !offline {
+		t.Error("Expected the watch to report the terminal offline")
+	}
+	// The watch only reports; the database flag is the caller's business
+	if db.IsReadOnly() {
+		t.Error("Expected the watch to leave the database alone")
+	}
+}
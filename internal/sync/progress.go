@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"strconv"
+	gosync "sync"
+
+	"github.com/google/uuid"
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/internal/jobs"
+)
+
+// Sync modes
+const (
+	ModeBootstrap = "bootstrap" // First sync of a terminal, pulling the full catalog
+	ModeRegular   = "regular"   // Periodic or manual sync of the changes since the last one
+)
+
+// ProgressReport is the data of every event a Tracker publishes
+type ProgressReport struct {
+	Run     string `json:"run"` // Identifies the run; every event of one run carries the same
+	Mode    string `json:"mode"`
+	Batches int    `json:"batches"`           // Batches transferred so far
+	Records int    `json:"records"`           // Records transferred so far
+	Total   int    `json:"total,omitempty"`   // Records to transfer; 0 if unknown
+	Percent int    `json:"percent,omitempty"` // Only known with a total
+	Error   string `json:"error,omitempty"`   // Set on sync.finished when the sync failed
+}
+
+// Tracker publishes the progress of one sync run, batch by batch, for the
+// admin UI's progress bar (GET /sync/events). A bootstrap sync running as a
+// job also reports to the job, so GET /jobs/:id follows along.
+type Tracker struct {
+	bus *events.Bus
+	job *jobs.Progress
+
+	mu     gosync.Mutex
+	report ProgressReport
+}
+
+// StartTracker publishes the start of a sync expected to transfer total
+// records (0 if unknown). A nil bus publishes nothing.
+func StartTracker(bus *events.Bus, mode string, total int) *Tracker {
+	t := &Tracker{bus: bus, report: ProgressReport{Run: uuid.NewString(), Mode: mode, Total: total}}
+	t.publish(events.SyncStarted, t.report)
+	return t
+}
+
+// Run returns the ID every event of this run carries, so a caller can
+// tell its run's events from those of others on the bus
+func (t *Tracker) Run() string {
+	return t.report.Run
+}
+
+// WithJob also reports progress to the job running the sync
+func (t *Tracker) WithJob(p *jobs.Progress) *Tracker {
+	t.job = p
+	return t
+}
+
+// Batch records a transferred batch of n records
+func (t *Tracker) Batch(n int) {
+	t.mu.Lock()
+	t.report.Batches++
+	t.report.Records += n
+	if t.report.Total > 0 {
+		t.report.Percent = min(100, t.report.Records*100/t.report.Total)
+	}
+	report := t.report
+	t.mu.Unlock()
+
+	if t.job != nil {
+		t.job.Update(report.Percent, "Synced batch "+strconv.Itoa(report.Batches))
+	}
+	t.publish(events.SyncProgress, report)
+}
+
+// Finish publishes the end of the sync, failed if err is not nil
+func (t *Tracker) Finish(err error) ProgressReport {
+	t.mu.Lock()
+	report := t.report
+	t.mu.Unlock()
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+	t.publish(events.SyncFinished, report)
+	return report
+}
+
+func (t *Tracker) publish(eventType string, report ProgressReport) {
+	if t.bus != nil {
+		t.bus.Publish(eventType, report)
+	}
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/events"
+)
+
+func TestTracker_PublishesBatches(t *testing.T) {
+	bus := events.NewBus()
+	stream, cancel := bus.Subscribe(8)
+	defer cancel()
+
+	tracker := StartTracker(bus, ModeBootstrap, 250)
+	tracker.Batch(100)
+	tracker.Batch(100)
+	tracker.Batch(50)
+	final := tracker.Finish(errors.New("backend unreachable"))
+
+	want := []struct {
+		eventType string
+		records   int
+		percent   int
+	}{
+		{events.SyncStarted, 0, 0},
+		{events.SyncProgress, 100, 40},
+		{events.SyncProgress, 200, 80},
+		{events.SyncProgress, 250, 100},
+		{events.SyncFinished, 250, 100},
+	}
+	for i, w := range want {
+		event := <-stream
+		report, ok := event.Data.(ProgressReport)
+		if event.Type != w.eventType || !ok || report.Records != w.records || report.Percent != w.percent {
+			t.Errorf("Event %d: got %s %+v, want %s with %d records at %d%%", i, event.Type, event.Data, w.eventType, w.records, w.percent)
+		}
+	}
+	if final.Batches != 3 || final.Mode != ModeBootstrap || final.Error != "backend unreachable" {
+		t.Errorf("Unexpected final report %+v", final)
+	}
+
+	// Without a bus nothing is published, and nothing breaks
+	StartTracker(nil, ModeRegular, 0).Batch(10)
+}
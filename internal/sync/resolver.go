@@ -0,0 +1,243 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDNSCacheTTL is how long a successful lookup is reused
+	DefaultDNSCacheTTL = 5 * time.Minute
+
+	// DefaultDNSStaleTTL is how long an expired lookup may still be used
+	// when the resolver fails; store routers drop DNS far more often than
+	// the backend changes address
+	DefaultDNSStaleTTL = 24 * time.Hour
+
+	// DefaultFallbackDelay is the head start of one connection attempt
+	// over the next (RFC 8305 recommends 250ms)
+	DefaultFallbackDelay = 250 * time.Millisecond
+)
+
+// Resolver resolves backend hosts with a cache, so a flaky store router
+// does not turn into a spurious offline period. When a lookup fails it
+// falls back to the last good answer and then to static addresses from the
+// configuration.
+type Resolver struct {
+	ttl      time.Duration
+	staleTTL time.Duration
+	fallback map[string][]net.IP
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]dnsEntry
+}
+
+// dnsEntry is one cached lookup
+type dnsEntry struct {
+	ips      []net.IP
+	resolved time.Time
+}
+
+// NewResolver creates a caching resolver. fallback maps host names to
+// static addresses used when neither DNS nor the cache can answer.
+func NewResolver(ttl time.Duration, fallback map[string][]string) (*Resolver, error) {
+	if ttl <= 0 {
+		ttl = DefaultDNSCacheTTL
+	}
+
+	static := make(map[string][]net.IP, len(fallback))
+	for host, addrs := range fallback {
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid fallback address %q for %s", addr, host)
+			}
+			static[host] = append(static[host], ip)
+		}
+	}
+
+	return &Resolver{
+		ttl:      ttl,
+		staleTTL: DefaultDNSStaleTTL,
+		fallback: static,
+		lookup:   net.DefaultResolver.LookupIPAddr,
+		now:      time.Now,
+		cache:    make(map[string]dnsEntry),
+	}, nil
+}
+
+// Resolve returns the addresses of host
+func (r *Resolver) Resolve(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	r.mu.Lock()
+	entry, cached := r.cache[host]
+	r.mu.Unlock()
+
+	age := r.now().Sub(entry.resolved)
+	if cached && age < r.ttl {
+		return entry.ips, nil
+	}
+
+	addrs, err := r.lookup(ctx, host)
+	if err == nil && len(addrs) > 0 {
+		ips := make([]net.IP, len(addrs))
+		for i, addr := range addrs {
+			ips[i] = addr.IP
+		}
+
+		r.mu.Lock()
+		r.cache[host] = dnsEntry{ips: ips, resolved: r.now()}
+		r.mu.Unlock()
+		return ips, nil
+	}
+
+	if cached && age < r.staleTTL {
+		return entry.ips, nil
+	}
+	if ips, ok := r.fallback[host]; ok {
+		return ips, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no addresses for %s", host)
+	}
+	return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+}
+
+// HappyEyeballsDialer dials a host's addresses resolved by a Resolver,
+// alternating IPv6 and IPv4 and starting the next attempt whenever the
+// current one fails or has not connected within the fallback delay, so a
+// broken address family costs a fraction of a second instead of a timeout.
+type HappyEyeballsDialer struct {
+	Resolver      *Resolver
+	Dialer        net.Dialer
+	FallbackDelay time.Duration // Zero uses DefaultFallbackDelay
+}
+
+// DialContext implements the http.Transport dial hook
+func (d *HappyEyeballsDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := d.Resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range interleaveFamilies(ips) {
+		addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+	}
+	return d.dialParallel(ctx, network, addrs)
+}
+
+// dialParallel races staggered connection attempts and returns the first
+// to succeed; later winners are closed
+func (d *HappyEyeballsDialer) dialParallel(ctx context.Context, network string, addrs []string) (net.Conn, error) {
+	delay := d.FallbackDelay
+	if delay <= 0 {
+		delay = DefaultFallbackDelay
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(addrs))
+
+	// Close connections that lose the race once the winner is returned
+	discard := func(pending int) {
+		go func() {
+			for ; pending > 0; pending-- {
+				if r := <-results; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var firstErr error
+	next, pending := 0, 0
+	for {
+		if next == len(addrs) && pending == 0 {
+			return nil, firstErr
+		}
+
+		var start <-chan time.Time
+		if next < len(addrs) {
+			start = timer.C
+		}
+
+		select {
+		case <-start:
+			addr := addrs[next]
+			next++
+			pending++
+			go func() {
+				conn, err := d.Dialer.DialContext(ctx, network, addr)
+				results <- result{conn, err}
+			}()
+			timer.Reset(delay)
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				discard(pending)
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			// Do not wait out the delay behind a failed attempt
+			timer.Reset(0)
+
+		case <-ctx.Done():
+			discard(pending)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// interleaveFamilies orders addresses alternating between IPv6 and IPv4,
+// starting with the family of the first address (RFC 8305 section 4)
+func interleaveFamilies(ips []net.IP) []net.IP {
+	var v6, v4 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	first, second := v6, v4
+	if len(ips) > 0 && ips[0].To4() != nil {
+		first, second = v4, v6
+	}
+
+	ordered := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,120 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestResolver_CachesAndFallsBack(t *testing.T) {
+	resolver, err := NewResolver(time.Minute, map[string][]string{"backend.example": {"192.0.2.10"}})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+
+	now := time.Now()
+	resolver.now = func() time.Time { return now }
+	lookups := 0
+	dnsDown := false
+	resolver.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		if dnsDown {
+			return nil, errors.New("router dropped the query")
+		}
+		return []net.IPAddr{{IP: net.ParseIP("198.51.100.7")}}, nil
+	}
+
+	resolve := func() string {
+		ips, err := resolver.Resolve(context.Background(), "backend.example")
+		if err != nil || len(ips) != 1 {
+			t.Fatalf("Resolve failed: %v, %v", ips, err)
+		}
+		return ips[0].String()
+	}
+
+	if ip := resolve(); ip != "198.51.100.7" {
+		t.Errorf("Expected the DNS answer, got %s", ip)
+	}
+	resolve()
+	if lookups != 1 {
+		t.Errorf("Expected the second call to hit the cache, got %d lookups", lookups)
+	}
+
+	// DNS fails after expiry: the stale answer is kept
+	dnsDown = true
+	now = now.Add(time.Hour)
+	if ip := resolve(); ip != "198.51.100.7" {
+		t.Errorf("Expected the stale answer, got %s", ip)
+	}
+
+	// Nothing usable cached: the static fallback answers
+	now = now.Add(2 * DefaultDNSStaleTTL)
+	if ip := resolve(); ip != "192.0.2.10" {
+		t.Errorf("Expected the static fallback, got %s", ip)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), "other.example"); err == nil {
+		t.Error("Expected an error for a host without cache or fallback")
+	}
+}
+
+func TestNewResolver_RejectsBadFallback(t *testing.T) {
+	if _, err := NewResolver(0, map[string][]string{"backend.example": {"not-an-ip"}}); err == nil {
+		t.Error("Expected an error for an invalid fallback address")
+	}
+}
+
+func TestInterleaveFamilies(t *testing.T) {
+	var ips []net.IP
+	for _, s := range []string{"2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2"} {
+		ips = append(ips, net.ParseIP(s))
+	}
+
+	var got []string
+	for _, ip := range interleaveFamilies(ips) {
+		got = append(got, ip.String())
+	}
+	want := []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestHappyEyeballsDialer_SkipsDeadAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// A port nobody listens on, tried first
+	dead, _ := net.Listen("tcp", "127.0.0.1:0")
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	dialer := &HappyEyeballsDialer{FallbackDelay: time.Second}
+	start := time.Now()
+	conn, err := dialer.dialParallel(context.Background(), "tcp", []string{deadAddr, listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("dialParallel failed: %v", err)
+	}
+	conn.Close()
+
+	// A refused attempt must not hold back the next one for the full delay
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the fallback to start on failure, took %v", elapsed)
+	}
+}
@@ -0,0 +1,48 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+)
+
+// Schedule places a terminal's periodic sync at a fixed offset within each
+// interval. Offsets come from a hash of the machine ID, so a fleet spreads
+// evenly over the interval instead of hitting the backend on the same
+// second, and a terminal keeps its slot across restarts.
+type Schedule struct {
+	Interval time.Duration
+	Offset   time.Duration
+}
+
+// NewSchedule derives the schedule of machineID for interval
+func NewSchedule(machineID string, interval time.Duration) Schedule {
+	return Schedule{Interval: interval, Offset: ScheduleOffset(machineID, interval)}
+}
+
+// ScheduleOffset returns the deterministic offset of machineID within
+// interval, at millisecond granularity
+func ScheduleOffset(machineID string, interval time.Duration) time.Duration {
+	slots := uint64(interval / time.Millisecond)
+	if slots == 0 {
+		return 0
+	}
+
+	sum := sha256.Sum256([]byte(machineID))
+	return time.Duration(binary.BigEndian.Uint64(sum[:8])%slots) * time.Millisecond
+}
+
+// Next returns the first sync time strictly after now. Intervals are
+// aligned to the Unix epoch so every terminal counts them from the same
+// origin and only the offset differs.
+func (s Schedule) Next(now time.Time) time.Time {
+	if s.Interval <= 0 {
+		return now
+	}
+
+	start := now.Truncate(s.Interval).Add(s.Offset)
+	if !start.After(now) {
+		start = start.Add(s.Interval)
+	}
+	return start
+}
@@ -0,0 +1,52 @@
+package sync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestScheduleOffset_DeterministicAndSpread(t *testing.T) {
+	interval := 59 * time.Second
+
+	if ScheduleOffset("machine-1", interval) != ScheduleOffset("machine-1", interval) {
+		t.Fatal("Expected the same offset for the same machine")
+	}
+
+	// A thousand terminals should land in most of the 59 one-second buckets
+	buckets := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		offset := ScheduleOffset(fmt.Sprintf("machine-%d", i), interval)
+		if offset < 0 || offset >= interval {
+			t.Fatalf("Offset %v outside the interval", offset)
+		}
+		buckets[int(offset/time.Second)]++
+	}
+	if len(buckets) < 55 {
+		t.Errorf("Expected offsets spread over the interval, got %d of 59 buckets", len(buckets))
+	}
+	for second, n := range buckets {
+		if n > 50 {
+			t.Errorf("%d terminals share second %d", n, second)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	s := Schedule{Interval: time.Minute, Offset: 15 * time.Second}
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{base, base.Add(15 * time.Second)},
+		{base.Add(15 * time.Second), base.Add(75 * time.Second)},
+		{base.Add(40 * time.Second), base.Add(75 * time.Second)},
+	}
+	for _, tc := range cases {
+		if got := s.Next(tc.now); !got.Equal(tc.want) {
+			t.Errorf("Next(%v) = %v, want %v", tc.now, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sync
+
+import (
+	gosync "sync"
+	"time"
+)
+
+// statsHistory is how many recent sync runs Stats keeps
+const statsHistory = 50
+
+// Stats accumulates the outcome of sync runs and when the backend last
+// answered, for /metrics and the health checks. Offline time is measured
+// from the last successful sync, or from startup before the first one.
+type Stats struct {
+	mu          gosync.RWMutex
+	started     time.Time
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastContact time.Time
+	succeeded   int64
+	failed      int64
+	records     int64
+	history     []Run
+}
+
+// Run is one finished sync run, kept for diagnostics bundles
+type Run struct {
+	At      time.Time `json:"at"`
+	Mode    string    `json:"mode"`
+	Records int       `json:"records"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// StatsSnapshot is a copy of the stats at one moment
+type StatsSnapshot struct {
+	Started     time.Time
+	LastSuccess time.Time // Zero until a sync succeeds
+	LastFailure time.Time // Zero until a sync fails
+	LastContact time.Time // Zero until the backend answers
+	Succeeded   int64
+	Failed      int64
+	Records     int64 // Records transferred by successful syncs
+}
+
+// NewStats creates stats starting now
+func NewStats() *Stats {
+	return &Stats{started: time.Now()}
+}
+
+// Record adds a finished sync run (see Tracker.Finish). A successful run
+// also counts as contact with the backend.
+func (s *Stats) Record(report ProgressReport) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.history) == statsHistory {
+		s.history = append(s.history[:0], s.history[1:]...)
+	}
+	s.history = append(s.history, Run{At: now, Mode: report.Mode, Records: report.Records, Error: report.Error})
+	if report.Error != "" {
+		s.failed++
+		s.lastFailure = now
+		return
+	}
+	s.succeeded++
+	s.records += int64(report.Records)
+	s.lastSuccess = now
+	s.lastContact = now
+}
+
+// Contact records that the backend answered, whether or not the exchange
+// it was part of succeeded
+func (s *Stats) Contact() {
+	now := time.Now()
+
+	s.mu.Lock()
+	s.lastContact = now
+	s.mu.Unlock()
+}
+
+// Snapshot returns a copy of the stats
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return StatsSnapshot{
+		Started:     s.started,
+		LastSuccess: s.lastSuccess,
+		LastFailure: s.lastFailure,
+		LastContact: s.lastContact,
+		Succeeded:   s.succeeded,
+		Failed:      s.failed,
+		Records:     s.records,
+	}
+}
+
+// History returns the most recent sync runs, oldest first
+func (s *Stats) History() []Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Run(nil), s.history...)
+}
@@ -0,0 +1,34 @@
+package sync
+
+import "testing"
+
+func TestStats_Record(t *testing.T) {
+	stats := NewStats()
+
+	stats.Record(ProgressReport{Records: 5})
+	stats.Record(ProgressReport{Records: 3, Error: "backend unreachable"})
+
+	snap := stats.Snapshot()
+	if snap.Succeeded != 1 || snap.Failed != 1 || snap.Records != 5 {
+		t.Errorf("Unexpected stats: %+v", snap)
+	}
+	if snap.LastSuccess.IsZero() || snap.LastFailure.IsZero() || snap.LastContact != snap.LastSuccess {
+		t.Errorf("Expected success, failure and contact times: %+v", snap)
+	}
+}
+
+func TestStats_History(t *testing.T) {
+	stats := NewStats()
+	for i := 0; i < statsHistory+2; i++ {
+		stats.Record(ProgressReport{Mode: ModeRegular, Records: i})
+	}
+	stats.Record(ProgressReport{Mode: ModeRegular, Error: "timeout"})
+
+	history := stats.History()
+	if len(history) != statsHistory {
+		t.Fatalf("Expected %d runs, got %d", statsHistory, len(history))
+	}
+	if history[0].Records != 3 || history[len(history)-1].Error != "timeout" {
+		t.Errorf("Expected the oldest runs dropped first: %+v ... %+v", history[0], history[len(history)-1])
+	}
+}
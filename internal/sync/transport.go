@@ -0,0 +1,228 @@
+package sync
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/logging"
+	"github.com/professor93/promo-pos/internal/security"
+	"github.com/professor93/promo-pos/pkg/constants"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Sync transports. QUIC (HTTP/3) is experimental: it avoids TCP head-of-line
+// stalls on congested LTE links, but UDP may be blocked, so it always falls
+// back to TCP.
+const (
+	TransportTCP  = constants.SyncTransportTCP
+	TransportQUIC = constants.SyncTransportQUIC
+)
+
+// ClientConfig configures the sync HTTP client
+type ClientConfig struct {
+	Transport string        // TransportTCP (default) or TransportQUIC
+	Timeout   time.Duration // Whole-request timeout
+	TLSConfig *tls.Config   // Optional TLS settings shared by both transports
+	Metrics   *TransportMetrics
+
+	// Signer, if set, signs every request and rejects unsigned or
+	// tampered responses
+	Signer *security.RequestSigner
+
+	// Pins, if set, restricts the backend to certificates whose chain
+	// contains one of these SPKI pins (see security.PinnedTLSConfig)
+	Pins []string
+
+	// Resolver, if set, resolves hosts for the TCP transport with caching
+	// and static fallbacks, dialing with happy eyeballs (QUIC keeps the
+	// system resolver)
+	Resolver *Resolver
+}
+
+// NewHTTPClient creates the HTTP client used for sync traffic
+func NewHTTPClient(cfg *ClientConfig) (*http.Client, error) {
+	if cfg == nil {
+		cfg = &ClientConfig{}
+	}
+
+	metrics := cfg.Metrics
+	if metrics == nil {
+		metrics = NewTransportMetrics()
+	}
+
+	tlsConfig, err := security.PinnedTLSConfig(cfg.TLSConfig, cfg.Pins)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate pin: %w", err)
+	}
+
+	tcp := http.DefaultTransport.(*http.Transport).Clone()
+	tcp.TLSClientConfig = tlsConfig
+	if cfg.Resolver != nil {
+		dialer := &HappyEyeballsDialer{
+			Resolver: cfg.Resolver,
+			Dialer:   net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		}
+		tcp.DialContext = dialer.DialContext
+	}
+	tcpTransport := &instrumentedTransport{name: TransportTCP, next: tcp, metrics: metrics}
+
+	var transport http.RoundTripper
+	switch cfg.Transport {
+	case "", TransportTCP:
+		transport = tcpTransport
+	case TransportQUIC:
+		transport = &instrumentedTransport{
+			name: TransportQUIC,
+			next: &http3.RoundTripper{
+				TLSClientConfig: tlsConfig,
+				QUICConfig: &quic.Config{
+					// Sync cycles every ~59s; keep the connection warm between them
+					KeepAlivePeriod: 15 * time.Second,
+					MaxIdleTimeout:  90 * time.Second,
+				},
+			},
+			fallback: tcpTransport,
+			metrics:  metrics,
+		}
+	default:
+		return nil, fmt.Errorf("unknown sync transport: %s", cfg.Transport)
+	}
+
+	if cfg.Signer != nil {
+		transport = &signingTransport{signer: cfg.Signer, next: transport}
+	}
+
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
+
+// signingTransport signs requests and verifies the signed responses
+type signingTransport struct {
+	signer *security.RequestSigner
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	signed := req.Clone(req.Context())
+	if id := logging.RequestID(req.Context()); id != "" && signed.Header.Get("X-Request-ID") == "" {
+		// Let head office log sync made for a local request under its ID
+		signed.Header.Set("X-Request-ID", id)
+	}
+	if err := t.signer.Sign(signed); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.signer.VerifyResponse(signed, resp); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("rejected sync response from %s: %w", req.URL.Host, err)
+	}
+	return resp, nil
+}
+
+// TransportStats summarizes one transport for A/B comparison
+type TransportStats struct {
+	Transport    string  `json:"transport"`
+	Requests     int64   `json:"requests"`
+	Failures     int64   `json:"failures"`
+	Fallbacks    int64   `json:"fallbacks"` // Requests retried over TCP
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// TransportMetrics collects per-transport request counters
+type TransportMetrics struct {
+	tcp  transportCounters
+	quic transportCounters
+}
+
+type transportCounters struct {
+	requests  atomic.Int64
+	failures  atomic.Int64
+	fallbacks atomic.Int64
+	latency   atomic.Int64 // Nanoseconds across successful requests
+}
+
+// NewTransportMetrics creates an empty metrics collector
+func NewTransportMetrics() *TransportMetrics {
+	return &TransportMetrics{}
+}
+
+// Snapshot returns the current stats of every transport
+func (m *TransportMetrics) Snapshot() []TransportStats {
+	return []TransportStats{
+		m.tcp.snapshot(TransportTCP),
+		m.quic.snapshot(TransportQUIC),
+	}
+}
+
+// counters returns the counters for a transport
+func (m *TransportMetrics) counters(name string) *transportCounters {
+	if name == TransportQUIC {
+		return &m.quic
+	}
+	return &m.tcp
+}
+
+// snapshot copies the counters into stats
+func (c *transportCounters) snapshot(name string) TransportStats {
+	stats := TransportStats{
+		Transport: name,
+		Requests:  c.requests.Load(),
+		Failures:  c.failures.Load(),
+		Fallbacks: c.fallbacks.Load(),
+	}
+	if ok := stats.Requests - stats.Failures; ok > 0 {
+		stats.AvgLatencyMs = float64(c.latency.Load()) / float64(ok) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+// instrumentedTransport records metrics and optionally retries failed
+// requests on a fallback transport
+type instrumentedTransport struct {
+	name     string
+	next     http.RoundTripper
+	fallback http.RoundTripper
+	metrics  *TransportMetrics
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	counters := t.metrics.counters(t.name)
+	counters.requests.Add(1)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		counters.latency.Add(int64(time.Since(start)))
+		return resp, nil
+	}
+	counters.failures.Add(1)
+
+	// Retry over the fallback only when the body can be replayed
+	if t.fallback == nil || req.Context().Err() != nil || (req.Body != nil && req.GetBody == nil) {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+
+	counters.fallbacks.Add(1)
+	return t.fallback.RoundTrip(retry)
+}
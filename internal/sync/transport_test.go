@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/professor93/promo-pos/internal/security"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("udp blocked")
+}
+
+func TestNewHTTPClient_UnknownTransport(t *testing.T) {
+	if _, err := NewHTTPClient(&ClientConfig{Transport: "carrier-pigeon"}); err == nil {
+		t.Error("Expected error for unknown transport")
+	}
+}
+
+func TestInstrumentedTransport_FallsBackToTCP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	metrics := NewTransportMetrics()
+	tcp := &instrumentedTransport{name: TransportTCP, next: http.DefaultTransport, metrics: metrics}
+	client := &http.Client{Transport: &instrumentedTransport{
+		name:     TransportQUIC,
+		next:     failingTransport{},
+		fallback: tcp,
+		metrics:  metrics,
+	}}
+
+	resp, err := client.Post(upstream.URL, "application/json", strings.NewReader(`{"cursor":1}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	stats := metrics.Snapshot()
+	tcpStats, quicStats := stats[0], stats[1]
+	if quicStats.Requests != 1 || quicStats.Failures != 1 || quicStats.Fallbacks != 1 {
+		t.Errorf("Unexpected QUIC stats: %+v", quicStats)
+	}
+	if tcpStats.Requests != 1 || tcpStats.Failures != 0 {
+		t.Errorf("Unexpected TCP stats: %+v", tcpStats)
+	}
+}
+
+func TestSigningTransport_SignsAndVerifies(t *testing.T) {
+	signer, _ := security.NewRequestSigner("store-1", []byte("store-token"))
+
+	tamper := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := signer.VerifyRequest(r); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body := []byte(`{"ok":true}`)
+		for name, values := range signer.SignResponse(r.Header.Get(security.HeaderSignature), http.StatusOK, body) {
+			w.Header()[name] = values
+		}
+		if tamper {
+			body = []byte(`{"ok":false}`)
+		}
+		w.Write(body)
+	}))
+	defer upstream.Close()
+
+	client, err := NewHTTPClient(&ClientConfig{Signer: signer})
+	if err != nil {
+		t.Fatalf("NewHTTPClient failed: %v", err)
+	}
+
+	resp, err := client.Post(upstream.URL+"/sync/push", "application/json", strings.NewReader(`{"cursor":1}`))
+	if err != nil {
+		t.Fatalf("Signed request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Backend rejected the signed request with %d", resp.StatusCode)
+	}
+
+	tamper = true
+	if _, err := client.Post(upstream.URL+"/sync/push", "application/json", strings.NewReader(`{"cursor":2}`)); !errors.Is(err, security.ErrSignatureInvalid) {
+		t.Errorf("Expected a tampered response to be rejected, got %v", err)
+	}
+}
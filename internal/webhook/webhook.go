@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/events"
+	"github.com/professor93/promo-pos/pkg/ids"
+)
+
+// Each service event is POSTed as JSON ({"type", "time", "data"}) to every
+// endpoint subscribed to its type. Receivers check the signature: the hex
+// HMAC-SHA256, keyed with webhook_secret, of the timestamp header, a dot
+// and the raw body. Delivery is at least once and best effort: failures
+// are retried a few times, then dropped and logged.
+
+// Headers sent with every callback
+const (
+	HeaderEvent     = "X-POS-Event"     // Event type
+	HeaderDelivery  = "X-POS-Delivery"  // Unique per event and endpoint; the same across retries
+	HeaderTimestamp = "X-POS-Timestamp" // Unix seconds the callback was signed at
+	HeaderSignature = "X-POS-Signature" // "sha256=" + hex HMAC, see Sign
+)
+
+const (
+	// queueSize is how many events may wait per endpoint; beyond it events
+	// for that endpoint are dropped
+	queueSize = 64
+
+	defaultAttempts   = 3
+	defaultRetryDelay = 2 * time.Second
+	defaultTimeout    = 5 * time.Second
+)
+
+// Endpoint is one URL notified of events
+type Endpoint struct {
+	URL    string
+	Events []string // Event types sent; empty sends all but sync.progress
+}
+
+// Config holds webhook delivery configuration
+type Config struct {
+	Endpoints []Endpoint
+	Secret    []byte // HMAC key; required
+
+	// Client sends the callbacks; nil uses a client with a 5-second timeout
+	Client *http.Client
+	// Attempts per event and endpoint (default 3); RetryDelay (default 2s)
+	// doubles after each failed one
+	Attempts   int
+	RetryDelay time.Duration
+}
+
+// Dispatcher delivers service events to the configured endpoints
+type Dispatcher struct {
+	endpoints  []Endpoint
+	secret     []byte
+	client     *http.Client
+	attempts   int
+	retryDelay time.Duration
+}
+
+// New creates a dispatcher (call Run to start delivering)
+func New(cfg *Config) (*Dispatcher, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("webhooks require a webhook_secret")
+	}
+
+	d := &Dispatcher{
+		endpoints:  cfg.Endpoints,
+		secret:     cfg.Secret,
+		client:     cfg.Client,
+		attempts:   cfg.Attempts,
+		retryDelay: cfg.RetryDelay,
+	}
+	if d.client == nil {
+		d.client = &http.Client{Timeout: defaultTimeout}
+	}
+	if d.attempts <= 0 {
+		d.attempts = defaultAttempts
+	}
+	if d.retryDelay <= 0 {
+		d.retryDelay = defaultRetryDelay
+	}
+	return d, nil
+}
+
+// Run delivers the events published on bus until ctx is done. Each endpoint
+// has its own queue, so a slow one does not hold up the others.
+func (d *Dispatcher) Run(ctx context.Context, bus *events.Bus) {
+	stream, cancel := bus.Subscribe(queueSize)
+	defer cancel()
+
+	queues := make([]chan events.Event, len(d.endpoints))
+	for i, endpoint := range d.endpoints {
+		queues[i] = make(chan events.Event, queueSize)
+		go d.deliverAll(ctx, endpoint, queues[i])
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			for i, endpoint := range d.endpoints {
+				if !subscribed(endpoint, event.Type) {
+					continue
+				}
+				select {
+				case queues[i] <- event:
+				default:
+					log.Printf("Webhook %s is falling behind; dropped %s event", endpoint.URL, event.Type)
+				}
+			}
+		}
+	}
+}
+
+// deliverAll sends the events queued for endpoint in order
+func (d *Dispatcher) deliverAll(ctx context.Context, endpoint Endpoint, queue <-chan events.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-queue:
+			if err := d.Deliver(ctx, endpoint.URL, event); err != nil && ctx.Err() == nil {
+				log.Printf("Webhook %s failed for %s event: %v", endpoint.URL, event.Type, err)
+			}
+		}
+	}
+}
+
+// Deliver POSTs one signed event to url, retrying network errors and 5xx
+// answers. Other 4xx answers are not retried: the receiver rejected it.
+func (d *Dispatcher) Deliver(ctx context.Context, url string, event events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	delivery := ids.New()
+
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, url, event.Type, delivery, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == d.attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (d *Dispatcher) post(ctx context.Context, url, eventType, delivery string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, delivery)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribed reports whether endpoint receives events of eventType
+func subscribed(endpoint Endpoint, eventType string) bool {
+	if len(endpoint.Events) == 0 {
+		return eventType != events.SyncProgress
+	}
+	for _, t := range endpoint.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
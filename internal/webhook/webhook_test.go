@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/professor93/promo-pos/internal/events"
+)
+
+func TestNew_RequiresSecret(t *testing.T) {
+	if _, err := New(&Config{Endpoints: []Endpoint{{URL: "http://127.0.0.1:9"}}}); err == nil {
+		t.Error("Expected an error without a secret")
+	}
+}
+
+func TestRun_DeliversSignedEvents(t *testing.T) {
+	secret := []byte("kitchen-display-secret")
+	received := make(chan events.Event, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderSignature) != Sign(secret, r.Header.Get(HeaderTimestamp), body) {
+			t.Errorf("Bad signature %q", r.Header.Get(HeaderSignature))
+		}
+		var event events.Event
+		json.Unmarshal(body, &event)
+		if r.Header.Get(HeaderEvent) != event.Type || r.Header.Get(HeaderDelivery) == "" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	d, err := New(&Config{
+		Endpoints: []Endpoint{{URL: server.URL, Events: []string{events.SaleCompleted}}},
+		Secret:    secret,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	bus := events.NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx, bus)
+	for bus.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	bus.Publish(events.OfflineChanged, map[string]bool{"offline": true}) // Not subscribed
+	bus.Publish(events.SaleCompleted, map[string]string{"id": "S-1"})
+
+	select {
+	case event := <-received:
+		if event.Type != events.SaleCompleted {
+			t.Errorf("Expected only sale.completed, got %s", event.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No callback received")
+	}
+}
+
+func TestDeliver_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	d, _ := New(&Config{Secret: []byte("s"), RetryDelay: time.Millisecond})
+	if err := d.Deliver(context.Background(), server.URL, events.Event{Type: events.SaleCompleted}); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected one retry, got %d calls", calls.Load())
+	}
+
+	// A rejected callback is not retried
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	calls.Store(0)
+	if err := d.Deliver(context.Background(), rejecting.URL, events.Event{Type: events.SaleCompleted}); err == nil || calls.Load() != 1 {
+		t.Errorf("Expected one failed attempt, got %d (%v)", calls.Load(), err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/kardianos/service"
+	"github.com/professor93/promo-pos/pkg/paths"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -136,7 +137,7 @@ func main() {
 	}
 
 	// Check if service is already installed
-	status, err := s.Status()
+	_, err = s.Status()
 	if err == nil {
 		// Service is installed, run as service
 		if *flagDebug {
@@ -349,7 +350,7 @@ func (p *Program) performSync() {
 // initLogger initializes the zap logger
 func initLogger(debug bool) *zap.Logger {
 	// Create log directory
-	logDir := filepath.Join(os.Getenv("ProgramData"), "POSService", "logs")
+	logDir := paths.Default().LogDir
 	os.MkdirAll(logDir, 0755)
 
 	// Configure logger
@@ -1,35 +1,101 @@
 package constants
 
+// PathTemplate is a platform path that may reference environment variables
+// (%VAR% or $VAR); use pkg/paths to expand and validate it
+type PathTemplate string
+
+// Default directory templates (Windows style, expanded at runtime by pkg/paths)
+const (
+	DefaultDataDir   PathTemplate = `%PROGRAMDATA%\POSService`
+	DefaultConfigDir PathTemplate = `%PROGRAMDATA%\POSService`
+	DefaultLogDir    PathTemplate = `%PROGRAMDATA%\POSService\logs`
+)
+
 const (
 	// Application metadata
 	AppName        = "POSService"
 	AppDisplayName = "POS Background Service"
 	AppDescription = "Windows POS Service with offline-first architecture"
 
-	// File paths (Windows style, will be converted at runtime)
-	DefaultDataDir   = `%PROGRAMDATA%\POSService`
-	DefaultConfigDir = `%PROGRAMDATA%\POSService`
-	DefaultLogDir    = `%PROGRAMDATA%\POSService\logs`
-
 	// File names
 	ConfigFileName   = "config.enc"
 	DatabaseFileName = "data.db"
+	LogFileName      = "service.log" // JSON lines, in the log directory
 
 	// Default configuration values
-	DefaultPort           = 8080
-	DefaultSyncInterval   = 59 // seconds
+	DefaultPort            = 8080
+	DefaultSyncInterval    = 59 // seconds
 	DefaultMaxOfflineHours = 24
-	DefaultLogLevel       = "info"
+	DefaultLogLevel        = "info"
+	DefaultRetentionDays   = 30   // days synced history is kept locally
+	DefaultCompressAbove   = 4096 // bytes; larger encrypted values are compressed first
+
+	// HTTP listener bind addresses
+	BindLoopback = "127.0.0.1" // Single-terminal installs: only the local frontend reaches the API
+	BindAll      = "0.0.0.0"   // Multi-lane setups: other lanes, handhelds and the hub reach the API over the LAN
 
 	// HTTP Server settings
 	DefaultMaxConcurrentConnections = 100
-	DefaultRateLimitPerMinute      = 100
-	DefaultRequestTimeout          = 30 // seconds
+	DefaultRateLimitPerMinute       = 100
+	DefaultRequestTimeout           = 30       // seconds; handler deadline of routes without their own
+	PriceRequestTimeout             = 2        // seconds; price lookups on the checkout hot path fail fast
+	BulkRequestTimeout              = 120      // seconds; sync and imports move whole catalogs
+	ChangesWaitSeconds              = 30       // longest a GET /changes poll waits for a change
+	DefaultBodyLimit                = 1 << 20  // bytes; larger request bodies are refused
+	DefaultImportBodyLimit          = 32 << 20 // bytes; the limit for POST /import uploads
 
 	// Sync settings
+	SyncTransportTCP            = "tcp"  // HTTP/1.1 or HTTP/2 over TCP
+	SyncTransportQUIC           = "quic" // Experimental HTTP/3 over QUIC
+	DefaultSyncTransport        = SyncTransportTCP
 	DefaultSyncRetryMax         = 5
 	DefaultSyncRetryBackoffBase = 2 // seconds
 
+	// Local API frontend tokens (HS256 JWTs signed with api_secret)
+	MinAPISecretLength = 32 // bytes
+
+	// Admin password guarding destructive routes (bcrypt-hashed)
+	MinAdminPasswordLength = 12 // characters
+
+	// Cashier PIN sign-in (argon2id-hashed PINs, short-lived sessions)
+	MinPINLength         = 4  // digits
+	MaxPINLength         = 8  // digits
+	PINSessionTTLMinutes = 30 // lifetime of a cashier session token
+
+	// One-time tickets opening /ws/status and /sync/events from a browser
+	StreamTicketTTLSeconds = 30 // how long a ticket may wait to be used
+
+	// Key storage backends for sealed secrets (server key)
+	KeyStorageAuto    = "auto" // TPM when present, else DPAPI/file
+	KeyStorageTPM     = "tpm"  // Require a TPM
+	KeyStorageFile    = "file" // DPAPI-wrapped (Windows) or 0600 file
+	DefaultKeyStorage = KeyStorageAuto
+
+	// Secrets vault backends for tokens and passwords from the config
+	SecretVaultAuto       = "auto"    // Credential Manager on Windows, else file
+	SecretVaultFile       = "file"    // vault.enc, encrypted with the config key
+	SecretVaultCredential = "credman" // Windows Credential Manager
+	SecretVaultEnv        = "env"     // Read-only, from POS_SECRET_* variables
+	DefaultSecretVault    = SecretVaultAuto
+
+	// Database ciphers; every ciphertext is tagged with the one it used
+	DBCipherChaCha20  = "chacha20-poly1305"  // Untagged, as before ciphers were tagged
+	DBCipherAES256GCM = "aes-256-gcm"        // FIPS-validated; forced by fips_mode
+	DBCipherXChaCha20 = "xchacha20-poly1305" // 24-byte nonces, for high write rates
+	DefaultDBCipher   = DBCipherChaCha20
+
+	// Large basket handling
+	MaxBasketChunkLines     = 200  // lines accepted per append request
+	MaxBasketLines          = 5000 // lines per basket
+	DefaultReceiptPageBytes = 4096 // printer buffer budget per receipt page
+
+	// Receipt printer encodings (ESC/POS character tables)
+	ReceiptEncodingASCII   = "ascii"  // Any printer; everything else is transliterated
+	ReceiptEncodingCP866   = "cp866"  // DOS Cyrillic, ESC t 17 on most printers
+	ReceiptEncodingCP1251  = "cp1251" // Windows Cyrillic, ESC t 46 on most printers
+	ReceiptEncodingUTF8    = "utf-8"  // UTF-8 capable models
+	DefaultReceiptEncoding = ReceiptEncodingASCII
+
 	// Service settings
 	WindowsServiceName        = "POSService"
 	WindowsServiceDisplayName = "POS Background Service"
@@ -40,4 +106,80 @@ const (
 
 	// Offline grace period
 	OfflineGracePeriodHours = 24
+
+	// Store roles
+	RoleTerminal = "terminal" // Regular till, syncs directly or via the store hub
+	RoleHub      = "hub"      // Designated store machine serving other terminals
+	DefaultRole  = RoleTerminal
+
+	// Lane profiles
+	LaneProfileTill         = "till"          // Staffed till, full local API
+	LaneProfileSelfCheckout = "self_checkout" // Customer-facing lane, restricted API unless an attendant token is presented
+	DefaultLaneProfile      = LaneProfileTill
+	DefaultSCOMaxItems      = 50 // items per self-checkout cart before an attendant must take over
+
+	// Handheld stock-taking devices
+	HandheldTokenTTLMinutes = 15  // lifetime of a device session token
+	MaxLabelCopies          = 100 // shelf labels per print request
+	DefaultDeviceAuditLimit = 100 // audit entries returned per device
+
+	// Inventory
+	DefaultStockMovementLimit = 50  // movements returned with a SKU's stock level
+	MaxStockMovementLimit     = 500 // most movements one request can ask for
+	MaxReceiptLines           = 500 // lines in one goods receipt
+
+	// Day-close checklist items
+	ClosingTabsClosed            = "tabs_closed"       // No carts left open
+	ClosingDrawerReconciled      = "drawer_reconciled" // Cash drawer counted and matched
+	ClosingPendingSync           = "pending_sync"      // Unsynced changes at most closing_max_pending_sync
+	ClosingBackupDone            = "backup_done"       // Database backed up within the last day
+	DefaultClosingMaxPendingSync = 50
+
+	// Privacy mode (masked responses while screen sharing)
+	DefaultPrivacyMinutes = 30
+	MaxPrivacyMinutes     = 240
+
+	// Brute-force lockout of failed authentication attempts
+	AuthLockoutThreshold     = 5  // failures per source before a lockout
+	AuthFailureWindowMinutes = 15 // failures older than this are forgotten
+	AuthLockoutBaseSeconds   = 30 // first lockout, doubled for each further one
+	AuthLockoutMaxMinutes    = 60 // longest lockout
+
+	// Security audit log
+	DefaultAuditLimit = 100 // entries returned per request
+
+	// Scheduled reports, rendered each morning for the previous day
+	ReportDailySales    = "daily_sales"    // Sales and revenue per terminal
+	ReportPromoUptake   = "promo_uptake"   // Lines, units and revenue per promotion
+	ReportRefundSummary = "refund_summary" // Every refund with its amount
+	ReportFormatPDF     = "pdf"
+	ReportFormatCSV     = "csv"
+	DefaultReportTime   = "06:00" // local time the previous day's reports are generated
+
+	// External peripherals reported in /status
+	PeripheralPrinter      = "printer" // Receipt printer
+	PeripheralEFT          = "eft"     // Card payment terminal
+	PeripheralScale        = "scale"
+	PeripheralDisplay      = "display" // Customer-facing display
+	PeripheralProbeSeconds = 30        // between probes of networked peripherals
+	PeripheralStaleSeconds = 120       // reports older than this become unknown
+
+	// Boot history behind GET /admin/uptime
+	BootHistoryDays      = 90 // boots older than this are pruned
+	BootHeartbeatSeconds = 60 // how often a running boot records it is alive
+
+	// Safe mode after a crash loop: core API and diagnostics only
+	DefaultSafeModeCrashes       = 3  // crashes within the window that start safe mode
+	DefaultSafeModeWindowMinutes = 15 // how far back crashes are counted
+
+	// Deep health checks in /health
+	HealthQuickCheckSeconds = 300       // between quick integrity checks; pings run every time
+	HealthSyncLateIntervals = 3         // sync intervals without a success before last_sync is degraded
+	HealthDiskLowBytes      = 1 << 30   // free space below which disk_space is degraded
+	HealthDiskCriticalBytes = 100 << 20 // free space below which disk_space fails
+
+	// Remote log retrieval (GET /logs)
+	LogTailDefaultLines = 200  // entries returned without ?tail=
+	LogTailMaxLines     = 5000 // most entries one request may ask for
+	DiagnosticsLogLines = 1000 // log entries in a diagnostics bundle
 )
@@ -0,0 +1,170 @@
+package ids
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ULIDLength is the length of an encoded ULID
+const ULIDLength = 26
+
+// crockford is the Crockford base32 alphabet ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ErrInvalidID is returned when parsing something that is not a ULID
+var ErrInvalidID = errors.New("invalid ULID")
+
+// Generator creates record IDs
+type Generator interface {
+	NewID() string
+}
+
+var (
+	mu        sync.RWMutex
+	generator Generator = NewULIDGenerator()
+)
+
+// New returns a new ID from the current generator
+func New() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return generator.NewID()
+}
+
+// SetGenerator replaces the generator used by New and returns the previous one
+func SetGenerator(g Generator) Generator {
+	mu.Lock()
+	defer mu.Unlock()
+	previous := generator
+	generator = g
+	return previous
+}
+
+// ULIDGenerator creates ULIDs: a 48-bit millisecond timestamp followed by
+// 80 random bits. They sort by creation time as plain strings, and random
+// bits make IDs minted on different terminals before a sync collision-safe.
+// IDs from one generator are strictly increasing, even within a millisecond
+// or when the clock steps back.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entropy io.Reader
+	last    [16]byte
+}
+
+// NewULIDGenerator creates a ULID generator reading crypto/rand
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{now: time.Now, entropy: rand.Reader}
+}
+
+// NewID returns a new ULID. It panics if the entropy source fails, which
+// crypto/rand does not.
+func (g *ULIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var id [16]byte
+	ms := uint64(g.now().UnixMilli())
+	lastMS := timestamp(g.last)
+
+	if ms <= lastMS {
+		// Same millisecond (or a clock step back): increment the previous
+		// ID so order is kept
+		id = g.last
+		for i := 15; i >= 0; i-- {
+			id[i]++
+			if id[i] != 0 {
+				break
+			}
+		}
+	} else {
+		putTimestamp(&id, ms)
+		if _, err := io.ReadFull(g.entropy, id[6:]); err != nil {
+			panic(fmt.Sprintf("failed to read ULID entropy: %v", err))
+		}
+	}
+
+	g.last = id
+	return encode(id)
+}
+
+// Time returns the creation time encoded in a ULID
+func Time(id string) (time.Time, error) {
+	raw, err := decode(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(int64(timestamp(raw))), nil
+}
+
+// Valid reports whether id is a well-formed ULID
+func Valid(id string) bool {
+	_, err := decode(id)
+	return err == nil
+}
+
+// timestamp returns the millisecond timestamp of a raw ULID
+func timestamp(id [16]byte) uint64 {
+	var ms uint64
+	for _, b := range id[:6] {
+		ms = ms<<8 | uint64(b)
+	}
+	return ms
+}
+
+// putTimestamp writes a millisecond timestamp into a raw ULID
+func putTimestamp(id *[16]byte, ms uint64) {
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// encode writes 128 bits as 26 base32 characters (the first carries 3 bits)
+func encode(id [16]byte) string {
+	var out [ULIDLength]byte
+	var acc uint64
+	bits := 2 // 130 output bits: pad the front with two zero bits
+	pos := 0
+	for _, b := range id {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = crockford[(acc>>uint(bits))&31]
+			pos++
+		}
+	}
+	return string(out[:])
+}
+
+// decode parses an encoded ULID
+func decode(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) != ULIDLength || s[0] > '7' {
+		return id, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+
+	var acc uint64
+	bits := -2 // Drop the two padding bits
+	pos := 0
+	for _, c := range strings.ToUpper(s) {
+		v := strings.IndexRune(crockford, c)
+		if v < 0 {
+			return id, fmt.Errorf("%w: %q", ErrInvalidID, s)
+		}
+		acc = acc<<5 | uint64(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			id[pos] = byte(acc >> uint(bits))
+			pos++
+		}
+	}
+	return id, nil
+}
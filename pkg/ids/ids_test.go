@@ -0,0 +1,88 @@
+package ids
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestULID_RoundTrip(t *testing.T) {
+	g := NewULIDGenerator()
+	at := time.Date(2026, 5, 1, 12, 0, 0, 123e6, time.UTC)
+	g.now = func() time.Time { return at }
+
+	id := g.NewID()
+	if len(id) != ULIDLength || !Valid(id) {
+		t.Fatalf("Invalid ULID %q", id)
+	}
+	if got, err := Time(id); err != nil || !got.Equal(at) {
+		t.Errorf("Expected timestamp %v, got %v (%v)", at, got, err)
+	}
+
+	raw, _ := decode(id)
+	if encode(raw) != id {
+		t.Errorf("Encoding does not round-trip: %q", id)
+	}
+}
+
+func TestULID_KnownEncoding(t *testing.T) {
+	g := NewULIDGenerator()
+	g.now = func() time.Time { return time.UnixMilli(1469918176385) }
+	g.entropy = bytes.NewReader(make([]byte, 10))
+
+	// Timestamp from the ULID specification, zero entropy
+	if id := g.NewID(); id != "01ARYZ6S410000000000000000" {
+		t.Errorf("Unexpected encoding %q", id)
+	}
+}
+
+func TestULID_Monotonic(t *testing.T) {
+	g := NewULIDGenerator()
+	now := time.Now()
+	g.now = func() time.Time { return now }
+
+	var generated []string
+	for i := 0; i < 1000; i++ {
+		generated = append(generated, g.NewID())
+	}
+
+	// The clock stepping back must not reorder IDs
+	now = now.Add(-time.Second)
+	generated = append(generated, g.NewID())
+	now = now.Add(time.Hour)
+	generated = append(generated, g.NewID())
+
+	if !sort.StringsAreSorted(generated) {
+		t.Error("Expected IDs to sort in creation order")
+	}
+	seen := make(map[string]bool)
+	for _, id := range generated {
+		if seen[id] {
+			t.Fatalf("Duplicate ID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+type fixedGenerator string
+
+func (f fixedGenerator) NewID() string { return string(f) }
+
+func TestSetGenerator(t *testing.T) {
+	previous := SetGenerator(fixedGenerator("fixed"))
+	defer SetGenerator(previous)
+
+	if id := New(); id != "fixed" {
+		t.Errorf("Expected the replacement generator, got %q", id)
+	}
+}
+
+func TestDecode_Rejects(t *testing.T) {
+	for _, id := range []string{"", "01ARYZ6S41", "81ARYZ6S410000000000000000", "01ARYZ6S41000000000000000U"} {
+		if _, err := Time(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Expected ErrInvalidID for %q, got %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+// Environment variables overriding the default directories
+const (
+	EnvDataDir   = "POS_DATA_DIR"
+	EnvConfigDir = "POS_CONFIG_DIR"
+	EnvLogDir    = "POS_LOG_DIR"
+)
+
+var windowsVarPattern = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// Paths holds the resolved, absolute application directories
+type Paths struct {
+	DataDir   string
+	ConfigDir string
+	LogDir    string
+}
+
+// Overrides replaces individual default directories (empty fields keep the default)
+type Overrides struct {
+	DataDir   string
+	ConfigDir string
+	LogDir    string
+}
+
+// Resolve expands and validates the application directories.
+// Precedence per directory: explicit override, environment variable, default template.
+func Resolve(o *Overrides) (*Paths, error) {
+	if o == nil {
+		o = &Overrides{}
+	}
+
+	dataDir, err := resolveOne(o.DataDir, EnvDataDir, constants.DefaultDataDir, "POSService")
+	if err != nil {
+		return nil, fmt.Errorf("data directory: %w", err)
+	}
+
+	configDir, err := resolveOne(o.ConfigDir, EnvConfigDir, constants.DefaultConfigDir, "POSService")
+	if err != nil {
+		return nil, fmt.Errorf("config directory: %w", err)
+	}
+
+	logDir, err := resolveOne(o.LogDir, EnvLogDir, constants.DefaultLogDir, filepath.Join("POSService", "logs"))
+	if err != nil {
+		return nil, fmt.Errorf("log directory: %w", err)
+	}
+
+	return &Paths{
+		DataDir:   dataDir,
+		ConfigDir: configDir,
+		LogDir:    logDir,
+	}, nil
+}
+
+// Default resolves the directories without overrides. It never fails:
+// invalid environment overrides are ignored in favour of the defaults.
+func Default() *Paths {
+	p, err := Resolve(nil)
+	if err == nil {
+		return p
+	}
+
+	return &Paths{
+		DataDir:   devFallback("POSService"),
+		ConfigDir: devFallback("POSService"),
+		LogDir:    devFallback(filepath.Join("POSService", "logs")),
+	}
+}
+
+// Ensure creates all directories
+func (p *Paths) Ensure() error {
+	for _, dir := range []string{p.DataDir, p.ConfigDir, p.LogDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// ConfigFile returns the path of the encrypted config file
+func (p *Paths) ConfigFile() string {
+	return filepath.Join(p.ConfigDir, constants.ConfigFileName)
+}
+
+// DatabaseFile returns the path of the default database file
+func (p *Paths) DatabaseFile() string {
+	return filepath.Join(p.DataDir, constants.DatabaseFileName)
+}
+
+// Expand expands %VAR% and $VAR references and converts separators to
+// the current platform. Referencing an unset variable is an error.
+func Expand(template constants.PathTemplate) (string, error) {
+	s := string(template)
+
+	var missing []string
+	s = windowsVarPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := strings.Trim(ref, "%")
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			missing = append(missing, name)
+			return ref
+		}
+		return value
+	})
+	s = os.Expand(s, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			missing = append(missing, name)
+		}
+		return value
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unset environment variable(s) in %q: %s", template, strings.Join(missing, ", "))
+	}
+
+	if runtime.GOOS != "windows" {
+		s = strings.ReplaceAll(s, `\`, "/")
+	}
+
+	return filepath.Clean(filepath.FromSlash(s)), nil
+}
+
+// Validate checks that an expanded path is usable and returns it absolute
+func Validate(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	if strings.ContainsRune(path, 0) {
+		return "", fmt.Errorf("path contains a NUL byte")
+	}
+	if strings.Contains(path, "%") {
+		return "", fmt.Errorf("path %q contains an unexpanded variable", path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to make %q absolute: %w", path, err)
+	}
+
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		return "", fmt.Errorf("path %q exists and is not a directory", abs)
+	}
+
+	return abs, nil
+}
+
+// resolveOne resolves a single directory
+func resolveOne(override, envVar string, template constants.PathTemplate, fallback string) (string, error) {
+	if override == "" {
+		override = os.Getenv(envVar)
+	}
+
+	if override != "" {
+		expanded, err := Expand(constants.PathTemplate(override))
+		if err != nil {
+			return "", err
+		}
+		return Validate(expanded)
+	}
+
+	expanded, err := Expand(template)
+	if err != nil {
+		// %PROGRAMDATA% is unset outside Windows: fall back to the working
+		// directory, as development builds always have
+		return devFallback(fallback), nil
+	}
+
+	return Validate(expanded)
+}
+
+// devFallback returns a directory relative to the working directory
+func devFallback(rel string) string {
+	abs, err := filepath.Abs(rel)
+	if err != nil {
+		return rel
+	}
+	return abs
+}
@@ -0,0 +1,80 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/professor93/promo-pos/pkg/constants"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("POS_TEST_ROOT", "/tmp/posroot")
+
+	got, err := Expand(`%POS_TEST_ROOT%\POSService\logs`)
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+
+	expected := filepath.Clean(filepath.FromSlash("/tmp/posroot/POSService/logs"))
+	if filepath.Separator == '\\' {
+		expected = `\tmp\posroot\POSService\logs`
+	}
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	if got, _ := Expand("$POS_TEST_ROOT/data"); got != filepath.FromSlash("/tmp/posroot/data") {
+		t.Errorf("Unexpected $VAR expansion: %s", got)
+	}
+}
+
+func TestExpand_UnsetVariable(t *testing.T) {
+	os.Unsetenv("POS_TEST_UNSET")
+
+	if _, err := Expand(`%POS_TEST_UNSET%\POSService`); err == nil {
+		t.Error("Expected error for unset variable")
+	}
+}
+
+func TestResolve_Precedence(t *testing.T) {
+	envDir := t.TempDir()
+	overrideDir := t.TempDir()
+	t.Setenv(EnvDataDir, envDir)
+	t.Setenv(EnvLogDir, envDir)
+
+	p, err := Resolve(&Overrides{DataDir: overrideDir})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if p.DataDir != overrideDir {
+		t.Errorf("Expected override %s, got %s", overrideDir, p.DataDir)
+	}
+	if p.LogDir != envDir {
+		t.Errorf("Expected environment value %s, got %s", envDir, p.LogDir)
+	}
+	if !filepath.IsAbs(p.ConfigDir) {
+		t.Errorf("Expected absolute config dir, got %s", p.ConfigDir)
+	}
+}
+
+func TestResolve_RejectsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	os.WriteFile(file, []byte("x"), 0644)
+
+	if _, err := Resolve(&Overrides{DataDir: file}); err == nil {
+		t.Error("Expected error when data dir is a file")
+	}
+}
+
+func TestPaths_Files(t *testing.T) {
+	p := &Paths{DataDir: "/data", ConfigDir: "/config"}
+
+	if p.ConfigFile() != filepath.Join("/config", constants.ConfigFileName) {
+		t.Errorf("Unexpected config file: %s", p.ConfigFile())
+	}
+	if p.DatabaseFile() != filepath.Join("/data", constants.DatabaseFileName) {
+		t.Errorf("Unexpected database file: %s", p.DatabaseFile())
+	}
+}